@@ -0,0 +1,207 @@
+// Package brokersim simulates a Kafka-like message broker in memory, on
+// the virtual clock of a synctest bubble, for testing event-driven
+// services without containers: topics split into partitions, each an
+// ordered log of records with offsets; retention that deletes records once
+// they are old enough; and consumer groups whose members share the
+// partitions of the topics they subscribe to, rebalance as members join,
+// leave or stop polling, and commit the offsets they processed up to.
+//
+// As with Kafka, a consumer resumes a partition it is assigned from the
+// offset its group committed last, so records processed but not committed
+// before a rebalance are processed again, and a consumer that commits
+// after its group rebalanced without it noticing fails with
+// ErrRebalanced. Rebalances take effect at once, without the delays of a
+// real group coordinator.
+package brokersim
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Errors of the broker and its consumers.
+var (
+	ErrClosed       = errors.New("brokersim: closed")
+	ErrRebalanced   = errors.New("brokersim: group rebalanced since the last poll")
+	ErrUnknownTopic = errors.New("brokersim: unknown topic")
+)
+
+// Config configures a Broker.
+type Config struct {
+	// Retention is how long records are kept, 0 for forever.
+	Retention time.Duration
+	// MaxPollInterval is how long a consumer may go between polls before
+	// it is removed from its group, default 30s, as max.poll.interval.ms
+	// would.
+	MaxPollInterval time.Duration
+}
+
+func (c Config) maxPollInterval() time.Duration {
+	if c.MaxPollInterval > 0 {
+		return c.MaxPollInterval
+	}
+	return 30 * time.Second
+}
+
+// Record is a record of a partition.
+type Record struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Time      time.Time // when it was produced
+}
+
+func (r Record) String() string { return fmt.Sprintf("%s/%d@%d", r.Topic, r.Partition, r.Offset) }
+
+// TopicPartition names a partition of a topic.
+type TopicPartition struct {
+	Topic     string
+	Partition int
+}
+
+func (tp TopicPartition) String() string { return fmt.Sprintf("%s/%d", tp.Topic, tp.Partition) }
+
+// Broker is a simulated broker. It is safe for concurrent use.
+type Broker struct {
+	cfg Config
+
+	mu      sync.Mutex
+	topics  map[string]*topic
+	groups  map[string]*group
+	changed chan struct{} // closed and replaced whenever records or groups change
+	closed  bool
+}
+
+// topic is the partitions of a topic, with b.mu held.
+type topic struct {
+	parts []*partition
+	next  int // partition of the next record without a key
+}
+
+// partition is the log of a partition: the records from its start offset.
+type partition struct {
+	start   int64
+	records []Record
+}
+
+// end returns the offset of the next record of p.
+func (p *partition) end() int64 { return p.start + int64(len(p.records)) }
+
+// New returns a broker configured by cfg. Close it before the bubble
+// ends.
+func New(cfg Config) *Broker {
+	return &Broker{cfg: cfg, topics: make(map[string]*topic), groups: make(map[string]*group), changed: make(chan struct{})}
+}
+
+// Close closes b and the consumers of its groups.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, g := range b.groups {
+		for _, m := range g.members {
+			m.timer.Stop()
+		}
+	}
+	b.notify()
+}
+
+// notify wakes the consumers waiting for records, with b.mu held.
+func (b *Broker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// CreateTopic creates the topic name of n partitions, if it does not
+// exist. It panics if n is less than 1.
+func (b *Broker) CreateTopic(name string, n int) {
+	if n < 1 {
+		panic(fmt.Sprintf("brokersim: topic %s of %d partitions", name, n))
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.topics[name] != nil {
+		return
+	}
+	t := &topic{parts: make([]*partition, n)}
+	for i := range t.parts {
+		t.parts[i] = new(partition)
+	}
+	b.topics[name] = t
+	for _, g := range b.groups {
+		if g.subscribed(name) {
+			b.rebalance(g)
+		}
+	}
+}
+
+// Produce appends a record to the topic, in the partition its key hashes
+// to, or the next one in turn if it has none, and returns where.
+func (b *Broker) Produce(topicName string, key, value []byte) (Record, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return Record{}, ErrClosed
+	}
+	t := b.topics[topicName]
+	if t == nil {
+		return Record{}, fmt.Errorf("%w %q", ErrUnknownTopic, topicName)
+	}
+	i := t.next
+	if key != nil {
+		h := fnv.New32a()
+		h.Write(key)
+		i = int(h.Sum32() % uint32(len(t.parts)))
+	} else {
+		t.next = (t.next + 1) % len(t.parts)
+	}
+	p := t.parts[i]
+	b.expire(p)
+	r := Record{Topic: topicName, Partition: i, Offset: p.end(), Key: key, Value: value, Time: time.Now()}
+	p.records = append(p.records, r)
+	b.notify()
+	return r, nil
+}
+
+// expire deletes the records of p past retention, with b.mu held.
+func (b *Broker) expire(p *partition) {
+	if b.cfg.Retention <= 0 {
+		return
+	}
+	n := 0
+	for n < len(p.records) && time.Since(p.records[n].Time) >= b.cfg.Retention {
+		n++
+	}
+	p.records = p.records[n:]
+	p.start += int64(n)
+}
+
+// Offsets returns the start offset of the partition, that of its oldest
+// record kept, and its end offset, that of its next record.
+func (b *Broker) Offsets(tp TopicPartition) (start, end int64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, err := b.partition(tp)
+	if err != nil {
+		return 0, 0, err
+	}
+	b.expire(p)
+	return p.start, p.end(), nil
+}
+
+// partition returns the partition tp, with b.mu held.
+func (b *Broker) partition(tp TopicPartition) (*partition, error) {
+	t := b.topics[tp.Topic]
+	if t == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTopic, tp.Topic)
+	}
+	if tp.Partition < 0 || tp.Partition >= len(t.parts) {
+		return nil, fmt.Errorf("brokersim: no partition %v", tp)
+	}
+	return t.parts[tp.Partition], nil
+}
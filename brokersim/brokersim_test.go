@@ -0,0 +1,71 @@
+package brokersim
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestProduce(t *testing.T) {
+	b := New(Config{})
+	defer b.Close()
+	b.CreateTopic("orders", 3)
+	var parts []int
+	for range 3 {
+		r, err := b.Produce("orders", nil, []byte("v"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, r.Partition)
+	}
+	if parts[0] == parts[1] || parts[1] == parts[2] || parts[0] == parts[2] {
+		t.Errorf("records without keys went to partitions %v, want each in turn", parts)
+	}
+	r1, _ := b.Produce("orders", []byte("k"), []byte("v1"))
+	r2, _ := b.Produce("orders", []byte("k"), []byte("v2"))
+	if r1.Partition != r2.Partition || r2.Offset != r1.Offset+1 {
+		t.Errorf("records of the same key: %v then %v, want the next offset of the same partition", r1, r2)
+	}
+	if _, err := b.Produce("payments", nil, nil); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("producing to an unknown topic: %v", err)
+	}
+}
+
+func TestCreateTopicWithoutPartitions(t *testing.T) {
+	b := New(Config{})
+	defer b.Close()
+	defer func() {
+		if r := recover(); r != "brokersim: topic orders of 0 partitions" {
+			t.Errorf("recovered %v", r)
+		}
+	}()
+	b.CreateTopic("orders", 0)
+}
+
+func TestRetention(t *testing.T) {
+	synctest.Run(func() {
+		b := New(Config{Retention: time.Hour})
+		defer b.Close()
+		b.CreateTopic("events", 1)
+		for range 3 {
+			b.Produce("events", nil, []byte("old"))
+		}
+		time.Sleep(30 * time.Minute)
+		b.Produce("events", nil, []byte("new"))
+		time.Sleep(45 * time.Minute)
+		tp := TopicPartition{"events", 0}
+		if start, end, _ := b.Offsets(tp); start != 3 || end != 4 {
+			t.Errorf("offsets %d to %d after an hour and a quarter, want 3 to 4", start, end)
+		}
+		c, _ := b.Subscribe("late", "c1", "events")
+		rs, err := c.Poll(context.Background(), 0)
+		if err != nil || len(rs) != 1 || rs[0].Offset != 3 {
+			t.Errorf("a new group polled %v, %v; want the record at 3 only", rs, err)
+		}
+		if lag := b.Lag("late", "events"); lag != 1 {
+			t.Errorf("lag %d before committing, want 1", lag)
+		}
+	})
+}
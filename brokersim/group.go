@@ -0,0 +1,293 @@
+package brokersim
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// group is a consumer group, with b.mu held.
+type group struct {
+	generation int
+	members    map[string]*member // by consumer ID
+	committed  map[TopicPartition]int64
+}
+
+// member is a consumer in its group.
+type member struct {
+	c        *Consumer
+	assigned []TopicPartition
+	timer    *time.Timer // removing it once it did not poll for MaxPollInterval
+}
+
+func (g *group) subscribed(topic string) bool {
+	for _, m := range g.members {
+		if slices.Contains(m.c.topics, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Consumer is a member of a consumer group, consuming the partitions
+// assigned to it. It is safe for concurrent use, though, as with Kafka,
+// one goroutine usually polls and commits.
+type Consumer struct {
+	b      *Broker
+	g      *group
+	id     string
+	topics []string
+
+	// With b.mu held:
+	m          *member // nil once removed from the group
+	generation int     // of the group at the last poll
+	assigned   []TopicPartition
+	positions  map[TopicPartition]int64
+	next       int // assigned partition to fetch from first
+	closed     bool
+}
+
+// Subscribe adds the consumer id to the group, subscribed to topics, and
+// rebalances the group. The ID must be unique in the group.
+func (b *Broker) Subscribe(groupName, id string, topics ...string) (*Consumer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	g := b.groups[groupName]
+	if g == nil {
+		g = &group{members: make(map[string]*member), committed: make(map[TopicPartition]int64)}
+		b.groups[groupName] = g
+	}
+	if g.members[id] != nil {
+		return nil, fmt.Errorf("brokersim: %s is already a member of %s", id, groupName)
+	}
+	c := &Consumer{b: b, g: g, id: id, topics: slices.Clone(topics)}
+	b.join(c)
+	return c, nil
+}
+
+// join adds c to its group and rebalances it, with b.mu held.
+func (b *Broker) join(c *Consumer) {
+	m := &member{c: c}
+	m.timer = time.AfterFunc(b.cfg.maxPollInterval(), func() { b.leave(c, m) })
+	c.m = m
+	c.g.members[c.id] = m
+	b.rebalance(c.g)
+}
+
+// leave removes the member m of c from its group, if it still is one,
+// and rebalances it.
+func (b *Broker) leave(c *Consumer, m *member) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(c, m)
+}
+
+// remove is leave with b.mu held.
+func (b *Broker) remove(c *Consumer, m *member) {
+	if m == nil || c.m != m {
+		return
+	}
+	m.timer.Stop()
+	c.m = nil
+	delete(c.g.members, c.id)
+	b.rebalance(c.g)
+}
+
+// rebalance starts a new generation of g, assigning the partitions of
+// every topic to the members subscribed to it, in ranges, in the order of
+// their IDs, with b.mu held.
+func (b *Broker) rebalance(g *group) {
+	g.generation++
+	ids := slices.Sorted(maps.Keys(g.members))
+	for _, id := range ids {
+		g.members[id].assigned = nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.topics)) {
+		var ms []*member
+		for _, id := range ids {
+			if m := g.members[id]; slices.Contains(m.c.topics, name) {
+				ms = append(ms, m)
+			}
+		}
+		if len(ms) == 0 {
+			continue
+		}
+		n := len(b.topics[name].parts)
+		for i, p := 0, 0; i < len(ms); i++ {
+			size := n / len(ms)
+			if i < n%len(ms) {
+				size++
+			}
+			for range size {
+				ms[i].assigned = append(ms[i].assigned, TopicPartition{name, p})
+				p++
+			}
+		}
+	}
+	b.notify()
+}
+
+// Poll returns the next records of the partitions assigned to c, up to
+// n if positive, waiting for some until ctx is done. If the group
+// rebalanced since the last poll, c takes its new assignment first,
+// resuming every partition from the offset committed, or from its start
+// if none was; if c was removed from the group, it joins again. Records
+// deleted by retention are skipped.
+func (c *Consumer) Poll(ctx context.Context, n int) ([]Record, error) {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		if b.closed || c.closed {
+			return nil, ErrClosed
+		}
+		if c.m == nil {
+			b.join(c)
+		}
+		c.sync()
+		if rs := c.fetch(n); len(rs) > 0 {
+			c.m.timer.Reset(b.cfg.maxPollInterval())
+			return rs, nil
+		}
+		// Waiting in Poll is polling.
+		c.m.timer.Stop()
+		changed := b.changed
+		b.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			if c.m != nil {
+				c.m.timer.Reset(b.cfg.maxPollInterval())
+			}
+			return nil, ctx.Err()
+		case <-changed:
+		}
+		b.mu.Lock()
+	}
+}
+
+// sync takes the assignment of the current generation of the group, with
+// b.mu held.
+func (c *Consumer) sync() {
+	if c.generation == c.g.generation {
+		return
+	}
+	c.generation = c.g.generation
+	c.assigned = slices.Clone(c.m.assigned)
+	c.positions = make(map[TopicPartition]int64)
+	for _, tp := range c.assigned {
+		c.positions[tp] = c.g.committed[tp]
+	}
+	c.next = 0
+}
+
+// fetch returns up to n records from the positions of c, and advances
+// them, with b.mu held.
+func (c *Consumer) fetch(n int) []Record {
+	var rs []Record
+	for i := range c.assigned {
+		tp := c.assigned[(c.next+i)%len(c.assigned)]
+		p, err := c.b.partition(tp)
+		if err != nil {
+			continue
+		}
+		c.b.expire(p)
+		pos := min(max(c.positions[tp], p.start), p.end())
+		for pos < p.end() && (n <= 0 || len(rs) < n) {
+			rs = append(rs, p.records[pos-p.start])
+			pos++
+		}
+		c.positions[tp] = pos
+	}
+	if len(c.assigned) > 0 {
+		c.next = (c.next + 1) % len(c.assigned)
+	}
+	return rs
+}
+
+// Commit commits the positions of c in the partitions assigned to it:
+// the offsets of the records after those polled. It fails with
+// ErrRebalanced if the group rebalanced since the last poll, or removed
+// c.
+func (c *Consumer) Commit() error {
+	return c.CommitOffsets(nil)
+}
+
+// CommitOffsets commits offsets, by partition, as the offsets of the
+// next records to process, or the positions of c if offsets is nil; see
+// Commit. Partitions not assigned to c are ignored.
+func (c *Consumer) CommitOffsets(offsets map[TopicPartition]int64) error {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.closed || c.closed:
+		return ErrClosed
+	case c.m == nil || c.generation != c.g.generation:
+		return ErrRebalanced
+	}
+	if offsets == nil {
+		offsets = c.positions
+	}
+	for _, tp := range c.assigned {
+		if off, ok := offsets[tp]; ok {
+			c.g.committed[tp] = off
+		}
+	}
+	return nil
+}
+
+// Assignment returns the partitions assigned to c at the last poll.
+func (c *Consumer) Assignment() []TopicPartition {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return slices.Clone(c.assigned)
+}
+
+// Close removes c from its group, which rebalances.
+func (c *Consumer) Close() {
+	b := c.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c.closed = true
+	b.remove(c, c.m)
+}
+
+// Committed returns the offset the group committed for the partition, if
+// it did.
+func (b *Broker) Committed(groupName string, tp TopicPartition) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g := b.groups[groupName]
+	if g == nil {
+		return 0, false
+	}
+	off, ok := g.committed[tp]
+	return off, ok
+}
+
+// Lag returns how many records of the topic the group has not committed,
+// of those kept.
+func (b *Broker) Lag(groupName, topicName string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topics[topicName]
+	if t == nil {
+		return 0
+	}
+	var lag int64
+	for i, p := range t.parts {
+		b.expire(p)
+		off := p.start
+		if g := b.groups[groupName]; g != nil {
+			off = max(off, g.committed[TopicPartition{topicName, i}])
+		}
+		lag += p.end() - off
+	}
+	return lag
+}
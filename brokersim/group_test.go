@@ -0,0 +1,122 @@
+package brokersim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// produce produces n records without keys to the topic.
+func produce(b *Broker, topic string, n int) {
+	for i := range n {
+		b.Produce(topic, nil, []byte(fmt.Sprint(i)))
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	synctest.Run(func() {
+		b := New(Config{})
+		defer b.Close()
+		b.CreateTopic("t", 2)
+		produce(b, "t", 6)
+		ctx := context.Background()
+		c1, _ := b.Subscribe("g", "c1", "t")
+		rs, _ := c1.Poll(ctx, 0)
+		if len(rs) != 6 {
+			t.Fatalf("c1 alone polled %v, want every record", rs)
+		}
+		// c2 joins while c1 processes what it polled.
+		c2, _ := b.Subscribe("g", "c2", "t")
+		if err := c1.Commit(); !errors.Is(err, ErrRebalanced) {
+			t.Errorf("committing after the rebalance: %v, want ErrRebalanced", err)
+		}
+		rs1, _ := c1.Poll(ctx, 0)
+		rs2, _ := c2.Poll(ctx, 0)
+		if a1, a2 := c1.Assignment(), c2.Assignment(); fmt.Sprint(a1, a2) != "[t/0] [t/1]" {
+			t.Errorf("assignments %v and %v, want a partition each", a1, a2)
+		}
+		if len(rs1)+len(rs2) != 6 {
+			t.Errorf("polled %v and %v after the rebalance, want every record again", rs1, rs2)
+		}
+		if c1.Commit() != nil || c2.Commit() != nil || b.Lag("g", "t") != 0 {
+			t.Errorf("lag %d once both committed", b.Lag("g", "t"))
+		}
+		c2.Close()
+		wait, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if rs, err := c1.Poll(wait, 0); err != context.DeadlineExceeded {
+			t.Errorf("c1 polled %v, %v once c2 left, though c2 committed", rs, err)
+		}
+		if a := c1.Assignment(); len(a) != 2 {
+			t.Errorf("c1 was assigned %v once c2 left, want both partitions", a)
+		}
+	})
+}
+
+// service consumes with c until ctx is done, taking d to process each
+// record it polls, and committing after each batch. It reports the
+// records it processed and the commits that failed.
+func service(ctx context.Context, c *Consumer, d time.Duration, processed func(Record), failed func(error)) {
+	for {
+		rs, err := c.Poll(ctx, 0)
+		if err != nil {
+			return
+		}
+		for _, r := range rs {
+			time.Sleep(d)
+			processed(r)
+		}
+		if err := c.Commit(); err != nil {
+			failed(err)
+		}
+	}
+}
+
+func TestMaxPollInterval(t *testing.T) {
+	synctest.Run(func() {
+		b := New(Config{MaxPollInterval: 10 * time.Second})
+		defer b.Close()
+		b.CreateTopic("t", 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		var mu sync.Mutex
+		counts := make(map[int64]int) // processings, by offset
+		var errs []string
+		processed := func(r Record) {
+			mu.Lock()
+			counts[r.Offset]++
+			mu.Unlock()
+		}
+		failed := func(id string) func(error) {
+			return func(err error) {
+				mu.Lock()
+				errs = append(errs, id+": "+err.Error())
+				mu.Unlock()
+			}
+		}
+		// c1 gets the partition, and takes 16s over its batch, past the
+		// interval; c2 waits.
+		c1, _ := b.Subscribe("g", "c1", "t")
+		c2, _ := b.Subscribe("g", "c2", "t")
+		produce(b, "t", 4)
+		go service(ctx, c1, 4*time.Second, processed, failed("c1"))
+		go service(ctx, c2, time.Second, processed, failed("c2"))
+		time.Sleep(time.Minute)
+		cancel()
+		synctest.Wait()
+		if fmt.Sprint(errs) != "[c1: "+ErrRebalanced.Error()+"]" {
+			t.Errorf("failed commits: %q, want that of c1 once removed", errs)
+		}
+		for off := range int64(4) {
+			if counts[off] != 2 {
+				t.Errorf("record %d processed %d times, want twice: by c1, then by c2", off, counts[off])
+			}
+		}
+		if lag := b.Lag("g", "t"); lag != 0 {
+			t.Errorf("lag %d", lag)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+// Package bubble runs tests inside synctest bubbles and explains what went
+// wrong when a bubble gets stuck.
+package bubble
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/timeline"
+)
+
+// Goroutine is one goroutine of a bubble as seen in a stack dump.
+type Goroutine = gstack.Goroutine
+
+// Goroutines returns the other goroutines of the caller's bubble. After
+// Wait it tells which goroutines are still alive and where they block.
+func Goroutines() []Goroutine { return gstack.Bubble() }
+
+// Run runs f in a new synctest bubble. If every goroutine in the bubble
+// blocks, Run fails t with a report telling for each goroutine what it is
+// blocked on, rather than only panicking with "all goroutines are blocked".
+// Operations on chanx channels, syncx mutexes and Conns are reported with
+// the object, the operation and the line of code that started it. If t
+// fails, the annotations of the bubble are reported too.
+func Run(t testing.TB, f func()) {
+	t.Helper()
+	var group int64
+	defer func() {
+		// Also when f ended the test with t.FailNow.
+		if story := narrative(group); story != "" && t.Failed() {
+			t.Logf("bubble narrative:\n%s", story)
+		}
+	}()
+	_, r := gstack.Run(func() {
+		group = gstack.Self().Group
+		f()
+	})
+	if r == nil {
+		return
+	}
+	if msg, ok := r.(string); !ok || !strings.HasPrefix(msg, "deadlock") {
+		panic(r)
+	}
+	report := Report(gstack.Group(group))
+	if story := narrative(group); story != "" {
+		report += "narrative:\n" + story
+	}
+	t.Fatalf("bubble deadlocked: all goroutines are blocked\n%s", report)
+}
+
+// stamp formats virtual times in UTC, in which bubbles start at midnight.
+const stamp = "15:04:05.000"
+
+var (
+	notesMu sync.Mutex
+	notes   = make(map[int64][]string) // by bubble
+)
+
+// Annotate labels the current step of the scenario, e.g. "server sent 100
+// Continue". The label is recorded in the timeline of the bubble, if it is
+// recorded, and reported by Run if the test fails, so the failure reads as
+// the story of what happened before it.
+func Annotate(label string) {
+	site := block.Caller(1)
+	timeline.Note(label, site)
+	self := gstack.Self()
+	if self.Group == 0 {
+		return
+	}
+	line := fmt.Sprintf("%s %s: %s at %s", time.Now().UTC().Format(stamp), goid.Name(self.ID), label, site)
+	notesMu.Lock()
+	notes[self.Group] = append(notes[self.Group], line)
+	notesMu.Unlock()
+}
+
+// narrative returns, and forgets, the annotations of the bubble of group.
+func narrative(group int64) string {
+	notesMu.Lock()
+	lines := notes[group]
+	delete(notes, group)
+	notesMu.Unlock()
+	var b strings.Builder
+	for _, l := range lines {
+		fmt.Fprintf(&b, "\t%s\n", l)
+	}
+	return b.String()
+}
+
+// Go starts f in a new goroutine named name. The name identifies the
+// goroutine in reports and recorded traces.
+func Go(name string, f func()) {
+	site := block.Caller(1)
+	go func() {
+		goid.SetName(name)
+		timeline.Spawn(site)
+		defer func() {
+			timeline.Exit()
+			goid.Forget()
+		}()
+		f()
+	}()
+}
+
+// Wait is synctest.Wait.
+func Wait() { synctest.Wait() }
+
+// Report describes what each of gs is blocked on.
+func Report(gs []Goroutine) string {
+	var b strings.Builder
+	for _, g := range gs {
+		if name := goid.Name(g.ID); name != fmt.Sprint("g", g.ID) {
+			fmt.Fprintf(&b, "goroutine %d %s [%s]:\n", g.ID, name, g.State)
+		} else {
+			fmt.Fprintf(&b, "goroutine %d [%s]:\n", g.ID, g.State)
+		}
+		if op, ok := block.Lookup(g.ID); ok {
+			fmt.Fprintf(&b, "\t%v\n", op)
+			continue
+		}
+		if f, ok := g.UserFrame(); ok {
+			fmt.Fprintf(&b, "\tin %s at %s:%d\n", f.Func, f.File, f.Line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package bubble
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/chanx"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/syncx"
+)
+
+// runFailing runs Run with a recording TB on its own goroutine and returns
+// the fatal report.
+func runFailing(t *testing.T, f func()) string {
+	errs := testtb.Run(t, func(t testing.TB) { Run(t, f) })
+	if len(errs) != 1 {
+		t.Fatalf("got %d failures, want 1: %q", len(errs), errs)
+	}
+	return errs[0]
+}
+
+func TestRun(t *testing.T) {
+	Run(t, func() {
+		ch := chanx.Make[int]("ok", 0)
+		go ch.Send(1)
+		if v, _ := ch.Recv(); v != 1 {
+			t.Errorf("got %d, want 1", v)
+		}
+	})
+}
+
+func TestRunDeadlockReport(t *testing.T) {
+	var made, recv int // lines of the results channel and of its receive
+	report := runFailing(t, func() {
+		jobs := chanx.Make[int]("jobs", 0)
+		_, _, made, _ = runtime.Caller(0)
+		results := chanx.Make[string]("results", 0)
+		done := chanx.Make[bool]("done", 0)
+		mu := syncx.NewMutex(t, "state")
+
+		go func() { jobs.Send(1) }()
+		go func() { chanx.Select(chanx.OnRecv(results, nil), chanx.OnSend(done, true, nil)) }()
+		mu.Lock()
+		go func() { mu.Lock() }()
+		_, _, recv, _ = runtime.Caller(0)
+		results.Recv()
+	})
+
+	for _, want := range []string{
+		`send on channel "jobs" (created at bubble/bubble_test.go:`,
+		`select {recv on channel "results"`,
+		`send on channel "done" (created at bubble/bubble_test.go:`,
+		`lock mutex "state" held by goroutine`,
+		fmt.Sprintf(`recv on channel "results" (created at bubble/bubble_test.go:%d) at bubble/bubble_test.go:%d`, made+1, recv+1),
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRunDeadlockPlainChannels(t *testing.T) {
+	report := runFailing(t, func() {
+		ch := make(chan int)
+		go func() { ch <- 1 }()
+		<-make(chan int)
+	})
+	if !strings.Contains(report, "[chan send]") || !strings.Contains(report, "bubble.TestRunDeadlockPlainChannels") {
+		t.Errorf("report does not locate the plain channel send:\n%s", report)
+	}
+}
+
+func TestAnnotateDeadlock(t *testing.T) {
+	report := runFailing(t, func() {
+		Annotate("client sent request")
+		time.Sleep(time.Second)
+		Annotate("server sent 100 Continue")
+		chanx.Make[int]("body", 0).Recv()
+	})
+	for _, want := range []string{
+		"narrative:\n\t00:00:00.000 ",
+		": client sent request at bubble/bubble_test.go:",
+		"\t00:00:01.000 ",
+		": server sent 100 Continue at bubble/bubble_test.go:",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestAnnotatePassing(t *testing.T) {
+	Run(t, func() { Annotate("nothing to see") })
+	notesMu.Lock()
+	defer notesMu.Unlock()
+	if len(notes) != 0 {
+		t.Errorf("annotations kept after the bubble: %v", notes)
+	}
+}
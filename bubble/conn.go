@@ -0,0 +1,28 @@
+package bubble
+
+import (
+	"net"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+)
+
+// Conn returns c, named name, with its reads and writes reported like the
+// operations of chanx channels: in deadlock reports, while they block, and
+// in recorded timelines and their blocking profiles. Every read and write
+// counts as blocking, however briefly.
+func Conn(c net.Conn, name string) net.Conn { return conn{c, name} }
+
+type conn struct {
+	net.Conn
+	name string
+}
+
+func (c conn) Read(p []byte) (int, error) {
+	defer block.Enter(block.Op{Kind: "read from", Object: "conn " + c.name}, 1)()
+	return c.Conn.Read(p)
+}
+
+func (c conn) Write(p []byte) (int, error) {
+	defer block.Enter(block.Op{Kind: "write to", Object: "conn " + c.name}, 1)()
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,17 @@
+package bubble
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestConnDeadlock(t *testing.T) {
+	report := runFailing(t, func() {
+		c, _ := net.Pipe()
+		Conn(c, "client").Read(make([]byte, 1))
+	})
+	if want := "read from conn client at bubble/conn_test.go:12"; !strings.Contains(report, want) {
+		t.Errorf("report lacks %q:\n%s", want, report)
+	}
+}
@@ -0,0 +1,153 @@
+package causal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// Kind is the kind of an event of a history.
+type Kind int
+
+const (
+	Send    Kind = iota // a process sent a message
+	Deliver             // a process delivered it
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Send:
+		return "send"
+	case Deliver:
+		return "deliver"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Event is an event of a history: a process sending or delivering the
+// message Msg, which names one message of the history.
+type Event struct {
+	Proc string
+	Kind Kind
+	Msg  string
+}
+
+func (e Event) String() string { return e.Proc + " " + e.Kind.String() + " " + e.Msg }
+
+// Recorder records a history, the events of each process in the order
+// they are recorded. A process records the sending of a message before
+// it sends it, and a broadcast it delivers to itself as a delivery too.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Send records that proc sent msg.
+func (r *Recorder) Send(proc, msg string) { r.record(Event{proc, Send, msg}) }
+
+// Deliver records that proc delivered msg.
+func (r *Recorder) Deliver(proc, msg string) { r.record(Event{proc, Deliver, msg}) }
+
+func (r *Recorder) record(e Event) {
+	r.mu.Lock()
+	r.events = append(r.events, e)
+	r.mu.Unlock()
+}
+
+// Events returns the events recorded so far, in order.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Stamp returns the clocks of events, computed from the history alone:
+// each event follows those of its process before it, and a delivery also
+// the sending of its message. It fails if a message is sent twice, or
+// delivered before it was sent.
+func Stamp(events []Event) ([]Clock, error) {
+	clocks := make([]Clock, len(events))
+	procs := make(map[string]Clock)
+	sent := make(map[string]Clock) // by message
+	for i, e := range events {
+		c := procs[e.Proc]
+		switch e.Kind {
+		case Send:
+			if _, ok := sent[e.Msg]; ok {
+				return nil, fmt.Errorf("causal: %s sent twice", e.Msg)
+			}
+		case Deliver:
+			s, ok := sent[e.Msg]
+			if !ok {
+				return nil, fmt.Errorf("causal: %s delivered %s, not sent", e.Proc, e.Msg)
+			}
+			c = c.Merge(s)
+		}
+		c = c.Tick(e.Proc)
+		procs[e.Proc], clocks[i] = c, c
+		if e.Kind == Send {
+			sent[e.Msg] = c
+		}
+	}
+	return clocks, nil
+}
+
+// Verify checks that events respect causal delivery: that no process
+// delivers a message twice, nor before another whose sending happened
+// before its own. It returns the violations joined, nil if there are
+// none.
+func Verify(events []Event) error {
+	return errors.Join(violations(events)...)
+}
+
+func violations(events []Event) []error {
+	clocks, err := Stamp(events)
+	if err != nil {
+		return []error{err}
+	}
+	sent := make(map[string]Clock)
+	var order []string
+	delivered := make(map[string][]string) // by process, in order
+	for i, e := range events {
+		switch e.Kind {
+		case Send:
+			sent[e.Msg] = clocks[i]
+		case Deliver:
+			if delivered[e.Proc] == nil {
+				order = append(order, e.Proc)
+			}
+			delivered[e.Proc] = append(delivered[e.Proc], e.Msg)
+		}
+	}
+	var errs []error
+	for _, p := range order {
+		var ms []string // delivered, once each
+		for _, m := range delivered[p] {
+			if slices.Contains(ms, m) {
+				errs = append(errs, fmt.Errorf("causal: %s delivered %s twice", p, m))
+				continue
+			}
+			ms = append(ms, m)
+		}
+		for i, m := range ms {
+			for _, later := range ms[i+1:] {
+				if sent[later].Compare(sent[m]) == Before {
+					errs = append(errs, fmt.Errorf("causal: %s delivered %s before %s, which happened before it", p, m, later))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// Check fails t with every violation of causal delivery by events; see
+// Verify.
+func Check(t testing.TB, events []Event) {
+	t.Helper()
+	for _, err := range violations(events) {
+		t.Error(err)
+	}
+}
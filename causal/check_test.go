@@ -0,0 +1,170 @@
+package causal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+func TestVerify(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		events []Event
+		want   []string
+	}{
+		{
+			name: "causal",
+			events: []Event{
+				{"a", Send, "m1"}, {"b", Deliver, "m1"}, {"b", Send, "m2"},
+				{"c", Deliver, "m1"}, {"c", Deliver, "m2"},
+			},
+		},
+		{
+			name: "concurrent",
+			events: []Event{
+				{"a", Send, "m1"}, {"b", Send, "m2"},
+				{"c", Deliver, "m2"}, {"c", Deliver, "m1"},
+			},
+		},
+		{
+			name: "reordered",
+			events: []Event{
+				{"a", Send, "m1"}, {"b", Deliver, "m1"}, {"b", Send, "m2"},
+				{"c", Deliver, "m2"}, {"c", Deliver, "m1"}, {"c", Deliver, "m1"},
+			},
+			want: []string{
+				"causal: c delivered m1 twice",
+				"causal: c delivered m2 before m1, which happened before it",
+			},
+		},
+		{
+			name: "program order",
+			events: []Event{
+				{"a", Send, "m1"}, {"a", Send, "m2"},
+				{"b", Deliver, "m2"}, {"b", Deliver, "m1"},
+			},
+			want: []string{"causal: b delivered m2 before m1, which happened before it"},
+		},
+		{
+			name:   "not sent",
+			events: []Event{{"b", Deliver, "m1"}, {"a", Send, "m1"}},
+			want:   []string{"causal: b delivered m1, not sent"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			if err := Verify(c.events); err != nil {
+				got = strings.Split(err.Error(), "\n")
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(c.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestStamp(t *testing.T) {
+	var r Recorder
+	r.Send("a", "m1")
+	r.Deliver("b", "m1")
+	r.Send("b", "m2")
+	r.Send("a", "m3")
+	clocks, err := Stamp(r.Events())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"{a:1}", "{a:1 b:1}", "{a:1 b:2}", "{a:2}"}
+	if got := fmt.Sprint(clocks); got != fmt.Sprint(want) {
+		t.Errorf("clocks %s, want %s", got, want)
+	}
+	if o := clocks[2].Compare(clocks[3]); o != Concurrent {
+		t.Errorf("m2 and m3 are %v, want concurrent", o)
+	}
+}
+
+// broadcaster broadcasts to every other node the message first at start,
+// if any, and then on delivering on, reply, recording all of it to rec.
+// Unless causal, it delivers messages as they arrive.
+func broadcaster(rec *Recorder, peers []string, causal bool, first, on, reply string) simnet.Boot {
+	return func(p *simnet.Proc) {
+		name := p.Node().Name
+		mb, err := p.Mailbox("causal")
+		if err != nil {
+			return
+		}
+		d := NewDelivery[string](name)
+		send := func(body string) {
+			rec.Send(name, body)
+			rec.Deliver(name, body)
+			data, _ := json.Marshal(d.Send(body))
+			for _, peer := range peers {
+				if peer != name {
+					mb.Send(peer, data)
+				}
+			}
+		}
+		p.Go(func(ctx context.Context) {
+			if first != "" {
+				send(first)
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case in := <-mb.Inbox():
+					var m Message[string]
+					json.Unmarshal(in.Data, &m)
+					ms := []Message[string]{m}
+					if causal {
+						ms = d.Receive(m)
+					}
+					for _, m := range ms {
+						rec.Deliver(name, m.Body)
+						if m.Body == on {
+							send(reply)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// broadcast runs n1 broadcasting a, and n2 broadcasting b once it
+// delivered a, on links of 1ms but that from n1 to n3, of 100ms, so that
+// b reaches n3 first, and returns the history.
+func broadcast(causal bool) []Event {
+	var rec Recorder
+	synctest.Run(func() {
+		nw := simnet.New()
+		defer nw.Close()
+		nw.SetConditions(simnet.Conditions{Latency: faultfs.Constant(time.Millisecond)})
+		nw.SetLink("n1", "n3", simnet.Conditions{Latency: faultfs.Constant(100 * time.Millisecond)})
+		peers := []string{"n1", "n2", "n3"}
+		nw.Add("n1", broadcaster(&rec, peers, causal, "a", "", ""))
+		nw.Add("n2", broadcaster(&rec, peers, causal, "", "a", "b"))
+		nw.Add("n3", broadcaster(&rec, peers, causal, "", "", ""))
+		time.Sleep(time.Second)
+	})
+	return rec.Events()
+}
+
+func TestBroadcast(t *testing.T) {
+	events := broadcast(true)
+	Check(t, events)
+	if got := len(events); got != 8 {
+		t.Errorf("%d events, want 2 sends and 6 deliveries: %v", got, events)
+	}
+	want := "causal: n3 delivered b before a, which happened before it"
+	if err := Verify(broadcast(false)); err == nil || err.Error() != want {
+		t.Errorf("delivering as messages arrive: %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,112 @@
+// Package causal orders the events of distributed processes with vector
+// clocks: Clock timestamps events so that one happened before another
+// exactly when its clock is below the other's, Delivery delivers broadcast
+// messages in causal order, and Verify checks, as an oracle, that a
+// recorded history of sends and deliveries respects causal order.
+//
+// Clocks are values: their methods return new clocks and never change
+// theirs, so a clock can be shared between goroutines and kept in
+// messages. The zero Clock, nil, is that of no event.
+package causal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Clock is a vector clock: how many events of each process, by ID, the
+// event it stamps follows.
+type Clock map[string]uint64
+
+// Tick returns c, one more event of the process id after.
+func (c Clock) Tick(id string) Clock {
+	d := maps.Clone(c)
+	if d == nil {
+		d = make(Clock)
+	}
+	d[id]++
+	return d
+}
+
+// Merge returns the clock of the events that c or o follow: the maximum
+// of each entry.
+func (c Clock) Merge(o Clock) Clock {
+	d := maps.Clone(c)
+	if d == nil {
+		d = make(Clock, len(o))
+	}
+	for id, n := range o {
+		d[id] = max(d[id], n)
+	}
+	return d
+}
+
+// Order is how two clocks compare.
+type Order int
+
+const (
+	Equal      Order = iota
+	Before           // happened before
+	After            // happened after
+	Concurrent       // neither
+)
+
+func (o Order) String() string {
+	switch o {
+	case Equal:
+		return "equal"
+	case Before:
+		return "before"
+	case After:
+		return "after"
+	case Concurrent:
+		return "concurrent"
+	}
+	return fmt.Sprintf("Order(%d)", int(o))
+}
+
+// Compare returns whether the event of c happened before that of o,
+// after it, or neither.
+func (c Clock) Compare(o Clock) Order {
+	less, more := false, false
+	for id, n := range c {
+		if n > o[id] {
+			more = true
+		} else if n < o[id] {
+			less = true
+		}
+	}
+	for id, n := range o {
+		if _, ok := c[id]; !ok && n > 0 {
+			less = true
+		}
+	}
+	switch {
+	case less && more:
+		return Concurrent
+	case less:
+		return Before
+	case more:
+		return After
+	}
+	return Equal
+}
+
+// String formats c as "{a:1 b:2}", by ID, without the zero entries.
+func (c Clock) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for _, id := range slices.Sorted(maps.Keys(c)) {
+		if c[id] == 0 {
+			continue
+		}
+		if b.Len() > 1 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s:%d", id, c[id])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
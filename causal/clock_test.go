@@ -0,0 +1,128 @@
+package causal
+
+import (
+	"fmt"
+	"math/bits"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+func TestCompare(t *testing.T) {
+	a1 := Clock(nil).Tick("a")
+	b1 := Clock(nil).Tick("b")
+	ab := a1.Merge(b1)
+	for _, c := range []struct {
+		c, o Clock
+		want Order
+	}{
+		{nil, nil, Equal},
+		{nil, a1, Before},
+		{a1, nil, After},
+		{a1, b1, Concurrent},
+		{a1, ab, Before},
+		{ab.Tick("a"), ab.Tick("b"), Concurrent},
+		{Clock{"a": 1, "b": 0}, a1, Equal},
+	} {
+		if got := c.c.Compare(c.o); got != c.want {
+			t.Errorf("%v compared to %v: %v, want %v", c.c, c.o, got, c.want)
+		}
+	}
+	if a1.Tick("a"); a1["a"] != 1 {
+		t.Errorf("Tick changed its clock: %v", a1)
+	}
+	if s := ab.Tick("b").String(); s != "{a:1 b:2}" {
+		t.Errorf("String: %s", s)
+	}
+}
+
+// broadcasts are five messages of the processes a, b and c, stamped by
+// their deliveries, and the messages each follows, as bits: b sends m1
+// once it delivered m0, c sends m3 once it delivered m0 and m1.
+func broadcasts() ([]Message[string], []uint8) {
+	a, b, c := NewDelivery[string]("a"), NewDelivery[string]("b"), NewDelivery[string]("c")
+	m0 := a.Send("m0")
+	b.Receive(m0)
+	m1 := b.Send("m1")
+	m2 := a.Send("m2")
+	c.Receive(m1)
+	c.Receive(m0)
+	m3 := c.Send("m3")
+	m4 := b.Send("m4")
+	return []Message[string]{m0, m1, m2, m3, m4}, []uint8{0, 1, 1, 3, 3}
+}
+
+// arrival is what arrived at a process, and what it delivered, as bits.
+type arrival struct{ Arrived, Delivered uint8 }
+
+// arrivals models the delivery of the broadcasts in causal order, from
+// the messages they follow rather than clocks: each arrives, or arrives
+// again, in any order, and a process delivers those arrived that follow
+// only messages delivered.
+func arrivals(deps []uint8) *model.Model[arrival] {
+	m := &model.Model[arrival]{Name: "causal delivery"}
+	for i := range deps {
+		bit := uint8(1) << i
+		m.Actions = append(m.Actions,
+			model.Action[arrival]{
+				Name:  fmt.Sprint("arrive m", i),
+				Guard: func(s arrival) bool { return s.Arrived&bit == 0 },
+				Step: func(s arrival) arrival {
+					s.Arrived |= bit
+					for again := true; again; {
+						again = false
+						for j, d := range deps {
+							if b := uint8(1) << j; s.Arrived&b != 0 && s.Delivered&b == 0 && s.Delivered&d == d {
+								s.Delivered |= b
+								again = true
+							}
+						}
+					}
+					return s
+				},
+			},
+			model.Action[arrival]{
+				Name:  fmt.Sprint("duplicate m", i),
+				Guard: func(s arrival) bool { return s.Arrived&bit != 0 },
+				Step:  func(s arrival) arrival { return s },
+			})
+	}
+	m.Invariants = []model.Invariant[arrival]{{Name: "delivered arrived", Check: func(s arrival) bool { return s.Delivered&^s.Arrived == 0 }}}
+	return m
+}
+
+// receiver is a process receiving the broadcasts with a Delivery.
+type receiver struct {
+	d                  *Delivery[string]
+	msgs               []Message[string]
+	deps               []uint8
+	arrived, delivered uint8
+}
+
+func (r *receiver) Do(action string) error {
+	i := int(action[len(action)-1] - '0')
+	r.arrived |= 1 << i
+	for _, m := range r.d.Receive(r.msgs[i]) {
+		j := int(m.Body[1] - '0')
+		if d := r.deps[j]; r.delivered&d != d {
+			return fmt.Errorf("delivered %s before the messages it follows", m.Body)
+		}
+		r.delivered |= 1 << j
+	}
+	if held := bits.OnesCount8(r.arrived &^ r.delivered); r.d.Pending() != held {
+		return fmt.Errorf("%d messages pending, want %d", r.d.Pending(), held)
+	}
+	return nil
+}
+
+func (r *receiver) Observe() arrival { return arrival{r.arrived, r.delivered} }
+
+func TestDeliveryModel(t *testing.T) {
+	msgs, deps := broadcasts()
+	rep := model.Explore(t, arrivals(deps), model.ExploreConfig{}, func() model.System[arrival] {
+		return model.Oracle(arrivals(deps), &receiver{d: NewDelivery[string]("d"), msgs: msgs, deps: deps})
+	})
+	if rep.States != 1<<len(msgs) {
+		t.Errorf("explored %v, want every set of messages arrived", rep)
+	}
+}
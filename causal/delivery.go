@@ -0,0 +1,91 @@
+package causal
+
+import "sync"
+
+// Message is a message broadcast by a Delivery.
+type Message[M any] struct {
+	From  string // the ID of the sender
+	Clock Clock  // the broadcasts the sender delivered, its own included
+	Body  M
+}
+
+// Delivery delivers the messages a process receives from the broadcasts
+// of others in causal order, whatever the order they arrive in: a message
+// is delivered once every message delivered by its sender before sending
+// it is. Its clock counts broadcasts only, those of the process and those
+// it delivered. Messages are assumed not to be lost; a message is held
+// back as long as one it follows has not arrived. It is safe for
+// concurrent use.
+type Delivery[M any] struct {
+	id string
+
+	mu      sync.Mutex
+	clock   Clock
+	pending []Message[M] // arrived, not deliverable yet
+}
+
+// NewDelivery returns the delivery of the process id.
+func NewDelivery[M any](id string) *Delivery[M] { return &Delivery[M]{id: id} }
+
+// Send stamps body as the next broadcast of the process, delivered by it
+// at once, for the caller to send to every other process.
+func (d *Delivery[M]) Send(body M) Message[M] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clock = d.clock.Tick(d.id)
+	return Message[M]{From: d.id, Clock: d.clock, Body: body}
+}
+
+// Receive takes m as it arrives and returns the messages it makes
+// deliverable, in causal order, m among them unless it is held back.
+// Messages delivered already, and those of the process, are dropped.
+func (d *Delivery[M]) Receive(m Message[M]) []Message[M] {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if m.From == d.id || m.Clock[m.From] <= d.clock[m.From] {
+		return nil
+	}
+	for _, p := range d.pending {
+		if p.From == m.From && p.Clock[p.From] == m.Clock[m.From] {
+			return nil
+		}
+	}
+	d.pending = append(d.pending, m)
+	var out []Message[M]
+	for i := 0; i < len(d.pending); {
+		if p := d.pending[i]; !d.deliverable(p) {
+			i++
+			continue
+		}
+		out = append(out, d.pending[i])
+		d.clock = d.clock.Merge(d.pending[i].Clock)
+		d.pending = append(d.pending[:i], d.pending[i+1:]...)
+		i = 0
+	}
+	return out
+}
+
+// deliverable reports whether m is the next broadcast of its sender and
+// follows only broadcasts delivered, with d.mu held.
+func (d *Delivery[M]) deliverable(m Message[M]) bool {
+	for id, n := range m.Clock {
+		if id == m.From && n != d.clock[id]+1 || id != m.From && n > d.clock[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// Clock returns the clock of the broadcasts the process delivered.
+func (d *Delivery[M]) Clock() Clock {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.clock
+}
+
+// Pending returns how many messages arrived that are held back.
+func (d *Delivery[M]) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.pending)
+}
@@ -0,0 +1,67 @@
+// Package chanx contains channel primitives and bubble-based assertions for
+// channel-based code.
+//
+// Durations passed to the assertions are measured on the clock of the
+// calling goroutine, which is virtual time inside a synctest bubble, so
+// waiting for a long window costs nothing.
+package chanx
+
+import (
+	"testing"
+	"time"
+)
+
+// MustReceiveWithin receives a value from ch, failing t if none arrives
+// within d or if ch is closed.
+func MustReceiveWithin[T any](t testing.TB, ch <-chan T, d time.Duration) T {
+	t.Helper()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatalf("channel closed while waiting %v for a value", d)
+		}
+		return v
+	case <-timer.C:
+		t.Fatalf("no value received within %v", d)
+	}
+	panic("unreachable")
+}
+
+// MustNotReceive fails t if a value arrives on ch, or ch is closed, within
+// the window d.
+func MustNotReceive[T any](t testing.TB, ch <-chan T, d time.Duration) {
+	t.Helper()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatalf("channel closed within %v, want it to stay silent", d)
+		}
+		t.Fatalf("received %v within %v, want no value", v, d)
+	case <-timer.C:
+	}
+}
+
+// MustBeClosedBy drains ch and fails t unless it is closed within d. It
+// returns the values received before the close.
+func MustBeClosedBy[T any](t testing.TB, ch <-chan T, d time.Duration) []T {
+	t.Helper()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	var got []T
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		case <-timer.C:
+			t.Fatalf("channel not closed within %v (received %d values)", d, len(got))
+			return got
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package chanx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestMustReceiveWithin(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan int)
+		go func() {
+			time.Sleep(5 * time.Second)
+			ch <- 1
+		}()
+		if v := MustReceiveWithin(t, ch, 10*time.Second); v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+
+		errs := testtb.Run(t, func(t testing.TB) { MustReceiveWithin(t, ch, time.Hour) })
+		if len(errs) != 1 || !strings.Contains(errs[0], "no value received within 1h0m0s") {
+			t.Fatalf("unexpected failures %q", errs)
+		}
+	})
+}
+
+func TestMustNotReceive(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan string, 1)
+		MustNotReceive(t, ch, time.Minute)
+
+		ch <- "late"
+		errs := testtb.Run(t, func(t testing.TB) { MustNotReceive(t, ch, time.Minute) })
+		if len(errs) != 1 || !strings.Contains(errs[0], "received late") {
+			t.Fatalf("unexpected failures %q", errs)
+		}
+	})
+}
+
+func TestMustBeClosedBy(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan int)
+		go func() {
+			for i := range 3 {
+				time.Sleep(time.Second)
+				ch <- i
+			}
+			close(ch)
+		}()
+		if got := MustBeClosedBy(t, ch, 4*time.Second); !slices.Equal(got, []int{0, 1, 2}) {
+			t.Fatalf("got %v, want [0 1 2]", got)
+		}
+
+		open := make(chan int)
+		errs := testtb.Run(t, func(t testing.TB) { MustBeClosedBy(t, open, time.Second) })
+		if len(errs) != 1 || !strings.Contains(errs[0], "not closed within 1s") {
+			t.Fatalf("unexpected failures %q", errs)
+		}
+	})
+}
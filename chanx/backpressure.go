@@ -0,0 +1,78 @@
+package chanx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// ExpectFull fails t unless, once the bubble has settled, the buffered
+// channel c is full and some goroutine is blocked sending on it, i.e. the
+// producer is being held back by the buffer rather than having run out of
+// work. Producers must send with c.Send or a Select, so that their blocked
+// sends can be told apart from other blocked goroutines. It must be called
+// inside a bubble.
+func ExpectFull[T any](t testing.TB, c *Chan[T]) {
+	t.Helper()
+	synctest.Wait()
+	if c.Cap() == 0 {
+		t.Fatalf("ExpectFull on unbuffered %v", c)
+	}
+	if c.Len() != c.Cap() {
+		t.Errorf("%v holds %d of %d values after the bubble settled, want it full", c, c.Len(), c.Cap())
+		return
+	}
+	if len(sendersOn(c)) == 0 {
+		t.Errorf("%v is full but no goroutine is blocked sending on it", c)
+	}
+}
+
+// ExpectBackpressure checks a producer feeding the buffered channel c:
+// after the bubble settles the buffer must be full with the producer
+// blocked, and after each of steps receives the producer must resume and
+// refill exactly the one freed slot before blocking again. It returns the
+// values received.
+func ExpectBackpressure[T any](t testing.TB, c *Chan[T], steps int) []T {
+	t.Helper()
+	ExpectFull(t, c)
+	var got []T
+	for i := range steps {
+		v, ok := c.Recv()
+		if !ok {
+			t.Errorf("%v closed after %d receives", c, i)
+			return got
+		}
+		got = append(got, v)
+		// Only one slot is free, so refilling it cannot overshoot.
+		synctest.Wait()
+		if c.Len() != c.Cap() {
+			t.Errorf("receive %d freed a slot of %v but the producer did not resume (buffer %d of %d)", i+1, c, c.Len(), c.Cap())
+			return got
+		}
+		if len(sendersOn(c)) == 0 {
+			t.Errorf("after receive %d the producer refilled %v but did not block on it again", i+1, c)
+			return got
+		}
+	}
+	return got
+}
+
+// sendersOn returns the goroutines of the bubble blocked sending on c,
+// directly or in a Select.
+func sendersOn[T any](c *Chan[T]) []string {
+	send := "send on " + c.String()
+	var gs []string
+	for _, g := range gstack.Bubble() {
+		op, ok := block.Lookup(g.ID)
+		inSelect := op.Kind == "select" && strings.Contains(op.Object, send) && !strings.Contains(op.Object, send+" (disabled)")
+		if !ok || (op.Kind+" "+op.Object != send && !inSelect) {
+			continue
+		}
+		gs = append(gs, fmt.Sprintf("goroutine %d %v", g.ID, op))
+	}
+	return gs
+}
@@ -0,0 +1,99 @@
+package chanx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestExpectBackpressure(t *testing.T) {
+	synctest.Run(func() {
+		c := Make[int]("jobs", 3)
+		go func() {
+			for i := range 10 {
+				c.Send(i)
+			}
+			c.Close()
+		}()
+		if got := ExpectBackpressure(t, c, 4); !slices.Equal(got, []int{0, 1, 2, 3}) {
+			t.Errorf("received %v", got)
+		}
+		for range c.C() {
+		}
+	})
+}
+
+func TestExpectBackpressureSelect(t *testing.T) {
+	synctest.Run(func() {
+		c := Make[int]("jobs", 1)
+		stop := Make[struct{}]("stop", 0)
+		go func() {
+			for i := 0; ; i++ {
+				if Select(OnSend(c, i, nil), OnRecv(stop, nil)) == 1 {
+					return
+				}
+			}
+		}()
+		ExpectBackpressure(t, c, 2)
+		stop.Close()
+	})
+}
+
+func TestExpectFullNotFull(t *testing.T) {
+	synctest.Run(func() {
+		c := Make[int]("jobs", 3)
+		c.Send(1)
+		errs := testtb.Run(t, func(t testing.TB) { ExpectFull(t, c) })
+		if len(errs) != 1 || !strings.Contains(errs[0], "holds 1 of 3 values") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
+
+func TestExpectFullDroppingProducer(t *testing.T) {
+	synctest.Run(func() {
+		c := Make[int]("jobs", 2)
+		more, stop := make(chan struct{}), make(chan struct{})
+		go func() {
+			// drops values on a full buffer instead of waiting, then parks
+			// until asked for more
+			for i := 0; ; i++ {
+				select {
+				case c.C() <- i:
+					continue
+				default:
+				}
+				select {
+				case <-more:
+				case <-stop:
+					return
+				}
+			}
+		}()
+		errs := testtb.Run(t, func(t testing.TB) { ExpectFull(t, c) })
+		close(stop)
+		if len(errs) != 1 || !strings.Contains(errs[0], "no goroutine is blocked sending") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
+
+func TestExpectFullIgnoresOtherBlockedGoroutines(t *testing.T) {
+	synctest.Run(func() {
+		c := Make[int]("jobs", 1)
+		in, out := Make[int]("in", 0), Make[int]("out", 0)
+		c.Send(1)
+		// blocked, but in a receive-only select and on another channel
+		go Select(OnRecv(in, nil))
+		go out.Send(2)
+		errs := testtb.Run(t, func(t testing.TB) { ExpectFull(t, c) })
+		in.Send(0)
+		out.Recv()
+		if len(errs) != 1 || !strings.Contains(errs[0], "no goroutine is blocked sending") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
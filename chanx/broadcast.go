@@ -0,0 +1,220 @@
+package chanx
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+)
+
+// SlowPolicy decides what Publish does with a subscriber whose channel is
+// full.
+type SlowPolicy int
+
+const (
+	// Block makes Publish wait until the subscriber has room, so the
+	// slowest subscriber paces the publisher.
+	Block SlowPolicy = iota
+	// Drop skips the message for that subscriber and counts it in Dropped.
+	Drop
+	// Buffer queues the message for that subscriber without bound, so
+	// Publish never waits.
+	Buffer
+)
+
+func (p SlowPolicy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case Drop:
+		return "drop"
+	case Buffer:
+		return "buffer"
+	}
+	return fmt.Sprintf("SlowPolicy(%d)", int(p))
+}
+
+// Broadcaster delivers every published message to every subscriber, in
+// publish order. It is safe for concurrent use.
+type Broadcaster[T any] struct {
+	name string
+	site string
+
+	// token is held while publishing or changing subs; a channel rather
+	// than a sync.Mutex so that waiting for it is durably blocking.
+	token  chan struct{}
+	subs   []*Subscription[T]
+	closed bool
+	nsubs  int
+}
+
+// NewBroadcaster creates a Broadcaster. The name and the caller's file:line
+// identify it in reports.
+func NewBroadcaster[T any](name string) *Broadcaster[T] {
+	return &Broadcaster[T]{name: name, site: block.Caller(1), token: make(chan struct{}, 1)}
+}
+
+func (b *Broadcaster[T]) String() string {
+	return fmt.Sprintf("broadcaster %q (created at %s)", b.name, b.site)
+}
+
+// Subscription is one subscriber of a Broadcaster.
+type Subscription[T any] struct {
+	b       *Broadcaster[T]
+	id      int
+	policy  SlowPolicy
+	c       chan T
+	in      chan T        // Buffer only: feeds the queueing goroutine
+	done    chan struct{} // closed by Cancel
+	cancel  sync.Once
+	dropped atomic.Int64
+}
+
+func (s *Subscription[T]) String() string {
+	return fmt.Sprintf("subscriber %d (%v) of %v", s.id, s.policy, s.b)
+}
+
+// Subscribe adds a subscriber whose channel buffers capacity messages and
+// which is treated according to policy once that buffer is full. It
+// receives the messages published from now on. Subscribing to a closed
+// Broadcaster returns a subscription whose channel is already closed.
+func (b *Broadcaster[T]) Subscribe(capacity int, policy SlowPolicy) *Subscription[T] {
+	b.token <- struct{}{}
+	defer func() { <-b.token }()
+	b.nsubs++
+	s := &Subscription[T]{b: b, id: b.nsubs, policy: policy, c: make(chan T, capacity), done: make(chan struct{})}
+	if b.closed {
+		close(s.c)
+		return s
+	}
+	if policy == Buffer {
+		s.in = make(chan T)
+		go s.queue()
+	}
+	b.subs = append(b.subs, s)
+	return s
+}
+
+// C returns the channel the subscriber receives on. It is closed after
+// Close or Cancel.
+func (s *Subscription[T]) C() <-chan T { return s.c }
+
+// Dropped returns how many messages the subscriber missed under Drop.
+func (s *Subscription[T]) Dropped() int { return int(s.dropped.Load()) }
+
+// Cancel removes the subscriber and closes its channel; messages still
+// queued for it under Buffer are discarded. A Publish blocked on it under Block moves
+// on to the next subscriber.
+func (s *Subscription[T]) Cancel() { s.cancel.Do(s.remove) }
+
+func (s *Subscription[T]) remove() {
+	close(s.done)
+	b := s.b
+	b.token <- struct{}{}
+	defer func() { <-b.token }()
+	for i, x := range b.subs {
+		if x == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			s.finish()
+			return
+		}
+	}
+}
+
+// Publish delivers v to every current subscriber. Under Block it waits
+// for each slow subscriber in turn.
+func (b *Broadcaster[T]) Publish(v T) {
+	b.token <- struct{}{}
+	defer func() { <-b.token }()
+	if b.closed {
+		panic(fmt.Sprintf("chanx: Publish on closed %v", b))
+	}
+	for _, s := range b.subs {
+		s.deliver(v)
+	}
+}
+
+// Close closes the channels of all subscribers once the messages queued
+// for them under Buffer have been received.
+func (b *Broadcaster[T]) Close() {
+	b.token <- struct{}{}
+	defer func() { <-b.token }()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, s := range b.subs {
+		s.finish()
+	}
+	b.subs = nil
+}
+
+func (s *Subscription[T]) deliver(v T) {
+	switch s.policy {
+	case Drop:
+		select {
+		case s.c <- v:
+		default:
+			s.dropped.Add(1)
+		}
+	case Buffer:
+		s.in <- v // the queueing goroutine always accepts
+	default:
+		select {
+		case s.c <- v:
+		case <-s.done:
+		default:
+			leave := block.Enter(block.Op{Kind: "publish to", Object: s.String()}, 2)
+			select {
+			case s.c <- v:
+			case <-s.done:
+			}
+			leave()
+		}
+	}
+}
+
+// finish ends delivery to s; it is called with the token held.
+func (s *Subscription[T]) finish() {
+	if s.in != nil {
+		close(s.in) // the queueing goroutine closes c when done
+		return
+	}
+	close(s.c)
+}
+
+// queue moves messages from in to c under Buffer, holding any backlog. It
+// closes c once in is closed and the backlog received, or right away on
+// Cancel.
+func (s *Subscription[T]) queue() {
+	var backlog []T
+	in := s.in
+	for in != nil || len(backlog) > 0 {
+		var out chan T
+		var next T
+		if len(backlog) > 0 {
+			out, next = s.c, backlog[0]
+		}
+		select {
+		case v, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			backlog = append(backlog, v)
+		case out <- next:
+			var zero T
+			backlog[0] = zero
+			backlog = backlog[1:]
+		case <-s.done:
+			close(s.c)
+			if in != nil {
+				for range in {
+				}
+			}
+			return
+		}
+	}
+	close(s.c)
+}
@@ -0,0 +1,146 @@
+package chanx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+)
+
+// collect receives from s until it is closed, sleeping pace after each
+// message, and sends what it got on the returned channel.
+func collect(s *Subscription[int], pace time.Duration) <-chan []int {
+	res := make(chan []int, 1)
+	go func() {
+		var got []int
+		for v := range s.C() {
+			got = append(got, v)
+			time.Sleep(pace)
+		}
+		res <- got
+	}()
+	return res
+}
+
+func TestBroadcastBlock(t *testing.T) {
+	synctest.Run(func() {
+		b := NewBroadcaster[int]("events")
+		fast := collect(b.Subscribe(0, Block), 0)
+		slow := collect(b.Subscribe(1, Block), time.Second)
+		start := time.Now()
+		for i := range 5 {
+			b.Publish(i)
+		}
+		// The slow subscriber takes 0 at once and 1 into its buffer; each
+		// further message waits for it to finish one.
+		if d := time.Since(start); d != 3*time.Second {
+			t.Errorf("publishing took %v, want 3s", d)
+		}
+		b.Close()
+		want := []int{0, 1, 2, 3, 4}
+		if got := <-fast; !slices.Equal(got, want) {
+			t.Errorf("fast subscriber got %v", got)
+		}
+		if got := <-slow; !slices.Equal(got, want) {
+			t.Errorf("slow subscriber got %v", got)
+		}
+	})
+}
+
+func TestBroadcastDrop(t *testing.T) {
+	synctest.Run(func() {
+		b := NewBroadcaster[int]("events")
+		fast := collect(b.Subscribe(0, Block), 0)
+		slow := b.Subscribe(1, Drop)
+		start := time.Now()
+		for i := range 5 {
+			b.Publish(i)
+		}
+		if d := time.Since(start); d != 0 {
+			t.Errorf("publishing took %v, want no wait", d)
+		}
+		if n := slow.Dropped(); n != 4 {
+			t.Errorf("slow subscriber dropped %d messages, want 4", n)
+		}
+		b.Close()
+		if got := <-collect(slow, 0); !slices.Equal(got, []int{0}) {
+			t.Errorf("slow subscriber got %v, want [0]", got)
+		}
+		if got := <-fast; len(got) != 5 {
+			t.Errorf("fast subscriber got %v", got)
+		}
+	})
+}
+
+func TestBroadcastBuffer(t *testing.T) {
+	synctest.Run(func() {
+		b := NewBroadcaster[int]("events")
+		s := b.Subscribe(0, Buffer)
+		start := time.Now()
+		for i := range 5 {
+			b.Publish(i)
+		}
+		if d := time.Since(start); d != 0 {
+			t.Errorf("publishing took %v, want no wait", d)
+		}
+		b.Close()
+		// The backlog outlives Close and arrives in order.
+		if got := <-collect(s, time.Second); !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+			t.Errorf("subscriber got %v", got)
+		}
+	})
+}
+
+func TestBroadcastCancelUnblocksPublisher(t *testing.T) {
+	synctest.Run(func() {
+		b := NewBroadcaster[int]("events")
+		stuck := b.Subscribe(0, Block)
+		other := collect(b.Subscribe(0, Block), 0)
+		published := make(chan int64)
+		go func() {
+			id := goid.ID()
+			published <- id
+			b.Publish(1)
+			published <- id
+		}()
+		id := <-published
+		synctest.Wait()
+		op, ok := block.Lookup(id)
+		if !ok || !strings.Contains(op.String(), "publish to subscriber 1 (block)") {
+			t.Fatalf("publisher blocked in %v, %v", op, ok)
+		}
+		stuck.Cancel()
+		<-published
+		if _, ok := <-stuck.C(); ok {
+			t.Errorf("cancelled subscription still open")
+		}
+		b.Close()
+		if got := <-other; !slices.Equal(got, []int{1}) {
+			t.Errorf("other subscriber got %v", got)
+		}
+	})
+}
+
+func TestBroadcastCancelBuffered(t *testing.T) {
+	synctest.Run(func() {
+		b := NewBroadcaster[int]("events")
+		s := b.Subscribe(0, Buffer)
+		b.Publish(1)
+		b.Publish(2)
+		s.Cancel()
+		s.Cancel()
+		synctest.Wait()
+		if got := <-collect(s, 0); len(got) != 0 {
+			t.Errorf("cancelled subscriber got %v", got)
+		}
+		b.Publish(3)
+		b.Close()
+		if _, ok := <-b.Subscribe(1, Drop).C(); ok {
+			t.Errorf("subscription to closed broadcaster is open")
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package chanx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/inject"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/vclock"
+)
+
+// Chan is an instrumented channel. It behaves like a chan T, but a
+// goroutine blocked on it is reported together with the channel's name,
+// its creation site and the operation, e.g. by bubble.Run when the bubble
+// deadlocks.
+type Chan[T any] struct {
+	c    chan T
+	name string
+	site string
+	id   uint64 // tells apart channels with the same name and site
+}
+
+var chanIDs atomic.Uint64
+
+// Make creates a Chan with the given buffer capacity. The name and the
+// caller's file:line identify it in reports.
+func Make[T any](name string, capacity int) *Chan[T] {
+	return &Chan[T]{c: make(chan T, capacity), name: name, site: block.Caller(1), id: chanIDs.Add(1)}
+}
+
+func (c *Chan[T]) String() string {
+	return fmt.Sprintf("channel %q (created at %s)", c.name, c.site)
+}
+
+// Name returns the name given to Make.
+func (c *Chan[T]) Name() string { return c.name }
+
+// C returns the underlying channel, for use with range or a plain select.
+// Operations on it are not attributed in reports.
+func (c *Chan[T]) C() chan T { return c.c }
+
+// Len and Cap are len and cap of the underlying channel.
+func (c *Chan[T]) Len() int { return len(c.c) }
+func (c *Chan[T]) Cap() int { return cap(c.c) }
+
+// Send sends v on c.
+func (c *Chan[T]) Send(v T) {
+	inject.Point()
+	vclock.Send(c, c.String(), 1)
+	select {
+	case c.c <- v:
+	default:
+		leave := block.Enter(block.Op{Kind: "send on", Object: c.String()}, 1)
+		c.c <- v
+		leave()
+	}
+	recordOp(c.id, c.name, c.site, Send)
+}
+
+// Recv receives from c. ok is false if c is closed and drained.
+func (c *Chan[T]) Recv() (v T, ok bool) {
+	inject.Point()
+	select {
+	case v, ok = <-c.c:
+	default:
+		leave := block.Enter(block.Op{Kind: "recv on", Object: c.String()}, 1)
+		v, ok = <-c.c
+		leave()
+	}
+	if ok {
+		recordOp(c.id, c.name, c.site, Recv)
+		vclock.Recv(c, c.String(), 1)
+	}
+	return v, ok
+}
+
+// Close closes c.
+func (c *Chan[T]) Close() { close(c.c) }
+
+// Case is one case of Select, created by OnRecv or OnSend.
+type Case struct {
+	sc       reflect.SelectCase
+	desc     string
+	done     func(v reflect.Value, ok bool)
+	disabled bool
+}
+
+// OnRecv is a Select case receiving from c; f, if not nil, is called with
+// the outcome when the case is chosen.
+func OnRecv[T any](c *Chan[T], f func(v T, ok bool)) Case {
+	return Case{
+		sc:   reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.c)},
+		desc: "recv on " + c.String(),
+		done: func(v reflect.Value, ok bool) {
+			if ok {
+				recordOp(c.id, c.name, c.site, Recv)
+				vclock.Recv(c, c.String(), 2)
+			}
+			if f != nil {
+				var x T
+				if ok {
+					x, _ = v.Interface().(T)
+				}
+				f(x, ok)
+			}
+		},
+	}
+}
+
+// OnSend is a Select case sending v on c; f, if not nil, is called when the
+// case is chosen.
+func OnSend[T any](c *Chan[T], v T, f func()) Case {
+	return Case{
+		sc:   reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(c.c), Send: reflect.ValueOf(&v).Elem()},
+		desc: "send on " + c.String(),
+		done: func(reflect.Value, bool) {
+			recordOp(c.id, c.name, c.site, Send)
+			// Only known to be chosen once done, so a receiver may have
+			// gone ahead and missed it.
+			vclock.Send(c, c.String(), 2)
+			if f != nil {
+				f()
+			}
+		},
+	}
+}
+
+// Select blocks until one of the cases can proceed, runs its callback and
+// returns its index. A goroutine blocked in Select is reported with all of
+// its cases.
+func Select(cases ...Case) int {
+	scs := make([]reflect.SelectCase, len(cases), len(cases)+1)
+	descs := make([]string, len(cases))
+	kind := deadSelect
+	for i, c := range cases {
+		scs[i] = c.sc
+		descs[i] = c.desc
+		if c.disabled {
+			descs[i] += " (disabled)"
+		} else {
+			kind = "select"
+		}
+	}
+
+	i, v, ok := reflect.Select(append(scs, reflect.SelectCase{Dir: reflect.SelectDefault}))
+	if i == len(cases) {
+		leave := block.Enter(block.Op{Kind: kind, Object: "{" + strings.Join(descs, "; ") + "}"}, 1)
+		i, v, ok = reflect.Select(scs)
+		leave()
+	}
+	cases[i].done(v, ok)
+	return i
+}
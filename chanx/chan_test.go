@@ -0,0 +1,59 @@
+package chanx
+
+import (
+	"errors"
+	"testing"
+	"testing/synctest"
+)
+
+func TestChanSendRecv(t *testing.T) {
+	synctest.Run(func() {
+		c := Make[int]("c", 1)
+		c.Send(1)
+		go c.Send(2)
+		for _, want := range []int{1, 2} {
+			if v, ok := c.Recv(); !ok || v != want {
+				t.Fatalf("Recv() = %d, %t; want %d, true", v, ok, want)
+			}
+		}
+		c.Close()
+		if _, ok := c.Recv(); ok {
+			t.Fatal("Recv on closed channel reported ok")
+		}
+	})
+}
+
+func TestSelect(t *testing.T) {
+	synctest.Run(func() {
+		a := Make[int]("a", 0)
+		b := Make[string]("b", 1)
+		go a.Send(7)
+
+		var got int
+		i := Select(OnRecv(a, func(v int, ok bool) { got = v }))
+		if i != 0 || got != 7 {
+			t.Fatalf("Select = %d with %d, want case 0 with 7", i, got)
+		}
+
+		sent := false
+		if i := Select(OnRecv(a, nil), OnSend(b, "x", func() { sent = true })); i != 1 || !sent {
+			t.Fatalf("Select = %d, want the send case", i)
+		}
+		if v, _ := b.Recv(); v != "x" {
+			t.Fatalf("received %q, want x", v)
+		}
+	})
+}
+
+func TestSelectNilInterface(t *testing.T) {
+	synctest.Run(func() {
+		c := Make[error]("errs", 1)
+		c.Send(nil)
+		var got error = errors.New("not received")
+		var gotOK bool
+		Select(OnRecv(c, func(err error, ok bool) { got, gotOK = err, ok }))
+		if got != nil || !gotOK {
+			t.Fatalf("received %v, %t; want nil, true", got, gotOK)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package chanx
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+)
+
+// Checked is a channel that turns send on a closed channel and double
+// close into test failures naming the goroutine that closed it and where.
+// A plain channel panics in both cases, which takes the whole bubble down
+// without saying who closed it.
+//
+// The offending goroutine is ended with runtime.Goexit after the failure is
+// reported. A Checked created with a nil test panics with the same report.
+type Checked[T any] struct {
+	t    testing.TB
+	name string
+	site string
+	c    chan T
+
+	// token is held while sending on or closing c, so that no send can
+	// complete once Close has returned. It is a channel rather than a
+	// sync.Mutex so that goroutines waiting for it are durably blocked.
+	token   chan struct{}
+	closing chan struct{} // closed when Close starts
+
+	mu     sync.Mutex // guards closer and stack
+	closer int64      // goroutine that closed c
+	stack  string     // where it did
+}
+
+// NewChecked creates a Checked with the given buffer capacity that reports
+// on t.
+func NewChecked[T any](t testing.TB, name string, capacity int) *Checked[T] {
+	return &Checked[T]{
+		t: t, name: name, site: block.Caller(1), c: make(chan T, capacity),
+		token: make(chan struct{}, 1), closing: make(chan struct{}),
+	}
+}
+
+func (c *Checked[T]) String() string {
+	return fmt.Sprintf("channel %q (created at %s)", c.name, c.site)
+}
+
+// C returns the underlying channel for receiving, e.g. with range.
+func (c *Checked[T]) C() <-chan T { return c.c }
+
+// Recv receives from c. ok is false if c is closed and drained.
+func (c *Checked[T]) Recv() (v T, ok bool) {
+	v, ok = <-c.c
+	return v, ok
+}
+
+// Send sends v on c. Sending on a closed Checked, or being blocked sending
+// when it is closed, is reported. A send racing with Close either completes
+// before Close returns or is reported.
+func (c *Checked[T]) Send(v T) {
+	leave := func() {}
+	select {
+	case c.token <- struct{}{}:
+	default:
+		leave = block.Enter(block.Op{Kind: "send on", Object: c.String()}, 1)
+		c.token <- struct{}{}
+	}
+	defer func() { <-c.token }()
+	defer func() { leave() }()
+
+	select {
+	case <-c.closing:
+		c.sendOnClosed()
+	default:
+	}
+	select {
+	case c.c <- v:
+		return
+	default:
+	}
+	leave()
+	leave = block.Enter(block.Op{Kind: "send on", Object: c.String()}, 1)
+	select {
+	case c.c <- v:
+	case <-c.closing:
+		c.sendOnClosed()
+	}
+}
+
+// Close closes c. Closing it twice is reported with both stacks.
+func (c *Checked[T]) Close() {
+	id, stack := goid.ID(), goid.Stack()
+	c.mu.Lock()
+	if c.stack != "" {
+		closer, first := c.closer, c.stack
+		c.mu.Unlock()
+		c.misuse("close of closed %v\n\nfirst closed by goroutine %s:\n%s\nclosed again by goroutine %s:\n%s",
+			c, goid.Name(closer), first, goid.Name(id), stack)
+		return
+	}
+	c.closer, c.stack = id, stack
+	c.mu.Unlock()
+
+	// A sender blocked on c gives up the token once closing is closed.
+	close(c.closing)
+	c.token <- struct{}{}
+	close(c.c)
+	<-c.token
+}
+
+func (c *Checked[T]) sendOnClosed() {
+	c.mu.Lock()
+	closer, stack := c.closer, c.stack
+	c.mu.Unlock()
+	c.misuse("send on closed %v\n\nclosed by goroutine %s:\n%s\nsend by goroutine %s:\n%s",
+		c, goid.Name(closer), stack, goid.Name(goid.ID()), goid.Stack())
+}
+
+// misuse reports and ends the calling goroutine.
+func (c *Checked[T]) misuse(format string, args ...any) {
+	msg := "chanx: " + fmt.Sprintf(format, args...)
+	if c.t == nil {
+		panic(msg)
+	}
+	c.t.Errorf("%s", msg)
+	runtime.Goexit()
+}
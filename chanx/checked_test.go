@@ -0,0 +1,139 @@
+package chanx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestChecked(t *testing.T) {
+	synctest.Run(func() {
+		c := NewChecked[int](t, "jobs", 1)
+		go func() {
+			for i := range 3 {
+				c.Send(i)
+			}
+			c.Close()
+		}()
+		var got []int
+		for v := range c.C() {
+			got = append(got, v)
+		}
+		if !slices.Equal(got, []int{0, 1, 2}) {
+			t.Errorf("received %v", got)
+		}
+	})
+}
+
+func closeJobs(c *Checked[int]) { c.Close() }
+
+func TestCheckedSendOnClosed(t *testing.T) {
+	synctest.Run(func() {
+		var c *Checked[int]
+		errs := testtb.Run(t, func(t testing.TB) {
+			c = NewChecked[int](t, "jobs", 0)
+			closeJobs(c)
+			c.Send(1)
+		})
+		if len(errs) != 1 || !strings.Contains(errs[0], `send on closed channel "jobs"`) || !strings.Contains(errs[0], "chanx.closeJobs") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
+
+func TestCheckedBlockedSenderOnClose(t *testing.T) {
+	synctest.Run(func() {
+		ft := testtb.New(t)
+		c := NewChecked[int](ft, "jobs", 0)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.Send(1)
+		}()
+		synctest.Wait()
+		closeJobs(c)
+		<-done
+		if len(ft.Errors()) != 1 || !strings.Contains(ft.Errors()[0], "send on closed") {
+			t.Errorf("errors = %q", ft.Errors())
+		}
+		if _, ok := c.Recv(); ok {
+			t.Error("Recv on closed channel returned a value")
+		}
+	})
+}
+
+func TestCheckedDoubleClose(t *testing.T) {
+	synctest.Run(func() {
+		errs := testtb.Run(t, func(t testing.TB) {
+			c := NewChecked[string](t, "results", 0)
+			c.Close()
+			c.Close()
+		})
+		if len(errs) != 1 || !strings.Contains(errs[0], `close of closed channel "results"`) || strings.Count(errs[0], "checked_test.go:") < 2 {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
+
+func TestCheckedWithoutTestPanics(t *testing.T) {
+	c := NewChecked[int](nil, "", 0)
+	c.Close()
+	defer func() {
+		if r, _ := recover().(string); !strings.Contains(r, "close of closed") {
+			t.Errorf("recovered %q", r)
+		}
+	}()
+	c.Close()
+}
+
+func TestCheckedSendAfterCloseWithRoom(t *testing.T) {
+	synctest.Run(func() {
+		for range 100 {
+			var c *Checked[int]
+			errs := testtb.Run(t, func(t testing.TB) {
+				c = NewChecked[int](t, "jobs", 1)
+				c.Close()
+				c.Send(1)
+			})
+			if len(errs) != 1 || !strings.Contains(errs[0], "send on closed") {
+				t.Fatalf("errors = %q", errs)
+			}
+			if v, ok := c.Recv(); ok {
+				t.Fatalf("received %d sent after Close", v)
+			}
+		}
+	})
+}
+
+func TestCheckedSendAfterCloseWithReceiver(t *testing.T) {
+	synctest.Run(func() {
+		for range 100 {
+			ft := testtb.New(t)
+			c := NewChecked[int](ft, "jobs", 0)
+			got := make(chan bool)
+			go func() {
+				_, ok := c.Recv()
+				got <- ok
+			}()
+			// the receiver is waiting when the channel is closed and
+			// when the late send comes in
+			synctest.Wait()
+			c.Close()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				c.Send(1)
+			}()
+			<-done
+			if ok := <-got; ok {
+				t.Fatal("receiver got a value sent after Close")
+			}
+			if len(ft.Errors()) != 1 || !strings.Contains(ft.Errors()[0], "send on closed") {
+				t.Fatalf("errors = %q", ft.Errors())
+			}
+		}
+	})
+}
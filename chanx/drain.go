@@ -0,0 +1,37 @@
+package chanx
+
+import "testing/synctest"
+
+// DrainUntilQuiet receives from ch until the bubble is quiescent, i.e.
+// every other goroutine in it is durably blocked and ch holds nothing,
+// and returns everything received. It also returns once ch is closed and
+// drained. Values that would only be sent after virtual time advances, by
+// a goroutine sleeping or waiting on a timer, are not waited for. It must
+// be called inside a bubble.
+//
+// After shutting a component down, an empty result shows that nothing was
+// still in flight, without guessing how long to wait for stragglers.
+func DrainUntilQuiet[T any](ch <-chan T) []T {
+	var got []T
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+			continue
+		default:
+		}
+		synctest.Wait()
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, v)
+		default:
+			return got
+		}
+	}
+}
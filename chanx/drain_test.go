@@ -0,0 +1,76 @@
+package chanx
+
+import (
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestDrainUntilQuiet(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan int)
+		stop := make(chan struct{})
+		// A relay chain: every value passes through three goroutines, so it
+		// is not on ch yet when the first receive finds it empty.
+		src := make(chan int)
+		go func() {
+			defer close(src)
+			for i := range 3 {
+				src <- i
+			}
+			<-stop
+		}()
+		mid := src
+		for range 2 {
+			next := make(chan int)
+			go func(in <-chan int) {
+				defer close(next)
+				for v := range in {
+					next <- v
+				}
+			}(mid)
+			mid = next
+		}
+		go func() {
+			for v := range mid {
+				ch <- v
+			}
+		}()
+		if got := DrainUntilQuiet(ch); !slices.Equal(got, []int{0, 1, 2}) {
+			t.Errorf("drained %v", got)
+		}
+		if got := DrainUntilQuiet(ch); len(got) != 0 {
+			t.Errorf("second drain got %v", got)
+		}
+		close(stop)
+	})
+}
+
+func TestDrainUntilQuietClosed(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan int, 2)
+		ch <- 1
+		close(ch)
+		if got := DrainUntilQuiet(ch); !slices.Equal(got, []int{1}) {
+			t.Errorf("drained %v", got)
+		}
+	})
+}
+
+func TestDrainUntilQuietIgnoresTimers(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan int, 1)
+		go func() {
+			time.Sleep(time.Second)
+			ch <- 1
+		}()
+		if got := DrainUntilQuiet(ch); len(got) != 0 {
+			t.Errorf("drained %v before the timer fired", got)
+		}
+		time.Sleep(time.Second)
+		if got := DrainUntilQuiet(ch); !slices.Equal(got, []int{1}) {
+			t.Errorf("drained %v after the timer fired", got)
+		}
+	})
+}
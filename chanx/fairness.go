@@ -0,0 +1,191 @@
+package chanx
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"testing/synctest"
+)
+
+// Selector is a select-heavy component driven by ExploreSelect. It loops
+// selecting over ins, forwards every value it receives on out, and returns
+// once done is closed. The values are the indices of the inputs they were
+// sent on.
+type Selector func(ins []<-chan int, out chan<- int, done <-chan struct{})
+
+// SelectConfig sizes an exploration.
+type SelectConfig struct {
+	Inputs     int
+	Iterations int // selections per run
+	Runs       int // refill schedules, each seeded with its run number
+
+	// Load is the probability that an empty input is refilled before a
+	// selection; default 0.5. At 1 every input is always ready.
+	Load float64
+	// MaxStarve is how many selections in a row an input may be ready
+	// without being chosen; zero disables the check.
+	MaxStarve int
+}
+
+func (cfg SelectConfig) load() float64 {
+	if cfg.Load > 0 {
+		return cfg.Load
+	}
+	return 0.5
+}
+
+// SelectStat is what ExploreSelect saw for one input over all runs.
+//
+// Rerunning StarveRun reproduces which inputs were ready when, but not the
+// selector's choices: those are made by the Go runtime's select, which is
+// random and not seeded.
+type SelectStat struct {
+	Ready     int    // selections in which the input had a value
+	Chosen    int    // selections that took it
+	MaxStarve int    // most selections in a row it was ready but passed over
+	StarveRun uint64 // run in which MaxStarve was seen
+}
+
+// SelectReport summarizes an exploration.
+type SelectReport struct {
+	Runs  int
+	Stats []SelectStat // by input index
+}
+
+func (r SelectReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "select over %d runs:", r.Runs)
+	for i, s := range r.Stats {
+		freq := 0.0
+		if s.Ready > 0 {
+			freq = float64(s.Chosen) / float64(s.Ready)
+		}
+		fmt.Fprintf(&b, "\n\tcase %-3d chosen %-6d of %-6d ready (%.2f), starved up to %d (run %d)",
+			i, s.Chosen, s.Ready, freq, s.MaxStarve, s.StarveRun)
+	}
+	return b.String()
+}
+
+// ExploreSelect drives sel through cfg.Runs runs of cfg.Iterations
+// selections. Before every selection the bubble is settled and a random
+// subset of the empty inputs is refilled, so the set of ready cases is known
+// exactly; which inputs are refilled is seeded with the run number. t fails
+// if a selection offers no value, or if some input is ready for more than
+// cfg.MaxStarve selections in a row without being chosen. It must be called
+// inside a bubble.
+func ExploreSelect(t testing.TB, cfg SelectConfig, sel Selector) SelectReport {
+	t.Helper()
+	rep := SelectReport{Runs: cfg.Runs, Stats: make([]SelectStat, cfg.Inputs)}
+	for run := range uint64(cfg.Runs) {
+		if !exploreSelect(t, cfg, sel, run, &rep) {
+			break
+		}
+	}
+	if cfg.MaxStarve > 0 {
+		for i, s := range rep.Stats {
+			if s.MaxStarve > cfg.MaxStarve {
+				t.Errorf("select case %d was ready but passed over %d times in a row (run %d), limit %d\n%v",
+					i, s.MaxStarve, s.StarveRun, cfg.MaxStarve, rep)
+			}
+		}
+	}
+	return rep
+}
+
+func exploreSelect(t testing.TB, cfg SelectConfig, sel Selector, run uint64, rep *SelectReport) bool {
+	t.Helper()
+	r := rand.New(rand.NewPCG(run, run))
+	chs := make([]chan int, cfg.Inputs)
+	ins := make([]<-chan int, cfg.Inputs)
+	for i := range chs {
+		chs[i] = make(chan int, 1)
+		ins[i] = chs[i]
+	}
+	// refill tops up empty inputs and returns which are ready; at least one
+	// always is.
+	refill := func() []bool {
+		ready := make([]bool, cfg.Inputs)
+		some := false
+		for i, ch := range chs {
+			if len(ch) == 0 && r.Float64() < cfg.load() {
+				ch <- i
+			}
+			ready[i] = len(ch) > 0
+			some = some || ready[i]
+		}
+		if !some {
+			i := r.IntN(cfg.Inputs)
+			chs[i] <- i
+			ready[i] = true
+		}
+		return ready
+	}
+
+	out := make(chan int)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	ready := refill()
+	go func() {
+		defer close(exited)
+		sel(ins, out, done)
+	}()
+	defer func() {
+		close(done)
+		for {
+			select {
+			case <-out:
+			case <-exited:
+				return
+			}
+		}
+	}()
+
+	starve := make([]int, cfg.Inputs)
+	for range cfg.Iterations {
+		// The component has taken one of the ready values and is waiting
+		// to forward it; refilling now cannot change what it chose.
+		synctest.Wait()
+		next := refill()
+		var chosen int
+		select {
+		case chosen = <-out:
+		default:
+			t.Errorf("run %d: selector did not forward a value with cases %v ready", run, readyCases(ready))
+			return false
+		}
+		if chosen < 0 || chosen >= cfg.Inputs || !ready[chosen] {
+			t.Errorf("run %d: selector forwarded %d, ready cases were %v", run, chosen, readyCases(ready))
+			return false
+		}
+		for i, ok := range ready {
+			if !ok {
+				starve[i] = 0
+				continue
+			}
+			s := &rep.Stats[i]
+			s.Ready++
+			if i == chosen {
+				s.Chosen++
+				starve[i] = 0
+				continue
+			}
+			starve[i]++
+			if starve[i] > s.MaxStarve {
+				s.MaxStarve, s.StarveRun = starve[i], run
+			}
+		}
+		ready = next
+	}
+	return true
+}
+
+func readyCases(ready []bool) []int {
+	var cs []int
+	for i, ok := range ready {
+		if ok {
+			cs = append(cs, i)
+		}
+	}
+	return cs
+}
@@ -0,0 +1,88 @@
+package chanx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// fairSelect selects uniformly among the ready inputs, like a plain select.
+func fairSelect(ins []<-chan int, out chan<- int, done <-chan struct{}) {
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)}}
+	for _, in := range ins {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(in)})
+	}
+	for {
+		i, v, _ := reflect.Select(cases)
+		if i == 0 {
+			return
+		}
+		out <- int(v.Int())
+	}
+}
+
+// prioritySelect always prefers input 0, starving the rest while it is
+// busy.
+func prioritySelect(ins []<-chan int, out chan<- int, done <-chan struct{}) {
+	for {
+		select {
+		case v := <-ins[0]:
+			out <- v
+			continue
+		default:
+		}
+		select {
+		case v := <-ins[0]:
+			out <- v
+		case v := <-ins[1]:
+			out <- v
+		case v := <-ins[2]:
+			out <- v
+		case <-done:
+			return
+		}
+	}
+}
+
+func TestExploreSelectFair(t *testing.T) {
+	synctest.Run(func() {
+		rep := ExploreSelect(t, SelectConfig{Inputs: 3, Iterations: 200, Runs: 20, MaxStarve: 40}, fairSelect)
+		for i, s := range rep.Stats {
+			if s.Chosen == 0 || s.Ready < s.Chosen {
+				t.Errorf("case %d: %+v", i, s)
+			}
+		}
+	})
+}
+
+func TestExploreSelectDetectsStarvation(t *testing.T) {
+	synctest.Run(func() {
+		var rep SelectReport
+		errs := testtb.Run(t, func(t testing.TB) {
+			rep = ExploreSelect(t, SelectConfig{Inputs: 3, Iterations: 50, Runs: 3, Load: 1, MaxStarve: 10}, prioritySelect)
+		})
+		if len(errs) != 2 || !strings.Contains(errs[0], "select case 1 was ready but passed over 50 times") {
+			t.Errorf("errors = %q", errs)
+		}
+		if s := rep.Stats[0]; s.Chosen != 150 || s.MaxStarve != 0 {
+			t.Errorf("case 0: %+v", s)
+		}
+	})
+}
+
+func TestExploreSelectBadSelector(t *testing.T) {
+	synctest.Run(func() {
+		errs := testtb.Run(t, func(t testing.TB) {
+			ExploreSelect(t, SelectConfig{Inputs: 2, Iterations: 5, Runs: 1}, func(ins []<-chan int, out chan<- int, done <-chan struct{}) {
+				<-ins[0]
+				<-done
+			})
+		})
+		if len(errs) != 1 || !strings.Contains(errs[0], "did not forward a value") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package chanx
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Msg is a message of a fan-in or fan-out scenario. Producer and Seq
+// together identify it, so the kit can tell lost and duplicated messages
+// apart.
+type Msg struct {
+	Producer int
+	Seq      int
+}
+
+func (m Msg) String() string { return fmt.Sprintf("p%d#%d", m.Producer, m.Seq) }
+
+// FanConfig sizes a fan-in or fan-out scenario.
+type FanConfig struct {
+	Producers int // fan-in only
+	Consumers int // fan-out only
+	Messages  int // per producer for fan-in, in total for fan-out
+	Capacity  int // buffer of the channels the kit creates
+
+	// Ordered requires fan-in to preserve each producer's order.
+	Ordered bool
+	// Shutdown is the virtual time allowed, after the last input channel is
+	// closed, for every output channel to be closed; default one second.
+	Shutdown time.Duration
+}
+
+func (cfg FanConfig) shutdown() time.Duration {
+	if cfg.Shutdown > 0 {
+		return cfg.Shutdown
+	}
+	return time.Second
+}
+
+// FanResult is what the consumers of a scenario received.
+type FanResult struct {
+	Delivered  map[Msg]int // how often each message arrived
+	PerOutput  []int       // messages per output channel
+	Stragglers []gstack.Goroutine
+}
+
+// CheckFanIn runs Producers producers, each sending Messages messages on
+// its own channel before closing it, and passes the channels to merge. The
+// merged channel is drained, and t fails unless every message arrived
+// exactly once (in producer order if cfg.Ordered), the output was closed in
+// time and all goroutines started by merge have exited.
+func CheckFanIn(t testing.TB, cfg FanConfig, merge func(inputs []<-chan Msg) <-chan Msg) FanResult {
+	t.Helper()
+	before := goroutineIDs()
+	inputs := make([]<-chan Msg, cfg.Producers)
+	for p := range cfg.Producers {
+		ch := make(chan Msg, cfg.Capacity)
+		inputs[p] = ch
+		go produce(ch, p, 0, cfg.Messages)
+	}
+
+	out := merge(inputs)
+	res := FanResult{Delivered: make(map[Msg]int), PerOutput: make([]int, 1)}
+	last := make([]int, cfg.Producers)
+	for i := range last {
+		last[i] = -1
+	}
+	for m := range drainWithin(t, out, cfg) {
+		res.Delivered[m]++
+		res.PerOutput[0]++
+		if cfg.Ordered && m.Producer >= 0 && m.Producer < cfg.Producers {
+			if m.Seq < last[m.Producer] {
+				t.Errorf("fan-in: %v delivered after p%d#%d, producer order not preserved", m, m.Producer, last[m.Producer])
+			}
+			last[m.Producer] = m.Seq
+		}
+	}
+
+	want := make(map[Msg]bool)
+	for p := range cfg.Producers {
+		for s := range cfg.Messages {
+			want[Msg{p, s}] = true
+		}
+	}
+	checkConservation(t, "fan-in", want, res.Delivered)
+	res.Stragglers = stragglers(t, "fan-in", before)
+	return res
+}
+
+// CheckFanOut runs one producer sending Messages messages on a channel
+// that it closes afterwards, and passes it to split, which must return
+// Consumers output channels. All outputs are drained concurrently, and t
+// fails unless every message arrived exactly once on some output, every
+// output was closed in time and all goroutines started by split have
+// exited.
+func CheckFanOut(t testing.TB, cfg FanConfig, split func(in <-chan Msg, n int) []<-chan Msg) FanResult {
+	t.Helper()
+	before := goroutineIDs()
+	in := make(chan Msg, cfg.Capacity)
+	go produce(in, 0, 0, cfg.Messages)
+
+	outs := split(in, cfg.Consumers)
+	if len(outs) != cfg.Consumers {
+		t.Fatalf("fan-out: split returned %d outputs, want %d", len(outs), cfg.Consumers)
+	}
+	res := FanResult{Delivered: make(map[Msg]int), PerOutput: make([]int, len(outs))}
+	received := make([][]Msg, len(outs))
+	done := make(chan struct{})
+	for i, out := range outs {
+		go func() {
+			for m := range drainWithin(t, out, cfg) {
+				received[i] = append(received[i], m)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for range outs {
+		<-done
+	}
+	for i, got := range received {
+		res.PerOutput[i] = len(got)
+		for _, m := range got {
+			res.Delivered[m]++
+		}
+	}
+
+	want := make(map[Msg]bool)
+	for s := range cfg.Messages {
+		want[Msg{0, s}] = true
+	}
+	checkConservation(t, "fan-out", want, res.Delivered)
+	res.Stragglers = stragglers(t, "fan-out", before)
+	return res
+}
+
+func produce(ch chan<- Msg, producer, from, n int) {
+	for s := from; s < from+n; s++ {
+		ch <- Msg{producer, s}
+	}
+	close(ch)
+}
+
+// drainWithin yields the values of ch until it is closed. It fails t if ch
+// is still open cfg.shutdown() after it last had a value.
+func drainWithin(t testing.TB, ch <-chan Msg, cfg FanConfig) func(yield func(Msg) bool) {
+	return func(yield func(Msg) bool) {
+		for {
+			timer := time.NewTimer(cfg.shutdown())
+			select {
+			case m, ok := <-ch:
+				timer.Stop()
+				if !ok || !yield(m) {
+					return
+				}
+			case <-timer.C:
+				t.Errorf("output channel not closed within %v after the last message", cfg.shutdown())
+				return
+			}
+		}
+	}
+}
+
+func checkConservation(t testing.TB, kind string, want map[Msg]bool, got map[Msg]int) {
+	t.Helper()
+	var lost, dup, unknown []Msg
+	for m := range want {
+		if got[m] == 0 {
+			lost = append(lost, m)
+		}
+	}
+	for m, n := range got {
+		switch {
+		case !want[m]:
+			unknown = append(unknown, m)
+		case n > 1:
+			dup = append(dup, m)
+		}
+	}
+	for _, s := range []struct {
+		what string
+		ms   []Msg
+	}{{"lost", lost}, {"duplicated", dup}, {"never sent", unknown}} {
+		if len(s.ms) > 0 {
+			sort.Slice(s.ms, func(i, j int) bool {
+				if s.ms[i].Producer != s.ms[j].Producer {
+					return s.ms[i].Producer < s.ms[j].Producer
+				}
+				return s.ms[i].Seq < s.ms[j].Seq
+			})
+			t.Errorf("%s: %d of %d messages %s: %v", kind, len(s.ms), len(want), s.what, s.ms)
+		}
+	}
+}
+
+func goroutineIDs() map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, g := range gstack.Bubble() {
+		ids[g.ID] = true
+	}
+	return ids
+}
+
+// stragglers waits for the bubble to settle and reports goroutines that
+// were started during the scenario and are still alive.
+func stragglers(t testing.TB, kind string, before map[int64]bool) []gstack.Goroutine {
+	t.Helper()
+	synctest.Wait()
+	var left []gstack.Goroutine
+	for _, g := range gstack.Bubble() {
+		if before[g.ID] {
+			continue
+		}
+		left = append(left, g)
+		where := ""
+		if f, ok := g.UserFrame(); ok {
+			where = fmt.Sprintf(" in %s at %s:%d", f.Func, f.File, f.Line)
+		}
+		t.Errorf("%s: goroutine %d still running after shutdown [%s]%s", kind, g.ID, g.State, where)
+	}
+	return left
+}
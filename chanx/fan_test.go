@@ -0,0 +1,104 @@
+package chanx
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func merge(inputs []<-chan Msg) <-chan Msg {
+	out := make(chan Msg)
+	var wg sync.WaitGroup
+	for _, in := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range in {
+				out <- m
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+func split(in <-chan Msg, n int) []<-chan Msg {
+	outs := make([]<-chan Msg, n)
+	for i := range outs {
+		out := make(chan Msg)
+		outs[i] = out
+		go func() {
+			defer close(out)
+			for m := range in {
+				out <- m
+			}
+		}()
+	}
+	return outs
+}
+
+func TestCheckFanIn(t *testing.T) {
+	for _, cfg := range []FanConfig{
+		{Producers: 1, Messages: 1},
+		{Producers: 8, Messages: 50, Ordered: true},
+		{Producers: 100, Messages: 20, Capacity: 4, Ordered: true},
+	} {
+		synctest.Run(func() {
+			res := CheckFanIn(t, cfg, merge)
+			if got := res.PerOutput[0]; got != cfg.Producers*cfg.Messages {
+				t.Errorf("%+v: %d messages delivered", cfg, got)
+			}
+		})
+	}
+}
+
+func TestCheckFanOut(t *testing.T) {
+	synctest.Run(func() {
+		res := CheckFanOut(t, FanConfig{Consumers: 4, Messages: 200, Capacity: 2}, split)
+		total := 0
+		for _, n := range res.PerOutput {
+			total += n
+		}
+		if total != 200 {
+			t.Errorf("%d messages delivered, want 200", total)
+		}
+	})
+}
+
+func TestCheckFanInDetectsBugs(t *testing.T) {
+	synctest.Run(func() {
+		// lossy duplicates the first input, drops the second and leaves a
+		// goroutine behind until release is closed.
+		release := make(chan struct{})
+		lossy := func(inputs []<-chan Msg) <-chan Msg {
+			out := make(chan Msg)
+			go func() {
+				for m := range inputs[0] {
+					out <- m
+					out <- m
+				}
+				close(out)
+			}()
+			go func() {
+				for range inputs[1] {
+				}
+				<-release
+			}()
+			return out
+		}
+		errs := testtb.Run(t, func(t testing.TB) { CheckFanIn(t, FanConfig{Producers: 2, Messages: 3}, lossy) })
+		got := strings.Join(errs, "\n")
+		for _, want := range []string{"messages lost: [p1#0 p1#1 p1#2]", "messages duplicated: [p0#0 p0#1 p0#2]", "still running after shutdown [chan receive]"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("failures lack %q:\n%s", want, got)
+			}
+		}
+		close(release)
+	})
+}
@@ -0,0 +1,113 @@
+package chanx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ReceivesInOrder receives len(want) values from ch and fails t unless they
+// equal want in order. Each value must arrive within d of the previous one.
+// The failure shows a line diff of the values received against want.
+func ReceivesInOrder[T comparable](t testing.TB, ch <-chan T, d time.Duration, want ...T) []T {
+	t.Helper()
+	got, why := receiveN(ch, len(want), d)
+	if why != "" || !slices.Equal(got, want) {
+		t.Errorf("received values differ from want (- want, + received)%s:\n%s", why, diff(want, got))
+	}
+	return got
+}
+
+// ReceivesExactlySet receives len(want) values from ch and fails t unless
+// they are want in some order, counting duplicates. Each value must arrive
+// within d of the previous one.
+func ReceivesExactlySet[T comparable](t testing.TB, ch <-chan T, d time.Duration, want ...T) []T {
+	t.Helper()
+	got, why := receiveN(ch, len(want), d)
+	count := make(map[T]int)
+	for _, v := range want {
+		count[v]++
+	}
+	var unexpected []T
+	for _, v := range got {
+		if count[v] > 0 {
+			count[v]--
+			continue
+		}
+		unexpected = append(unexpected, v)
+	}
+	var missing []T
+	for _, v := range want {
+		if count[v] > 0 {
+			count[v]--
+			missing = append(missing, v)
+		}
+	}
+	if why == "" && len(missing) == 0 && len(unexpected) == 0 {
+		return got
+	}
+	var b strings.Builder
+	for _, v := range missing {
+		fmt.Fprintf(&b, "\t- %v\n", v)
+	}
+	for _, v := range unexpected {
+		fmt.Fprintf(&b, "\t+ %v\n", v)
+	}
+	t.Errorf("received set differs from want (- missing, + unexpected)%s:\n%s", why, strings.TrimSuffix(b.String(), "\n"))
+	return got
+}
+
+// receiveN receives up to n values, giving up when ch is closed or stays
+// silent for d. why says which, if any.
+func receiveN[T any](ch <-chan T, n int, d time.Duration) (got []T, why string) {
+	for len(got) < n {
+		timer := time.NewTimer(d)
+		select {
+		case v, ok := <-ch:
+			timer.Stop()
+			if !ok {
+				return got, fmt.Sprintf(", channel closed after %d values", len(got))
+			}
+			got = append(got, v)
+		case <-timer.C:
+			return got, fmt.Sprintf(", nothing received for %v after %d values", d, len(got))
+		}
+	}
+	return got, ""
+}
+
+// diff renders the edit script turning want into got, one value per line,
+// using a longest common subsequence.
+func diff[T comparable](want, got []T) string {
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var b strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			fmt.Fprintf(&b, "\t  %v\n", want[i])
+			i, j = i+1, j+1
+		case j < len(got) && (i == len(want) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&b, "\t+ %v\n", got[j])
+			j++
+		default:
+			fmt.Fprintf(&b, "\t- %v\n", want[i])
+			i++
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
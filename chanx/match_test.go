@@ -0,0 +1,64 @@
+package chanx
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func sendEvery(ch chan<- string, d time.Duration, vs ...string) {
+	for _, v := range vs {
+		time.Sleep(d)
+		ch <- v
+	}
+}
+
+func TestReceivesInOrder(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan string)
+		go sendEvery(ch, time.Second, "a", "b", "c")
+		ReceivesInOrder(t, ch, 2*time.Second, "a", "b", "c")
+
+		go sendEvery(ch, time.Second, "a", "c", "x", "d")
+		errs := testtb.Run(t, func(t testing.TB) { ReceivesInOrder(t, ch, 2*time.Second, "a", "b", "c", "d") })
+		want := "received values differ from want (- want, + received):\n" +
+			"\t  a\n\t- b\n\t  c\n\t+ x\n\t  d"
+		if len(errs) != 1 || errs[0] != want {
+			t.Errorf("errors %q, want %q", errs, want)
+		}
+	})
+}
+
+func TestReceivesInOrderTimeout(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan string)
+		go sendEvery(ch, time.Second, "a")
+		errs := testtb.Run(t, func(t testing.TB) { ReceivesInOrder(t, ch, 2*time.Second, "a", "b") })
+		want := "received values differ from want (- want, + received), nothing received for 2s after 1 values:\n" +
+			"\t  a\n\t- b"
+		if len(errs) != 1 || errs[0] != want {
+			t.Errorf("errors %q, want %q", errs, want)
+		}
+	})
+}
+
+func TestReceivesExactlySet(t *testing.T) {
+	synctest.Run(func() {
+		ch := make(chan string, 4)
+		go sendEvery(ch, 0, "c", "a", "b", "a")
+		ReceivesExactlySet(t, ch, time.Second, "a", "a", "b", "c")
+
+		go func() {
+			sendEvery(ch, 0, "b", "a", "b")
+			close(ch)
+		}()
+		errs := testtb.Run(t, func(t testing.TB) { ReceivesExactlySet(t, ch, time.Second, "a", "b", "c", "d") })
+		want := "received set differs from want (- missing, + unexpected), channel closed after 3 values:\n" +
+			"\t- c\n\t- d\n\t+ b"
+		if len(errs) != 1 || errs[0] != want {
+			t.Errorf("errors %q, want %q", errs, want)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package chanx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// deadSelect is the block.Op kind of a Select whose cases are all
+// disabled.
+const deadSelect = "select with every case disabled"
+
+// RecvIf returns ch if on is true and nil otherwise, for the idiom of
+// disabling a select case by receiving from a nil channel:
+//
+//	case v := <-chanx.RecvIf(in, len(buf) < max):
+func RecvIf[T any](ch <-chan T, on bool) <-chan T {
+	if on {
+		return ch
+	}
+	return nil
+}
+
+// SendIf returns ch if on is true and nil otherwise, see RecvIf.
+func SendIf[T any](ch chan<- T, on bool) chan<- T {
+	if on {
+		return ch
+	}
+	return nil
+}
+
+// If disables the case unless on is true, like a select case on a nil
+// channel. Disabled cases are shown as such in reports.
+func (c Case) If(on bool) Case {
+	if !on {
+		c.sc.Chan = reflect.Value{}
+		c.disabled = true
+	}
+	return c
+}
+
+// ExpectNoDeadSelects waits for the bubble to settle and fails t for every
+// goroutine in it that is blocked on nothing but nil channels: a Select
+// whose cases are all disabled, an empty select statement, or a plain send
+// or receive on a nil channel. Such a goroutine
+// can never proceed, since only the goroutine itself could re-enable its
+// cases. A loop that disables its cases one by one, for example as inputs
+// close, without leaving once none is left ends up here; with a default
+// case it spins instead. It must be called inside a bubble.
+//
+// A select statement with several nil cases is reported by the runtime
+// like any other select, so only chanx.Select and single-channel
+// operations are recognized.
+func ExpectNoDeadSelects(t testing.TB) {
+	t.Helper()
+	synctest.Wait()
+	for _, g := range gstack.Bubble() {
+		if op, ok := block.Lookup(g.ID); ok {
+			if op.Kind == deadSelect {
+				t.Errorf("goroutine %d can never proceed: %v", g.ID, op)
+			}
+			continue
+		}
+		if !strings.Contains(g.State, "(nil chan)") && g.State != "select (no cases)" {
+			continue
+		}
+		where := ""
+		if f, ok := g.UserFrame(); ok {
+			where = fmt.Sprintf(" in %s at %s:%d", f.Func, f.File, f.Line)
+		}
+		t.Errorf("goroutine %d can never proceed: %s%s", g.ID, g.State, where)
+	}
+}
@@ -0,0 +1,123 @@
+package chanx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// mergeTwo merges a and b, disabling each case once its input is closed.
+// With exit false it forgets to stop once both are.
+func mergeTwo(a, b *Chan[int], out chan<- int, exit bool) {
+	aOpen, bOpen := true, true
+	for !exit || aOpen || bOpen {
+		Select(
+			OnRecv(a, func(v int, ok bool) {
+				if aOpen = ok; ok {
+					out <- v
+				}
+			}).If(aOpen),
+			OnRecv(b, func(v int, ok bool) {
+				if bOpen = ok; ok {
+					out <- v
+				}
+			}).If(bOpen),
+		)
+	}
+	close(out)
+}
+
+func TestSelectIf(t *testing.T) {
+	synctest.Run(func() {
+		a, b := Make[int]("a", 1), Make[int]("b", 1)
+		out := make(chan int, 2)
+		a.Send(1)
+		b.Send(2)
+		a.Close()
+		b.Close()
+		go mergeTwo(a, b, out, true)
+		var got []int
+		for v := range out {
+			got = append(got, v)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, []int{1, 2}) {
+			t.Errorf("merged %v", got)
+		}
+		ExpectNoDeadSelects(t)
+	})
+}
+
+func TestExpectNoDeadSelects(t *testing.T) {
+	// The dead goroutines can never exit, so the bubble ends in a deadlock.
+	_, r := gstack.Run(func() {
+		a, b := Make[int]("a", 0), Make[int]("b", 0)
+		a.Close()
+		b.Close()
+		go mergeTwo(a, b, make(chan int), false)
+
+		var in chan int
+		go func() {
+			for {
+				if _, ok := <-in; !ok {
+					in = nil
+				}
+			}
+		}()
+
+		errs := testtb.Run(t, func(t testing.TB) { ExpectNoDeadSelects(t) })
+		all := strings.Join(errs, "\n")
+		if len(errs) != 2 ||
+			!strings.Contains(all, `can never proceed: chan receive (nil chan) in github.com/denisjgr/Go-Project-Modelbased-SE/chanx.TestExpectNoDeadSelects.func1.1`) ||
+			!strings.Contains(all, `can never proceed: select with every case disabled {recv on channel "a"`) ||
+			!strings.Contains(all, `(disabled); recv on channel "b"`) ||
+			!strings.Contains(all, "(disabled)} at chanx/nilcase_test.go:18") {
+			t.Errorf("errors %q", errs)
+		}
+	})
+	if r, _ := r.(string); !strings.HasPrefix(r, "deadlock") {
+		t.Errorf("bubble ended with %v, want a deadlock", r)
+	}
+}
+
+func TestRecvSendIf(t *testing.T) {
+	synctest.Run(func() {
+		// A bounded relay: stop receiving while the buffer is full, stop
+		// sending while it is empty.
+		in, out := make(chan int), make(chan int)
+		go func() {
+			var buf []int
+			for in != nil || len(buf) > 0 {
+				var next int
+				if len(buf) > 0 {
+					next = buf[0]
+				}
+				select {
+				case v, ok := <-RecvIf(in, len(buf) < 2):
+					if !ok {
+						in = nil
+						continue
+					}
+					buf = append(buf, v)
+				case SendIf(out, len(buf) > 0) <- next:
+					buf = buf[1:]
+				}
+			}
+			close(out)
+		}()
+		go func() {
+			for i := range 5 {
+				in <- i
+			}
+			close(in)
+		}()
+		synctest.Wait()
+		if got := DrainUntilQuiet(out); !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+			t.Errorf("relayed %v", got)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package chanx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testing/synctest"
+)
+
+// Stage is one stage of a pipeline. It reads from in and writes to the
+// channel it returns. When ctx is canceled it must stop, even if in is
+// still open and nobody reads its output any more, and close its output.
+type Stage[T any] func(ctx context.Context, in <-chan T) <-chan T
+
+// CancelPoint is where CheckPipelineCancel cancels the context: right after
+// the After-th value crossed Boundary and the pipeline settled. Boundary 0 is between the source and
+// the first stage, boundary len(stages) between the last stage and the
+// sink. After 0 cancels before the pipeline starts.
+type CancelPoint struct {
+	Boundary, After int
+}
+
+func (p CancelPoint) String() string {
+	return fmt.Sprintf("boundary %d after %d values", p.Boundary, p.After)
+}
+
+// CheckPipelineCancel runs the pipeline built from stages once for every
+// cancel point, feeding it inputs from a source that honours the context.
+// After each cancellation the downstream side stops reading, as a
+// well-behaved consumer would, and t fails for every stage goroutine that
+// does not exit and for every stage output channel that is not closed. It
+// returns the cancel points at which the pipeline leaked.
+func CheckPipelineCancel[T any](t testing.TB, inputs []T, stages ...Stage[T]) []CancelPoint {
+	t.Helper()
+	points := []CancelPoint{{0, 0}}
+	for b := 0; b <= len(stages); b++ {
+		for k := 1; k <= len(inputs); k++ {
+			points = append(points, CancelPoint{b, k})
+		}
+	}
+	var leaks []CancelPoint
+	for _, p := range points {
+		if !runPipelineCancel(t, p, inputs, stages) {
+			leaks = append(leaks, p)
+		}
+	}
+	return leaks
+}
+
+func runPipelineCancel[T any](t testing.TB, p CancelPoint, inputs []T, stages []Stage[T]) bool {
+	t.Helper()
+	before := goroutineIDs()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := make(chan T)
+	go func() {
+		defer close(src)
+		for _, v := range inputs {
+			select {
+			case src <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	crossed := func(b int) func(n int) {
+		return func(n int) {
+			if b == p.Boundary && n == p.After {
+				// let everything downstream drain, so stages upstream are
+				// caught blocked on their sends
+				synctest.Wait()
+				cancel()
+			}
+		}
+	}
+	if p.After == 0 && p.Boundary == 0 {
+		cancel()
+	}
+
+	var in <-chan T = src
+	outs := make([]<-chan T, len(stages))
+	for i, stage := range stages {
+		outs[i] = stage(ctx, relay(ctx, in, crossed(i)))
+		in = outs[i]
+	}
+	sink := relay(ctx, in, crossed(len(stages)))
+	for {
+		select {
+		case _, ok := <-sink:
+			if ok {
+				continue
+			}
+		case <-ctx.Done():
+		}
+		break
+	}
+	cancel()
+
+	ok := len(stragglers(t, fmt.Sprintf("pipeline canceled at %v", p), before)) == 0
+	for i, out := range outs {
+		if !closedAfterDrain(out) {
+			t.Errorf("pipeline canceled at %v: stage %d did not close its output", p, i)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// relay forwards in to the returned channel and calls crossed with the
+// number of values forwarded so far. Once ctx is canceled it stops without
+// closing its output, so the next stage has to notice the cancellation
+// itself.
+func relay[T any](ctx context.Context, in <-chan T, crossed func(n int)) <-chan T {
+	out := make(chan T)
+	go func() {
+		n := 0
+		for ctx.Err() == nil {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					close(out)
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+				n++
+				crossed(n)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// closedAfterDrain discards buffered values of ch and reports whether it is
+// closed.
+func closedAfterDrain[T any](ch <-chan T) bool {
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package chanx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// double is a well-behaved stage.
+func double(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- 2 * v:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// leakySquare ignores ctx when sending, the classic pipeline leak.
+func leakySquare(ctx context.Context, in <-chan int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				out <- v * v
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func TestCheckPipelineCancel(t *testing.T) {
+	synctest.Run(func() {
+		if leaks := CheckPipelineCancel(t, []int{1, 2, 3}, double, double, double); leaks != nil {
+			t.Errorf("well-behaved pipeline leaked at %v", leaks)
+		}
+	})
+}
+
+func TestCheckPipelineCancelDetectsLeak(t *testing.T) {
+	synctest.Run(func() {
+		var leaks []CancelPoint
+		errs := testtb.Run(t, func(t testing.TB) {
+			leaks = CheckPipelineCancel(t, []int{1, 2}, double, leakySquare)
+		})
+		// leakySquare is caught holding 4 when cancel comes after 1 went by
+		if got := fmt.Sprint(leaks); got != "[boundary 2 after 1 values]" {
+			t.Errorf("leaks = %s", got)
+		}
+		if len(errs) == 0 || !strings.Contains(errs[0], "chanx.leakySquare.func1") {
+			t.Errorf("failures do not point at the leaking stage: %q", errs)
+		}
+		// let the leaked goroutines go so the bubble can end
+		synctest.Wait()
+	})
+}
@@ -0,0 +1,113 @@
+package chanx
+
+import (
+	"sync"
+)
+
+// Priority multiplexes a high and a low priority lane into one stream.
+// Recv prefers the high lane, but once it has served MaxBurst high values
+// in a row while a low value was waiting, it serves one low value, so a
+// steady stream of high priority work cannot starve the low lane.
+//
+// Each lane is FIFO. Priority is safe for concurrent use; with several
+// receivers the burst limit holds for the receives as a whole.
+type Priority[T any] struct {
+	high, low chan T
+	maxBurst  int
+
+	mu    sync.Mutex
+	burst int // high values served in a row while low was waiting
+}
+
+// NewPriority creates a Priority whose lanes buffer capacity values each.
+// maxBurst must be at least 1.
+func NewPriority[T any](capacity, maxBurst int) *Priority[T] {
+	if maxBurst < 1 {
+		panic("chanx: NewPriority with maxBurst < 1")
+	}
+	return &Priority[T]{high: make(chan T, capacity), low: make(chan T, capacity), maxBurst: maxBurst}
+}
+
+// SendHigh sends v on the high lane, blocking while it is full.
+func (p *Priority[T]) SendHigh(v T) { p.high <- v }
+
+// SendLow sends v on the low lane, blocking while it is full.
+func (p *Priority[T]) SendLow(v T) { p.low <- v }
+
+// Close closes both lanes. Values already sent are still delivered.
+func (p *Priority[T]) Close() {
+	close(p.high)
+	close(p.low)
+}
+
+// Recv returns the next value and whether it came from the high lane. ok
+// is false once both lanes are closed and drained.
+func (p *Priority[T]) Recv() (v T, fromHigh, ok bool) {
+	high, low := p.high, p.low
+	for high != nil || low != nil {
+		p.mu.Lock()
+		lowFirst := p.burst >= p.maxBurst
+		p.mu.Unlock()
+
+		// Take what is ready right now, in priority order.
+		lanes := []*chan T{&high, &low}
+		if lowFirst {
+			lanes[0], lanes[1] = lanes[1], lanes[0]
+		}
+		for _, lane := range lanes {
+			v, ok, closed := tryRecv(*lane)
+			if ok {
+				return v, p.served(*lane == p.high), true
+			}
+			if closed {
+				*lane = nil
+			}
+		}
+		if high == nil && low == nil {
+			break
+		}
+
+		// Nothing ready: wait for either lane.
+		select {
+		case v, ok := <-high:
+			if ok {
+				return v, p.served(true), true
+			}
+			high = nil
+		case v, ok := <-low:
+			if ok {
+				return v, p.served(false), true
+			}
+			low = nil
+		}
+	}
+	return v, false, false
+}
+
+// served updates the burst count after a value of the given lane was taken.
+func (p *Priority[T]) served(high bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case !high:
+		p.burst = 0
+	case len(p.low) > 0:
+		p.burst++
+	default:
+		p.burst = 0
+	}
+	return high
+}
+
+// tryRecv receives from ch without blocking. A nil ch is never ready.
+func tryRecv[T any](ch chan T) (v T, ok, closed bool) {
+	if ch == nil {
+		return v, false, false
+	}
+	select {
+	case v, ok := <-ch:
+		return v, ok, !ok
+	default:
+		return v, false, false
+	}
+}
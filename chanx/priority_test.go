@@ -0,0 +1,219 @@
+package chanx
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+)
+
+// priorityModel is the reference behaviour of Priority: it delivers to a
+// waiting receiver right away and otherwise picks by lane and burst.
+type priorityModel struct {
+	maxBurst    int
+	high, low   []string
+	burst       int
+	pending     int
+	deliveredTo []string
+}
+
+func (m *priorityModel) serve(v string, high bool) {
+	switch {
+	case !high:
+		m.burst = 0
+	case len(m.low) > 0:
+		m.burst++
+	default:
+		m.burst = 0
+	}
+	m.deliveredTo = append(m.deliveredTo, v)
+}
+
+func (m *priorityModel) recv() {
+	lanes := []*[]string{&m.high, &m.low}
+	if m.burst >= m.maxBurst {
+		lanes[0], lanes[1] = lanes[1], lanes[0]
+	}
+	for _, lane := range lanes {
+		if len(*lane) > 0 {
+			v := (*lane)[0]
+			*lane = (*lane)[1:]
+			m.serve(v, lane == &m.high)
+			return
+		}
+	}
+	m.pending++
+}
+
+func (m *priorityModel) send(v string, high bool) {
+	if m.pending > 0 {
+		m.pending--
+		m.serve(v, high)
+		return
+	}
+	if high {
+		m.high = append(m.high, v)
+	} else {
+		m.low = append(m.low, v)
+	}
+}
+
+// interleavings returns every ordering of the given event counts.
+func interleavings(counts map[byte]int) []string {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	var out []string
+	var rec func(prefix []byte)
+	rec = func(prefix []byte) {
+		if len(prefix) == total {
+			out = append(out, string(prefix))
+			return
+		}
+		for _, e := range []byte("HLR") {
+			if counts[e] > 0 {
+				counts[e]--
+				rec(append(prefix, e))
+				counts[e]++
+			}
+		}
+	}
+	rec(nil)
+	return out
+}
+
+// runPriority replays events against a Priority in a fresh bubble: H and L
+// send the next high or low value, R starts one Recv in its own goroutine,
+// which may block until a value arrives. The bubble is settled after every
+// event, so at most one delivery happens per event.
+func runPriority(events string, maxBurst int) (got []string, at []int) {
+	synctest.Run(func() {
+		p := NewPriority[string](len(events), maxBurst)
+		var mu sync.Mutex
+		event := 0
+		nh, nl := 0, 0
+		for i, e := range events {
+			mu.Lock()
+			event = i
+			mu.Unlock()
+			switch e {
+			case 'H':
+				p.SendHigh(fmt.Sprint("h", nh))
+				nh++
+			case 'L':
+				p.SendLow(fmt.Sprint("l", nl))
+				nl++
+			case 'R':
+				go func() {
+					v, _, _ := p.Recv()
+					mu.Lock()
+					got, at = append(got, v), append(at, event)
+					mu.Unlock()
+				}()
+			}
+			synctest.Wait()
+		}
+	})
+	return got, at
+}
+
+func TestPriorityAllInterleavings(t *testing.T) {
+	for _, maxBurst := range []int{1, 2} {
+		for _, events := range interleavings(map[byte]int{'H': 4, 'L': 2, 'R': 6}) {
+			m := &priorityModel{maxBurst: maxBurst}
+			nh, nl := 0, 0
+			for _, e := range events {
+				switch e {
+				case 'H':
+					m.send(fmt.Sprint("h", nh), true)
+					nh++
+				case 'L':
+					m.send(fmt.Sprint("l", nl), false)
+					nl++
+				case 'R':
+					m.recv()
+				}
+			}
+			got, at := runPriority(events, maxBurst)
+			if !slices.Equal(got, m.deliveredTo) {
+				t.Fatalf("maxBurst %d, events %s: delivered %v, model says %v", maxBurst, events, got, m.deliveredTo)
+			}
+			checkStarvation(t, events, got, at, maxBurst)
+		}
+	}
+}
+
+// checkStarvation checks the guarantee itself, independently of the model:
+// while a low value waits, no more than maxBurst high values are
+// delivered in a row. at[i] is the event during which got[i] was delivered.
+func checkStarvation(t *testing.T, events string, got []string, at []int, maxBurst int) {
+	t.Helper()
+	sentAt := make(map[string]int)
+	nl := 0
+	for i, e := range events {
+		if e == 'L' {
+			sentAt[fmt.Sprint("l", nl)] = i
+			nl++
+		}
+	}
+	for _, l := range slices.Collect(maps.Keys(sentAt)) {
+		streak := 0
+		for i, v := range got {
+			if v == l {
+				break
+			}
+			if at[i] <= sentAt[l] {
+				continue
+			}
+			if v[0] == 'l' {
+				streak = 0
+				continue
+			}
+			if streak++; streak > maxBurst {
+				t.Fatalf("events %s: %s starved by %d high values in a row: %v", events, l, streak, got)
+			}
+		}
+	}
+}
+
+func TestPriorityServesLowAfterBurst(t *testing.T) {
+	synctest.Run(func() {
+		p := NewPriority[int](10, 2)
+		for i := range 6 {
+			p.SendHigh(i)
+		}
+		p.SendLow(100)
+		p.SendLow(101)
+		var got []int
+		for range 8 {
+			v, _, _ := p.Recv()
+			got = append(got, v)
+		}
+		if want := []int{0, 1, 100, 2, 3, 101, 4, 5}; !slices.Equal(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+}
+
+func TestPriorityClose(t *testing.T) {
+	synctest.Run(func() {
+		p := NewPriority[int](2, 1)
+		p.SendLow(1)
+		p.SendHigh(2)
+		p.Close()
+		var got []int
+		for {
+			v, _, ok := p.Recv()
+			if !ok {
+				break
+			}
+			got = append(got, v)
+		}
+		if want := []int{2, 1}; !slices.Equal(got, want) {
+			t.Errorf("received %v, want %v", got, want)
+		}
+	})
+}
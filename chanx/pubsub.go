@@ -0,0 +1,228 @@
+package chanx
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Guarantee is the delivery guarantee a pub/sub component promises each
+// subscriber.
+type Guarantee int
+
+const (
+	AtLeastOnce Guarantee = iota // no message lost, duplicates allowed
+	ExactlyOnce                  // no message lost or duplicated
+)
+
+func (g Guarantee) String() string {
+	if g == ExactlyOnce {
+		return "exactly-once"
+	}
+	return "at-least-once"
+}
+
+// Deliveries records which messages were published and which each
+// subscriber processed, for checking a Guarantee. It is safe for
+// concurrent use.
+type Deliveries struct {
+	mu        sync.Mutex
+	subs      []string
+	published []Msg
+	isPub     map[Msg]bool
+	delivered map[string]map[Msg]int
+}
+
+// NewDeliveries creates a record for the named subscribers.
+func NewDeliveries(subscribers ...string) *Deliveries {
+	d := &Deliveries{subs: subscribers, isPub: make(map[Msg]bool), delivered: make(map[string]map[Msg]int)}
+	for _, s := range subscribers {
+		d.delivered[s] = make(map[Msg]int)
+	}
+	return d
+}
+
+// Published records that m was published.
+func (d *Deliveries) Published(m Msg) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.isPub[m] {
+		d.isPub[m] = true
+		d.published = append(d.published, m)
+	}
+}
+
+// Delivered records that subscriber processed m.
+func (d *Deliveries) Delivered(subscriber string, m Msg) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.delivered[subscriber] == nil {
+		d.subs = append(d.subs, subscriber)
+		d.delivered[subscriber] = make(map[Msg]int)
+	}
+	d.delivered[subscriber][m]++
+}
+
+// Count returns how often subscriber processed m.
+func (d *Deliveries) Count(subscriber string, m Msg) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.delivered[subscriber][m]
+}
+
+// Check fails t for every subscriber that missed a published message,
+// processed one that was never published, or, under ExactlyOnce,
+// processed one more than once.
+func (d *Deliveries) Check(t testing.TB, g Guarantee) {
+	t.Helper()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, s := range d.subs {
+		got := d.delivered[s]
+		var lost, dup, unknown []Msg
+		for _, m := range d.published {
+			switch n := got[m]; {
+			case n == 0:
+				lost = append(lost, m)
+			case n > 1 && g == ExactlyOnce:
+				dup = append(dup, m)
+			}
+		}
+		for m := range got {
+			if !d.isPub[m] {
+				unknown = append(unknown, m)
+			}
+		}
+		for _, c := range []struct {
+			what string
+			ms   []Msg
+		}{{"lost", lost}, {"duplicated", dup}, {"never published", unknown}} {
+			if len(c.ms) > 0 {
+				slices.SortFunc(c.ms, func(a, b Msg) int {
+					if a.Producer != b.Producer {
+						return a.Producer - b.Producer
+					}
+					return a.Seq - b.Seq
+				})
+				t.Errorf("%s: subscriber %s: %d of %d messages %s: %v", g, s, len(c.ms), len(d.published), c.what, c.ms)
+			}
+		}
+	}
+}
+
+// Envelope is a message handed to a subscriber by Redelivery.
+type Envelope struct {
+	Msg     Msg
+	Attempt int // 1 for the first delivery
+}
+
+// Redelivery is a virtual-time model of an acknowledging broker: every
+// published message is delivered to every subscriber and delivered again
+// each Timeout until the subscriber acknowledges it, at most Attempts
+// times. It provides at-least-once delivery to subscribers that ack what
+// they process, and is meant as the broker side of tests for consumers,
+// e.g. of their deduplication.
+type Redelivery struct {
+	timeout  time.Duration
+	attempts int
+
+	mu      sync.Mutex
+	subs    map[string]chan Envelope
+	active  map[string]int // delivering goroutines per subscriber
+	acks    map[string]map[Msg]chan struct{}
+	closed  bool
+	stopped chan struct{}
+}
+
+// NewRedelivery creates a Redelivery for the named subscribers.
+func NewRedelivery(timeout time.Duration, attempts int, subscribers ...string) *Redelivery {
+	r := &Redelivery{
+		timeout: timeout, attempts: attempts,
+		subs: make(map[string]chan Envelope), active: make(map[string]int),
+		acks: make(map[string]map[Msg]chan struct{}), stopped: make(chan struct{}),
+	}
+	for _, s := range subscribers {
+		r.subs[s] = make(chan Envelope)
+		r.acks[s] = make(map[Msg]chan struct{})
+	}
+	return r
+}
+
+// C returns the channel subscriber receives on. It is closed after Close,
+// once no delivery to it is in progress.
+func (r *Redelivery) C(subscriber string) <-chan Envelope { return r.subs[subscriber] }
+
+// Publish starts delivering m to every subscriber.
+func (r *Redelivery) Publish(m Msg) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		panic("chanx: Publish on closed Redelivery")
+	}
+	for s, ch := range r.subs {
+		if _, ok := r.acks[s][m]; ok {
+			continue
+		}
+		acked := make(chan struct{})
+		r.acks[s][m] = acked
+		r.active[s]++
+		go r.deliver(s, ch, m, acked)
+	}
+}
+
+// Ack acknowledges m for subscriber, stopping its redelivery. Acking twice
+// is harmless.
+func (r *Redelivery) Ack(subscriber string, m Msg) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if acked, ok := r.acks[subscriber][m]; ok && acked != nil {
+		close(acked)
+		r.acks[subscriber][m] = nil
+	}
+}
+
+// Close stops all deliveries.
+func (r *Redelivery) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	close(r.stopped)
+	for s, ch := range r.subs {
+		if r.active[s] == 0 {
+			close(ch)
+		}
+	}
+}
+
+func (r *Redelivery) deliver(s string, ch chan Envelope, m Msg, acked chan struct{}) {
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.active[s]--; r.active[s] == 0 && r.closed {
+			close(ch)
+		}
+	}()
+	for attempt := 1; attempt <= r.attempts; attempt++ {
+		select {
+		case ch <- Envelope{m, attempt}:
+		case <-acked:
+			return
+		case <-r.stopped:
+			return
+		}
+		timer := time.NewTimer(r.timeout)
+		select {
+		case <-timer.C:
+		case <-acked:
+			timer.Stop()
+			return
+		case <-r.stopped:
+			timer.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package chanx
+
+import (
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// consume processes the deliveries of one subscriber until r is closed.
+// skip decides, per envelope, whether the consumer crashes before
+// processing it; dedup makes it ignore messages it already processed.
+func consume(r *Redelivery, d *Deliveries, sub string, dedup bool, skip func(Envelope) bool) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		seen := make(map[Msg]bool)
+		for e := range r.C(sub) {
+			if skip != nil && skip(e) {
+				continue
+			}
+			if !dedup || !seen[e.Msg] {
+				d.Delivered(sub, e.Msg)
+				seen[e.Msg] = true
+			}
+			// A duplicate is acked too, or it would keep coming back.
+			r.Ack(sub, e.Msg)
+		}
+	}()
+	return done
+}
+
+// lostAck is a consumer that loses the ack of the first delivery of p0#1,
+// as if the consumer crashed after processing it.
+func lostAck(r *Redelivery, d *Deliveries, sub string, dedup bool) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		seen := make(map[Msg]bool)
+		for e := range r.C(sub) {
+			if !dedup || !seen[e.Msg] {
+				d.Delivered(sub, e.Msg)
+				seen[e.Msg] = true
+			}
+			if e.Msg != (Msg{0, 1}) || e.Attempt > 1 {
+				r.Ack(sub, e.Msg)
+			}
+		}
+	}()
+	return done
+}
+
+func publish(r *Redelivery, d *Deliveries, n int) {
+	for s := range n {
+		d.Published(Msg{0, s})
+		r.Publish(Msg{0, s})
+	}
+}
+
+func TestRedeliveryExactlyOnce(t *testing.T) {
+	synctest.Run(func() {
+		r := NewRedelivery(time.Second, 3, "a", "b")
+		d := NewDeliveries("a", "b")
+		a, b := consume(r, d, "a", false, nil), consume(r, d, "b", false, nil)
+		publish(r, d, 5)
+		time.Sleep(time.Minute)
+		r.Close()
+		<-a
+		<-b
+		d.Check(t, ExactlyOnce)
+	})
+}
+
+func TestRedeliveryAfterLostAck(t *testing.T) {
+	synctest.Run(func() {
+		r := NewRedelivery(time.Second, 3, "a")
+		d := NewDeliveries("a")
+		done := lostAck(r, d, "a", false)
+		publish(r, d, 3)
+		synctest.Wait()
+		if n := d.Count("a", Msg{0, 1}); n != 1 {
+			t.Fatalf("p0#1 processed %d times before the timeout", n)
+		}
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if n := d.Count("a", Msg{0, 1}); n != 2 {
+			t.Fatalf("p0#1 processed %d times after the timeout, want a redelivery", n)
+		}
+		r.Close()
+		<-done
+
+		d.Check(t, AtLeastOnce)
+		errs := testtb.Run(t, func(t testing.TB) { d.Check(t, ExactlyOnce) })
+		if len(errs) != 1 || !strings.Contains(errs[0], "exactly-once: subscriber a: 1 of 3 messages duplicated: [p0#1]") {
+			t.Errorf("errors %q", errs)
+		}
+	})
+}
+
+func TestRedeliveryDedupConsumer(t *testing.T) {
+	synctest.Run(func() {
+		r := NewRedelivery(time.Second, 3, "a")
+		d := NewDeliveries("a")
+		done := lostAck(r, d, "a", true)
+		publish(r, d, 3)
+		time.Sleep(time.Minute)
+		r.Close()
+		<-done
+		d.Check(t, ExactlyOnce)
+	})
+}
+
+func TestRedeliveryGivesUp(t *testing.T) {
+	synctest.Run(func() {
+		r := NewRedelivery(time.Second, 2, "a")
+		d := NewDeliveries("a")
+		// The consumer crashes on every delivery of p0#2.
+		done := consume(r, d, "a", false, func(e Envelope) bool { return e.Msg == Msg{0, 2} })
+		publish(r, d, 3)
+		time.Sleep(time.Minute)
+		r.Close()
+		<-done
+		d.Delivered("a", Msg{9, 9})
+
+		errs := testtb.Run(t, func(t testing.TB) { d.Check(t, AtLeastOnce) })
+		want := []string{
+			"at-least-once: subscriber a: 1 of 3 messages lost: [p0#2]",
+			"at-least-once: subscriber a: 1 of 3 messages never published: [p9#9]",
+		}
+		if strings.Join(errs, "\n") != strings.Join(want, "\n") {
+			t.Errorf("errors %q, want %q", errs, want)
+		}
+	})
+}
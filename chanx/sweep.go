@@ -0,0 +1,120 @@
+package chanx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Unbounded as a capacity for Pipe emulates a channel with an unlimited
+// buffer.
+const Unbounded = -1
+
+// DefaultCapacities is the sweep SweepCapacity uses when given none: the
+// unbuffered and single-slot cases where most ordering bugs show, a small
+// buffer, and an unbounded one.
+var DefaultCapacities = []int{0, 1, 4, Unbounded}
+
+// Pipe returns the two ends of a channel with the given capacity. With
+// Unbounded a goroutine between the ends buffers without limit, so sends
+// never block; closing in closes out once the backlog is received.
+func Pipe[T any](capacity int) (in chan<- T, out <-chan T) {
+	if capacity != Unbounded {
+		c := make(chan T, capacity)
+		return c, c
+	}
+	i, o := make(chan T), make(chan T)
+	go func() {
+		defer close(o)
+		var backlog []T
+		for in := i; in != nil || len(backlog) > 0; {
+			var send chan T
+			var next T
+			if len(backlog) > 0 {
+				send, next = o, backlog[0]
+			}
+			select {
+			case v, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				backlog = append(backlog, v)
+			case send <- next:
+				backlog = backlog[1:]
+			}
+		}
+	}()
+	return i, o
+}
+
+func capName(c int) string {
+	if c == Unbounded {
+		return "unbounded"
+	}
+	return fmt.Sprint(c)
+}
+
+// Sweep is the outcome of SweepCapacity.
+type Sweep[R any] struct {
+	Caps       []int
+	Results    map[int]R // by capacity, for runs that did not deadlock
+	Deadlocked map[int]bool
+}
+
+// Consistent reports whether every capacity gave the same result.
+func (s Sweep[R]) Consistent() bool {
+	for _, c := range s.Caps {
+		if s.Deadlocked[c] != s.Deadlocked[s.Caps[0]] || !reflect.DeepEqual(s.Results[c], s.Results[s.Caps[0]]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Sweep[R]) String() string {
+	var b strings.Builder
+	for _, c := range s.Caps {
+		if s.Deadlocked[c] {
+			fmt.Fprintf(&b, "\n\tcapacity %-9s deadlock", capName(c))
+		} else {
+			fmt.Fprintf(&b, "\n\tcapacity %-9s %v", capName(c), s.Results[c])
+		}
+	}
+	return b.String()
+}
+
+// SweepCapacity runs scenario in a fresh bubble once per capacity in caps,
+// DefaultCapacities if empty; scenario creates its channels with that
+// capacity, for example with Pipe. t fails if the results, compared with
+// reflect.DeepEqual, differ between capacities, or if the bubble
+// deadlocks at some capacities but not at others.
+func SweepCapacity[R any](t testing.TB, caps []int, scenario func(capacity int) R) Sweep[R] {
+	t.Helper()
+	if len(caps) == 0 {
+		caps = DefaultCapacities
+	}
+	s := Sweep[R]{Caps: caps, Results: make(map[int]R), Deadlocked: make(map[int]bool)}
+	for _, c := range caps {
+		if !runSweep(func() { s.Results[c] = scenario(c) }) {
+			s.Deadlocked[c] = true
+		}
+	}
+	if !s.Consistent() {
+		t.Errorf("behaviour depends on channel capacity:%v", s)
+	}
+	return s
+}
+
+// runSweep runs f in a bubble and reports whether it finished rather than
+// deadlocked.
+func runSweep(f func()) (finished bool) {
+	_, r := gstack.Run(f)
+	if msg, ok := r.(string); r != nil && (!ok || !strings.HasPrefix(msg, "deadlock")) {
+		panic(r)
+	}
+	return r == nil
+}
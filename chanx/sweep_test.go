@@ -0,0 +1,82 @@
+package chanx
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// squares is capacity-independent: a producer and a consumer in their own
+// goroutines.
+func squares(capacity int) []int {
+	in, out := Pipe[int](capacity)
+	go func() {
+		defer close(in)
+		for i := range 4 {
+			in <- i * i
+		}
+	}()
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+	return got
+}
+
+func TestSweepCapacity(t *testing.T) {
+	s := SweepCapacity(t, nil, squares)
+	if !s.Consistent() || !slices.Equal(s.Results[Unbounded], []int{0, 1, 4, 9}) {
+		t.Errorf("sweep %v", s)
+	}
+}
+
+// sendThenReceive sends two requests before reading anything, which only
+// works if the channel can hold both.
+func sendThenReceive(capacity int) int {
+	in, out := Pipe[int](capacity)
+	in <- 1
+	in <- 2
+	close(in)
+	sum := 0
+	for v := range out {
+		sum += v
+	}
+	return sum
+}
+
+// dropWhenFull drops events the channel has no room for, so how many get
+// through depends on the buffer.
+func dropWhenFull(capacity int) int {
+	in, out := Pipe[int](capacity)
+	for i := range 8 {
+		select {
+		case in <- i:
+		default:
+		}
+	}
+	close(in)
+	n := 0
+	for range out {
+		n++
+	}
+	return n
+}
+
+func TestSweepCapacityFlagsDifferences(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) { SweepCapacity(t, []int{0, 1, 2, Unbounded}, sendThenReceive) })
+	want := "behaviour depends on channel capacity:\n" +
+		"\tcapacity 0         deadlock\n" +
+		"\tcapacity 1         deadlock\n" +
+		"\tcapacity 2         3\n" +
+		"\tcapacity unbounded 3"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+
+	errs = testtb.Run(t, func(t testing.TB) { SweepCapacity(t, []int{0, 1, 4}, dropWhenFull) })
+	if len(errs) != 1 || !strings.Contains(errs[0], "capacity 0         0\n\tcapacity 1         1\n\tcapacity 4         4") {
+		t.Errorf("errors %q", errs)
+	}
+}
@@ -0,0 +1,237 @@
+package chanx
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Dir is the direction of a channel operation.
+type Dir int
+
+const (
+	Send Dir = iota
+	Recv
+)
+
+func (d Dir) String() string {
+	if d == Send {
+		return "send"
+	}
+	return "recv"
+}
+
+// Edge says that a goroutine sent to or received from a channel Count
+// times. Goroutines are identified by the names given to bubble.Go, or
+// "g<id>" if unnamed. Channels are identified by their name and creation
+// site; Channel is the name.
+type Edge struct {
+	Goroutine string
+	Channel   string
+	Site      string
+	Dir       Dir
+	Count     int
+
+	ch uint64 // identity of the channel, see Chan.id
+}
+
+// Topology records which goroutines use which Chan in one bubble while it
+// is active.
+type Topology struct {
+	group int64 // bubble being recorded
+
+	mu    sync.Mutex
+	edges map[Edge]int // keyed with Count zero
+}
+
+var (
+	topologiesMu sync.Mutex
+	topologies   = make(map[int64]*Topology) // by bubble
+	recording    atomic.Int32                // len(topologies)
+)
+
+// RecordTopology starts recording the channel operations performed in the
+// calling goroutine's bubble into a new Topology, until Stop is called or
+// the test ends. Only one Topology records a bubble at a time; operations
+// in other bubbles are not recorded.
+func RecordTopology(t testing.TB) *Topology {
+	t.Helper()
+	group := gstack.Self().Group
+	if group == 0 {
+		t.Fatalf("RecordTopology called outside a synctest bubble")
+	}
+	tp := &Topology{group: group, edges: make(map[Edge]int)}
+	topologiesMu.Lock()
+	if _, ok := topologies[group]; ok {
+		topologiesMu.Unlock()
+		t.Fatalf("RecordTopology: this bubble is already being recorded")
+	}
+	topologies[group] = tp
+	recording.Add(1)
+	topologiesMu.Unlock()
+	t.Cleanup(tp.Stop)
+	return tp
+}
+
+// Stop ends the recording.
+func (tp *Topology) Stop() {
+	topologiesMu.Lock()
+	defer topologiesMu.Unlock()
+	if topologies[tp.group] == tp {
+		delete(topologies, tp.group)
+		recording.Add(-1)
+	}
+}
+
+func recordOp(ch uint64, name, site string, dir Dir) {
+	if recording.Load() == 0 {
+		return
+	}
+	self := gstack.Self()
+	topologiesMu.Lock()
+	tp := topologies[self.Group]
+	topologiesMu.Unlock()
+	if tp == nil {
+		return
+	}
+	g := goid.Name(self.ID)
+	tp.mu.Lock()
+	tp.edges[Edge{Goroutine: g, Channel: name, Site: site, Dir: dir, ch: ch}]++
+	tp.mu.Unlock()
+}
+
+// Edges returns the recorded edges sorted by channel, direction and
+// goroutine.
+func (tp *Topology) Edges() []Edge {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	var es []Edge
+	for e, n := range tp.edges {
+		e.Count = n
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool {
+		a, b := es[i], es[j]
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		if a.Site != b.Site {
+			return a.Site < b.Site
+		}
+		if a.ch != b.ch {
+			return a.ch < b.ch
+		}
+		if a.Dir != b.Dir {
+			return a.Dir < b.Dir
+		}
+		return a.Goroutine < b.Goroutine
+	})
+	return es
+}
+
+// Flows returns the pairs of distinct goroutines where from sent on a
+// channel that to received from. Goroutine names are matched with
+// path.Match patterns, e.g. "worker-*".
+func (tp *Topology) Flows(from, to string) [][2]string {
+	es := tp.Edges()
+	seen := make(map[[2]string]bool)
+	var flows [][2]string
+	for _, s := range es {
+		if s.Dir != Send || !match(from, s.Goroutine) {
+			continue
+		}
+		for _, r := range es {
+			pair := [2]string{s.Goroutine, r.Goroutine}
+			if r.Dir != Recv || r.ch != s.ch || r.Goroutine == s.Goroutine || !match(to, r.Goroutine) || seen[pair] {
+				continue
+			}
+			seen[pair] = true
+			flows = append(flows, pair)
+		}
+	}
+	return flows
+}
+
+// ExpectNoFlow fails t if any goroutine matching from sent a value on a
+// channel that a different goroutine matching to received from, e.g.
+// ExpectNoFlow(t, "worker-*", "worker-*") for workers that must only talk
+// to the coordinator.
+func (tp *Topology) ExpectNoFlow(t testing.TB, from, to string) {
+	t.Helper()
+	for _, f := range tp.Flows(from, to) {
+		t.Errorf("goroutine %s talks to %s directly, want no flow from %q to %q", f[0], f[1], from, to)
+	}
+}
+
+func match(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// DOT renders the topology in GraphViz format: goroutines are boxes,
+// channels ellipses labelled with name and creation site, and edges point in
+// the direction data flows.
+func (tp *Topology) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph topology {\n\trankdir=LR;\n")
+	es := tp.Edges()
+	gs := make(map[string]bool)
+	nodes := make(map[uint64]string)
+	var chans []Edge
+	for _, e := range es {
+		gs[e.Goroutine] = true
+		if _, ok := nodes[e.ch]; !ok {
+			nodes[e.ch] = ""
+			chans = append(chans, e)
+		}
+	}
+	for _, g := range sortedKeys(gs) {
+		fmt.Fprintf(&b, "\t%q [shape=box];\n", "g:"+g)
+	}
+	// Make node ids unique: channels sharing a name get their site
+	// appended, channels sharing name and site a "#n" in creation order.
+	names, sites := make(map[string]int), make(map[string]int)
+	for _, e := range chans {
+		names[e.Channel]++
+		sites[e.Channel+"@"+e.Site]++
+	}
+	seen := make(map[string]int)
+	for _, e := range chans {
+		id, label := e.Channel, e.Channel
+		if names[e.Channel] > 1 {
+			id += "@" + e.Site
+		}
+		if key := e.Channel + "@" + e.Site; sites[key] > 1 {
+			suffix := fmt.Sprintf("#%d", seen[key])
+			seen[key]++
+			id, label = id+suffix, label+suffix
+		}
+		nodes[e.ch] = "c:" + id
+		fmt.Fprintf(&b, "\t%q [shape=ellipse, label=%q];\n", "c:"+id, label+"\n"+e.Site)
+	}
+	for _, e := range es {
+		from, to := "g:"+e.Goroutine, nodes[e.ch]
+		if e.Dir == Recv {
+			from, to = to, from
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [label=%d];\n", from, to, e.Count)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,134 @@
+package chanx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/bubble"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// pipeline runs a coordinator with two workers. If leak is set, worker-1
+// forwards its result to worker-2 instead of the coordinator.
+func pipeline(t *testing.T, leak bool) *Topology {
+	tp := RecordTopology(t)
+	defer tp.Stop()
+
+	jobs := Make[int]("jobs", 0)
+	results := Make[int]("results", 0)
+	side := Make[int]("side", 1)
+	done := Make[bool]("done", 0)
+
+	worker := func(id int) {
+		v, _ := jobs.Recv()
+		if leak && id == 1 {
+			side.Send(v)
+			v = 0
+		}
+		if leak && id == 2 {
+			v, _ = side.Recv()
+		}
+		results.Send(v * 2)
+		done.Send(true)
+	}
+	bubble.Go("worker-1", func() { worker(1) })
+	bubble.Go("worker-2", func() { worker(2) })
+
+	bubble.Go("coordinator", func() {
+		jobs.Send(1)
+		jobs.Send(2)
+		results.Recv()
+		results.Recv()
+	})
+	done.Recv()
+	done.Recv()
+	bubble.Wait()
+	return tp
+}
+
+func TestTopology(t *testing.T) {
+	bubble.Run(t, func() {
+		tp := pipeline(t, false)
+		tp.ExpectNoFlow(t, "worker-*", "worker-*")
+		if flows := tp.Flows("coordinator", "worker-*"); len(flows) != 2 {
+			t.Errorf("coordinator flows = %v, want both workers", flows)
+		}
+		dot := tp.DOT()
+		for _, want := range []string{`"g:coordinator" -> "c:jobs" [label=2]`, `"c:results" -> "g:coordinator" [label=2]`} {
+			if !strings.Contains(dot, want) {
+				t.Errorf("DOT lacks %s:\n%s", want, dot)
+			}
+		}
+	})
+}
+
+func TestTopologyDetectsDirectFlow(t *testing.T) {
+	bubble.Run(t, func() {
+		tp := pipeline(t, true)
+		if got := fmt.Sprint(tp.Flows("worker-*", "worker-*")); got != "[[worker-1 worker-2]]" {
+			t.Errorf("worker flows = %s, want [[worker-1 worker-2]]", got)
+		}
+	})
+}
+
+func TestTopologySameName(t *testing.T) {
+	bubble.Run(t, func() {
+		tp := RecordTopology(t)
+		defer tp.Stop()
+		// one "in" channel per worker, all created on the same line
+		var ins []*Chan[int]
+		for i := range 2 {
+			in := Make[int]("in", 0)
+			ins = append(ins, in)
+			bubble.Go(fmt.Sprint("worker-", i), func() { in.Recv() })
+		}
+		for _, in := range ins {
+			in.Send(1)
+		}
+		bubble.Wait()
+		// worker-0 and worker-1 used different channels, so they did not
+		// talk to each other even though the channels share a name
+		tp.ExpectNoFlow(t, "worker-*", "worker-*")
+		if es := tp.Edges(); len(es) != 4 {
+			t.Errorf("edges = %+v, want a send and a receive per channel", es)
+		}
+		dot := tp.DOT()
+		if !strings.Contains(dot, "#0") || !strings.Contains(dot, "#1") {
+			t.Errorf("DOT does not tell the channels apart:\n%s", dot)
+		}
+	})
+}
+
+func TestTopologySecondRecorder(t *testing.T) {
+	bubble.Run(t, func() {
+		tp := RecordTopology(t)
+		defer tp.Stop()
+		errs := testtb.Run(t, func(t testing.TB) { RecordTopology(t) })
+		if len(errs) != 1 || !strings.Contains(errs[0], "already being recorded") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
+
+func TestTopologyOnlyRecordsItsBubble(t *testing.T) {
+	c := Make[int]("outside", 1)
+	started, proceed := make(chan *Topology), make(chan struct{})
+	done := make(chan struct{})
+	go synctest.Run(func() {
+		defer close(done)
+		tp := RecordTopology(t)
+		defer tp.Stop()
+		started <- tp
+		<-proceed
+		if es := tp.Edges(); len(es) != 0 {
+			t.Errorf("recorded operations from outside the bubble: %+v", es)
+		}
+	})
+	<-started
+	c.Send(1)
+	c.Recv()
+	close(proceed)
+	<-done
+}
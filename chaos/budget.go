@@ -0,0 +1,190 @@
+// Package chaos injects faults into a system under test within a budget
+// the test declares, "at most 3 injected failures, all healed by 60s of
+// virtual time", and checks that the system returns to a steady state,
+// one satisfying its invariants, within a bounded time once the last
+// fault healed.
+//
+// Presets, such as FlakyWifi, assemble the conditions of a simnet network,
+// the latency of its disks and the faults to inject into profiles that
+// give meaningful chaos without a dozen knobs set by hand.
+package chaos
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/failpoint"
+)
+
+// Budget bounds the faults of a test.
+type Budget struct {
+	Max    int           // faults injected at most
+	HealBy time.Duration // after Start, by which every fault is healed; 0 for no bound
+	// Within is how long after the last fault healed the system has to be
+	// steady again, default 10s.
+	Within time.Duration
+}
+
+func (b Budget) within() time.Duration {
+	if b.Within > 0 {
+		return b.Within
+	}
+	return 10 * time.Second
+}
+
+// Ledger keeps the faults of a test to its budget. It is safe for
+// concurrent use.
+type Ledger struct {
+	Budget
+	start time.Time
+
+	mu      sync.Mutex
+	faults  []*Fault
+	refused int
+}
+
+// Fault is a fault injected.
+type Fault struct {
+	What   string
+	At     time.Duration // since Start
+	Healed time.Duration // since Start, or -1 while not
+	heal   func()
+	l      *Ledger
+}
+
+func (f *Fault) String() string {
+	if f.Healed < 0 {
+		return fmt.Sprintf("+%v %s, not healed", f.At, f.What)
+	}
+	return fmt.Sprintf("+%v %s, healed at +%v", f.At, f.What, f.Healed)
+}
+
+// Start starts keeping the faults of t to b, which are logged if t fails.
+// Call it in the bubble the faults are injected in, since HealBy runs on
+// its clock.
+func Start(t testing.TB, b Budget) *Ledger {
+	l := &Ledger{Budget: b, start: time.Now()}
+	if b.HealBy > 0 {
+		time.AfterFunc(b.HealBy, l.healAll)
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("chaos: %v", l)
+		}
+	})
+	return l
+}
+
+// Inject injects a fault by calling inject, which returns the function
+// healing it, nil if it heals by itself, unless the budget is spent or
+// past HealBy; then Inject returns nil.
+func (l *Ledger) Inject(what string, inject func() (heal func())) *Fault {
+	l.mu.Lock()
+	now := time.Since(l.start)
+	if len(l.faults) >= l.Max || l.HealBy > 0 && now >= l.HealBy {
+		l.refused++
+		l.mu.Unlock()
+		return nil
+	}
+	f := &Fault{What: what, At: now, Healed: -1, l: l}
+	l.faults = append(l.faults, f)
+	l.mu.Unlock()
+	if f.heal = inject(); f.heal == nil {
+		f.Heal()
+	}
+	return f
+}
+
+// Heal heals f, if it was not yet.
+func (f *Fault) Heal() {
+	f.l.mu.Lock()
+	if f.Healed >= 0 {
+		f.l.mu.Unlock()
+		return
+	}
+	f.Healed = time.Since(f.l.start)
+	heal := f.heal
+	f.l.mu.Unlock()
+	if heal != nil {
+		heal()
+	}
+}
+
+func (l *Ledger) healAll() {
+	l.mu.Lock()
+	fs := append([]*Fault(nil), l.faults...)
+	l.mu.Unlock()
+	for _, f := range fs {
+		f.Heal()
+	}
+}
+
+// Policy returns p as the failpoint policy of a fault what: every hit on
+// which p fails is a fault of the budget, healed at once, and once the
+// budget is spent hits no longer fail.
+func (l *Ledger) Policy(what string, p failpoint.Policy) failpoint.Policy {
+	return func(hit int) error {
+		var err error
+		l.Inject(fmt.Sprintf("%s (hit %d)", what, hit), func() func() {
+			err = p(hit)
+			return nil
+		})
+		return err
+	}
+}
+
+// Faults returns the faults injected so far.
+func (l *Ledger) Faults() []Fault {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fs := make([]Fault, len(l.faults))
+	for i, f := range l.faults {
+		fs[i] = *f
+	}
+	return fs
+}
+
+func (l *Ledger) String() string {
+	fs := l.Faults()
+	l.mu.Lock()
+	refused := l.refused
+	l.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d faults injected, %d refused", len(fs), l.Max, refused)
+	for _, f := range fs {
+		fmt.Fprintf(&b, "\n\t%v", &f)
+	}
+	return b.String()
+}
+
+// Recover waits until HealBy, if set, heals the faults left, and fails t
+// unless steady returns nil within Within of the last fault healing. It
+// is retried with the virtual time doubling from 1ms between tries.
+func (l *Ledger) Recover(t testing.TB, steady func() error) {
+	t.Helper()
+	if d := l.HealBy - time.Since(l.start); l.HealBy > 0 && d > 0 {
+		time.Sleep(d)
+	}
+	l.healAll()
+	var last time.Duration
+	for _, f := range l.Faults() {
+		last = max(last, f.Healed)
+	}
+	deadline := l.start.Add(last + l.within())
+	wait := time.Millisecond
+	for {
+		err := steady()
+		if err == nil {
+			return
+		}
+		if time.Now().Add(wait).After(deadline) {
+			t.Errorf("chaos: not steady within %v of the last fault healing at +%v: %v", l.within(), last, err)
+			return
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
@@ -0,0 +1,78 @@
+package chaos
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/failpoint"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// service is down while its dependency is, and takes 2s to come back
+// after it.
+type service struct{ upAt atomic.Int64 }
+
+func (s *service) depDown() func() {
+	s.upAt.Store(1 << 62)
+	return func() { s.upAt.Store(time.Now().Add(2 * time.Second).UnixNano()) }
+}
+
+func (s *service) steady() error {
+	if time.Now().UnixNano() < s.upAt.Load() {
+		return errors.New("service unavailable")
+	}
+	return nil
+}
+
+func TestBudget(t *testing.T) {
+	synctest.Run(func() {
+		s := new(service)
+		l := Start(t, Budget{Max: 2, HealBy: time.Minute})
+		f := l.Inject("dependency down", s.depDown)
+		time.Sleep(10 * time.Second)
+		f.Heal()
+		l.Inject("dependency down", s.depDown)
+		if l.Inject("dependency down", s.depDown) != nil {
+			t.Error("fault beyond the budget injected")
+		}
+		l.Recover(t, s.steady)
+		if d := time.Since(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)); d < time.Minute+2*time.Second {
+			t.Errorf("steady after %v, want after the faults healed at 60s", d)
+		}
+		want := "2 of 2 faults injected, 1 refused\n\t+0s dependency down, healed at +10s\n\t+10s dependency down, healed at +1m0s"
+		if got := l.String(); got != want {
+			t.Errorf("ledger:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestPolicy(t *testing.T) {
+	l := Start(t, Budget{Max: 2})
+	failpoint.Enable(t, "chaos/write", l.Policy("write failed", failpoint.Fail()))
+	var failed int
+	for range 5 {
+		if failpoint.Inject("chaos/write") != nil {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Errorf("%d hits failed, want the budget of 2", failed)
+	}
+}
+
+func TestRecoverFails(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		synctest.Run(func() {
+			l := Start(t, Budget{Max: 1, Within: 5 * time.Second})
+			l.Inject("dependency down", func() func() { return func() {} })
+			l.Recover(t, func() error { return errors.New("split brain") })
+		})
+	})
+	want := "chaos: not steady within 5s of the last fault healing at +0s: split brain"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
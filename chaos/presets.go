@@ -0,0 +1,210 @@
+package chaos
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// Preset is a named profile of chaos: the conditions of the network, the
+// latency of the disks, and the faults to inject at random, or in turn.
+type Preset struct {
+	Name string
+	Link simnet.Conditions
+	Disk map[faultfs.Op]faultfs.Dist
+	// Disconnects is the mean time between connection resets of a random
+	// node, 0 for none.
+	Disconnects time.Duration
+	// Crashes is the mean time between crashes of a random node, which
+	// restarts after Down; 0 for none.
+	Crashes time.Duration
+	Down    time.Duration
+	// Rolling, if positive, restarts the nodes one by one, by name, every
+	// Rolling, each down for Down.
+	Rolling time.Duration
+}
+
+var (
+	// FlakyWifi is a network of jittery, lossy links that drop their
+	// connections now and then.
+	FlakyWifi = Preset{
+		Name: "flaky-wifi",
+		Link: simnet.Conditions{
+			Latency: faultfs.Tail(0.1, faultfs.Uniform(5*time.Millisecond, 30*time.Millisecond), faultfs.Uniform(200*time.Millisecond, time.Second)),
+			Loss:    0.05,
+		},
+		Disconnects: 20 * time.Second,
+	}
+	// DegradedDC is a data center having a bad day: links with a long
+	// tail of latency and a little loss, disks that stall, and nodes that
+	// crash and take a while to come back.
+	DegradedDC = Preset{
+		Name: "degraded-dc",
+		Link: simnet.Conditions{
+			Latency: faultfs.Tail(0.02, faultfs.Uniform(time.Millisecond, 3*time.Millisecond), faultfs.Uniform(50*time.Millisecond, 500*time.Millisecond)),
+			Loss:    0.01,
+		},
+		Disk: map[faultfs.Op]faultfs.Dist{
+			faultfs.Write: faultfs.Tail(0.05, faultfs.Uniform(100*time.Microsecond, time.Millisecond), faultfs.Uniform(100*time.Millisecond, time.Second)),
+			faultfs.Sync:  faultfs.Tail(0.05, faultfs.Uniform(time.Millisecond, 5*time.Millisecond), faultfs.Uniform(time.Second, 3*time.Second)),
+		},
+		Crashes: 30 * time.Second,
+		Down:    5 * time.Second,
+	}
+	// RollingRestart restarts every node in turn, as a deployment would.
+	RollingRestart = Preset{
+		Name:    "rolling-restart",
+		Rolling: 10 * time.Second,
+		Down:    3 * time.Second,
+	}
+)
+
+var presets = []Preset{FlakyWifi, DegradedDC, RollingRestart}
+
+// Lookup returns the preset called name.
+func Lookup(name string) (Preset, bool) {
+	for _, p := range presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Seed returns the seed of the caller's sched run, so that -synctest.seed
+// replays the faults of a failing run with its schedule, or else
+// sched.Seed.
+func Seed() uint64 {
+	if seed, ok := sched.BubbleSeed(); ok {
+		return seed
+	}
+	return sched.Seed()
+}
+
+// Run is RunSeed with Seed.
+func (p Preset) Run(t testing.TB, nw *simnet.Network, b Budget) *Ledger {
+	return p.RunSeed(t, nw, Seed(), b)
+}
+
+// RunSeed applies p to the network nw, and to the disks of its nodes,
+// from now until b.HealBy, default 1m, and returns the ledger of its
+// faults for Recover. The conditions are one fault of the budget, every
+// disconnect and crash another one; those beyond b.Max are refused.
+//
+// Which faults are injected, when and into which nodes, is the schedule
+// of seed, which is logged if t fails, and the latencies and losses of
+// the links are a function of seed as well; see simnet.Network.Seed. Those
+// of the disks follow the order of the operations on each.
+func (p Preset) RunSeed(t testing.TB, nw *simnet.Network, seed uint64, b Budget) *Ledger {
+	if b.HealBy <= 0 {
+		b.HealBy = time.Minute
+	}
+	l := Start(t, b)
+	nodes := nw.Nodes()
+	names := make([]string, len(nodes))
+	byName := make(map[string]*simnet.Node)
+	for i, n := range nodes {
+		names[i], byName[n.Name] = n.Name, n
+	}
+	evs := p.Schedule(seed, names, b.HealBy)
+	t.Cleanup(func() {
+		if t.Failed() {
+			var s strings.Builder
+			for _, ev := range evs {
+				fmt.Fprintf(&s, "\n\t%v", ev)
+			}
+			t.Logf("chaos: %s with seed %d, replay with -synctest.seed=%d; schedule:%s", p.Name, seed, seed, s.String())
+		}
+	})
+	l.Inject(p.Name+" conditions", func() func() {
+		nw.Seed(seed)
+		nw.SetConditions(p.Link)
+		for i, n := range nodes {
+			n.Disk.Seed(seed + uint64(i) + 1)
+			for op, d := range p.Disk {
+				n.Disk.Latency(op, d)
+			}
+		}
+		return func() {
+			nw.SetConditions(simnet.Conditions{})
+			for _, n := range nodes {
+				for op := range p.Disk {
+					n.Disk.Latency(op, nil)
+				}
+			}
+		}
+	})
+	start := time.Now()
+	go func() {
+		for _, ev := range evs {
+			time.Sleep(time.Until(start.Add(ev.At)))
+			n := byName[ev.Node]
+			switch ev.Kind {
+			case "disconnect":
+				l.Inject("disconnect "+n.Name, func() func() {
+					n.Disconnect()
+					return nil
+				})
+			case "crash":
+				f := l.Inject("crash "+n.Name, func() func() {
+					n.Crash()
+					return func() { n.Restart(nil) }
+				})
+				if f != nil {
+					time.AfterFunc(p.Down, f.Heal)
+				}
+			}
+		}
+	}()
+	return l
+}
+
+// Event is a fault of the schedule of a preset.
+type Event struct {
+	At   time.Duration // since the preset started
+	Kind string        // "disconnect" or "crash"
+	Node string
+}
+
+func (e Event) String() string { return fmt.Sprintf("+%v %s %s", e.At, e.Kind, e.Node) }
+
+// Schedule returns the faults p injects into the nodes until end, in the
+// order of their times, a function of seed only.
+func (p Preset) Schedule(seed uint64, nodes []string, end time.Duration) []Event {
+	if len(nodes) == 0 {
+		return nil
+	}
+	r := rand.New(rand.NewPCG(seed, 0))
+	var evs []Event
+	random := func(mean time.Duration, kind string) {
+		if mean <= 0 {
+			return
+		}
+		for at := time.Duration(0); ; {
+			at += time.Duration(r.ExpFloat64() * float64(mean))
+			if at >= end {
+				return
+			}
+			evs = append(evs, Event{at, kind, nodes[r.IntN(len(nodes))]})
+		}
+	}
+	random(p.Disconnects, "disconnect")
+	random(p.Crashes, "crash")
+	if p.Rolling > 0 {
+		for i, n := range nodes {
+			if at := time.Duration(i+1) * p.Rolling; at < end {
+				evs = append(evs, Event{at, "crash", n})
+			}
+		}
+	}
+	slices.SortStableFunc(evs, func(a, b Event) int { return cmp.Compare(a.At, b.At) })
+	return evs
+}
@@ -0,0 +1,131 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// echo serves on port 80 of its node, sending back what it reads.
+func echo(p *simnet.Proc) {
+	l, err := p.Listen("80")
+	if err != nil {
+		return
+	}
+	p.Go(func(context.Context) {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			p.Go(func(context.Context) {
+				defer c.Close()
+				buf := make([]byte, 64)
+				for {
+					n, err := c.Read(buf)
+					if err != nil {
+						return
+					}
+					c.Write(buf[:n])
+				}
+			})
+		}
+	})
+}
+
+func cluster(names ...string) *simnet.Network {
+	nw := simnet.New()
+	for _, n := range names {
+		nw.Add(n, echo)
+	}
+	return nw
+}
+
+// ping sends a message to node and waits for the echo.
+func ping(p *simnet.Proc, node string) error {
+	c, err := p.Dial(node + ":80")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if _, err := c.Write([]byte("ping")); err != nil {
+		return err
+	}
+	buf := make([]byte, 4)
+	if _, err := c.Read(buf); err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"flaky-wifi", "degraded-dc", "rolling-restart"} {
+		if p, ok := Lookup(name); !ok || p.Name != name {
+			t.Errorf("Lookup(%q) = %v, %v", name, p.Name, ok)
+		}
+	}
+	if _, ok := Lookup("sunny-day"); ok {
+		t.Error("found a preset that does not exist")
+	}
+}
+
+func TestRollingRestart(t *testing.T) {
+	synctest.Run(func() {
+		nw := cluster("a", "b", "c")
+		defer nw.Close()
+		l := RollingRestart.RunSeed(t, nw, 1, Budget{Max: 10, HealBy: 40 * time.Second})
+		start := time.Now()
+		var up []string
+		for i := range 5 {
+			time.Sleep(time.Until(start.Add(time.Duration(i+1)*10*time.Second + time.Millisecond)))
+			s := ""
+			for _, n := range nw.Nodes() {
+				s += map[bool]string{true: "+", false: "-"}[n.Up()] + n.Name
+			}
+			up = append(up, s)
+		}
+		if got := fmt.Sprint(up); got != "[-a+b+c +a-b+c +a+b-c +a+b+c +a+b+c]" {
+			t.Errorf("nodes up %s", got)
+		}
+		client := nw.Add("client", func(*simnet.Proc) {}).Proc()
+		l.Recover(t, func() error {
+			return errors.Join(ping(client, "a"), ping(client, "b"), ping(client, "c"))
+		})
+	})
+}
+
+func TestFlakyWifiReproducible(t *testing.T) {
+	type result struct {
+		ledger string
+		took   time.Duration
+	}
+	run := func(seed uint64) result {
+		res := make(chan result, 1)
+		synctest.Run(func() {
+			nw := cluster("a", "b")
+			defer nw.Close()
+			l := FlakyWifi.RunSeed(t, nw, seed, Budget{Max: 100, HealBy: 2 * time.Minute})
+			client := nw.Add("client", func(*simnet.Proc) {}).Proc()
+			start := time.Now()
+			for range 50 {
+				ping(client, "a")
+			}
+			took := time.Since(start)
+			time.Sleep(2 * time.Minute)
+			res <- result{l.String(), took}
+		})
+		return <-res
+	}
+	r1, r2 := run(7), run(7)
+	if r1 != r2 {
+		t.Errorf("seed 7 ran differently:\n%s after %v\n%s after %v", r1.ledger, r1.took, r2.ledger, r2.took)
+	}
+	if r1.took < 50*10*time.Millisecond {
+		t.Errorf("50 pings took %v on flaky wifi", r1.took)
+	}
+}
@@ -0,0 +1,66 @@
+package chaos
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+)
+
+// cleanupT runs the cleanups of a Recorder on demand and keeps its logs.
+type cleanupT struct {
+	*testtb.Recorder
+	cleanups []func()
+	logs     []string
+}
+
+func (t *cleanupT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *cleanupT) Logf(format string, args ...any) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+func (t *cleanupT) end() {
+	for _, f := range t.cleanups {
+		f()
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	nodes := []string{"a", "b", "c"}
+	s1, s2 := DegradedDC.Schedule(3, nodes, 5*time.Minute), DegradedDC.Schedule(3, nodes, 5*time.Minute)
+	if len(s1) == 0 || !reflect.DeepEqual(s1, s2) {
+		t.Errorf("schedules of seed 3 differ or are empty:\n%v\n%v", s1, s2)
+	}
+	if reflect.DeepEqual(s1, DegradedDC.Schedule(4, nodes, 5*time.Minute)) {
+		t.Error("seeds 3 and 4 have the same schedule")
+	}
+}
+
+func TestRunSeededByBubble(t *testing.T) {
+	ft := &cleanupT{Recorder: testtb.New(t)}
+	var faults []string
+	sched.RunSeed(t, 9, func() {
+		nw := cluster("a", "b")
+		defer nw.Close()
+		l := FlakyWifi.Run(ft, nw, Budget{Max: 100})
+		time.Sleep(time.Minute)
+		for _, f := range l.Faults()[1:] {
+			faults = append(faults, fmt.Sprintf("+%v %s", f.At, f.What))
+		}
+	})
+	var want []string
+	for _, ev := range FlakyWifi.Schedule(9, []string{"a", "b"}, time.Minute) {
+		want = append(want, ev.String())
+	}
+	if len(want) == 0 || !reflect.DeepEqual(faults, want) {
+		t.Errorf("faults injected:\n%v\nwant the schedule of seed 9:\n%v", faults, want)
+	}
+	ft.Error("fail")
+	ft.end()
+	if len(ft.logs) != 2 || !strings.HasPrefix(ft.logs[1], "chaos: flaky-wifi with seed 9, replay with -synctest.seed=9; schedule:\n\t"+want[0]) {
+		t.Errorf("logged %q, want the ledger and the schedule", ft.logs)
+	}
+}
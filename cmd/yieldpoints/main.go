@@ -0,0 +1,147 @@
+// Command yieldpoints instruments packages with the preemption points of
+// package sched, for test builds: it writes copies of their files with a
+// call of sched.Yield before every statement accessing shared memory,
+// see package instrument, and an overlay file for the go command to build
+// them in place of the originals. It prints the path of the overlay:
+//
+//	go test -overlay=$(yieldpoints ./store/...) ./store/...
+//
+// The files are written to the directory of -o, or else a new temporary
+// one. With -tests the test files of the packages are instrumented as
+// well. Package sched and the packages it imports cannot be instrumented.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched/instrument"
+)
+
+// pkg is what go list tells of a package.
+type pkg struct {
+	ImportPath                         string
+	Dir                                string
+	GoFiles, TestGoFiles, XTestGoFiles []string
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("yieldpoints: ")
+	out := flag.String("o", "", "directory to write the instrumented files and the overlay to")
+	tests := flag.Bool("tests", false, "instrument the test files as well")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: yieldpoints [-o dir] [-tests] packages\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		dir, err := os.MkdirTemp("", "yieldpoints")
+		if err != nil {
+			log.Fatal(err)
+		}
+		*out = dir
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	deps, err := list("-deps", instrument.SchedPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	excluded := make(map[string]bool)
+	for _, p := range deps {
+		excluded[p.ImportPath] = true
+	}
+	pkgs, err := list(flag.Args()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	replace := make(map[string]string)
+	for _, p := range pkgs {
+		if excluded[p.ImportPath] {
+			log.Fatalf("%s: package sched depends on it", p.ImportPath)
+		}
+		groups := [][]string{p.GoFiles}
+		if *tests {
+			groups = [][]string{append(p.GoFiles, p.TestGoFiles...), p.XTestGoFiles}
+		}
+		for _, names := range groups {
+			if err := instrumentFiles(p, names, *out, replace); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	overlay, err := json.MarshalIndent(struct{ Replace map[string]string }{replace}, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := filepath.Join(*out, "overlay.json")
+	if err := os.WriteFile(path, overlay, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(path)
+}
+
+// instrumentFiles instruments the files of p named names, which make up
+// one package, into dir and adds them to replace.
+func instrumentFiles(p pkg, names []string, dir string, replace map[string]string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(p.Dir, name)
+	}
+	files, err := instrument.Files(paths)
+	if err != nil {
+		return err
+	}
+	pdir := filepath.Join(dir, strings.ReplaceAll(p.ImportPath, "/", "_"))
+	if err := os.MkdirAll(pdir, 0o755); err != nil {
+		return err
+	}
+	for path, src := range files {
+		to, err := filepath.Abs(filepath.Join(pdir, filepath.Base(path)))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(to, src, 0o644); err != nil {
+			return err
+		}
+		replace[path] = to
+	}
+	return nil
+}
+
+// list runs go list -json with args.
+func list(args ...string) ([]pkg, error) {
+	cmd := exec.Command("go", append([]string{"list", "-json"}, args...)...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %v", err)
+	}
+	var pkgs []pkg
+	for d := json.NewDecoder(bytes.NewReader(out)); ; {
+		var p pkg
+		if err := d.Decode(&p); err == io.EOF {
+			return pkgs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+}
@@ -0,0 +1,267 @@
+// Package crdtcheck checks that the replicas of a CRDT converge: it
+// applies random operations to replicas, delivers the messages they send
+// each other in a random order, seeded, possibly delivering messages
+// twice and partitioning the replicas for a while, and once every message
+// is delivered after the partitions heal, it checks that every replica
+// has the same value. A run whose replicas diverge is shrunk to a minimal
+// set of operations that still makes them diverge, under one of a few
+// delivery orders.
+//
+// Runs do not need a bubble: replicas are plain values the harness calls
+// from a single goroutine, and a run is a function of its operations and
+// the seed of its schedule, so Converges replays it.
+package crdtcheck
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/causal"
+)
+
+// Replica is a replica of a CRDT, with operations of type O and messages
+// of type M, whole states for a state-based CRDT or the effects of
+// operations for an operation-based one.
+type Replica[O, M any] interface {
+	// Apply performs op on the replica and returns the message to send to
+	// every other replica.
+	Apply(op O) M
+	// Receive merges a message of another replica.
+	Receive(m M)
+	// Value returns what must converge, compared formatted with %v.
+	Value() any
+}
+
+// CRDT describes the CRDT under test.
+type CRDT[O, M any] struct {
+	Name string
+	// New returns a fresh replica named id.
+	New func(id string) Replica[O, M]
+	// Gen draws an operation.
+	Gen func(r *rand.Rand) O
+}
+
+// Config sizes the runs of Check and the schedules of their messages.
+type Config struct {
+	Seed     uint64 // runs are seeded with Seed and their run number
+	Runs     int    // default 100
+	Replicas int    // "r1" to "rN", default 3
+	Ops      int    // operations per run, up to; default 10
+	// Causal delivers the messages in causal order, as operation-based
+	// CRDTs commonly require; otherwise in any order.
+	Causal bool
+	// Duplicates delivers messages twice, sometimes.
+	Duplicates bool
+	// Partitions splits the replicas in two, sometimes, while operations
+	// remain, until a heal.
+	Partitions bool
+}
+
+func (cfg Config) runs() int {
+	if cfg.Runs > 0 {
+		return cfg.Runs
+	}
+	return 100
+}
+
+func (cfg Config) replicas() []string {
+	n := cfg.Replicas
+	if n <= 0 {
+		n = 3
+	}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("r%d", i+1)
+	}
+	return names
+}
+
+func (cfg Config) ops() int {
+	if cfg.Ops > 0 {
+		return cfg.Ops
+	}
+	return 10
+}
+
+// Update is an operation on a replica.
+type Update[O any] struct {
+	Replica string
+	Op      O
+}
+
+func (u Update[O]) String() string { return fmt.Sprintf("%s %v", u.Replica, u.Op) }
+
+// Divergence is a run whose replicas did not converge.
+type Divergence[O any] struct {
+	Ops    []Update[O]
+	Seed   uint64   // of the schedule
+	Trace  []string // the operations, the arrivals of their messages, partitions and heals
+	Values []string // of the replicas, formatted, by replica
+}
+
+func (d *Divergence[O]) Error() string {
+	return fmt.Sprintf("replicas diverged after %v, schedule seed %d:\n\t%s\nvalues:\n\t%s",
+		d.Ops, d.Seed, strings.Join(d.Trace, "\n\t"), strings.Join(d.Values, "\n\t"))
+}
+
+// flight is a message on its way to a replica.
+type flight[M any] struct {
+	from, to string
+	op       string // that sent it, formatted
+	m        causal.Message[M]
+}
+
+// Converges applies ops to fresh replicas of c, delivering their messages
+// as the schedule seed draws, and returns how they diverged, if they did.
+func Converges[O, M any](c CRDT[O, M], cfg Config, ops []Update[O], seed uint64) *Divergence[O] {
+	r := rand.New(rand.NewPCG(seed, 1))
+	names := cfg.replicas()
+	replicas := make(map[string]Replica[O, M])
+	deliveries := make(map[string]*causal.Delivery[M])
+	for _, n := range names {
+		replicas[n], deliveries[n] = c.New(n), causal.NewDelivery[M](n)
+	}
+	var trace []string
+	var inflight []flight[M]
+	var side map[string]bool // of the replicas partitioned from the others, nil if none
+	reachable := func(f flight[M]) bool { return side == nil || side[f.from] == side[f.to] }
+	for next := 0; next < len(ops) || len(inflight) > 0; {
+		if cfg.Partitions && next < len(ops) && r.IntN(10) == 0 {
+			if side == nil {
+				side = make(map[string]bool)
+				for _, n := range names[:len(names)-1] {
+					side[n] = r.IntN(2) == 0
+				}
+				side[names[0]] = true // and the last replica is on the other side
+				var a, b []string
+				for _, n := range names {
+					if side[n] {
+						a = append(a, n)
+					} else {
+						b = append(b, n)
+					}
+				}
+				trace = append(trace, fmt.Sprintf("partition %s | %s", strings.Join(a, " "), strings.Join(b, " ")))
+			} else {
+				side = nil
+				trace = append(trace, "heal")
+			}
+		}
+		var ready []int
+		for i, f := range inflight {
+			if reachable(f) {
+				ready = append(ready, i)
+			}
+		}
+		switch {
+		case next < len(ops) && (len(ready) == 0 || r.IntN(2) == 0):
+			u := ops[next]
+			next++
+			rep, ok := replicas[u.Replica]
+			if !ok {
+				continue
+			}
+			m := deliveries[u.Replica].Send(rep.Apply(u.Op))
+			trace = append(trace, u.String())
+			for _, n := range names {
+				if n != u.Replica {
+					inflight = append(inflight, flight[M]{u.Replica, n, fmt.Sprint(u.Op), m})
+				}
+			}
+		case len(ready) > 0:
+			i := ready[r.IntN(len(ready))]
+			f := inflight[i]
+			if !cfg.Duplicates || r.IntN(4) != 0 {
+				inflight = slices.Delete(inflight, i, i+1)
+			}
+			trace = append(trace, fmt.Sprintf("%s → %s %s", f.from, f.to, f.op))
+			if !cfg.Causal {
+				replicas[f.to].Receive(f.m.Body)
+				continue
+			}
+			for _, m := range deliveries[f.to].Receive(f.m) {
+				replicas[f.to].Receive(m.Body)
+			}
+		default:
+			side = nil
+			trace = append(trace, "heal")
+		}
+	}
+	values := make([]string, len(names))
+	for i, n := range names {
+		values[i] = fmt.Sprintf("%s = %v", n, replicas[n].Value())
+	}
+	for _, n := range names[1:] {
+		if fmt.Sprint(replicas[n].Value()) != fmt.Sprint(replicas[names[0]].Value()) {
+			return &Divergence[O]{Ops: ops, Seed: seed, Trace: trace, Values: values}
+		}
+	}
+	return nil
+}
+
+// Gen draws up to cfg.Ops operations of c on random replicas.
+func Gen[O, M any](c CRDT[O, M], cfg Config, r *rand.Rand) []Update[O] {
+	names := cfg.replicas()
+	ops := make([]Update[O], 1+r.IntN(cfg.ops()))
+	for i := range ops {
+		ops[i] = Update[O]{names[r.IntN(len(names))], c.Gen(r)}
+	}
+	return ops
+}
+
+// Check runs cfg.Runs random runs of c. At the first whose replicas
+// diverge, the operations are shrunk and t fails with the minimal ones,
+// the schedule they diverge under and the seed and run that generated the
+// original ones.
+func Check[O, M any](t testing.TB, c CRDT[O, M], cfg Config) {
+	t.Helper()
+	for run := range uint64(cfg.runs()) {
+		r := rand.New(rand.NewPCG(cfg.Seed, run))
+		ops := Gen(c, cfg, r)
+		if d := Converges(c, cfg, ops, r.Uint64()); d != nil {
+			t.Errorf("crdtcheck: %s: seed %d run %d, shrunk from %d operations: %v", c.Name, cfg.Seed, run, len(d.Ops), Shrink(c, cfg, d))
+			return
+		}
+	}
+}
+
+// Schedules of a shrinking candidate that are tried before it is
+// dropped, starting with the seed of the divergence shrunk, and bound on
+// the candidates.
+const (
+	shrinkSchedules = 32
+	maxShrinkRuns   = 2000
+)
+
+// Shrink minimizes the operations of d, dropping chunks of them and then
+// single ones while the replicas still diverge under one of a few
+// schedules, and returns the divergence of the smallest set found.
+func Shrink[O, M any](c CRDT[O, M], cfg Config, d *Divergence[O]) *Divergence[O] {
+	tries := 0
+	diverges := func(ops []Update[O]) *Divergence[O] {
+		for k := range uint64(shrinkSchedules) {
+			tries++
+			if e := Converges(c, cfg, ops, d.Seed+k); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+	for improved := true; improved && tries < maxShrinkRuns; {
+		improved = false
+	chunks:
+		for size := len(d.Ops) / 2; size >= 1; size /= 2 {
+			for i := 0; i+size <= len(d.Ops); i += size {
+				ops := slices.Delete(slices.Clone(d.Ops), i, i+size)
+				if e := diverges(ops); e != nil {
+					d, improved = e, true
+					break chunks
+				}
+			}
+		}
+	}
+	return d
+}
@@ -0,0 +1,199 @@
+package crdtcheck
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// gcounter is a state-based grow-only counter: the count of increments of
+// each replica, merged by maximum.
+type gcounter struct {
+	id     string
+	counts map[string]int
+}
+
+func (g *gcounter) Apply(struct{}) map[string]int {
+	g.counts[g.id]++
+	return maps.Clone(g.counts)
+}
+
+func (g *gcounter) Receive(m map[string]int) {
+	for id, n := range m {
+		g.counts[id] = max(g.counts[id], n)
+	}
+}
+
+func (g *gcounter) Value() any {
+	sum := 0
+	for _, n := range g.counts {
+		sum += n
+	}
+	return sum
+}
+
+var gcounterCRDT = CRDT[struct{}, map[string]int]{
+	Name: "gcounter",
+	New:  func(id string) Replica[struct{}, map[string]int] { return &gcounter{id, make(map[string]int)} },
+	Gen:  func(*rand.Rand) struct{} { return struct{}{} },
+}
+
+// counter is an operation-based counter: increments are sent as such.
+type counter int
+
+func (c *counter) Apply(d int) int { *c += counter(d); return d }
+func (c *counter) Receive(d int)   { *c += counter(d) }
+func (c *counter) Value() any      { return int(*c) }
+
+var counterCRDT = CRDT[int, int]{
+	Name: "counter",
+	New:  func(string) Replica[int, int] { return new(counter) },
+	Gen:  func(r *rand.Rand) int { return 1 + r.IntN(3) },
+}
+
+// setOp adds or removes an element of a set.
+type setOp struct {
+	Add  bool
+	Elem string
+}
+
+func (o setOp) String() string {
+	if o.Add {
+		return fmt.Sprintf("add(%s)", o.Elem)
+	}
+	return fmt.Sprintf("remove(%s)", o.Elem)
+}
+
+func genSetOp(r *rand.Rand) setOp { return setOp{r.IntN(2) == 0, []string{"x", "y"}[r.IntN(2)]} }
+
+// orSet is an observed-remove set: an add tags the element uniquely, and
+// a remove removes the tags its replica observed.
+type orSet struct {
+	id   string
+	n    int
+	tags map[string]map[string]bool // by element
+}
+
+// orSetMsg adds the element with the tag, or removes it with the tags.
+type orSetMsg struct {
+	Elem string
+	Add  string
+	Rm   []string
+}
+
+func (s *orSet) Apply(op setOp) orSetMsg {
+	m := orSetMsg{Elem: op.Elem}
+	if op.Add {
+		s.n++
+		m.Add = fmt.Sprintf("%s.%d", s.id, s.n)
+	} else {
+		m.Rm = slices.Sorted(maps.Keys(s.tags[op.Elem]))
+	}
+	s.Receive(m)
+	return m
+}
+
+func (s *orSet) Receive(m orSetMsg) {
+	if s.tags[m.Elem] == nil {
+		s.tags[m.Elem] = make(map[string]bool)
+	}
+	if m.Add != "" {
+		s.tags[m.Elem][m.Add] = true
+	}
+	for _, tag := range m.Rm {
+		delete(s.tags[m.Elem], tag)
+	}
+}
+
+func (s *orSet) Value() any {
+	var elems []string
+	for e, tags := range s.tags {
+		if len(tags) > 0 {
+			elems = append(elems, e)
+		}
+	}
+	slices.Sort(elems)
+	return elems
+}
+
+var orSetCRDT = CRDT[setOp, orSetMsg]{
+	Name: "orset",
+	New: func(id string) Replica[setOp, orSetMsg] {
+		return &orSet{id: id, tags: make(map[string]map[string]bool)}
+	},
+	Gen: genSetOp,
+}
+
+// set is a naive set whose adds and removes are sent as such.
+type set map[string]bool
+
+func (s set) Apply(op setOp) setOp { s.Receive(op); return op }
+func (s set) Receive(op setOp) {
+	if op.Add {
+		s[op.Elem] = true
+	} else {
+		delete(s, op.Elem)
+	}
+}
+func (s set) Value() any { return slices.Sorted(maps.Keys(s)) }
+
+var setCRDT = CRDT[setOp, setOp]{
+	Name: "set",
+	New:  func(string) Replica[setOp, setOp] { return make(set) },
+	Gen:  genSetOp,
+}
+
+func TestConverge(t *testing.T) {
+	Check(t, gcounterCRDT, Config{Seed: 1, Replicas: 4, Duplicates: true, Partitions: true})
+	Check(t, counterCRDT, Config{Seed: 1, Partitions: true})
+	Check(t, orSetCRDT, Config{Seed: 1, Ops: 20, Causal: true, Duplicates: true, Partitions: true})
+}
+
+// diverging checks that check fails once, with a divergence after the
+// operations matched by ops.
+func diverging(t *testing.T, ops string, check func(t testing.TB)) {
+	t.Helper()
+	errs := testtb.Run(t, check)
+	re := regexp.MustCompile(`, shrunk from \d+ operations: replicas diverged after \[` + ops + `\], schedule seed \d+:\n`)
+	if len(errs) != 1 || !re.MatchString(errs[0]) {
+		t.Errorf("errors %q, want one diverging after %s", errs, ops)
+	}
+}
+
+func TestDiverge(t *testing.T) {
+	// Increments delivered twice are counted twice.
+	diverging(t, `r\d \d`, func(t testing.TB) {
+		Check(t, counterCRDT, Config{Seed: 1, Duplicates: true})
+	})
+	// An add and a remove of the same element, concurrent, end in the
+	// order they arrive.
+	diverging(t, `r\d (add|remove)\((\w)\) r\d (add|remove)\(\w\)`, func(t testing.TB) {
+		Check(t, setCRDT, Config{Seed: 1, Causal: true})
+	})
+	// A remove arriving before the add it observed does not remove it.
+	diverging(t, `r\d add\((\w)\) r\d remove\(\w\)`, func(t testing.TB) {
+		Check(t, orSetCRDT, Config{Seed: 1})
+	})
+}
+
+func TestReplay(t *testing.T) {
+	ops := []Update[setOp]{{"r1", setOp{true, "x"}}, {"r2", setOp{false, "x"}}}
+	var d *Divergence[setOp]
+	for seed := range uint64(100) {
+		if d = Converges(setCRDT, Config{Replicas: 2}, ops, seed); d != nil {
+			break
+		}
+	}
+	if d == nil {
+		t.Fatal("an add and a remove on different replicas never diverged")
+	}
+	e := Converges(setCRDT, Config{Replicas: 2}, d.Ops, d.Seed)
+	if e == nil || e.Error() != d.Error() {
+		t.Errorf("replaying\n%v\ngave\n%v", d, e)
+	}
+}
@@ -0,0 +1,222 @@
+// Package delivery verifies the delivery guarantees of messaging over
+// simulated transports end to end: a Tracker tags every logical message
+// with an ID, records what becomes of it, from its sending and its
+// retransmissions to its arrivals and the times the receiver applies it,
+// and checks, once a scenario is over, the guarantee it was configured
+// with: at least once, no message lost; at most once, no message applied
+// twice; exactly once, both. A message that breaks the guarantee is
+// reported with its audit trail, every event of it with its virtual time.
+//
+// Mailbox is a simnet mailbox that carries the IDs in its frames and
+// records the sends and arrivals; the receiver reports applying a message
+// with Applied, as only it knows whether it applies one it received again.
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Guarantee is a delivery guarantee.
+type Guarantee int
+
+const (
+	AtMostOnce  Guarantee = iota // no message applied twice
+	AtLeastOnce                  // no message lost
+	ExactlyOnce                  // both
+)
+
+func (g Guarantee) String() string {
+	switch g {
+	case AtMostOnce:
+		return "at-most-once"
+	case AtLeastOnce:
+		return "at-least-once"
+	case ExactlyOnce:
+		return "exactly-once"
+	}
+	return fmt.Sprintf("Guarantee(%d)", int(g))
+}
+
+// Kind is the kind of an event of a message.
+type Kind int
+
+const (
+	Sent     Kind = iota // first transmission
+	Resent               // retransmission
+	Dropped              // a transmission or arrival a full queue refused
+	Received             // an arrival
+	Applied              // the receiver applied it
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Sent:
+		return "sent"
+	case Resent:
+		return "resent"
+	case Dropped:
+		return "dropped"
+	case Received:
+		return "received"
+	case Applied:
+		return "applied"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Event is an event of the audit trail of a message.
+type Event struct {
+	At   time.Duration // since the tracker was created
+	Node string        // where it happened
+	Kind Kind
+}
+
+// Trail is the audit trail of a message.
+type Trail struct {
+	ID       string
+	From, To string // empty if the message was never sent
+	Events   []Event
+}
+
+// count returns the events of t of kind k at node.
+func (t *Trail) count(k Kind, node string) int {
+	n := 0
+	for _, e := range t.Events {
+		if e.Kind == k && e.Node == node {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *Trail) String() string {
+	var b strings.Builder
+	if t.From == "" {
+		fmt.Fprintf(&b, "%s, never sent", t.ID)
+	} else {
+		fmt.Fprintf(&b, "%s from %s to %s", t.ID, t.From, t.To)
+	}
+	for _, e := range t.Events {
+		fmt.Fprintf(&b, "\n\t+%v %s %s", e.At, e.Node, e.Kind)
+	}
+	return b.String()
+}
+
+// Tracker tags logical messages and records their trails. It is safe for
+// concurrent use.
+type Tracker struct {
+	g  Guarantee
+	t0 time.Time
+
+	mu     sync.Mutex
+	n      int // messages tagged
+	trails map[string]*Trail
+	order  []string // of the IDs, as they were first seen
+}
+
+// New returns a tracker checking the guarantee g, from now.
+func New(g Guarantee) *Tracker {
+	return &Tracker{g: g, t0: time.Now(), trails: make(map[string]*Trail)}
+}
+
+// Tag returns the ID of a new logical message from the node from to the
+// node to, and records its sending.
+func (tr *Tracker) Tag(from, to string) string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.n++
+	id := fmt.Sprintf("m%d", tr.n)
+	tr.trails[id] = &Trail{ID: id, From: from, To: to}
+	tr.order = append(tr.order, id)
+	tr.record(id, from, Sent)
+	return id
+}
+
+// Record records an event of the message id at node.
+func (tr *Tracker) Record(id, node string, k Kind) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.record(id, node, k)
+}
+
+// record records an event, with tr.mu held.
+func (tr *Tracker) record(id, node string, k Kind) {
+	t := tr.trails[id]
+	if t == nil {
+		t = &Trail{ID: id}
+		tr.trails[id] = t
+		tr.order = append(tr.order, id)
+	}
+	t.Events = append(t.Events, Event{time.Since(tr.t0), node, k})
+}
+
+// Trail returns the trail of the message id, if it was seen.
+func (tr *Tracker) Trail(id string) (Trail, bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	t := tr.trails[id]
+	if t == nil {
+		return Trail{}, false
+	}
+	c := *t
+	c.Events = append([]Event(nil), t.Events...)
+	return c, true
+}
+
+// Verify checks every message seen against the guarantee of tr: that it
+// was sent, applied only at its destination, there at least once unless
+// the guarantee is at most once, and at most once unless it is at least
+// once. It returns the violations, with the trails, joined; nil if there
+// are none. It is meant for the end of a scenario, once every message
+// had the time to be applied.
+func (tr *Tracker) Verify() error { return errors.Join(tr.violations()...) }
+
+func (tr *Tracker) violations() []error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	var errs []error
+	for _, id := range tr.order {
+		t := tr.trails[id]
+		applied := t.count(Applied, t.To)
+		var what string
+		switch {
+		case t.From == "":
+			errs = append(errs, fmt.Errorf("delivery: %v", t))
+			continue
+		case t.elsewhere() != "":
+			what = "applied at " + t.elsewhere()
+		case applied == 0 && tr.g != AtMostOnce:
+			what = "not applied"
+		case applied > 1 && tr.g != AtLeastOnce:
+			what = fmt.Sprintf("applied %d times", applied)
+		default:
+			continue
+		}
+		errs = append(errs, fmt.Errorf("delivery: %s under %v: %v", what, tr.g, t))
+	}
+	return errs
+}
+
+// elsewhere returns a node other than its destination that applied the
+// message of t, if one did.
+func (t *Trail) elsewhere() string {
+	for _, e := range t.Events {
+		if e.Kind == Applied && e.Node != t.To {
+			return e.Node
+		}
+	}
+	return ""
+}
+
+// Check fails t with every violation of the guarantee of tr; see Verify.
+func (tr *Tracker) Check(t testing.TB) {
+	t.Helper()
+	for _, err := range tr.violations() {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,168 @@
+package delivery
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// scenario is how the nodes of run behave.
+type scenario struct {
+	retry     time.Duration // how often n1 resends until acknowledged, 0 for never
+	dedup     bool          // whether n2 applies messages once only
+	partition bool          // whether n1 and n2 are partitioned for the first second
+}
+
+// run has n1 send three messages to n2, over links of 60ms, and returns
+// the tracker once the scenario settled.
+func run(g Guarantee, sc scenario) *Tracker {
+	var tr *Tracker
+	synctest.Run(func() {
+		tr = New(g)
+		nw := simnet.New()
+		defer nw.Close()
+		nw.SetConditions(simnet.Conditions{Latency: faultfs.Constant(60 * time.Millisecond)})
+		nw.Add("n1", func(p *simnet.Proc) {
+			mb, err := Open(p, "msg", tr)
+			if err != nil {
+				return
+			}
+			acks, err := p.Mailbox("ack")
+			if err != nil {
+				return
+			}
+			p.Go(func(ctx context.Context) {
+				pending := make(map[string][]byte)
+				for _, data := range []string{"a", "b", "c"} {
+					id, _ := mb.Send("n2", []byte(data))
+					pending[id] = []byte(data)
+				}
+				var resend <-chan time.Time
+				if sc.retry > 0 {
+					tick := time.NewTicker(sc.retry)
+					defer tick.Stop()
+					resend = tick.C
+				}
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case ack := <-acks.Inbox():
+						delete(pending, string(ack.Data))
+					case <-resend:
+						for _, id := range []string{"m1", "m2", "m3"} {
+							if data, ok := pending[id]; ok {
+								mb.Resend("n2", id, data)
+							}
+						}
+					}
+				}
+			})
+		})
+		nw.Add("n2", func(p *simnet.Proc) {
+			mb, err := Open(p, "msg", tr)
+			if err != nil {
+				return
+			}
+			acks, err := p.Mailbox("ack")
+			if err != nil {
+				return
+			}
+			p.Go(func(ctx context.Context) {
+				seen := make(map[string]bool)
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case m := <-mb.Inbox():
+						if !sc.dedup || !seen[m.ID] {
+							seen[m.ID] = true
+							mb.Applied(m.ID)
+						}
+						acks.Send(m.From, []byte(m.ID))
+					}
+				}
+			})
+		})
+		if sc.partition {
+			nw.Partition([]string{"n1"})
+			time.Sleep(time.Second)
+			nw.Heal()
+		}
+		time.Sleep(5 * time.Second)
+	})
+	return tr
+}
+
+// violations returns the violations of tr, one by one.
+func violations(tr *Tracker) []string {
+	err := tr.Verify()
+	if err == nil {
+		return nil
+	}
+	return strings.Split(err.Error(), "\ndelivery: ")
+}
+
+func TestExactlyOnce(t *testing.T) {
+	tr := run(ExactlyOnce, scenario{retry: 100 * time.Millisecond, dedup: true})
+	tr.Check(t)
+	if trail, ok := tr.Trail("m1"); !ok || trail.count(Resent, "n1") == 0 || trail.count(Received, "n2") < 2 {
+		t.Errorf("m1 was not received twice: %v", &trail)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	// The acknowledgements come back after 120ms at best, the
+	// retransmissions leave after 100ms.
+	sc := scenario{retry: 100 * time.Millisecond}
+	if errs := violations(run(AtLeastOnce, sc)); len(errs) > 0 {
+		t.Errorf("at least once: %q", errs)
+	}
+	errs := violations(run(ExactlyOnce, sc))
+	want := "delivery: applied 2 times under exactly-once: m1 from n1 to n2\n" +
+		"\t+0s n1 sent\n" +
+		"\t+60ms n2 received\n" +
+		"\t+60ms n2 applied\n" +
+		"\t+100ms n1 resent\n" +
+		"\t+240ms n2 received\n" +
+		"\t+240ms n2 applied"
+	if len(errs) != 3 || errs[0] != want {
+		t.Errorf("messages applied twice reported:\n%s\nwant 3 violations, the first:\n%s", strings.Join(errs, "\n"), want)
+	}
+}
+
+func TestLost(t *testing.T) {
+	sc := scenario{partition: true}
+	if errs := violations(run(AtMostOnce, sc)); len(errs) > 0 {
+		t.Errorf("at most once: %q", errs)
+	}
+	errs := violations(run(AtLeastOnce, sc))
+	want := "delivery: not applied under at-least-once: m1 from n1 to n2\n\t+0s n1 sent"
+	if len(errs) != 3 || errs[0] != want {
+		t.Errorf("messages lost to a partition reported:\n%s\nwant 3 violations, the first:\n%s", strings.Join(errs, "\n"), want)
+	}
+}
+
+func TestStray(t *testing.T) {
+	var tr *Tracker
+	synctest.Run(func() {
+		tr = New(ExactlyOnce)
+		id := tr.Tag("n1", "n2")
+		time.Sleep(time.Millisecond)
+		tr.Record(id, "n2", Received)
+		tr.Record(id, "n3", Applied)
+		tr.Record("m9", "n2", Applied)
+	})
+	want := []string{
+		"delivery: applied at n3 under exactly-once: m1 from n1 to n2\n\t+0s n1 sent\n\t+1ms n2 received\n\t+1ms n3 applied",
+		"m9, never sent\n\t+1ms n2 applied",
+	}
+	if errs := violations(tr); strings.Join(errs, "|") != strings.Join(want, "|") {
+		t.Errorf("stray applications reported:\n%s\nwant:\n%s", strings.Join(errs, "\n"), strings.Join(want, "\n"))
+	}
+}
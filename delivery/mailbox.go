@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// Message is a message received by a Mailbox.
+type Message struct {
+	From string // node
+	ID   string // of the logical message, the same for its retransmissions
+	Data []byte
+}
+
+// Mailbox is a simnet.Mailbox whose frames carry the IDs of the logical
+// messages a Tracker tags, and which records their transmissions and
+// arrivals to it.
+type Mailbox struct {
+	tr    *Tracker
+	name  string
+	m     *simnet.Mailbox
+	inbox chan Message
+}
+
+// Open listens on port and returns the mailbox of p there, recording to
+// tr.
+func Open(p *simnet.Proc, port string, tr *Tracker) (*Mailbox, error) {
+	m, err := p.Mailbox(port)
+	if err != nil {
+		return nil, err
+	}
+	mb := &Mailbox{tr: tr, name: p.Node().Name, m: m, inbox: make(chan Message, cap(m.Inbox()))}
+	p.Go(func(ctx context.Context) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case in := <-m.Inbox():
+				n, k := binary.Uvarint(in.Data)
+				if k <= 0 || uint64(len(in.Data)-k) < n {
+					continue
+				}
+				id := string(in.Data[k : k+int(n)])
+				tr.Record(id, mb.name, Received)
+				select {
+				case mb.inbox <- Message{in.From, id, in.Data[k+int(n):]}:
+				default:
+					tr.Record(id, mb.name, Dropped)
+				}
+			}
+		}
+	})
+	return mb, nil
+}
+
+// Inbox returns the messages received, retransmissions included, in a
+// channel never closed.
+func (mb *Mailbox) Inbox() <-chan Message { return mb.inbox }
+
+// Send sends data to the node to as a new logical message and returns
+// its ID, and whether it was queued.
+func (mb *Mailbox) Send(to string, data []byte) (id string, ok bool) {
+	id = mb.tr.Tag(mb.name, to)
+	return id, mb.send(to, id, data)
+}
+
+// Resend sends data to the node to again, as the logical message id.
+func (mb *Mailbox) Resend(to, id string, data []byte) bool {
+	mb.tr.Record(id, mb.name, Resent)
+	return mb.send(to, id, data)
+}
+
+func (mb *Mailbox) send(to, id string, data []byte) bool {
+	frame := binary.AppendUvarint(nil, uint64(len(id)))
+	frame = append(append(frame, id...), data...)
+	if !mb.m.Send(to, frame) {
+		mb.tr.Record(id, mb.name, Dropped)
+		return false
+	}
+	return true
+}
+
+// Applied reports that the node applied the message id.
+func (mb *Mailbox) Applied(id string) { mb.tr.Record(id, mb.name, Applied) }
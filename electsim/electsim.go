@@ -0,0 +1,357 @@
+// Package electsim is a scenario kit for leader elections other than
+// Raft's, whether by leases a lock service grants or by a protocol of the
+// candidates' own: it runs N candidates on a simnet network in a synctest
+// bubble, with a lock service node if asked for, and checks, as the
+// candidates report taking and giving up leadership, that there is at
+// most one leader at any virtual instant and that a leader exists within
+// Config.Within of a failure.
+//
+// Faults injects crashes and partitions drawn from the seed of the
+// cluster, that of the caller's sched run by default, so that exploring
+// schedules with sched.ExploreRandom explores failures too, and a failing
+// run replays with its seed.
+package electsim
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// Ports of the candidates' mailboxes and of the lock service, which runs
+// on the node LockNode.
+const (
+	Port     = "elect"
+	LockPort = "lock"
+	LockNode = "lock"
+)
+
+// Start starts the candidate of env: it starts its goroutines with
+// env.Proc.Go and returns.
+type Start func(env *Env)
+
+// Env is what a candidate of a Cluster runs with, for an incarnation of
+// its process.
+type Env struct {
+	Name    string
+	Peers   []string // the other candidates, by name
+	Proc    *simnet.Proc
+	Mailbox *simnet.Mailbox // on Port, to exchange messages with the peers
+
+	c *Cluster
+}
+
+// Report reports that the candidate became leader, or stopped being one.
+// A leader must report stepping down before another can take over, e.g.
+// when its lease expires, even if it cannot reach anyone.
+func (e *Env) Report(leader bool) { e.c.report(e.Proc, leader) }
+
+// Acquire acquires or renews the lease key of the lock service for the
+// candidate and returns until when it holds it, or the holder if another
+// candidate does. The lease is counted from before the request was sent,
+// so it ends for the candidate before it does for the lock service.
+func (e *Env) Acquire(key string) (until time.Time, holder string, err error) {
+	start := time.Now()
+	ttl := e.c.cfg.LeaseTTL
+	if ttl <= 0 {
+		return time.Time{}, "", errors.New("electsim: no lock service")
+	}
+	c, err := e.Proc.Dial(net.JoinHostPort(LockNode, LockPort))
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	defer c.Close()
+	c.SetDeadline(start.Add(ttl))
+	fmt.Fprintf(c, "acquire %s %s\n", key, e.Name)
+	line, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	if holder = strings.TrimSpace(line); holder != e.Name {
+		return time.Time{}, holder, nil
+	}
+	return start.Add(ttl), holder, nil
+}
+
+// Config configures a Cluster.
+type Config struct {
+	Nodes int // candidates, default 3
+	// LeaseTTL is how long the leases of the lock service last, 0 for no
+	// lock service.
+	LeaseTTL time.Duration
+	// Within is how long after a failure a leader must exist, default
+	// 10s.
+	Within time.Duration
+	// Seed is the seed of the faults and of the network, default that of
+	// the caller's sched run, or else sched.Seed, logged if t fails.
+	Seed uint64
+}
+
+func (c Config) nodes() int {
+	if c.Nodes > 0 {
+		return c.Nodes
+	}
+	return 3
+}
+
+func (c Config) within() time.Duration {
+	if c.Within > 0 {
+		return c.Within
+	}
+	return 10 * time.Second
+}
+
+// Cluster is a cluster of candidates, "n1" to "nN". Whenever a candidate
+// reports becoming leader, the cluster fails t if another running one
+// leads, and Within after a failure injected with its methods, unless
+// another came since, it fails t if no candidate was elected in between
+// and none leads.
+type Cluster struct {
+	Net *simnet.Network
+
+	t     testing.TB
+	cfg   Config
+	start Start
+	t0    time.Time
+
+	mu      sync.Mutex
+	leaders map[string]*simnet.Proc // claiming leadership, by node
+	elected time.Time               // when a leader last reported
+	check   *time.Timer             // of the last failure
+	failed  bool
+}
+
+// New starts a cluster of cfg.Nodes candidates running start, and the
+// lock service if cfg.LeaseTTL is set, on a network of its own, in the
+// caller's bubble. Close it before the bubble ends.
+func New(t testing.TB, cfg Config, start Start) *Cluster {
+	if cfg.Seed == 0 {
+		cfg.Seed = sched.SeedFor(t, "electsim")
+	}
+	c := &Cluster{Net: simnet.New(), t: t, cfg: cfg, start: start, t0: time.Now(), leaders: make(map[string]*simnet.Proc)}
+	c.Net.Seed(cfg.Seed)
+	if cfg.LeaseTTL > 0 {
+		c.Net.Add(LockNode, c.lockService)
+	}
+	names := c.Names()
+	for _, name := range names {
+		peers := slices.DeleteFunc(slices.Clone(names), func(p string) bool { return p == name })
+		c.Net.Add(name, func(p *simnet.Proc) { c.boot(p, peers) })
+	}
+	return c
+}
+
+// Names returns the names of the candidates of c.
+func (c *Cluster) Names() []string {
+	names := make([]string, c.cfg.nodes())
+	for i := range names {
+		names[i] = fmt.Sprintf("n%d", i+1)
+	}
+	return names
+}
+
+func (c *Cluster) boot(p *simnet.Proc, peers []string) {
+	m, err := p.Mailbox(Port)
+	if err != nil {
+		return
+	}
+	c.start(&Env{Name: p.Node().Name, Peers: peers, Proc: p, Mailbox: m, c: c})
+}
+
+// Close stops checking c and crashes its nodes, for the end of a
+// scenario.
+func (c *Cluster) Close() {
+	c.mu.Lock()
+	if c.check != nil {
+		c.check.Stop()
+	}
+	c.mu.Unlock()
+	c.Net.Close()
+}
+
+// Node returns the node name of c.
+func (c *Cluster) Node(name string) *simnet.Node { return c.Net.Node(name) }
+
+// report records that the process p leads, or not, and checks it.
+func (c *Cluster) report(p *simnet.Proc, leader bool) {
+	name := p.Node().Name
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !leader {
+		if c.leaders[name] == p {
+			delete(c.leaders, name)
+		}
+		return
+	}
+	if others := c.leading(name); len(others) > 0 {
+		c.fail("%s and %s are both leaders at +%v", others[0], name, time.Since(c.t0))
+	}
+	c.leaders[name], c.elected = p, time.Now()
+}
+
+// leading returns the running candidates other than except that claim
+// leadership, by name, with c.mu held.
+func (c *Cluster) leading(except string) []string {
+	var names []string
+	for n, p := range c.leaders {
+		if n != except && p.Node().Proc() == p {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// fail fails c.t, the first time only, with c.mu held.
+func (c *Cluster) fail(format string, args ...any) {
+	if c.failed {
+		return
+	}
+	c.failed = true
+	c.t.Errorf("electsim: "+format, args...)
+}
+
+// Leader returns the running candidate that leads, if one does.
+func (c *Cluster) Leader() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ls := c.leading(""); len(ls) > 0 {
+		return ls[0], true
+	}
+	return "", false
+}
+
+// AwaitLeader waits until a running candidate leads and returns it, or
+// fails t after Config.Within.
+func (c *Cluster) AwaitLeader() string {
+	c.t.Helper()
+	deadline := time.Now().Add(c.cfg.within())
+	for {
+		if name, ok := c.Leader(); ok {
+			return name
+		}
+		if !time.Now().Before(deadline) {
+			c.t.Errorf("electsim: no leader within %v", c.cfg.within())
+			return ""
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// failure records the failure what, after which a leader must be
+// elected, or still lead, within Config.Within, unless another failure
+// comes first.
+func (c *Cluster) failure(what string) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.check != nil {
+		c.check.Stop()
+	}
+	c.check = time.AfterFunc(c.cfg.within(), func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.elected.Before(now) && len(c.leading("")) == 0 {
+			c.fail("no leader within %v of %s at +%v", c.cfg.within(), what, now.Sub(c.t0))
+		}
+	})
+}
+
+// Crash crashes the node name, a failure.
+func (c *Cluster) Crash(name string) {
+	c.Net.Node(name).Crash()
+	c.failure("crash " + name)
+}
+
+// Restart restarts the node name.
+func (c *Cluster) Restart(name string) { c.Net.Node(name).Restart(nil) }
+
+// Partition partitions the network, a failure; see simnet.Network.Partition.
+func (c *Cluster) Partition(groups ...[]string) {
+	c.Net.Partition(groups...)
+	c.failure(fmt.Sprintf("partition %v", groups))
+}
+
+// Heal ends the partition of the network.
+func (c *Cluster) Heal() { c.Net.Heal() }
+
+// Faults injects failures from now until d, every mean on average, each
+// crashing a random candidate, which restarts after down, or isolating it
+// from the other nodes for down, as drawn from the seed of c.
+func (c *Cluster) Faults(d, mean, down time.Duration) {
+	r := rand.New(rand.NewPCG(c.cfg.Seed, 2))
+	names := c.Names()
+	end := time.Now().Add(d)
+	for {
+		time.Sleep(time.Duration(r.ExpFloat64() * float64(mean)))
+		if !time.Now().Before(end) {
+			return
+		}
+		n := names[r.IntN(len(names))]
+		if r.IntN(2) == 0 {
+			c.Crash(n)
+			time.Sleep(down)
+			c.Restart(n)
+			continue
+		}
+		c.Partition([]string{n})
+		time.Sleep(down)
+		c.Heal()
+	}
+}
+
+// lockService grants the leases of keys to one owner at a time, for
+// Config.LeaseTTL: it answers every line "acquire key owner" with the
+// owner of the lease of key, granted to the caller if it expired, and
+// renewed if the caller holds it.
+func (c *Cluster) lockService(p *simnet.Proc) {
+	l, err := p.Listen(LockPort)
+	if err != nil {
+		return
+	}
+	type lease struct {
+		owner string
+		until time.Time
+	}
+	var mu sync.Mutex
+	leases := make(map[string]lease)
+	acquire := func(key, owner string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if ls, ok := leases[key]; ok && ls.owner != owner && !now.After(ls.until) {
+			return ls.owner
+		}
+		leases[key] = lease{owner, now.Add(c.cfg.LeaseTTL)}
+		return owner
+	}
+	p.Go(func(context.Context) {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			p.Go(func(context.Context) {
+				defer conn.Close()
+				for sc := bufio.NewScanner(conn); sc.Scan(); {
+					f := strings.Fields(sc.Text())
+					if len(f) != 3 || f[0] != "acquire" {
+						return
+					}
+					fmt.Fprintln(conn, acquire(f[1], f[2]))
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package electsim
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+)
+
+const ttl = 2 * time.Second
+
+// elector leads while it holds the lease "leader", renewing it every
+// ttl/4. Unless it is careful, it does not step down when its lease
+// expires.
+func elector(careful bool) Start {
+	return func(env *Env) {
+		var (
+			mu      sync.Mutex
+			leading bool
+			until   time.Time
+			expiry  *time.Timer
+		)
+		env.Proc.Go(func(ctx context.Context) {
+			defer func() {
+				if expiry != nil {
+					expiry.Stop()
+				}
+			}()
+			for {
+				if u, holder, err := env.Acquire("leader"); err == nil && holder == env.Name {
+					mu.Lock()
+					until = u
+					if !leading {
+						leading = true
+						env.Report(true)
+					}
+					switch {
+					case !careful:
+					case expiry == nil:
+						expiry = time.AfterFunc(time.Until(u), func() {
+							mu.Lock()
+							defer mu.Unlock()
+							if leading && !time.Now().Before(until) {
+								leading = false
+								env.Report(false)
+							}
+						})
+					default:
+						expiry.Reset(time.Until(u))
+					}
+					mu.Unlock()
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(ttl / 4):
+				}
+			}
+		})
+	}
+}
+
+func TestLockService(t *testing.T) {
+	sched.ExploreRandom(t, 10, func(t testing.TB) {
+		c := New(t, Config{LeaseTTL: ttl}, elector(true))
+		defer c.Close()
+		c.AwaitLeader()
+		c.Faults(time.Minute, 5*time.Second, 3*time.Second)
+		time.Sleep(10 * time.Second)
+	})
+}
+
+func TestTwoLeaders(t *testing.T) {
+	rec := testtb.New(t)
+	synctest.Run(func() {
+		c := New(rec, Config{LeaseTTL: ttl, Seed: 1}, elector(false))
+		defer c.Close()
+		leader := c.AwaitLeader()
+		c.Partition([]string{leader})
+		time.Sleep(2 * ttl)
+	})
+	if errs := rec.Errors(); len(errs) != 1 || !strings.Contains(errs[0], "are both leaders at +") {
+		t.Errorf("a leader not stepping down reported %q, want two leaders", errs)
+	}
+}
+
+func TestNoLeader(t *testing.T) {
+	rec := testtb.New(t)
+	synctest.Run(func() {
+		c := New(rec, Config{LeaseTTL: ttl, Within: 5 * time.Second, Seed: 1}, elector(true))
+		defer c.Close()
+		leader := c.AwaitLeader()
+		c.Net.Node(LockNode).Crash()
+		c.Crash(leader)
+		time.Sleep(10 * time.Second)
+	})
+	want := "electsim: no leader within 5s of crash n"
+	if errs := rec.Errors(); len(errs) != 1 || !strings.HasPrefix(errs[0], want) {
+		t.Errorf("reported %q, want %q", errs, want)
+	}
+}
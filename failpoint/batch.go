@@ -0,0 +1,90 @@
+package failpoint
+
+import (
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Item is an item of a batched operation, as a BatchPolicy sees it.
+type Item struct {
+	Batch int // the hit of the batch, counting from 1
+	Index int // in the batch
+	Value any
+}
+
+// BatchPolicy is what a failpoint enabled with EnableBatch does for each
+// item of a batch: the error it returns is the one of the item.
+type BatchPolicy func(it Item) error
+
+// AtIndex applies p to the items at the indices idx of every batch, with
+// the batch as the hit.
+func AtIndex(p Policy, idx ...int) BatchPolicy {
+	return func(it Item) error {
+		for _, i := range idx {
+			if i == it.Index {
+				return p(it.Batch)
+			}
+		}
+		return nil
+	}
+}
+
+// Where applies p to the items for which pred holds, with the batch as the
+// hit.
+func Where(pred func(it Item) bool, p Policy) BatchPolicy {
+	return func(it Item) error {
+		if !pred(it) {
+			return nil
+		}
+		return p(it.Batch)
+	}
+}
+
+// EnableBatch is Enable for a failpoint of batched operations, which
+// fails some of their items and not the others, for the partial-success
+// handling of multi-puts and the like to be tested. A hit with Inject is
+// a batch of one item with no value.
+func EnableBatch(t testing.TB, name string, p BatchPolicy) *Failpoint {
+	t.Helper()
+	return enable(t, &Failpoint{name: name, group: gstack.Self().Group, batch: p})
+}
+
+// InjectBatch hits the failpoint name with a batch of items:
+//
+//	if errs := failpoint.InjectBatch("kv/multiput", keys); errs != nil {
+//		keys = retry(keys, errs)
+//	}
+//
+// It returns the error of every item, nil for those that succeed, or nil
+// if all do. A failpoint enabled with Enable applies its policy to every
+// item in turn, one hit each.
+func InjectBatch[T any](name string, items []T) []error {
+	if count.Load() == 0 {
+		return nil
+	}
+	f := lookup(name)
+	if f == nil {
+		return nil
+	}
+	var errs []error
+	batch := 0
+	if f.batch != nil {
+		batch = int(f.hits.Add(1))
+	}
+	for i, v := range items {
+		var err error
+		if f.batch != nil {
+			err = f.batch(Item{Batch: batch, Index: i, Value: v})
+		} else {
+			err = f.policy(int(f.hits.Add(1)))
+		}
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(items))
+			}
+			errs[i] = err
+		}
+	}
+	return errs
+}
@@ -0,0 +1,59 @@
+package failpoint
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// multiPut stores the keys it can and returns those that failed.
+func multiPut(store map[string]bool, keys []string) []string {
+	errs := InjectBatch("kv/multiput", keys)
+	var failed []string
+	for i, k := range keys {
+		if errs != nil && errs[i] != nil {
+			failed = append(failed, k)
+			continue
+		}
+		store[k] = true
+	}
+	return failed
+}
+
+func TestAtIndex(t *testing.T) {
+	f := EnableBatch(t, "kv/multiput", AtIndex(Fail(), 2, 7))
+	keys := strings.Fields("a b c d e f g h i j")
+	store := make(map[string]bool)
+	failed := multiPut(store, keys)
+	if !reflect.DeepEqual(failed, []string{"c", "h"}) || len(store) != 8 {
+		t.Errorf("failed %v with %d stored, want c and h of 10 to fail", failed, len(store))
+	}
+	if failed := multiPut(store, failed); len(failed) != 0 {
+		t.Errorf("retry of the 2 failed %v, want none to fail at indices 2 and 7", failed)
+	}
+	if f.Hits() != 2 {
+		t.Errorf("%d hits, want one per batch", f.Hits())
+	}
+}
+
+func TestWhere(t *testing.T) {
+	EnableBatch(t, "kv/multiput", Where(func(it Item) bool {
+		return it.Batch == 1 && strings.HasPrefix(it.Value.(string), "tmp/")
+	}, Fail()))
+	errs := InjectBatch("kv/multiput", []string{"tmp/a", "b", "tmp/c"})
+	if len(errs) != 3 || !errors.Is(errs[0], ErrInjected) || errs[1] != nil || !errors.Is(errs[2], ErrInjected) {
+		t.Errorf("errors %v, want the tmp/ items to fail", errs)
+	}
+	if errs := InjectBatch("kv/multiput", []string{"tmp/a"}); errs != nil {
+		t.Errorf("second batch: errors %v", errs)
+	}
+}
+
+func TestBatchWithPolicy(t *testing.T) {
+	Enable(t, "kv/multiput", OnHit(3, Fail()))
+	errs := InjectBatch("kv/multiput", []int{1, 2, 3, 4})
+	if len(errs) != 4 || errs[2] == nil || errs[0] != nil || errs[1] != nil || errs[3] != nil {
+		t.Errorf("errors %v, want the third item to fail", errs)
+	}
+}
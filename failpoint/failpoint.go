@@ -0,0 +1,175 @@
+// Package failpoint lets tests make production code fail where it rarely
+// does. The code marks the places with Inject, a no-op until a test
+// enables the failpoint with a Policy: return an error on the third hit,
+// or sleep for 10s of virtual time and then panic. Error paths that no
+// test could reach become exercisable.
+//
+//	if err := failpoint.Inject("wal/sync"); err != nil {
+//		return err
+//	}
+//
+// Acquire and Release mark where limited resources are taken and given
+// back, for tests to Exhaust them and check the system degrades
+// gracefully, then recovers once they are available again, and
+// InjectBatch marks batched operations, of which a test can make only
+// some items fail.
+package failpoint
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Policy is what an enabled failpoint does when hit, the hits counted
+// from 1: the error it returns is what Inject returns, and it may sleep or
+// panic too.
+type Policy func(hit int) error
+
+// ErrInjected is the error of Fail.
+var ErrInjected = errors.New("failpoint: injected failure")
+
+// Return returns err on every hit.
+func Return(err error) Policy { return func(int) error { return err } }
+
+// Fail returns ErrInjected, wrapped with the hit, on every hit.
+func Fail() Policy {
+	return func(hit int) error { return fmt.Errorf("%w (hit %d)", ErrInjected, hit) }
+}
+
+// Sleep sleeps for d, which is virtual in a bubble, on every hit.
+func Sleep(d time.Duration) Policy {
+	return func(int) error {
+		time.Sleep(d)
+		return nil
+	}
+}
+
+// Panic panics with v on every hit.
+func Panic(v any) Policy { return func(int) error { panic(v) } }
+
+// Seq applies ps in turn on every hit, until one returns an error.
+func Seq(ps ...Policy) Policy {
+	return func(hit int) error {
+		for _, p := range ps {
+			if err := p(hit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// OnHit applies p on the nth hit only.
+func OnHit(n int, p Policy) Policy {
+	return func(hit int) error {
+		if hit != n {
+			return nil
+		}
+		return p(hit)
+	}
+}
+
+// From applies p from the nth hit on.
+func From(n int, p Policy) Policy {
+	return func(hit int) error {
+		if hit < n {
+			return nil
+		}
+		return p(hit)
+	}
+}
+
+// Failpoint is an enabled failpoint.
+type Failpoint struct {
+	name   string
+	group  int64 // bubble it applies in, 0 for all
+	policy Policy
+	batch  BatchPolicy // instead of policy, if enabled with EnableBatch
+	hits   atomic.Int64
+}
+
+// Hits returns the number of times f was hit. A batch counts as one hit
+// of a failpoint enabled with EnableBatch, and as one per item otherwise.
+func (f *Failpoint) Hits() int { return int(f.hits.Load()) }
+
+var (
+	mu      sync.Mutex
+	enabled = make(map[string][]*Failpoint)
+	count   atomic.Int32 // of the enabled failpoints
+)
+
+// Enable makes the failpoint name apply p when hit, until t ends. Enabled
+// in a bubble, it only applies there, so that tests enabling the same
+// failpoint can run in parallel in bubbles of their own; enabled outside,
+// it applies everywhere, and only one test may then enable it at a time.
+func Enable(t testing.TB, name string, p Policy) *Failpoint {
+	t.Helper()
+	return enable(t, &Failpoint{name: name, group: gstack.Self().Group, policy: p})
+}
+
+func enable(t testing.TB, f *Failpoint) *Failpoint {
+	t.Helper()
+	name := f.name
+	mu.Lock()
+	defer mu.Unlock()
+	for _, g := range enabled[name] {
+		if g.group == f.group || g.group == 0 || f.group == 0 {
+			t.Fatalf("failpoint: %s is already enabled", name)
+		}
+	}
+	enabled[name] = append(enabled[name], f)
+	count.Add(1)
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		fs := enabled[name]
+		for i, g := range fs {
+			if g == f {
+				enabled[name] = append(fs[:i:i], fs[i+1:]...)
+				count.Add(-1)
+			}
+		}
+		if len(enabled[name]) == 0 {
+			delete(enabled, name)
+		}
+	})
+	return f
+}
+
+// Inject hits the failpoint name: if a test enabled it, its policy is
+// applied and its error returned; otherwise Inject returns nil, having
+// done no more than load an atomic counter.
+func Inject(name string) error {
+	if count.Load() == 0 {
+		return nil
+	}
+	f := lookup(name)
+	if f == nil {
+		return nil
+	}
+	hit := int(f.hits.Add(1))
+	if f.batch != nil {
+		return f.batch(Item{Batch: hit})
+	}
+	return f.policy(hit)
+}
+
+// lookup returns the failpoint name enabled for the caller, or nil.
+func lookup(name string) *Failpoint {
+	group := gstack.Self().Group
+	mu.Lock()
+	defer mu.Unlock()
+	var f *Failpoint
+	for _, g := range enabled[name] {
+		if g.group == 0 || g.group == group {
+			f = g
+		}
+	}
+	return f
+}
@@ -0,0 +1,75 @@
+package failpoint
+
+import (
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// flush is production code with a failpoint.
+func flush() error {
+	if err := Inject("wal/sync"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestDisabled(t *testing.T) {
+	if err := flush(); err != nil {
+		t.Errorf("disabled failpoint returned %v", err)
+	}
+}
+
+func TestOnHit(t *testing.T) {
+	f := Enable(t, "wal/sync", OnHit(3, Fail()))
+	var errs []error
+	for range 4 {
+		errs = append(errs, flush())
+	}
+	if errs[0] != nil || errs[1] != nil || !errors.Is(errs[2], ErrInjected) || errs[3] != nil {
+		t.Errorf("errors %v, want the third to be injected", errs)
+	}
+	if f.Hits() != 4 {
+		t.Errorf("%d hits, want 4", f.Hits())
+	}
+}
+
+func TestSleepThenPanic(t *testing.T) {
+	synctest.Run(func() {
+		Enable(t, "wal/sync", Seq(Sleep(10*time.Second), Panic("disk gone")))
+		start := time.Now()
+		defer func() {
+			if r := recover(); r != "disk gone" {
+				t.Errorf("recovered %v, want the injected panic", r)
+			}
+			if d := time.Since(start); d != 10*time.Second {
+				t.Errorf("panicked after %v, want 10s", d)
+			}
+		}()
+		flush()
+	})
+}
+
+func TestBubbleScope(t *testing.T) {
+	synctest.Run(func() {
+		Enable(t, "wal/sync", Fail())
+		done := make(chan error)
+		go func() { done <- flush() }()
+		if err := <-done; err == nil {
+			t.Error("failpoint did not apply in its bubble")
+		}
+	})
+	if err := flush(); err != nil {
+		t.Errorf("failpoint of a bubble applied outside: %v", err)
+	}
+	errs := testtb.Run(t, func(t testing.TB) {
+		Enable(t, "wal/sync", Fail())
+		Enable(t, "wal/sync", Fail())
+	})
+	if len(errs) != 1 {
+		t.Errorf("enabling twice: errors %q", errs)
+	}
+}
@@ -0,0 +1,173 @@
+package failpoint
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// ErrExhausted is the error of Acquire for a resource a test exhausted.
+var ErrExhausted = errors.New("failpoint: resource exhausted")
+
+// Acquire hits the resource name before production code acquires a unit
+// of it, a buffer from a pool, a file descriptor, a worker slot:
+//
+//	if err := failpoint.Acquire("worker/slot"); err != nil {
+//		return errBusy
+//	}
+//	defer failpoint.Release("worker/slot")
+//
+// It fails with ErrExhausted once a test exhausted the resource, and else
+// with the error of the failpoint name if enabled. Without either it
+// returns nil, as cheaply as Inject.
+func Acquire(name string) error {
+	if exhausting.Load() != 0 {
+		if e := exhaustion(name); e != nil {
+			if err := e.acquire(); err != nil {
+				return err
+			}
+		}
+	}
+	return Inject(name)
+}
+
+// Release hits the resource name once production code released a unit it
+// acquired.
+func Release(name string) {
+	if exhausting.Load() == 0 {
+		return
+	}
+	if e := exhaustion(name); e != nil {
+		e.mu.Lock()
+		e.held--
+		e.mu.Unlock()
+	}
+}
+
+// Exhaustion is a resource a test exhausted.
+type Exhaustion struct {
+	name  string
+	group int64 // bubble it applies in, 0 for all
+
+	mu       sync.Mutex
+	limit    int // units Acquire grants, or -1 once cleared
+	held     int // units acquired since Exhaust and not released
+	failures int
+}
+
+var (
+	exhausted  = make(map[string][]*Exhaustion) // guarded by mu
+	exhausting atomic.Int32                     // of the exhausted resources
+)
+
+// Exhaust makes Acquire of the resource name fail once limit units are
+// held, counting those acquired from now on, until t ends or the
+// exhaustion is cleared; with limit 0 every Acquire fails. It is scoped to
+// the calling bubble like Enable.
+func Exhaust(t testing.TB, name string, limit int) *Exhaustion {
+	t.Helper()
+	e := &Exhaustion{name: name, group: gstack.Self().Group, limit: limit}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, g := range exhausted[name] {
+		if g.group == e.group || g.group == 0 || e.group == 0 {
+			t.Fatalf("failpoint: %s is already exhausted", name)
+		}
+	}
+	exhausted[name] = append(exhausted[name], e)
+	exhausting.Add(1)
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		es := exhausted[name]
+		for i, g := range es {
+			if g == e {
+				exhausted[name] = append(es[:i:i], es[i+1:]...)
+				exhausting.Add(-1)
+			}
+		}
+		if len(exhausted[name]) == 0 {
+			delete(exhausted, name)
+		}
+	})
+	return e
+}
+
+// exhaustion returns the exhaustion of name that applies to the caller.
+func exhaustion(name string) *Exhaustion {
+	group := gstack.Self().Group
+	mu.Lock()
+	defer mu.Unlock()
+	for _, e := range exhausted[name] {
+		if e.group == 0 || e.group == group {
+			return e
+		}
+	}
+	return nil
+}
+
+func (e *Exhaustion) acquire() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.limit >= 0 && e.held >= e.limit {
+		e.failures++
+		return fmt.Errorf("%w: %s (%d held)", ErrExhausted, e.name, e.held)
+	}
+	e.held++
+	return nil
+}
+
+// Held returns the units acquired since Exhaust and not yet released.
+func (e *Exhaustion) Held() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.held
+}
+
+// Failures returns the number of Acquires that failed.
+func (e *Exhaustion) Failures() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.failures
+}
+
+// Clear lifts the limit; Acquire and Release are still counted.
+func (e *Exhaustion) Clear() {
+	e.mu.Lock()
+	e.limit = -1
+	e.mu.Unlock()
+}
+
+// Recover clears the exhaustion and fails t unless the system returns to
+// health within d: healthy returns nil and every unit acquired during the
+// exhaustion has been released, so that a degradation path that leaks
+// what it held is caught as well. Both are retried with the virtual time
+// in a bubble doubling from 1ms between tries.
+func (e *Exhaustion) Recover(t testing.TB, d time.Duration, healthy func() error) {
+	t.Helper()
+	e.Clear()
+	start := time.Now()
+	wait := time.Millisecond
+	for {
+		err := healthy()
+		n := e.Held()
+		if err == nil && n <= 0 {
+			return
+		}
+		if time.Since(start)+wait > d {
+			if err != nil {
+				t.Errorf("failpoint: not healthy within %v of clearing %s: %v", d, e.name, err)
+			} else {
+				t.Errorf("failpoint: %d units of %s still held %v after clearing it", n, e.name, d)
+			}
+			return
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
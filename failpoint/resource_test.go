@@ -0,0 +1,78 @@
+package failpoint
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+var errBusy = errors.New("busy")
+
+// workers runs jobs in worker slots, shedding load when none is free.
+type workers struct {
+	leak bool // keep the slot of a job that failed
+}
+
+func (w *workers) do(job func() error) error {
+	if err := Acquire("worker/slot"); err != nil {
+		return errBusy
+	}
+	if err := job(); err != nil {
+		if !w.leak {
+			Release("worker/slot")
+		}
+		return err
+	}
+	Release("worker/slot")
+	return nil
+}
+
+func TestExhaust(t *testing.T) {
+	synctest.Run(func() {
+		w := new(workers)
+		e := Exhaust(t, "worker/slot", 1)
+		block := make(chan struct{})
+		go w.do(func() error { <-block; return nil })
+		synctest.Wait()
+		if err := w.do(func() error { return nil }); err != errBusy {
+			t.Errorf("second job: %v, want it shed", err)
+		}
+		if e.Failures() != 1 || e.Held() != 1 {
+			t.Errorf("%d failures, %d held; want 1 and 1", e.Failures(), e.Held())
+		}
+		time.AfterFunc(5*time.Millisecond, func() { close(block) })
+		e.Recover(t, time.Second, func() error { return w.do(func() error { return nil }) })
+	})
+}
+
+func TestRecoverLeak(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		synctest.Run(func() {
+			w := &workers{leak: true}
+			e := Exhaust(t, "worker/slot", 0)
+			w.do(func() error { return nil })
+			e.Clear()
+			w.do(func() error { return errors.New("job failed") })
+			e.Recover(t, time.Second, func() error { return w.do(func() error { return nil }) })
+		})
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], "1 units of worker/slot still held 1s after") {
+		t.Errorf("errors %q, want the leaked slot", errs)
+	}
+}
+
+func TestRecoverTimeout(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		synctest.Run(func() {
+			e := Exhaust(t, "worker/slot", 0)
+			e.Recover(t, 100*time.Millisecond, func() error { return errBusy })
+		})
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], "not healthy within 100ms of clearing worker/slot: busy") {
+		t.Errorf("errors %q, want the timeout", errs)
+	}
+}
@@ -0,0 +1,42 @@
+package faultfs
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Dist is a distribution of latencies, drawn from r.
+type Dist func(r *rand.Rand) time.Duration
+
+// Constant always takes d.
+func Constant(d time.Duration) Dist {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// Uniform takes between lo and hi, uniformly.
+func Uniform(lo, hi time.Duration) Dist {
+	return func(r *rand.Rand) time.Duration {
+		if hi <= lo {
+			return lo
+		}
+		return lo + time.Duration(r.Int64N(int64(hi-lo)+1))
+	}
+}
+
+// Exponential takes mean on average, as a memoryless queue would.
+func Exponential(mean time.Duration) Dist {
+	return func(r *rand.Rand) time.Duration {
+		return time.Duration(r.ExpFloat64() * float64(mean))
+	}
+}
+
+// Tail draws from slow with probability p and from fast otherwise, for
+// disks that are mostly quick with the occasional stall.
+func Tail(p float64, fast, slow Dist) Dist {
+	return func(r *rand.Rand) time.Duration {
+		if r.Float64() < p {
+			return slow(r)
+		}
+		return fast(r)
+	}
+}
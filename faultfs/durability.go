@@ -0,0 +1,89 @@
+package faultfs
+
+import (
+	"slices"
+	"syscall"
+)
+
+// Barrier holds the syncs of an FS back until the test releases them.
+type Barrier struct {
+	fsys  *FS
+	syncs []*heldSync
+}
+
+// heldSync is a Sync waiting for its release, with the data it makes
+// durable.
+type heldSync struct {
+	n    *node
+	data []byte
+	done chan error
+}
+
+// HoldSyncs makes every Sync wait until the barrier returned releases it,
+// so the test decides when data becomes durable and can crash the FS in
+// between.
+func (fsys *FS) HoldSyncs() *Barrier {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.held == nil {
+		fsys.held = &Barrier{fsys: fsys}
+	}
+	return fsys.held
+}
+
+// Pending returns the number of syncs b holds.
+func (b *Barrier) Pending() int {
+	b.fsys.mu.Lock()
+	defer b.fsys.mu.Unlock()
+	return len(b.syncs)
+}
+
+// Release completes the syncs b holds, in the order they were called, and
+// keeps holding later ones.
+func (b *Barrier) Release() {
+	b.fsys.mu.Lock()
+	defer b.fsys.mu.Unlock()
+	b.release(nil)
+}
+
+// Close releases the syncs b holds and stops holding them.
+func (b *Barrier) Close() {
+	b.fsys.mu.Lock()
+	defer b.fsys.mu.Unlock()
+	b.release(nil)
+	if b.fsys.held == b {
+		b.fsys.held = nil
+	}
+}
+
+// release completes the held syncs with err, making their data durable
+// if nil, with fsys.mu held.
+func (b *Barrier) release(err error) {
+	for _, s := range b.syncs {
+		if err == nil {
+			s.n.synced = s.data
+		}
+		s.done <- err
+	}
+	b.syncs = nil
+}
+
+// Crash loses what fsys did not make durable, as a power failure would:
+// every file goes back to the data of its last completed Sync, the files
+// open are closed, and held syncs fail with syscall.EIO. The directory
+// tree, which the FS does not sync, stays as it is, so a file renamed
+// into place before its data was synced comes back empty. Holding syncs
+// and injected faults go on.
+func (fsys *FS) Crash() {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.held != nil {
+		fsys.held.release(syscall.EIO)
+	}
+	for _, n := range fsys.nodes {
+		if !n.dir {
+			n.data = slices.Clone(n.synced)
+		}
+	}
+	fsys.epoch++
+}
@@ -0,0 +1,112 @@
+package faultfs
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// replace writes data to name through a temporary file, syncing it first
+// if sync is set.
+func replace(fsys *FS, name string, data []byte, sync bool) error {
+	f, err := fsys.Create(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if sync {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return fsys.Rename(name+".tmp", name)
+}
+
+func TestCrashLosesUnsynced(t *testing.T) {
+	for _, sync := range []bool{false, true} {
+		fsys := New()
+		if err := replace(fsys, "config", []byte("v1"), sync); err != nil {
+			t.Fatal(err)
+		}
+		fsys.Crash()
+		data, err := fsys.ReadFile("config")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[bool]string{false: "", true: "v1"}[sync]; string(data) != want {
+			t.Errorf("with sync %v: %q after the crash, want %q", sync, data, want)
+		}
+	}
+}
+
+func TestHoldSyncs(t *testing.T) {
+	synctest.Run(func() {
+		fsys := New()
+		b := fsys.HoldSyncs()
+		f, _ := fsys.Create("wal")
+		errs := make(chan error, 2)
+		for _, rec := range []string{"a", "b"} {
+			f.Write([]byte(rec))
+			go func() { errs <- f.Sync() }()
+			synctest.Wait()
+		}
+		if n := b.Pending(); n != 2 {
+			t.Fatalf("%d syncs pending, want 2", n)
+		}
+		b.Release()
+		for range 2 {
+			if err := <-errs; err != nil {
+				t.Error(err)
+			}
+		}
+		f.Write([]byte("c"))
+		go func() { errs <- f.Sync() }()
+		synctest.Wait()
+		fsys.Crash()
+		if err := <-errs; !errors.Is(err, syscall.EIO) {
+			t.Errorf("sync held over the crash returned %v, want EIO", err)
+		}
+		if data, _ := fsys.ReadFile("wal"); string(data) != "ab" {
+			t.Errorf("%q after the crash, want the released syncs' %q", data, "ab")
+		}
+		if _, err := f.Write([]byte("d")); err == nil {
+			t.Error("write to a file open before the crash succeeded")
+		}
+	})
+}
+
+func TestLatency(t *testing.T) {
+	synctest.Run(func() {
+		fsys := New()
+		fsys.Seed(1)
+		fsys.Latency(Write, Uniform(time.Millisecond, 5*time.Millisecond))
+		fsys.Latency(Sync, Tail(0.5, Constant(time.Millisecond), Constant(time.Second)))
+		f, _ := fsys.Create("x")
+		var took []time.Duration
+		for range 8 {
+			start := time.Now()
+			f.Write([]byte("x"))
+			f.Sync()
+			took = append(took, time.Since(start))
+		}
+		slow := 0
+		for _, d := range took {
+			if d < 2*time.Millisecond || d > 6*time.Millisecond && d < time.Second || d > time.Second+5*time.Millisecond {
+				t.Errorf("write and sync took %v, outside the distributions", d)
+			}
+			if d > time.Second {
+				slow++
+			}
+		}
+		if slow == 0 || slow == len(took) {
+			t.Errorf("%d of %d syncs slow, want some", slow, len(took))
+		}
+	})
+}
@@ -0,0 +1,389 @@
+// Package faultfs is an in-memory file system whose operations can be
+// delayed on the virtual clock and made to fail, per path and per call:
+// a write that runs out of space, a read that hits an I/O error, a write
+// torn halfway. File-handling code written against fs.FS and the small
+// write API of FS can so have its error and retry paths tested
+// deterministically.
+//
+// Operations can also take a while drawn from a latency distribution,
+// see FS.Latency. Written data only survives FS.Crash once a Sync of its
+// file completed, and FS.HoldSyncs holds syncs back until the test
+// releases them, to check what storage code assumes to be durable, and
+// in which order it makes it so.
+package faultfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Op is an operation of the file system.
+type Op string
+
+const (
+	Open    Op = "open"
+	Read    Op = "read"
+	Write   Op = "write"
+	Sync    Op = "sync"
+	Close   Op = "close"
+	Stat    Op = "stat"
+	Remove  Op = "remove"
+	Rename  Op = "rename"
+	Mkdir   Op = "mkdir"
+	ReadDir Op = "readdir"
+)
+
+// Fault is a failure or delay of the operations it matches.
+type Fault struct {
+	Op   Op     // "" for every operation
+	Path string // a path.Match pattern; "" for every path
+	// Call, if positive, limits the fault to the Call-th operation it
+	// matches, counting from 1.
+	Call int
+	// Delay is how long matching operations take first, on the virtual
+	// clock in a bubble.
+	Delay time.Duration
+	// Err is the error matching operations fail with, as the Err of an
+	// fs.PathError, e.g. syscall.ENOSPC; nil to only delay them.
+	Err error
+	// Torn, if positive, makes a matching write write its first Torn
+	// bytes before failing, with Err or else syscall.EIO.
+	Torn int
+}
+
+// fault is an injected Fault and the operations it matched.
+type fault struct {
+	Fault
+	calls int
+}
+
+func (f *fault) matches(op Op, name string) bool {
+	if f.Op != "" && f.Op != op {
+		return false
+	}
+	if f.Path != "" {
+		if ok, _ := path.Match(f.Path, name); !ok {
+			return false
+		}
+	}
+	f.calls++
+	return f.Call <= 0 || f.calls == f.Call
+}
+
+// FS is an in-memory file system. Its zero value is not ready to use;
+// make one with New. It is safe for concurrent use.
+type FS struct {
+	mu     sync.Mutex
+	nodes  map[string]*node // by clean path, "." for the root
+	faults []*fault
+	// latency of the operations, drawn from rand.
+	latency map[Op]Dist
+	rand    *rand.Rand
+	held    *Barrier // holding syncs back, or nil
+	epoch   int      // of the files open, counting crashes
+}
+
+type node struct {
+	dir     bool
+	data    []byte
+	synced  []byte // data as of the last completed Sync
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// New returns an empty FS.
+func New() *FS {
+	return &FS{
+		nodes: map[string]*node{".": {dir: true, mode: fs.ModeDir | 0o755, modTime: time.Now()}},
+		rand:  rand.New(rand.NewPCG(0, 0)),
+	}
+}
+
+// Seed reseeds the latencies fsys draws.
+func (fsys *FS) Seed(seed uint64) {
+	fsys.mu.Lock()
+	fsys.rand = rand.New(rand.NewPCG(seed, 0))
+	fsys.mu.Unlock()
+}
+
+// Latency makes every operation op take a while drawn from d, on the
+// virtual clock in a bubble, before its faults apply; a nil d removes it.
+func (fsys *FS) Latency(op Op, d Dist) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.latency == nil {
+		fsys.latency = make(map[Op]Dist)
+	}
+	if d == nil {
+		delete(fsys.latency, op)
+		return
+	}
+	fsys.latency[op] = d
+}
+
+// Inject adds f to the faults of fsys.
+func (fsys *FS) Inject(f Fault) {
+	fsys.mu.Lock()
+	fsys.faults = append(fsys.faults, &fault{Fault: f})
+	fsys.mu.Unlock()
+}
+
+// Clear removes the faults of fsys.
+func (fsys *FS) Clear() {
+	fsys.mu.Lock()
+	fsys.faults = nil
+	fsys.mu.Unlock()
+}
+
+// check applies the faults matching op on name: it sleeps for their
+// delays and returns the first error, and the fault that tears a write.
+func (fsys *FS) check(op Op, name string) (torn *Fault, err error) {
+	fsys.mu.Lock()
+	var delay time.Duration
+	if d := fsys.latency[op]; d != nil {
+		delay = max(d(fsys.rand), 0)
+	}
+	for _, f := range fsys.faults {
+		if !f.matches(op, name) {
+			continue
+		}
+		delay += f.Delay
+		if op == Write && f.Torn > 0 && torn == nil && err == nil {
+			torn = &f.Fault
+		} else if f.Err != nil && err == nil && torn == nil {
+			err = &fs.PathError{Op: string(op), Path: name, Err: f.Err}
+		}
+	}
+	fsys.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return torn, err
+}
+
+func pathErr(op Op, name string, err error) error {
+	return &fs.PathError{Op: string(op), Path: name, Err: err}
+}
+
+// lookup returns the node of name, with fsys.mu held.
+func (fsys *FS) lookup(op Op, name string) (*node, error) {
+	if !fs.ValidPath(name) {
+		return nil, pathErr(op, name, fs.ErrInvalid)
+	}
+	n, ok := fsys.nodes[name]
+	if !ok {
+		return nil, pathErr(op, name, fs.ErrNotExist)
+	}
+	return n, nil
+}
+
+// parent checks that the directory of name exists, with fsys.mu held.
+func (fsys *FS) parent(op Op, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return pathErr(op, name, fs.ErrInvalid)
+	}
+	if p, ok := fsys.nodes[path.Dir(name)]; !ok || !p.dir {
+		return pathErr(op, name, fs.ErrNotExist)
+	}
+	return nil
+}
+
+// Open opens name for reading.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	return fsys.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Create creates or truncates name for writing.
+func (fsys *FS) Create(name string) (*File, error) {
+	return fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+}
+
+// OpenFile opens name with the flags of os.OpenFile.
+func (fsys *FS) OpenFile(name string, flag int, perm fs.FileMode) (*File, error) {
+	if _, err := fsys.check(Open, name); err != nil {
+		return nil, err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	n, err := fsys.lookup(Open, name)
+	switch {
+	case err == nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, pathErr(Open, name, fs.ErrExist)
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		if err := fsys.parent(Open, name); err != nil {
+			return nil, err
+		}
+		n = &node{mode: perm, modTime: time.Now()}
+		fsys.nodes[name] = n
+	case err != nil:
+		return nil, err
+	}
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if n.dir && writable {
+		return nil, pathErr(Open, name, syscall.EISDIR)
+	}
+	if writable && flag&os.O_TRUNC != 0 {
+		n.data, n.modTime = nil, time.Now()
+	}
+	return &File{fsys: fsys, name: name, n: n, flag: flag, epoch: fsys.epoch}, nil
+}
+
+// ReadFile returns the contents of name.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WriteFile writes data to name, creating or truncating it.
+func (fsys *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Stat describes name.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if _, err := fsys.check(Stat, name); err != nil {
+		return nil, err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	n, err := fsys.lookup(Stat, name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(name), nil
+}
+
+// ReadDir lists the directory name, sorted by file name.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if _, err := fsys.check(ReadDir, name); err != nil {
+		return nil, err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	n, err := fsys.lookup(ReadDir, name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.dir {
+		return nil, pathErr(ReadDir, name, syscall.ENOTDIR)
+	}
+	var es []fs.DirEntry
+	for p, c := range fsys.nodes {
+		if p != "." && path.Dir(p) == name {
+			es = append(es, fs.FileInfoToDirEntry(c.info(p)))
+		}
+	}
+	slices.SortFunc(es, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return es, nil
+}
+
+// MkdirAll creates the directory name and its missing parents.
+func (fsys *FS) MkdirAll(name string, perm fs.FileMode) error {
+	if _, err := fsys.check(Mkdir, name); err != nil {
+		return err
+	}
+	if !fs.ValidPath(name) {
+		return pathErr(Mkdir, name, fs.ErrInvalid)
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	for p := name; p != "."; p = path.Dir(p) {
+		if n, ok := fsys.nodes[p]; ok {
+			if !n.dir {
+				return pathErr(Mkdir, p, syscall.ENOTDIR)
+			}
+			break
+		}
+		fsys.nodes[p] = &node{dir: true, mode: fs.ModeDir | perm, modTime: time.Now()}
+	}
+	return nil
+}
+
+// Remove removes the file or empty directory name.
+func (fsys *FS) Remove(name string) error {
+	if _, err := fsys.check(Remove, name); err != nil {
+		return err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	n, err := fsys.lookup(Remove, name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return pathErr(Remove, name, fs.ErrInvalid)
+	}
+	if n.dir {
+		for p := range fsys.nodes {
+			if p != name && path.Dir(p) == name {
+				return pathErr(Remove, name, syscall.ENOTEMPTY)
+			}
+		}
+	}
+	delete(fsys.nodes, name)
+	return nil
+}
+
+// Rename moves the file oldname to newname, replacing any file there.
+func (fsys *FS) Rename(oldname, newname string) error {
+	if _, err := fsys.check(Rename, oldname); err != nil {
+		return err
+	}
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	n, err := fsys.lookup(Rename, oldname)
+	if err != nil {
+		return err
+	}
+	if n.dir {
+		return pathErr(Rename, oldname, syscall.EISDIR)
+	}
+	if err := fsys.parent(Rename, newname); err != nil {
+		return err
+	}
+	if m, ok := fsys.nodes[newname]; ok && m.dir {
+		return pathErr(Rename, newname, syscall.EISDIR)
+	}
+	delete(fsys.nodes, oldname)
+	fsys.nodes[newname] = n
+	return nil
+}
+
+func (n *node) info(name string) fs.FileInfo {
+	return fileInfo{path.Base(name), int64(len(n.data)), n.mode, n.modTime}
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
@@ -0,0 +1,118 @@
+package faultfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"testing/synctest"
+	"time"
+)
+
+func TestFS(t *testing.T) {
+	fsys := New()
+	if err := fsys.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/b/c.txt", "a/d.txt", "e.txt"} {
+		if err := fsys.WriteFile(name, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fstest.TestFS(fsys, "a/b/c.txt", "a/d.txt", "e.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+// appendRetry appends rec to name, retrying transient failures once.
+func appendRetry(fsys *FS, name string, rec []byte) error {
+	var err error
+	for range 2 {
+		var f *File
+		if f, err = fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644); err != nil {
+			continue
+		}
+		_, err = f.Write(rec)
+		if err == nil {
+			err = f.Sync()
+		}
+		f.Close()
+		if err == nil || errors.Is(err, syscall.ENOSPC) {
+			return err
+		}
+	}
+	return err
+}
+
+func TestWriteFaults(t *testing.T) {
+	fsys := New()
+	fsys.Inject(Fault{Op: Write, Path: "*.log", Call: 2, Torn: 3})
+	fsys.Inject(Fault{Op: Sync, Path: "wal.log", Call: 3, Err: syscall.ENOSPC})
+	var errs []error
+	for _, rec := range []string{"one\n", "two\n", "three\n"} {
+		errs = append(errs, appendRetry(fsys, "wal.log", []byte(rec)))
+	}
+	if errs[0] != nil || errs[1] != nil || !errors.Is(errs[2], syscall.ENOSPC) {
+		t.Errorf("errors %v, want only the last to be ENOSPC", errs)
+	}
+	var pe *fs.PathError
+	if !errors.As(errs[2], &pe) || pe.Op != "sync" || pe.Path != "wal.log" {
+		t.Errorf("error %v, want a sync path error on wal.log", errs[2])
+	}
+	// The torn write left a prefix before its retry.
+	data, _ := fsys.ReadFile("wal.log")
+	if got, want := string(data), "one\ntwotwo\nthree\n"; got != want {
+		t.Errorf("file %q, want %q", got, want)
+	}
+}
+
+func TestReadFaultDelay(t *testing.T) {
+	synctest.Run(func() {
+		fsys := New()
+		fsys.WriteFile("data", []byte("hello"), 0o644)
+		fsys.Inject(Fault{Op: Read, Path: "data", Delay: time.Second, Err: syscall.EIO})
+		start := time.Now()
+		_, err := fsys.ReadFile("data")
+		if !errors.Is(err, syscall.EIO) {
+			t.Errorf("read error %v, want EIO", err)
+		}
+		if d := time.Since(start); d != time.Second {
+			t.Errorf("read failed after %v, want 1s", d)
+		}
+		fsys.Clear()
+		if data, err := fsys.ReadFile("data"); err != nil || string(data) != "hello" {
+			t.Errorf("read %q, %v after Clear", data, err)
+		}
+	})
+}
+
+func TestFileErrors(t *testing.T) {
+	fsys := New()
+	if _, err := fsys.Create("missing/x"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("create in a missing directory: %v", err)
+	}
+	f, err := fsys.Create("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := f.Write([]byte("late")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("write after close: %v", err)
+	}
+	r, _ := fsys.Open("x")
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read of an empty file: %v", err)
+	}
+	if _, err := r.(*File).Write([]byte("x")); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("write to a read-only file: %v", err)
+	}
+	if err := fsys.Rename("x", "y"); err != nil {
+		t.Error(err)
+	}
+	if _, err := fsys.Stat("x"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stat after rename: %v", err)
+	}
+}
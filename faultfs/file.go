@@ -0,0 +1,172 @@
+package faultfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// File is an open file of an FS.
+type File struct {
+	fsys   *FS
+	name   string
+	n      *node
+	flag   int
+	off    int64
+	dirOff int // entries of a directory read so far
+	closed bool
+	epoch  int // of fsys when f was opened
+}
+
+// check applies the faults of op on f, after checking f is open.
+func (f *File) check(op Op) (*Fault, error) {
+	f.fsys.mu.Lock()
+	closed := f.closed || f.epoch != f.fsys.epoch
+	f.fsys.mu.Unlock()
+	if closed {
+		return nil, pathErr(op, f.name, fs.ErrClosed)
+	}
+	return f.fsys.check(op, f.name)
+}
+
+// Name returns the name f was opened with.
+func (f *File) Name() string { return f.name }
+
+func (f *File) Stat() (fs.FileInfo, error) {
+	if _, err := f.check(Stat); err != nil {
+		return nil, err
+	}
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	return f.n.info(f.name), nil
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	if _, err := f.check(Read); err != nil {
+		return 0, err
+	}
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.n.dir {
+		return 0, pathErr(Read, f.name, syscall.EISDIR)
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, pathErr(Read, f.name, fs.ErrPermission)
+	}
+	if f.off >= int64(len(f.n.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.n.data[f.off:])
+	f.off += int64(n)
+	return n, nil
+}
+
+// Write writes p at the offset of f, or at its end if opened with
+// os.O_APPEND. A torn write writes part of p and fails.
+func (f *File) Write(p []byte) (int, error) {
+	torn, err := f.check(Write)
+	if err != nil {
+		return 0, err
+	}
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, pathErr(Write, f.name, fs.ErrPermission)
+	}
+	if torn != nil && torn.Torn < len(p) {
+		err = torn.Err
+		if err == nil {
+			err = syscall.EIO
+		}
+		err = pathErr(Write, f.name, err)
+		p = p[:torn.Torn]
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.off = int64(len(f.n.data))
+	}
+	if end := f.off + int64(len(p)); end > int64(len(f.n.data)) {
+		f.n.data = append(f.n.data, make([]byte, end-int64(len(f.n.data)))...)
+	}
+	copy(f.n.data[f.off:], p)
+	f.off += int64(len(p))
+	f.n.modTime = time.Now()
+	return len(p), err
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(f.n.data))
+	}
+	if offset < 0 {
+		return 0, pathErr("seek", f.name, fs.ErrInvalid)
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// Sync makes the data of f durable: it survives a Crash of its FS once
+// Sync returns. While the FS holds syncs, Sync waits for their release.
+func (f *File) Sync() error {
+	if _, err := f.check(Sync); err != nil {
+		return err
+	}
+	fsys := f.fsys
+	fsys.mu.Lock()
+	data := slices.Clone(f.n.data)
+	if fsys.held == nil {
+		f.n.synced = data
+		fsys.mu.Unlock()
+		return nil
+	}
+	s := &heldSync{n: f.n, data: data, done: make(chan error, 1)}
+	fsys.held.syncs = append(fsys.held.syncs, s)
+	fsys.mu.Unlock()
+	if err := <-s.done; err != nil {
+		return pathErr(Sync, f.name, err)
+	}
+	return nil
+}
+
+// ReadDir reads the entries of the directory f, as fs.ReadDirFile.
+func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
+	if _, err := f.check(ReadDir); err != nil {
+		return nil, err
+	}
+	es, err := f.fsys.ReadDir(f.name)
+	if err != nil {
+		return nil, err
+	}
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	es = es[min(f.dirOff, len(es)):]
+	if n > 0 {
+		if len(es) == 0 {
+			return nil, io.EOF
+		}
+		es = es[:min(n, len(es))]
+	}
+	f.dirOff += len(es)
+	return es, nil
+}
+
+// Close closes f. Even when a fault fails it, f is closed.
+func (f *File) Close() error {
+	if _, err := f.check(Close); err != nil {
+		f.fsys.mu.Lock()
+		f.closed = true
+		f.fsys.mu.Unlock()
+		return err
+	}
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	f.closed = true
+	return nil
+}
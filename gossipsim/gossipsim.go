@@ -0,0 +1,359 @@
+// Package gossipsim is a scenario kit for gossip and anti-entropy
+// protocols: it runs their nodes on a simnet network in a synctest bubble,
+// each talking to its neighbors in a Topology, spreads rumors from the
+// nodes a test picks, crashes and restarts nodes at random with Churn,
+// and checks that every rumor reaches every node it can reach within a
+// bound of virtual time.
+//
+// The churn and the latencies of the links derive from the seed of the
+// cluster, that of the caller's sched run by default, so that exploring
+// schedules with sched.ExploreRandom explores them too, and a failing run
+// replays with its seed.
+package gossipsim
+
+import (
+	"cmp"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// Port is the port of the mailboxes of the nodes.
+const Port = "gossip"
+
+// Topology is who talks to whom: the neighbors of every node, by name,
+// both ways.
+type Topology map[string][]string
+
+// Names returns the nodes of t, in the order of their numbers.
+func (t Topology) Names() []string { return slices.SortedFunc(maps.Keys(t), byNumber) }
+
+// byNumber orders "n2" before "n10".
+func byNumber(a, b string) int { return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b)) }
+
+// link links the nodes a and b of t, if they are not.
+func (t Topology) link(a, b string) {
+	if a != b && !slices.Contains(t[a], b) {
+		t[a], t[b] = append(t[a], b), append(t[b], a)
+	}
+}
+
+// empty returns the topology of n nodes, "n1" to "nN", without links,
+// and their names.
+func empty(n int) (Topology, []string) {
+	t := make(Topology)
+	ns := make([]string, n)
+	for i := range ns {
+		ns[i] = "n" + strconv.Itoa(i+1)
+		t[ns[i]] = nil
+	}
+	return t, ns
+}
+
+// Full returns the topology of n nodes, "n1" to "nN", all neighbors.
+func Full(n int) Topology {
+	t, ns := empty(n)
+	for _, a := range ns {
+		for _, b := range ns {
+			t.link(a, b)
+		}
+	}
+	return t
+}
+
+// Ring returns the topology of n nodes, "n1" to "nN", each the neighbor
+// of the next, and the last of the first.
+func Ring(n int) Topology {
+	t, ns := empty(n)
+	for i, a := range ns {
+		t.link(a, ns[(i+1)%n])
+	}
+	return t
+}
+
+// Random returns the topology of n nodes, "n1" to "nN", a ring to which
+// links between random nodes are added until they have degree neighbors
+// on average, drawn from seed.
+func Random(n, degree int, seed uint64) Topology {
+	t := Ring(n)
+	r := rand.New(rand.NewPCG(seed, 3))
+	ns := t.Names()
+	links := n
+	for links < n*degree/2 && links < n*(n-1)/2 {
+		a, b := ns[r.IntN(n)], ns[r.IntN(n)]
+		if a != b && !slices.Contains(t[a], b) {
+			t.link(a, b)
+			links++
+		}
+	}
+	return t
+}
+
+// Start starts the node of env: it starts its goroutines with
+// env.Proc.Go and returns.
+type Start func(env *Env)
+
+// Env is what a node of a Cluster runs with, for an incarnation of its
+// process.
+type Env struct {
+	Name    string
+	Peers   []string // the neighbors, the only nodes to talk to
+	Proc    *simnet.Proc
+	Mailbox *simnet.Mailbox // on Port, to exchange messages with the peers
+	// Rumors are the rumors the node is to spread, as Spread queues them.
+	Rumors <-chan string
+
+	c *Cluster
+}
+
+// Delivered reports that the node learned the rumor id. A node knows the
+// rumors its running process reported, so a node that restarts reports
+// again those it kept.
+func (e *Env) Delivered(id string) { e.c.delivered(e.Proc, id) }
+
+// Config configures a Cluster.
+type Config struct {
+	Topology Topology // default Full(3)
+	// Link are the conditions of the links of the network.
+	Link simnet.Conditions
+	// Seed is the seed of the churn and of the network, default that of
+	// the caller's sched run, or else sched.Seed, logged if t fails.
+	Seed uint64
+}
+
+// Cluster is a set of nodes gossiping.
+type Cluster struct {
+	Net *simnet.Network
+
+	t    testing.TB
+	cfg  Config
+	topo Topology
+
+	mu      sync.Mutex
+	rumors  map[string]chan string        // of the running processes, by node
+	spread  map[string]spread             // by rumor
+	learned map[string]map[string]learned // by rumor and node
+
+	churnMu sync.Mutex    // held while restarting a node
+	churn   []*time.Timer // restarting the nodes crashed
+	closed  bool
+}
+
+// learned is when a node first learned a rumor, and the last process
+// that reported it.
+type learned struct {
+	at time.Time
+	p  *simnet.Proc
+}
+
+// spread is where and when a rumor was spread.
+type spread struct {
+	from *simnet.Proc
+	at   time.Time
+}
+
+// New starts a cluster of the nodes of cfg.Topology running start, on a
+// network of its own, in the caller's bubble. Close it before the bubble
+// ends.
+func New(t testing.TB, cfg Config, start Start) *Cluster {
+	if cfg.Seed == 0 {
+		cfg.Seed = sched.SeedFor(t, "gossipsim")
+	}
+	topo := cfg.Topology
+	if topo == nil {
+		topo = Full(3)
+	}
+	c := &Cluster{
+		Net: simnet.New(), t: t, cfg: cfg, topo: topo,
+		rumors: make(map[string]chan string), spread: make(map[string]spread), learned: make(map[string]map[string]learned),
+	}
+	c.Net.Seed(cfg.Seed)
+	c.Net.SetConditions(cfg.Link)
+	for _, name := range topo.Names() {
+		c.Net.Add(name, c.boot(start))
+	}
+	return c
+}
+
+func (c *Cluster) boot(start Start) simnet.Boot {
+	return func(p *simnet.Proc) {
+		name := p.Node().Name
+		m, err := p.Mailbox(Port)
+		if err != nil {
+			return
+		}
+		rumors := make(chan string, 64)
+		c.mu.Lock()
+		c.rumors[name] = rumors
+		c.mu.Unlock()
+		start(&Env{Name: name, Peers: slices.Clone(c.topo[name]), Proc: p, Mailbox: m, Rumors: rumors, c: c})
+	}
+}
+
+// Close crashes every node of c, for good, for the end of a scenario.
+func (c *Cluster) Close() {
+	c.churnMu.Lock()
+	c.closed = true
+	for _, t := range c.churn {
+		t.Stop()
+	}
+	c.churnMu.Unlock()
+	c.Net.Close()
+}
+
+// Node returns the node name of c.
+func (c *Cluster) Node(name string) *simnet.Node { return c.Net.Node(name) }
+
+// Spread queues the rumor id for the node from to spread, if it is up,
+// and counts the time it takes to reach the others from now.
+func (c *Cluster) Spread(from, id string) {
+	c.mu.Lock()
+	p := c.Net.Node(from).Proc()
+	if _, ok := c.spread[id]; !ok {
+		c.spread[id] = spread{p, time.Now()}
+	}
+	rumors := c.rumors[from]
+	c.mu.Unlock()
+	if p != nil {
+		rumors <- id
+	}
+}
+
+func (c *Cluster) delivered(p *simnet.Proc, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.learned[id]
+	if l == nil {
+		l = make(map[string]learned)
+		c.learned[id] = l
+	}
+	name := p.Node().Name
+	at := time.Now()
+	if old, ok := l[name]; ok {
+		at = old.at
+	}
+	l[name] = learned{at, p}
+}
+
+// Churn crashes a random node from now until d, every mean on average,
+// unless it is down, and restarts it after down, as drawn from the seed of
+// c.
+func (c *Cluster) Churn(d, mean, down time.Duration) {
+	r := rand.New(rand.NewPCG(c.cfg.Seed, 4))
+	ns := c.topo.Names()
+	end := time.Now().Add(d)
+	for {
+		time.Sleep(time.Duration(r.ExpFloat64() * float64(mean)))
+		if !time.Now().Before(end) {
+			return
+		}
+		n := c.Net.Node(ns[r.IntN(len(ns))])
+		c.churnMu.Lock()
+		if c.closed {
+			c.churnMu.Unlock()
+			return
+		}
+		if n.Up() {
+			n.Crash()
+			c.churn = append(c.churn, time.AfterFunc(down, func() {
+				c.churnMu.Lock()
+				defer c.churnMu.Unlock()
+				if !c.closed {
+					n.Restart(nil)
+				}
+			}))
+		}
+		c.churnMu.Unlock()
+	}
+}
+
+// Missing returns the nodes that do not know the rumor id though they can
+// reach one that does, or the process it was spread from while it runs:
+// the running nodes connected to such a node by a path of running
+// neighbors on the same side of the partition, if any.
+func (c *Cluster) Missing(id string) []string {
+	c.mu.Lock()
+	knows := make(map[string]bool)
+	for n, l := range c.learned[id] {
+		knows[n] = l.p.Node().Proc() == l.p
+	}
+	sp, ok := c.spread[id]
+	c.mu.Unlock()
+	up := func(n string) bool { return c.Net.Node(n).Up() }
+	var queue []string
+	seen := make(map[string]bool)
+	for n, k := range knows {
+		if k {
+			queue = append(queue, n)
+			seen[n] = true
+		}
+	}
+	if ok && sp.from != nil {
+		if n := sp.from.Node(); !seen[n.Name] && n.Proc() == sp.from {
+			queue = append(queue, n.Name)
+			seen[n.Name] = true
+		}
+	}
+	var missing []string
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if !knows[n] {
+			missing = append(missing, n)
+		}
+		for _, m := range c.topo[n] {
+			if !seen[m] && up(m) && c.Net.Reachable(n, m) {
+				seen[m] = true
+				queue = append(queue, m)
+			}
+		}
+	}
+	slices.SortFunc(missing, byNumber)
+	return missing
+}
+
+// AwaitConverged waits until no node is Missing the rumor id and returns
+// how long after Spread that was, or fails t if it was not within bound
+// of it.
+func (c *Cluster) AwaitConverged(id string, bound time.Duration) time.Duration {
+	c.t.Helper()
+	c.mu.Lock()
+	sp, ok := c.spread[id]
+	c.mu.Unlock()
+	if !ok {
+		c.t.Errorf("gossipsim: %s was not spread", id)
+		return 0
+	}
+	for {
+		missing := c.Missing(id)
+		took := time.Since(sp.at)
+		if len(missing) == 0 {
+			return took
+		}
+		if took >= bound {
+			c.t.Errorf("gossipsim: %s did not reach %s within %v", id, strings.Join(missing, ", "), bound)
+			return took
+		}
+		time.Sleep(min(10*time.Millisecond, bound-took))
+	}
+}
+
+// Learned returns when each node first learned the rumor id, since it was
+// spread.
+func (c *Cluster) Learned(id string) map[string]time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ls := make(map[string]time.Duration)
+	for n, l := range c.learned[id] {
+		ls[n] = l.at.Sub(c.spread[id].at)
+	}
+	return ls
+}
@@ -0,0 +1,174 @@
+package gossipsim
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// msg is a message of the gossip of the tests: "push" sends rumors,
+// "digest" the rumors the sender knows, for the receiver to push those it
+// lacks.
+type msg struct {
+	Type string
+	IDs  []string
+}
+
+// gossip pushes every rumor it learns to two random peers for three
+// rounds of 100ms. With antiEntropy, it also sends its digest to a random
+// peer every second.
+func gossip(antiEntropy bool) Start {
+	return func(env *Env) {
+		h := fnv.New64a()
+		h.Write([]byte(env.Name))
+		r := rand.New(rand.NewPCG(h.Sum64(), uint64(env.Proc.Incarnation())))
+		known := make(map[string]bool)
+		hot := make(map[string]int) // rounds left to push, by rumor
+		send := func(to string, m msg) {
+			data, _ := json.Marshal(m)
+			env.Mailbox.Send(to, data)
+		}
+		learn := func(id string) {
+			if !known[id] {
+				known[id], hot[id] = true, 3
+				env.Delivered(id)
+			}
+		}
+		env.Proc.Go(func(ctx context.Context) {
+			round := time.NewTicker(100 * time.Millisecond)
+			defer round.Stop()
+			for n := 1; ; {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-env.Rumors:
+					learn(id)
+				case in := <-env.Mailbox.Inbox():
+					var m msg
+					json.Unmarshal(in.Data, &m)
+					switch m.Type {
+					case "push":
+						for _, id := range m.IDs {
+							learn(id)
+						}
+					case "digest":
+						var lacking []string
+						for id := range known {
+							if !slices.Contains(m.IDs, id) {
+								lacking = append(lacking, id)
+							}
+						}
+						if len(lacking) > 0 {
+							send(in.From, msg{"push", lacking})
+						}
+					}
+				case <-round.C:
+					for _, id := range slices.Sorted(maps.Keys(hot)) {
+						peers := slices.Clone(env.Peers)
+						r.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+						for _, p := range peers[:min(2, len(peers))] {
+							send(p, msg{"push", []string{id}})
+						}
+						if hot[id]--; hot[id] == 0 {
+							delete(hot, id)
+						}
+					}
+					if n++; antiEntropy && n%10 == 0 && len(env.Peers) > 0 {
+						var ids []string
+						for id := range known {
+							ids = append(ids, id)
+						}
+						send(env.Peers[r.IntN(len(env.Peers))], msg{"digest", ids})
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTopologies(t *testing.T) {
+	for _, c := range []struct {
+		topo   Topology
+		degree int
+	}{{Full(5), 4}, {Ring(5), 2}, {Random(20, 4, 1), 4}} {
+		links := 0
+		for _, n := range c.topo.Names() {
+			for _, m := range c.topo[n] {
+				if !slices.Contains(c.topo[m], n) {
+					t.Errorf("%s is a neighbor of %s, not the other way", m, n)
+				}
+			}
+			links += len(c.topo[n])
+		}
+		if got := links / len(c.topo); got != c.degree {
+			t.Errorf("%d nodes have %d neighbors on average, want %d", len(c.topo), got, c.degree)
+		}
+	}
+	if names := Ring(11).Names(); names[1] != "n2" || names[10] != "n11" {
+		t.Errorf("names: %v, want them by number", names)
+	}
+}
+
+func TestRing(t *testing.T) {
+	sched.ExploreRandom(t, 10, func(t testing.TB) {
+		c := New(t, Config{Topology: Ring(8)}, gossip(false))
+		defer c.Close()
+		c.Spread("n1", "r1")
+		if took := c.AwaitConverged("r1", 2*time.Second); took > 0 && len(c.Learned("r1")) != 8 {
+			t.Errorf("r1 converged in %v, learned by %v", took, c.Learned("r1"))
+		}
+	})
+}
+
+func TestChurn(t *testing.T) {
+	sched.ExploreRandom(t, 5, func(t testing.TB) {
+		c := New(t, Config{
+			Topology: Random(20, 4, 1),
+			Link:     simnet.Conditions{Latency: faultfs.Uniform(time.Millisecond, 20*time.Millisecond)},
+		}, gossip(true))
+		defer c.Close()
+		done := make(chan struct{})
+		go func() {
+			c.Churn(20*time.Second, time.Second, 2*time.Second)
+			close(done)
+		}()
+		rumors := []string{"r1", "r2", "r3", "r4", "r5"}
+		for i, id := range rumors {
+			c.Spread(c.Net.Nodes()[i].Name, id)
+			time.Sleep(2 * time.Second)
+		}
+		<-done
+		for _, id := range rumors {
+			c.AwaitConverged(id, time.Minute)
+		}
+	})
+}
+
+func TestPartitioned(t *testing.T) {
+	rec := testtb.New(t)
+	synctest.Run(func() {
+		c := New(rec, Config{Topology: Full(4), Seed: 1}, gossip(false))
+		defer c.Close()
+		c.Net.Partition([]string{"n1", "n2"})
+		c.Spread("n1", "r1")
+		c.AwaitConverged("r1", time.Second)
+		time.Sleep(time.Second)
+		c.Net.Heal()
+		c.AwaitConverged("r1", 5*time.Second)
+	})
+	want := "gossipsim: r1 did not reach n3, n4 within 5s"
+	if errs := rec.Errors(); len(errs) != 1 || errs[0] != want {
+		t.Errorf("rumor lost with the partition reported %q, want %q", errs, want)
+	}
+}
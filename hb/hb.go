@@ -0,0 +1,43 @@
+// Package hb records the happens-before order of the goroutines of a
+// synctest bubble: the order the synchronization of chanx channels,
+// syncx mutexes and syncx wait groups imposes on what the goroutines do.
+// A send happens before the receive of its value, an Unlock before the
+// next Lock, a Done before the return of Wait, and what a goroutine does
+// before what it does later. Marks label events of interest, such as a
+// write and a read of shared state, so that a test can assert that one is
+// ordered before the other rather than rely on the race detector not
+// seeing the schedule in which they race.
+//
+// Values are taken to be received in the order their sends were recorded,
+// which holds for a single sender. Plain channels and the primitives of
+// package sync are not seen.
+//
+// With -synctest.hbdot=dir, a failing ExpectBefore writes the events the
+// unordered marks depend on, and the edges missing between them, to dir
+// as a graph in the DOT language of GraphViz; Graph.WriteDot writes any
+// part of a graph.
+package hb
+
+import (
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/vclock"
+)
+
+// Graph is the happens-before graph of a bubble.
+type Graph = vclock.Graph
+
+// Event is an event of a graph: a synchronization operation, or a mark.
+type Event = vclock.Event
+
+// Edge orders two events, directly.
+type Edge = vclock.Edge
+
+// Record starts recording the caller's bubble until the test ends.
+func Record(t testing.TB) *Graph {
+	t.Helper()
+	return vclock.Record(t)
+}
+
+// Mark records an event labeled label in the calling goroutine.
+func Mark(label string) { vclock.Mark(label, 1) }
@@ -0,0 +1,117 @@
+package hb
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/bubble"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/chanx"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/syncx"
+)
+
+func TestChannel(t *testing.T) {
+	synctest.Run(func() {
+		g := Record(t)
+		ch := chanx.Make[int]("ch", 1)
+		bubble.Go("writer", func() {
+			Mark("write")
+			ch.Send(1)
+			Mark("after send")
+		})
+		ch.Recv()
+		Mark("read")
+		synctest.Wait()
+		g.ExpectBefore(t, "write", "read")
+
+		errs := testtb.Run(t, func(t testing.TB) { g.ExpectBefore(t, "after send", "read") })
+		if len(errs) != 1 || !strings.Contains(errs[0], `"after send" is not ordered before "read"`) || !strings.Contains(errs[0], "writer") {
+			t.Errorf("errors %q", errs)
+		}
+		var ops []string
+		for _, e := range g.Events() {
+			ops = append(ops, e.Goroutine+" "+e.Op)
+		}
+		if len(ops) != 5 || ops[1] != "writer send" || !strings.HasSuffix(ops[3], " recv") {
+			t.Errorf("events %q", ops)
+		}
+	})
+}
+
+func TestMutexAndWaitGroup(t *testing.T) {
+	synctest.Run(func() {
+		g := Record(t)
+		mu := syncx.NewMutex(t, "mu")
+		var wg syncx.WaitGroup
+		wg.Add(2)
+		mu.Lock()
+		bubble.Go("a", func() {
+			mu.Lock()
+			Mark("a")
+			mu.Unlock()
+			wg.Done()
+		})
+		bubble.Go("b", func() {
+			Mark("b")
+			wg.Done()
+		})
+		synctest.Wait()
+		Mark("locked")
+		mu.Unlock()
+		wg.Wait()
+		Mark("waited")
+		g.ExpectBefore(t, "locked", "a")
+		g.ExpectBefore(t, "a", "waited")
+		g.ExpectBefore(t, "b", "waited")
+
+		as, bs := g.Marks("a"), g.Marks("b")
+		if g.Before(as[0], bs[0]) || g.Before(bs[0], as[0]) {
+			t.Errorf("a and b ordered")
+		}
+		for _, e := range g.Edges() {
+			if e.From >= e.To {
+				t.Errorf("edge %v goes back", e)
+			}
+		}
+	})
+}
+
+func TestDotOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	flag.Set("synctest.hbdot", dir)
+	defer flag.Set("synctest.hbdot", "")
+	synctest.Run(func() {
+		g := Record(t)
+		ch := chanx.Make[int]("ch", 1)
+		bubble.Go("writer", func() {
+			Mark("write")
+			ch.Send(1)
+			Mark("after send")
+		})
+		bubble.Go("bystander", func() { Mark("unrelated") })
+		ch.Recv()
+		Mark("read")
+		synctest.Wait()
+		t.Run("sub", func(t *testing.T) {
+			if errs := testtb.Run(t, func(t testing.TB) { g.ExpectBefore(t, "after send", "read") }); len(errs) != 1 {
+				t.Errorf("errors %q", errs)
+			}
+		})
+	})
+	dot, err := os.ReadFile(filepath.Join(dir, "TestDotOnFailure_sub.dot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`label="writer"`, `mark \"write\"`, `mark \"after send\"`, `mark \"read\"`, `[style=dashed, color=red, label="missing"]`, "[color=blue]"} {
+		if !strings.Contains(string(dot), want) {
+			t.Errorf("graph lacks %s:\n%s", want, dot)
+		}
+	}
+	if strings.Contains(string(dot), "unrelated") {
+		t.Errorf("graph has events unrelated to the marks:\n%s", dot)
+	}
+}
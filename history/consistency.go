@@ -0,0 +1,190 @@
+package history
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+)
+
+// Consistency is a consistency model a history can be checked against.
+type Consistency int
+
+const (
+	// Linearizability: every operation takes effect at one instant
+	// between its call and its return.
+	Linearizability Consistency = iota
+	// SequentialConsistency: the operations can be put in one order
+	// valid for the specification that keeps each client's operations in
+	// the order the client issued them, regardless of real time.
+	SequentialConsistency
+	// ReadYourWrites: for every client, its own operations in the order
+	// it issued them, interleaved with the writes of all clients in
+	// their issue order, are valid for the specification. A client thus
+	// sees its own writes and never goes back in time, but may see the
+	// writes of others late. Options.IsRead tells reads from writes.
+	ReadYourWrites
+)
+
+func (c Consistency) String() string {
+	switch c {
+	case Linearizability:
+		return "linearizability"
+	case SequentialConsistency:
+		return "sequential consistency"
+	case ReadYourWrites:
+		return "read-your-writes"
+	}
+	return fmt.Sprintf("Consistency(%d)", int(c))
+}
+
+// Options select what CheckWith verifies.
+type Options[I any] struct {
+	Model Consistency
+	// Partition, if set, splits the history by key, e.g. the key of a
+	// key-value operation, and checks each part on its own against a
+	// fresh specification: per-key linearizability with Linearizability.
+	Partition func(input I) string
+	// IsRead tells operations that do not change the state; required by
+	// ReadYourWrites.
+	IsRead func(input I) bool
+}
+
+// Verify checks the operations of ops against spec under opts: under
+// Linearizability the pending ones as Linearizable does, under the other
+// models only the complete ones.
+// With a Partition, Window is from the first part, by key, that fails.
+// Order is only found for plain linearizability.
+func Verify[S, I, O any](spec Spec[S, I, O], ops []Op[I, O], opts Options[I]) (Result[I, O], error) {
+	if opts.Model == ReadYourWrites && opts.IsRead == nil {
+		return Result[I, O]{}, fmt.Errorf("history: %v needs Options.IsRead", opts.Model)
+	}
+	if opts.Model == Linearizability && opts.Partition == nil {
+		return Linearizable(spec, ops), nil
+	}
+	parts := make(map[string][]int)
+	for i, op := range ops {
+		if op.Pending() && opts.Model != Linearizability {
+			continue
+		}
+		k := ""
+		if opts.Partition != nil {
+			k = opts.Partition(op.Input)
+		}
+		parts[k] = append(parts[k], i)
+	}
+	keys := make([]string, 0, len(parts))
+	for k := range parts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		idx := parts[k]
+		bad := func(sub []int) bool { return !consistent(spec, ops, sub, opts) }
+		if bad(idx) {
+			return Result[I, O]{Window: minimize(ops, idx, bad)}, nil
+		}
+	}
+	return Result[I, O]{OK: true}, nil
+}
+
+// CheckWith fails t with the violating window if ops does not satisfy
+// opts.Model.
+func CheckWith[S, I, O any](t testing.TB, spec Spec[S, I, O], ops []Op[I, O], opts Options[I]) Result[I, O] {
+	t.Helper()
+	res, err := Verify(spec, ops, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.OK {
+		part := ""
+		if opts.Partition != nil && len(res.Window) > 0 {
+			part = fmt.Sprintf(" for key %q", opts.Partition(res.Window[0].Input))
+		}
+		t.Errorf("history of %d operations violates %v with respect to %s%s; these %d are inconsistent:%s",
+			len(ops), opts.Model, spec.Name, part, len(res.Window), format(res.Window))
+	}
+	return res
+}
+
+func consistent[S, I, O any](spec Spec[S, I, O], ops []Op[I, O], idx []int, opts Options[I]) bool {
+	switch opts.Model {
+	case SequentialConsistency:
+		return serializable(spec, ops, idx, programOrder(ops, idx))
+	case ReadYourWrites:
+		clients := make(map[int]bool)
+		for _, j := range idx {
+			clients[ops[j].Client] = true
+		}
+		for c := range clients {
+			var sub []int
+			for _, j := range idx {
+				if ops[j].Client == c || !opts.IsRead(ops[j].Input) {
+					sub = append(sub, j)
+				}
+			}
+			if !serializable(spec, ops, sub, programOrder(ops, sub)) {
+				return false
+			}
+		}
+		return true
+	default:
+		_, ok := linearize(spec, ops, idx)
+		return ok
+	}
+}
+
+// programOrder returns, for each of ops[idx...], the positions in idx of
+// the earlier operations of the same client.
+func programOrder[I, O any](ops []Op[I, O], idx []int) [][]int {
+	preds := make([][]int, len(idx))
+	for a, i := range idx {
+		for b, j := range idx {
+			if ops[j].Client == ops[i].Client && ops[j].callSeq < ops[i].callSeq {
+				preds[a] = append(preds[a], b)
+			}
+		}
+	}
+	return preds
+}
+
+// serializable searches an order of ops[idx...] valid for spec in which
+// every operation comes after its preds, remembering the states already
+// reached for each set of operations placed.
+func serializable[S, I, O any](spec Spec[S, I, O], ops []Op[I, O], idx []int, preds [][]int) bool {
+	failed := make(map[string]bool)
+	done := new(big.Int)
+	var place func(s S, n int) bool
+	place = func(s S, n int) bool {
+		if n == len(idx) {
+			return true
+		}
+		k := done.Text(16) + "|" + spec.key(s)
+		if failed[k] {
+			return false
+		}
+	next:
+		for a, j := range idx {
+			if done.Bit(a) == 1 {
+				continue
+			}
+			for _, p := range preds[a] {
+				if done.Bit(p) == 0 {
+					continue next
+				}
+			}
+			ok, s2 := spec.Step(s, ops[j].Input, ops[j].Output)
+			if !ok {
+				continue
+			}
+			done.SetBit(done, a, 1)
+			if place(s2, n+1) {
+				return true
+			}
+			done.SetBit(done, a, 0)
+		}
+		failed[k] = true
+		return false
+	}
+	return place(spec.Init(), 0)
+}
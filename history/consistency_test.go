@@ -0,0 +1,117 @@
+package history
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// kvOp is a put or get on one key of a key-value store.
+type kvOp struct {
+	Key   string
+	Put   bool
+	Value int
+}
+
+func (o kvOp) String() string {
+	if o.Put {
+		return fmt.Sprintf("put(%s=%d)", o.Key, o.Value)
+	}
+	return fmt.Sprintf("get(%s)", o.Key)
+}
+
+var kv = Spec[map[string]int, kvOp, int]{
+	Name: "kv",
+	Init: func() map[string]int { return map[string]int{} },
+	Step: func(s map[string]int, in kvOp, out int) (bool, map[string]int) {
+		if !in.Put {
+			return s[in.Key] == out, s
+		}
+		next := maps.Clone(s)
+		next[in.Key] = in.Value
+		return true, next
+	},
+	Key: func(s map[string]int) string { return fmt.Sprint(s) },
+}
+
+func kvPut(client int, k string, v int) Op[kvOp, int] {
+	return Op[kvOp, int]{Client: client, Input: kvOp{k, true, v}}
+}
+
+func kvGet(client int, k string, v int) Op[kvOp, int] {
+	return Op[kvOp, int]{Client: client, Input: kvOp{Key: k}, Output: v}
+}
+
+func isRead(in kvOp) bool { return !in.Put }
+
+func byKey(in kvOp) string { return in.Key }
+
+func TestConsistencyModels(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		events string
+		ops    []Op[kvOp, int]
+		ok     map[Consistency]bool
+	}{
+		{
+			// Another client reads an old value after the put returned.
+			"stale read by other client", "0011",
+			[]Op[kvOp, int]{kvPut(0, "x", 1), kvGet(1, "x", 0)},
+			map[Consistency]bool{Linearizability: false, SequentialConsistency: true, ReadYourWrites: true},
+		},
+		{
+			// The writer itself does not see its own put.
+			"own write lost", "0011",
+			[]Op[kvOp, int]{kvPut(0, "x", 1), kvGet(0, "x", 0)},
+			map[Consistency]bool{Linearizability: false, SequentialConsistency: false, ReadYourWrites: false},
+		},
+		{
+			// A reader goes back in time.
+			"non-monotonic reads", "001122",
+			[]Op[kvOp, int]{kvPut(0, "x", 1), kvGet(1, "x", 1), kvGet(1, "x", 0)},
+			map[Consistency]bool{Linearizability: false, SequentialConsistency: false, ReadYourWrites: false},
+		},
+		{
+			// Each client sees the other's write late: fine per client,
+			// but no single order explains both.
+			"independent reads of independent writes", "00112233",
+			[]Op[kvOp, int]{kvPut(0, "x", 1), kvGet(0, "y", 0), kvPut(1, "y", 1), kvGet(1, "x", 0)},
+			map[Consistency]bool{Linearizability: false, SequentialConsistency: false, ReadYourWrites: true},
+		},
+	} {
+		for model, want := range c.ok {
+			res, err := Verify(kv, Concurrent(c.events, c.ops...), Options[kvOp]{Model: model, IsRead: isRead})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.OK != want {
+				t.Errorf("%s: %v holds %t, want %t", c.name, model, res.OK, want)
+			}
+		}
+	}
+}
+
+func TestPerKey(t *testing.T) {
+	// Per key the history is sequentially consistent, as a whole it is not.
+	ops := Concurrent("00112233",
+		kvPut(0, "x", 1), kvGet(0, "y", 0), kvPut(1, "y", 1), kvGet(1, "x", 0))
+	CheckWith(t, kv, ops, Options[kvOp]{Model: SequentialConsistency, Partition: byKey})
+
+	ops = Concurrent("001122", kvPut(0, "x", 1), kvPut(0, "y", 1), kvGet(1, "y", 0))
+	errs := testtb.Run(t, func(t testing.TB) {
+		CheckWith(t, kv, ops, Options[kvOp]{Model: Linearizability, Partition: byKey})
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], `violates linearizability with respect to kv for key "y"; these 2 are inconsistent:`+
+		"\n\tclient 0: put(y=1) → 0\n\tclient 1: get(y) → 0") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+func TestReadYourWritesNeedsIsRead(t *testing.T) {
+	if _, err := Verify(kv, nil, Options[kvOp]{Model: ReadYourWrites}); err == nil {
+		t.Error("no error without IsRead")
+	}
+}
@@ -0,0 +1,127 @@
+// Package history records the operations concurrent clients perform on a
+// shared object and checks the recorded history against a sequential
+// specification of that object.
+//
+// Inside a synctest bubble every operation of a goroutine may happen at
+// the same virtual instant, so the order of events is taken from the
+// order in which they were recorded rather than from their timestamps:
+// an operation precedes another if it returned before the other was
+// called. The timestamps are kept for reports.
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Op is one operation of a history.
+type Op[I, O any] struct {
+	Client int
+	Input  I
+	Output O
+
+	Call, Return       time.Time
+	callSeq, returnSeq int64 // position of the events in the history; returnSeq 0 while pending
+}
+
+// Pending reports whether the operation has not returned.
+func (op Op[I, O]) Pending() bool { return op.returnSeq == 0 }
+
+// Precedes reports whether op returned before other was called.
+func (op Op[I, O]) Precedes(other Op[I, O]) bool {
+	return !op.Pending() && op.returnSeq < other.callSeq
+}
+
+func (op Op[I, O]) String() string {
+	s := fmt.Sprintf("client %d: %v → %v", op.Client, op.Input, op.Output)
+	if op.Pending() {
+		s = fmt.Sprintf("client %d: %v → pending", op.Client, op.Input)
+	}
+	switch {
+	case op.Call.IsZero():
+	case op.Pending():
+		s += fmt.Sprintf(" [%s, -]", op.Call.UTC().Format(stamp))
+	default:
+		s += fmt.Sprintf(" [%s, %s]", op.Call.UTC().Format(stamp), op.Return.UTC().Format(stamp))
+	}
+	return s
+}
+
+// stamp formats times in UTC, in which bubbles start at midnight.
+const stamp = "15:04:05.000"
+
+// Recorder records a history. It is safe for concurrent use.
+type Recorder[I, O any] struct {
+	mu  sync.Mutex
+	seq int64
+	ops []Op[I, O]
+}
+
+// Call records that client invoked an operation with input and returns
+// the function to call with its output once it returns.
+func (r *Recorder[I, O]) Call(client int, input I) (ret func(output O)) {
+	r.mu.Lock()
+	r.seq++
+	i := len(r.ops)
+	r.ops = append(r.ops, Op[I, O]{Client: client, Input: input, Call: time.Now(), callSeq: r.seq})
+	r.mu.Unlock()
+	var once sync.Once
+	return func(output O) {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.seq++
+			op := &r.ops[i]
+			op.Output, op.Return, op.returnSeq = output, time.Now(), r.seq
+		})
+	}
+}
+
+// Ops returns the operations recorded so far, in the order they were
+// called.
+func (r *Recorder[I, O]) Ops() []Op[I, O] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Op[I, O](nil), r.ops...)
+}
+
+// Sequential builds the history of operations that ran one after the
+// other in the given order, for tests of checkers and specifications.
+func Sequential[I, O any](client int, ops ...Op[I, O]) []Op[I, O] {
+	for i := range ops {
+		ops[i].Client = client
+		ops[i].callSeq, ops[i].returnSeq = int64(2*i+1), int64(2*i+2)
+	}
+	return ops
+}
+
+// Concurrent builds, for tests, a history from events given as a string
+// of op indices: the first occurrence of an index is its call, the second
+// its return. "0101" makes ops 0 and 1 overlap, "0011" runs them in turn.
+func Concurrent[I, O any](events string, ops ...Op[I, O]) []Op[I, O] {
+	seen := make(map[int]bool)
+	for n, c := range events {
+		i := int(c - '0')
+		if !seen[i] {
+			seen[i] = true
+			ops[i].callSeq = int64(n + 1)
+		} else {
+			ops[i].returnSeq = int64(n + 1)
+		}
+	}
+	return ops
+}
+
+// format lists ops in call order.
+func format[I, O any](ops []Op[I, O]) string {
+	ops = append([]Op[I, O](nil), ops...)
+	sort.Slice(ops, func(i, j int) bool { return ops[i].callSeq < ops[j].callSeq })
+	var b strings.Builder
+	for _, op := range ops {
+		fmt.Fprintf(&b, "\n\t%v", op)
+	}
+	return b.String()
+}
@@ -0,0 +1,224 @@
+package history
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"testing"
+)
+
+// Spec is the sequential specification of an object.
+type Spec[S, I, O any] struct {
+	Name string
+	Init func() S
+	// Step applies an operation with input to state s and reports whether
+	// output is a result the object may return, and the state after it.
+	// It must not modify s. For a pending operation, whose output is
+	// unknown, ok is ignored, so next must be the state after the
+	// operation whatever it returned.
+	Step func(s S, input I, output O) (ok bool, next S)
+	// Key identifies a state, to recognize configurations already tried;
+	// the default formats it with %v.
+	Key func(s S) string
+}
+
+func (sp Spec[S, I, O]) key(s S) string {
+	if sp.Key != nil {
+		return sp.Key(s)
+	}
+	return fmt.Sprintf("%v", s)
+}
+
+// Result is the outcome of a check.
+type Result[I, O any] struct {
+	OK bool
+	// Order is a linearization of the operations, as indices into the
+	// checked history, when OK; pending operations that took no effect are
+	// left out.
+	Order []int
+	// Window is, otherwise, a smallest set of operations found that
+	// already cannot be linearized on its own, in call order.
+	Window []Op[I, O]
+}
+
+// Linearizable reports whether the operations of ops can be ordered so
+// that each takes effect at one instant between its call and its return,
+// consistent with spec. A pending operation may take effect at any
+// instant after its call, with any output, or not at all.
+//
+// The search is the one of Wing and Gong with Lowe's memoization of the
+// states already reached for each set of linearized operations. If the
+// history cannot be linearized, the Window is found by dropping
+// operations, first from the end and then one at a time, as long as the
+// rest still cannot be linearized.
+func Linearizable[S, I, O any](spec Spec[S, I, O], ops []Op[I, O]) Result[I, O] {
+	idx := make([]int, len(ops))
+	for i := range idx {
+		idx[i] = i
+	}
+	if order, ok := linearize(spec, ops, idx); ok {
+		return Result[I, O]{OK: true, Order: order}
+	}
+	return Result[I, O]{Window: minimize(ops, idx, func(sub []int) bool {
+		_, ok := linearize(spec, ops, sub)
+		return !ok
+	})}
+}
+
+// Check fails t with the violating window if ops is not linearizable.
+func Check[S, I, O any](t testing.TB, spec Spec[S, I, O], ops []Op[I, O]) Result[I, O] {
+	t.Helper()
+	res := Linearizable(spec, ops)
+	if !res.OK {
+		t.Errorf("history of %d operations is not linearizable with respect to %s; these %d cannot be linearized:%s",
+			len(ops), spec.Name, len(res.Window), format(res.Window))
+	}
+	return res
+}
+
+// event is a call or return in the doubly linked event list of the search.
+type event struct {
+	op         int // index into the checked subset
+	call       bool
+	match      *event // the return of a call
+	prev, next *event
+}
+
+// linearize searches a linearization of ops[idx...].
+func linearize[S, I, O any](spec Spec[S, I, O], ops []Op[I, O], idx []int) ([]int, bool) {
+	type ev struct {
+		seq  int64
+		op   int
+		call bool
+	}
+	evs := make([]ev, 0, 2*len(idx))
+	for i, j := range idx {
+		ret := ops[j].returnSeq
+		if ops[j].Pending() {
+			// Returns after everything else, so the search ends there.
+			ret = math.MaxInt64
+		}
+		evs = append(evs, ev{ops[j].callSeq, i, true}, ev{ret, i, false})
+	}
+	slices.SortFunc(evs, func(a, b ev) int { return cmp.Compare(a.seq, b.seq) })
+
+	head := &event{}
+	calls := make([]*event, len(idx))
+	last := head
+	for _, e := range evs {
+		n := &event{op: e.op, call: e.call, prev: last}
+		last.next = n
+		last = n
+		if e.call {
+			calls[e.op] = n
+		} else {
+			calls[e.op].match = n
+		}
+	}
+	lift := func(c *event) {
+		c.prev.next = c.next
+		if c.next != nil {
+			c.next.prev = c.prev
+		}
+		r := c.match
+		r.prev.next = r.next
+		if r.next != nil {
+			r.next.prev = r.prev
+		}
+	}
+	unlift := func(c *event) {
+		r := c.match
+		r.prev.next = r
+		if r.next != nil {
+			r.next.prev = r
+		}
+		c.prev.next = c
+		if c.next != nil {
+			c.next.prev = c
+		}
+	}
+
+	type frame struct {
+		call  *event
+		state S
+	}
+	var stack []frame
+	lin := new(big.Int)
+	seen := make(map[string]bool)
+	state := spec.Init()
+	e := head.next
+	for head.next != nil {
+		if e.call {
+			op := ops[idx[e.op]]
+			if ok, next := spec.Step(state, op.Input, op.Output); ok || op.Pending() {
+				lin.SetBit(lin, e.op, 1)
+				k := lin.Text(16) + "|" + spec.key(next)
+				if !seen[k] {
+					seen[k] = true
+					stack = append(stack, frame{e, state})
+					state = next
+					lift(e)
+					e = head.next
+					continue
+				}
+				lin.SetBit(lin, e.op, 0)
+			}
+			e = e.next
+			continue
+		}
+		if ops[idx[e.op]].Pending() {
+			// Only pending operations are left, which need not take effect.
+			break
+		}
+		// A return with its call not linearized: undo the last choice.
+		if len(stack) == 0 {
+			return nil, false
+		}
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state = f.state
+		lin.SetBit(lin, f.call.op, 0)
+		unlift(f.call)
+		e = f.call.next
+	}
+	order := make([]int, len(stack))
+	for i, f := range stack {
+		order[i] = idx[f.call.op]
+	}
+	return order, true
+}
+
+// minimize shrinks idx while bad holds for the rest.
+func minimize[I, O any](ops []Op[I, O], idx []int, bad func([]int) bool) []Op[I, O] {
+	ret := func(i int) int64 {
+		if ops[i].Pending() {
+			return math.MaxInt64
+		}
+		return ops[i].returnSeq
+	}
+	slices.SortFunc(idx, func(a, b int) int { return cmp.Compare(ret(a), ret(b)) })
+	// The shortest prefix by return order that still fails.
+	lo, hi := 1, len(idx)
+	for lo < hi {
+		if mid := (lo + hi) / 2; bad(idx[:mid]) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	cur := slices.Clone(idx[:hi])
+	for i := len(cur) - 1; i >= 0; i-- {
+		sub := slices.Delete(slices.Clone(cur), i, i+1)
+		if bad(sub) {
+			cur = sub
+		}
+	}
+	w := make([]Op[I, O], len(cur))
+	for i, j := range cur {
+		w[i] = ops[j]
+	}
+	slices.SortFunc(w, func(a, b Op[I, O]) int { return int(a.callSeq - b.callSeq) })
+	return w
+}
@@ -0,0 +1,183 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// regOp is an operation on a register: a put of Value, or a get.
+type regOp struct {
+	Put   bool
+	Value int
+}
+
+func (o regOp) String() string {
+	if o.Put {
+		return fmt.Sprintf("put(%d)", o.Value)
+	}
+	return "get()"
+}
+
+var register = Spec[int, regOp, int]{
+	Name: "register",
+	Init: func() int { return 0 },
+	Step: func(s int, in regOp, out int) (bool, int) {
+		if in.Put {
+			return true, in.Value
+		}
+		return out == s, s
+	},
+}
+
+func put(v int) Op[regOp, int] { return Op[regOp, int]{Input: regOp{true, v}} }
+func get(v int) Op[regOp, int] { return Op[regOp, int]{Input: regOp{}, Output: v} }
+
+func TestLinearizable(t *testing.T) {
+	for _, c := range []struct {
+		events string
+		ops    []Op[regOp, int]
+		ok     bool
+	}{
+		// A get overlapping a put may see either value.
+		{"0101", []Op[regOp, int]{put(1), get(0)}, true},
+		{"0101", []Op[regOp, int]{put(1), get(1)}, true},
+		// After the put returned, a get must see it.
+		{"0011", []Op[regOp, int]{put(1), get(0)}, false},
+		// Gets in turn during a put must not see it undone.
+		{"011220", []Op[regOp, int]{put(1), get(1), get(0)}, false},
+		{"022110", []Op[regOp, int]{put(1), get(1), get(0)}, true},
+	} {
+		res := Linearizable(register, Concurrent(c.events, c.ops...))
+		if res.OK != c.ok {
+			t.Errorf("%s %v: linearizable %t, want %t", c.events, c.ops, res.OK, c.ok)
+		}
+	}
+}
+
+func TestLinearizableOrder(t *testing.T) {
+	ops := Concurrent("001221", put(1), get(2), put(2))
+	res := Linearizable(register, ops)
+	if !res.OK || len(res.Order) != 3 || res.Order[0] != 0 || res.Order[1] != 2 || res.Order[2] != 1 {
+		t.Errorf("result %+v", res)
+	}
+}
+
+func TestLinearizableWindow(t *testing.T) {
+	// Only put(3) and the stale get after it matter.
+	ops := Sequential(0, put(1), get(1), put(2), get(2), put(3), get(2), put(4), get(4))
+	res := Linearizable(register, ops)
+	if res.OK || len(res.Window) != 2 || res.Window[0].Input != (regOp{true, 3}) || res.Window[1].Output != 2 {
+		t.Fatalf("window %v", res.Window)
+	}
+	errs := testtb.Run(t, func(t testing.TB) { Check(t, register, ops) })
+	if len(errs) != 1 || !strings.Contains(errs[0], "history of 8 operations is not linearizable with respect to register; these 2 cannot be linearized:\n\tclient 0: put(3) → 0\n\tclient 0: get() → 2") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+// cache is a register with a read cache per client that a buggy version
+// forgets to invalidate.
+type cache struct {
+	mu       sync.Mutex
+	value    int
+	cached   map[int]int
+	stale    bool
+	recorder *Recorder[regOp, int]
+}
+
+func (c *cache) put(client, v int) {
+	ret := c.recorder.Call(client, regOp{true, v})
+	c.mu.Lock()
+	c.value = v
+	if !c.stale {
+		clear(c.cached)
+	}
+	c.mu.Unlock()
+	ret(0)
+}
+
+func (c *cache) get(client int) {
+	ret := c.recorder.Call(client, regOp{})
+	c.mu.Lock()
+	v, ok := c.cached[client]
+	if !ok {
+		v = c.value
+		c.cached[client] = v
+	}
+	c.mu.Unlock()
+	ret(v)
+}
+
+func runCache(stale bool) []Op[regOp, int] {
+	var ops []Op[regOp, int]
+	synctest.Run(func() {
+		c := &cache{cached: make(map[int]int), stale: stale, recorder: new(Recorder[regOp, int])}
+		var wg sync.WaitGroup
+		for client := range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 3 {
+					time.Sleep(time.Duration(client+1) * time.Second)
+					if client == 0 {
+						c.put(client, i+1)
+					} else {
+						c.get(client)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		ops = c.recorder.Ops()
+	})
+	return ops
+}
+
+func TestCheckRecordedHistory(t *testing.T) {
+	Check(t, register, runCache(false))
+
+	errs := testtb.Run(t, func(t testing.TB) { Check(t, register, runCache(true)) })
+	if len(errs) != 1 || !strings.Contains(errs[0], "cannot be linearized") || !strings.Contains(errs[0], "[00:00:0") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+func TestRecorderPending(t *testing.T) {
+	r := new(Recorder[regOp, int])
+	r.Call(0, regOp{true, 5})
+	r.Call(1, regOp{})(0)
+	ops := r.Ops()
+	if !ops[0].Pending() || ops[1].Pending() || !Linearizable(register, ops).OK {
+		t.Errorf("ops %v", ops)
+	}
+}
+
+func TestLinearizablePending(t *testing.T) {
+	for _, c := range []struct {
+		events string
+		ops    []Op[regOp, int]
+		ok     bool
+	}{
+		// A get may see a put that never returned, or not see it.
+		{"011", []Op[regOp, int]{put(1), get(1)}, true},
+		{"011", []Op[regOp, int]{put(1), get(0)}, true},
+		// But not before its call, nor undone once seen.
+		{"110", []Op[regOp, int]{put(1), get(1)}, false},
+		{"01122", []Op[regOp, int]{put(1), get(1), get(0)}, false},
+	} {
+		res := Linearizable(register, Concurrent(c.events, c.ops...))
+		if res.OK != c.ok {
+			t.Errorf("%s %v: linearizable %t, want %t", c.events, c.ops, res.OK, c.ok)
+		}
+	}
+	res := Linearizable(register, Concurrent("011", put(1), get(1)))
+	if len(res.Order) != 2 || res.Order[0] != 0 || res.Order[1] != 1 {
+		t.Errorf("order %v, want the pending put before the get", res.Order)
+	}
+}
@@ -0,0 +1,202 @@
+package httpconf
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// Dial connects to the simulated server, whatever the address.
+type Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Patience is how long the server lets a client wait for 100 Continue in
+// the model's timeout action; the client must send the body by then.
+const Patience = time.Minute
+
+// Conform explores the model of every request of reqs, DefaultRequests if
+// none, against the RoundTripper newRT builds on dial, used through an
+// http.Client. t fails at the first server move after which the
+// simulated server saw something else than the model expects, with the
+// moves leading there.
+func Conform(t testing.TB, newRT func(dial Dial) http.RoundTripper, reqs ...Request) {
+	t.Helper()
+	if len(reqs) == 0 {
+		reqs = DefaultRequests
+	}
+	for _, r := range reqs {
+		m := Model(r)
+		model.Explore(t, m, model.ExploreConfig{}, func() model.System[Client] {
+			return model.Oracle(m, start(r, newRT))
+		})
+	}
+}
+
+// server is a simulated server and the client sending it a request.
+type server struct {
+	rt   http.RoundTripper
+	done chan struct{} // closed when the client returned
+
+	mu     sync.Mutex
+	closed bool
+	conns  []net.Conn // the server ends
+	reqs   []*received
+	result string
+}
+
+// received is a request the server received.
+type received struct {
+	conn         net.Conn
+	method, path string
+	length       int64
+	body         strings.Builder
+	reused       bool
+}
+
+// start sends r through the RoundTripper built by newRT and returns once
+// the client is blocked, presumably waiting for the server.
+func start(r Request, newRT func(dial Dial) http.RoundTripper) *server {
+	s := &server{done: make(chan struct{})}
+	s.rt = newRT(s.dial)
+	go func() {
+		defer close(s.done)
+		var body io.Reader
+		if r.Body != "" {
+			body = strings.NewReader(r.Body)
+		}
+		req, err := http.NewRequest(r.Method, "http://conform.test/", body)
+		if err != nil {
+			panic(err)
+		}
+		if r.ExpectContinue {
+			req.Header.Set("Expect", "100-continue")
+		}
+		result := "error"
+		if resp, err := (&http.Client{Transport: s.rt}).Do(req); err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			result = strconv.Itoa(resp.StatusCode)
+		}
+		s.mu.Lock()
+		s.result = result
+		s.mu.Unlock()
+	}()
+	synctest.Wait()
+	return s
+}
+
+func (s *server) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errors.New("server closed")
+	}
+	srv, cli := net.Pipe()
+	s.conns = append(s.conns, srv)
+	go s.serve(srv)
+	return cli, nil
+}
+
+// serve records the requests coming on conn.
+func (s *server) serve(conn net.Conn) {
+	br := bufio.NewReader(conn)
+	for reused := false; ; reused = true {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		rec := &received{conn: conn, method: req.Method, path: req.URL.Path, length: req.ContentLength, reused: reused}
+		s.mu.Lock()
+		s.reqs = append(s.reqs, rec)
+		s.mu.Unlock()
+		buf := make([]byte, 512)
+		for {
+			n, err := req.Body.Read(buf)
+			s.mu.Lock()
+			rec.body.Write(buf[:n])
+			s.mu.Unlock()
+			if err != nil {
+				break
+			}
+		}
+	}
+}
+
+var responses = map[string]string{
+	"continue":           "HTTP/1.1 100 Continue\r\n\r\n",
+	"reject":             "HTTP/1.1 417 Expectation Failed\r\nConnection: close\r\nContent-Length: 0\r\n\r\n",
+	"ok":                 "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n",
+	"see other":          "HTTP/1.1 303 See Other\r\nLocation: /next\r\nContent-Length: 0\r\n\r\n",
+	"temporary redirect": "HTTP/1.1 307 Temporary Redirect\r\nLocation: /next\r\nContent-Length: 0\r\n\r\n",
+}
+
+func (s *server) Do(action string) error {
+	s.mu.Lock()
+	if len(s.reqs) == 0 {
+		s.mu.Unlock()
+		return errors.New("no request received")
+	}
+	conn := s.reqs[len(s.reqs)-1].conn
+	s.mu.Unlock()
+	switch action {
+	case "timeout":
+		time.Sleep(Patience)
+	case "drop":
+		conn.Close()
+	default:
+		resp, ok := responses[action]
+		if !ok {
+			return fmt.Errorf("unknown action %q", action)
+		}
+		// In the background, since a client that does not read would
+		// block the write.
+		go conn.Write([]byte(resp))
+	}
+	return nil
+}
+
+func (s *server) Observe() Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := Client{Requests: len(s.reqs), Conns: len(s.conns), Result: s.result}
+	if len(s.reqs) > 0 {
+		r := s.reqs[len(s.reqs)-1]
+		c.Method, c.Path, c.Length, c.Body, c.Reused = r.method, r.path, r.length, r.body.String(), r.reused
+	}
+	switch {
+	case c.Result != "":
+		c.Phase = Done
+	case int64(len(c.Body)) < c.Length:
+		c.Phase = Awaiting
+	default:
+		c.Phase = Sent
+	}
+	return c
+}
+
+// Close closes the connections and refuses new ones, so that the client
+// gives up.
+func (s *server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	<-s.done
+	if c, ok := s.rt.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package httpconf
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func transport(dial Dial) http.RoundTripper {
+	return &http.Transport{DialContext: dial, ExpectContinueTimeout: time.Second}
+}
+
+func TestTransport(t *testing.T) {
+	Conform(t, transport)
+}
+
+// retryAll retries every failed request once, whatever its method.
+type retryAll struct{ rt http.RoundTripper }
+
+func (r retryAll) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.rt.RoundTrip(req)
+	if err != nil && req.GetBody != nil {
+		req.Body, _ = req.GetBody()
+		return r.rt.RoundTrip(req)
+	}
+	return resp, err
+}
+
+func (r retryAll) CloseIdleConnections() { r.rt.(*http.Transport).CloseIdleConnections() }
+
+func TestRetryingPost(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		Conform(t, func(dial Dial) http.RoundTripper { return retryAll{transport(dial)} }, Request{Method: "POST", Body: "a=1"})
+	})
+	want := "model client POST with body: after drop: model state {Method:POST Path:/ Length:3 Body:a=1 Phase:done Requests:1 Conns:1 Reused:false Result:error}: " +
+		"observed state {Method:POST Path:/ Length:3 Body:a=1 Phase:sent Requests:2 Conns:2 Reused:false Result:}"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
+
+// A client waiting longer for 100 Continue than the server's patience.
+func TestWaitingForever(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		Conform(t, func(dial Dial) http.RoundTripper {
+			return &http.Transport{DialContext: dial, ExpectContinueTimeout: 2 * Patience}
+		}, Request{Method: "PUT", Body: "upload", ExpectContinue: true})
+	})
+	want := "model client PUT with body expecting 100-continue: after timeout: model state {Method:PUT Path:/ Length:6 Body:upload Phase:sent Requests:1 Conns:1 Reused:false Result:}: " +
+		"observed state {Method:PUT Path:/ Length:6 Body: Phase:awaiting Requests:1 Conns:1 Reused:false Result:}"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
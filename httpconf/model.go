@@ -0,0 +1,146 @@
+// Package httpconf checks HTTP clients for conformance to a model of the
+// behaviour RFC 9110 asks of them: retrying requests that are safe to
+// repeat when a reused connection fails, following redirects, and waiting
+// for 100 Continue before sending the body of a request that expects it.
+//
+// The model's actions are the moves of a server, and Conform explores all
+// their sequences against a RoundTripper, in a synctest bubble, over a
+// simulated server on in-memory connections.
+package httpconf
+
+import (
+	"fmt"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// Request is a request a client is checked with.
+type Request struct {
+	Method         string
+	Body           string
+	ExpectContinue bool // sent with "Expect: 100-continue"
+}
+
+func (r Request) String() string {
+	s := r.Method
+	if r.Body != "" {
+		s += " with body"
+	}
+	if r.ExpectContinue {
+		s += " expecting 100-continue"
+	}
+	return s
+}
+
+// DefaultRequests are the requests Conform checks if given none.
+var DefaultRequests = []Request{
+	{Method: "GET"},
+	{Method: "POST", Body: "a=1"},
+	{Method: "PUT", Body: "upload", ExpectContinue: true},
+}
+
+// Phase is how far the server has received the current request.
+type Phase string
+
+const (
+	Awaiting Phase = "awaiting" // the headers, with the body withheld until 100 Continue
+	Sent     Phase = "sent"     // the whole request
+	Done     Phase = "done"     // the client returned
+)
+
+// Client is the state of a request through a client, as the server sees
+// it: the request on the wire, redirected or retried from the original
+// one, and the outcome.
+type Client struct {
+	Method   string
+	Path     string
+	Length   int64  // the declared length of the body
+	Body     string // the part of the body received
+	Phase    Phase
+	Requests int    // requests received, with redirects and retries
+	Conns    int    // connections the client opened
+	Reused   bool   // the request came on a connection used before
+	Result   string // the status code the client returned, "error" or "" while pending
+}
+
+// Model returns the model of a client sending r to the path "/". The
+// server may, as actions:
+//
+//   - continue, timeout: send 100 Continue, or let the client's wait for
+//     it time out; either way the client must then send the body.
+//   - reject: answer 417 before the body and close the connection; the
+//     client must then not send the body. On a connection kept open, it
+//     would have to, to frame the next request.
+//   - ok: answer 200, which the client returns.
+//   - see other: redirect to /next with 303, which the client follows
+//     with a GET without body.
+//   - temporary redirect: redirect to /next with 307, which the client
+//     follows with the same method and body, sent right away since the
+//     server took it once. The path bounds the redirects to one.
+//   - drop: close the connection before answering. The client retries a
+//     request with a safe method, GET, HEAD, OPTIONS or TRACE, whose body
+//     it can replay, but only if the connection was used before, since a
+//     fresh one failing suggests the server is down; otherwise it fails.
+func Model(r Request) *model.Model[Client] {
+	send := func(c Client, method string, body, expect bool) Client {
+		c.Method, c.Length, c.Body, c.Phase = method, 0, "", Sent
+		if body {
+			c.Length = int64(len(r.Body))
+			if expect {
+				c.Phase = Awaiting
+			} else {
+				c.Body = r.Body
+			}
+		}
+		c.Requests++
+		return c
+	}
+	in := func(p Phase) func(Client) bool { return func(c Client) bool { return c.Phase == p } }
+	done := func(result string) func(Client) Client {
+		return func(c Client) Client { c.Phase, c.Result = Done, result; return c }
+	}
+	redirect := func(c Client) bool { return c.Phase == Sent && c.Path == "/" }
+	initial := send(Client{Path: "/", Conns: 1}, r.Method, r.Body != "", r.ExpectContinue)
+	return &model.Model[Client]{
+		Name: fmt.Sprintf("client %v", r),
+		Init: initial,
+		Actions: []model.Action[Client]{
+			{Name: "continue", Guard: in(Awaiting), Step: func(c Client) Client { c.Phase, c.Body = Sent, r.Body; return c }},
+			{Name: "timeout", Guard: in(Awaiting), Step: func(c Client) Client { c.Phase, c.Body = Sent, r.Body; return c }},
+			{Name: "reject", Guard: in(Awaiting), Step: done("417")},
+			{Name: "ok", Guard: in(Sent), Step: done("200")},
+			{Name: "see other", Guard: redirect, Step: func(c Client) Client {
+				method := "GET"
+				if c.Method == "HEAD" {
+					method = "HEAD"
+				}
+				c = send(c, method, false, false)
+				c.Path, c.Reused = "/next", true
+				return c
+			}},
+			{Name: "temporary redirect", Guard: redirect, Step: func(c Client) Client {
+				c = send(c, c.Method, c.Length > 0, false)
+				c.Path, c.Reused = "/next", true
+				return c
+			}},
+			{Name: "drop", Guard: func(c Client) bool { return c.Phase != Done }, Step: func(c Client) Client {
+				if !c.Reused || !safe(c.Method) {
+					return done("error")(c)
+				}
+				c = send(c, c.Method, c.Length > 0, r.ExpectContinue && c.Path == "/")
+				c.Conns++
+				c.Reused = false
+				return c
+			}},
+		},
+	}
+}
+
+// safe reports whether method is one a client may repeat on its own.
+func safe(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
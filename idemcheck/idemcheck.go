@@ -0,0 +1,203 @@
+// Package idemcheck tests the claim that a message handler is
+// idempotent: that delivering a message more than once, as at-least-once
+// transports do, with the handler crashing between the deliveries or
+// amid one, leaves the same observable state as delivering it exactly
+// once.
+//
+// The handler keeps its state on a faultfs disk, where only what it
+// synced survives a crash, and reports the side effects it has on the
+// world, such as calls to other services, which no crash undoes. For every
+// message in turn, Check delivers it up to Config.Deliveries times before
+// it is acknowledged, each delivery but the last followed by one of: its
+// acknowledgement lost, a crash after it, or a crash before one of its
+// disk operations, each of them in turn. Then it delivers the message
+// until it is acknowledged, delivers the other messages once, and compares
+// the state and the effects with those of delivering every message once.
+//
+// The handler must return the errors of its disk operations, so that
+// Check can tell whether a crash point was reached; a crash before an
+// operation whose error it ignores, such as closing a file it read, is
+// not checked.
+package idemcheck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+)
+
+// Env is what an incarnation of a handler runs with.
+type Env struct {
+	Disk    *faultfs.FS
+	effects []string
+}
+
+// Effect records a side effect of the handler on the world, which no
+// crash undoes.
+func (e *Env) Effect(what string) { e.effects = append(e.effects, what) }
+
+// Handler is the handler under test.
+type Handler[M any] struct {
+	Name string
+	// Start starts an incarnation of the handler, the first or the one
+	// after a crash, and returns its function handling a delivery: an
+	// error leaves the message unacknowledged, to be delivered again.
+	Start func(env *Env) func(m M) error
+	// State returns the observable state on env.Disk, compared formatted
+	// with %v.
+	State func(env *Env) (any, error)
+}
+
+// Config bounds Check.
+type Config struct {
+	// Deliveries is how many times a message is delivered at most before
+	// the one that is acknowledged, counting it; default 3.
+	Deliveries int
+	// MaxOps bounds the disk operations of a delivery crashed before,
+	// default 64.
+	MaxOps int
+}
+
+func (c Config) deliveries() int {
+	if c.Deliveries > 0 {
+		return c.Deliveries
+	}
+	return 3
+}
+
+func (c Config) maxOps() int {
+	if c.MaxOps > 0 {
+		return c.MaxOps
+	}
+	return 64
+}
+
+// attempt is a delivery that is not acknowledged: after it, with op 0,
+// the acknowledgement is lost, or with crash the handler crashes; with op
+// positive, the handler crashes before its op-th disk operation.
+type attempt struct {
+	op    int
+	crash bool
+}
+
+func (a attempt) String() string {
+	switch {
+	case a.op > 0:
+		return fmt.Sprintf("crash before disk op %d", a.op)
+	case a.crash:
+		return "crash after it"
+	}
+	return "acknowledgement lost"
+}
+
+// errCrash fails the disk operation before which the handler crashes.
+var errCrash = errors.New("idemcheck: crash")
+
+// retries bounds the deliveries of a message once its attempts are done.
+const retries = 3
+
+// outcome is the observable state after a run.
+type outcome struct {
+	state   string
+	effects []string
+}
+
+func (o outcome) String() string {
+	return fmt.Sprintf("state %s, effects [%s]", o.state, strings.Join(o.effects, ", "))
+}
+
+// run delivers msgs to fresh incarnations of h on a fresh disk, the
+// message faulty after the attempts of plan, and reports whether the
+// crash point of the last of them was reached.
+func run[M any](h Handler[M], msgs []M, faulty int, plan []attempt) (o outcome, reached bool, err error) {
+	env := &Env{Disk: faultfs.New()}
+	handle := h.Start(env)
+	crash := func() {
+		env.Disk.Crash()
+		handle = h.Start(env)
+	}
+	for i, m := range msgs {
+		if i == faulty {
+			for _, a := range plan {
+				if a.op > 0 {
+					env.Disk.Inject(faultfs.Fault{Call: a.op, Err: errCrash})
+				}
+				err := handle(m)
+				env.Disk.Clear()
+				reached = errors.Is(err, errCrash)
+				if a.op > 0 || a.crash {
+					crash()
+				}
+			}
+		}
+		acked := false
+		for range retries {
+			if acked = handle(m) == nil; acked {
+				break
+			}
+		}
+		if !acked {
+			return outcome{}, reached, fmt.Errorf("message %d, %v, not acknowledged in %d deliveries", i+1, m, retries)
+		}
+	}
+	state, err := h.State(env)
+	if err != nil {
+		return outcome{}, reached, err
+	}
+	return outcome{fmt.Sprint(state), env.effects}, reached, nil
+}
+
+// Check fails t, for every message of msgs that some plan of deliveries
+// and crashes makes leave another state or other effects than delivering
+// every message once does, with the shortest such plan.
+func Check[M any](t testing.TB, h Handler[M], msgs []M, cfg Config) {
+	t.Helper()
+	want, _, err := run(h, msgs, -1, nil)
+	if err != nil {
+		t.Errorf("idemcheck: %s: delivering every message once: %v", h.Name, err)
+		return
+	}
+	for i, m := range msgs {
+		plans := [][]attempt{nil}
+		for len(plans) > 0 {
+			plan := plans[0]
+			plans = plans[1:]
+			if len(plan) > 0 {
+				got, reached, err := run(h, msgs, i, plan)
+				if last := plan[len(plan)-1]; last.op > 0 && !reached {
+					continue // not a crash point of the delivery
+				}
+				if err != nil {
+					t.Errorf("idemcheck: %s: %s: %v", h.Name, describe(i, m, plan), err)
+					break
+				}
+				if got.String() != want.String() {
+					t.Errorf("idemcheck: %s: %s: %v, want %v", h.Name, describe(i, m, plan), got, want)
+					break
+				}
+			}
+			if len(plan)+1 >= cfg.deliveries() {
+				continue
+			}
+			next := []attempt{{}, {crash: true}}
+			for op := 1; op <= cfg.maxOps(); op++ {
+				next = append(next, attempt{op: op})
+			}
+			for _, a := range next {
+				plans = append(plans, append(plan[:len(plan):len(plan)], a))
+			}
+		}
+	}
+}
+
+// describe describes the deliveries of the message i, m, after plan.
+func describe[M any](i int, m M, plan []attempt) string {
+	ds := make([]string, len(plan))
+	for j, a := range plan {
+		ds[j] = a.String()
+	}
+	return fmt.Sprintf("message %d, %v, delivered %d times: %s, then acknowledged", i+1, m, len(plan)+1, strings.Join(ds, "; "))
+}
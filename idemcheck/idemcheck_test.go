@@ -0,0 +1,206 @@
+package idemcheck
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// deposit is a message crediting an account.
+type deposit struct {
+	ID     string
+	Amount int
+}
+
+func (d deposit) String() string { return fmt.Sprintf("%s:%+d", d.ID, d.Amount) }
+
+var deposits = []deposit{{"d1", 10}, {"d2", 5}}
+
+// ledger is the account: its balance and the deposits applied to it.
+type ledger struct {
+	Balance int
+	Applied []string
+}
+
+// read reads the ledger from name, if it exists.
+func read(disk *faultfs.FS, name string) (ledger, error) {
+	data, err := disk.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ledger{}, nil
+	} else if err != nil {
+		return ledger{}, err
+	}
+	lines := strings.Fields(string(data))
+	if len(lines) == 0 {
+		return ledger{}, nil
+	}
+	balance, err := strconv.Atoi(lines[0])
+	return ledger{balance, lines[1:]}, err
+}
+
+// write writes l to name atomically: to a temporary file, synced, then
+// renamed into place.
+func write(disk *faultfs.FS, name string, l ledger) error {
+	f, err := disk.Create(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, strings.Join(append([]string{strconv.Itoa(l.Balance)}, l.Applied...), "\n"))
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return disk.Rename(name+".tmp", name)
+}
+
+func state(env *Env) (any, error) { return read(env.Disk, "ledger") }
+
+// naive applies every delivery.
+var naive = Handler[deposit]{
+	Name: "naive",
+	Start: func(env *Env) func(deposit) error {
+		return func(d deposit) error {
+			l, err := read(env.Disk, "ledger")
+			if err != nil {
+				return err
+			}
+			l.Balance += d.Amount
+			return write(env.Disk, "ledger", l)
+		}
+	},
+	State: state,
+}
+
+// remembering skips the deposits it applied, remembered in memory only.
+var remembering = Handler[deposit]{
+	Name: "remembering",
+	Start: func(env *Env) func(deposit) error {
+		applied := make(map[string]bool)
+		return func(d deposit) error {
+			if applied[d.ID] {
+				return nil
+			}
+			if err := naive.Start(env)(d); err != nil {
+				return err
+			}
+			applied[d.ID] = true
+			return nil
+		}
+	},
+	State: state,
+}
+
+// split keeps the balance and the deposits applied in two files, written
+// one after the other.
+var split = Handler[deposit]{
+	Name: "split",
+	Start: func(env *Env) func(deposit) error {
+		return func(d deposit) error {
+			seen, err := read(env.Disk, "applied")
+			if err != nil || slices.Contains(seen.Applied, d.ID) {
+				return err
+			}
+			l, err := read(env.Disk, "ledger")
+			if err != nil {
+				return err
+			}
+			l.Balance += d.Amount
+			if err := write(env.Disk, "ledger", l); err != nil {
+				return err
+			}
+			seen.Applied = append(seen.Applied, d.ID)
+			return write(env.Disk, "applied", seen)
+		}
+	},
+	State: func(env *Env) (any, error) {
+		l, err := read(env.Disk, "ledger")
+		return l.Balance, err
+	},
+}
+
+// atomic writes the balance and the deposits applied together; with
+// notify, it notifies the owner of the account of every deposit before
+// writing it.
+func atomic(notify bool) Handler[deposit] {
+	return Handler[deposit]{
+		Name: fmt.Sprintf("atomic, notify %t", notify),
+		Start: func(env *Env) func(deposit) error {
+			return func(d deposit) error {
+				l, err := read(env.Disk, "ledger")
+				if err != nil || slices.Contains(l.Applied, d.ID) {
+					return err
+				}
+				if notify {
+					env.Effect("notified " + d.String())
+				}
+				l.Balance += d.Amount
+				l.Applied = append(l.Applied, d.ID)
+				return write(env.Disk, "ledger", l)
+			}
+		},
+		State: state,
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		h    Handler[deposit]
+		want []string
+	}{
+		{naive, []string{
+			"idemcheck: naive: message 1, d1:+10, delivered 2 times: acknowledgement lost, then acknowledged: state {25 []}, effects [], want state {15 []}, effects []",
+			"idemcheck: naive: message 2, d2:+5, delivered 2 times: acknowledgement lost, then acknowledged: state {20 []}, effects [], want state {15 []}, effects []",
+		}},
+		{remembering, []string{
+			"idemcheck: remembering: message 1, d1:+10, delivered 2 times: crash after it, then acknowledged: state {25 []}, effects [], want state {15 []}, effects []",
+			"idemcheck: remembering: message 2, d2:+5, delivered 2 times: crash after it, then acknowledged: state {20 []}, effects [], want state {15 []}, effects []",
+		}},
+		{split, []string{
+			"idemcheck: split: message 1, d1:+10, delivered 2 times: crash before disk op 8, then acknowledged: state 25, effects [], want state 15, effects []",
+			"idemcheck: split: message 2, d2:+5, delivered 2 times: crash before disk op 14, then acknowledged: state 20, effects [], want state 15, effects []",
+		}},
+		{atomic(false), nil},
+		{atomic(true), []string{
+			"idemcheck: atomic, notify true: message 1, d1:+10, delivered 2 times: crash before disk op 2, then acknowledged: state {15 [d1 d2]}, effects [notified d1:+10, notified d1:+10, notified d2:+5], want state {15 [d1 d2]}, effects [notified d1:+10, notified d2:+5]",
+			"idemcheck: atomic, notify true: message 2, d2:+5, delivered 2 times: crash before disk op 5, then acknowledged: state {15 [d1 d2]}, effects [notified d1:+10, notified d2:+5, notified d2:+5], want state {15 [d1 d2]}, effects [notified d1:+10, notified d2:+5]",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.h.Name, func(t *testing.T) {
+			errs := testtb.Run(t, func(t testing.TB) { Check(t, tt.h, deposits, Config{}) })
+			if !slices.Equal(errs, tt.want) {
+				t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(errs, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnacknowledged(t *testing.T) {
+	failing := Handler[deposit]{
+		Name: "failing",
+		Start: func(env *Env) func(deposit) error {
+			return func(d deposit) error {
+				if d.ID == "d2" {
+					return errors.New("account closed")
+				}
+				return nil
+			}
+		},
+		State: state,
+	}
+	errs := testtb.Run(t, func(t testing.TB) { Check(t, failing, deposits, Config{}) })
+	want := "idemcheck: failing: delivering every message once: message 2, d2:+5, not acknowledged in 3 deliveries"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
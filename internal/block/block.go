@@ -0,0 +1,81 @@
+// Package block keeps track of what instrumented goroutines are blocked on,
+// so a stalled bubble can be explained in terms of channels and locks
+// instead of raw goroutine states.
+package block
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/timeline"
+)
+
+// Op describes a blocking operation in progress.
+type Op struct {
+	Kind   string    // "send", "recv", "select", "lock", ...
+	Object string    // what the goroutine waits for, e.g. a channel description
+	Site   string    // file:line of the operation in the caller's code
+	Since  time.Time // when the goroutine started to wait
+}
+
+func (op Op) String() string {
+	s := op.Kind + " " + op.Object
+	if op.Site != "" {
+		s += " at " + op.Site
+	}
+	return s
+}
+
+var (
+	mu      sync.Mutex
+	blocked = make(map[int64]Op)
+)
+
+// Enter records that the calling goroutine is about to block in op and
+// returns a function to call once it no longer is. skip is the number of
+// frames between the caller of Enter and the user code to attribute the
+// operation to.
+func Enter(op Op, skip int) (leave func()) {
+	id := goid.ID()
+	if op.Site == "" {
+		op.Site = Caller(skip + 1)
+	}
+	op.Since = time.Now()
+	mu.Lock()
+	blocked[id] = op
+	mu.Unlock()
+	timeline.Block(op.Kind+" "+op.Object, op.Site)
+	return func() {
+		mu.Lock()
+		delete(blocked, id)
+		mu.Unlock()
+		timeline.Unblock()
+	}
+}
+
+// Lookup returns the operation goroutine id is blocked in, if it was
+// recorded by Enter.
+func Lookup(id int64) (Op, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	op, ok := blocked[id]
+	return op, ok
+}
+
+// Caller returns the file:line skip frames above the caller of Caller.
+func Caller(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	if i := strings.LastIndex(file, "/"); i >= 0 {
+		if j := strings.LastIndex(file[:i], "/"); j >= 0 {
+			file = file[j+1:]
+		}
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
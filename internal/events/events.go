@@ -0,0 +1,84 @@
+// Package events writes the events of the harness, such as a failing
+// schedule or a violated invariant, as a stream of JSON objects, one per
+// line, to the file of the flag -synctest.events, for CI systems and
+// dashboards to read rather than parse the output of go test.
+package events
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+var file = flag.String("synctest.events", "", "file to append a line-delimited JSON stream of harness events to")
+
+// Event is an event of the harness. Its fields other than Test and Action
+// are left out when zero.
+type Event struct {
+	// Time is when the event was reported, in RFC 3339, unless it was
+	// reported from a bubble, whose clock is not the real one; Virtual is
+	// then the virtual time since the bubble started.
+	Time     string  `json:"time,omitempty"`
+	Virtual  string  `json:"virtual,omitempty"`
+	Test     string  `json:"test"`
+	Action   string  `json:"action"` // "start", "fail", "violation" or "done"
+	Seed     *uint64 `json:"seed,omitempty"`
+	Run      int     `json:"run,omitempty"` // counting from 1
+	Runs     int     `json:"runs,omitempty"`
+	Failures int     `json:"failures,omitempty"`
+	Schedule string  `json:"schedule,omitempty"`
+	Message  string  `json:"message,omitempty"`
+}
+
+// epoch is when synctest bubbles start.
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var mu sync.Mutex
+
+// Enabled reports whether events are written, for callers to skip the
+// work of describing them otherwise.
+func Enabled() bool { return *file != "" }
+
+// Emit writes e, as an event of t, unless events are not written. An
+// error writing it fails t.
+func Emit(t testing.TB, e Event) {
+	if !Enabled() {
+		return
+	}
+	t.Helper()
+	e.Test = t.Name()
+	if gstack.Self().Group != 0 {
+		e.Virtual = time.Since(epoch).String()
+	} else {
+		e.Time = time.Now().Format(time.RFC3339Nano)
+	}
+	line, err := json.Marshal(e)
+	if err == nil {
+		mu.Lock()
+		err = appendLine(*file, line)
+		mu.Unlock()
+	}
+	if err != nil {
+		t.Errorf("events: %v", err)
+	}
+}
+
+// Seed returns a pointer to seed, for Event.Seed.
+func Seed(seed uint64) *uint64 { return &seed }
+
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
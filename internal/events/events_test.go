@@ -0,0 +1,53 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestEmit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	flag.Set("synctest.events", path)
+	defer flag.Set("synctest.events", "")
+
+	Emit(t, Event{Action: "start", Runs: 2})
+	synctest.Run(func() {
+		time.Sleep(1500 * time.Millisecond)
+		Emit(t, Event{Action: "fail", Seed: Seed(0), Run: 1, Message: "broken"})
+	})
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []Event
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 2 {
+		t.Fatalf("events %+v, want 2", got)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, got[0].Time); err != nil || got[0].Virtual != "" {
+		t.Errorf("event outside a bubble at %q, virtual %q", got[0].Time, got[0].Virtual)
+	}
+	got[0].Time = ""
+	want := []Event{
+		{Test: "TestEmit", Action: "start", Runs: 2},
+		{Virtual: "1.5s", Test: "TestEmit", Action: "fail", Seed: Seed(0), Run: 1, Message: "broken"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+// Package goid identifies goroutines for diagnostics.
+package goid
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// ID returns the runtime's id of the calling goroutine, parsed from the
+// header of its stack trace. Ids are only meant for reports and bookkeeping
+// in tests; they are never reused while the goroutine is alive.
+func ID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
+
+// Stack returns the stack trace of the calling goroutine.
+func Stack() string {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+var (
+	namesMu sync.Mutex
+	names   = make(map[int64]string)
+)
+
+// SetName names the calling goroutine for reports. The name is forgotten
+// by Forget, which should be called when the goroutine exits.
+func SetName(name string) {
+	id := ID()
+	namesMu.Lock()
+	names[id] = name
+	namesMu.Unlock()
+}
+
+// Forget drops the name of the calling goroutine.
+func Forget() {
+	id := ID()
+	namesMu.Lock()
+	delete(names, id)
+	namesMu.Unlock()
+}
+
+// Name returns the name of goroutine id, or "g<id>" if it has none.
+func Name(id int64) string {
+	namesMu.Lock()
+	name, ok := names[id]
+	namesMu.Unlock()
+	if !ok {
+		return "g" + strconv.FormatInt(id, 10)
+	}
+	return name
+}
@@ -0,0 +1,29 @@
+package goid
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestID(t *testing.T) {
+	self := ID()
+	if self <= 0 {
+		t.Fatalf("ID() = %d, want a positive id", self)
+	}
+	other := make(chan int64)
+	go func() { other <- ID() }()
+	if id := <-other; id == self || id <= 0 {
+		t.Fatalf("goroutine ids %d and %d should differ and be positive", self, id)
+	}
+}
+
+func TestName(t *testing.T) {
+	if got, want := Name(ID()), "g"+strconv.FormatInt(ID(), 10); got != want {
+		t.Fatalf("Name of unnamed goroutine = %q, want %q", got, want)
+	}
+	SetName("main")
+	defer Forget()
+	if got := Name(ID()); got != "main" {
+		t.Fatalf("Name = %q, want main", got)
+	}
+}
@@ -0,0 +1,171 @@
+// Package gstack parses goroutine stack dumps, in particular to find the
+// goroutines belonging to a synctest bubble.
+package gstack
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing/synctest"
+)
+
+// Goroutine is one goroutine as seen in a stack dump.
+type Goroutine struct {
+	ID      int64
+	State   string  // e.g. "chan receive", "select", "sleep"
+	Group   int64   // id of the goroutine that started its bubble, 0 if none
+	Creator int64   // id of the goroutine whose go statement started it
+	Frames  []Frame // innermost first
+}
+
+// Frame is one function call in a goroutine's stack.
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+// All returns every goroutine of the program.
+func All() []Goroutine {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return parse(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// Group returns the goroutines of the bubble started by goroutine group,
+// not counting the starting goroutine itself.
+func Group(group int64) []Goroutine {
+	var gs []Goroutine
+	for _, g := range All() {
+		if g.Group == group && g.ID != group {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// Self returns the calling goroutine without its frames.
+func Self() Goroutine {
+	var buf [128]byte
+	gs := parse(buf[:runtime.Stack(buf[:], false)])
+	if len(gs) == 0 {
+		return Goroutine{}
+	}
+	gs[0].Frames = nil
+	return gs[0]
+}
+
+// Bubble returns the other goroutines of the caller's bubble, or nil if the
+// caller is not in a bubble.
+func Bubble() []Goroutine {
+	self := Self()
+	if self.Group == 0 {
+		return nil
+	}
+	var gs []Goroutine
+	for _, g := range Group(self.Group) {
+		if g.ID != self.ID {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// parse parses the output of runtime.Stack. Goroutines inside a bubble
+// carry a "synctest group N" annotation in their header, where N is the id
+// of the goroutine that started the bubble.
+func parse(buf []byte) []Goroutine {
+	var gs []Goroutine
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		lines := strings.Split(strings.TrimSpace(string(block)), "\n")
+		header, ok := strings.CutPrefix(lines[0], "goroutine ")
+		if !ok {
+			continue
+		}
+		idStr, rest, _ := strings.Cut(header, " ")
+		var g Goroutine
+		g.ID, _ = strconv.ParseInt(idStr, 10, 64)
+		rest = strings.TrimSuffix(strings.TrimPrefix(rest, "["), "]:")
+		for i, attr := range strings.Split(rest, ", ") {
+			if i == 0 {
+				g.State = strings.TrimSuffix(attr, " (synctest)")
+			} else if s, ok := strings.CutPrefix(attr, "synctest group "); ok {
+				g.Group, _ = strconv.ParseInt(s, 10, 64)
+			}
+		}
+		for i := 1; i+1 < len(lines); i += 2 {
+			fn := lines[i]
+			if by, ok := strings.CutPrefix(fn, "created by "); ok {
+				if _, id, ok := strings.Cut(by, " in goroutine "); ok {
+					g.Creator, _ = strconv.ParseInt(id, 10, 64)
+				}
+				continue
+			}
+			if j := strings.LastIndexByte(fn, '('); j > 0 {
+				fn = fn[:j]
+			}
+			loc := strings.TrimSpace(lines[i+1])
+			if j := strings.LastIndex(loc, " +0x"); j >= 0 {
+				loc = loc[:j]
+			}
+			file, line, _ := strings.Cut(loc, ":")
+			n, _ := strconv.Atoi(line)
+			g.Frames = append(g.Frames, Frame{Func: fn, File: file, Line: n})
+		}
+		gs = append(gs, g)
+	}
+	return gs
+}
+
+// UserFrame returns the innermost frame outside the runtime, the standard
+// library's synchronization internals and this module's instrumentation,
+// which is usually where the goroutine is stuck.
+func (g Goroutine) UserFrame() (Frame, bool) {
+	for _, f := range g.Frames {
+		if strings.HasPrefix(f.Func, "runtime.") || strings.HasPrefix(f.Func, "internal/") ||
+			strings.HasPrefix(f.Func, "reflect.") || strings.HasPrefix(f.Func, "sync.") ||
+			strings.HasPrefix(f.Func, "testing/synctest.") || strings.Contains(f.Func, "/internal/block.") {
+			continue
+		}
+		return f, true
+	}
+	return Frame{}, false
+}
+
+// Run runs f in a new synctest bubble and returns the id the bubble's
+// goroutines carry as their Group, and what Run panicked with, if
+// anything. If f calls runtime.Goexit, as t.FailNow does, so does Run.
+//
+// The runtime names a bubble after the goroutine that started it and
+// reuses the structures of exited goroutines, so the goroutines a
+// deadlocked bubble leaves behind would be counted in the bubble of
+// whichever goroutine next takes over the starter's structure. Run
+// therefore starts the bubble from a goroutine of its own, which never
+// exits unless the bubble ends normally.
+func Run(f func()) (group int64, panicked any) {
+	done := make(chan struct{})
+	returned := false
+	go func() {
+		group = Self().ID
+		defer func() {
+			panicked = recover()
+			close(done)
+			if !returned {
+				select {}
+			}
+		}()
+		synctest.Run(f)
+		returned = true
+	}()
+	<-done
+	if !returned && panicked == nil {
+		runtime.Goexit()
+	}
+	return group, panicked
+}
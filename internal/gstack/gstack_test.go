@@ -0,0 +1,64 @@
+package gstack
+
+import (
+	"testing"
+	"testing/synctest"
+)
+
+func TestGroup(t *testing.T) {
+	synctest.Run(func() {
+		block := make(chan struct{})
+		go func() { <-block }()
+		synctest.Wait()
+		var group int64
+		for _, g := range All() {
+			if g.State == "chan receive" && g.Group != 0 {
+				group = g.Group
+			}
+		}
+		gs := Group(group)
+		// the bubble's root goroutine and the blocked one
+		if len(gs) != 2 {
+			t.Fatalf("Group(%d) = %+v, want two goroutines", group, gs)
+		}
+		for _, g := range gs {
+			if g.State != "chan receive" {
+				continue
+			}
+			if f, ok := g.UserFrame(); !ok || f.Func != "github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack.TestGroup.func1.1" {
+				t.Fatalf("UserFrame() = %+v", f)
+			}
+		}
+		close(block)
+	})
+}
+
+func TestBubble(t *testing.T) {
+	if gs := Bubble(); gs != nil {
+		t.Fatalf("Bubble() outside a bubble = %v, want nil", gs)
+	}
+	synctest.Run(func() {
+		block := make(chan struct{})
+		go func() { <-block }()
+		synctest.Wait()
+		gs := Bubble()
+		if len(gs) != 1 || gs[0].State != "chan receive" {
+			t.Fatalf("Bubble() = %+v, want one goroutine in chan receive", gs)
+		}
+		if f, ok := gs[0].UserFrame(); !ok || f.Func != "github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack.TestBubble.func1.1" {
+			t.Fatalf("UserFrame() = %+v", f)
+		}
+		close(block)
+	})
+}
+
+func TestSelf(t *testing.T) {
+	if g := Self(); g.ID == 0 || g.Group != 0 || g.State != "running" {
+		t.Fatalf("Self() outside a bubble = %+v", g)
+	}
+	synctest.Run(func() {
+		if g := Self(); g.Group == 0 {
+			t.Fatalf("Self() inside a bubble = %+v, want a group", g)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+// Package inject lets a test harness act before the operations of the
+// instrumented packages, without them depending on the harness: package
+// sched delays them.
+package inject
+
+import "sync/atomic"
+
+var hook atomic.Pointer[func()]
+
+// Point is called by instrumented code before an operation.
+func Point() {
+	if f := hook.Load(); f != nil {
+		(*f)()
+	}
+}
+
+// Set makes Point call f.
+func Set(f func()) { hook.Store(&f) }
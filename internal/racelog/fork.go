@@ -0,0 +1,101 @@
+package racelog
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// forkEnv tells a child process started by Fork which of the calls of Fork
+// in the test to run, and what the earlier ones returned.
+const forkEnv = "RACELOG_FORK"
+
+// fork is the value of forkEnv.
+type fork struct {
+	Call  int    // the call to run, counting from 0
+	Prior string // file of the outputs of the earlier calls
+	Arg   string
+}
+
+var (
+	mu    sync.Mutex
+	calls = make(map[testing.TB][]string) // outputs of the calls of Fork, by test
+)
+
+// Fork runs the calling test again in a child process of the test binary,
+// with reports of every race, not only the first, and returns its
+// combined output, for Parse. In the child the same call of Fork instead
+// runs inChild with arg and ends the process; the earlier calls of the
+// test return what they returned in the parent, so that the test takes
+// the same path to it. Fork returns false in the child if the test takes
+// another.
+func Fork(t testing.TB, arg string, inChild func(arg string)) (out string, ok bool) {
+	t.Helper()
+	mu.Lock()
+	n := len(calls[t])
+	prior := calls[t]
+	mu.Unlock()
+	if env, ok := os.LookupEnv(forkEnv); ok {
+		var f fork
+		if err := json.Unmarshal([]byte(env), &f); err != nil {
+			t.Fatalf("racelog: %s: %v", forkEnv, err)
+		}
+		if n == f.Call {
+			inChild(f.Arg)
+			os.Exit(0)
+		}
+		if n > f.Call {
+			return "", false
+		}
+		b, err := os.ReadFile(f.Prior)
+		if err == nil {
+			err = json.Unmarshal(b, &prior)
+		}
+		if err != nil || n >= len(prior) {
+			t.Fatalf("racelog: outputs of the earlier calls: %v", err)
+		}
+		out = prior[n]
+	} else {
+		out = run(t, fork{n, filepath.Join(t.TempDir(), "prior.json"), arg}, prior)
+	}
+	mu.Lock()
+	calls[t] = append(calls[t], out)
+	mu.Unlock()
+	return out, true
+}
+
+// run runs the calling test in a child process to make the call of f.
+func run(t testing.TB, f fork, prior []string) string {
+	t.Helper()
+	b, err := json.Marshal(prior)
+	if err == nil {
+		err = os.WriteFile(f.Prior, b, 0o644)
+	}
+	if err != nil {
+		t.Fatalf("racelog: %v", err)
+	}
+	env, _ := json.Marshal(f)
+	cmd := exec.Command(os.Args[0], "-test.run="+runPattern(t.Name()), "-test.count=1")
+	cmd.Env = append(os.Environ(), forkEnv+"="+string(env),
+		"GORACE=halt_on_error=0 suppress_equal_stacks=0 suppress_equal_addresses=0")
+	out, err := cmd.CombinedOutput()
+	if _, exit := err.(*exec.ExitError); err != nil && !exit {
+		t.Fatalf("racelog: %v", err)
+	}
+	return string(out)
+}
+
+// runPattern returns the -test.run pattern matching the test named name
+// alone.
+func runPattern(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
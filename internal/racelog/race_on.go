@@ -0,0 +1,6 @@
+//go:build race
+
+package racelog
+
+// Enabled reports whether the race detector is on.
+const Enabled = true
@@ -0,0 +1,123 @@
+// Package racelog parses the reports the race detector writes to standard
+// error.
+package racelog
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Report is one report of a data race.
+type Report struct {
+	// Accesses are the racing accesses, the one detected first, then the
+	// earlier one it races with.
+	Accesses []Access
+	// Created tells where the goroutines involved were started, by id.
+	Created map[int64][]gstack.Frame
+	Text    string // the report as printed
+}
+
+// Access is one of the racing memory accesses.
+type Access struct {
+	Op        string // "read", "write", "atomic read", ...
+	Addr      string
+	Goroutine int64 // 0 for the main goroutine
+	Frames    []gstack.Frame
+}
+
+// Site returns the innermost frame of the access outside the runtime and
+// the standard library's synchronization.
+func (a Access) Site() gstack.Frame {
+	f, _ := gstack.Goroutine{Frames: a.Frames}.UserFrame()
+	return f
+}
+
+// Key identifies the race by the sites of its accesses, which are the
+// same wherever the racing goroutines and variables are.
+func (r Report) Key() string {
+	var sites []string
+	for _, a := range r.Accesses {
+		f := a.Site()
+		sites = append(sites, a.Op+" "+f.Func+" "+f.File+":"+strconv.Itoa(f.Line))
+	}
+	slices.Sort(sites)
+	return strings.Join(sites, "\n")
+}
+
+const separator = "=================="
+
+var (
+	accessRE  = regexp.MustCompile(`^(?:Previous )?((?i:atomic )?(?i:read|write)) at (0x[0-9a-f]+) by (?:goroutine (\d+)|main goroutine):$`)
+	createdRE = regexp.MustCompile(`^Goroutine (\d+) \(\w+\) created at:$`)
+)
+
+// Parse returns the race reports in out, in order.
+func Parse(out string) []Report {
+	var reps []Report
+	lines := strings.Split(out, "\n")
+	for i := 0; i < len(lines); i++ {
+		if lines[i] != separator || i+1 >= len(lines) || lines[i+1] != "WARNING: DATA RACE" {
+			continue
+		}
+		start := i
+		end := start + 2
+		for end < len(lines) && lines[end] != separator {
+			end++
+		}
+		reps = append(reps, parse(lines[start+2:end]))
+		reps[len(reps)-1].Text = strings.Join(lines[start:min(end+1, len(lines))], "\n")
+		i = end
+	}
+	return reps
+}
+
+// parse parses the sections of a report: a header line, then frames as
+// pairs of lines, then an empty line.
+func parse(lines []string) Report {
+	r := Report{Created: make(map[int64][]gstack.Frame)}
+	var frames *[]gstack.Frame
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := accessRE.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.ParseInt(m[3], 10, 64)
+			r.Accesses = append(r.Accesses, Access{Op: strings.ToLower(m[1]), Addr: m[2], Goroutine: id})
+			frames = &r.Accesses[len(r.Accesses)-1].Frames
+			continue
+		}
+		if m := createdRE.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.ParseInt(m[1], 10, 64)
+			var fs []gstack.Frame
+			frames = nil
+			for i+2 < len(lines) && strings.HasPrefix(lines[i+1], "  ") {
+				fs = append(fs, frame(lines[i+1], lines[i+2]))
+				i += 2
+			}
+			r.Created[id] = fs
+			continue
+		}
+		if frames != nil && strings.HasPrefix(line, "  ") && i+1 < len(lines) {
+			*frames = append(*frames, frame(line, lines[i+1]))
+			i++
+		}
+	}
+	return r
+}
+
+// frame parses a function line and the location line below it.
+func frame(fn, loc string) gstack.Frame {
+	fn = strings.TrimSpace(fn)
+	if j := strings.LastIndexByte(fn, '('); j > 0 {
+		fn = fn[:j]
+	}
+	loc = strings.TrimSpace(loc)
+	if j := strings.LastIndex(loc, " +0x"); j >= 0 {
+		loc = loc[:j]
+	}
+	file, line, _ := strings.Cut(loc, ":")
+	n, _ := strconv.Atoi(line)
+	return gstack.Frame{Func: fn, File: file, Line: n}
+}
@@ -0,0 +1,40 @@
+package racelog
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+func TestParse(t *testing.T) {
+	out, err := os.ReadFile("testdata/races.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reps := Parse(string(out))
+	if len(reps) != 2 {
+		t.Fatalf("%d reports, want 2", len(reps))
+	}
+	r := reps[0]
+	if len(r.Accesses) != 2 || r.Accesses[0].Op != "read" || r.Accesses[0].Goroutine != 7 || r.Accesses[1].Op != "write" || r.Accesses[1].Goroutine != 8 {
+		t.Fatalf("accesses %+v", r.Accesses)
+	}
+	if got, want := r.Accesses[1].Frames, []gstack.Frame{{Func: "rr.TestR.func1", File: "/tmp/rr/r_test.go", Line: 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("frames %+v, want %+v", got, want)
+	}
+	if got := r.Created[8]; len(got) != 3 || got[0].Line != 5 {
+		t.Errorf("goroutine 8 created at %+v", got)
+	}
+	if !strings.HasPrefix(r.Text, "==================\nWARNING: DATA RACE\nRead at") || !strings.HasSuffix(r.Text, "\n==================") {
+		t.Errorf("text %q", r.Text)
+	}
+
+	// The map accesses are attributed to the code indexing the map.
+	want := "read rr.TestR /tmp/rr/r_test.go:10\nwrite rr.TestR.func2 /tmp/rr/r_test.go:9"
+	if got := reps[1].Key(); got != want {
+		t.Errorf("key %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+// Package testtb provides a testing.TB that records failures instead of
+// failing the test, for testing this module's assertion helpers.
+package testtb
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// Recorder is a testing.TB that records the messages of Error, Errorf,
+// Fatal and Fatalf. Like the real ones Fatal and Fatalf end the calling
+// goroutine with runtime.Goexit. Everything else is forwarded to the
+// embedded TB.
+type Recorder struct {
+	testing.TB
+
+	mu     sync.Mutex
+	errors []string
+}
+
+// New returns a Recorder forwarding to t.
+func New(t testing.TB) *Recorder {
+	return &Recorder{TB: t}
+}
+
+func (r *Recorder) Helper() {}
+
+func (r *Recorder) Error(args ...any) { r.record(fmt.Sprintln(args...)) }
+
+func (r *Recorder) Errorf(format string, args ...any) { r.record(fmt.Sprintf(format, args...)) }
+
+func (r *Recorder) Fatal(args ...any) {
+	r.Error(args...)
+	runtime.Goexit()
+}
+
+func (r *Recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (r *Recorder) Fail() { r.record("") }
+
+func (r *Recorder) FailNow() {
+	r.Fail()
+	runtime.Goexit()
+}
+
+// Failed reports whether a failure was recorded.
+func (r *Recorder) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.errors) > 0
+}
+
+// Errors returns the recorded messages.
+func (r *Recorder) Errors() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.errors...)
+}
+
+func (r *Recorder) record(msg string) {
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	r.mu.Lock()
+	r.errors = append(r.errors, msg)
+	r.mu.Unlock()
+}
+
+// Run runs fn with a new Recorder in its own goroutine, so a Fatalf ends
+// only fn, and returns the failures it recorded.
+func Run(t testing.TB, fn func(t testing.TB)) []string {
+	r := New(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+	return r.Errors()
+}
@@ -0,0 +1,29 @@
+package testtb
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	errs := Run(t, func(t testing.TB) {
+		t.Errorf("first %d", 1)
+		t.Error("second")
+		t.Fatalf("fatal")
+		t.Error("not reached")
+	})
+	if want := []string{"first 1", "second", "fatal"}; !slices.Equal(errs, want) {
+		t.Errorf("Run recorded %q, want %q", errs, want)
+	}
+}
+
+func TestRecorderFailed(t *testing.T) {
+	r := New(t)
+	if r.Failed() {
+		t.Fatal("new Recorder reports a failure")
+	}
+	r.Fail()
+	if !r.Failed() {
+		t.Fatal("Fail not recorded")
+	}
+}
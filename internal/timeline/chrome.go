@@ -0,0 +1,55 @@
+package timeline
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// traceEvent is an event of the Trace Event Format of Chrome.
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Ph    string         `json:"ph"`
+	Ts    float64        `json:"ts"` // microseconds
+	Pid   int            `json:"pid"`
+	Tid   int            `json:"tid"`
+	Scope string         `json:"s,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes tl to w in the Trace Event Format of Chrome, for
+// Perfetto or chrome://tracing to show: a thread for each goroutine,
+// running from its start to its exit, with a slice for every time it
+// blocked, and instants for its operations, marks, notes and failures.
+// Times are virtual.
+func (tl *Timeline) WriteChromeTrace(w io.Writer) error {
+	tid := make(map[int64]int)
+	evs := []traceEvent{{Name: "process_name", Ph: "M", Pid: 1, Args: map[string]any{"name": "bubble"}}}
+	for i, l := range tl.Lanes() {
+		tid[l.Events[0].g] = i + 1
+		evs = append(evs, traceEvent{Name: "thread_name", Ph: "M", Pid: 1, Tid: i + 1, Args: map[string]any{"name": l.Goroutine}})
+	}
+	for _, e := range tl.Events() {
+		te := traceEvent{Name: e.What, Cat: e.Kind, Ts: float64(e.At.Nanoseconds()) / 1e3, Pid: 1, Tid: tid[e.g]}
+		if e.Site != "" {
+			te.Args = map[string]any{"site": e.Site}
+		}
+		switch e.Kind {
+		case "spawn":
+			te.Name, te.Ph = e.Goroutine, "B"
+		case "exit":
+			te.Ph = "E"
+		case "block":
+			te.Ph = "B"
+		case "unblock":
+			te.Ph = "E"
+		default:
+			te.Ph, te.Scope = "i", "t"
+		}
+		evs = append(evs, te)
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{evs, "ms"})
+}
@@ -0,0 +1,134 @@
+package timeline
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("synctest.update", false, "rewrite the golden files of timelines instead of checking them")
+
+var (
+	unnamed  = regexp.MustCompile(`^g\d+$`)
+	lines    = regexp.MustCompile(`\.go:\d+`)
+	goroutID = regexp.MustCompile(`goroutine \d+`)
+)
+
+// Normalized returns the structure of tl, without what varies from run to
+// run of the same code: the events of each goroutine, the goroutines in
+// the order of their names, with times replaced by the rank of their
+// instant among those of the timeline, unnamed goroutines numbered in the
+// order they were first seen, and no line numbers or goroutine ids.
+func (tl *Timeline) Normalized() string {
+	lanes := tl.Lanes()
+	var instants []time.Duration
+	for _, l := range lanes {
+		for _, e := range l.Events {
+			instants = append(instants, e.At)
+		}
+	}
+	slices.Sort(instants)
+	instants = slices.Compact(instants)
+	n := 0
+	for i, l := range lanes {
+		if unnamed.MatchString(l.Goroutine) {
+			n++
+			lanes[i].Goroutine = fmt.Sprintf("g#%d", n)
+		}
+	}
+	slices.SortStableFunc(lanes, func(a, b Lane) int { return strings.Compare(a.Goroutine, b.Goroutine) })
+	var b strings.Builder
+	for _, l := range lanes {
+		fmt.Fprintf(&b, "%s:\n", l.Goroutine)
+		for _, e := range l.Events {
+			rank, _ := slices.BinarySearch(instants, e.At)
+			fmt.Fprintf(&b, "\t@%d %s", rank, e.Kind)
+			if e.What != "" {
+				fmt.Fprintf(&b, " %s", goroutID.ReplaceAllString(lines.ReplaceAllString(e.What, ".go"), "goroutine"))
+			}
+			if e.Site != "" {
+				fmt.Fprintf(&b, " at %s", lines.ReplaceAllString(e.Site, ".go"))
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// CheckGolden fails t if the Normalized timeline differs from the golden
+// file at path, showing the difference. With -synctest.update it writes
+// the file instead.
+func (tl *Timeline) CheckGolden(t testing.TB, path string) {
+	t.Helper()
+	got := tl.Normalized()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("timeline: no golden file %s; create it with -synctest.update", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("timeline differs from %s (-want +got); update it with -synctest.update if intended:\n%s", path, diff(string(want), got))
+	}
+}
+
+// diff returns the lines of want and got, those only in want marked "-"
+// and those only in got "+", by a longest common subsequence.
+func diff(want, got string) string {
+	a := strings.SplitAfter(want, "\n")
+	b := strings.SplitAfter(got, "\n")
+	// lcs[i][j] is the length of a longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var s strings.Builder
+	line := func(mark, l string) {
+		if l != "" {
+			s.WriteString(mark + strings.TrimSuffix(l, "\n") + "\n")
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			line(" ", a[i])
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			line("+", b[j])
+			j++
+		default:
+			line("-", a[i])
+			i++
+		}
+	}
+	return s.String()
+}
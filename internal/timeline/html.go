@@ -0,0 +1,127 @@
+package timeline
+
+import (
+	"flag"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var reportDir = flag.String("synctest.report", "", "directory to write HTML reports of the timelines of bubbles to")
+
+// cell is an event in the lane of its goroutine, or what the goroutine
+// does meanwhile.
+type cell struct {
+	Event *Event
+	State string // "", "running", "blocked" or "exited"
+}
+
+type row struct {
+	At    time.Duration
+	Cells []cell
+}
+
+// page is what the report shows.
+type page struct {
+	Test   string
+	Failed bool
+	Lanes  []string
+	Rows   []row
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Test}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; }
+th, td { border-left: 1px solid #ccc; padding: 2px 8px; vertical-align: top; text-align: left; }
+th { position: sticky; top: 0; background: #fff; border-bottom: 1px solid #888; }
+td.at { color: #666; white-space: nowrap; }
+td.running { background: #eef7ee; }
+td.blocked { background: #fbeaea; }
+td.exited { background: #f2f2f2; }
+.spawn, .exit { font-weight: bold; }
+.block { color: #a00; }
+.mark { color: #05a; }
+.note { color: #060; font-style: italic; }
+.fail { color: #fff; background: #c00; padding: 0 4px; }
+.site { color: #888; font-size: 11px; }
+</style>
+</head>
+<body>
+<h1>{{.Test}}: {{if .Failed}}failed{{else}}passed{{end}}</h1>
+<table>
+<tr><th>virtual time</th>{{range .Lanes}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr><td class="at">+{{.At}}</td>{{range .Cells}}<td class="{{.State}}">{{with .Event}}<span class="{{.Kind}}">{{.Kind}}{{with .What}} {{.}}{{end}}</span>{{with .Site}}<div class="site">{{.}}</div>{{end}}{{end}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// writeReports writes the HTML report of tl of the test named test to
+// dir, with its Chrome trace and blocking profile beside it, and returns
+// the report's path.
+func (tl *Timeline) writeReports(dir, test string, failed bool) (string, error) {
+	lanes := tl.Lanes()
+	p := page{Test: test, Failed: failed}
+	lane := make(map[int64]int)
+	for i, l := range lanes {
+		p.Lanes = append(p.Lanes, l.Goroutine)
+		lane[l.Events[0].g] = i
+	}
+	states := make([]string, len(lanes))
+	events := tl.Events()
+	for i := range events {
+		e := &events[i]
+		l := lane[e.g]
+		switch e.Kind {
+		case "spawn", "unblock":
+			states[l] = "running"
+		case "block":
+			states[l] = "blocked"
+		case "exit":
+			states[l] = "exited"
+		default:
+			if states[l] == "" {
+				states[l] = "running"
+			}
+		}
+		r := row{At: e.At, Cells: make([]cell, len(lanes))}
+		for j := range r.Cells {
+			r.Cells[j].State = states[j]
+		}
+		r.Cells[l].Event = e
+		p.Rows = append(p.Rows, r)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, strings.NewReplacer("/", "_", " ", "_").Replace(test))
+	err := writeFile(base+".html", func(w io.Writer) error { return reportTemplate.Execute(w, p) })
+	if err == nil {
+		err = writeFile(base+".json", tl.WriteChromeTrace)
+	}
+	if err == nil {
+		err = writeFile(base+".block.pb.gz", tl.WriteBlockProfile)
+	}
+	return base + ".html", err
+}
+
+// writeFile creates the file at path with what write writes.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package timeline
+
+import (
+	"compress/gzip"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Blocking is how long goroutines were blocked, in virtual time, in an
+// operation at a site.
+type Blocking struct {
+	What  string // the operation, without goroutine ids
+	Site  string
+	Count int           // times goroutines blocked
+	Delay time.Duration // in total
+}
+
+// BlockProfile returns the time the goroutines of tl spent blocked, by
+// operation and site, the longest first. A goroutine still blocked counts
+// until the last event recorded.
+func (tl *Timeline) BlockProfile() []Blocking {
+	events := tl.Events()
+	if len(events) == 0 {
+		return nil
+	}
+	end := events[len(events)-1].At
+	type key struct{ what, site string }
+	index := make(map[key]int)
+	var prof []Blocking
+	for _, l := range tl.Lanes() {
+		var blocked *Event
+		count := func(until time.Duration) {
+			k := key{goroutID.ReplaceAllString(blocked.What, "goroutine"), blocked.Site}
+			i, ok := index[k]
+			if !ok {
+				i = len(prof)
+				index[k] = i
+				prof = append(prof, Blocking{What: k.what, Site: k.site})
+			}
+			prof[i].Count++
+			prof[i].Delay += until - blocked.At
+			blocked = nil
+		}
+		for i, e := range l.Events {
+			switch e.Kind {
+			case "block":
+				blocked = &l.Events[i]
+			case "unblock", "exit":
+				if blocked != nil {
+					count(e.At)
+				}
+			}
+		}
+		if blocked != nil {
+			count(end)
+		}
+	}
+	slices.SortStableFunc(prof, func(a, b Blocking) int {
+		if a.Delay != b.Delay {
+			return int(b.Delay - a.Delay)
+		}
+		return strings.Compare(a.Site, b.Site)
+	})
+	return prof
+}
+
+// WriteBlockProfile writes the BlockProfile of tl to w as a gzipped pprof
+// profile, like the block profile of the runtime: with the number of times
+// and the nanoseconds of virtual time goroutines were blocked for a stack
+// of the operation called from its site, for go tool pprof to show.
+func (tl *Timeline) WriteBlockProfile(w io.Writer) error {
+	var p protobuf
+	strs := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		i, ok := strs[s]
+		if !ok {
+			i = int64(len(table))
+			strs[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+	valueType := func(field int, typ, unit string) {
+		var vt protobuf
+		vt.int(1, str(typ))
+		vt.int(2, str(unit))
+		p.bytes(field, vt)
+	}
+	valueType(1, "contentions", "count")
+	valueType(1, "delay", "nanoseconds")
+	// A function and a location for every operation and every site.
+	funcs := make(map[string]uint64)
+	location := func(name, file string, line int64) uint64 {
+		if id, ok := funcs[name]; ok {
+			return id
+		}
+		id := uint64(len(funcs) + 1)
+		funcs[name] = id
+		var fn protobuf
+		fn.uint(1, id)
+		fn.int(2, str(name))
+		fn.int(3, str(name))
+		fn.int(4, str(file))
+		p.bytes(5, fn)
+		var ln protobuf
+		ln.uint(1, id)
+		ln.int(2, line)
+		var loc protobuf
+		loc.uint(1, id)
+		loc.bytes(4, ln)
+		p.bytes(4, loc)
+		return id
+	}
+	for _, b := range tl.BlockProfile() {
+		file, line := b.Site, int64(0)
+		if i := strings.LastIndex(b.Site, ":"); i >= 0 {
+			file = b.Site[:i]
+			for _, c := range b.Site[i+1:] {
+				line = 10*line + int64(c-'0')
+			}
+		}
+		var s protobuf
+		s.packed(1, []uint64{location(b.What, "", 0), location(b.Site, file, line)})
+		s.packed(2, []uint64{uint64(b.Count), uint64(b.Delay.Nanoseconds())})
+		p.bytes(2, s)
+	}
+	for _, s := range table {
+		p.string(6, s)
+	}
+	valueType(11, "delay", "nanoseconds")
+	p.int(12, 1)
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(p); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// protobuf is an encoded protocol buffer message.
+type protobuf []byte
+
+func (p *protobuf) varint(v uint64) {
+	for v >= 0x80 {
+		*p = append(*p, byte(v)|0x80)
+		v >>= 7
+	}
+	*p = append(*p, byte(v))
+}
+
+func (p *protobuf) uint(field int, v uint64) {
+	p.varint(uint64(field) << 3)
+	p.varint(v)
+}
+
+func (p *protobuf) int(field int, v int64) { p.uint(field, uint64(v)) }
+
+func (p *protobuf) bytes(field int, b []byte) {
+	p.varint(uint64(field)<<3 | 2)
+	p.varint(uint64(len(b)))
+	*p = append(*p, b...)
+}
+
+func (p *protobuf) string(field int, s string) { p.bytes(field, []byte(s)) }
+
+func (p *protobuf) packed(field int, vs []uint64) {
+	var b protobuf
+	for _, v := range vs {
+		b.varint(v)
+	}
+	p.bytes(field, b)
+}
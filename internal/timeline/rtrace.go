@@ -0,0 +1,62 @@
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+)
+
+// runtimeTrace writes the events of a timeline to the execution trace of
+// runtime/trace, so that go tool trace shows them beside the scheduling
+// of the goroutines by the runtime: the recording is a task named after
+// the test, each time a goroutine blocks a region of it on the goroutine,
+// and every other event a log message on it, prefixed by its virtual time.
+// A nil runtimeTrace writes nothing.
+type runtimeTrace struct {
+	ctx     context.Context
+	task    *trace.Task
+	regions map[int64]*trace.Region // open, by goroutine
+}
+
+// startTrace starts the task of the recording of test, if runtime/trace
+// is on.
+func startTrace(test string) *runtimeTrace {
+	if !trace.IsEnabled() {
+		return nil
+	}
+	ctx, task := trace.NewTask(context.Background(), "bubble "+test)
+	return &runtimeTrace{ctx: ctx, task: task, regions: make(map[int64]*trace.Region)}
+}
+
+// add writes e, an event of the calling goroutine.
+func (rt *runtimeTrace) add(e Event) {
+	if rt == nil {
+		return
+	}
+	switch e.Kind {
+	case "block":
+		rt.regions[e.g] = trace.StartRegion(rt.ctx, fmt.Sprintf("+%v %s at %s", e.At, e.What, e.Site))
+	case "unblock":
+		if r := rt.regions[e.g]; r != nil {
+			r.End()
+			delete(rt.regions, e.g)
+		}
+	default:
+		msg := fmt.Sprintf("+%v %s", e.At, e.Goroutine)
+		if e.What != "" {
+			msg += " " + e.What
+		}
+		if e.Site != "" {
+			msg += " at " + e.Site
+		}
+		trace.Log(rt.ctx, e.Kind, msg)
+	}
+}
+
+// end ends the task. Regions still open are left to the goroutines, which
+// alone can end them, and are cut off by the task's end.
+func (rt *runtimeTrace) end() {
+	if rt != nil {
+		rt.task.End()
+	}
+}
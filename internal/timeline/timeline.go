@@ -0,0 +1,218 @@
+// Package timeline records when the goroutines of a bubble start, block,
+// unblock and exit, in virtual time, as package timeline exposes. The
+// instrumented packages report the events here.
+package timeline
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Event is a change of what a goroutine does.
+type Event struct {
+	At        time.Duration // virtual time since the recording started
+	Goroutine string        // name given to bubble.Go or sched.Go, or "g<id>"
+	Kind      string        // "spawn", "block", "unblock", "exit", "op", "mark", "note" or "fail"
+	What      string        // the operation, e.g. "recv on chan c (0/1)", label, note or failure
+	Site      string        // dir/file:line of the go statement, operation or mark
+
+	g int64
+}
+
+func (e Event) String() string {
+	s := fmt.Sprintf("+%v %s: %s", e.At, e.Goroutine, e.Kind)
+	if e.What != "" {
+		s += " " + e.What
+	}
+	if e.Site != "" {
+		s += " at " + e.Site
+	}
+	return s
+}
+
+// Lane is what a goroutine did: its events, in order.
+type Lane struct {
+	Goroutine string
+	Events    []Event
+}
+
+// State is what the goroutine did after its last event: "running",
+// "blocked in" the operation, or "exited".
+func (l Lane) State() string {
+	switch last := l.Events[len(l.Events)-1]; last.Kind {
+	case "block":
+		return fmt.Sprintf("blocked in %s at %s since +%v", last.What, last.Site, last.At)
+	case "exit":
+		return fmt.Sprintf("exited at +%v", last.At)
+	}
+	return "running"
+}
+
+// Timeline is the events of the goroutines of a bubble.
+type Timeline struct {
+	group int64
+	start time.Time
+
+	mu     sync.Mutex
+	events []Event
+	trace  *runtimeTrace // if runtime/trace was on when the recording started
+}
+
+var (
+	timelinesMu sync.Mutex
+	timelines   = make(map[int64]*Timeline) // by bubble
+	recording   atomic.Int32                // len(timelines)
+)
+
+// Record starts recording the caller's bubble until the test ends. If the
+// test fails, the timeline is logged with the failure. With
+// -synctest.report, an HTML report of it, its Chrome trace and its
+// blocking profile are written to that directory. If runtime/trace is on,
+// as with go test -trace, the events are also written to the execution
+// trace, see startTrace.
+func Record(t testing.TB) *Timeline {
+	t.Helper()
+	group := gstack.Self().Group
+	if group == 0 {
+		t.Fatalf("timeline.Record called outside a synctest bubble")
+	}
+	tl := &Timeline{group: group, start: time.Now(), trace: startTrace(t.Name())}
+	timelinesMu.Lock()
+	if _, ok := timelines[group]; ok {
+		timelinesMu.Unlock()
+		t.Fatalf("timeline.Record: this bubble is already being recorded")
+	}
+	timelines[group] = tl
+	recording.Add(1)
+	timelinesMu.Unlock()
+	t.Cleanup(func() {
+		tl.Stop()
+		if t.Failed() {
+			t.Logf("timeline of the bubble:\n%v", tl)
+		}
+		if *reportDir != "" {
+			path, err := tl.writeReports(*reportDir, t.Name(), t.Failed())
+			if err != nil {
+				t.Errorf("timeline: %v", err)
+			} else {
+				base := strings.TrimSuffix(path, ".html")
+				t.Logf("timeline: report written to %s, trace to %s.json, blocking profile to %s.block.pb.gz", path, base, base)
+			}
+		}
+	})
+	return tl
+}
+
+// Stop ends the recording.
+func (tl *Timeline) Stop() {
+	timelinesMu.Lock()
+	defer timelinesMu.Unlock()
+	if timelines[tl.group] == tl {
+		delete(timelines, tl.group)
+		recording.Add(-1)
+		tl.trace.end()
+	}
+}
+
+// add records an event of the calling goroutine, if its bubble is
+// recorded.
+func add(kind, what, site string) {
+	if recording.Load() == 0 {
+		return
+	}
+	self := gstack.Self()
+	timelinesMu.Lock()
+	tl := timelines[self.Group]
+	timelinesMu.Unlock()
+	if tl == nil {
+		return
+	}
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	e := Event{time.Since(tl.start), goid.Name(self.ID), kind, what, site, self.ID}
+	tl.events = append(tl.events, e)
+	tl.trace.add(e)
+}
+
+// Spawn records that the calling goroutine, started at site, runs. It is
+// called once the goroutine has its name.
+func Spawn(site string) { add("spawn", "", site) }
+
+// Exit records that the calling goroutine returns.
+func Exit() { add("exit", "", "") }
+
+// Block records that the calling goroutine blocks in operation what at
+// site.
+func Block(what, site string) { add("block", what, site) }
+
+// Unblock records that the calling goroutine no longer blocks.
+func Unblock() { add("unblock", "", "") }
+
+// Op records that the calling goroutine performs the synchronization
+// operation what at site.
+func Op(what, site string) { add("op", what, site) }
+
+// Mark records a mark labeled label at site, see hb.Mark.
+func Mark(label, site string) { add("mark", label, site) }
+
+// Note records an annotation of the scenario at site, see
+// bubble.Annotate.
+func Note(label, site string) { add("note", label, site) }
+
+// Fail records a failure of the test, reported at site.
+func Fail(msg, site string) { add("fail", msg, site) }
+
+// Recording reports whether some bubble is recorded, for callers to skip
+// the work of describing an event otherwise.
+func Recording() bool { return recording.Load() > 0 }
+
+// Events returns the events recorded so far, in the order they were.
+func (tl *Timeline) Events() []Event {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return slices.Clone(tl.events)
+}
+
+// Lanes returns the events by goroutine, in the order the goroutines were
+// first seen.
+func (tl *Timeline) Lanes() []Lane {
+	var lanes []Lane
+	index := make(map[int64]int)
+	for _, e := range tl.Events() {
+		i, ok := index[e.g]
+		if !ok {
+			i = len(lanes)
+			index[e.g] = i
+			lanes = append(lanes, Lane{Goroutine: e.Goroutine})
+		}
+		lanes[i].Events = append(lanes[i].Events, e)
+	}
+	return lanes
+}
+
+// String lists the events of each goroutine and what it does now.
+func (tl *Timeline) String() string {
+	var b strings.Builder
+	for _, l := range tl.Lanes() {
+		fmt.Fprintf(&b, "%s, %s:\n", l.Goroutine, l.State())
+		for _, e := range l.Events {
+			fmt.Fprintf(&b, "\t+%v %s", e.At, e.Kind)
+			if e.What != "" {
+				fmt.Fprintf(&b, " %s", e.What)
+			}
+			if e.Site != "" {
+				fmt.Fprintf(&b, " at %s", e.Site)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
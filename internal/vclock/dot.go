@@ -0,0 +1,103 @@
+package vclock
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var dotDir = flag.String("synctest.hbdot", "", "directory to write the happens-before graphs behind failed ordering assertions to, in DOT")
+
+// WriteDot writes to w, in the DOT language of GraphViz, the part of g
+// that happens before or at any of focus, all of g if focus is empty: a
+// cluster of events for each goroutine, in program order, and edges from
+// releases to acquisitions. The events of focus are highlighted, and
+// missing is drawn as dashed red edges, for orderings expected and not
+// found.
+func (g *Graph) WriteDot(w io.Writer, focus []Event, missing []Edge) error {
+	events := g.Events()
+	keep := make([]bool, len(events))
+	highlight := make(map[int]bool)
+	for _, f := range focus {
+		highlight[f.ID] = true
+	}
+	for i, e := range events {
+		keep[i] = len(focus) == 0 || highlight[e.ID]
+		for _, f := range focus {
+			keep[i] = keep[i] || g.Before(e, f)
+		}
+	}
+	var b strings.Builder
+	b.WriteString("digraph hb {\n\trankdir=TB;\n\tnode [shape=box, fontname=monospace];\n")
+	var lanes []string
+	byLane := make(map[string][]Event)
+	for i, e := range events {
+		if !keep[i] {
+			continue
+		}
+		if byLane[e.Goroutine] == nil {
+			lanes = append(lanes, e.Goroutine)
+		}
+		byLane[e.Goroutine] = append(byLane[e.Goroutine], e)
+	}
+	for i, lane := range lanes {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", i, lane)
+		for _, e := range byLane[lane] {
+			label := e.Op + " " + e.Object
+			if e.Op == "mark" {
+				label = fmt.Sprintf("mark %q", e.Object)
+			}
+			attrs := ""
+			if highlight[e.ID] {
+				attrs = ", color=red, penwidth=2"
+			}
+			fmt.Fprintf(&b, "\t\te%d [label=%q%s];\n", e.ID, label+"\n"+e.Site, attrs)
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, ed := range g.Edges() {
+		if keep[ed.From] && keep[ed.To] {
+			style := ""
+			if events[ed.From].g != events[ed.To].g {
+				style = " [color=blue]"
+			}
+			fmt.Fprintf(&b, "\te%d -> e%d%s;\n", ed.From, ed.To, style)
+		}
+	}
+	for _, ed := range missing {
+		fmt.Fprintf(&b, "\te%d -> e%d [style=dashed, color=red, label=\"missing\"];\n", ed.From, ed.To)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDot writes, with -synctest.hbdot, the graph behind the failed
+// assertion of t that the events of focus are ordered as missing says.
+func (g *Graph) writeDot(t testing.TB, focus []Event, missing []Edge) {
+	t.Helper()
+	if *dotDir == "" {
+		return
+	}
+	if err := os.MkdirAll(*dotDir, 0o755); err != nil {
+		t.Errorf("hb: %v", err)
+		return
+	}
+	path := filepath.Join(*dotDir, strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())+".dot")
+	f, err := os.Create(path)
+	if err == nil {
+		err = g.WriteDot(f, focus, missing)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		t.Errorf("hb: %v", err)
+		return
+	}
+	t.Logf("hb: happens-before graph written to %s", path)
+}
@@ -0,0 +1,285 @@
+// Package vclock records the synchronization of the goroutines of a bubble
+// with vector clocks, as the happens-before graph package hb exposes. The
+// instrumented packages report their operations here.
+package vclock
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/timeline"
+)
+
+// clock counts, for each goroutine, its events that happen before or at
+// an event.
+type clock map[int64]int
+
+func (c clock) join(o clock) {
+	for g, n := range o {
+		c[g] = max(c[g], n)
+	}
+}
+
+// Event is a synchronization operation, or a mark.
+type Event struct {
+	ID        int    // index into the events of the graph
+	Goroutine string // name given to bubble.Go, or "g<id>"
+	Op        string // "send", "recv", "unlock", "lock", "done", "wait" or "mark"
+	Object    string // what the operation is on, the label of a mark
+	Site      string // dir/file:line of the operation
+
+	g     int64 // goroutine id
+	seq   int   // position among the events of g, from 1
+	clock clock
+}
+
+func (e Event) String() string {
+	if e.Op == "mark" {
+		return fmt.Sprintf("%d %s: %q at %s", e.ID, e.Goroutine, e.Object, e.Site)
+	}
+	return fmt.Sprintf("%d %s: %s %s at %s", e.ID, e.Goroutine, e.Op, e.Object, e.Site)
+}
+
+// Edge is an edge of the happens-before graph, between two events of one
+// goroutine, or from a release of an object to an acquisition.
+type Edge struct{ From, To int }
+
+// Graph is the happens-before order of the events recorded in a bubble.
+type Graph struct {
+	group int64
+
+	mu       sync.Mutex
+	events   []Event
+	clocks   map[int64]clock // of each goroutine, after its last event
+	last     map[int64]int   // last event of each goroutine
+	released map[any][]int   // latest releases of each object, none before another
+	sent     map[any][]int   // sends on each channel not yet received, in order
+	edges    []Edge
+}
+
+var (
+	graphsMu  sync.Mutex
+	graphs    = make(map[int64]*Graph) // by bubble
+	recording atomic.Int32             // len(graphs)
+)
+
+// Record starts recording the caller's bubble until the test ends.
+func Record(t testing.TB) *Graph {
+	t.Helper()
+	group := gstack.Self().Group
+	if group == 0 {
+		t.Fatalf("hb.Record called outside a synctest bubble")
+	}
+	g := &Graph{group: group, clocks: make(map[int64]clock), last: make(map[int64]int), released: make(map[any][]int), sent: make(map[any][]int)}
+	graphsMu.Lock()
+	if _, ok := graphs[group]; ok {
+		graphsMu.Unlock()
+		t.Fatalf("hb.Record: this bubble is already being recorded")
+	}
+	graphs[group] = g
+	recording.Add(1)
+	graphsMu.Unlock()
+	t.Cleanup(g.Stop)
+	return g
+}
+
+// Stop ends the recording.
+func (g *Graph) Stop() {
+	graphsMu.Lock()
+	defer graphsMu.Unlock()
+	if graphs[g.group] == g {
+		delete(graphs, g.group)
+		recording.Add(-1)
+	}
+}
+
+// add records an event of the calling goroutine with g.mu held and
+// returns it.
+func (g *Graph) add(self gstack.Goroutine, op, object string, skip int) *Event {
+	c := g.clocks[self.ID]
+	if c == nil {
+		c = make(clock)
+		g.clocks[self.ID] = c
+	}
+	c[self.ID]++
+	e := Event{ID: len(g.events), Goroutine: goid.Name(self.ID), Op: op, Object: object, Site: block.Caller(skip + 1), g: self.ID, seq: c[self.ID]}
+	if prev, ok := g.last[self.ID]; ok {
+		g.edges = append(g.edges, Edge{prev, e.ID})
+	}
+	g.last[self.ID] = e.ID
+	g.events = append(g.events, e)
+	return &g.events[e.ID]
+}
+
+// acquire joins the events from into the clock of e and adds their edges.
+func (g *Graph) acquire(e *Event, from []int) {
+	c := g.clocks[e.g]
+	for _, id := range from {
+		if src := g.events[id]; src.seq > c[src.g] {
+			c.join(src.clock)
+			g.edges = append(g.edges, Edge{id, e.ID})
+		}
+	}
+}
+
+// record runs f on the graph of the caller's bubble, if it is recorded.
+func record(f func(g *Graph, self gstack.Goroutine)) {
+	if recording.Load() == 0 {
+		return
+	}
+	self := gstack.Self()
+	graphsMu.Lock()
+	g := graphs[self.Group]
+	graphsMu.Unlock()
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f(g, self)
+}
+
+// observe reports operation op to the timeline of the caller's bubble, if
+// it is recorded.
+func observe(op string, skip int) {
+	if timeline.Recording() {
+		timeline.Op(op, block.Caller(skip+2))
+	}
+}
+
+// Release records that the caller releases obj, described by object, in
+// operation op, so that the acquisitions after it happen after everything
+// before it. skip is the number of frames between the caller of Release
+// and the code to attribute the operation to.
+func Release(obj any, op, object string, skip int) {
+	observe(op+" "+object, skip)
+	record(func(g *Graph, self gstack.Goroutine) {
+		e := g.add(self, op, object, skip+3)
+		e.clock = copyOf(g.clocks[self.ID])
+		rel := slices.DeleteFunc(g.released[obj], func(id int) bool {
+			before := g.events[id]
+			return before.seq <= e.clock[before.g]
+		})
+		g.released[obj] = append(rel, e.ID)
+	})
+}
+
+// Acquire records that the caller acquired obj in operation op.
+func Acquire(obj any, op, object string, skip int) {
+	observe(op+" "+object, skip)
+	record(func(g *Graph, self gstack.Goroutine) {
+		e := g.add(self, op, object, skip+3)
+		g.acquire(e, g.released[obj])
+		e.clock = copyOf(g.clocks[self.ID])
+	})
+}
+
+// Send records a send on channel obj, before it is performed, for the
+// receive of the value to happen after it.
+func Send(obj any, object string, skip int) {
+	observe("send"+" "+object, skip)
+	record(func(g *Graph, self gstack.Goroutine) {
+		e := g.add(self, "send", object, skip+3)
+		e.clock = copyOf(g.clocks[self.ID])
+		g.sent[obj] = append(g.sent[obj], e.ID)
+	})
+}
+
+// Recv records a receive of a value from channel obj, once performed. The
+// values are assumed to be received in the order their sends were
+// recorded.
+func Recv(obj any, object string, skip int) {
+	observe("recv"+" "+object, skip)
+	record(func(g *Graph, self gstack.Goroutine) {
+		e := g.add(self, "recv", object, skip+3)
+		if sent := g.sent[obj]; len(sent) > 0 {
+			g.acquire(e, sent[:1])
+			g.sent[obj] = sent[1:]
+		}
+		e.clock = copyOf(g.clocks[self.ID])
+	})
+}
+
+// Mark records an event labeled label, such as a write or a read of
+// shared state, for the order of marks to be checked.
+func Mark(label string, skip int) {
+	if timeline.Recording() {
+		timeline.Mark(label, block.Caller(skip+1))
+	}
+	record(func(g *Graph, self gstack.Goroutine) {
+		e := g.add(self, "mark", label, skip+3)
+		e.clock = copyOf(g.clocks[self.ID])
+	})
+}
+
+func copyOf(c clock) clock {
+	d := make(clock, len(c))
+	d.join(c)
+	return d
+}
+
+// Events returns the events recorded so far, in the order they were.
+func (g *Graph) Events() []Event {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.events)
+}
+
+// Edges returns the edges of the graph, which is acyclic, in the order
+// they were added.
+func (g *Graph) Edges() []Edge {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.edges)
+}
+
+// Before reports whether a happens before b.
+func (g *Graph) Before(a, b Event) bool {
+	return a.ID != b.ID && a.seq <= b.clock[a.g]
+}
+
+// Marks returns the marks labeled label.
+func (g *Graph) Marks(label string) []Event {
+	var es []Event
+	for _, e := range g.Events() {
+		if e.Op == "mark" && e.Object == label {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+// ExpectBefore fails t unless every mark labeled a happens before every
+// mark labeled b, and both occurred. With -synctest.hbdot, the events the
+// unordered marks depend on are written as a graph, see writeDot.
+func (g *Graph) ExpectBefore(t testing.TB, a, b string) {
+	t.Helper()
+	as, bs := g.Marks(a), g.Marks(b)
+	if len(as) == 0 || len(bs) == 0 {
+		t.Errorf("hb: no mark %q or %q recorded", a, b)
+		return
+	}
+	var unordered []string
+	var focus []Event
+	var missing []Edge
+	for _, x := range as {
+		for _, y := range bs {
+			if !g.Before(x, y) {
+				unordered = append(unordered, fmt.Sprintf("\n\t%v\n\tdoes not happen before\n\t%v", x, y))
+				focus = append(focus, x, y)
+				missing = append(missing, Edge{x.ID, y.ID})
+			}
+		}
+	}
+	if len(unordered) > 0 {
+		t.Errorf("hb: %q is not ordered before %q:%s", a, b, strings.Join(unordered, "\n"))
+		g.writeDot(t, focus, missing)
+	}
+}
@@ -0,0 +1,199 @@
+// Package metrics provides counters, gauges and histograms for code under
+// test to report to, and snapshots of their values at chosen instants of
+// virtual time, so that a test can assert on metrics deterministically:
+// that a counter reached 3 by the 10s mark, or that a request's latency,
+// measured on the bubble's clock, landed in the 5s bucket. Metrics are
+// expvar.Vars, and snapshots can be written in the text format of
+// Prometheus.
+package metrics
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry holds the metrics of a test. It is safe for concurrent use.
+type Registry struct {
+	start time.Time
+
+	mu         sync.Mutex
+	counters   map[string]*Counter
+	gauges     map[string]*Gauge
+	histograms map[string]*Histogram
+}
+
+// NewRegistry returns an empty Registry whose snapshots are timed from
+// now, the start of the bubble if called first thing in it.
+func NewRegistry() *Registry {
+	return &Registry{
+		start:      time.Now(),
+		counters:   make(map[string]*Counter),
+		gauges:     make(map[string]*Gauge),
+		histograms: make(map[string]*Histogram),
+	}
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Add adds d, which must not be negative, to c.
+func (c *Counter) Add(d float64) {
+	if d < 0 {
+		panic(fmt.Sprintf("metrics: counter decreased by %v", d))
+	}
+	c.mu.Lock()
+	c.v += d
+	c.mu.Unlock()
+}
+
+// Inc adds 1 to c.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Value returns the value of c.
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v
+}
+
+// String formats c as JSON, for expvar.
+func (c *Counter) String() string { return fmt.Sprint(c.Value()) }
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Set sets g to v.
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.v = v
+	g.mu.Unlock()
+}
+
+// Add adds d to g.
+func (g *Gauge) Add(d float64) {
+	g.mu.Lock()
+	g.v += d
+	g.mu.Unlock()
+}
+
+// Value returns the value of g.
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+// String formats g as JSON, for expvar.
+func (g *Gauge) String() string { return fmt.Sprint(g.Value()) }
+
+// Histogram counts observations in buckets by upper bound, like a
+// Prometheus histogram.
+type Histogram struct {
+	bounds []float64 // ascending; an observation goes to the first it does not exceed
+
+	mu     sync.Mutex
+	counts []uint64 // by bucket, the last one for those above every bound
+	sum    float64
+}
+
+// Observe adds v to h.
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// ObserveDuration adds d, in seconds, to h.
+func (h *Histogram) ObserveDuration(d time.Duration) { h.Observe(d.Seconds()) }
+
+// Since adds the virtual time since start, in seconds, to h.
+func (h *Histogram) Since(start time.Time) { h.ObserveDuration(time.Since(start)) }
+
+// String formats h as JSON, for expvar.
+func (h *Histogram) String() string {
+	s := h.snapshot()
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"count": %d, "sum": %v, "buckets": {`, s.Count, s.Sum)
+	for i, bk := range s.Buckets {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%q: %d", le(bk.UpperBound), bk.Count)
+	}
+	b.WriteString("}}")
+	return b.String()
+}
+
+func (h *Histogram) snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := HistogramSnapshot{Sum: h.sum}
+	for i, n := range h.counts {
+		le := inf
+		if i < len(h.bounds) {
+			le = h.bounds[i]
+		}
+		s.Buckets = append(s.Buckets, Bucket{le, n})
+		s.Count += n
+	}
+	return s
+}
+
+// Counter returns the counter named name, created on first use.
+func (r *Registry) Counter(name string) *Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.counters[name]
+	if !ok {
+		c = new(Counter)
+		r.counters[name] = c
+	}
+	return c
+}
+
+// Gauge returns the gauge named name, created on first use.
+func (r *Registry) Gauge(name string) *Gauge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.gauges[name]
+	if !ok {
+		g = new(Gauge)
+		r.gauges[name] = g
+	}
+	return g
+}
+
+// Histogram returns the histogram named name, created on first use with
+// buckets of the given upper bounds, or DurationBuckets if none. Later
+// calls get the histogram as created, whatever their bounds.
+func (r *Registry) Histogram(name string, bounds ...float64) *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.histograms[name]
+	if !ok {
+		if len(bounds) == 0 {
+			bounds = DurationBuckets
+		}
+		bounds = slices.Clone(bounds)
+		sort.Float64s(bounds)
+		bounds = slices.Compact(bounds)
+		h = &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+		r.histograms[name] = h
+	}
+	return h
+}
+
+// DurationBuckets are the default bounds of histograms, in seconds.
+var DurationBuckets = []float64{0.001, 0.01, 0.1, 0.5, 1, 2, 5, 10, 30, 60}
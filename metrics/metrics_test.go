@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+var _ expvar.Var = (*Histogram)(nil)
+
+// serve handles a request taking 4s.
+func serve(r *Registry) {
+	inflight := r.Gauge("inflight")
+	inflight.Add(1)
+	defer inflight.Add(-1)
+	start := time.Now()
+	time.Sleep(4 * time.Second)
+	r.Histogram("latency_seconds").Since(start)
+	r.Counter("requests_total").Inc()
+}
+
+func TestAt(t *testing.T) {
+	synctest.Run(func() {
+		r := NewRegistry()
+		c := r.At(10*time.Second, 2*time.Second)
+		go serve(r)
+		time.Sleep(time.Minute)
+
+		snaps := c.Snapshots()
+		if len(snaps) != 2 || snaps[0].At != 2*time.Second || snaps[1].At != 10*time.Second {
+			t.Fatalf("snapshots %+v, want at 2s and 10s", snaps)
+		}
+		if got := snaps[0].Gauges["inflight"]; got != 1 {
+			t.Errorf("inflight at 2s = %v, want 1", got)
+		}
+		if got := snaps[0].Counters["requests_total"]; got != 0 {
+			t.Errorf("requests at 2s = %v, want 0", got)
+		}
+		s := snaps[1]
+		if s.Gauges["inflight"] != 0 || s.Counters["requests_total"] != 1 {
+			t.Errorf("at 10s: %+v", s)
+		}
+		h := s.Histograms["latency_seconds"]
+		if le := h.BucketOf(4); le != 5 {
+			t.Errorf("4s in the bucket of %v, want 5", le)
+		}
+		if h.In(5) != 1 || h.Count != 1 || h.Sum != 4 {
+			t.Errorf("latency %+v, want one observation of 4s in the 5s bucket", h)
+		}
+	})
+}
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests_total").Add(2)
+	h := r.Histogram("latency_seconds", 5, 1)
+	h.Observe(0.5)
+	h.Observe(3)
+	h.Observe(7)
+	var b strings.Builder
+	if err := r.Snapshot().WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE requests_total counter
+requests_total 2
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="1"} 1
+latency_seconds_bucket{le="5"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 10.5
+latency_seconds_count 3
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+	if s := h.String(); !json.Valid([]byte(s)) {
+		t.Errorf("expvar value %s is not JSON", s)
+	}
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var inf = math.Inf(1)
+
+// le formats the upper bound of a bucket as Prometheus does.
+func le(bound float64) string {
+	if bound == inf {
+		return "+Inf"
+	}
+	return fmt.Sprint(bound)
+}
+
+// Snapshot is the values of the metrics of a Registry at an instant.
+type Snapshot struct {
+	At         time.Duration // virtual time since the Registry was made
+	Counters   map[string]float64
+	Gauges     map[string]float64
+	Histograms map[string]HistogramSnapshot
+}
+
+// HistogramSnapshot is the observations of a histogram at an instant.
+type HistogramSnapshot struct {
+	Buckets []Bucket // ascending, the last one with bound +Inf
+	Count   uint64
+	Sum     float64
+}
+
+// Bucket is the number of observations of a histogram above the bound of
+// the previous bucket and at most UpperBound. Unlike in the Prometheus
+// format, buckets do not include the observations of those below them.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// BucketOf returns the upper bound of the bucket v falls in.
+func (h HistogramSnapshot) BucketOf(v float64) float64 {
+	for _, b := range h.Buckets {
+		if v <= b.UpperBound {
+			return b.UpperBound
+		}
+	}
+	return inf
+}
+
+// In returns the number of observations in the bucket of upper bound le,
+// 0 if there is none.
+func (h HistogramSnapshot) In(le float64) uint64 {
+	for _, b := range h.Buckets {
+		if b.UpperBound == le {
+			return b.Count
+		}
+	}
+	return 0
+}
+
+// Snapshot returns the values of the metrics of r now.
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Snapshot{
+		At:         time.Since(r.start),
+		Counters:   make(map[string]float64, len(r.counters)),
+		Gauges:     make(map[string]float64, len(r.gauges)),
+		Histograms: make(map[string]HistogramSnapshot, len(r.histograms)),
+	}
+	for name, c := range r.counters {
+		s.Counters[name] = c.Value()
+	}
+	for name, g := range r.gauges {
+		s.Gauges[name] = g.Value()
+	}
+	for name, h := range r.histograms {
+		s.Histograms[name] = h.snapshot()
+	}
+	return s
+}
+
+// Capture is the snapshots a Registry takes at instants set in advance.
+type Capture struct {
+	mu        sync.Mutex
+	snapshots []Snapshot
+}
+
+// At takes a snapshot of r at each of instants, virtual times since r was
+// made. Called inside a bubble, the timers belong to the bubble, so
+// the snapshots see everything its goroutines did before those instants;
+// what they do at the very instants may or may not be seen.
+func (r *Registry) At(instants ...time.Duration) *Capture {
+	c := new(Capture)
+	for _, at := range instants {
+		time.AfterFunc(time.Until(r.start.Add(at)), func() {
+			s := r.Snapshot()
+			c.mu.Lock()
+			c.snapshots = append(c.snapshots, s)
+			c.mu.Unlock()
+		})
+	}
+	return c
+}
+
+// Snapshots returns the snapshots taken so far, by time.
+func (c *Capture) Snapshots() []Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := slices.Clone(c.snapshots)
+	slices.SortStableFunc(s, func(a, b Snapshot) int { return int(a.At - b.At) })
+	return s
+}
+
+// WritePrometheus writes s to w in the text exposition format of
+// Prometheus, with names unchanged and metrics sorted by name.
+func (s Snapshot) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(s.Counters)) {
+		fmt.Fprintf(&b, "# TYPE %s counter\n%s %v\n", name, name, s.Counters[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Gauges)) {
+		fmt.Fprintf(&b, "# TYPE %s gauge\n%s %v\n", name, name, s.Gauges[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Histograms)) {
+		h := s.Histograms[name]
+		fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+		var n uint64
+		for _, bk := range h.Buckets {
+			n += bk.Count
+			fmt.Fprintf(&b, "%s_bucket{le=%q} %d\n", name, le(bk.UpperBound), n)
+		}
+		fmt.Fprintf(&b, "%s_sum %v\n%s_count %d\n", name, h.Sum, name, h.Count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+)
+
+// workers are three interchangeable workers, each idle or busy.
+type workers [3]bool
+
+func workersModel() *Model[workers] {
+	m := &Model[workers]{Name: "workers"}
+	for i := range 3 {
+		m.Actions = append(m.Actions,
+			Action[workers]{Name: fmt.Sprint("start ", i), Guard: func(s workers) bool { return !s[i] }, Step: func(s workers) workers { s[i] = true; return s }},
+			Action[workers]{Name: fmt.Sprint("stop ", i), Guard: func(s workers) bool { return s[i] }, Step: func(s workers) workers { s[i] = false; return s }},
+		)
+	}
+	return m
+}
+
+func TestAbstract(t *testing.T) {
+	m := workersModel()
+	rep := Explore(t, m, ExploreConfig{}, func() System[workers] { return nopWorkers{} })
+	if rep.States != 8 {
+		t.Errorf("concrete: %v", rep)
+	}
+	// Only the number of busy workers matters.
+	m.Abstract = func(s workers) any {
+		n := 0
+		for _, busy := range s {
+			if busy {
+				n++
+			}
+		}
+		return n
+	}
+	rep = Explore(t, m, ExploreConfig{}, func() System[workers] { return nopWorkers{} })
+	if rep.States != 4 {
+		t.Errorf("abstract: %v", rep)
+	}
+	sp, err := m.StateSpace(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.States) != 4 || sp.States[3] != "[true true true]" {
+		t.Errorf("state space %q", sp.States)
+	}
+}
+
+type nopWorkers struct{}
+
+func (nopWorkers) Do(string) error     { return nil }
+func (nopWorkers) Check(workers) error { return nil }
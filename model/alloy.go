@@ -0,0 +1,57 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Alloy is the Backend writing Alloy modules. Every state is a singleton
+// signature, each action a relation between them, and each invariant an
+// assertion that the states reachable from the initial one satisfy it,
+// checked in a scope of all states.
+type Alloy struct{}
+
+func (Alloy) Write(w io.Writer, sp *StateSpace) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "module %s\n-- Generated from the Go model %q; do not edit.\n\n", ident(sp.Name), sp.Name)
+	for i, k := range sp.States {
+		fmt.Fprintf(b, "-- S%d: %s\n", i, comment(k, "\n"))
+	}
+	fmt.Fprintf(b, "abstract sig State {}\none sig %s extends State {}\n\n", alloySet(all(len(sp.States)), ", "))
+	fmt.Fprintf(b, "fun Init: State { S0 }\n\n")
+	var next []string
+	for _, a := range sp.Actions {
+		id := ident(a.Name)
+		next = append(next, id)
+		pairs := make([]string, len(a.Transitions))
+		for i, tr := range a.Transitions {
+			pairs[i] = fmt.Sprintf("S%d -> S%d", tr[0], tr[1])
+		}
+		rel := strings.Join(pairs, " + ")
+		if rel == "" {
+			rel = "none -> none"
+		}
+		fmt.Fprintf(b, "fun %s: State -> State { %s }\n", id, rel)
+	}
+	fmt.Fprintf(b, "fun Next: State -> State { %s }\n\n", strings.Join(next, " + "))
+	fmt.Fprintf(b, "fun Reachable: set State { Init.*Next }\n")
+	for _, inv := range sp.Invariants {
+		id := ident(inv.Name)
+		holds := alloySet(inv.Holds, " + ")
+		if holds == "" {
+			holds = "none"
+		}
+		fmt.Fprintf(b, "\nassert %s { Reachable in %s }\ncheck %s for %d State\n", id, holds, id, len(sp.States))
+	}
+	return b.Flush()
+}
+
+func alloySet(idx []int, sep string) string {
+	s := make([]string, len(idx))
+	for i, j := range idx {
+		s[i] = fmt.Sprintf("S%d", j)
+	}
+	return strings.Join(s, sep)
+}
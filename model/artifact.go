@@ -0,0 +1,160 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// Artifact is a counterexample saved by Explore: the actions leading to
+// the failure, minimized, with the virtual time the bubble had advanced
+// to after each of them, so that Replay can tell whether it reproduces the
+// same run.
+type Artifact struct {
+	Model    string
+	Actions  []string
+	Elapsed  []time.Duration
+	Err      string
+	Original int // number of actions before minimization
+}
+
+// replay runs actions on a new System in a fresh bubble, checking it
+// against the model after every step. It returns the virtual time elapsed
+// after each step and the first failure, or an error wrapping
+// errNotARun if m cannot take the actions.
+func replay[S any](m *Model[S], actions []string, newSystem func() System[S]) (elapsed []time.Duration, err error) {
+	tr, err := m.Run(actions...)
+	var me *Error[S]
+	if errors.As(err, &me) && me.Disabled == "" {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNotARun, err)
+	}
+	synctest.Run(func() {
+		start := time.Now()
+		sys := newSystem()
+		if c, ok := sys.(io.Closer); ok {
+			defer func() {
+				if cerr := c.Close(); cerr != nil && err == nil {
+					err = fmt.Errorf("close: %w", cerr)
+				}
+			}()
+		}
+		for i, st := range tr {
+			if err = sys.Do(st.Action); err != nil {
+				err = fmt.Errorf("step %d %s: %w", i+1, st.Action, err)
+				return
+			}
+			synctest.Wait()
+			elapsed = append(elapsed, time.Since(start))
+			if err = sys.Check(st.State); err != nil {
+				err = fmt.Errorf("after %v: model state %+v: %w", tr[:i+1], st.State, err)
+				return
+			}
+		}
+	})
+	return elapsed, err
+}
+
+var errNotARun = errors.New("not a run of the model")
+
+// minimize drops actions from a failing run as long as the rest is still
+// a run of m that fails.
+func minimize[S any](m *Model[S], actions []string, newSystem func() System[S]) ([]string, []time.Duration, error) {
+	elapsed, err := replay(m, actions, newSystem)
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for i := range actions {
+			cand := slices.Delete(slices.Clone(actions), i, i+1)
+			if el, cerr := replay(m, cand, newSystem); cerr != nil && !errors.Is(cerr, errNotARun) {
+				actions, elapsed, err, shrunk = cand, el, cerr, true
+				break
+			}
+		}
+	}
+	return actions, elapsed, err
+}
+
+// saveArtifact minimizes the failing run tr and saves it in dir.
+func saveArtifact[S any](t testing.TB, m *Model[S], dir string, tr Trace[S], newSystem func() System[S]) {
+	t.Helper()
+	actions, elapsed, err := minimize(m, tr.Actions(), newSystem)
+	if err == nil {
+		t.Logf("model %s: the failure after %v does not reproduce; no artifact saved", m.Name, tr)
+		return
+	}
+	a := Artifact{Model: m.Name, Actions: actions, Elapsed: elapsed, Err: err.Error(), Original: len(tr)}
+	h := fnv.New64a()
+	fmt.Fprint(h, strings.Join(actions, "\x00"))
+	path := filepath.Join(dir, fmt.Sprintf("%s-%016x.json", ident(m.Name), h.Sum64()))
+	if err := writeArtifact(path, a); err != nil {
+		t.Errorf("saving counterexample: %v", err)
+		return
+	}
+	t.Logf("model %s: counterexample of %d actions (of %d) saved to %s", m.Name, len(actions), len(tr), path)
+}
+
+func writeArtifact(path string, a Artifact) error {
+	b, err := json.MarshalIndent(a, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// ReadArtifact reads an artifact saved by Explore.
+func ReadArtifact(path string) (Artifact, error) {
+	var a Artifact
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(b, &a); err != nil {
+		return a, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, nil
+}
+
+// Replay runs the counterexample saved at path on a new System in a new
+// bubble and fails t if it still fails, so that a saved artifact makes a
+// regression test. It also fails t if the run does not reproduce the
+// recorded one: if the actions are no longer a run of m, or the bubble's
+// clock advances differently.
+func Replay[S any](t testing.TB, path string, m *Model[S], newSystem func() System[S]) {
+	t.Helper()
+	a, err := ReadArtifact(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Model != m.Name {
+		t.Fatalf("%s: counterexample of model %s, not %s", path, a.Model, m.Name)
+	}
+	elapsed, err := replay(m, a.Actions, newSystem)
+	if errors.Is(err, errNotARun) {
+		t.Fatalf("%s: %v", path, err)
+	}
+	for i := range min(len(elapsed), len(a.Elapsed)) {
+		if elapsed[i] != a.Elapsed[i] {
+			t.Errorf("%s: replay diverged: after step %d %s the clock is at %v, recorded %v", path, i+1, a.Actions[i], elapsed[i], a.Elapsed[i])
+			break
+		}
+	}
+	if err != nil {
+		t.Errorf("model %s: replay of %s: %v", m.Name, path, err)
+		return
+	}
+	t.Logf("model %s: %s no longer fails; it failed with: %s", m.Name, path, a.Err)
+}
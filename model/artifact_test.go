@@ -0,0 +1,60 @@
+package model
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// slowSystem takes d of virtual time for every action.
+type slowSystem struct {
+	queueSystem
+	d time.Duration
+}
+
+func (s slowSystem) Do(action string) error {
+	time.Sleep(s.d)
+	return s.queueSystem.Do(action)
+}
+
+func TestArtifact(t *testing.T) {
+	dir := t.TempDir()
+	buggy := func() System[buffer] { return slowSystem{queueSystem{newQueue(true)}, time.Second} }
+	testtb.Run(t, func(t testing.TB) {
+		Explore(t, bufferModel(), ExploreConfig{Strategy: DFS, ArtifactDir: dir}, buggy)
+	})
+	paths, _ := filepath.Glob(filepath.Join(dir, "buffer-*.json"))
+	if len(paths) != 1 {
+		t.Fatalf("artifacts %v", paths)
+	}
+	a, err := ReadArtifact(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(a.Actions, []string{"put", "close"}) || a.Original != 2 ||
+		!slices.Equal(a.Elapsed, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("artifact %+v", a)
+	}
+
+	actions, _, err := minimize(bufferModel(), []string{"put", "put", "take", "close", "take"}, buggy)
+	if !slices.Equal(actions, []string{"put", "close"}) || err == nil {
+		t.Errorf("minimized to %v, %v", actions, err)
+	}
+
+	errs := testtb.Run(t, func(t testing.TB) { Replay(t, paths[0], bufferModel(), buggy) })
+	if len(errs) != 1 || !strings.HasSuffix(errs[0], ": after put → close: model state {Len:1 Closed:true}: queue state {Len:0 Closed:true}") {
+		t.Errorf("errors %q", errs)
+	}
+	Replay(t, paths[0], bufferModel(), func() System[buffer] { return slowSystem{queueSystem{newQueue(false)}, time.Second} })
+
+	errs = testtb.Run(t, func(t testing.TB) {
+		Replay(t, paths[0], bufferModel(), func() System[buffer] { return slowSystem{queueSystem{newQueue(false)}, 2 * time.Second} })
+	})
+	if len(errs) != 1 || !strings.HasSuffix(errs[0], "replay diverged: after step 1 put the clock is at 2s, recorded 1s") {
+		t.Errorf("errors %q", errs)
+	}
+}
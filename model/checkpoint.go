@@ -0,0 +1,104 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Checkpoint is the progress of an exploration, saved by Explore so that a
+// later run can resume it. Visited states are saved as the tree of the
+// transitions that first reached them, since states need not be
+// serializable; the model recomputes them.
+type Checkpoint struct {
+	Model    string
+	Report   ExploreReport
+	States   []CheckpointState // every state visited, the initial one first
+	Frontier []int             // indices into States still to expand, in order
+}
+
+// CheckpointState is a visited state: the one Action leads to from the
+// state at index Parent.
+type CheckpointState struct {
+	Parent int
+	Action string
+}
+
+// ReadCheckpoint reads a checkpoint saved by Explore.
+func ReadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return cp, fmt.Errorf("%s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// writeCheckpoint saves cp to path through a temporary file, so that a run
+// killed meanwhile leaves the previous checkpoint intact.
+func writeCheckpoint(path string, cp Checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// checkpoint captures the progress of Explore.
+func checkpoint[S any](m *Model[S], rep ExploreReport, tree []CheckpointState, work []exploreNode[S]) Checkpoint {
+	cp := Checkpoint{Model: m.Name, Report: rep, States: slices.Clip(tree)}
+	for _, n := range work {
+		cp.Frontier = append(cp.Frontier, n.node)
+	}
+	return cp
+}
+
+// restore recomputes the states of cp and returns the visited set and the
+// work list to resume Explore with.
+func restore[S any](m *Model[S], cp Checkpoint) (map[any]bool, []exploreNode[S], error) {
+	if cp.Model != m.Name || len(cp.States) == 0 {
+		return nil, nil, fmt.Errorf("checkpoint of model %q does not match model %s", cp.Model, m.Name)
+	}
+	states := make([]S, len(cp.States))
+	keys := make([]any, len(cp.States))
+	states[0], keys[0] = m.Init, m.visitKey(m.Init)
+	visited := map[any]bool{keys[0]: true}
+	for i, st := range cp.States[1:] {
+		i++
+		a, ok := m.Action(st.Action)
+		if !ok || st.Parent < 0 || st.Parent >= i {
+			return nil, nil, fmt.Errorf("checkpoint of model %s: state %d: no action %q from state %d", m.Name, i, st.Action, st.Parent)
+		}
+		states[i] = a.Step(states[st.Parent])
+		keys[i] = m.visitKey(states[i])
+		visited[keys[i]] = true
+	}
+	var work []exploreNode[S]
+	for _, i := range cp.Frontier {
+		if i < 0 || i >= len(states) {
+			return nil, nil, fmt.Errorf("checkpoint of model %s: no state %d to expand", m.Name, i)
+		}
+		n := exploreNode[S]{state: states[i], node: i}
+		for j := i; j > 0; j = cp.States[j].Parent {
+			n.trace = append(n.trace, Step[S]{cp.States[j].Action, states[j]})
+			n.keys = append(n.keys, keys[j])
+		}
+		n.keys = append(n.keys, keys[0])
+		slices.Reverse(n.trace)
+		slices.Reverse(n.keys)
+		work = append(work, n)
+	}
+	return visited, work, nil
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.json")
+	cfg := ExploreConfig{Checkpoint: path, CheckpointEvery: 1}
+	errs := testtb.Run(t, func(t testing.TB) {
+		Explore(t, bufferModel(), cfg, func() System[buffer] { return queueSystem{newQueue(true)} })
+	})
+	if len(errs) != 1 {
+		t.Fatalf("errors %q", errs)
+	}
+	cp, err := ReadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Frontier) == 0 || cp.Report.States != len(cp.States) {
+		t.Fatalf("checkpoint %+v", cp)
+	}
+
+	// Resumed with the fix, the exploration completes where it stopped.
+	rep := Explore(t, bufferModel(), cfg, func() System[buffer] { return queueSystem{newQueue(false)} })
+	if rep.States != 6 || rep.Transitions != 9 || rep.Truncated {
+		t.Errorf("%v", rep)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("checkpoint not removed: %v", err)
+	}
+}
+
+func TestCheckpointMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.json")
+	if err := writeCheckpoint(path, Checkpoint{Model: "buffer", States: []CheckpointState{{Parent: -1}, {0, "pop"}}}); err != nil {
+		t.Fatal(err)
+	}
+	errs := testtb.Run(t, func(t testing.TB) {
+		Explore(t, bufferModel(), ExploreConfig{Checkpoint: path}, func() System[buffer] { return queueSystem{newQueue(false)} })
+	})
+	want := `checkpoint of model buffer: state 1: no action "pop" from state 0`
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
@@ -0,0 +1,356 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/history"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+)
+
+// Command is one kind of operation of a stateful property test, over model
+// states M and systems under test Sys.
+type Command[M, Sys any] struct {
+	Name string
+	// Gen draws the command's argument for model state m; nil means the
+	// command takes no argument.
+	Gen func(r *rand.Rand, m M) any
+	// Pre reports whether the command may run in m with arg; nil means
+	// always.
+	Pre func(m M, arg any) bool
+	// Run performs the command on the system and returns its result.
+	Run func(sys Sys, arg any) any
+	// Next returns the model state after the command. It must not modify m.
+	Next func(m M, arg any) M
+	// Post checks the result against the model state m before the
+	// command; nil accepts any result.
+	Post func(m M, arg, result any) error
+	// Shrink returns simpler arguments to try in place of arg when a
+	// failing program is minimized, simplest first; nil means none.
+	Shrink func(arg any) []any
+}
+
+func (c *Command[M, Sys]) pre(m M, arg any) bool { return c.Pre == nil || c.Pre(m, arg) }
+
+func (c *Command[M, Sys]) post(m M, arg, result any) error {
+	if c.Post == nil {
+		return nil
+	}
+	return c.Post(m, arg, result)
+}
+
+// Machine bundles the commands of a stateful property test with the
+// model's initial state and a constructor of the system under test.
+type Machine[M, Sys any] struct {
+	Init     func() M
+	New      func() Sys // called inside the bubble; an io.Closer is closed at the end
+	Commands []Command[M, Sys]
+}
+
+// Call is one command of a generated sequence with its argument.
+type Call struct {
+	Command string
+	Arg     any
+}
+
+func (c Call) String() string {
+	if c.Arg == nil {
+		return c.Command
+	}
+	return fmt.Sprintf("%s(%v)", c.Command, c.Arg)
+}
+
+// Program is a generated test: a sequential prefix, then branches run
+// concurrently, interleaved as Schedule picks; see sched.RunChoices.
+type Program struct {
+	Prefix   []Call
+	Parallel [][]Call
+	Schedule []byte
+}
+
+func (p Program) String() string {
+	var b strings.Builder
+	b.WriteString(joinCalls(p.Prefix))
+	for i, br := range p.Parallel {
+		fmt.Fprintf(&b, "\n\tbranch %d: %s", i, joinCalls(br))
+	}
+	if len(p.Parallel) > 0 {
+		fmt.Fprintf(&b, "\n\tschedule %v", p.Schedule)
+	}
+	return b.String()
+}
+
+// Len is the number of commands in p.
+func (p Program) Len() int {
+	n := len(p.Prefix)
+	for _, br := range p.Parallel {
+		n += len(br)
+	}
+	return n
+}
+
+func joinCalls(cs []Call) string {
+	s := make([]string, len(cs))
+	for i, c := range cs {
+		s[i] = c.String()
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+// CommandsConfig sizes a stateful property test.
+type CommandsConfig struct {
+	Seed   uint64 // runs are seeded with Seed and their run number
+	Runs   int    // default 100
+	MaxLen int    // commands in the prefix, default 20
+	// Branches, if at least 2, adds that many branches of up to
+	// BranchLen commands (default 3) that run concurrently after the
+	// prefix, as threads of the sched scheduler, with a preemption point
+	// before every command, in an interleaving drawn with the program.
+	// Their results must be explainable by some order of the branch
+	// commands consistent with real time, i.e. the system must be
+	// linearizable. Branch commands are only drawn if their preconditions
+	// hold in every interleaving.
+	Branches  int
+	BranchLen int
+}
+
+func (cfg CommandsConfig) runs() int {
+	if cfg.Runs > 0 {
+		return cfg.Runs
+	}
+	return 100
+}
+
+func (cfg CommandsConfig) maxLen() int {
+	if cfg.MaxLen > 0 {
+		return cfg.MaxLen
+	}
+	return 20
+}
+
+func (cfg CommandsConfig) branchLen() int {
+	if cfg.BranchLen > 0 {
+		return cfg.BranchLen
+	}
+	return 3
+}
+
+// CheckCommands runs cfg.Runs random programs of mach's commands, each on
+// a new system in a fresh bubble that is settled after every command of
+// the prefix. At the first program whose results contradict the model, the
+// program is shrunk and t fails with the minimal program, how it failed,
+// and the seed and run that generated the original one.
+func CheckCommands[M, Sys any](t testing.TB, mach Machine[M, Sys], cfg CommandsConfig) {
+	t.Helper()
+	for run := range uint64(cfg.runs()) {
+		r := rand.New(rand.NewPCG(cfg.Seed, run))
+		p := GenProgram(mach, r, cfg)
+		if err := RunProgram(mach, p); err != nil {
+			t.Errorf("seed %d run %d: %s", cfg.Seed, run, shrinkReport(mach, p, err))
+			return
+		}
+	}
+}
+
+// GenProgram draws a program whose commands satisfy their preconditions.
+func GenProgram[M, Sys any](mach Machine[M, Sys], r *rand.Rand, cfg CommandsConfig) Program {
+	var p Program
+	m := mach.Init()
+	n := 1 + r.IntN(cfg.maxLen())
+	p.Prefix, m = genCalls(mach, r, m, n)
+	if cfg.Branches >= 2 {
+		p.Parallel = genBranches(mach, r, m, cfg)
+		p.Schedule = genSchedule(r, p.Parallel)
+	}
+	return p
+}
+
+// genSchedule draws the choices of a schedule of branches: one for each
+// preemption point before or between their commands, and as many again
+// for those of the system under test.
+func genSchedule(r *rand.Rand, branches [][]Call) []byte {
+	n := 0
+	for _, br := range branches {
+		n += 1 + len(br)
+	}
+	s := make([]byte, 2*n)
+	for i := range s {
+		s[i] = byte(r.Uint32())
+	}
+	return s
+}
+
+// genBranches draws branches command by command, keeping only commands
+// after which the preconditions hold in every interleaving.
+func genBranches[M, Sys any](mach Machine[M, Sys], r *rand.Rand, m M, cfg CommandsConfig) [][]Call {
+	branches := make([][]Call, cfg.Branches)
+	for b := range branches {
+		for range 1 + r.IntN(cfg.branchLen()) {
+			for range 10 * len(mach.Commands) {
+				c := &mach.Commands[r.IntN(len(mach.Commands))]
+				var arg any
+				if c.Gen != nil {
+					arg = c.Gen(r, m)
+				}
+				branches[b] = append(branches[b], Call{c.Name, arg})
+				if mach.alwaysEnabled(m, branches) {
+					break
+				}
+				branches[b] = branches[b][:len(branches[b])-1]
+			}
+		}
+	}
+	return branches
+}
+
+// alwaysEnabled reports whether every interleaving of branches from m
+// satisfies the preconditions.
+func (mach Machine[M, Sys]) alwaysEnabled(m M, branches [][]Call) bool {
+	pos := make([]int, len(branches))
+	var walk func(m M) bool
+	walk = func(m M) bool {
+		for b, br := range branches {
+			if pos[b] == len(br) {
+				continue
+			}
+			call := br[pos[b]]
+			c := mach.command(call.Command)
+			if !c.pre(m, call.Arg) {
+				return false
+			}
+			pos[b]++
+			ok := walk(c.Next(m, call.Arg))
+			pos[b]--
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return walk(m)
+}
+
+func genCalls[M, Sys any](mach Machine[M, Sys], r *rand.Rand, m M, n int) ([]Call, M) {
+	var calls []Call
+	for range n {
+		ok := false
+		// A few draws, since preconditions may reject most of them.
+		for range 10 * len(mach.Commands) {
+			c := &mach.Commands[r.IntN(len(mach.Commands))]
+			var arg any
+			if c.Gen != nil {
+				arg = c.Gen(r, m)
+			}
+			if c.pre(m, arg) {
+				calls = append(calls, Call{c.Name, arg})
+				m = c.Next(m, arg)
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			break
+		}
+	}
+	return calls, m
+}
+
+func (mach Machine[M, Sys]) command(name string) *Command[M, Sys] {
+	for i := range mach.Commands {
+		if mach.Commands[i].Name == name {
+			return &mach.Commands[i]
+		}
+	}
+	return nil
+}
+
+// ErrPrecondition is returned by RunProgram for a program that calls a
+// command whose precondition does not hold.
+var ErrPrecondition = errors.New("precondition does not hold")
+
+// RunProgram runs p on a new system in a fresh bubble and checks the
+// results against the model. The bubble is that of a sched run following
+// p.Schedule, so the same program runs the same way.
+func RunProgram[M, Sys any](mach Machine[M, Sys], p Program) (err error) {
+	_, derr := sched.RunChoices(p.Schedule, func() {
+		sys := mach.New()
+		if c, ok := any(sys).(io.Closer); ok {
+			defer func() {
+				if cerr := c.Close(); cerr != nil && err == nil {
+					err = fmt.Errorf("close: %w", cerr)
+				}
+			}()
+		}
+		m := mach.Init()
+		for i, call := range p.Prefix {
+			c := mach.command(call.Command)
+			if c == nil {
+				err = fmt.Errorf("step %d: no command %q", i+1, call.Command)
+				return
+			}
+			if !c.pre(m, call.Arg) {
+				err = fmt.Errorf("step %d %v: %w", i+1, call, ErrPrecondition)
+				return
+			}
+			res := c.Run(sys, call.Arg)
+			// Alone, the thread is resumed once the bubble settled.
+			sched.Yield("settle")
+			if perr := c.post(m, call.Arg, res); perr != nil {
+				err = fmt.Errorf("step %d %v → %v: %w", i+1, call, res, perr)
+				return
+			}
+			m = c.Next(m, call.Arg)
+		}
+		if len(p.Parallel) > 0 {
+			err = runParallel(mach, sys, m, p.Parallel)
+		}
+	})
+	if derr != nil {
+		return derr
+	}
+	return err
+}
+
+// runParallel runs the branches as threads of the caller's sched run,
+// records their history and checks it is linearizable with respect to the
+// model.
+func runParallel[M, Sys any](mach Machine[M, Sys], sys Sys, m M, branches [][]Call) error {
+	rec := new(history.Recorder[Call, any])
+	done := make(chan struct{})
+	for b, br := range branches {
+		sched.Go(fmt.Sprintf("branch %d", b), func() {
+			defer func() { done <- struct{}{} }()
+			for _, call := range br {
+				sched.Yield(call.Command)
+				ret := rec.Call(b, call)
+				ret(mach.command(call.Command).Run(sys, call.Arg))
+			}
+		})
+	}
+	for range branches {
+		<-done
+	}
+	spec := history.Spec[M, Call, any]{
+		Name: "model",
+		Init: func() M { return m },
+		Step: func(s M, call Call, res any) (bool, M) {
+			c := mach.command(call.Command)
+			if !c.pre(s, call.Arg) {
+				return false, s
+			}
+			return c.post(s, call.Arg, res) == nil, c.Next(s, call.Arg)
+		},
+	}
+	if res := history.Linearizable(spec, rec.Ops()); !res.OK {
+		var b strings.Builder
+		for _, op := range res.Window {
+			fmt.Fprintf(&b, "\n\tbranch %d: %v → %v", op.Client, op.Input, op.Output)
+		}
+		return fmt.Errorf("no interleaving of the parallel branches explains their results; these cannot be ordered:%s", b.String())
+	}
+	return nil
+}
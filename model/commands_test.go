@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+)
+
+// counter is the system under test: an atomic counter with bugs to
+// switch on.
+type counter struct {
+	n            atomic.Int64
+	lostUpdate   bool // Add reads and writes in two steps, with a preemption point between
+	resetMissing bool // Reset does nothing above 5
+}
+
+func (c *counter) add(d int64) {
+	if !c.lostUpdate {
+		c.n.Add(d)
+		return
+	}
+	v := c.n.Load()
+	sched.Yield("store")
+	c.n.Store(v + d)
+}
+
+func counterMachine(lostUpdate, resetMissing bool) Machine[int64, *counter] {
+	return Machine[int64, *counter]{
+		Init: func() int64 { return 0 },
+		New:  func() *counter { return &counter{lostUpdate: lostUpdate, resetMissing: resetMissing} },
+		Commands: []Command[int64, *counter]{
+			{
+				Name: "add",
+				Gen:  func(r *rand.Rand, _ int64) any { return int64(1 + r.IntN(3)) },
+				Shrink: func(arg any) []any {
+					var simpler []any
+					for d := int64(1); d < arg.(int64); d++ {
+						simpler = append(simpler, d)
+					}
+					return simpler
+				},
+				Run:  func(c *counter, arg any) any { c.add(arg.(int64)); return nil },
+				Next: func(m int64, arg any) int64 { return m + arg.(int64) },
+			},
+			{
+				Name: "get",
+				Run:  func(c *counter, _ any) any { return c.n.Load() },
+				Next: func(m int64, _ any) int64 { return m },
+				Post: func(m int64, _, res any) error {
+					if res.(int64) != m {
+						return fmt.Errorf("got %d, model has %d", res, m)
+					}
+					return nil
+				},
+			},
+			{
+				Name: "reset",
+				Pre:  func(m int64, _ any) bool { return m > 0 },
+				Run: func(c *counter, _ any) any {
+					if !c.resetMissing || c.n.Load() <= 5 {
+						c.n.Store(0)
+					}
+					return nil
+				},
+				Next: func(int64, any) int64 { return 0 },
+			},
+		},
+	}
+}
+
+func TestCheckCommands(t *testing.T) {
+	CheckCommands(t, counterMachine(false, false), CommandsConfig{Seed: 1})
+	CheckCommands(t, counterMachine(false, false), CommandsConfig{Seed: 1, Branches: 2})
+}
+
+func TestCheckCommandsFindsBug(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		CheckCommands(t, counterMachine(false, true), CommandsConfig{Seed: 1})
+	})
+	// Above 5 takes two adds of 3; reset and a get show the bug.
+	if len(errs) != 1 || !strings.Contains(errs[0], "seed 1 run ") ||
+		!strings.Contains(errs[0], "step 4 get → 6: got 6, model has 0") ||
+		!strings.Contains(errs[0], "minimal program (4 of ") ||
+		!strings.HasSuffix(errs[0], "[add(3) add(3) reset get]") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+func TestCheckCommandsParallel(t *testing.T) {
+	// Sequentially the lost update cannot show.
+	CheckCommands(t, counterMachine(true, false), CommandsConfig{Seed: 2, Runs: 20})
+
+	errs := testtb.Run(t, func(t testing.TB) {
+		CheckCommands(t, counterMachine(true, false), CommandsConfig{Seed: 2, Branches: 2})
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], "no interleaving of the parallel branches explains their results") {
+		t.Fatalf("errors %q", errs)
+	}
+	if !strings.HasSuffix(errs[0], "minimal program (3 of 8 commands, 85 runs to shrink) []\n\tbranch 0: [add(1)]\n\tbranch 1: [add(1) get]\n\tschedule [189 59 228 196 77 252 95 186 230 7]") {
+		t.Errorf("not shrunk to the lost update: %s", errs[0])
+	}
+}
+
+func TestRunProgramPrecondition(t *testing.T) {
+	err := RunProgram(counterMachine(false, false), Program{Prefix: []Call{{"reset", nil}}})
+	if err == nil || !strings.Contains(err.Error(), "step 1 reset: precondition does not hold") {
+		t.Errorf("error %v", err)
+	}
+}
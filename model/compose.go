@@ -0,0 +1,120 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// Component is a model with its state type erased, to be composed with
+// models over other state types.
+type Component struct {
+	name       string
+	init       any
+	actions    []Action[any]
+	invariants []Invariant[any]
+	liveness   []Liveness[any]
+	key        func(any) string
+}
+
+// Component returns m as a component for Compose.
+func (m *Model[S]) Component() Component {
+	c := Component{name: m.Name, init: m.Init, key: func(s any) string { return m.StateKey(s.(S)) }}
+	for _, a := range m.Actions {
+		ea := Action[any]{Name: a.Name, Step: func(s any) any { return a.Step(s.(S)) }, Fairness: a.Fairness, Weight: a.Weight}
+		if a.Guard != nil {
+			ea.Guard = func(s any) bool { return a.Guard(s.(S)) }
+		}
+		c.actions = append(c.actions, ea)
+	}
+	for _, inv := range m.Invariants {
+		c.invariants = append(c.invariants, Invariant[any]{inv.Name, func(s any) bool { return inv.Check(s.(S)) }})
+	}
+	for _, l := range m.Liveness {
+		el := Liveness[any]{Name: l.Name, To: func(s any) bool { return l.To(s.(S)) }}
+		if l.From != nil {
+			el.From = func(s any) bool { return l.From(s.(S)) }
+		}
+		c.liveness = append(c.liveness, el)
+	}
+	return c
+}
+
+// Composite is the state of composed models: the states of the components,
+// in the order they were composed.
+type Composite []any
+
+// Compose returns the parallel composition of components, such as the
+// client, network and server of a protocol. An action only one component
+// has is taken by that component alone, interleaved with the actions of
+// the others; an action several components have is shared: it is enabled
+// when it is enabled in each of them and they take it together, which is
+// how the components communicate. A shared action is as fair as the
+// fairest of them. The invariants and liveness properties of the
+// components carry over, named after their component, e.g.
+// "server.at most one response".
+func Compose(name string, components ...Component) *Model[Composite] {
+	m := &Model[Composite]{Name: name, Key: func(s Composite) string {
+		keys := make([]string, len(s))
+		for i, c := range components {
+			keys[i] = c.key(s[i])
+		}
+		return "[" + strings.Join(keys, " | ") + "]"
+	}}
+	for _, c := range components {
+		m.Init = append(m.Init, c.init)
+	}
+	var names []string
+	for _, c := range components {
+		for _, a := range c.actions {
+			if !slices.Contains(names, a.Name) {
+				names = append(names, a.Name)
+			}
+		}
+	}
+	for _, name := range names {
+		var parts []int // the components taking part
+		var acts []Action[any]
+		ca := Action[Composite]{Name: name}
+		for i, c := range components {
+			for _, a := range c.actions {
+				if a.Name == name {
+					parts = append(parts, i)
+					acts = append(acts, a)
+					ca.Fairness = max(ca.Fairness, a.Fairness)
+					if ca.Weight == 0 {
+						ca.Weight = a.Weight
+					}
+				}
+			}
+		}
+		ca.Guard = func(s Composite) bool {
+			for j, i := range parts {
+				if !acts[j].Enabled(s[i]) {
+					return false
+				}
+			}
+			return true
+		}
+		ca.Step = func(s Composite) Composite {
+			s = slices.Clone(s)
+			for j, i := range parts {
+				s[i] = acts[j].Step(s[i])
+			}
+			return s
+		}
+		m.Actions = append(m.Actions, ca)
+	}
+	for i, c := range components {
+		for _, inv := range c.invariants {
+			m.Invariants = append(m.Invariants, Invariant[Composite]{c.name + "." + inv.Name, func(s Composite) bool { return inv.Check(s[i]) }})
+		}
+		for _, l := range c.liveness {
+			cl := Liveness[Composite]{Name: c.name + "." + l.Name, To: func(s Composite) bool { return l.To(s[i]) }}
+			if l.From != nil {
+				cl.From = func(s Composite) bool { return l.From(s[i]) }
+			}
+			m.Liveness = append(m.Liveness, cl)
+		}
+	}
+	return m
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"testing"
+)
+
+// The 100-continue handshake: the client sends its headers and waits for
+// the server to either let it send the body or reject the request.
+type client struct{ Phase string }
+
+type server struct {
+	Phase     string
+	Responses int
+}
+
+func clientModel() *Model[client] {
+	in := func(phases ...string) func(client) bool {
+		return func(s client) bool {
+			for _, p := range phases {
+				if s.Phase == p {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	to := func(p string) func(client) client { return func(client) client { return client{p} } }
+	return &Model[client]{
+		Name: "client",
+		Init: client{"idle"},
+		Actions: []Action[client]{
+			{Name: "headers", Guard: in("idle"), Step: to("waiting")},
+			{Name: "continue", Guard: in("waiting"), Step: to("sending")},
+			{Name: "body", Guard: in("sending"), Step: to("sent")},
+			{Name: "reject", Guard: in("waiting"), Step: to("done")},
+			{Name: "response", Guard: in("sent"), Step: to("done")},
+		},
+		Liveness: []Liveness[client]{{Name: "finishes", To: func(s client) bool { return s.Phase == "done" }}},
+	}
+}
+
+func serverModel(timeout bool) *Model[server] {
+	phase := func(p string) func(server) bool { return func(s server) bool { return s.Phase == p } }
+	return &Model[server]{
+		Name: "server",
+		Init: server{Phase: "idle"},
+		Actions: []Action[server]{
+			{Name: "headers", Guard: phase("idle"), Step: func(s server) server { s.Phase = "deciding"; return s }},
+			{Name: "continue", Guard: phase("deciding"), Step: func(s server) server { s.Phase = "reading"; return s }},
+			{Name: "reject", Guard: phase("deciding"), Step: func(s server) server { s.Phase = "closed"; s.Responses++; return s }},
+			{Name: "body", Guard: phase("reading"), Step: func(s server) server { s.Phase = "replying"; return s }},
+			{Name: "response", Guard: phase("replying"), Step: func(s server) server { s.Phase = "closed"; s.Responses++; return s }},
+			{Name: "timeout", Guard: func(s server) bool { return timeout && s.Phase == "replying" }, Step: func(s server) server { s.Phase = "closed"; return s }},
+		},
+		Invariants: []Invariant[server]{{Name: "one response", Check: func(s server) bool { return s.Responses <= 1 }}},
+	}
+}
+
+func TestCompose(t *testing.T) {
+	m := Compose("100-continue", clientModel().Component(), serverModel(false).Component())
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := m.Run("headers", "continue", "body", "response")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.StateKey(tr[len(tr)-1].State); got != "[{Phase:done} | {Phase:closed Responses:1}]" {
+		t.Errorf("final state %s", got)
+	}
+	sp, err := m.StateSpace(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both sides move together: idle, waiting, sending, sent, and done
+	// after either a response or a rejection.
+	if len(sp.States) != 5 {
+		t.Errorf("states %q", sp.States)
+	}
+	if err := m.CheckLiveness(0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestComposeStuck(t *testing.T) {
+	// A server that may time out on its own after reading the body leaves
+	// the client waiting for the response forever.
+	m := Compose("100-continue", clientModel().Component(), serverModel(true).Component())
+	want := "model 100-continue: liveness client.finishes violated: after headers → continue → body → timeout the run ends in state [{Phase:sent} {Phase:closed Responses:0}]"
+	if err := m.CheckLiveness(0); err == nil || err.Error() != want {
+		t.Errorf("got %v\nwant %s", err, want)
+	}
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Coverage is how much of a model's reachable state graph a test run
+// visited.
+type Coverage struct {
+	Model                     string
+	States, Transitions       int // covered
+	TotalStates, TotalTrans   int // reachable in the model
+	MissedStates, MissedTrans []string
+}
+
+// StateRatio is the fraction of the reachable states covered.
+func (c Coverage) StateRatio() float64 { return ratio(c.States, c.TotalStates) }
+
+// TransitionRatio is the fraction of the reachable transitions covered.
+func (c Coverage) TransitionRatio() float64 { return ratio(c.Transitions, c.TotalTrans) }
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(n) / float64(total)
+}
+
+func (c Coverage) String() string {
+	return fmt.Sprintf("model %s: %d of %d states (%.1f%%), %d of %d transitions (%.1f%%)",
+		c.Model, c.States, c.TotalStates, 100*c.StateRatio(), c.Transitions, c.TotalTrans, 100*c.TransitionRatio())
+}
+
+// Coverage measures rep against the state graph of m, built from at most
+// maxStates states; zero means DefaultMaxStates. States and transitions
+// the report names but the model cannot reach are not counted.
+func (m *Model[S]) Coverage(rep Report, maxStates int) (Coverage, error) {
+	g, err := buildGraph(m, maxStates, false)
+	if err != nil {
+		return Coverage{}, err
+	}
+	c := Coverage{Model: m.Name, TotalStates: len(g.states), TotalTrans: g.edges()}
+	for _, k := range g.keys {
+		if rep.States[k] {
+			c.States++
+		} else {
+			c.MissedStates = append(c.MissedStates, k)
+		}
+	}
+	for _, es := range g.out {
+		for _, e := range es {
+			if k := g.edgeKey(e); rep.Transitions[k] {
+				c.Transitions++
+			} else {
+				c.MissedTrans = append(c.MissedTrans, k)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Record adds the states and transitions of tr, a run of m from its
+// initial state, to the coverage of rep, for runs other than those of
+// Execute.
+func Record[S any](rep *Report, m *Model[S], tr Trace[S]) {
+	if rep.States == nil {
+		rep.States, rep.Transitions = make(map[string]bool), make(map[string]bool)
+	}
+	from := m.StateKey(m.Init)
+	rep.States[from] = true
+	for _, st := range tr {
+		to := m.StateKey(st.State)
+		rep.States[to] = true
+		rep.Transitions[transitionKey(from, st.Action, to)] = true
+		from = to
+	}
+}
+
+// Thresholds are the least coverage a test run must reach, as fractions.
+type Thresholds struct {
+	States, Transitions float64
+}
+
+// RequireCoverage fails t, listing what was missed, if c is below min, so
+// that a build fails once tests stop covering the model.
+func RequireCoverage(t testing.TB, c Coverage, min Thresholds) {
+	t.Helper()
+	var short []string
+	if c.StateRatio() < min.States {
+		short = append(short, fmt.Sprintf("state coverage below %.1f%%, missed:\n\t\t%s",
+			100*min.States, strings.Join(slices.Sorted(slices.Values(c.MissedStates)), "\n\t\t")))
+	}
+	if c.TransitionRatio() < min.Transitions {
+		short = append(short, fmt.Sprintf("transition coverage below %.1f%%, missed:\n\t\t%s",
+			100*min.Transitions, strings.Join(slices.Sorted(slices.Values(c.MissedTrans)), "\n\t\t")))
+	}
+	if len(short) > 0 {
+		t.Errorf("%v\n\t%s", c, strings.Join(short, "\n\t"))
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestCoverage(t *testing.T) {
+	m := bufferModel()
+	tests, err := Generate(m, GenConfig{Criterion: StateCoverage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := Execute(t, tests, func() System[buffer] { return queueSystem{newQueue(false)} })
+	c, err := m.Coverage(rep, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.States != 6 || c.TotalStates != 6 || c.TotalTrans != 9 || c.Transitions == 9 {
+		t.Errorf("coverage %v", c)
+	}
+	RequireCoverage(t, c, Thresholds{States: 1})
+
+	errs := testtb.Run(t, func(t testing.TB) { RequireCoverage(t, c, Thresholds{States: 1, Transitions: 1}) })
+	if len(errs) != 1 || !strings.HasPrefix(errs[0], c.String()+"\n\ttransition coverage below 100.0%, missed:\n\t\t{Len:") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	m := bufferModel()
+	var rep Report
+	tr, err := m.Run("put", "take", "close")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Record(&rep, m, tr)
+	c, err := m.Coverage(rep, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.String(); got != "model buffer: 3 of 6 states (50.0%), 3 of 9 transitions (33.3%)" {
+		t.Errorf("coverage %s", got)
+	}
+}
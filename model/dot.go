@@ -0,0 +1,66 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dot writes the reachable state graph of m, of at most maxStates states
+// (zero means DefaultMaxStates), in the DOT language of GraphViz. The
+// states and transitions in covered are drawn solid, the others dashed and
+// gray; nil maps cover everything. States breaking an invariant are
+// filled red, and the transitions of cex, a trace from the initial state
+// such as a counterexample, are drawn bold and red.
+func (m *Model[S]) Dot(w io.Writer, covered Report, cex Trace[S], maxStates int) error {
+	g, err := buildGraph(m, maxStates, false)
+	if err != nil {
+		return err
+	}
+	onCex := make(map[edge]bool)
+	from := 0
+	for _, st := range cex {
+		to, ok := g.index[m.visitKey(st.State)]
+		if !ok {
+			return fmt.Errorf("model %s: counterexample %v leaves the state graph", m.Name, cex)
+		}
+		onCex[edge{st.Action, from, to}] = true
+		from = to
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %q {\n\tnode [shape=box];\n", m.Name)
+	for i, s := range g.states {
+		attrs := []string{fmt.Sprintf("label=%q", g.keys[i])}
+		var style []string
+		if i == 0 {
+			attrs = append(attrs, "peripheries=2")
+		}
+		if covered.States != nil && !covered.States[g.keys[i]] {
+			style, attrs = append(style, "dashed"), append(attrs, "color=gray")
+		}
+		if v := m.Violated(s); len(v) > 0 {
+			style = append(style, "filled")
+			attrs = append(attrs, "fillcolor=red", fmt.Sprintf("tooltip=%q", "violates "+strings.Join(v, ", ")))
+		}
+		if len(style) > 0 {
+			attrs = append(attrs, fmt.Sprintf("style=%q", strings.Join(style, ",")))
+		}
+		fmt.Fprintf(bw, "\t%d [%s];\n", i, strings.Join(attrs, ", "))
+	}
+	for _, es := range g.out {
+		for _, e := range es {
+			attrs := ""
+			switch {
+			case onCex[e]:
+				attrs = ", color=red, penwidth=2"
+			case covered.Transitions != nil && !covered.Transitions[g.edgeKey(e)]:
+				attrs = ", style=dashed, color=gray"
+			}
+			fmt.Fprintf(bw, "\t%d -> %d [label=%q%s];\n", e.from, e.to, e.action, attrs)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
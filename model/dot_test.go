@@ -0,0 +1,94 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestDot(t *testing.T) {
+	m := bufferModel()
+	tr, err := m.Run("put", "put")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Invariants = append(m.Invariants, Invariant[buffer]{Name: "never full", Check: func(s buffer) bool { return s.Len < 2 }})
+	var rep Report
+	Record(&rep, m, tr)
+	var b strings.Builder
+	if err := m.Dot(&b, rep, tr, 0); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"digraph \"buffer\" {\n\tnode [shape=box];\n",
+		"\t0 [label=\"{Len:0 Closed:false}\", peripheries=2];\n",
+		"\t2 [label=\"{Len:0 Closed:true}\", color=gray, style=\"dashed\"];\n",
+		"\t3 [label=\"{Len:2 Closed:false}\", fillcolor=red, tooltip=\"violates never full\", style=\"filled\"];\n",
+		"\t5 [label=\"{Len:2 Closed:true}\", color=gray, fillcolor=red, tooltip=\"violates never full\", style=\"dashed,filled\"];\n",
+		"\t0 -> 1 [label=\"put\", color=red, penwidth=2];\n",
+		"\t1 -> 0 [label=\"take\", style=dashed, color=gray];\n",
+		"}\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("graph lacks %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, " -> "); n != 9 {
+		t.Errorf("%d transitions, want 9", n)
+	}
+}
+
+func TestExploreDot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.dot")
+	errs := testtb.Run(t, func(t testing.TB) {
+		Explore(t, bufferModel(), ExploreConfig{DotFile: path}, func() System[buffer] { return queueSystem{newQueue(true)} })
+	})
+	if len(errs) != 1 {
+		t.Fatalf("errors %q", errs)
+	}
+	dot, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Breadth first, put → close fails before close is explored from the
+	// initial state; the failing close is drawn red.
+	for _, want := range []string{
+		"\t1 -> 4 [label=\"close\", color=red, penwidth=2];\n",
+		"\t0 -> 1 [label=\"put\", color=red, penwidth=2];\n",
+	} {
+		if !strings.Contains(string(dot), want) {
+			t.Errorf("graph lacks %q:\n%s", want, dot)
+		}
+	}
+	// Until then every transition checked is drawn solid, and so are the
+	// states it reached.
+	for _, want := range []string{
+		"\t0 [label=\"{Len:0 Closed:false}\", peripheries=2];\n",
+		"\t1 [label=\"{Len:1 Closed:false}\"];\n",
+		"\t0 -> 2 [label=\"close\"];\n",
+	} {
+		if !strings.Contains(string(dot), want) {
+			t.Errorf("graph lacks %q:\n%s", want, dot)
+		}
+	}
+}
+
+func TestExploreDotCovered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.dot")
+	Explore(t, bufferModel(), ExploreConfig{DotFile: path}, func() System[buffer] { return queueSystem{newQueue(false)} })
+	dot, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A full exploration covers the whole graph.
+	if strings.Contains(string(dot), "dashed") {
+		t.Errorf("graph of a full exploration with parts not covered:\n%s", dot)
+	}
+	if n := strings.Count(string(dot), " -> "); n != 9 {
+		t.Errorf("%d transitions, want 9:\n%s", n, dot)
+	}
+}
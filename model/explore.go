@@ -0,0 +1,294 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/events"
+)
+
+// Strategy is the order in which Explore visits states.
+type Strategy int
+
+const (
+	BFS Strategy = iota // shortest paths first
+	DFS                 // deepest paths first
+)
+
+// ExploreConfig bounds an exploration.
+type ExploreConfig struct {
+	Strategy  Strategy
+	MaxDepth  int // zero means no bound
+	MaxStates int // default DefaultMaxStates
+	// Independent, if set, reports actions that commute: taking both, in
+	// either order, leads to the same state, and taking one neither
+	// enables nor disables the other. Explore then skips interleavings of
+	// independent actions that are equivalent to one it does explore. The
+	// reduction reaches every end state, but not every intermediate one,
+	// so independent actions must not affect the invariants, nor what the
+	// System is checked for.
+	Independent func(a, b string) bool
+	// ArtifactDir, if set, is where a failing run is saved, minimized, for
+	// Replay.
+	ArtifactDir string
+	// DotFile, if set, is where the reachable state graph is written once
+	// the exploration ends, in the DOT language of GraphViz, with the
+	// states and transitions explored and any failing trace highlighted;
+	// see Model.Dot.
+	DotFile string
+	// Checkpoint, if set, is a file Explore saves its progress to every
+	// CheckpointEvery expansions (default 1000) and when TimeBudget runs
+	// out, and resumes from if it exists. It is removed once the
+	// exploration completes. States left out because of MaxStates are not
+	// saved, so a long exploration is better bounded by TimeBudget.
+	Checkpoint      string
+	CheckpointEvery int
+	// TimeBudget, if positive, stops the exploration after that much real
+	// time, truncated, without checking liveness.
+	TimeBudget time.Duration
+}
+
+func (cfg ExploreConfig) checkpointEvery() int {
+	if cfg.CheckpointEvery > 0 {
+		return cfg.CheckpointEvery
+	}
+	return 1000
+}
+
+// ExploreReport summarizes an exploration.
+type ExploreReport struct {
+	States      int // distinct states reached
+	Transitions int // transitions checked against the implementation
+	Cycles      int // transitions back to a state on the path that reached them
+	Pruned      int // transitions skipped as equivalent to others
+	Depth       int // length of the longest path explored
+	Truncated   bool
+}
+
+func (r ExploreReport) String() string {
+	s := fmt.Sprintf("%d states, %d transitions, %d cycles, depth %d", r.States, r.Transitions, r.Cycles, r.Depth)
+	if r.Pruned > 0 {
+		s += fmt.Sprintf(", %d pruned", r.Pruned)
+	}
+	if r.Truncated {
+		s += " (truncated)"
+	}
+	return s
+}
+
+type exploreNode[S any] struct {
+	state S
+	trace Trace[S]
+	keys  []any // visit keys of the states on trace, the initial one first
+	node  int   // index of the state in the checkpoint tree
+}
+
+// Explore runs m and the implementation in lockstep over every state
+// reachable within the bounds of cfg. Every transition the model can take
+// is replayed on a fresh System in a fresh bubble, from the initial state
+// along the path that reached it, and the System is checked against the
+// model after the last step, with the bubble settled; earlier steps were
+// checked when their own transitions were. States are told apart by
+// m.Abstract, or else m.Key, so a Key that ignores some field makes
+// states equal that differ only in it. States already visited are not
+// expanded again, so cycles end the path. t fails at the first transition on which the System errs
+// or a state breaks an invariant, with the trace leading there, and the
+// exploration stops. Once every transition checked out, t fails if the
+// model breaks one of its liveness properties under the fairness of its
+// actions, see CheckLiveness. With cfg.Checkpoint, the report covers the
+// runs resumed as well.
+func Explore[S any](t testing.TB, m *Model[S], cfg ExploreConfig, newSystem func() System[S]) ExploreReport {
+	t.Helper()
+	var rep ExploreReport
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	key := m.visitKey
+	maxStates := cfg.MaxStates
+	if maxStates <= 0 {
+		maxStates = DefaultMaxStates
+	}
+
+	var covered Report
+	var failed Trace[S]
+	if cfg.DotFile != "" {
+		covered = Report{States: make(map[string]bool), Transitions: make(map[string]bool)}
+		defer func() { writeDot(t, m, cfg.DotFile, covered, failed, maxStates) }()
+	}
+
+	if err := checkSystem(m.Init, nil, newSystem); err != nil {
+		t.Errorf("model %s: initial state %+v: %v", m.Name, m.Init, err)
+		return rep
+	}
+	if v := m.Violated(m.Init); len(v) > 0 {
+		t.Error(&Error[S]{Model: m.Name, State: m.Init, Violated: v})
+		return rep
+	}
+	visited := map[any]bool{key(m.Init): true}
+	if covered.States != nil {
+		covered.States[m.StateKey(m.Init)] = true
+	}
+	rep.States = 1
+	work := []exploreNode[S]{{state: m.Init, keys: []any{key(m.Init)}}}
+	var tree []CheckpointState
+	if cfg.Checkpoint != "" {
+		tree = []CheckpointState{{Parent: -1}}
+		cp, err := ReadCheckpoint(cfg.Checkpoint)
+		switch {
+		case err == nil:
+			if visited, work, err = restore(m, cp); err != nil {
+				t.Fatal(err)
+			}
+			rep, tree = cp.Report, cp.States
+			t.Logf("model %s: resuming from %s after %v", m.Name, cfg.Checkpoint, rep)
+		case !errors.Is(err, fs.ErrNotExist):
+			t.Fatal(err)
+		}
+	}
+	save := func() {
+		if err := writeCheckpoint(cfg.Checkpoint, checkpoint(m, rep, tree, work)); err != nil {
+			t.Errorf("model %s: checkpoint: %v", m.Name, err)
+		}
+	}
+	start := time.Now()
+	for expanded := 0; len(work) > 0; expanded++ {
+		if cfg.TimeBudget > 0 && time.Since(start) > cfg.TimeBudget {
+			if cfg.Checkpoint != "" {
+				save()
+				t.Logf("model %s: out of time after %v; progress saved to %s", m.Name, rep, cfg.Checkpoint)
+			}
+			rep.Truncated = true
+			return rep
+		}
+		if cfg.Checkpoint != "" && expanded > 0 && expanded%cfg.checkpointEvery() == 0 {
+			save()
+		}
+		var n exploreNode[S]
+		if cfg.Strategy == DFS {
+			n, work = work[len(work)-1], work[:len(work)-1]
+		} else {
+			n, work = work[0], work[1:]
+		}
+		if cfg.MaxDepth > 0 && len(n.trace) >= cfg.MaxDepth {
+			if len(m.Enabled(n.state)) > 0 {
+				rep.Truncated = true
+			}
+			continue
+		}
+		enabled := m.Enabled(n.state)
+		if cfg.Independent != nil {
+			amp := ample(m, cfg.Independent, n.state, enabled, func(s S) bool { return visited[key(s)] })
+			rep.Pruned += len(enabled) - len(amp)
+			enabled = amp
+		}
+		var next []exploreNode[S]
+		for _, a := range enabled {
+			s := a.Step(n.state)
+			tr := append(slices.Clip(n.trace), Step[S]{a.Name, s})
+			rep.Transitions++
+			rep.Depth = max(rep.Depth, len(tr))
+			if err := checkSystem(s, tr, newSystem); err != nil {
+				events.Emit(t, events.Event{Action: "fail", Run: rep.Transitions, Message: fmt.Sprintf("model %s: after %v: %v", m.Name, tr, err)})
+				t.Errorf("model %s: after %v: model state %+v: %v", m.Name, tr, s, err)
+				failed = tr
+				if cfg.ArtifactDir != "" {
+					saveArtifact(t, m, cfg.ArtifactDir, tr, newSystem)
+				}
+				return rep
+			}
+			if v := m.Violated(s); len(v) > 0 {
+				events.Emit(t, events.Event{Action: "violation", Run: rep.Transitions, Message: fmt.Sprintf("model %s: after %v: %v violated", m.Name, tr, v)})
+				t.Error(&Error[S]{Model: m.Name, Trace: tr, State: s, Violated: v})
+				failed = tr
+				if cfg.ArtifactDir != "" {
+					saveArtifact(t, m, cfg.ArtifactDir, tr, newSystem)
+				}
+				return rep
+			}
+			if covered.States != nil {
+				from, to := m.StateKey(n.state), m.StateKey(s)
+				covered.States[to] = true
+				covered.Transitions[transitionKey(from, a.Name, to)] = true
+			}
+			k := key(s)
+			if slices.Contains(n.keys, k) {
+				rep.Cycles++
+			}
+			if visited[k] {
+				continue
+			}
+			if rep.States == maxStates {
+				rep.Truncated = true
+				continue
+			}
+			visited[k] = true
+			rep.States++
+			next = append(next, exploreNode[S]{s, tr, append(slices.Clip(n.keys), k), len(tree)})
+			if tree != nil {
+				tree = append(tree, CheckpointState{n.node, a.Name})
+			}
+		}
+		if cfg.Strategy == DFS {
+			// Expand the first action first.
+			slices.Reverse(next)
+		}
+		work = append(work, next...)
+	}
+	if cfg.Checkpoint != "" {
+		if err := os.Remove(cfg.Checkpoint); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("model %s: checkpoint: %v", m.Name, err)
+		}
+	}
+	if err := m.CheckLiveness(maxStates); err != nil {
+		events.Emit(t, events.Event{Action: "violation", Message: err.Error()})
+		t.Error(err)
+	}
+	events.Emit(t, events.Event{Action: "done", Runs: rep.Transitions, Message: fmt.Sprintf("model %s: %v", m.Name, rep)})
+	return rep
+}
+
+// writeDot writes the graph explored to path.
+func writeDot[S any](t testing.TB, m *Model[S], path string, covered Report, failed Trace[S], maxStates int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err == nil {
+		err = m.Dot(f, covered, failed, maxStates)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		t.Errorf("model %s: state graph: %v", m.Name, err)
+	}
+}
+
+// checkSystem replays tr on a new System in a fresh bubble and checks it
+// against want at the end.
+func checkSystem[S any](want S, tr Trace[S], newSystem func() System[S]) (err error) {
+	synctest.Run(func() {
+		sys := newSystem()
+		if c, ok := sys.(io.Closer); ok {
+			defer func() {
+				if cerr := c.Close(); cerr != nil && err == nil {
+					err = fmt.Errorf("close: %w", cerr)
+				}
+			}()
+		}
+		for i, st := range tr {
+			if err = sys.Do(st.Action); err != nil {
+				err = fmt.Errorf("step %d %s: %w", i+1, st.Action, err)
+				return
+			}
+			synctest.Wait()
+		}
+		err = sys.Check(want)
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestExplore(t *testing.T) {
+	for _, strategy := range []Strategy{BFS, DFS} {
+		rep := Explore(t, bufferModel(), ExploreConfig{Strategy: strategy}, func() System[buffer] { return queueSystem{newQueue(false)} })
+		if rep.States != 6 || rep.Transitions != 9 || rep.Truncated {
+			t.Errorf("strategy %d: %v", strategy, rep)
+		}
+		// put → take, and take → put after it, return to a state on the path.
+		if rep.Cycles == 0 {
+			t.Errorf("strategy %d: no cycles found: %v", strategy, rep)
+		}
+	}
+}
+
+func TestExploreDepth(t *testing.T) {
+	rep := Explore(t, bufferModel(), ExploreConfig{MaxDepth: 1}, func() System[buffer] { return queueSystem{newQueue(false)} })
+	if rep.States != 3 || rep.Depth != 1 || !rep.Truncated {
+		t.Errorf("%v", rep)
+	}
+}
+
+func TestExploreKey(t *testing.T) {
+	m := bufferModel()
+	m.Key = func(s buffer) string { return fmt.Sprint(s.Len) }
+	rep := Explore(t, m, ExploreConfig{}, func() System[buffer] { return queueSystem{newQueue(false)} })
+	if rep.States != 3 {
+		t.Errorf("states told apart by length only: %v", rep)
+	}
+}
+
+func TestExploreFindsDivergence(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		Explore(t, bufferModel(), ExploreConfig{}, func() System[buffer] { return queueSystem{newQueue(true)} })
+	})
+	// The shortest divergence: close with one value buffered.
+	want := "model buffer: after put → close: model state {Len:1 Closed:true}: queue state {Len:0 Closed:true}"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
+
+func TestExploreInvariant(t *testing.T) {
+	m := bufferModel()
+	m.Actions[0].Guard = func(s buffer) bool { return s.Len < 3 }
+	errs := testtb.Run(t, func(t testing.TB) {
+		Explore(t, m, ExploreConfig{}, func() System[buffer] { return nopSystem{} })
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], "invariant bounded violated after put → put → put") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+type nopSystem struct{}
+
+func (nopSystem) Do(string) error    { return nil }
+func (nopSystem) Check(buffer) error { return nil }
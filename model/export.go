@@ -0,0 +1,99 @@
+package model
+
+import "io"
+
+// StateSpace is the reachable state graph of a model, stripped of Go
+// types so that export backends can write it in the language of a model
+// checker. States are numbered in breadth-first order from the initial
+// state, which is state 0.
+type StateSpace struct {
+	Name       string
+	States     []string // the keys of the states
+	Actions    []ActionSpace
+	Invariants []InvariantSpace
+}
+
+// ActionSpace lists the transitions of one action.
+type ActionSpace struct {
+	Name        string
+	Transitions [][2]int // from, to
+}
+
+// InvariantSpace lists the states satisfying one invariant.
+type InvariantSpace struct {
+	Name  string
+	Holds []int
+}
+
+// StateSpace builds the state space of m, exploring at most maxStates
+// states; zero means DefaultMaxStates. States breaking an invariant are
+// kept, so that the model checker reports them.
+func (m *Model[S]) StateSpace(maxStates int) (*StateSpace, error) {
+	g, err := buildGraph(m, maxStates, false)
+	if err != nil {
+		return nil, err
+	}
+	sp := &StateSpace{Name: m.Name, States: g.keys}
+	for _, a := range m.Actions {
+		as := ActionSpace{Name: a.Name}
+		for _, es := range g.out {
+			for _, e := range es {
+				if e.action == a.Name {
+					as.Transitions = append(as.Transitions, [2]int{e.from, e.to})
+				}
+			}
+		}
+		sp.Actions = append(sp.Actions, as)
+	}
+	for _, inv := range m.Invariants {
+		is := InvariantSpace{Name: inv.Name}
+		for i, s := range g.states {
+			if inv.Check(s) {
+				is.Holds = append(is.Holds, i)
+			}
+		}
+		sp.Invariants = append(sp.Invariants, is)
+	}
+	return sp, nil
+}
+
+// A Backend writes state spaces in the input language of a model checker.
+type Backend interface {
+	Write(w io.Writer, sp *StateSpace) error
+}
+
+// Export writes m with backend b, so that the model driving the Go tests
+// stays the single source of the specification checked elsewhere.
+// Regenerate the output whenever the model changes.
+func (m *Model[S]) Export(w io.Writer, b Backend, maxStates int) error {
+	sp, err := m.StateSpace(maxStates)
+	if err != nil {
+		return err
+	}
+	return b.Write(w, sp)
+}
+
+// ident turns name into an identifier of the model checkers' languages.
+func ident(name string) string {
+	var b []byte
+	for _, r := range name {
+		if r < 0x80 && (r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			b = append(b, byte(r))
+		} else {
+			b = append(b, '_')
+		}
+	}
+	if len(b) == 0 || '0' <= b[0] && b[0] <= '9' {
+		b = append([]byte("M"), b...)
+	}
+	return string(b)
+}
+
+// all returns the numbers of n states.
+func all(n int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStateSpace(t *testing.T) {
+	sp, err := bufferModel().StateSpace(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.States) != 6 || sp.States[0] != "{Len:0 Closed:false}" {
+		t.Errorf("states %q", sp.States)
+	}
+	n := 0
+	for _, a := range sp.Actions {
+		n += len(a.Transitions)
+	}
+	if n != 9 {
+		t.Errorf("%d transitions, want 9", n)
+	}
+	if len(sp.Invariants) != 1 || len(sp.Invariants[0].Holds) != 6 {
+		t.Errorf("invariants %+v", sp.Invariants)
+	}
+}
+
+func TestBackends(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		b    Backend
+		want []string
+	}{
+		{"promela", Promela{}, []string{
+			"/* state 0: {Len:0 Closed:false} */\n",
+			"#define bounded (state == 0 || state == 1",
+			"active proctype buffer() {\nend:\n\tdo\n\t/* put */\n\t:: atomic { state == 0 -> state = 1 }\n",
+			"\tod\n}\n",
+			"ltl always_bounded { [] bounded }\n",
+		}},
+		{"alloy", Alloy{}, []string{
+			"module buffer\n",
+			"-- S0: {Len:0 Closed:false}\n",
+			"one sig S0, S1, S2, S3, S4, S5 extends State {}\n",
+			"fun put: State -> State { S0 -> S1 + S1 -> S3 }\n",
+			"fun Next: State -> State { put + take + close }\n",
+			"assert bounded { Reachable in S0 + S1 + S2 + S3 + S4 + S5 }\ncheck bounded for 6 State\n",
+		}},
+	} {
+		var b strings.Builder
+		if err := bufferModel().Export(&b, tc.b, 0); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s: output lacks %q:\n%s", tc.name, want, b.String())
+			}
+		}
+	}
+}
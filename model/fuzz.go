@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// FuzzCommands bridges CheckCommands with go test -fuzz: the fuzzer's
+// inputs are decoded into programs of mach's commands, and the schedules
+// of their branches, by DecodeProgram and checked by CheckProgram. A
+// failing input is saved by the fuzzer in testdata/fuzz, already minimized
+// by its own shrinking, which keeps the short program a short input
+// decodes to; from then on plain go test runs it as a regression test. A
+// few inputs drawn from cfg.Seed seed the corpus.
+//
+//	func FuzzCounter(f *testing.F) {
+//		model.FuzzCommands(f, counterMachine(), model.CommandsConfig{})
+//	}
+func FuzzCommands[M, Sys any](f *testing.F, mach Machine[M, Sys], cfg CommandsConfig) {
+	for run := range uint64(4) {
+		r := rand.New(rand.NewPCG(cfg.Seed, run))
+		b := make([]byte, 8*(1+run))
+		for i := range b {
+			b[i] = byte(r.Uint32())
+		}
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		CheckProgram(t, mach, DecodeProgram(mach, data, cfg))
+	})
+}
+
+// DecodeProgram decodes a program from data, taking each choice of
+// command and argument from the next byte, so that every input is a
+// program whose commands satisfy their preconditions. The first byte
+// bounds the length of the prefix by cfg.MaxLen, and the prefix also ends
+// with the input. Branches are drawn from what is left, if cfg asks for
+// them, and the bytes left after them are the choices of their schedule,
+// so that an input pins the interleaving it failed in too.
+func DecodeProgram[M, Sys any](mach Machine[M, Sys], data []byte, cfg CommandsConfig) Program {
+	var p Program
+	if len(data) == 0 {
+		return p
+	}
+	src := &byteSource{data: data[1:]}
+	r := rand.New(src)
+	m := mach.Init()
+	for n := 1 + int(data[0])%cfg.maxLen(); n > 0 && len(src.data) > 0; n-- {
+		calls, next := genCalls(mach, r, m, 1)
+		if len(calls) == 0 {
+			break
+		}
+		p.Prefix, m = append(p.Prefix, calls...), next
+	}
+	if cfg.Branches >= 2 && len(src.data) > 0 {
+		p.Parallel = genBranches(mach, r, m, cfg)
+		p.Schedule = slices.Clone(src.data)
+	}
+	return p
+}
+
+// byteSource is a rand.Source spreading one byte of input over each
+// value. Once the input is used up it goes on with a fixed stream, since
+// rand.Rand may draw again to reject a value.
+type byteSource struct {
+	data []byte
+	rest rand.PCG
+}
+
+func (s *byteSource) Uint64() uint64 {
+	if len(s.data) == 0 {
+		return s.rest.Uint64()
+	}
+	b := uint64(s.data[0])
+	s.data = s.data[1:]
+	// The middle of the b-th of 256 equal ranges, which rand.Rand does not
+	// reject.
+	return b<<56 | 1<<55
+}
+
+// CheckProgram runs p and, if its results contradict the model, fails t
+// with the program shrunk.
+func CheckProgram[M, Sys any](t testing.TB, mach Machine[M, Sys], p Program) {
+	t.Helper()
+	if err := RunProgram(mach, p); err != nil {
+		t.Error(shrinkReport(mach, p, err))
+	}
+}
+
+func shrinkReport[M, Sys any](mach Machine[M, Sys], p Program, err error) string {
+	small, serr, tries := Shrink(mach, p, err)
+	return fmt.Sprintf("%v\nminimal program (%d of %d commands, %d runs to shrink) %v", serr, small.Len(), p.Len(), tries, small)
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func FuzzCounter(f *testing.F) {
+	FuzzCommands(f, counterMachine(false, false), CommandsConfig{Seed: 1})
+}
+
+func TestDecodeProgram(t *testing.T) {
+	mach := counterMachine(false, true)
+	if p := DecodeProgram(mach, nil, CommandsConfig{}); p.Len() != 0 {
+		t.Errorf("empty input decoded to %v", p)
+	}
+	// Bytes choose among the three commands by thirds and the add
+	// argument likewise: add(3) add(3) reset get, then over.
+	data := []byte{3, 0, 255, 0, 255, 200, 128}
+	p := DecodeProgram(mach, data, CommandsConfig{})
+	if got := p.String(); got != "[add(3) add(3) reset get]" {
+		t.Fatalf("decoded %s", got)
+	}
+	errs := testtb.Run(t, func(t testing.TB) { CheckProgram(t, mach, p) })
+	if len(errs) != 1 || !strings.HasPrefix(errs[0], "step 4 get → 6: got 6, model has 0") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+func TestDecodeProgramSchedule(t *testing.T) {
+	mach := counterMachine(true, false)
+	cfg := CommandsConfig{Branches: 2}
+	// get, then branches of one and two commands, add(1) and add(1) get;
+	// the bytes left choose the interleaving.
+	program := []byte{0, 128, 0, 0, 0, 128, 0, 0, 128}
+	for _, tt := range []struct {
+		schedule []byte
+		fails    bool
+	}{
+		{[]byte{0, 0}, false},
+		{[]byte{1, 1}, true}, // branch 1 stores over the add of branch 0
+	} {
+		p := DecodeProgram(mach, append(program, tt.schedule...), cfg)
+		if got := p.String(); got != fmt.Sprintf("[get]\n\tbranch 0: [add(1)]\n\tbranch 1: [add(1) get]\n\tschedule %v", tt.schedule) {
+			t.Fatalf("decoded %s", got)
+		}
+		for range 3 {
+			if err := RunProgram(mach, p); (err != nil) != tt.fails {
+				t.Errorf("schedule %v: %v", tt.schedule, err)
+			}
+		}
+	}
+}
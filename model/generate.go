@@ -0,0 +1,249 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/synctest"
+)
+
+// Criterion selects what generated tests must cover.
+type Criterion int
+
+const (
+	// StateCoverage visits every reachable state at least once.
+	StateCoverage Criterion = iota
+	// TransitionCoverage takes every transition between reachable states
+	// at least once.
+	TransitionCoverage
+	// AllPaths takes every action sequence up to MaxLen actions that
+	// cannot be extended further within that bound.
+	AllPaths
+)
+
+func (c Criterion) String() string {
+	switch c {
+	case StateCoverage:
+		return "state coverage"
+	case TransitionCoverage:
+		return "transition coverage"
+	case AllPaths:
+		return "all paths"
+	}
+	return fmt.Sprintf("Criterion(%d)", int(c))
+}
+
+// GenConfig configures Generate.
+type GenConfig struct {
+	Criterion Criterion
+	// MaxLen bounds the paths of AllPaths, and is the length after which
+	// a coverage test stops chaining further targets; default 20. A test
+	// still grows beyond it if its first target is farther away.
+	MaxLen int
+	// MaxTests bounds the number of tests; zero means no bound.
+	MaxTests int
+	// MaxStates bounds the state graph; default DefaultMaxStates.
+	MaxStates int
+}
+
+func (cfg GenConfig) maxLen() int {
+	if cfg.MaxLen > 0 {
+		return cfg.MaxLen
+	}
+	return 20
+}
+
+// TestCase is a generated test: the actions to take from the initial
+// state, the states expected after each of them, and what it covers.
+type TestCase[S any] struct {
+	Actions     []string
+	Expect      []S      // model state after each action
+	States      []string // keys of the states visited, the initial one included
+	Transitions []string // the transitions taken, as "from -action-> to"
+}
+
+func (tc TestCase[S]) String() string { return strings.Join(tc.Actions, " → ") }
+
+// Generate derives tests from m according to cfg.Criterion. It builds the
+// reachable state graph first and fails if that is too large or breaks an
+// invariant.
+func Generate[S any](m *Model[S], cfg GenConfig) ([]TestCase[S], error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	g, err := buildGraph(m, cfg.MaxStates, true)
+	if err != nil {
+		return nil, err
+	}
+	var paths [][]edge
+	switch cfg.Criterion {
+	case StateCoverage:
+		paths = g.cover(cfg, func(e edge, done map[string]bool) bool { return !done[g.keys[e.to]] },
+			func(p []edge, done map[string]bool) {
+				for _, e := range p {
+					done[g.keys[e.to]] = true
+				}
+			})
+	case TransitionCoverage:
+		paths = g.cover(cfg, func(e edge, done map[string]bool) bool { return !done[g.edgeKey(e)] },
+			func(p []edge, done map[string]bool) {
+				for _, e := range p {
+					done[g.edgeKey(e)] = true
+				}
+			})
+	case AllPaths:
+		paths = g.allPaths(cfg)
+	default:
+		return nil, fmt.Errorf("model %s: unknown %v", m.Name, cfg.Criterion)
+	}
+	tests := make([]TestCase[S], len(paths))
+	for i, p := range paths {
+		tests[i] = g.testCase(p)
+	}
+	return tests, nil
+}
+
+// cover builds tests greedily: each test walks from the initial state to
+// the nearest target not yet covered, then on to the next nearest one,
+// until it is MaxLen long or no target is left.
+func (g *graph[S]) cover(cfg GenConfig, target func(edge, map[string]bool) bool, mark func([]edge, map[string]bool)) [][]edge {
+	done := map[string]bool{g.keys[0]: true}
+	var paths [][]edge
+	for cfg.MaxTests == 0 || len(paths) < cfg.MaxTests {
+		var p []edge
+		at := 0
+		for len(p) == 0 || len(p) < cfg.maxLen() {
+			next := g.nearest(at, func(e edge) bool { return target(e, done) }, nil)
+			if next == nil {
+				break
+			}
+			mark(next, done)
+			p = append(p, next...)
+			at = next[len(next)-1].to
+		}
+		if len(p) == 0 {
+			break
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// allPaths enumerates the paths of MaxLen actions, and the shorter ones
+// ending in a state without enabled actions, depth first.
+func (g *graph[S]) allPaths(cfg GenConfig) [][]edge {
+	var paths [][]edge
+	var walk func(at int, p []edge) bool
+	walk = func(at int, p []edge) bool {
+		if len(p) == cfg.maxLen() || len(g.out[at]) == 0 {
+			if len(p) > 0 {
+				paths = append(paths, append([]edge(nil), p...))
+			}
+			return cfg.MaxTests == 0 || len(paths) < cfg.MaxTests
+		}
+		for _, e := range g.out[at] {
+			if !walk(e.to, append(p, e)) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(0, nil)
+	return paths
+}
+
+func (g *graph[S]) testCase(p []edge) TestCase[S] {
+	tc := TestCase[S]{States: []string{g.keys[0]}}
+	for _, e := range p {
+		tc.Actions = append(tc.Actions, e.action)
+		tc.Expect = append(tc.Expect, g.states[e.to])
+		tc.States = append(tc.States, g.keys[e.to])
+		tc.Transitions = append(tc.Transitions, g.edgeKey(e))
+	}
+	return tc
+}
+
+// System adapts an implementation to a model: Do performs an action on it
+// and Check compares its observable state with the state the model
+// expects. A System that starts goroutines should also implement
+// io.Closer and stop them in Close, which is called at the end of each
+// test; the bubble only ends once they have exited.
+type System[S any] interface {
+	Do(action string) error
+	Check(want S) error
+}
+
+// TestReport is the outcome of one executed test.
+type TestReport struct {
+	Actions     []string
+	States      []string // covered by the steps that were executed
+	Transitions []string
+	Err         error // why the test failed, if it did
+}
+
+// Report is the outcome of Execute.
+type Report struct {
+	Tests       []TestReport
+	States      map[string]bool // every state key covered by some test
+	Transitions map[string]bool
+}
+
+func (r Report) String() string {
+	failed := 0
+	for _, tr := range r.Tests {
+		if tr.Err != nil {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d tests, %d failed, covering %d states and %d transitions",
+		len(r.Tests), failed, len(r.States), len(r.Transitions))
+}
+
+// Execute runs each test in its own bubble against a System made by
+// newSystem inside that bubble. After every action the bubble is settled
+// and the System checked against the state the model expects; t fails
+// with the test's actions for every test in which Do or Check returns an
+// error, and that test stops there. The report tells what each test
+// covered until it stopped.
+func Execute[S any](t testing.TB, tests []TestCase[S], newSystem func() System[S]) Report {
+	t.Helper()
+	rep := Report{States: make(map[string]bool), Transitions: make(map[string]bool)}
+	for n, tc := range tests {
+		tr := TestReport{Actions: tc.Actions, States: tc.States[:1]}
+		synctest.Run(func() {
+			sys := newSystem()
+			if c, ok := sys.(io.Closer); ok {
+				defer func() {
+					if err := c.Close(); err != nil && tr.Err == nil {
+						tr.Err = fmt.Errorf("close: %w", err)
+					}
+				}()
+			}
+			for i, a := range tc.Actions {
+				if err := sys.Do(a); err != nil {
+					tr.Err = fmt.Errorf("step %d %s: %w", i+1, a, err)
+					return
+				}
+				synctest.Wait()
+				if err := sys.Check(tc.Expect[i]); err != nil {
+					tr.Err = fmt.Errorf("after step %d %s, model state %+v: %w", i+1, a, tc.Expect[i], err)
+					return
+				}
+				tr.States = tc.States[:i+2]
+				tr.Transitions = tc.Transitions[:i+1]
+			}
+		})
+		if tr.Err != nil {
+			t.Errorf("test %d [%v]: %v", n, tc, tr.Err)
+		}
+		for _, k := range tr.States {
+			rep.States[k] = true
+		}
+		for _, k := range tr.Transitions {
+			rep.Transitions[k] = true
+		}
+		rep.Tests = append(rep.Tests, tr)
+	}
+	return rep
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// queue is the implementation under test for bufferModel: a goroutine
+// owning the buffer, driven over channels.
+type queue struct {
+	put, take, close chan chan error
+	state            chan chan buffer
+	stop             chan struct{}
+}
+
+func newQueue(dropOnClose bool) *queue {
+	q := &queue{make(chan chan error), make(chan chan error), make(chan chan error), make(chan chan buffer), make(chan struct{})}
+	go func() {
+		var s buffer
+		for {
+			select {
+			case r := <-q.put:
+				if s.Closed || s.Len == 2 {
+					r <- errors.New("queue refused put")
+					continue
+				}
+				s.Len++
+				r <- nil
+			case r := <-q.take:
+				if s.Len == 0 {
+					r <- errors.New("queue empty")
+					continue
+				}
+				s.Len--
+				r <- nil
+			case r := <-q.close:
+				s.Closed = true
+				if dropOnClose {
+					s.Len = 0
+				}
+				r <- nil
+			case r := <-q.state:
+				r <- s
+			case <-q.stop:
+				return
+			}
+		}
+	}()
+	return q
+}
+
+type queueSystem struct{ q *queue }
+
+func (qs queueSystem) Do(action string) error {
+	ops := map[string]chan chan error{"put": qs.q.put, "take": qs.q.take, "close": qs.q.close}
+	r := make(chan error)
+	ops[action] <- r
+	return <-r
+}
+
+func (qs queueSystem) Close() error {
+	close(qs.q.stop)
+	return nil
+}
+
+func (qs queueSystem) Check(want buffer) error {
+	r := make(chan buffer)
+	qs.q.state <- r
+	if got := <-r; got != want {
+		return fmt.Errorf("queue state %+v", got)
+	}
+	return nil
+}
+
+func TestGenerateStateCoverage(t *testing.T) {
+	tests, err := Generate(bufferModel(), GenConfig{Criterion: StateCoverage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := Execute(t, tests, func() System[buffer] { return queueSystem{newQueue(false)} })
+	if len(rep.States) != 6 {
+		t.Errorf("covered %d states, want all 6: %v", len(rep.States), rep.States)
+	}
+	t.Log(rep)
+}
+
+func TestGenerateTransitionCoverage(t *testing.T) {
+	tests, err := Generate(bufferModel(), GenConfig{Criterion: TransitionCoverage, MaxLen: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range tests {
+		if _, err := bufferModel().Run(tc.Actions...); err != nil {
+			t.Errorf("generated test %v does not run on the model: %v", tc, err)
+		}
+	}
+	rep := Execute(t, tests, func() System[buffer] { return queueSystem{newQueue(false)} })
+	if len(rep.Transitions) != 9 {
+		t.Errorf("covered %d transitions, want all 9: %v", len(rep.Transitions), rep.Transitions)
+	}
+}
+
+func TestGenerateAllPaths(t *testing.T) {
+	m := bufferModel()
+	tests, err := Generate(m, GenConfig{Criterion: AllPaths, MaxLen: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Count the maximal sequences of up to 3 actions by brute force.
+	var want int
+	var count func(prefix []string)
+	count = func(prefix []string) {
+		tr, _ := m.Run(prefix...)
+		s := m.Init
+		if len(tr) > 0 {
+			s = tr[len(tr)-1].State
+		}
+		if len(prefix) == 3 || len(m.Enabled(s)) == 0 {
+			want++
+			return
+		}
+		for _, a := range m.Enabled(s) {
+			count(append(prefix, a.Name))
+		}
+	}
+	count(nil)
+	seen := make(map[string]bool)
+	for _, tc := range tests {
+		seen[tc.String()] = true
+	}
+	if len(tests) != want || len(seen) != want {
+		t.Errorf("generated %d tests (%d distinct), want %d", len(tests), len(seen), want)
+	}
+	if tests, _ := Generate(m, GenConfig{Criterion: AllPaths, MaxLen: 3, MaxTests: 5}); len(tests) != 5 {
+		t.Errorf("MaxTests 5 gave %d tests", len(tests))
+	}
+}
+
+func TestExecuteReportsFailures(t *testing.T) {
+	tests, err := Generate(bufferModel(), GenConfig{Criterion: TransitionCoverage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rep Report
+	errs := testtb.Run(t, func(t testing.TB) {
+		rep = Execute(t, tests, func() System[buffer] { return queueSystem{newQueue(true)} })
+	})
+	if len(errs) == 0 || !strings.Contains(errs[0], "close, model state {Len:1 Closed:true}: queue state {Len:0 Closed:true}") {
+		t.Fatalf("errors %q", errs)
+	}
+	if len(rep.Transitions) == 9 {
+		t.Errorf("failing tests still reported as covering every transition")
+	}
+}
@@ -0,0 +1,132 @@
+package model
+
+import "fmt"
+
+// DefaultMaxStates bounds the state graphs built by Generate and the
+// explorers when the config sets no limit.
+const DefaultMaxStates = 100_000
+
+// graph is the reachable state graph of a model.
+type graph[S any] struct {
+	m      *Model[S]
+	states []S
+	keys   []string
+	index  map[any]int // by visitKey
+	out    [][]edge    // by state index, in action order
+	depth  []int       // shortest distance from the initial state
+	parent []edge      // shortest-path tree; parent[0] is unused
+}
+
+type edge struct {
+	action string
+	from   int
+	to     int
+}
+
+// edgeKey names a transition by its states and action.
+func (g *graph[S]) edgeKey(e edge) string {
+	return transitionKey(g.keys[e.from], e.action, g.keys[e.to])
+}
+
+func transitionKey(from, action, to string) string {
+	return fmt.Sprintf("%s -%s-> %s", from, action, to)
+}
+
+// buildGraph explores m breadth first. It fails if more than maxStates
+// states are reachable, or, if checkInvariants, with an *Error if one
+// breaks an invariant.
+func buildGraph[S any](m *Model[S], maxStates int, checkInvariants bool) (*graph[S], error) {
+	if maxStates <= 0 {
+		maxStates = DefaultMaxStates
+	}
+	g := &graph[S]{m: m, index: make(map[any]int)}
+	g.add(m.Init, 0, edge{})
+	for i := 0; i < len(g.states); i++ {
+		s := g.states[i]
+		if v := m.Violated(s); checkInvariants && len(v) > 0 {
+			return nil, &Error[S]{Model: m.Name, Trace: g.trace(i), State: s, Violated: v}
+		}
+		for _, a := range m.Actions {
+			if !a.Enabled(s) {
+				continue
+			}
+			next := a.Step(s)
+			j, ok := g.index[m.visitKey(next)]
+			if !ok {
+				if len(g.states) == maxStates {
+					return nil, fmt.Errorf("model %s: more than %d reachable states", m.Name, maxStates)
+				}
+				j = g.add(next, g.depth[i]+1, edge{a.Name, i, len(g.states)})
+			}
+			g.out[i] = append(g.out[i], edge{a.Name, i, j})
+		}
+	}
+	return g, nil
+}
+
+func (g *graph[S]) add(s S, depth int, parent edge) int {
+	i := len(g.states)
+	g.states = append(g.states, s)
+	g.keys = append(g.keys, g.m.StateKey(s))
+	g.index[g.m.visitKey(s)] = i
+	g.out = append(g.out, nil)
+	g.depth = append(g.depth, depth)
+	g.parent = append(g.parent, parent)
+	return i
+}
+
+// path returns the edges of the shortest path from the initial state to i.
+func (g *graph[S]) path(i int) []edge {
+	p := make([]edge, g.depth[i])
+	for d := g.depth[i] - 1; d >= 0; d-- {
+		p[d] = g.parent[i]
+		i = g.parent[i].from
+	}
+	return p
+}
+
+// trace is the shortest trace to state i.
+func (g *graph[S]) trace(i int) Trace[S] {
+	var tr Trace[S]
+	for _, e := range g.path(i) {
+		tr = append(tr, Step[S]{e.action, g.states[e.to]})
+	}
+	return tr
+}
+
+// nearest returns the shortest path from state from to an edge for which
+// want reports true, ending with that edge, or nil if there is none. If in
+// is not nil, the path only goes through states it reports true for.
+func (g *graph[S]) nearest(from int, want func(edge) bool, in func(int) bool) []edge {
+	prev := map[int]edge{from: {}}
+	queue := []int{from}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, e := range g.out[i] {
+			if in != nil && !in(e.to) {
+				continue
+			}
+			if want(e) {
+				p := []edge{e}
+				for j := i; j != from; j = prev[j].from {
+					p = append([]edge{prev[j]}, p...)
+				}
+				return p
+			}
+			if _, seen := prev[e.to]; !seen {
+				prev[e.to] = e
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *graph[S]) edges() int {
+	n := 0
+	for _, es := range g.out {
+		n += len(es)
+	}
+	return n
+}
@@ -0,0 +1,287 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Fairness is a constraint on the scheduler of a model: which of its runs
+// count when checking liveness. Without constraints on the actions, any
+// run that keeps taking some action counts, however unlikely the schedule.
+type Fairness int
+
+const (
+	Unfair Fairness = iota
+	// Weak: an action enabled from some point on must eventually be taken.
+	Weak
+	// Strong: an action enabled again and again must eventually be taken.
+	Strong
+)
+
+func (f Fairness) String() string {
+	switch f {
+	case Unfair:
+		return "unfair"
+	case Weak:
+		return "weak"
+	case Strong:
+		return "strong"
+	}
+	return fmt.Sprintf("Fairness(%d)", int(f))
+}
+
+// Liveness is a property that something good eventually happens: every
+// run reaching a state where From holds goes on to a state where To holds,
+// which may be the same one. A nil From means the initial state only, so
+// the property is that To eventually holds. Runs only end in states in
+// which no action is enabled.
+type Liveness[S any] struct {
+	Name string
+	From func(s S) bool
+	To   func(s S) bool
+}
+
+// LivenessError is a run breaking a liveness property: a prefix from the
+// initial state followed by a cycle it can repeat forever, or an end
+// state, without To holding, and which the fairness constraints allow.
+type LivenessError[S any] struct {
+	Model    string
+	Property string
+	Prefix   Trace[S] // to the first state of the cycle
+	Cycle    Trace[S] // back to that state; empty if the run ends there
+	State    S        // where the cycle starts or the run ends
+}
+
+func (e *LivenessError[S]) Error() string {
+	where := "from the initial state"
+	if len(e.Prefix) > 0 {
+		where = "after " + e.Prefix.String()
+	}
+	if len(e.Cycle) == 0 {
+		return fmt.Sprintf("model %s: liveness %s violated: %s the run ends in state %+v", e.Model, e.Property, where, e.State)
+	}
+	return fmt.Sprintf("model %s: liveness %s violated: %s in state %+v the run can repeat %v forever", e.Model, e.Property, where, e.State, e.Cycle)
+}
+
+// CheckLiveness checks the liveness properties of m over its reachable
+// states, at most maxStates of them; zero means DefaultMaxStates. It fails
+// with a *LivenessError for the first property a fair run breaks.
+//
+// A run breaks a property if, once From held, it stays among states where
+// To does not hold: it ends in one of them, or cycles through a strongly
+// connected set of them forever. Such a cycle is fair if each weakly fair
+// action is taken in it or disabled somewhere in it, and each strongly
+// fair action is taken in it or disabled everywhere in it.
+func (m *Model[S]) CheckLiveness(maxStates int) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if len(m.Liveness) == 0 {
+		return nil
+	}
+	g, err := buildGraph(m, maxStates, false)
+	if err != nil {
+		return err
+	}
+	for _, l := range m.Liveness {
+		if err := g.checkLiveness(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *graph[S]) checkLiveness(l Liveness[S]) error {
+	bad := make([]bool, len(g.states))
+	for i, s := range g.states {
+		bad[i] = !l.To(s)
+	}
+	for start, s := range g.states {
+		if !bad[start] || (l.From == nil && start != 0) || (l.From != nil && !l.From(s)) {
+			continue
+		}
+		// The states a run can stay in from start without To holding.
+		reach := map[int]bool{start: true}
+		work := []int{start}
+		for len(work) > 0 {
+			i := work[len(work)-1]
+			work = work[:len(work)-1]
+			for _, e := range g.out[i] {
+				if bad[e.to] && !reach[e.to] {
+					reach[e.to] = true
+					work = append(work, e.to)
+				}
+			}
+		}
+		in := func(i int) bool { return reach[i] }
+		for i := range g.states {
+			if reach[i] && len(g.out[i]) == 0 {
+				return g.livenessError(l, start, in, i, nil)
+			}
+		}
+		for _, c := range g.sccs(in) {
+			if cyc := g.fairCycle(c); cyc != nil {
+				return g.livenessError(l, start, in, cyc[0], cyc)
+			}
+		}
+	}
+	return nil
+}
+
+// fairCycle returns a cycle through every state of a fair subset of the
+// strongly connected set c, taking an edge of every fair action on the
+// way, or nil if no subset of it is fair.
+func (g *graph[S]) fairCycle(c []int) []int {
+	in := make(map[int]bool, len(c))
+	for _, i := range c {
+		in[i] = true
+	}
+	inside := func(e edge) bool { return in[e.from] && in[e.to] }
+	for _, a := range g.m.Actions {
+		if a.Fairness == Unfair {
+			continue
+		}
+		taken := false
+		var enabled []int
+		for _, i := range c {
+			if a.Enabled(g.states[i]) {
+				enabled = append(enabled, i)
+			}
+			for _, e := range g.out[i] {
+				taken = taken || e.action == a.Name && inside(e)
+			}
+		}
+		if taken || len(enabled) == 0 || a.Fairness == Weak && len(enabled) < len(c) {
+			continue
+		}
+		if a.Fairness == Weak {
+			return nil
+		}
+		// Strongly fair and never taken: only runs that avoid the states
+		// enabling it are fair.
+		for _, i := range enabled {
+			delete(in, i)
+		}
+		for _, sub := range g.sccs(func(i int) bool { return in[i] }) {
+			if cyc := g.fairCycle(sub); cyc != nil {
+				return cyc
+			}
+		}
+		return nil
+	}
+	return c
+}
+
+// sccs returns the strongly connected sets of the states in, that have a
+// cycle, each sorted.
+func (g *graph[S]) sccs(in func(int) bool) [][]int {
+	index := make(map[int]int)
+	low := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var out [][]int
+	var visit func(i int)
+	visit = func(i int) {
+		index[i] = len(index)
+		low[i] = index[i]
+		stack = append(stack, i)
+		onStack[i] = true
+		for _, e := range g.out[i] {
+			if !in(e.to) {
+				continue
+			}
+			if _, ok := index[e.to]; !ok {
+				visit(e.to)
+				low[i] = min(low[i], low[e.to])
+			} else if onStack[e.to] {
+				low[i] = min(low[i], index[e.to])
+			}
+		}
+		if low[i] != index[i] {
+			return
+		}
+		var c []int
+		for {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[j] = false
+			c = append(c, j)
+			if j == i {
+				break
+			}
+		}
+		if len(c) > 1 || slices.ContainsFunc(g.out[i], func(e edge) bool { return e.to == i }) {
+			slices.Sort(c)
+			out = append(out, c)
+		}
+	}
+	for i := range g.states {
+		if _, ok := index[i]; !ok && in(i) {
+			visit(i)
+		}
+	}
+	return out
+}
+
+// livenessError builds the error for a run from the initial state to
+// start, then through the states in to end, then around cycle if any.
+func (g *graph[S]) livenessError(l Liveness[S], start int, in func(int) bool, end int, cycle []int) error {
+	e := &LivenessError[S]{Model: g.m.Name, Property: l.Name, State: g.states[end]}
+	steps := g.path(start)
+	steps = append(steps, g.within(start, end, in)...)
+	if cycle != nil {
+		inCycle := func(i int) bool { return slices.Contains(cycle, i) }
+		var tour []edge
+		at := end
+		visit := func(to int) {
+			tour = append(tour, g.within(at, to, inCycle)...)
+			at = to
+		}
+		for _, i := range cycle {
+			visit(i)
+		}
+		// Take an edge of every fair action the cycle can take.
+		for _, a := range g.m.Actions {
+			if a.Fairness == Unfair || slices.ContainsFunc(tour, func(e edge) bool { return e.action == a.Name }) {
+				continue
+			}
+		edges:
+			for _, i := range cycle {
+				for _, ed := range g.out[i] {
+					if ed.action == a.Name && inCycle(ed.to) {
+						visit(i)
+						tour = append(tour, ed)
+						at = ed.to
+						break edges
+					}
+				}
+			}
+		}
+		visit(end)
+		if len(tour) == 0 {
+			// A cycle through a single state takes its loop.
+			i := slices.IndexFunc(g.out[end], func(e edge) bool { return e.to == end })
+			tour = append(tour, g.out[end][i])
+		}
+		e.Cycle = g.steps(tour)
+	}
+	e.Prefix = g.steps(steps)
+	return e
+}
+
+// within returns a shortest path from state from to state to through the
+// states in, empty if they are the same.
+func (g *graph[S]) within(from, to int, in func(int) bool) []edge {
+	if from == to {
+		return nil
+	}
+	return g.nearest(from, func(e edge) bool { return e.to == to }, in)
+}
+
+func (g *graph[S]) steps(es []edge) Trace[S] {
+	var tr Trace[S]
+	for _, e := range es {
+		tr = append(tr, Step[S]{e.action, g.states[e.to]})
+	}
+	return tr
+}
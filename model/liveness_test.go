@@ -0,0 +1,85 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestLivenessFairness(t *testing.T) {
+	closes := Liveness[buffer]{Name: "closes", To: func(s buffer) bool { return s.Closed }}
+	m := bufferModel()
+	m.Liveness = []Liveness[buffer]{closes}
+	err := m.CheckLiveness(0)
+	var le *LivenessError[buffer]
+	if !errors.As(err, &le) {
+		t.Fatalf("unfair: %v", err)
+	}
+	if want := "model buffer: liveness closes violated: from the initial state in state {Len:0 Closed:false} the run can repeat put → put → take → take forever"; err.Error() != want {
+		t.Errorf("unfair:\n%v\nwant\n%s", err, want)
+	}
+
+	// Under weak fairness close, enabled until taken, must be taken.
+	m.Actions[2].Fairness = Weak
+	if err := m.CheckLiveness(0); err != nil {
+		t.Errorf("weak: %v", err)
+	}
+}
+
+func TestLivenessLeadsTo(t *testing.T) {
+	m := bufferModel()
+	m.Liveness = []Liveness[buffer]{{
+		Name: "drains",
+		From: func(s buffer) bool { return s.Closed },
+		To:   func(s buffer) bool { return s.Len == 0 },
+	}}
+	if err := m.CheckLiveness(0); err != nil {
+		t.Error(err)
+	}
+	// Once the buffer is full, nothing happens any more.
+	m.Actions = m.Actions[:1]
+	m.Liveness[0] = Liveness[buffer]{Name: "empties", From: func(s buffer) bool { return s.Len > 0 }, To: func(s buffer) bool { return s.Len == 0 }}
+	want := "model buffer: liveness empties violated: after put → put the run ends in state {Len:2 Closed:false}"
+	if err := m.CheckLiveness(0); err == nil || err.Error() != want {
+		t.Errorf("%v\nwant\n%s", err, want)
+	}
+}
+
+// toggler keeps flipping Busy until it finishes, which it can only do
+// while not busy.
+type toggler struct{ Busy, Done bool }
+
+func togglerModel(finish Fairness) *Model[toggler] {
+	return &Model[toggler]{
+		Name: "toggler",
+		Actions: []Action[toggler]{
+			{Name: "toggle", Guard: func(s toggler) bool { return !s.Done }, Step: func(s toggler) toggler { s.Busy = !s.Busy; return s }, Fairness: Weak},
+			{Name: "finish", Guard: func(s toggler) bool { return !s.Busy && !s.Done }, Step: func(s toggler) toggler { s.Done = true; return s }, Fairness: finish},
+		},
+		Liveness: []Liveness[toggler]{{Name: "finishes", To: func(s toggler) bool { return s.Done }}},
+	}
+}
+
+func TestLivenessStrongFairness(t *testing.T) {
+	// finish is disabled every other step, so weak fairness does not
+	// force it.
+	want := "model toggler: liveness finishes violated: from the initial state in state {Busy:false Done:false} the run can repeat toggle → toggle forever"
+	if err := togglerModel(Weak).CheckLiveness(0); err == nil || err.Error() != want {
+		t.Errorf("weak: %v\nwant\n%s", err, want)
+	}
+	if err := togglerModel(Strong).CheckLiveness(0); err != nil {
+		t.Errorf("strong: %v", err)
+	}
+}
+
+func TestExploreLiveness(t *testing.T) {
+	m := bufferModel()
+	m.Liveness = []Liveness[buffer]{{Name: "closes", To: func(s buffer) bool { return s.Closed }}}
+	errs := testtb.Run(t, func(t testing.TB) {
+		Explore(t, m, ExploreConfig{}, func() System[buffer] { return queueSystem{newQueue(false)} })
+	})
+	if len(errs) != 1 || errs[0] != "model buffer: liveness closes violated: from the initial state in state {Len:0 Closed:false} the run can repeat put → put → take → take forever" {
+		t.Errorf("errors %q", errs)
+	}
+}
@@ -0,0 +1,221 @@
+// Package model declares finite state machines in Go, for testing
+// concurrent implementations against them: a model names its initial
+// state, the actions that move it from state to state, each enabled by a
+// guard, and the invariants every reachable state must satisfy.
+//
+// States are plain values. Step receives a copy of the current state and
+// returns the next one; a state holding maps or slices must copy them
+// before changing them, so that the states already visited stay intact.
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Action is a guarded transition.
+type Action[S any] struct {
+	Name string
+	// Guard reports whether the action is enabled in s; nil means always.
+	Guard func(s S) bool
+	// Step returns the state after the action.
+	Step func(s S) S
+	// Post, if set, is the postcondition an implementation must meet: it
+	// checks the states observed before and after the action, see Oracle.
+	Post func(before, after S) error
+	// Fairness is what the scheduler guarantees about taking the action,
+	// for checking liveness.
+	Fairness Fairness
+	// Weight is how likely the action is taken relative to the others
+	// enabled, for probabilistic models; zero counts as 1.
+	Weight float64
+}
+
+func (a Action[S]) weight() float64 {
+	if a.Weight > 0 {
+		return a.Weight
+	}
+	return 1
+}
+
+// Enabled reports whether a can be taken in s.
+func (a Action[S]) Enabled(s S) bool { return a.Guard == nil || a.Guard(s) }
+
+// Invariant is a property every reachable state must have.
+type Invariant[S any] struct {
+	Name  string
+	Check func(s S) bool
+}
+
+// Model is a finite state machine over states of type S.
+type Model[S any] struct {
+	Name       string
+	Init       S
+	Actions    []Action[S]
+	Invariants []Invariant[S]
+	Liveness   []Liveness[S]
+	// Key identifies a state, so that explorers can tell states apart and
+	// recognize those already visited. The default formats the state with
+	// %+v, which suits structs of plain values.
+	Key func(s S) string
+	// Abstract, if set, maps a state to a canonical comparable form, such
+	// as the sorted states of interchangeable replicas or a struct without
+	// the fields that do not matter. Explorers prune a state whose form
+	// they have already seen instead of its key, so reachable sets too
+	// large to explore can shrink to their abstractions. Compared directly
+	// as map keys, forms are also cheaper than formatted keys.
+	Abstract func(s S) any
+}
+
+// StateKey returns the key of s.
+func (m *Model[S]) StateKey(s S) string {
+	if m.Key != nil {
+		return m.Key(s)
+	}
+	return fmt.Sprintf("%+v", s)
+}
+
+// visitKey identifies s among the states an explorer has visited.
+func (m *Model[S]) visitKey(s S) any {
+	if m.Abstract != nil {
+		return m.Abstract(s)
+	}
+	return m.StateKey(s)
+}
+
+// Validate reports a model that cannot be run: one without actions, or
+// with unnamed, duplicate or stepless actions or invariants without a
+// check.
+func (m *Model[S]) Validate() error {
+	var errs []error
+	if len(m.Actions) == 0 {
+		errs = append(errs, errors.New("no actions"))
+	}
+	seen := make(map[string]bool)
+	for i, a := range m.Actions {
+		switch {
+		case a.Name == "":
+			errs = append(errs, fmt.Errorf("action %d has no name", i))
+		case seen[a.Name]:
+			errs = append(errs, fmt.Errorf("action %q declared twice", a.Name))
+		}
+		seen[a.Name] = true
+		if a.Step == nil {
+			errs = append(errs, fmt.Errorf("action %q has no Step", a.Name))
+		}
+	}
+	for i, inv := range m.Invariants {
+		if inv.Check == nil {
+			errs = append(errs, fmt.Errorf("invariant %d (%s) has no Check", i, inv.Name))
+		}
+	}
+	for i, l := range m.Liveness {
+		if l.To == nil {
+			errs = append(errs, fmt.Errorf("liveness property %d (%s) has no To", i, l.Name))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("model %s: %w", m.Name, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Action returns the action with the given name.
+func (m *Model[S]) Action(name string) (Action[S], bool) {
+	for _, a := range m.Actions {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Action[S]{}, false
+}
+
+// Enabled returns the actions enabled in s, in declaration order.
+func (m *Model[S]) Enabled(s S) []Action[S] {
+	var as []Action[S]
+	for _, a := range m.Actions {
+		if a.Enabled(s) {
+			as = append(as, a)
+		}
+	}
+	return as
+}
+
+// Violated returns the names of the invariants s breaks.
+func (m *Model[S]) Violated(s S) []string {
+	var names []string
+	for _, inv := range m.Invariants {
+		if !inv.Check(s) {
+			names = append(names, inv.Name)
+		}
+	}
+	return names
+}
+
+// Step is one transition of a run.
+type Step[S any] struct {
+	Action string
+	State  S // the state after the action
+}
+
+// Trace is a run of a model from its initial state.
+type Trace[S any] []Step[S]
+
+// Actions returns the names of the actions taken.
+func (tr Trace[S]) Actions() []string {
+	names := make([]string, len(tr))
+	for i, st := range tr {
+		names[i] = st.Action
+	}
+	return names
+}
+
+func (tr Trace[S]) String() string { return strings.Join(tr.Actions(), " → ") }
+
+// Error is a run that went wrong: an action that was not enabled, or a
+// state breaking invariants.
+type Error[S any] struct {
+	Model    string
+	Trace    Trace[S] // up to and including the offending step
+	State    S        // the state in which it happened
+	Disabled string   // the action that was not enabled, if that was it
+	Violated []string // the invariants broken, otherwise
+}
+
+func (e *Error[S]) Error() string {
+	where := "initial state"
+	if len(e.Trace) > 0 {
+		where = "after " + e.Trace.String()
+	}
+	if e.Disabled != "" {
+		return fmt.Sprintf("model %s: action %s not enabled %s, state %+v", e.Model, e.Disabled, where, e.State)
+	}
+	return fmt.Sprintf("model %s: invariant %s violated %s, state %+v", e.Model, strings.Join(e.Violated, ", "), where, e.State)
+}
+
+// Run takes the named actions in order from the initial state and returns
+// the trace. It fails with an *Error if an action is not enabled when its
+// turn comes or a state, including the initial one, breaks an invariant.
+func (m *Model[S]) Run(actions ...string) (Trace[S], error) {
+	s := m.Init
+	if v := m.Violated(s); len(v) > 0 {
+		return nil, &Error[S]{Model: m.Name, State: s, Violated: v}
+	}
+	var tr Trace[S]
+	for _, name := range actions {
+		a, ok := m.Action(name)
+		if !ok {
+			return tr, fmt.Errorf("model %s: no action %q", m.Name, name)
+		}
+		if !a.Enabled(s) {
+			return tr, &Error[S]{Model: m.Name, Trace: tr, State: s, Disabled: name}
+		}
+		s = a.Step(s)
+		tr = append(tr, Step[S]{name, s})
+		if v := m.Violated(s); len(v) > 0 {
+			return tr, &Error[S]{Model: m.Name, Trace: tr, State: s, Violated: v}
+		}
+	}
+	return tr, nil
+}
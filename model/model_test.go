@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// buffer is the state of a bounded buffer of capacity 2.
+type buffer struct {
+	Len    int
+	Closed bool
+}
+
+func bufferModel() *Model[buffer] {
+	return &Model[buffer]{
+		Name: "buffer",
+		Actions: []Action[buffer]{
+			{Name: "put", Guard: func(s buffer) bool { return !s.Closed && s.Len < 2 }, Step: func(s buffer) buffer { s.Len++; return s }},
+			{Name: "take", Guard: func(s buffer) bool { return s.Len > 0 }, Step: func(s buffer) buffer { s.Len--; return s }},
+			{Name: "close", Guard: func(s buffer) bool { return !s.Closed }, Step: func(s buffer) buffer { s.Closed = true; return s }},
+		},
+		Invariants: []Invariant[buffer]{
+			{Name: "bounded", Check: func(s buffer) bool { return s.Len >= 0 && s.Len <= 2 }},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	m := bufferModel()
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := m.Run("put", "put", "take", "close", "take")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.String(); got != "put → put → take → close → take" {
+		t.Errorf("trace %s", got)
+	}
+	if last := tr[len(tr)-1].State; last != (buffer{0, true}) {
+		t.Errorf("final state %+v", last)
+	}
+	var names []string
+	for _, a := range m.Enabled(buffer{1, false}) {
+		names = append(names, a.Name)
+	}
+	if !slices.Equal(names, []string{"put", "take", "close"}) {
+		t.Errorf("enabled %v", names)
+	}
+}
+
+func TestRunDisabled(t *testing.T) {
+	_, err := bufferModel().Run("put", "put", "put")
+	var e *Error[buffer]
+	if !errors.As(err, &e) || e.Disabled != "put" || len(e.Trace) != 2 {
+		t.Fatalf("error %v", err)
+	}
+	if want := "model buffer: action put not enabled after put → put, state {Len:2 Closed:false}"; err.Error() != want {
+		t.Errorf("error %q, want %q", err, want)
+	}
+	if _, err := bufferModel().Run("get"); err == nil || !strings.Contains(err.Error(), `no action "get"`) {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestRunInvariant(t *testing.T) {
+	m := bufferModel()
+	m.Actions[0].Guard = nil // put ignores the capacity
+	_, err := m.Run("put", "put", "put")
+	var e *Error[buffer]
+	if !errors.As(err, &e) || !slices.Equal(e.Violated, []string{"bounded"}) || len(e.Trace) != 3 {
+		t.Fatalf("error %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := bufferModel()
+	m.Actions = append(m.Actions, Action[buffer]{Name: "put"}, Action[buffer]{})
+	m.Invariants = append(m.Invariants, Invariant[buffer]{Name: "empty"})
+	err := m.Validate()
+	for _, want := range []string{`action "put" declared twice`, `action "put" has no Step`, "action 4 has no name", "invariant 1 (empty) has no Check"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"io"
+)
+
+// Observer is an implementation that can tell its state in terms of the
+// model: Do performs an action and Observe returns the current state.
+// Like a System, an Observer that starts goroutines should implement
+// io.Closer.
+type Observer[S any] interface {
+	Do(action string) error
+	Observe() S
+}
+
+// Oracle returns a System whose Check is derived from m, so that tests
+// describe the expected behaviour once, in the model. After an action with
+// a Post condition the states observed before and after it must meet it;
+// after any other action the observed state must be the one the model
+// expects. Every observed state must also satisfy m's invariants.
+func Oracle[S any](m *Model[S], obs Observer[S]) System[S] {
+	return &oracle[S]{m: m, obs: obs}
+}
+
+type oracle[S any] struct {
+	m      *Model[S]
+	obs    Observer[S]
+	action string
+	before S
+}
+
+func (o *oracle[S]) Do(action string) error {
+	o.action, o.before = action, o.obs.Observe()
+	return o.obs.Do(action)
+}
+
+func (o *oracle[S]) Check(want S) error {
+	got := o.obs.Observe()
+	if a, ok := o.m.Action(o.action); ok && a.Post != nil {
+		if err := a.Post(o.before, got); err != nil {
+			return fmt.Errorf("postcondition of %s from observed state %+v to %+v: %w", o.action, o.before, got, err)
+		}
+	} else if o.m.StateKey(got) != o.m.StateKey(want) {
+		return fmt.Errorf("observed state %+v", got)
+	}
+	if v := o.m.Violated(got); len(v) > 0 {
+		return fmt.Errorf("observed state %+v violates %v", got, v)
+	}
+	return nil
+}
+
+func (o *oracle[S]) Close() error {
+	if c, ok := o.obs.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// queueObserver drives a queue and observes its state.
+type queueObserver struct{ queueSystem }
+
+func (qo queueObserver) Observe() buffer {
+	r := make(chan buffer)
+	qo.q.state <- r
+	return <-r
+}
+
+func TestOracle(t *testing.T) {
+	m := bufferModel()
+	m.Actions[2].Post = func(before, after buffer) error {
+		if !after.Closed || after.Len != before.Len {
+			return errors.New("close must keep the buffered values")
+		}
+		return nil
+	}
+	tests, err := Generate(m, GenConfig{Criterion: TransitionCoverage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Execute(t, tests, func() System[buffer] { return Oracle(m, queueObserver{queueSystem{newQueue(false)}}) })
+
+	errs := testtb.Run(t, func(t testing.TB) {
+		Execute(t, tests, func() System[buffer] { return Oracle(m, queueObserver{queueSystem{newQueue(true)}}) })
+	})
+	if len(errs) == 0 {
+		t.Fatal("dropping values on close passed")
+	}
+	for _, e := range errs {
+		if !strings.Contains(e, ": postcondition of close from observed state {Len:1 Closed:false} to {Len:0 Closed:true}: close must keep the buffered values") &&
+			!strings.Contains(e, ": postcondition of close from observed state {Len:2 Closed:false} to {Len:0 Closed:true}: close must keep the buffered values") {
+			t.Errorf("error %q", e)
+		}
+	}
+}
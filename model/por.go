@@ -0,0 +1,39 @@
+package model
+
+import "slices"
+
+// ample returns the actions to expand in s out of its enabled ones, for a
+// partial-order reduction: the enabled actions that depend, directly or
+// through other actions, on the first enabled action. Every other action
+// of the model commutes with all of them, so the interleavings taking the
+// others first reach no end state taking these first does not. The
+// reduction is dropped, and all of enabled returned, when it would lead
+// only to states already visited, which could otherwise postpone the
+// other actions forever around a cycle.
+func ample[S any](m *Model[S], independent func(a, b string) bool, s S, enabled []Action[S], visited func(S) bool) []Action[S] {
+	for _, first := range enabled {
+		group := []string{first.Name}
+		for i := 0; i < len(group); i++ {
+			for _, b := range m.Actions {
+				if !slices.Contains(group, b.Name) && !independent(group[i], b.Name) {
+					group = append(group, b.Name)
+				}
+			}
+		}
+		if len(group) == len(m.Actions) {
+			continue
+		}
+		var amp []Action[S]
+		fresh := true
+		for _, a := range enabled {
+			if slices.Contains(group, a.Name) {
+				amp = append(amp, a)
+				fresh = fresh && !visited(a.Step(s))
+			}
+		}
+		if fresh && len(amp) < len(enabled) {
+			return amp
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Promela is the Backend writing Promela models for SPIN. States are
+// numbered, each action is a guarded assignment to the variable state
+// inside a loop, and each invariant is a macro checked by an LTL formula
+// that it always holds. The loop is an end state, so states without
+// enabled actions are not reported as invalid end states.
+type Promela struct{}
+
+func (Promela) Write(w io.Writer, sp *StateSpace) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "/* Generated from the Go model %q; do not edit. */\n\n", sp.Name)
+	for i, k := range sp.States {
+		fmt.Fprintf(b, "/* state %d: %s */\n", i, comment(k, "*/"))
+	}
+	fmt.Fprintf(b, "\nint state = 0;\n\n")
+	for _, inv := range sp.Invariants {
+		fmt.Fprintf(b, "#define %s (%s)\n", ident(inv.Name), promelaIn(inv.Holds))
+	}
+	if len(sp.Invariants) > 0 {
+		fmt.Fprintln(b)
+	}
+	fmt.Fprintf(b, "active proctype %s() {\nend:\n\tdo\n", ident(sp.Name))
+	for _, a := range sp.Actions {
+		fmt.Fprintf(b, "\t/* %s */\n", comment(a.Name, "*/"))
+		for _, tr := range a.Transitions {
+			fmt.Fprintf(b, "\t:: atomic { state == %d -> state = %d }\n", tr[0], tr[1])
+		}
+	}
+	fmt.Fprintf(b, "\tod\n}\n")
+	for _, inv := range sp.Invariants {
+		id := ident(inv.Name)
+		fmt.Fprintf(b, "\nltl always_%s { [] %s }\n", id, id)
+	}
+	return b.Flush()
+}
+
+func promelaIn(idx []int) string {
+	if len(idx) == 0 {
+		return "false"
+	}
+	eq := make([]string, len(idx))
+	for i, s := range idx {
+		eq[i] = fmt.Sprintf("state == %d", s)
+	}
+	return strings.Join(eq, " || ")
+}
+
+// comment makes s safe inside a comment ending with end.
+func comment(s, end string) string {
+	s = strings.ReplaceAll(s, end, strings.Join(strings.Split(end, ""), " "))
+	return strings.ReplaceAll(s, "\n", " ")
+}
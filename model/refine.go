@@ -0,0 +1,120 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Mapping is an abstraction function from the states C of an
+// implementation, or of a more detailed model, to the states of an
+// abstract model. The implementation refines the abstract model if every
+// step it takes maps to a step of the abstract model, or to no change of
+// the abstract state at all.
+type Mapping[C, S any] struct {
+	Abstract *Model[S]
+	Map      func(c C) S
+}
+
+// RefinementError is a concrete step that maps to no abstract one.
+type RefinementError[C, S any] struct {
+	Model string   // of the abstract model
+	Trace []string // concrete steps up to and including the offending one
+	From  C        // concrete states before and after the step
+	To    C
+	AFrom S // their abstract states
+	ATo   S
+	Init  bool // the initial concrete state maps to another abstract state than the initial one
+}
+
+func (e *RefinementError[C, S]) Error() string {
+	if e.Init {
+		return fmt.Sprintf("model %s: initial concrete state %+v maps to %+v, not the initial state %+v", e.Model, e.To, e.ATo, e.AFrom)
+	}
+	return fmt.Sprintf("model %s: after %s: concrete state %+v to %+v maps %+v to %+v, which no enabled action takes",
+		e.Model, strings.Join(e.Trace, " → "), e.From, e.To, e.AFrom, e.ATo)
+}
+
+// refines reports whether the abstractions of from and to are the same or
+// an enabled abstract action takes the one to the other.
+func (mp Mapping[C, S]) refines(from, to C) bool {
+	m := mp.Abstract
+	af := mp.Map(from)
+	k := m.StateKey(mp.Map(to))
+	if m.StateKey(af) == k {
+		return true
+	}
+	for _, a := range m.Enabled(af) {
+		if m.StateKey(a.Step(af)) == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (mp Mapping[C, S]) initial(c C) error {
+	m := mp.Abstract
+	if a := mp.Map(c); m.StateKey(a) != m.StateKey(m.Init) {
+		return &RefinementError[C, S]{Model: m.Name, Init: true, To: c, ATo: a, AFrom: m.Init}
+	}
+	return nil
+}
+
+// Refines checks that every transition of concrete between its reachable
+// states, at most maxStates of them, maps to a transition of the abstract
+// model or leaves the abstract state unchanged, and that the initial
+// states correspond. It fails with a *RefinementError for the first
+// transition that does not.
+func (mp Mapping[C, S]) Refines(concrete *Model[C], maxStates int) error {
+	if err := mp.initial(concrete.Init); err != nil {
+		return err
+	}
+	g, err := buildGraph(concrete, maxStates, false)
+	if err != nil {
+		return err
+	}
+	for i, es := range g.out {
+		for _, e := range es {
+			if !mp.refines(g.states[i], g.states[e.to]) {
+				return mp.error(append(g.trace(i).Actions(), e.action), g.states[i], g.states[e.to])
+			}
+		}
+	}
+	return nil
+}
+
+func (mp Mapping[C, S]) error(tr []string, from, to C) error {
+	return &RefinementError[C, S]{Model: mp.Abstract.Name, Trace: tr, From: from, To: to, AFrom: mp.Map(from), ATo: mp.Map(to)}
+}
+
+// Tracker checks the states of a running implementation against a
+// Mapping, one step at a time. It is safe for concurrent use.
+type Tracker[C, S any] struct {
+	mp    Mapping[C, S]
+	mu    sync.Mutex
+	last  C
+	seen  bool
+	trace []string
+}
+
+// Track returns a Tracker for an implementation starting now.
+func (mp Mapping[C, S]) Track() *Tracker[C, S] { return &Tracker[C, S]{mp: mp} }
+
+// Observe checks the implementation's state c after the step named step,
+// or its initial state on the first call. Call it after every step of a
+// schedule, with the bubble settled, so that no intermediate state the
+// end state would hide goes unchecked. It fails with a *RefinementError.
+func (tk *Tracker[C, S]) Observe(step string, c C) error {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	if !tk.seen {
+		tk.seen, tk.last = true, c
+		return tk.mp.initial(c)
+	}
+	tk.trace = append(tk.trace, step)
+	if !tk.mp.refines(tk.last, c) {
+		return tk.mp.error(append([]string(nil), tk.trace...), tk.last, c)
+	}
+	tk.last = c
+	return nil
+}
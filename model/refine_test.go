@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"testing/synctest"
+)
+
+// slotted is a buffer whose puts reserve a slot before filling it.
+type slotted struct {
+	Len, Reserved int
+	Closed        bool
+}
+
+func slottedModel(commitAfterClose bool) *Model[slotted] {
+	return &Model[slotted]{
+		Name: "slotted",
+		Actions: []Action[slotted]{
+			{Name: "reserve", Guard: func(s slotted) bool { return !s.Closed && s.Len+s.Reserved < 2 }, Step: func(s slotted) slotted { s.Reserved++; return s }},
+			{Name: "commit", Guard: func(s slotted) bool { return s.Reserved > 0 }, Step: func(s slotted) slotted {
+				s.Reserved--
+				if !s.Closed || commitAfterClose {
+					s.Len++
+				}
+				return s
+			}},
+			{Name: "take", Guard: func(s slotted) bool { return s.Len > 0 }, Step: func(s slotted) slotted { s.Len--; return s }},
+			{Name: "close", Guard: func(s slotted) bool { return !s.Closed }, Step: func(s slotted) slotted { s.Closed = true; return s }},
+		},
+	}
+}
+
+var slottedMapping = Mapping[slotted, buffer]{
+	Abstract: bufferModel(),
+	Map:      func(s slotted) buffer { return buffer{s.Len, s.Closed} },
+}
+
+func TestRefines(t *testing.T) {
+	if err := slottedMapping.Refines(slottedModel(false), 0); err != nil {
+		t.Error(err)
+	}
+	err := slottedMapping.Refines(slottedModel(true), 0)
+	var re *RefinementError[slotted, buffer]
+	if !errors.As(err, &re) {
+		t.Fatalf("got %v", err)
+	}
+	want := "model buffer: after reserve → close → commit: concrete state {Len:0 Reserved:1 Closed:true} to {Len:1 Reserved:0 Closed:true} maps {Len:0 Closed:true} to {Len:1 Closed:true}, which no enabled action takes"
+	if err.Error() != want {
+		t.Errorf("got\n%v\nwant\n%s", err, want)
+	}
+}
+
+func TestRefinesInit(t *testing.T) {
+	m := slottedModel(false)
+	m.Init.Len = 1
+	want := "model buffer: initial concrete state {Len:1 Reserved:0 Closed:false} maps to {Len:1 Closed:false}, not the initial state {Len:0 Closed:false}"
+	if err := slottedMapping.Refines(m, 0); err == nil || err.Error() != want {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	mp := Mapping[buffer, buffer]{Abstract: bufferModel(), Map: func(s buffer) buffer { return s }}
+	synctest.Run(func() {
+		qs := queueSystem{newQueue(true)}
+		defer qs.Close()
+		state := func() buffer {
+			r := make(chan buffer)
+			qs.q.state <- r
+			return <-r
+		}
+		tk := mp.Track()
+		if err := tk.Observe("", state()); err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range []string{"put", "put", "take", "close"} {
+			if err := qs.Do(a); err != nil {
+				t.Fatal(err)
+			}
+			synctest.Wait()
+			err := tk.Observe(a, state())
+			if a != "close" && err != nil {
+				t.Fatal(err)
+			}
+			want := "model buffer: after put → put → take → close: concrete state {Len:1 Closed:false} to {Len:0 Closed:true} maps {Len:1 Closed:false} to {Len:0 Closed:true}, which no enabled action takes"
+			if a == "close" && (err == nil || err.Error() != want) {
+				t.Errorf("got %v", err)
+			}
+		}
+	})
+}
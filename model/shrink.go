@@ -0,0 +1,133 @@
+package model
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+)
+
+// Shrink minimizes a program p that failed with err, re-running every
+// candidate with RunProgram in a fresh bubble and keeping it if it still
+// fails, other than by a violated precondition. It tries, until none
+// helps: dropping branches, or turning the last two into a sequential
+// suffix; dropping chunks of commands and then single ones, from the
+// branches and from the prefix; and replacing arguments by the simpler
+// ones their command's Shrink offers. A candidate with branches that
+// passes with the schedule of p, which no longer lines up with its
+// commands, is also run with shrinkSchedules schedules drawn afresh. It
+// returns the smallest program found, the error it fails with and the
+// number of runs, which is bounded by maxShrinkRuns.
+func Shrink[M, Sys any](mach Machine[M, Sys], p Program, err error) (Program, error, int) {
+	tries := 0
+	r := rand.New(rand.NewPCG(0, 0))
+	fails := func(q *Program) (error, bool) {
+		if len(q.Parallel) > 0 && !mach.alwaysEnabled(mach.stateAfter(q.Prefix), q.Parallel) {
+			return nil, false
+		}
+		for i := 0; i <= shrinkSchedules; i++ {
+			if i > 0 {
+				if len(q.Parallel) == 0 {
+					break
+				}
+				q.Schedule = genSchedule(r, q.Parallel)
+			}
+			tries++
+			if e := RunProgram(mach, *q); e != nil && !errors.Is(e, ErrPrecondition) {
+				return e, true
+			}
+		}
+		return nil, false
+	}
+	for improved := true; improved && tries < maxShrinkRuns; {
+		improved = false
+		for _, q := range candidates(mach, p) {
+			if e, ok := fails(&q); ok {
+				p, err, improved = q, e, true
+				break
+			}
+		}
+	}
+	return p, err, tries
+}
+
+const (
+	maxShrinkRuns   = 2000
+	shrinkSchedules = 8
+)
+
+// stateAfter runs calls on the model alone, ignoring preconditions.
+func (mach Machine[M, Sys]) stateAfter(calls []Call) M {
+	m := mach.Init()
+	for _, c := range calls {
+		if cmd := mach.command(c.Command); cmd != nil {
+			m = cmd.Next(m, c.Arg)
+		}
+	}
+	return m
+}
+
+// candidates returns the programs one shrinking step away from p, the
+// ones removing most first.
+func candidates[M, Sys any](mach Machine[M, Sys], p Program) []Program {
+	var qs []Program
+	clone := func() Program {
+		q := Program{Prefix: slices.Clone(p.Prefix), Schedule: p.Schedule}
+		for _, br := range p.Parallel {
+			q.Parallel = append(q.Parallel, slices.Clone(br))
+		}
+		return q
+	}
+
+	// Fewer branches: drop one, or run the last two one after the other.
+	if n := len(p.Parallel); n > 0 {
+		for b := range n {
+			q := clone()
+			q.Parallel = slices.Delete(q.Parallel, b, b+1)
+			if len(q.Parallel) == 1 {
+				q.Prefix, q.Parallel = append(q.Prefix, q.Parallel[0]...), nil
+			}
+			qs = append(qs, q)
+		}
+		if n == 2 {
+			q := clone()
+			q.Prefix, q.Parallel = append(append(q.Prefix, q.Parallel[0]...), q.Parallel[1]...), nil
+			qs = append(qs, q)
+		}
+	}
+
+	// Fewer commands, in chunks halving in size.
+	seqs := []func(q *Program) *[]Call{func(q *Program) *[]Call { return &q.Prefix }}
+	for b := range p.Parallel {
+		seqs = append(seqs, func(q *Program) *[]Call { return &q.Parallel[b] })
+	}
+	for _, seq := range seqs {
+		n := len(*seq(&p))
+		for size := n; size >= 1; size /= 2 {
+			for at := 0; at+size <= n; at += size {
+				q := clone()
+				s := seq(&q)
+				*s = slices.Delete(*s, at, at+size)
+				if len(q.Parallel) > 0 && slices.ContainsFunc(q.Parallel, func(br []Call) bool { return len(br) == 0 }) {
+					continue
+				}
+				qs = append(qs, q)
+			}
+		}
+	}
+
+	// Simpler arguments.
+	for _, seq := range seqs {
+		for i, c := range *seq(&p) {
+			cmd := mach.command(c.Command)
+			if cmd == nil || cmd.Shrink == nil || c.Arg == nil {
+				continue
+			}
+			for _, arg := range cmd.Shrink(c.Arg) {
+				q := clone()
+				(*seq(&q))[i].Arg = arg
+				qs = append(qs, q)
+			}
+		}
+	}
+	return qs
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+)
+
+// Distribution returns the probabilities of the states m is in after n
+// steps from its initial state, by state key, when each step takes one of
+// the enabled actions with probability proportional to its Weight. A state
+// without enabled actions keeps its probability.
+func (m *Model[S]) Distribution(n int) map[string]float64 {
+	dist := map[string]float64{m.StateKey(m.Init): 1}
+	states := map[string]S{m.StateKey(m.Init): m.Init}
+	for range n {
+		next := make(map[string]float64)
+		for k, p := range dist {
+			s := states[k]
+			as := m.Enabled(s)
+			if len(as) == 0 {
+				next[k] += p
+				continue
+			}
+			total := 0.0
+			for _, a := range as {
+				total += a.weight()
+			}
+			for _, a := range as {
+				t := a.Step(s)
+				tk := m.StateKey(t)
+				states[tk] = t
+				next[tk] += p * a.weight() / total
+			}
+		}
+		dist = next
+	}
+	return dist
+}
+
+// TrialsConfig sizes a statistical test.
+type TrialsConfig struct {
+	Seed   uint64  // trial i is seeded with Seed and i
+	Trials int     // default 1000
+	Alpha  float64 // significance level, default 0.001
+}
+
+// CheckDistribution runs cfg.Trials seeded trials, each in a fresh bubble,
+// and fails t if the states they end in are unlikely to follow the
+// distribution of m after steps steps: if a chi-squared test rejects it
+// at significance cfg.Alpha, or a trial ends in a state m cannot reach.
+// A trial takes the steps on the implementation, drawing its randomness
+// from r, and returns the model state the implementation ended in. The
+// trials are deterministic in cfg.Seed, so a failure does not go away by
+// itself; with Alpha 0.001 one seed in a thousand fails a correct
+// implementation.
+func CheckDistribution[S any](t testing.TB, m *Model[S], steps int, cfg TrialsConfig, trial func(r *rand.Rand) S) {
+	t.Helper()
+	n, alpha := cfg.Trials, cfg.Alpha
+	if n <= 0 {
+		n = 1000
+	}
+	if alpha <= 0 {
+		alpha = 0.001
+	}
+	observed := make(map[string]int)
+	for i := range uint64(n) {
+		r := rand.New(rand.NewPCG(cfg.Seed, i))
+		var s S
+		synctest.Run(func() { s = trial(r) })
+		observed[m.StateKey(s)]++
+	}
+	CheckFrequencies(t, observed, m.Distribution(steps), alpha)
+}
+
+// CheckFrequencies fails t if Pearson's chi-squared test rejects, at
+// significance alpha, that the observed counts follow the expected
+// probabilities, or if an outcome was observed that has no probability.
+// Every outcome should be expected at least 5 times for the test to be
+// accurate.
+func CheckFrequencies(t testing.TB, observed map[string]int, expected map[string]float64, alpha float64) {
+	t.Helper()
+	n := 0
+	for _, c := range observed {
+		n += c
+	}
+	keys := make([]string, 0, len(expected))
+	for k, p := range expected {
+		if p > 0 {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	var table strings.Builder
+	stat := 0.0
+	for _, k := range keys {
+		want := expected[k] * float64(n)
+		d := float64(observed[k]) - want
+		stat += d * d / want
+		fmt.Fprintf(&table, "\n\t%-30s observed %6d expected %9.1f", k, observed[k], want)
+	}
+	for k, c := range observed {
+		if expected[k] == 0 && c > 0 {
+			t.Errorf("observed %d times outcome %s, which has probability 0", c, k)
+			return
+		}
+	}
+	df := len(keys) - 1
+	if df < 1 {
+		return
+	}
+	if p := ChiSquaredP(stat, df); p < alpha {
+		t.Errorf("observed frequencies of %d trials do not match the model: χ² = %.2f with %d degrees of freedom, p = %.3g < %g:%s",
+			n, stat, df, p, alpha, table.String())
+	}
+}
+
+// ChiSquaredP returns the probability that a chi-squared distributed
+// variable with df degrees of freedom is at least stat.
+func ChiSquaredP(stat float64, df int) float64 {
+	return gammaQ(float64(df)/2, stat/2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function, computed by
+// its series for small x and its continued fraction otherwise.
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lg, _ := math.Lgamma(a)
+	front := math.Exp(-x + a*math.Log(x) - lg)
+	const eps, tiny = 1e-15, 1e-300
+	if x < a+1 {
+		sum, del, ap := 1/a, 1/a, a
+		for range 1000 {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return 1 - sum*front
+	}
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	h := d
+	for i := 1.0; i < 1000; i++ {
+		an := -i * (i - a)
+		b += 2
+		if d = an*d + b; math.Abs(d) < tiny {
+			d = tiny
+		}
+		if c = b + an/c; math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return front * h
+}
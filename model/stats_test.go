@@ -0,0 +1,77 @@
+package model
+
+import (
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestChiSquaredP(t *testing.T) {
+	for _, tc := range []struct {
+		stat float64
+		df   int
+		p    float64
+	}{
+		{3.841, 1, 0.05},
+		{5.991, 2, 0.05},
+		{0, 3, 1},
+		{16.266, 3, 0.001},
+		{2.366, 3, 0.5},
+	} {
+		if p := ChiSquaredP(tc.stat, tc.df); math.Abs(p-tc.p) > 1e-3 {
+			t.Errorf("ChiSquaredP(%v, %d) = %v, want %v", tc.stat, tc.df, p, tc.p)
+		}
+	}
+}
+
+type backend struct{ Last string }
+
+// balancerModel sends each request to a with weight 2, to b and c with
+// weight 1.
+func balancerModel() *Model[backend] {
+	to := func(name string, w float64) Action[backend] {
+		return Action[backend]{Name: "to " + name, Weight: w, Step: func(backend) backend { return backend{name} }}
+	}
+	return &Model[backend]{Name: "balancer", Actions: []Action[backend]{to("a", 2), to("b", 1), to("c", 1)}}
+}
+
+// pick is the balancer under test: it waits a jittered backoff and picks
+// a backend by weight, or uniformly if it ignores the weights.
+func pick(r *rand.Rand, uniform bool) backend {
+	time.Sleep(time.Duration(r.IntN(100)) * time.Millisecond)
+	n := r.IntN(4)
+	if uniform {
+		n = r.IntN(3) + 1
+	}
+	return backend{[]string{"a", "a", "b", "c"}[n]}
+}
+
+func TestDistribution(t *testing.T) {
+	d := balancerModel().Distribution(1)
+	if len(d) != 3 || d["{Last:a}"] != 0.5 || d["{Last:b}"] != 0.25 {
+		t.Errorf("distribution %v", d)
+	}
+}
+
+func TestCheckDistribution(t *testing.T) {
+	CheckDistribution(t, balancerModel(), 1, TrialsConfig{Seed: 1}, func(r *rand.Rand) backend { return pick(r, false) })
+
+	errs := testtb.Run(t, func(t testing.TB) {
+		CheckDistribution(t, balancerModel(), 1, TrialsConfig{Seed: 1}, func(r *rand.Rand) backend { return pick(r, true) })
+	})
+	if len(errs) != 1 || !strings.HasPrefix(errs[0], "observed frequencies of 1000 trials do not match the model: χ² = ") ||
+		!strings.Contains(errs[0], "\n\t{Last:a}                       observed ") {
+		t.Errorf("errors %q", errs)
+	}
+
+	errs = testtb.Run(t, func(t testing.TB) {
+		CheckFrequencies(t, map[string]int{"x": 1, "a": 3}, map[string]float64{"a": 1}, 0.001)
+	})
+	if len(errs) != 1 || errs[0] != "observed 1 times outcome x, which has probability 0" {
+		t.Errorf("errors %q", errs)
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TLA is the Backend writing TLA+ modules for TLC. The module is
+// explicit-state: its variable state ranges over the keys of the reachable
+// states, each action is the set of its transitions between them, and each
+// invariant is the set of states satisfying it.
+type TLA struct{}
+
+func (TLA) Write(w io.Writer, sp *StateSpace) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "---- MODULE %s ----\n", ident(sp.Name))
+	fmt.Fprintf(b, "\\* Generated from the Go model %q; do not edit.\n", sp.Name)
+	fmt.Fprintf(b, "VARIABLE state\n\n")
+	fmt.Fprintf(b, "States == %s\n\n", tlaSet(sp.States, all(len(sp.States))))
+	fmt.Fprintf(b, "TypeOK == state \\in States\n\n")
+	fmt.Fprintf(b, "Init == state = %s\n\n", strconv.Quote(sp.States[0]))
+
+	var next []string
+	for _, a := range sp.Actions {
+		id := ident(a.Name)
+		next = append(next, id)
+		if len(a.Transitions) == 0 {
+			fmt.Fprintf(b, "%s == FALSE\n\n", id)
+			continue
+		}
+		disj := make([]string, len(a.Transitions))
+		for i, tr := range a.Transitions {
+			disj[i] = fmt.Sprintf("(state = %s /\\ state' = %s)", strconv.Quote(sp.States[tr[0]]), strconv.Quote(sp.States[tr[1]]))
+		}
+		fmt.Fprintf(b, "%s ==\n    \\/ %s\n\n", id, strings.Join(disj, "\n    \\/ "))
+	}
+	fmt.Fprintf(b, "Next == %s\n\n", strings.Join(next, " \\/ "))
+	fmt.Fprintf(b, "Spec == Init /\\ [][Next]_state\n\n")
+
+	for _, inv := range sp.Invariants {
+		fmt.Fprintf(b, "%s == state \\in %s\n\n", ident(inv.Name), tlaSet(sp.States, inv.Holds))
+	}
+	fmt.Fprintf(b, "====\n")
+	return b.Flush()
+}
+
+// Config writes the TLC configuration for the module of sp, checking
+// TypeOK and every invariant. States without enabled actions are not
+// reported as deadlocks, as the Go model does not treat them as errors
+// either.
+func (TLA) Config(w io.Writer, sp *StateSpace) error {
+	invs := []string{"TypeOK"}
+	for _, inv := range sp.Invariants {
+		invs = append(invs, ident(inv.Name))
+	}
+	_, err := fmt.Fprintf(w, "SPECIFICATION Spec\nINVARIANT %s\nCHECK_DEADLOCK FALSE\n", strings.Join(invs, " "))
+	return err
+}
+
+// tlaSet writes the set of the keys of the states idx.
+func tlaSet(keys []string, idx []int) string {
+	var q []string
+	for _, i := range idx {
+		q = append(q, strconv.Quote(keys[i]))
+	}
+	return "{" + strings.Join(q, ", ") + "}"
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTLA(t *testing.T) {
+	m := bufferModel()
+	var b strings.Builder
+	if err := m.Export(&b, TLA{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"---- MODULE buffer ----\n",
+		"VARIABLE state\n",
+		`Init == state = "{Len:0 Closed:false}"`,
+		"put ==\n    \\/ (state = \"{Len:0 Closed:false}\" /\\ state' = \"{Len:1 Closed:false}\")\n    \\/ (state = \"{Len:1 Closed:false}\" /\\ state' = \"{Len:2 Closed:false}\")\n\n",
+		"Next == put \\/ take \\/ close\n",
+		"Spec == Init /\\ [][Next]_state\n",
+		"bounded == state \\in {",
+		"====\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("module lacks %q:\n%s", want, got)
+		}
+	}
+	if n := strings.Count(got, "state' ="); n != 9 {
+		t.Errorf("%d transitions, want 9", n)
+	}
+
+	sp, err := m.StateSpace(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if err := (TLA{}).Config(&b, sp); err != nil {
+		t.Fatal(err)
+	}
+	if want := "SPECIFICATION Spec\nINVARIANT TypeOK bounded\nCHECK_DEADLOCK FALSE\n"; b.String() != want {
+		t.Errorf("config\n%s", b.String())
+	}
+}
+
+func TestTLAViolated(t *testing.T) {
+	m := bufferModel()
+	m.Invariants = append(m.Invariants, Invariant[buffer]{Name: "never full", Check: func(s buffer) bool { return s.Len < 2 }})
+	var b strings.Builder
+	if err := m.Export(&b, TLA{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if want := `never_full == state \in {"{Len:0 Closed:false}", "{Len:1 Closed:false}", "{Len:0 Closed:true}", "{Len:1 Closed:true}"}`; !strings.Contains(b.String(), want) {
+		t.Errorf("module lacks %q:\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,157 @@
+package modelfile
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// typ is the type of a variable or expression: int64 or bool.
+type typ int
+
+const (
+	intType typ = iota
+	boolType
+)
+
+func (t typ) String() string {
+	if t == boolType {
+		return "bool"
+	}
+	return "int"
+}
+
+// compiled is an expression over the state variables.
+type compiled struct {
+	eval func(s State) any
+	typ  typ
+}
+
+// compile compiles an expression in Go syntax over the variables of
+// vars: integer and boolean literals, the variables, parentheses, and the
+// arithmetic, comparison and logical operators.
+func compile(src string, vars map[string]typ) (compiled, error) {
+	e, err := parser.ParseExpr(src)
+	if err != nil {
+		// Positions are 1:col of the one-line expression.
+		return compiled{}, fmt.Errorf("%q: %s", src, strings.TrimPrefix(err.Error(), "1:"))
+	}
+	c := exprCompiler{src: src, vars: vars}
+	return c.expr(e)
+}
+
+type exprCompiler struct {
+	src  string
+	vars map[string]typ
+}
+
+func (c exprCompiler) errorf(n ast.Node, format string, args ...any) error {
+	return fmt.Errorf("%q: column %d: %s", c.src, int(n.Pos()), fmt.Sprintf(format, args...))
+}
+
+func (c exprCompiler) expr(e ast.Expr) (compiled, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return c.expr(e.X)
+	case *ast.BasicLit:
+		if e.Kind != token.INT {
+			return compiled{}, c.errorf(e, "only integer literals are supported")
+		}
+		v, err := strconv.ParseInt(e.Value, 0, 64)
+		if err != nil {
+			return compiled{}, c.errorf(e, "%v", err)
+		}
+		return compiled{func(State) any { return v }, intType}, nil
+	case *ast.Ident:
+		switch e.Name {
+		case "true", "false":
+			v := e.Name == "true"
+			return compiled{func(State) any { return v }, boolType}, nil
+		}
+		t, ok := c.vars[e.Name]
+		if !ok {
+			return compiled{}, c.errorf(e, "undefined variable %s", e.Name)
+		}
+		name := e.Name
+		return compiled{func(s State) any { return s[name] }, t}, nil
+	case *ast.UnaryExpr:
+		x, err := c.expr(e.X)
+		if err != nil {
+			return compiled{}, err
+		}
+		switch {
+		case e.Op == token.NOT && x.typ == boolType:
+			return compiled{func(s State) any { return !x.eval(s).(bool) }, boolType}, nil
+		case e.Op == token.SUB && x.typ == intType:
+			return compiled{func(s State) any { return -x.eval(s).(int64) }, intType}, nil
+		}
+		return compiled{}, c.errorf(e, "operator %s not defined on %v", e.Op, x.typ)
+	case *ast.BinaryExpr:
+		return c.binary(e)
+	}
+	return compiled{}, c.errorf(e, "unsupported expression")
+}
+
+func (c exprCompiler) binary(e *ast.BinaryExpr) (compiled, error) {
+	x, err := c.expr(e.X)
+	if err != nil {
+		return compiled{}, err
+	}
+	y, err := c.expr(e.Y)
+	if err != nil {
+		return compiled{}, err
+	}
+	if x.typ != y.typ {
+		return compiled{}, c.errorf(e, "mismatched types %v and %v for %s", x.typ, y.typ, e.Op)
+	}
+	switch e.Op {
+	case token.EQL:
+		return compiled{func(s State) any { return x.eval(s) == y.eval(s) }, boolType}, nil
+	case token.NEQ:
+		return compiled{func(s State) any { return x.eval(s) != y.eval(s) }, boolType}, nil
+	}
+	if x.typ == boolType {
+		switch e.Op {
+		case token.LAND:
+			return compiled{func(s State) any { return x.eval(s).(bool) && y.eval(s).(bool) }, boolType}, nil
+		case token.LOR:
+			return compiled{func(s State) any { return x.eval(s).(bool) || y.eval(s).(bool) }, boolType}, nil
+		}
+		return compiled{}, c.errorf(e, "operator %s not defined on bool", e.Op)
+	}
+	ints := func(f func(a, b int64) any, t typ) (compiled, error) {
+		return compiled{func(s State) any { return f(x.eval(s).(int64), y.eval(s).(int64)) }, t}, nil
+	}
+	src := c.src
+	switch e.Op {
+	case token.ADD:
+		return ints(func(a, b int64) any { return a + b }, intType)
+	case token.SUB:
+		return ints(func(a, b int64) any { return a - b }, intType)
+	case token.MUL:
+		return ints(func(a, b int64) any { return a * b }, intType)
+	case token.QUO, token.REM:
+		op := e.Op
+		return ints(func(a, b int64) any {
+			if b == 0 {
+				panic(fmt.Sprintf("modelfile: division by zero in %q", src))
+			}
+			if op == token.QUO {
+				return a / b
+			}
+			return a % b
+		}, intType)
+	case token.LSS:
+		return ints(func(a, b int64) any { return a < b }, boolType)
+	case token.LEQ:
+		return ints(func(a, b int64) any { return a <= b }, boolType)
+	case token.GTR:
+		return ints(func(a, b int64) any { return a > b }, boolType)
+	case token.GEQ:
+		return ints(func(a, b int64) any { return a >= b }, boolType)
+	}
+	return compiled{}, c.errorf(e, "operator %s not defined on int", e.Op)
+}
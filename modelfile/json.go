@@ -0,0 +1,87 @@
+package modelfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// parseJSON parses src into nodes, keeping the line each value starts on.
+func parseJSON(src []byte) (*node, error) {
+	d := json.NewDecoder(bytes.NewReader(src))
+	d.UseNumber()
+	line := func(off int64) int { return 1 + bytes.Count(src[:off], []byte("\n")) }
+	n, err := jsonValue(d, src, line)
+	if err == nil {
+		if _, err = d.Token(); err == io.EOF {
+			return n, nil
+		}
+		err = fmt.Errorf("%d: unexpected data after the document", line(d.InputOffset()))
+	}
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		return nil, fmt.Errorf("%d: %v", line(se.Offset), se)
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%d: unexpected end of file", line(int64(len(src))))
+	}
+	return nil, err
+}
+
+func jsonValue(d *json.Decoder, src []byte, line func(int64) int) (*node, error) {
+	// The offset after any whitespace, where the value starts.
+	off := d.InputOffset()
+	for off < int64(len(src)) && bytes.IndexByte([]byte(" \t\r\n,:"), src[off]) >= 0 {
+		off++
+	}
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	n := &node{line: line(off)}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			n.kind = listNode
+			for d.More() {
+				item, err := jsonValue(d, src, line)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, item)
+			}
+		} else {
+			n.kind, n.fields = mapNode, make(map[string]*node)
+			for d.More() {
+				kt, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				k := kt.(string)
+				v, err := jsonValue(d, src, line)
+				if err != nil {
+					return nil, err
+				}
+				if _, dup := n.fields[k]; dup {
+					return nil, fmt.Errorf("%d: key %q repeated", v.line, k)
+				}
+				n.keys = append(n.keys, k)
+				n.fields[k] = v
+			}
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.scalar, n.quoted = tok, true
+	case json.Number:
+		n.scalar = tok.String()
+	case bool:
+		n.scalar = strconv.FormatBool(tok)
+	case nil:
+	}
+	return n, nil
+}
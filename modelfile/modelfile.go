@@ -0,0 +1,329 @@
+// Package modelfile loads models from declarative YAML or JSON files, so
+// that models can be written and reviewed without writing Go. A file
+// declares the state variables with their initial values, the actions
+// with guards and updates, the invariants and the liveness properties;
+// guards, updates and properties are expressions in Go syntax over the
+// variables:
+//
+//	name: buffer
+//	state:
+//	  len: 0
+//	  closed: false
+//	actions:
+//	  - name: put
+//	    guard: "!closed && len < 2"
+//	    update:
+//	      len: len + 1
+//	  - name: close
+//	    guard: "!closed"
+//	    fairness: weak
+//	    update:
+//	      closed: "true"
+//	invariants:
+//	  - name: bounded
+//	    check: len >= 0 && len <= 2
+//
+// Variables are integers or booleans, as their initial values tell. The
+// updates of an action are simultaneous: each expression sees the state
+// before the action. The JSON form has the same structure.
+package modelfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// State is the state of a loaded model: the value, int64 or bool, of each
+// variable.
+type State map[string]any
+
+// Error is a problem in a model file.
+type Error struct {
+	File string
+	Line int // 0 if it concerns the whole model
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Load reads the model in the file at path, in YAML if its name ends in
+// .yaml or .yml and in JSON if it ends in .json.
+func Load(path string) (*model.Model[State], error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Parse parses the model in src, read from the file name, whose extension
+// tells the format. It fails with every problem found, each an *Error.
+func Parse(name string, src []byte) (*model.Model[State], error) {
+	var root *node
+	var err error
+	switch ext := filepath.Ext(name); ext {
+	case ".yaml", ".yml":
+		root, err = parseYAML(src)
+	case ".json":
+		root, err = parseJSON(src)
+	default:
+		return nil, &Error{File: name, Msg: fmt.Sprintf("unknown format %q; want .yaml, .yml or .json", ext)}
+	}
+	if err != nil {
+		line, msg, _ := strings.Cut(err.Error(), ": ")
+		n, _ := strconv.Atoi(line)
+		return nil, &Error{File: name, Line: n, Msg: msg}
+	}
+	l := &loader{file: name}
+	m := l.model(root)
+	if len(l.errs) == 0 {
+		if err := m.Validate(); err != nil {
+			l.errs = append(l.errs, &Error{File: name, Msg: err.Error()})
+		}
+	}
+	if len(l.errs) > 0 {
+		return nil, errors.Join(l.errs...)
+	}
+	return m, nil
+}
+
+type loader struct {
+	file  string
+	errs  []error
+	vars  []string
+	types map[string]typ
+}
+
+func (l *loader) errorf(n *node, format string, args ...any) {
+	l.errs = append(l.errs, &Error{File: l.file, Line: n.line, Msg: fmt.Sprintf(format, args...)})
+}
+
+// fields checks that n is a mapping with only the allowed keys, of which
+// the required ones.
+func (l *loader) fields(n *node, what string, allowed, required []string) bool {
+	if n.kind != mapNode {
+		l.errorf(n, "%s must be a mapping, not %v", what, n.kind)
+		return false
+	}
+	ok := true
+	for _, k := range n.keys {
+		if !contains(allowed, k) {
+			l.errorf(n.fields[k], "%s: unknown field %q; want one of %s", what, k, strings.Join(allowed, ", "))
+			ok = false
+		}
+	}
+	for _, k := range required {
+		if _, has := n.fields[k]; !has {
+			l.errorf(n, "%s: missing field %q", what, k)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *loader) string(n *node, what string) string {
+	if n.kind != scalarNode || n.scalar == "" {
+		l.errorf(n, "%s must be a non-empty string", what)
+		return ""
+	}
+	return n.scalar
+}
+
+func (l *loader) list(n *node, what string) []*node {
+	if n == nil {
+		return nil
+	}
+	if n.kind != listNode {
+		l.errorf(n, "%s must be a list, not %v", what, n.kind)
+		return nil
+	}
+	return n.items
+}
+
+// expr compiles the expression in n, which must be of type want.
+func (l *loader) expr(n *node, what string, want typ) func(State) any {
+	src := l.string(n, what)
+	if src == "" {
+		return nil
+	}
+	c, err := compile(src, l.types)
+	if err != nil {
+		l.errorf(n, "%s: %v", what, err)
+		return nil
+	}
+	if c.typ != want {
+		l.errorf(n, "%s: %q is %v, want %v", what, src, c.typ, want)
+		return nil
+	}
+	return c.eval
+}
+
+func (l *loader) model(root *node) *model.Model[State] {
+	m := &model.Model[State]{Init: State{}}
+	if !l.fields(root, "model", []string{"name", "state", "actions", "invariants", "liveness"}, []string{"name", "state", "actions"}) {
+		return m
+	}
+	m.Name = l.string(root.fields["name"], "name")
+	l.state(m, root.fields["state"])
+	m.Key = func(s State) string {
+		parts := make([]string, len(l.vars))
+		for i, v := range l.vars {
+			parts[i] = fmt.Sprintf("%s:%v", v, s[v])
+		}
+		return "{" + strings.Join(parts, " ") + "}"
+	}
+	for i, n := range l.list(root.fields["actions"], "actions") {
+		if a, ok := l.action(n, fmt.Sprintf("actions[%d]", i)); ok {
+			m.Actions = append(m.Actions, a)
+		}
+	}
+	for i, n := range l.list(root.fields["invariants"], "invariants") {
+		what := fmt.Sprintf("invariants[%d]", i)
+		if !l.fields(n, what, []string{"name", "check"}, []string{"name", "check"}) {
+			continue
+		}
+		check := l.expr(n.fields["check"], what+".check", boolType)
+		if check != nil {
+			m.Invariants = append(m.Invariants, model.Invariant[State]{
+				Name: l.string(n.fields["name"], what+".name"), Check: func(s State) bool { return check(s).(bool) }})
+		}
+	}
+	for i, n := range l.list(root.fields["liveness"], "liveness") {
+		what := fmt.Sprintf("liveness[%d]", i)
+		if !l.fields(n, what, []string{"name", "from", "to"}, []string{"name", "to"}) {
+			continue
+		}
+		lv := model.Liveness[State]{Name: l.string(n.fields["name"], what+".name")}
+		if to := l.expr(n.fields["to"], what+".to", boolType); to != nil {
+			lv.To = func(s State) bool { return to(s).(bool) }
+		}
+		if f, ok := n.fields["from"]; ok {
+			if from := l.expr(f, what+".from", boolType); from != nil {
+				lv.From = func(s State) bool { return from(s).(bool) }
+			}
+		}
+		m.Liveness = append(m.Liveness, lv)
+	}
+	return m
+}
+
+func (l *loader) state(m *model.Model[State], n *node) {
+	l.types = make(map[string]typ)
+	if n.kind != mapNode || len(n.keys) == 0 {
+		l.errorf(n, "state must be a mapping of at least one variable to its initial value")
+		return
+	}
+	for _, k := range n.keys {
+		v := n.fields[k]
+		if !isIdent(k) {
+			l.errorf(v, "state variable %q is not an identifier", k)
+			continue
+		}
+		switch b, err := strconv.ParseBool(v.scalar); {
+		case v.kind != scalarNode || v.quoted:
+		case err == nil && (v.scalar == "true" || v.scalar == "false"):
+			l.types[k], m.Init[k] = boolType, b
+		default:
+			if i, err := strconv.ParseInt(v.scalar, 10, 64); err == nil {
+				l.types[k], m.Init[k] = intType, i
+			}
+		}
+		if _, ok := l.types[k]; !ok {
+			l.errorf(v, "state variable %s: initial value must be an integer or true or false", k)
+			continue
+		}
+		l.vars = append(l.vars, k)
+	}
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return s != "" && s != "true" && s != "false"
+}
+
+func (l *loader) action(n *node, what string) (model.Action[State], bool) {
+	var a model.Action[State]
+	if !l.fields(n, what, []string{"name", "guard", "update", "weight", "fairness"}, []string{"name"}) {
+		return a, false
+	}
+	a.Name = l.string(n.fields["name"], what+".name")
+	what = fmt.Sprintf("action %s", a.Name)
+	if g, ok := n.fields["guard"]; ok {
+		if guard := l.expr(g, what+": guard", boolType); guard != nil {
+			a.Guard = func(s State) bool { return guard(s).(bool) }
+		}
+	}
+	type update struct {
+		v string
+		f func(State) any
+	}
+	var updates []update
+	if u, ok := n.fields["update"]; ok {
+		if u.kind != mapNode {
+			l.errorf(u, "%s: update must be a mapping of variables to expressions", what)
+		}
+		for _, v := range u.keys {
+			t, ok := l.types[v]
+			if !ok {
+				l.errorf(u.fields[v], "%s: update of undeclared variable %s", what, v)
+				continue
+			}
+			if f := l.expr(u.fields[v], what+": update of "+v, t); f != nil {
+				updates = append(updates, update{v, f})
+			}
+		}
+	}
+	a.Step = func(s State) State {
+		next := make(State, len(s))
+		for k, v := range s {
+			next[k] = v
+		}
+		for _, u := range updates {
+			next[u.v] = u.f(s)
+		}
+		return next
+	}
+	if w, ok := n.fields["weight"]; ok {
+		f, err := strconv.ParseFloat(w.scalar, 64)
+		if w.kind != scalarNode || err != nil || f <= 0 {
+			l.errorf(w, "%s: weight must be a positive number", what)
+		}
+		a.Weight = f
+	}
+	if fn, ok := n.fields["fairness"]; ok {
+		switch fn.scalar {
+		case "unfair":
+		case "weak":
+			a.Fairness = model.Weak
+		case "strong":
+			a.Fairness = model.Strong
+		default:
+			l.errorf(fn, "%s: fairness must be unfair, weak or strong", what)
+		}
+	}
+	return a, true
+}
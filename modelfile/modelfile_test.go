@@ -0,0 +1,78 @@
+package modelfile
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// buffer is testdata/buffer.yaml written in Go.
+type buffer struct {
+	len    int
+	closed bool
+}
+
+func bufferModel() *model.Model[buffer] {
+	return &model.Model[buffer]{
+		Name: "buffer",
+		Actions: []model.Action[buffer]{
+			{Name: "put", Guard: func(b buffer) bool { return !b.closed && b.len < 2 }, Step: func(b buffer) buffer { b.len++; return b }},
+			{Name: "take", Guard: func(b buffer) bool { return b.len > 0 }, Step: func(b buffer) buffer { b.len--; return b }},
+			{Name: "close", Guard: func(b buffer) bool { return !b.closed }, Step: func(b buffer) buffer { b.closed = true; return b }, Fairness: model.Weak},
+		},
+		Invariants: []model.Invariant[buffer]{{Name: "bounded", Check: func(b buffer) bool { return b.len >= 0 && b.len <= 2 }}},
+		Key:        func(b buffer) string { return fmt.Sprintf("{len:%d closed:%v}", b.len, b.closed) },
+	}
+}
+
+func TestLoad(t *testing.T) {
+	want, err := bufferModel().StateSpace(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"testdata/buffer.yaml", "testdata/buffer.json"} {
+		m, err := Load(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.StateSpace(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: state space\n%+v\nwant\n%+v", file, got, want)
+		}
+		if len(m.Liveness) != 1 || m.Actions[2].Fairness != model.Weak {
+			t.Errorf("%s: liveness %d, close fairness %v", file, len(m.Liveness), m.Actions[2].Fairness)
+		}
+		if err := m.CheckLiveness(0); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, src, want string
+	}{
+		{"m.toml", "", `m.toml: unknown format ".toml"; want .yaml, .yml or .json`},
+		{"m.yaml", "name: m\nstate:\n  n: 0\n   x: 1\n", "m.yaml:4: "},
+		{"m.yaml", "name: m\nstate:\n  n: zero\nactions:\n  - name: inc\n", "m.yaml:3: state variable n: initial value must be an integer or true or false"},
+		{"m.yaml", "name: m\nstate:\n  n: 0\nactions:\n  - name: inc\n    gaurd: n < 3\n", `m.yaml:6: actions[0]: unknown field "gaurd"; want one of name, guard, update, weight, fairness`},
+		{"m.yaml", "name: m\nstate:\n  n: 0\nactions:\n  - name: inc\n    guard: n + 1\n", `m.yaml:6: action inc: guard: "n + 1" is int, want bool`},
+		{"m.yaml", "name: m\nstate:\n  n: 0\nactions:\n  - name: inc\n    update:\n      n: n +\n", `m.yaml:7: action inc: update of n: "n +": `},
+		{"m.yaml", "name: m\nstate:\n  n: 0\nactions:\n  - name: inc\n    update:\n      m: n\n", "m.yaml:7: action inc: update of undeclared variable m"},
+		{"m.yaml", "name: m\nstate:\n  n: 0\nactions:\n  - name: inc\n    fairness: fair\n", "m.yaml:6: action inc: fairness must be unfair, weak or strong"},
+		{"m.yaml", "state:\n  n: 0\nactions:\n  - name: inc\n", `m.yaml:1: model: missing field "name"`},
+		{"m.json", "{\"name\": \"m\",\n \"state\": {\"n\": 0},\n \"actions\": [{\"name\": \"inc\", \"guard\": \"m > 0\"}]}", `m.json:3: action inc: guard: "m > 0": column 1: `},
+		{"m.yaml", "name: m\nstate:\n  n: 0\nactions:\n  - name: inc\n  - name: inc\n", "m.yaml: model m: "},
+	} {
+		_, err := Parse(tc.name, []byte(tc.src))
+		if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Errorf("Parse(%s, %q) = %v, want an error starting %q", tc.name, tc.src, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package modelfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// node is a parsed value with the line it starts on.
+type node struct {
+	line   int
+	scalar string // set for scalars
+	quoted bool   // the scalar was quoted, so it is a string
+	keys   []string
+	fields map[string]*node // for mappings, in the order of keys
+	items  []*node          // for sequences
+	kind   kind
+}
+
+type kind int
+
+const (
+	scalarNode kind = iota
+	mapNode
+	listNode
+)
+
+func (k kind) String() string {
+	return [...]string{"a scalar", "a mapping", "a list"}[k]
+}
+
+// yamlLine is a line of YAML without its indentation and comment.
+type yamlLine struct {
+	n      int
+	indent int
+	text   string
+}
+
+// parseYAML parses the block subset of YAML model files use: mappings,
+// lists and plain or quoted scalars, nested by indentation. Flow
+// collections, anchors and multi-line scalars are not supported.
+func parseYAML(src []byte) (*node, error) {
+	var lines []yamlLine
+	for i, l := range strings.Split(string(src), "\n") {
+		l = strings.TrimRight(stripComment(l), " \r")
+		text := strings.TrimLeft(l, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("%d: tabs are not allowed in indentation", i+1)
+		}
+		lines = append(lines, yamlLine{i + 1, len(l) - len(text), text})
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("1: empty document")
+	}
+	p := &yamlParser{lines: lines}
+	n, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(lines) {
+		return nil, fmt.Errorf("%d: unexpected indentation", lines[p.i].n)
+	}
+	return n, nil
+}
+
+// stripComment drops a # comment that is not inside quotes.
+func stripComment(l string) string {
+	var quote byte
+	for i := 0; i < len(l); i++ {
+		switch c := l[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || l[i-1] == ' '):
+			return l[:i]
+		}
+	}
+	return l
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+// block parses the mapping or list whose lines are indented by indent.
+func (p *yamlParser) block(indent int) (*node, error) {
+	first := p.lines[p.i]
+	if first.text == "-" || strings.HasPrefix(first.text, "- ") {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) list(indent int) (*node, error) {
+	n := &node{line: p.lines[p.i].n, kind: listNode}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		l := p.lines[p.i]
+		if l.text != "-" && !strings.HasPrefix(l.text, "- ") {
+			return nil, fmt.Errorf("%d: expected a list item", l.n)
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		var item *node
+		var err error
+		switch {
+		case rest == "":
+			p.i++
+			if p.i == len(p.lines) || p.lines[p.i].indent <= indent {
+				item = &node{line: l.n}
+				break
+			}
+			item, err = p.block(p.lines[p.i].indent)
+		case isKey(rest):
+			// The item is a mapping starting on the dash's line.
+			p.lines[p.i] = yamlLine{l.n, l.indent + len(l.text) - len(rest), rest}
+			item, err = p.mapping(p.lines[p.i].indent)
+		default:
+			item, err = scalar(l.n, rest)
+			p.i++
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+	}
+	return n, nil
+}
+
+func (p *yamlParser) mapping(indent int) (*node, error) {
+	n := &node{line: p.lines[p.i].n, kind: mapNode, fields: make(map[string]*node)}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		l := p.lines[p.i]
+		if !isKey(l.text) {
+			return nil, fmt.Errorf("%d: expected a key: value pair", l.n)
+		}
+		k, rest, _ := strings.Cut(l.text, ":")
+		k = strings.TrimSpace(k)
+		rest = strings.TrimSpace(rest)
+		if uk, err := strconv.Unquote(k); err == nil {
+			k = uk
+		}
+		if _, dup := n.fields[k]; dup {
+			return nil, fmt.Errorf("%d: key %q repeated", l.n, k)
+		}
+		p.i++
+		var v *node
+		var err error
+		switch {
+		case rest != "":
+			v, err = scalar(l.n, rest)
+		case p.i < len(p.lines) && (p.lines[p.i].indent > indent ||
+			p.lines[p.i].indent == indent && strings.HasPrefix(p.lines[p.i].text, "- ")):
+			v, err = p.block(p.lines[p.i].indent)
+		default:
+			v = &node{line: l.n}
+		}
+		if err != nil {
+			return nil, err
+		}
+		n.keys = append(n.keys, k)
+		n.fields[k] = v
+	}
+	return n, nil
+}
+
+// isKey reports whether text starts with a key, which is followed by a
+// colon and a space or the end of the line.
+func isKey(text string) bool {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, `'`) {
+		end := strings.IndexByte(text[1:], text[0])
+		return end >= 0 && strings.HasPrefix(text[end+2:], ":")
+	}
+	i := strings.Index(text, ":")
+	return i > 0 && (i == len(text)-1 || text[i+1] == ' ')
+}
+
+func scalar(line int, text string) (*node, error) {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return nil, fmt.Errorf("%d: flow collections are not supported; use indented blocks", line)
+	}
+	switch text[0] {
+	case '"':
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("%d: bad quoted string %s", line, text)
+		}
+		return &node{line: line, scalar: s, quoted: true}, nil
+	case '\'':
+		if len(text) < 2 || text[len(text)-1] != '\'' {
+			return nil, fmt.Errorf("%d: bad quoted string %s", line, text)
+		}
+		return &node{line: line, scalar: strings.ReplaceAll(text[1:len(text)-1], "''", "'"), quoted: true}, nil
+	}
+	return &node{line: line, scalar: text}, nil
+}
@@ -0,0 +1,117 @@
+// Package racecheck runs code under the race detector and asserts on the
+// data races it reports: that there are none, or only known benign ones.
+// Rather than the race detector failing the test at its first report, the
+// code runs in a child process of the test binary whose reports are
+// parsed, so that a test sees all races with their stacks.
+package racecheck
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/racelog"
+)
+
+// Report is a data race as reported by the race detector.
+type Report = racelog.Report
+
+// Access is one of the racing accesses of a report, with its stack.
+type Access = racelog.Access
+
+// startMarker precedes the output of fn in the child process.
+const startMarker = "\nracecheck: start\n"
+
+// Run runs fn under the race detector in a child process running only
+// the calling test, and returns the races reported while fn ran. The
+// test binary must be built with -race, else Run skips the test. In the
+// child, the test runs up to the call of Run, which runs fn and ends the
+// process; earlier calls of Run return what they did in the parent.
+func Run(t testing.TB, fn func()) []Report {
+	t.Helper()
+	if !racelog.Enabled {
+		t.Skip("racecheck: needs the race detector; run with -race")
+	}
+	out, ok := racelog.Fork(t, "", func(string) {
+		fmt.Fprint(os.Stderr, startMarker)
+		fn()
+	})
+	if !ok {
+		return nil
+	}
+	_, out, found := strings.Cut(out, startMarker)
+	if !found {
+		t.Fatalf("racecheck: the child process did not run:\n%s", out)
+	}
+	return racelog.Parse(out)
+}
+
+// Allow is a known benign race.
+type Allow struct {
+	Name string // why the race is benign
+	// Sites are where the accesses are, some access of a matching report
+	// at each: a function, as in "pkg.(*T).Method" or "(*T).Method", or a
+	// line of a file, as in "file.go:12", anywhere on its stack.
+	Sites []string
+}
+
+// Matches reports whether r is the race of a.
+func (a Allow) Matches(r Report) bool {
+	for _, site := range a.Sites {
+		if !slices.ContainsFunc(r.Accesses, func(acc Access) bool { return at(acc, site) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// at reports whether acc has site on its stack.
+func at(acc Access, site string) bool {
+	for _, f := range acc.Frames {
+		if f.Func == site || strings.HasSuffix(f.Func, "."+site) || strings.HasSuffix(f.File+":"+strconv.Itoa(f.Line), "/"+site) {
+			return true
+		}
+	}
+	return false
+}
+
+// NoRaces fails t for every race of reports.
+func NoRaces(t testing.TB, reports []Report) {
+	t.Helper()
+	for _, r := range reports {
+		t.Errorf("racecheck: data race between %s\n%s", sites(r), r.Text)
+	}
+}
+
+// Only fails t for every race of reports that is none of allow, and for
+// every one of allow that is none of the races.
+func Only(t testing.TB, reports []Report, allow ...Allow) {
+	t.Helper()
+	seen := make([]bool, len(allow))
+	for _, r := range reports {
+		i := slices.IndexFunc(allow, func(a Allow) bool { return a.Matches(r) })
+		if i < 0 {
+			t.Errorf("racecheck: data race between %s, which is not allowed\n%s", sites(r), r.Text)
+			continue
+		}
+		seen[i] = true
+	}
+	for i, a := range allow {
+		if !seen[i] {
+			t.Errorf("racecheck: allowed race %q at %s did not occur", a.Name, strings.Join(a.Sites, " and "))
+		}
+	}
+}
+
+// sites describes the accesses of r.
+func sites(r Report) string {
+	var s []string
+	for _, a := range r.Accesses {
+		f := a.Site()
+		s = append(s, fmt.Sprintf("%s at %s:%d", a.Op, f.File, f.Line))
+	}
+	return strings.Join(s, " and ")
+}
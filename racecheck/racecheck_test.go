@@ -0,0 +1,53 @@
+package racecheck
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/racelog"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// stats counts hits without synchronization, racing on purpose.
+type stats struct{ hits int }
+
+func (s *stats) hit() { s.hits++ }
+
+func hitTwice() {
+	var s stats
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.hit()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRun(t *testing.T) {
+	if !racelog.Enabled {
+		t.Skip("needs -race")
+	}
+	reports := Run(t, hitTwice)
+	if len(reports) != 1 {
+		t.Fatalf("got %d reports, want 1", len(reports))
+	}
+	if errs := testtb.Run(t, func(t testing.TB) { NoRaces(t, reports) }); len(errs) != 1 || !strings.Contains(errs[0], "racecheck_test.go:15") {
+		t.Errorf("NoRaces: errors %q", errs)
+	}
+	benign := Allow{Name: "approximate stats", Sites: []string{"(*stats).hit"}}
+	if errs := testtb.Run(t, func(t testing.TB) { Only(t, reports, benign) }); len(errs) != 0 {
+		t.Errorf("Only: errors %q", errs)
+	}
+	other := Allow{Name: "other", Sites: []string{"racecheck_test.go:99"}}
+	errs := testtb.Run(t, func(t testing.TB) { Only(t, reports, other) })
+	if len(errs) != 2 || !strings.Contains(errs[0], "not allowed") || !strings.Contains(errs[1], `"other"`) {
+		t.Errorf("Only: errors %q", errs)
+	}
+	if clean := Run(t, func() {}); len(clean) != 0 {
+		t.Errorf("got %d reports of no code", len(clean))
+	}
+}
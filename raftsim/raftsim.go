@@ -0,0 +1,271 @@
+// Package raftsim is a scenario kit for Raft implementations: it runs N
+// nodes of one on a simnet network in a synctest bubble, gives each a
+// mailbox to exchange its RPCs through and election timeouts drawn on the
+// virtual clock, and checks, as the nodes report their states, that there
+// is at most one leader per term, election safety, and that logs having
+// an entry of the same index and term agree up to it, log matching.
+//
+// The network can be partitioned and its links slowed with the methods of
+// Cluster.Net, and nodes crashed and restarted with those of its nodes.
+// The timeouts and the latencies of the links derive from the seed of the
+// caller's sched run, so exploring schedules with sched.ExploreRandom
+// explores elections too, and a failing run replays with its seed.
+package raftsim
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// Port is the port of the mailboxes of the nodes.
+const Port = "raft"
+
+// Entry is an entry of a replicated log.
+type Entry struct {
+	Term uint64
+	Data string
+}
+
+// State is what a node reports of itself to the checkers.
+type State struct {
+	Term   uint64
+	Leader bool    // of Term
+	Log    []Entry // from index 1
+	Commit int     // index of the last entry committed, 0 for none
+}
+
+// Start starts the Raft node of env: it reads its persistent state from
+// env.Proc.Disk, starts its goroutines with env.Proc.Go and returns.
+type Start func(env *Env)
+
+// Env is what a node of a Cluster runs with, for an incarnation of its
+// process.
+type Env struct {
+	Name    string
+	Peers   []string // the other nodes, by name
+	Proc    *simnet.Proc
+	Mailbox *simnet.Mailbox // on Port, to exchange messages with the peers
+
+	c    *Cluster
+	rand *rand.Rand // of the timeouts; guarded by c.mu
+}
+
+// ElectionTimeout draws an election timeout between Config.ElectionTimeout
+// and twice that. The timeouts of a node are a function of the seed of
+// the cluster, the node, its incarnation and how many it drew before.
+func (e *Env) ElectionTimeout() time.Duration {
+	e.c.mu.Lock()
+	defer e.c.mu.Unlock()
+	d := e.c.cfg.electionTimeout()
+	return d + time.Duration(e.rand.Int64N(int64(d)))
+}
+
+// Report reports the state of the node, whenever its term, its role or
+// its log changed, and checks it against the others; see Cluster.
+func (e *Env) Report(s State) { e.c.report(e.Name, s) }
+
+// Config configures a Cluster.
+type Config struct {
+	Nodes int // default 3
+	// ElectionTimeout is the shortest election timeout, default 150ms.
+	ElectionTimeout time.Duration
+	// Seed is the seed of the timeouts and of the network, default that
+	// of the caller's sched run, or else Seed, logged if t fails.
+	Seed uint64
+}
+
+func (c Config) nodes() int {
+	if c.Nodes > 0 {
+		return c.Nodes
+	}
+	return 3
+}
+
+func (c Config) electionTimeout() time.Duration {
+	if c.ElectionTimeout > 0 {
+		return c.ElectionTimeout
+	}
+	return 150 * time.Millisecond
+}
+
+// Cluster is a cluster of Raft nodes, "n1" to "nN". Whenever a node
+// reports its state, the cluster fails t if another node was reported
+// leader of the same term, or if its log and the last reported by another
+// node have an entry of the same index and term but differ before it.
+type Cluster struct {
+	Net *simnet.Network
+
+	t     testing.TB
+	cfg   Config
+	start Start
+
+	mu      sync.Mutex
+	leaders map[uint64]string // by term
+	states  map[string]State  // last reported, by node
+	failed  bool
+}
+
+// New starts a cluster of cfg.Nodes nodes running start, on a network of
+// its own, in the caller's bubble. Close it before the bubble ends.
+func New(t testing.TB, cfg Config, start Start) *Cluster {
+	if cfg.Seed == 0 {
+		cfg.Seed = sched.SeedFor(t, "raftsim")
+	}
+	c := &Cluster{
+		Net: simnet.New(), t: t, cfg: cfg, start: start,
+		leaders: make(map[uint64]string), states: make(map[string]State),
+	}
+	c.Net.Seed(cfg.Seed)
+	names := c.Names()
+	for _, name := range names {
+		peers := slices.DeleteFunc(slices.Clone(names), func(p string) bool { return p == name })
+		c.Net.Add(name, func(p *simnet.Proc) { c.boot(p, peers) })
+	}
+	return c
+}
+
+// Names returns the names of the nodes of c.
+func (c *Cluster) Names() []string {
+	names := make([]string, c.cfg.nodes())
+	for i := range names {
+		names[i] = fmt.Sprintf("n%d", i+1)
+	}
+	return names
+}
+
+func (c *Cluster) boot(p *simnet.Proc, peers []string) {
+	name := p.Node().Name
+	m, err := p.Mailbox(Port)
+	if err != nil {
+		return
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	env := &Env{
+		Name: name, Peers: peers, Proc: p, Mailbox: m, c: c,
+		rand: rand.New(rand.NewPCG(c.cfg.Seed^h.Sum64(), uint64(p.Incarnation()))),
+	}
+	c.start(env)
+}
+
+// Close crashes every node of c, for the end of a scenario.
+func (c *Cluster) Close() { c.Net.Close() }
+
+// Node returns the node name of c.
+func (c *Cluster) Node(name string) *simnet.Node { return c.Net.Node(name) }
+
+// report records the state s of the node name and checks it.
+func (c *Cluster) report(name string, s State) {
+	s.Log = slices.Clone(s.Log)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.Leader {
+		if l, ok := c.leaders[s.Term]; ok && l != name {
+			c.fail("election safety: %s and %s are both leaders of term %d", l, name, s.Term)
+		} else {
+			c.leaders[s.Term] = name
+		}
+	}
+	c.states[name] = s
+	for other, o := range c.states {
+		if other == name {
+			continue
+		}
+		if m, i := mismatch(s.Log, o.Log); i > 0 {
+			c.fail("log matching: %s and %s have an entry of term %d at index %d but differ at index %d:\n\t%s: %v\n\t%s: %v",
+				name, other, s.Log[m-1].Term, m, i, name, s.Log, other, o.Log)
+		}
+	}
+}
+
+// fail fails c.t, the first time only, with c.mu held: once two nodes
+// disagree, every later report would.
+func (c *Cluster) fail(format string, args ...any) {
+	if c.failed {
+		return
+	}
+	c.failed = true
+	c.t.Errorf("raftsim: "+format, args...)
+}
+
+// matched returns the highest index at which a and b have an entry of the
+// same term, 0 if none.
+func matched(a, b []Entry) int {
+	for i := min(len(a), len(b)); i > 0; i-- {
+		if a[i-1].Term == b[i-1].Term {
+			return i
+		}
+	}
+	return 0
+}
+
+// mismatch returns the highest index m at which a and b have an entry of
+// the same term and the first index i before it at which they differ, 0
+// if none.
+func mismatch(a, b []Entry) (m, i int) {
+	m = matched(a, b)
+	for i := range m {
+		if a[i] != b[i] {
+			return m, i + 1
+		}
+	}
+	return m, 0
+}
+
+// Leader returns the node reported leader of the highest term among the
+// running nodes, if one is.
+func (c *Cluster) Leader() (name string, term uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for n, s := range c.states {
+		if s.Leader && (!ok || s.Term > term || s.Term == term && n < name) && c.Net.Node(n).Up() {
+			name, term, ok = n, s.Term, true
+		}
+	}
+	return name, term, ok
+}
+
+// AwaitLeader waits until a running node is leader of a term no other
+// running node is past and returns it, or fails t after timeout.
+func (c *Cluster) AwaitLeader(timeout time.Duration) (name string, term uint64) {
+	c.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		if name, term, ok := c.Leader(); ok && c.highestTerm() == term {
+			return name, term
+		}
+		if !time.Now().Before(deadline) {
+			c.t.Errorf("raftsim: no leader within %v", timeout)
+			return "", 0
+		}
+		time.Sleep(c.cfg.electionTimeout() / 10)
+	}
+}
+
+// highestTerm returns the highest term reported by a running node.
+func (c *Cluster) highestTerm() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var term uint64
+	for n, s := range c.states {
+		if c.Net.Node(n).Up() {
+			term = max(term, s.Term)
+		}
+	}
+	return term
+}
+
+// State returns the state last reported by the node name.
+func (c *Cluster) State(name string) State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.states[name]
+}
@@ -0,0 +1,345 @@
+package raftsim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+)
+
+// msg is a message of the Raft of the tests.
+type msg struct {
+	Type      string // "prevote", "prevoted", "vote", "voted", "append" or "appended"
+	Term      uint64
+	From      string
+	LastIndex int // of the candidate
+	LastTerm  uint64
+	Granted   bool
+	PrevIndex int // of the entries appended
+	PrevTerm  uint64
+	Entries   []Entry
+	Commit    int
+	Success   bool
+	Match     int    // of the follower, if Success
+	Round     uint64 // of a pre-vote, the term of the node asking
+}
+
+// persistent is the state of a node kept on its disk.
+type persistent struct {
+	Term     uint64
+	VotedFor string
+	Log      []Entry
+}
+
+// raft is a small Raft, proposals being sent to the nodes by name. A
+// node campaigns only once a quorum would vote for it, pre-vote, so that
+// one partitioned away does not disrupt the cluster when it comes back,
+// and a new leader appends an empty entry of its term, so that it can
+// commit the entries of earlier terms. Unless it is careful, a candidate
+// counts its own vote twice.
+func raft(proposals map[string]chan string, careful bool) Start {
+	return func(env *Env) {
+		var st persistent
+		if data, err := env.Proc.Disk().ReadFile("raft"); err == nil {
+			json.Unmarshal(data, &st)
+		}
+		props := make(chan string, 16)
+		proposals[env.Name] = props
+		env.Proc.Go(func(ctx context.Context) { run(ctx, env, st, props, careful) })
+	}
+}
+
+func run(ctx context.Context, env *Env, st persistent, props <-chan string, careful bool) {
+	var (
+		leader, candidate, prevoting bool
+		votes, prevotes, commit      int
+		next, match                  = make(map[string]int), make(map[string]int)
+		heard                        time.Time // from a leader, last
+	)
+	quorum := (len(env.Peers)+1)/2 + 1
+	if !careful {
+		quorum--
+	}
+	election := time.NewTimer(env.ElectionTimeout())
+	heartbeat := time.NewTicker(50 * time.Millisecond)
+	defer election.Stop()
+	defer heartbeat.Stop()
+	send := func(to string, m msg) {
+		m.Term, m.From = st.Term, env.Name
+		data, _ := json.Marshal(m)
+		env.Mailbox.Send(to, data)
+	}
+	persist := func() bool {
+		data, _ := json.Marshal(st)
+		f, err := env.Proc.Disk().Create("raft")
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		if _, err := f.Write(data); err != nil {
+			return false
+		}
+		return f.Sync() == nil
+	}
+	last := func() (int, uint64) {
+		if len(st.Log) == 0 {
+			return 0, 0
+		}
+		return len(st.Log), st.Log[len(st.Log)-1].Term
+	}
+	replicate := func() {
+		for _, p := range env.Peers {
+			prev := next[p] - 1
+			m := msg{Type: "append", PrevIndex: prev, Entries: st.Log[prev:], Commit: commit}
+			if prev > 0 {
+				m.PrevTerm = st.Log[prev-1].Term
+			}
+			send(p, m)
+		}
+	}
+	lead := func() bool {
+		leader, candidate = true, false
+		for _, p := range env.Peers {
+			next[p], match[p] = len(st.Log)+1, 0
+		}
+		st.Log = append(st.Log, Entry{st.Term, ""})
+		if !persist() {
+			return false
+		}
+		replicate()
+		return true
+	}
+	campaign := func() bool {
+		st.Term++
+		st.VotedFor, candidate, prevoting, votes = env.Name, true, false, 1
+		if !persist() {
+			return false
+		}
+		if votes >= quorum {
+			return lead()
+		}
+		i, t := last()
+		for _, p := range env.Peers {
+			send(p, msg{Type: "vote", LastIndex: i, LastTerm: t})
+		}
+		return true
+	}
+	stepDown := func(term uint64) {
+		if term > st.Term {
+			st.Term, st.VotedFor = term, ""
+			leader, candidate = false, false
+			persist()
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-election.C:
+			if leader {
+				continue
+			}
+			election.Reset(env.ElectionTimeout())
+			if prevoting, prevotes = true, 1; prevotes >= quorum {
+				if !campaign() {
+					return
+				}
+				continue
+			}
+			i, t := last()
+			for _, p := range env.Peers {
+				send(p, msg{Type: "prevote", LastIndex: i, LastTerm: t})
+			}
+		case <-heartbeat.C:
+			if leader {
+				replicate()
+			}
+		case data := <-props:
+			if leader {
+				st.Log = append(st.Log, Entry{st.Term, data})
+				if !persist() {
+					return
+				}
+				replicate()
+			}
+		case in := <-env.Mailbox.Inbox():
+			var m msg
+			if json.Unmarshal(in.Data, &m) != nil {
+				continue
+			}
+			if m.Type != "prevote" && m.Type != "prevoted" {
+				stepDown(m.Term)
+			}
+			switch m.Type {
+			case "prevote":
+				// Granting a pre-vote changes no state: m.From has yet
+				// to campaign.
+				i, t := last()
+				upToDate := m.LastTerm > t || m.LastTerm == t && m.LastIndex >= i
+				granted := m.Term >= st.Term && upToDate && !leader && time.Since(heard) >= 150*time.Millisecond
+				send(m.From, msg{Type: "prevoted", Granted: granted, Round: m.Term})
+			case "prevoted":
+				if prevoting && m.Round == st.Term && m.Granted {
+					if prevotes++; prevotes == quorum && !campaign() {
+						return
+					}
+				}
+			case "vote":
+				i, t := last()
+				upToDate := m.LastTerm > t || m.LastTerm == t && m.LastIndex >= i
+				granted := m.Term == st.Term && upToDate && (st.VotedFor == "" || st.VotedFor == m.From)
+				if granted {
+					st.VotedFor = m.From
+					if !persist() {
+						return
+					}
+					election.Reset(env.ElectionTimeout())
+				}
+				send(m.From, msg{Type: "voted", Granted: granted})
+			case "voted":
+				if candidate && m.Term == st.Term && m.Granted {
+					if votes++; votes == quorum && !lead() {
+						return
+					}
+				}
+			case "append":
+				if m.Term < st.Term {
+					send(m.From, msg{Type: "appended"})
+					break
+				}
+				candidate, prevoting, heard = false, false, time.Now()
+				election.Reset(env.ElectionTimeout())
+				if m.PrevIndex > len(st.Log) || m.PrevIndex > 0 && st.Log[m.PrevIndex-1].Term != m.PrevTerm {
+					send(m.From, msg{Type: "appended"})
+					break
+				}
+				for i, e := range m.Entries {
+					j := m.PrevIndex + i
+					if j < len(st.Log) && st.Log[j].Term != e.Term {
+						st.Log = st.Log[:j]
+					}
+					if j >= len(st.Log) {
+						st.Log = append(st.Log, e)
+					}
+				}
+				if !persist() {
+					return
+				}
+				n := m.PrevIndex + len(m.Entries)
+				commit = max(commit, min(m.Commit, n))
+				send(m.From, msg{Type: "appended", Success: true, Match: n})
+			case "appended":
+				if !leader || m.Term != st.Term {
+					break
+				}
+				if !m.Success {
+					next[m.From] = max(1, next[m.From]-1)
+					break
+				}
+				match[m.From] = max(match[m.From], m.Match)
+				next[m.From] = match[m.From] + 1
+				for n := len(st.Log); n > commit && st.Log[n-1].Term == st.Term; n-- {
+					acks := 1
+					for _, p := range env.Peers {
+						if match[p] >= n {
+							acks++
+						}
+					}
+					if acks >= quorum {
+						commit = n
+						break
+					}
+				}
+			}
+		}
+		env.Report(State{Term: st.Term, Leader: leader, Log: st.Log, Commit: commit})
+	}
+}
+
+func TestRaft(t *testing.T) {
+	sched.ExploreRandom(t, 10, func(t testing.TB) {
+		proposals := make(map[string]chan string)
+		c := New(t, Config{Nodes: 5}, raft(proposals, true))
+		defer c.Close()
+		leader, _ := c.AwaitLeader(5 * time.Second)
+		if leader == "" {
+			return
+		}
+		proposals[leader] <- "a"
+		time.Sleep(time.Second)
+		var minority, majority []string
+		for _, n := range c.Names() {
+			if n == leader || len(minority) < 1 {
+				minority = append(minority, n)
+			} else {
+				majority = append(majority, n)
+			}
+		}
+		c.Net.Partition(minority, majority)
+		proposals[leader] <- "lost"
+		time.Sleep(2 * time.Second)
+		leader, _ = c.AwaitLeader(5 * time.Second)
+		if leader == "" {
+			return
+		}
+		if slices.Contains(minority, leader) {
+			t.Errorf("leader %s elected in the minority %v", leader, minority)
+			return
+		}
+		proposals[leader] <- "b"
+		time.Sleep(time.Second)
+		c.Node(majority[0]).Restart(nil)
+		c.Net.Heal()
+		time.Sleep(3 * time.Second)
+		for _, n := range c.Names() {
+			s := c.State(n)
+			var data []string
+			for _, e := range s.Log[:s.Commit] {
+				if e.Data != "" {
+					data = append(data, e.Data)
+				}
+			}
+			if got := strings.Join(data, " "); got != "a b" {
+				t.Errorf("%s committed %q, want \"a b\"", n, got)
+			}
+		}
+	})
+}
+
+func TestElectionSafety(t *testing.T) {
+	rec := testtb.New(t)
+	synctest.Run(func() {
+		proposals := make(map[string]chan string)
+		c := New(rec, Config{Nodes: 3, Seed: 1}, raft(proposals, false))
+		defer c.Close()
+		c.Net.Partition([]string{"n1"}, []string{"n2"})
+		time.Sleep(time.Second)
+	})
+	if errs := rec.Errors(); len(errs) != 1 || !strings.HasPrefix(errs[0], "raftsim: election safety: ") {
+		t.Errorf("electing without a quorum reported %q, want an election safety violation", errs)
+	}
+}
+
+func TestLogMatching(t *testing.T) {
+	rec := testtb.New(t)
+	synctest.Run(func() {
+		envs := make(map[string]*Env)
+		c := New(rec, Config{Nodes: 2, Seed: 1}, func(env *Env) { envs[env.Name] = env })
+		defer c.Close()
+		envs["n1"].Report(State{Term: 2, Log: []Entry{{1, "a"}, {2, "b"}}})
+		envs["n2"].Report(State{Term: 2, Log: []Entry{{1, "a"}, {2, "b"}, {2, "c"}}})
+		envs["n2"].Report(State{Term: 2, Log: []Entry{{1, "x"}, {2, "b"}}})
+	})
+	want := fmt.Sprintf("raftsim: log matching: n2 and n1 have an entry of term 2 at index 2 but differ at index 1:\n\tn2: %v\n\tn1: %v",
+		[]Entry{{1, "x"}, {2, "b"}}, []Entry{{1, "a"}, {2, "b"}})
+	if errs := rec.Errors(); len(errs) != 1 || errs[0] != want {
+		t.Errorf("reported %q, want %q", errs, want)
+	}
+}
@@ -0,0 +1,227 @@
+// Package rpcfault injects gRPC failures into a server, with the precise
+// signatures retry and hedging policies of clients key on: a status code
+// and message, response headers that come late, and a stream reset in the
+// middle of a response, at chosen instants of virtual time.
+//
+// It speaks the wire protocol of gRPC over HTTP, and wraps any
+// http.Handler that does too, such as a grpc.Server through its ServeHTTP
+// method, served for instance on a simnet listener.
+package rpcfault
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Code is a gRPC status code.
+type Code int
+
+const (
+	OK Code = iota
+	Canceled
+	Unknown
+	InvalidArgument
+	DeadlineExceeded
+	NotFound
+	AlreadyExists
+	PermissionDenied
+	ResourceExhausted
+	FailedPrecondition
+	Aborted
+	OutOfRange
+	Unimplemented
+	Internal
+	Unavailable
+	DataLoss
+	Unauthenticated
+)
+
+// Fault is a failure of the calls it matches.
+type Fault struct {
+	Method string // a path.Match pattern of "/package.Service/Method"; "" for every one
+	// Call, if positive, limits the fault to the Call-th call it matches,
+	// counting from 1.
+	Call int
+	// From and Until bound the calls matched to those that start in
+	// [From, Until) after the Injector was made; Until 0 for no bound.
+	From, Until time.Duration
+
+	// HeaderDelay holds the response headers back that long.
+	HeaderDelay time.Duration
+	// Status, if not OK, fails the call with Message. Without Trailer it
+	// fails at once, with a trailers-only response, and the server never
+	// sees the call; with it, the server responds and the status replaces
+	// the one of its trailers.
+	Status  Code
+	Message string
+	Trailer bool
+	// Reset, if positive, resets the stream that long after the call
+	// started, in the middle of the response if the server is still
+	// sending it.
+	Reset time.Duration
+}
+
+type fault struct {
+	Fault
+	calls int
+}
+
+// Injector is an http.Handler that injects faults into the calls to the
+// handler it wraps. It is safe for concurrent use.
+type Injector struct {
+	h     http.Handler
+	start time.Time
+
+	mu     sync.Mutex
+	faults []*fault
+}
+
+// New returns an Injector wrapping h. The From and Until of faults count
+// from now.
+func New(h http.Handler) *Injector {
+	return &Injector{h: h, start: time.Now()}
+}
+
+// Inject adds f to the faults of in.
+func (in *Injector) Inject(f Fault) {
+	in.mu.Lock()
+	in.faults = append(in.faults, &fault{Fault: f})
+	in.mu.Unlock()
+}
+
+// Clear removes the faults of in.
+func (in *Injector) Clear() {
+	in.mu.Lock()
+	in.faults = nil
+	in.mu.Unlock()
+}
+
+// match returns the faults of a call of method, combined: the delays and
+// reset of all of them, and the status of the first one with a status.
+func (in *Injector) match(method string) Fault {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	now := time.Since(in.start)
+	var f Fault
+	for _, g := range in.faults {
+		if g.Method != "" {
+			if ok, _ := path.Match(g.Method, method); !ok {
+				continue
+			}
+		}
+		if now < g.From || g.Until > 0 && now >= g.Until {
+			continue
+		}
+		g.calls++
+		if g.Call > 0 && g.calls != g.Call {
+			continue
+		}
+		f.HeaderDelay += g.HeaderDelay
+		if f.Status == OK && g.Status != OK {
+			f.Status, f.Message, f.Trailer = g.Status, g.Message, g.Trailer
+		}
+		if g.Reset > 0 && (f.Reset == 0 || g.Reset < f.Reset) {
+			f.Reset = g.Reset
+		}
+	}
+	return f
+}
+
+func (in *Injector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f := in.match(r.URL.Path)
+	start := time.Now()
+	if f.HeaderDelay > 0 {
+		time.Sleep(f.HeaderDelay)
+	}
+	if f.Status != OK && !f.Trailer {
+		h := w.Header()
+		h.Set("Content-Type", "application/grpc")
+		h.Set("Grpc-Status", strconv.Itoa(int(f.Status)))
+		if f.Message != "" {
+			h.Set("Grpc-Message", f.Message)
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	sw := &writer{w: w}
+	if f.Reset > 0 {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			in.h.ServeHTTP(sw, r)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Until(start.Add(f.Reset))):
+			sw.reset()
+			// Aborts the response, with RST_STREAM in HTTP/2.
+			panic(http.ErrAbortHandler)
+		}
+	} else {
+		in.h.ServeHTTP(sw, r)
+	}
+	if f.Status != OK {
+		h := w.Header()
+		h.Del("Grpc-Status")
+		h.Del("Grpc-Message")
+		h.Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(f.Status)))
+		if f.Message != "" {
+			h.Set(http.TrailerPrefix+"Grpc-Message", f.Message)
+		}
+	}
+}
+
+// errReset is what the handler writes once its stream was reset.
+var errReset = errors.New("rpcfault: stream reset")
+
+// writer is the ResponseWriter of a handler, which stops writing once the
+// stream was reset.
+type writer struct {
+	mu     sync.Mutex
+	w      http.ResponseWriter
+	closed bool
+}
+
+func (w *writer) reset() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+}
+
+func (w *writer) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return make(http.Header)
+	}
+	return w.w.Header()
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errReset
+	}
+	return w.w.Write(p)
+}
+
+func (w *writer) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.w.WriteHeader(code)
+	}
+}
+
+func (w *writer) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if f, ok := w.w.(http.Flusher); ok && !w.closed {
+		f.Flush()
+	}
+}
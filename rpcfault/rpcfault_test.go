@@ -0,0 +1,109 @@
+package rpcfault
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// stream responds to every call with 5 messages, 100ms apart, and OK
+// trailers, as a server-streaming gRPC method would.
+var stream = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status")
+	for i := range 5 {
+		fmt.Fprintf(w, "msg %d;", i)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+	}
+	w.Header().Set("Grpc-Status", "0")
+})
+
+// serve serves in on a simulated network and returns a client of it.
+func serve(t *testing.T, in *Injector) (*http.Client, *simnet.Network) {
+	nw := simnet.New()
+	nw.Add("server", func(p *simnet.Proc) {
+		l, err := p.Listen("443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go (&http.Server{Handler: in}).Serve(l)
+	})
+	p := nw.Add("client", func(*simnet.Proc) {}).Proc()
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(context.Context, string, string) (net.Conn, error) { return p.Dial("server:443") },
+	}}, nw
+}
+
+type call struct {
+	status, message string // of the headers, or else the trailers
+	body            string
+	err             error
+	headers, end    time.Duration
+}
+
+func do(c *http.Client, method string) call {
+	start := time.Now()
+	resp, err := c.Post("http://server"+method, "application/grpc", strings.NewReader(""))
+	if err != nil {
+		return call{err: err}
+	}
+	defer resp.Body.Close()
+	res := call{headers: time.Since(start)}
+	body, err := io.ReadAll(resp.Body)
+	res.body, res.err, res.end = string(body), err, time.Since(start)
+	res.status, res.message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	if res.status == "" {
+		res.status, res.message = resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	}
+	return res
+}
+
+func TestFaults(t *testing.T) {
+	synctest.Run(func() {
+		in := New(stream)
+		c, nw := serve(t, in)
+		defer nw.Close()
+		in.Inject(Fault{Method: "/kv.KV/*", Call: 2, Status: Unavailable, Message: "overloaded"})
+		in.Inject(Fault{Method: "/kv.KV/Watch", From: time.Hour, HeaderDelay: 2 * time.Second})
+		if res := do(c, "/kv.KV/Get"); res.status != "0" || res.body != "msg 0;msg 1;msg 2;msg 3;msg 4;" || res.err != nil {
+			t.Errorf("first call %+v, want it to pass", res)
+		}
+		if res := do(c, "/kv.KV/Get"); res.status != "14" || res.message != "overloaded" || res.body != "" || res.headers != 0 {
+			t.Errorf("second call %+v, want Unavailable at once", res)
+		}
+		if res := do(c, "/kv.KV/Watch"); res.headers != 0 {
+			t.Errorf("call before From delayed %v", res.headers)
+		}
+		time.Sleep(time.Hour)
+		if res := do(c, "/kv.KV/Watch"); res.headers != 2*time.Second || res.status != "0" {
+			t.Errorf("call after From %+v, want its headers 2s late", res)
+		}
+	})
+}
+
+func TestTrailerAndReset(t *testing.T) {
+	synctest.Run(func() {
+		in := New(stream)
+		c, nw := serve(t, in)
+		defer nw.Close()
+		in.Inject(Fault{Call: 1, Status: DataLoss, Message: "checksum", Trailer: true})
+		if res := do(c, "/kv.KV/Watch"); res.status != "15" || res.message != "checksum" || !strings.HasPrefix(res.body, "msg 0;msg 1;msg 2;msg 3;msg 4;") {
+			t.Errorf("call %+v, want all messages then DataLoss in the trailers", res)
+		}
+		in.Clear()
+		in.Inject(Fault{Reset: 250 * time.Millisecond})
+		res := do(c, "/kv.KV/Watch")
+		if res.err == nil || res.body != "msg 0;msg 1;msg 2;" || res.end != 250*time.Millisecond {
+			t.Errorf("call %+v, want it reset after 3 messages at 250ms", res)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+// Package saga runs workflows of steps with compensating actions, sagas,
+// and tests them: a saga either applies every step or, once one fails,
+// compensates the steps it ran, in reverse, and Check asserts it ends up
+// fully applied or fully compensated whatever step boundary a failure
+// hits.
+//
+// A step may fail after its effect, as on a timeout, so a failed step is
+// compensated too, and its compensation must cope with the step not
+// having happened. A compensation that fails is retried, so it must be
+// idempotent.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// ErrInjected is the error of the steps and compensations Check fails.
+var ErrInjected = errors.New("saga: injected failure")
+
+// attempts is how many times a compensation runs at most.
+const attempts = 3
+
+// Action is a step or a compensation, acting on the world w.
+type Action[W any] func(ctx context.Context, w W) error
+
+// Saga is a workflow of steps on worlds of type W, built with New and
+// Step.
+type Saga[W any] struct {
+	name  string
+	steps []step[W]
+}
+
+type step[W any] struct {
+	name       string
+	do         Action[W]
+	compensate Action[W] // nil if there is nothing to undo
+}
+
+// New returns the saga name, without steps.
+func New[W any](name string) *Saga[W] {
+	return &Saga[W]{name: name}
+}
+
+// Step appends the step name, doing do and undone by compensate, nil if
+// there is nothing to undo, and returns s.
+func (s *Saga[W]) Step(name string, do, compensate Action[W]) *Saga[W] {
+	s.steps = append(s.steps, step[W]{name, do, compensate})
+	return s
+}
+
+// Run runs the steps of s on w in order. If one fails, it compensates it
+// and those before it, in reverse, retrying each compensation that fails,
+// and returns the error of the step; if a compensation fails every time,
+// it gives up, leaving w partly applied, and returns that error too.
+func (s *Saga[W]) Run(ctx context.Context, w W) error {
+	return s.run(ctx, w, nil)
+}
+
+// point is where Check fails a run: before or after the effect of a step
+// or of its compensation.
+type point struct {
+	step       int
+	compensate bool
+	after      bool
+}
+
+// run is Run, failing once at each of fail.
+func (s *Saga[W]) run(ctx context.Context, w W, fail []point) error {
+	act := func(p point, a Action[W]) error {
+		take := func(p point) bool {
+			for i, f := range fail {
+				if f == p {
+					fail = append(fail[:i:i], fail[i+1:]...)
+					return true
+				}
+			}
+			return false
+		}
+		if take(p) {
+			return ErrInjected
+		}
+		if err := a(ctx, w); err != nil {
+			return err
+		}
+		if p.after = true; take(p) {
+			return ErrInjected
+		}
+		return nil
+	}
+	for i, st := range s.steps {
+		err := act(point{step: i}, st.do)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("saga: %s: step %s: %w", s.name, st.name, err)
+		for j := i; j >= 0; j-- {
+			c := s.steps[j].compensate
+			if c == nil {
+				continue
+			}
+			var cerr error
+			for range attempts {
+				if cerr = act(point{step: j, compensate: true}, c); cerr == nil {
+					break
+				}
+			}
+			if cerr != nil {
+				return errors.Join(err, fmt.Errorf("saga: %s: compensating %s: %w", s.name, s.steps[j].name, cerr))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// describe describes the failures of fail.
+func (s *Saga[W]) describe(fail []point) string {
+	if len(fail) == 0 {
+		return "without failures"
+	}
+	var d string
+	for i, p := range fail {
+		if i > 0 {
+			d += ", then "
+		}
+		what := "step " + s.steps[p.step].name
+		if p.compensate {
+			what = "compensating " + s.steps[p.step].name
+		}
+		when := "before"
+		if p.after {
+			when = "after"
+		}
+		d += fmt.Sprintf("%s failing %s its effect", what, when)
+	}
+	return d
+}
+
+// Check runs s on worlds made by world: without failures, which must
+// succeed and end fully applied, in the state applied, then with each of
+// its steps failing before and after its effect and, for each, with each
+// compensation that runs failing once too, before and after its effect.
+// t fails at the first run of every step failure that does not end fully
+// compensated, in the state of a fresh world. States are state of the
+// world, compared formatted with %v.
+func (s *Saga[W]) Check(t testing.TB, world func() W, state func(W) any, applied any) {
+	t.Helper()
+	initial := fmt.Sprint(state(world()))
+	w := world()
+	if err := s.run(context.Background(), w, nil); err != nil {
+		t.Errorf("saga: %s: without failures: %v", s.name, err)
+		return
+	}
+	if got, want := fmt.Sprint(state(w)), fmt.Sprint(applied); got != want {
+		t.Errorf("saga: %s: without failures: state %s, want fully applied %s", s.name, got, want)
+	}
+	for i := range s.steps {
+		for _, after := range []bool{false, true} {
+			fail := []point{{step: i, after: after}}
+			fails := [][]point{fail}
+			for j := i; j >= 0; j-- {
+				if s.steps[j].compensate == nil {
+					continue
+				}
+				for _, cafter := range []bool{false, true} {
+					fails = append(fails, append(fail[:1:1], point{step: j, compensate: true, after: cafter}))
+				}
+			}
+			for _, fail := range fails {
+				if !s.check(t, world, state, fail, initial) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// check fails t unless running s with the failures of fail leaves the
+// world compensated, in the state want, and reports whether it did.
+func (s *Saga[W]) check(t testing.TB, world func() W, state func(W) any, fail []point, want string) bool {
+	t.Helper()
+	w := world()
+	s.run(context.Background(), w, fail)
+	if got := fmt.Sprint(state(w)); got != want {
+		t.Errorf("saga: %s: %s: state %s, want fully compensated %s", s.name, s.describe(fail), got, want)
+		return false
+	}
+	return true
+}
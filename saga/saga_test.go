@@ -0,0 +1,126 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// shop is the world of an order: the stock of its item, the balance of
+// its customer, and whether the item is reserved, paid for and shipped.
+type shop struct {
+	Stock, Balance             int
+	Reserved, Charged, Shipped bool
+}
+
+func newShop() *shop { return &shop{Stock: 1, Balance: 50} }
+
+func shopState(s *shop) any { return *s }
+
+func reserve(ctx context.Context, s *shop) error {
+	if s.Stock == 0 {
+		return errors.New("out of stock")
+	}
+	s.Stock--
+	s.Reserved = true
+	return nil
+}
+
+func release(ctx context.Context, s *shop) error {
+	if s.Reserved {
+		s.Stock++
+		s.Reserved = false
+	}
+	return nil
+}
+
+func charge(ctx context.Context, s *shop) error {
+	s.Balance -= 20
+	s.Charged = true
+	return nil
+}
+
+func refund(ctx context.Context, s *shop) error {
+	if s.Charged {
+		s.Balance += 20
+		s.Charged = false
+	}
+	return nil
+}
+
+func ship(ctx context.Context, s *shop) error {
+	s.Shipped = true
+	return nil
+}
+
+func recall(ctx context.Context, s *shop) error {
+	s.Shipped = false
+	return nil
+}
+
+// order builds the saga of an order, refunding with refund.
+func order(refund Action[*shop]) *Saga[*shop] {
+	return New[*shop]("order").
+		Step("reserve", reserve, release).
+		Step("charge", charge, refund).
+		Step("ship", ship, recall)
+}
+
+func TestRun(t *testing.T) {
+	s := newShop()
+	if err := order(refund).Run(context.Background(), s); err != nil || *s != (shop{0, 30, true, true, true}) {
+		t.Errorf("order: %+v, %v", *s, err)
+	}
+	s = &shop{Balance: 50}
+	err := order(refund).Run(context.Background(), s)
+	if want := "saga: order: step reserve: out of stock"; err == nil || err.Error() != want || *s != (shop{Balance: 50}) {
+		t.Errorf("order out of stock: %+v, %v; want nothing changed, %s", *s, err, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		refund Action[*shop]
+		want   []string
+	}{
+		{"guarded", refund, nil},
+		{"unguarded", func(ctx context.Context, s *shop) error {
+			s.Balance += 20
+			s.Charged = false
+			return nil
+		}, []string{
+			"saga: order: step charge failing before its effect: state {1 70 false false false}, want fully compensated {1 50 false false false}",
+			"saga: order: step charge failing after its effect, then compensating charge failing after its effect: state {1 70 false false false}, want fully compensated {1 50 false false false}",
+			"saga: order: step ship failing before its effect, then compensating charge failing after its effect: state {1 70 false false false}, want fully compensated {1 50 false false false}",
+			"saga: order: step ship failing after its effect, then compensating charge failing after its effect: state {1 70 false false false}, want fully compensated {1 50 false false false}",
+		}},
+		{"none", nil, []string{
+			"saga: order: step charge failing after its effect: state {1 30 false true false}, want fully compensated {1 50 false false false}",
+			"saga: order: step ship failing before its effect: state {1 30 false true false}, want fully compensated {1 50 false false false}",
+			"saga: order: step ship failing after its effect: state {1 30 false true false}, want fully compensated {1 50 false false false}",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := testtb.Run(t, func(t testing.TB) { order(tt.refund).Check(t, newShop, shopState, shop{0, 30, true, true, true}) })
+			if !slices.Equal(errs, tt.want) {
+				t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(errs, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestCheckApplied(t *testing.T) {
+	lazy := New[*shop]("lazy").
+		Step("reserve", func(context.Context, *shop) error { return nil }, release)
+	errs := testtb.Run(t, func(t testing.TB) { lazy.Check(t, newShop, shopState, shop{Stock: 0, Balance: 50, Reserved: true}) })
+	want := []string{"saga: lazy: without failures: state {1 50 false false false}, want fully applied {0 50 true false false}"}
+	if !slices.Equal(errs, want) {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
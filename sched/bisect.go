@@ -0,0 +1,95 @@
+package sched
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// Bookmark identifies a run: the seed of the numbers Rand draws and the
+// schedule, for Replay and Bisect.
+type Bookmark struct {
+	Seed     uint64
+	Schedule Schedule
+}
+
+// follow resumes the threads of a schedule in its order, as far as they
+// are ready, and then always the first ready one.
+type follow struct{ schedule Schedule }
+
+func (f follow) pick(n int, ready []*thread) int {
+	if n < len(f.schedule) {
+		for i, th := range ready {
+			if th.id == f.schedule[n].Chosen {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// Replay runs fn in a fresh bubble as in the run of b, as far as fn starts
+// and blocks its threads the same way. If t failed in the run, Replay
+// reports the schedule.
+func Replay(t testing.TB, b Bookmark, fn func()) Schedule {
+	t.Helper()
+	failed := t.Failed()
+	res := run(newScheduler(b.Seed, follow{b.Schedule}), fn)
+	if res.deadlock != "" {
+		t.Fatalf("sched: bubble deadlocked after schedule %v, replaying seed %d\n%s", res.schedule, b.Seed, res.deadlock)
+	}
+	if !failed && t.Failed() {
+		t.Errorf("sched: failed after schedule %v, replaying seed %d", res.schedule, b.Seed)
+	}
+	return res.schedule
+}
+
+// Bisection is the decision a failure hinges on.
+type Bisection struct {
+	Decision int      // index into the schedules
+	Failing  Bookmark // a failing run
+	// Passing is the run taking the same decisions before Decision, and
+	// from there on resuming the first ready thread.
+	Passing Bookmark
+}
+
+func (b Bisection) String() string {
+	f, p := b.Failing.Schedule[b.Decision], b.Passing.Schedule[b.Decision]
+	return fmt.Sprintf("decision %d of %v: resuming thread %d, waiting at %v, fails; resuming thread %d, waiting at %v, passes",
+		b.Decision, b.Failing.Schedule, f.Chosen, f.Point(), p.Chosen, p.Point())
+}
+
+// Bisect finds the decision of the failing run of b that makes it fail,
+// compared to the run that always resumes the first ready thread, which
+// has to pass: it replays ever longer prefixes of b's schedule, each
+// continued by resuming the first ready thread, to find by binary search
+// the shortest prefix that fails. fn reports failures on the t it is given,
+// which records them; deadlocks fail as well.
+func Bisect(t testing.TB, b Bookmark, fn func(t testing.TB)) (Bisection, error) {
+	t.Helper()
+	try := func(n int) (Schedule, bool) {
+		rec := testtb.New(t)
+		res := run(newScheduler(b.Seed, follow{b.Schedule[:n]}), func() { fn(rec) })
+		return res.schedule, res.deadlock != "" || rec.Failed()
+	}
+	lo, hi := 0, len(b.Schedule)
+	passing, failed := try(lo)
+	if failed {
+		return Bisection{}, errors.New("sched: the schedule resuming the first ready thread fails as well")
+	}
+	failing, failed := try(hi)
+	if !failed {
+		return Bisection{}, fmt.Errorf("sched: schedule %v with seed %d passes", b.Schedule, b.Seed)
+	}
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if s, failed := try(mid); failed {
+			hi, failing = mid, s
+		} else {
+			lo, passing = mid, s
+		}
+	}
+	return Bisection{Decision: lo, Failing: Bookmark{b.Seed, failing}, Passing: Bookmark{b.Seed, passing}}, nil
+}
@@ -0,0 +1,34 @@
+package sched
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestBisect(t *testing.T) {
+	var rep RandomReport
+	testtb.Run(t, func(t testing.TB) { rep = ExploreRandom(t, 20, readEarly) })
+	if len(rep.Failures) != 1 {
+		t.Fatalf("%+v", rep)
+	}
+	f := rep.Failures[0]
+	errs := testtb.Run(t, func(t testing.TB) { Replay(t, f.Bookmark, func() { readEarly(t) }) })
+	if len(errs) != 2 {
+		t.Errorf("replay: errors %q", errs)
+	}
+
+	b, err := Bisect(t, f.Bookmark, readEarly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `^decision 1 of \[0 2 1\]: resuming thread 2, waiting at go at .*/pct_test.go:14, fails; resuming thread 1, waiting at go at .*/pct_test.go:13, passes$`
+	if !regexp.MustCompile(want).MatchString(b.String()) {
+		t.Errorf("bisection %v, want %s", b, want)
+	}
+
+	if _, err := Bisect(t, Bookmark{Schedule: b.Passing.Schedule}, readEarly); err == nil {
+		t.Error("bisected a passing schedule")
+	}
+}
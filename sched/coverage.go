@@ -0,0 +1,53 @@
+package sched
+
+import "fmt"
+
+// Order is an ordering of two preemption points: two threads waited at
+// them at once and the one at First was resumed.
+type Order struct{ First, Then Point }
+
+// Coverage is the set of orderings of preemption points runs took, a
+// measure of how much of the interleavings an exploration saw.
+type Coverage struct {
+	Orders map[Order]int // runs taking each
+	// Growth is how many orderings were covered after each run. Once it
+	// flattens out, more runs of the same strategy are unlikely to find
+	// new interleavings.
+	Growth []int
+}
+
+// add records the orderings of a run.
+func (c *Coverage) add(s Schedule) {
+	if c.Orders == nil {
+		c.Orders = make(map[Order]int)
+	}
+	seen := make(map[Order]bool)
+	for _, d := range s {
+		first := d.Point()
+		for i, id := range d.Ready {
+			if o := (Order{first, d.At[i]}); id != d.Chosen && !seen[o] {
+				seen[o] = true
+				c.Orders[o]++
+			}
+		}
+	}
+	c.Growth = append(c.Growth, len(c.Orders))
+}
+
+// LastNew returns the number of the run, counting from 1, that covered
+// the last new ordering, or 0 if none did.
+func (c Coverage) LastNew() int {
+	for i := len(c.Growth) - 1; i >= 0; i-- {
+		if i == 0 || c.Growth[i] > c.Growth[i-1] {
+			if c.Growth[i] == 0 {
+				return 0
+			}
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (c Coverage) String() string {
+	return fmt.Sprintf("%d orderings of preemption points in %d runs, the last new one in run %d", len(c.Orders), len(c.Growth), c.LastNew())
+}
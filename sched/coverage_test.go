@@ -0,0 +1,28 @@
+package sched
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	rep := ExploreAll(t, 0, func(t testing.TB) {
+		Go("a", func() {})
+		Go("b", func() {})
+	})
+	// a before b, then b before a.
+	if len(rep.Coverage.Orders) != 2 || !reflect.DeepEqual(rep.Coverage.Growth, []int{1, 2}) || rep.Coverage.LastNew() != 2 {
+		t.Errorf("%v: %v", rep.Coverage, rep.Coverage.Orders)
+	}
+	for o, n := range rep.Coverage.Orders {
+		if o.First.Label != "go" || o.First == o.Then || n != 1 {
+			t.Errorf("ordering %v taken by %d runs", o, n)
+		}
+	}
+
+	// Threads started by the same call are not told apart.
+	fn, _ := order()
+	if rep := ExploreAll(t, 0, func(t testing.TB) { fn() }); len(rep.Coverage.Orders) != 1 || rep.Coverage.LastNew() != 1 {
+		t.Errorf("%v", rep.Coverage)
+	}
+}
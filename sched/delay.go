@@ -0,0 +1,89 @@
+package sched
+
+import (
+	"math/rand/v2"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/inject"
+)
+
+// Delays configures ExploreDelays.
+type Delays struct {
+	P   float64       // probability that an operation is delayed, default 0.5
+	Max time.Duration // longest delay, default 10ms
+}
+
+// delayer draws the delays of a run.
+type delayer struct {
+	Delays
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (d *delayer) next() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.r.Float64() >= d.P {
+		return 0
+	}
+	return time.Duration(1 + d.r.Int64N(int64(d.Max)))
+}
+
+func init() { inject.Set(Delay) }
+
+// Delay marks an operation whose timing a run of ExploreDelays varies: the
+// calling goroutine sleeps for a random while of virtual time. The
+// channels of chanx, the mutexes of syncx and the connections of
+// DelayConn are delayed before every operation. Outside such a run Delay
+// does nothing and costs little.
+func Delay() {
+	if running.Load() == 0 {
+		return
+	}
+	if th := current(); th != nil && th.s.free != nil {
+		th.sleep()
+		return
+	}
+	if s := bubbleScheduler(); s != nil && s.delays != nil {
+		if d := s.delays.next(); d > 0 {
+			time.Sleep(d)
+		}
+	}
+}
+
+// ExploreDelays is ExploreRandom with delays injected at every call of
+// Delay, drawn from the seed of the run as well, to flush out code that
+// only works when operations complete fast enough, or in the order they
+// were started.
+func ExploreDelays(t testing.TB, n int, d Delays, fn func(t testing.TB)) RandomReport {
+	t.Helper()
+	if d.P <= 0 {
+		d.P = 0.5
+	}
+	if d.Max <= 0 {
+		d.Max = 10 * time.Millisecond
+	}
+	return exploreSeeds(t, n, func(seed uint64) *scheduler {
+		s := newScheduler(seed, seeded(seed))
+		s.delays = &delayer{Delays: d, r: rand.New(rand.NewPCG(seed, 2))}
+		return s
+	}, fn)
+}
+
+// DelayConn returns c with Delay called before every Read and Write.
+func DelayConn(c net.Conn) net.Conn { return delayConn{c} }
+
+type delayConn struct{ net.Conn }
+
+func (c delayConn) Read(b []byte) (int, error) {
+	Delay()
+	return c.Conn.Read(b)
+}
+
+func (c delayConn) Write(b []byte) (int, error) {
+	Delay()
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,37 @@
+package sched
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/chanx"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// fastEnough expects a reply within a millisecond, as it usually comes.
+func fastEnough(t testing.TB) {
+	req, resp := chanx.Make[int]("req", 0), chanx.Make[int]("resp", 1)
+	Go("server", func() {
+		v, _ := req.Recv()
+		resp.Send(v + 1)
+	})
+	req.Send(1)
+	select {
+	case <-resp.C():
+	case <-time.After(time.Millisecond):
+		t.Error("no reply in time")
+	}
+}
+
+func TestExploreDelays(t *testing.T) {
+	if rep := ExploreRandom(t, 20, fastEnough); len(rep.Failures) != 0 {
+		t.Fatalf("without delays: %+v", rep)
+	}
+	var rep RandomReport
+	errs := testtb.Run(t, func(t testing.TB) { rep = ExploreDelays(t, 20, Delays{}, fastEnough) })
+	if len(rep.Failures) != 1 || !strings.Contains(errs[0], "no reply in time") {
+		t.Errorf("%+v, errors %q", rep, errs)
+	}
+	Delay()
+}
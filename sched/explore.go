@@ -0,0 +1,94 @@
+package sched
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/events"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// ExploreReport summarizes an exploration of schedules.
+type ExploreReport struct {
+	Schedules int // schedules run
+	Coverage  Coverage
+	// Truncated reports that some schedule took decisions beyond the
+	// bound, which were not varied.
+	Truncated bool
+}
+
+// prefix resumes the threads at the given indices into the ready ones,
+// then always the first, and records the choices taken and how many there
+// were.
+type prefix struct {
+	choices []int
+	taken   []int
+	widths  []int
+}
+
+func (p *prefix) pick(n int, ready []*thread) int {
+	i := 0
+	if n < len(p.choices) {
+		// A schedule may offer fewer choices than the one it is derived
+		// from if fn does not depend on its decisions alone.
+		i = min(p.choices[n], len(ready)-1)
+	}
+	p.taken = append(p.taken, i)
+	p.widths = append(p.widths, len(ready))
+	return i
+}
+
+// next returns the choices of the schedule after the one of p in
+// depth-first order among those that differ in the first maxSteps
+// decisions, or false if there is none.
+func (p *prefix) next(maxSteps int) ([]int, bool) {
+	for j := min(len(p.taken), maxSteps) - 1; j >= 0; j-- {
+		if p.taken[j]+1 < p.widths[j] {
+			return append(p.taken[:j:j], p.taken[j]+1), true
+		}
+	}
+	return nil, false
+}
+
+// ExploreAll runs fn in a fresh bubble once for every schedule of its
+// threads that differs in the first maxSteps decisions, a bound that keeps
+// small scenarios exhaustive and large ones finite; zero means no bound.
+// fn reports failures on the t it is given, which records them for its
+// schedule rather than failing the test. At the first schedule in which
+// fn fails or the bubble deadlocks, t fails with the failures and the
+// schedule, and ExploreAll stops. Rand draws the same numbers in every
+// schedule.
+func ExploreAll(t testing.TB, maxSteps int, fn func(t testing.TB)) ExploreReport {
+	t.Helper()
+	var rep ExploreReport
+	if maxSteps <= 0 {
+		maxSteps = int(^uint(0) >> 1)
+	}
+	p := new(prefix)
+	prog := startProgress(t, "", 0)
+	events.Emit(t, events.Event{Action: "start"})
+	defer func() {
+		prog.done(rep.Schedules, rep.Coverage, nil)
+		events.Emit(t, events.Event{Action: "done", Runs: rep.Schedules, Message: rep.Coverage.String()})
+	}()
+	for {
+		rec := testtb.New(t)
+		res := run(newScheduler(0, p), func() { fn(rec) })
+		rep.Schedules++
+		rep.Coverage.add(res.schedule)
+		prog.update(rep.Schedules, rep.Coverage, nil)
+		rep.Truncated = rep.Truncated || len(res.schedule) > maxSteps
+		if errs, _ := outcome(res, rec); len(errs) > 0 {
+			emitFailure(t, nil, res.schedule, rep.Schedules, errs)
+			t.Errorf("sched: schedule %d of the exploration failed with:\n\t%s\nafter schedule %v",
+				rep.Schedules, strings.ReplaceAll(strings.Join(errs, "\n"), "\n", "\n\t"), res.schedule)
+			return rep
+		}
+		choices, ok := p.next(maxSteps)
+		if !ok {
+			t.Logf("sched: %v", rep.Coverage)
+			return rep
+		}
+		p = &prefix{choices: choices}
+	}
+}
@@ -0,0 +1,61 @@
+package sched
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestExploreAll(t *testing.T) {
+	seen := make(map[string]bool)
+	fn, got := order()
+	rep := ExploreAll(t, 0, func(t testing.TB) {
+		fn()
+		seen[fmt.Sprint(got())] = true
+	})
+	if rep.Schedules != 6 || rep.Truncated || len(seen) != 6 {
+		t.Errorf("%+v, orders %v", rep, seen)
+	}
+	if rep := ExploreAll(t, 1, func(t testing.TB) { fn() }); rep.Schedules != 1 || !rep.Truncated {
+		t.Errorf("bounded to 1 decision: %+v", rep)
+	}
+}
+
+func TestExploreAllFindsOrder(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		ExploreAll(t, 0, func(t testing.TB) {
+			v := 0
+			Go("writer", func() { v = 1 })
+			Go("reader", func() {
+				if v != 1 {
+					t.Error("read before written")
+				}
+			})
+		})
+	})
+	want := []string{"sched: schedule 2 of the exploration failed with:\n\tread before written\nafter schedule [0 2 1]"}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
+
+func TestExploreAllFailedBefore(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		t.Error("earlier failure")
+		ExploreAll(t, 0, func(t testing.TB) {
+			v := 0
+			Go("writer", func() { v = 1 })
+			Go("reader", func() {
+				if v != 1 {
+					t.Error("read before written")
+				}
+			})
+		})
+	})
+	want := []string{"earlier failure", "sched: schedule 2 of the exploration failed with:\n\tread before written\nafter schedule [0 2 1]"}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
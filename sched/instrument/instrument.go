@@ -0,0 +1,388 @@
+// Package instrument inserts the preemption points of package sched into
+// Go source: before every statement that accesses shared memory it adds a
+// call of sched.Yield, so that explorations interleave the threads at
+// those accesses without the code under test being annotated by hand.
+//
+// An access is shared if it reads or writes a package-level variable, a
+// variable a function literal captures from an enclosing function, or
+// memory reached by selecting, indexing or dereferencing such a variable
+// or a parameter. The analysis is syntactic: without types, accesses
+// through other local variables are not seen, and some that are seen may
+// not be shared. Statements are only instrumented where a call can
+// precede them: not the conditions of for loops, nor the cases of select
+// and switch statements. The calls are inserted on the line of the
+// statement, so line numbers and the sites of the preemption points stay
+// those of the original source.
+package instrument
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SchedPath is the import path of package sched.
+const SchedPath = "github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+
+// importName is the name the instrumented files import package sched by.
+const importName = "schedyield"
+
+// Files instruments the files of one package, given by path, and returns
+// the contents of those it changed by path.
+func Files(paths []string) (map[string][]byte, error) {
+	fset := token.NewFileSet()
+	srcs := make([][]byte, len(paths))
+	files := make([]*ast.File, len(paths))
+	for i, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		srcs[i], files[i] = src, f
+	}
+	globals := Globals(files)
+	out := make(map[string][]byte)
+	for i, path := range paths {
+		if src, ok := file(fset, files[i], srcs[i], globals); ok {
+			out[path] = src
+		}
+	}
+	return out, nil
+}
+
+// Source instruments the source of a file of a package whose
+// package-level variables are globals, and reports whether it changed
+// anything.
+func Source(filename string, src []byte, globals map[string]bool) ([]byte, bool, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, false, err
+	}
+	out, ok := file(fset, f, src, globals)
+	return out, ok, nil
+}
+
+// Globals returns the names of the package-level variables declared in
+// files.
+func Globals(files []*ast.File) map[string]bool {
+	globals := make(map[string]bool)
+	for _, f := range files {
+		for _, d := range f.Decls {
+			if d, ok := d.(*ast.GenDecl); ok && d.Tok == token.VAR {
+				for _, s := range d.Specs {
+					for _, n := range s.(*ast.ValueSpec).Names {
+						globals[n.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return globals
+}
+
+// edit inserts text at offset off.
+type edit struct {
+	off  int
+	text string
+}
+
+type instrumenter struct {
+	fset    *token.FileSet
+	src     []byte
+	globals map[string]bool
+	funcs   []ast.Node // enclosing functions, innermost last
+	edits   []edit
+}
+
+// file instruments f, parsed from src.
+func file(fset *token.FileSet, f *ast.File, src []byte, globals map[string]bool) ([]byte, bool) {
+	in := &instrumenter{fset: fset, src: src, globals: globals}
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && d.Body != nil {
+			in.funcs = append(in.funcs, d)
+			in.stmts(d.Body.List)
+			in.funcs = in.funcs[:0]
+		}
+	}
+	if len(in.edits) == 0 {
+		return src, false
+	}
+	in.edits = append(in.edits, edit{in.offset(f.Name.End()), fmt.Sprintf("; import %s %q", importName, SchedPath)})
+	slices.SortStableFunc(in.edits, func(a, b edit) int { return a.off - b.off })
+	var b bytes.Buffer
+	last := 0
+	for _, e := range in.edits {
+		b.Write(src[last:e.off])
+		b.WriteString(e.text)
+		last = e.off
+	}
+	b.Write(src[last:])
+	return b.Bytes(), true
+}
+
+func (in *instrumenter) offset(p token.Pos) int { return in.fset.Position(p).Offset }
+
+func (in *instrumenter) stmts(list []ast.Stmt) {
+	for _, s := range list {
+		in.stmt(s, s)
+	}
+}
+
+// stmt instruments s, inserting its preemption point before at.
+func (in *instrumenter) stmt(s ast.Stmt, at ast.Node) {
+	switch s := s.(type) {
+	case *ast.AssignStmt, *ast.IncDecStmt, *ast.ExprStmt, *ast.SendStmt, *ast.ReturnStmt, *ast.DeclStmt:
+		in.yield(at, s)
+	case *ast.GoStmt:
+		in.yield(at, callArgs(s.Call)...)
+	case *ast.DeferStmt:
+		in.yield(at, callArgs(s.Call)...)
+	case *ast.LabeledStmt:
+		in.stmt(s.Stmt, at)
+	case *ast.BlockStmt:
+		in.stmts(s.List)
+	case *ast.IfStmt:
+		in.ifStmt(s, at)
+	case *ast.ForStmt:
+		in.yield(at, s.Init)
+		in.stmts(s.Body.List)
+	case *ast.RangeStmt:
+		in.yield(at, s.X)
+		in.stmts(s.Body.List)
+	case *ast.SwitchStmt:
+		in.yield(at, s.Init, s.Tag)
+		for _, c := range s.Body.List {
+			in.stmts(c.(*ast.CaseClause).Body)
+		}
+	case *ast.TypeSwitchStmt:
+		in.yield(at, s.Init, s.Assign)
+		for _, c := range s.Body.List {
+			in.stmts(c.(*ast.CaseClause).Body)
+		}
+	case *ast.SelectStmt:
+		for _, c := range s.Body.List {
+			in.stmts(c.(*ast.CommClause).Body)
+		}
+	}
+}
+
+// ifStmt instruments an if statement, with the conditions of the ones
+// following its else, which no call can precede.
+func (in *instrumenter) ifStmt(s *ast.IfStmt, at ast.Node) {
+	var header []ast.Node
+	for {
+		header = append(header, s.Init, s.Cond)
+		in.stmts(s.Body.List)
+		next, ok := s.Else.(*ast.IfStmt)
+		if !ok {
+			break
+		}
+		s = next
+	}
+	if s.Else != nil {
+		in.stmt(s.Else, s.Else)
+	}
+	in.yield(at, header...)
+}
+
+// callArgs returns what a go or defer statement evaluates at once.
+func callArgs(c *ast.CallExpr) []ast.Node {
+	nodes := []ast.Node{c.Fun}
+	for _, a := range c.Args {
+		nodes = append(nodes, a)
+	}
+	return nodes
+}
+
+// yield inserts a preemption point before at if nodes access shared
+// memory, and instruments the function literals in them.
+func (in *instrumenter) yield(at ast.Node, nodes ...ast.Node) {
+	var accesses []string
+	seen := make(map[string]bool)
+	add := func(op string, e ast.Expr) {
+		if a := op + " " + in.text(e); !seen[a] {
+			seen[a] = true
+			accesses = append(accesses, a)
+		}
+	}
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, l := range n.Lhs {
+				if in.shared(l) && (n.Tok != token.DEFINE || !isIdent(l)) {
+					add("write", l)
+				}
+				in.reads(add, inner(l)...)
+			}
+			for _, r := range n.Rhs {
+				in.reads(add, r)
+			}
+			continue
+		case *ast.IncDecStmt:
+			if in.shared(n.X) {
+				add("write", n.X)
+			}
+			in.reads(add, inner(n.X)...)
+			continue
+		}
+		in.reads(add, n)
+	}
+	if len(accesses) > 0 {
+		in.edits = append(in.edits, edit{in.offset(at.Pos()), fmt.Sprintf("%s.Yield(%s); ", importName, strconv.Quote(strings.Join(accesses, ", ")))})
+	}
+}
+
+// inner returns what an expression that is written to reads: the index
+// of an index expression, the pointer of a dereference.
+func inner(e ast.Expr) []ast.Node {
+	switch e := e.(type) {
+	case *ast.IndexExpr:
+		return append(inner(e.X), e.Index)
+	case *ast.StarExpr:
+		return []ast.Node{e.X}
+	case *ast.SelectorExpr:
+		return inner(e.X)
+	case *ast.ParenExpr:
+		return inner(e.X)
+	}
+	return nil
+}
+
+// reads reports the shared reads in nodes, outermost first, and
+// instruments the function literals in them.
+func (in *instrumenter) reads(add func(op string, e ast.Expr), nodes ...ast.Node) {
+	for _, n := range nodes {
+		if n != nil {
+			ast.Inspect(n, func(n ast.Node) bool { return in.read(n, add) })
+		}
+	}
+}
+
+func (in *instrumenter) read(n ast.Node, add func(op string, e ast.Expr)) bool {
+	switch n := n.(type) {
+	case *ast.FuncLit:
+		in.funcs = append(in.funcs, n)
+		in.stmts(n.Body.List)
+		in.funcs = in.funcs[:len(in.funcs)-1]
+		return false
+	case *ast.CallExpr:
+		// Calling a method reads nothing of its receiver by itself.
+		if sel, ok := n.Fun.(*ast.SelectorExpr); ok {
+			in.reads(add, inner(sel)...)
+			for _, a := range n.Args {
+				in.reads(add, a)
+			}
+			return false
+		}
+	case *ast.UnaryExpr:
+		// Neither does taking an address.
+		if n.Op == token.AND {
+			in.reads(add, inner(n.X)...)
+			return false
+		}
+	case *ast.KeyValueExpr:
+		// The keys of struct literals name fields.
+		if _, ok := n.Key.(*ast.Ident); ok {
+			in.reads(add, n.Value)
+			return false
+		}
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.StarExpr:
+		e := n.(ast.Expr)
+		if in.shared(e) {
+			add("read", e)
+			in.reads(add, inner(e)...)
+			return false
+		}
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			in.reads(add, sel.X)
+			return false
+		}
+	}
+	return true
+}
+
+// shared reports whether e accesses shared memory.
+func (in *instrumenter) shared(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return in.sharedVar(e)
+	case *ast.ParenExpr:
+		return in.shared(e.X)
+	case *ast.SelectorExpr, *ast.IndexExpr, *ast.StarExpr:
+		root := rootIdent(e)
+		return root != nil && (in.sharedVar(root) || in.param(root))
+	}
+	return false
+}
+
+// sharedVar reports whether id is a package-level variable or one
+// captured from an enclosing function.
+func (in *instrumenter) sharedVar(id *ast.Ident) bool {
+	if id.Obj == nil {
+		return in.globals[id.Name]
+	}
+	if id.Obj.Kind != ast.Var {
+		return false
+	}
+	d, ok := id.Obj.Decl.(ast.Node)
+	if !ok {
+		return false
+	}
+	fn := in.funcs[len(in.funcs)-1]
+	return d.Pos() < fn.Pos() || d.Pos() >= fn.End()
+}
+
+// param reports whether id is a parameter or receiver of the function it
+// is used in.
+func (in *instrumenter) param(id *ast.Ident) bool {
+	if id.Obj == nil || id.Obj.Kind != ast.Var {
+		return false
+	}
+	_, ok := id.Obj.Decl.(*ast.Field)
+	return ok
+}
+
+// rootIdent returns the variable e selects, indexes or dereferences, or
+// nil.
+func rootIdent(e ast.Expr) *ast.Ident {
+	for {
+		switch x := e.(type) {
+		case *ast.Ident:
+			return x
+		case *ast.SelectorExpr:
+			e = x.X
+		case *ast.IndexExpr:
+			e = x.X
+		case *ast.StarExpr:
+			e = x.X
+		case *ast.ParenExpr:
+			e = x.X
+		default:
+			return nil
+		}
+	}
+}
+
+func isIdent(e ast.Expr) bool {
+	_, ok := e.(*ast.Ident)
+	return ok
+}
+
+// text returns the source of n.
+func (in *instrumenter) text(n ast.Node) string {
+	return string(in.src[in.offset(n.Pos()):in.offset(n.End())])
+}
@@ -0,0 +1,39 @@
+package instrument
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestFiles(t *testing.T) {
+	out, err := Files([]string{"testdata/counter.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out["testdata/counter.go"]
+	if *update {
+		os.WriteFile("testdata/counter.go.golden", got, 0o644)
+	}
+	want, err := os.ReadFile("testdata/counter.go.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("instrumented\n%s\nwant\n%s", got, want)
+	}
+	src, _ := os.ReadFile("testdata/counter.go")
+	if bytes.Count(got, []byte("\n")) != bytes.Count(src, []byte("\n")) {
+		t.Error("instrumenting moved lines")
+	}
+}
+
+func TestSourceUnchanged(t *testing.T) {
+	src := []byte("package p\n\nfunc f(a, b int) int {\n\tc := a + b\n\treturn c\n}\n")
+	if out, changed, err := Source("p.go", src, nil); err != nil || changed || !bytes.Equal(out, src) {
+		t.Errorf("got %q, %v, %v; want the source unchanged", out, changed, err)
+	}
+}
@@ -0,0 +1,41 @@
+package counter
+
+import "sync"
+
+var total int
+
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) Add(d int) {
+	c.mu.Lock()
+	c.n += d
+	c.mu.Unlock()
+	total++
+}
+
+func Sum(cs []*Counter) int {
+	s := 0
+	for _, c := range cs {
+		s += c.n
+	}
+	return s
+}
+
+func Race() int {
+	v, local := 0, 1
+	done := make(chan bool)
+	go func() {
+		v = local
+		done <- true
+	}()
+	if v > 0 {
+		local = v
+	} else if total > 0 {
+		return total
+	}
+	<-done
+	return v + local
+}
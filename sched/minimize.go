@@ -0,0 +1,134 @@
+package sched
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// Preemptions counts the decisions of s that resume another thread than
+// the one resumed last, if that is ready, or else the first ready one:
+// the switches a reader of the schedule has to follow.
+func (s Schedule) Preemptions() int { return len(deviations(s)) }
+
+// deviations returns the threads s resumes, by decision, where they differ
+// from those sticky resumes.
+func deviations(s Schedule) map[int]int {
+	devs := make(map[int]int)
+	last := 0
+	for n, d := range s {
+		if !slices.Contains(d.Ready, last) {
+			last = d.Ready[0]
+		}
+		if d.Chosen != last {
+			devs[n] = d.Chosen
+		}
+		last = d.Chosen
+	}
+	return devs
+}
+
+// sticky resumes the thread resumed last, as long as it is ready, and then
+// the first ready one, except at the decisions of picks.
+type sticky struct {
+	picks map[int]int // thread to resume, by decision, if ready
+	last  int
+}
+
+func (st *sticky) pick(n int, ready []*thread) int {
+	i := slices.IndexFunc(ready, func(th *thread) bool { return th.id == st.last })
+	if id, ok := st.picks[n]; ok {
+		if j := slices.IndexFunc(ready, func(th *thread) bool { return th.id == id }); j >= 0 {
+			i = j
+		}
+	}
+	i = max(i, 0)
+	st.last = ready[i].id
+	return i
+}
+
+// minimizeRuns bounds the runs of the search for fewer preemptions.
+const minimizeRuns = 1000
+
+// Minimize returns a run failing like the one of b, with the same seed, in
+// a schedule with as few preemptions as it finds. It drops the preemptions
+// of the failing schedule, one at a time, replaying the rest, for as long
+// as the run still fails; then it searches the schedules with fewer
+// preemptions, fewest first, for another failing one, in up to 1000 runs.
+// fn reports failures on the t it is given, which records them; deadlocks
+// fail as well.
+func Minimize(t testing.TB, b Bookmark, fn func(t testing.TB)) (Bookmark, error) {
+	t.Helper()
+	m, ok := minimize(t, b, func(seed uint64) *scheduler { return newScheduler(seed, nil) }, fn, func(res result, rec *testtb.Recorder) bool { return res.deadlock != "" || rec.Failed() })
+	if !ok {
+		return b, fmt.Errorf("sched: schedule %v with seed %d passes", b.Schedule, b.Seed)
+	}
+	return m, nil
+}
+
+// minimize shrinks the preemptions of b as long as runs of fn with the
+// schedulers newScheduler returns fail as reported by failed, and reports
+// whether b's run does.
+func minimize(t testing.TB, b Bookmark, newScheduler func(seed uint64) *scheduler, fn func(t testing.TB), failed func(result, *testtb.Recorder) bool) (Bookmark, bool) {
+	runs := 0
+	try := func(picks map[int]int) (Schedule, bool) {
+		runs++
+		rec := testtb.New(t)
+		s := newScheduler(b.Seed)
+		s.strategy = &sticky{picks: picks}
+		res := run(s, func() { fn(rec) })
+		return res.schedule, failed(res, rec)
+	}
+	cur, ok := try(deviations(b.Schedule))
+	if !ok {
+		return b, false
+	}
+	for shrunk := true; shrunk; {
+		shrunk = false
+		devs := deviations(cur)
+		for _, n := range slices.Sorted(maps.Keys(devs)) {
+			picks := maps.Clone(devs)
+			delete(picks, n)
+			if s, ok := try(picks); ok && s.Preemptions() < len(devs) {
+				cur, shrunk = s, true
+				break
+			}
+		}
+	}
+
+	// search tries the schedules taking picks and up to k more preemptions
+	// from decision from on.
+	var search func(picks map[int]int, from, k int) (Schedule, bool)
+	search = func(picks map[int]int, from, k int) (Schedule, bool) {
+		if runs >= minimizeRuns {
+			return nil, false
+		}
+		s, ok := try(picks)
+		if ok || k == 0 {
+			return s, ok
+		}
+		for n := from; n < len(s); n++ {
+			for _, id := range s[n].Ready {
+				if id == s[n].Chosen {
+					continue
+				}
+				p := maps.Clone(picks)
+				p[n] = id
+				if s, ok := search(p, n+1, k-1); ok {
+					return s, true
+				}
+			}
+		}
+		return nil, false
+	}
+	for k := range cur.Preemptions() {
+		if s, ok := search(map[int]int{}, 0, k); ok {
+			cur = s
+			break
+		}
+	}
+	return Bookmark{b.Seed, cur}, true
+}
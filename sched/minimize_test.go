@@ -0,0 +1,51 @@
+package sched
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// lostUpdate fails if increments of three threads interleave. They
+// read and write atomically, for parallel runs to have no data race.
+func lostUpdate(t testing.TB) {
+	var n atomic.Int64
+	done := make(chan bool)
+	for i := range 3 {
+		Go(fmt.Sprint("inc", i), func() {
+			v := n.Load()
+			Yield("read")
+			n.Store(v + 1)
+			done <- true
+		})
+	}
+	for range 3 {
+		<-done
+	}
+	if n := n.Load(); n != 3 {
+		t.Errorf("n = %d after 3 increments", n)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	var rep RandomReport
+	testtb.Run(t, func(t testing.TB) { rep = ExploreRandom(t, 20, lostUpdate) })
+	if len(rep.Failures) == 0 {
+		t.Fatalf("%+v", rep)
+	}
+	// Losing one update takes one preemption, losing two takes two.
+	want := map[string]int{"n = 2 after 3 increments": 1, "n = 1 after 3 increments": 2}
+	for _, f := range rep.Failures {
+		if got := f.Minimal.Schedule.Preemptions(); got != want[f.Errors[0]] {
+			t.Errorf("%v with %d preemptions minimized to %v with %d, want %d", f.Schedule, f.Schedule.Preemptions(), f.Minimal.Schedule, got, want[f.Errors[0]])
+		}
+		if errs := testtb.Run(t, func(t testing.TB) { Replay(t, f.Minimal, func() { lostUpdate(t) }) }); len(errs) != 2 {
+			t.Errorf("replay of %v: errors %q", f.Minimal.Schedule, errs)
+		}
+	}
+	if _, err := Minimize(t, Bookmark{}, lostUpdate); err == nil {
+		t.Error("minimized a passing schedule")
+	}
+}
@@ -0,0 +1,65 @@
+package sched
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// pct is the strategy of probabilistic concurrency testing (Burckhardt et
+// al., ASPLOS 2010): threads get random priorities, distinct and at least
+// depth, when first ready, and the highest ready one always runs, except
+// that at depth-1 random decisions the one about to run drops to a
+// priority below all others, the lower the later the decision.
+type pct struct {
+	r      *rand.Rand
+	depth  int
+	prio   map[int]int // by thread
+	change map[int]int // decisions at which to lower the priority, to what
+}
+
+func newPCT(seed uint64, depth, steps int) *pct {
+	p := &pct{r: rand.New(rand.NewPCG(seed, 0)), depth: depth, prio: make(map[int]int), change: make(map[int]int)}
+	for i := 1; i < depth; i++ {
+		p.change[p.r.IntN(max(steps, 1))] = depth - i
+	}
+	return p
+}
+
+func (p *pct) pick(n int, ready []*thread) int {
+	for _, th := range ready {
+		if _, ok := p.prio[th.id]; !ok {
+			p.prio[th.id] = p.depth + p.r.IntN(1<<30)
+		}
+	}
+	best := p.highest(ready)
+	if low, ok := p.change[n]; ok {
+		p.prio[ready[best].id] = low
+		best = p.highest(ready)
+	}
+	return best
+}
+
+func (p *pct) highest(ready []*thread) int {
+	best := 0
+	for i, th := range ready {
+		if p.prio[th.id] > p.prio[ready[best].id] {
+			best = i
+		}
+	}
+	return best
+}
+
+// ExplorePCT is ExploreRandom with the schedules of probabilistic
+// concurrency testing. A bug of depth d, one that shows whenever d
+// orderings between the operations of the threads hold, is found by each
+// run with a depth of at least d with probability at least 1/(n·k^(d-1))
+// for n threads taking k decisions, much more often than by random
+// schedules for small d and large k. k is estimated by a first run, not
+// counted, in which the threads run in the order they were started.
+func ExplorePCT(t testing.TB, n, depth int, fn func(t testing.TB)) RandomReport {
+	t.Helper()
+	steps := len(run(newScheduler(0, new(prefix)), func() { fn(testtb.New(t)) }).schedule)
+	return exploreSeeds(t, n, func(seed uint64) *scheduler { return newScheduler(seed, newPCT(seed, depth, steps)) }, fn)
+}
@@ -0,0 +1,39 @@
+package sched
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// readEarly fails if the reader runs before the writer.
+func readEarly(t testing.TB) {
+	v := 0
+	Go("writer", func() { v = 1 })
+	Go("reader", func() {
+		if v != 1 {
+			t.Error("read before written")
+		}
+	})
+}
+
+func TestExplorePCT(t *testing.T) {
+	for _, depth := range []int{1, 3} {
+		var rep RandomReport
+		errs := testtb.Run(t, func(t testing.TB) { rep = ExplorePCT(t, 40, depth, readEarly) })
+		if len(rep.Failures) != 1 || rep.Failures[0].Runs == rep.Runs || len(errs) != 1 {
+			t.Errorf("depth %d: %+v, errors %q", depth, rep, errs)
+		}
+	}
+}
+
+func TestPCTReplays(t *testing.T) {
+	fn, _ := order()
+	for seed := range uint64(10) {
+		first := run(newScheduler(seed, newPCT(seed, 2, 4)), fn).schedule
+		if again := run(newScheduler(seed, newPCT(seed, 2, 4)), fn).schedule; !reflect.DeepEqual(again, first) {
+			t.Errorf("seed %d: schedule %v then %v", seed, first, again)
+		}
+	}
+}
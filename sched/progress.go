@@ -0,0 +1,125 @@
+package sched
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var progressFlag = flag.Bool("synctest.progress", false, "show the progress of explorations on the terminal")
+
+// progressEvery is how often the progress is redrawn, in real time.
+const progressEvery = 200 * time.Millisecond
+
+// progress shows how an exploration goes, redrawn in place on the
+// terminal: the runs made, how fast, the time left if the number of runs
+// is known, the growth of the coverage and the failures found so far with
+// their first seeds. It writes to /dev/tty, so that it shows even while go
+// test holds back the output of the test, or else to standard error.
+type progress struct {
+	w      io.Writer
+	what   string
+	total  int // runs to make, 0 if unknown
+	start  time.Time
+	drawn  time.Time
+	lines  int // of the last drawing
+	closer io.Closer
+}
+
+// startProgress returns the progress of an exploration of t, of total
+// runs, with what telling it apart from others of t if not empty, or nil
+// unless -synctest.progress is set.
+func startProgress(t testing.TB, what string, total int) *progress {
+	if !*progressFlag {
+		return nil
+	}
+	if what != "" {
+		what = " " + what
+	}
+	p := &progress{w: os.Stderr, what: t.Name() + what, total: total, start: time.Now()}
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		p.w, p.closer = tty, tty
+	}
+	return p
+}
+
+// update redraws p after runs runs, unless it was drawn recently.
+func (p *progress) update(runs int, cov Coverage, failures []Failure) {
+	if p == nil || time.Since(p.drawn) < progressEvery {
+		return
+	}
+	p.draw(runs, cov, failures)
+}
+
+// done draws p a last time and leaves it on the terminal.
+func (p *progress) done(runs int, cov Coverage, failures []Failure) {
+	if p == nil {
+		return
+	}
+	p.draw(runs, cov, failures)
+	if p.closer != nil {
+		p.closer.Close()
+	}
+}
+
+func (p *progress) draw(runs int, cov Coverage, failures []Failure) {
+	p.drawn = time.Now()
+	elapsed := p.drawn.Sub(p.start)
+	var b strings.Builder
+	if p.lines > 0 {
+		// Back to the start of the last drawing, and clear it.
+		fmt.Fprintf(&b, "\x1b[%dA\r\x1b[J", p.lines)
+	}
+	fmt.Fprintf(&b, "sched: %s: %d", p.what, runs)
+	if p.total > 0 {
+		fmt.Fprintf(&b, "/%d", p.total)
+	}
+	fmt.Fprintf(&b, " runs in %v", elapsed.Round(time.Second))
+	if rate := float64(runs) / elapsed.Seconds(); runs > 0 {
+		fmt.Fprintf(&b, ", %.1f runs/s", rate)
+		if left := p.total - runs; p.total > 0 && left > 0 {
+			fmt.Fprintf(&b, ", ETA %v", time.Duration(float64(left)/rate*float64(time.Second)).Round(time.Second))
+		}
+	}
+	fmt.Fprintf(&b, "\n  coverage: %d orderings, the last new one in run %d %s\n", len(cov.Orders), cov.LastNew(), sparkline(cov.Growth, 30))
+	fmt.Fprintf(&b, "  failures: %d\n", len(failures))
+	lines := 3
+	for i, f := range failures {
+		if i == 5 {
+			fmt.Fprintf(&b, "    and %d more\n", len(failures)-i)
+			lines++
+			break
+		}
+		first, _, _ := strings.Cut(strings.Join(f.Errors, "\n"), "\n")
+		fmt.Fprintf(&b, "    %d runs, first with seed %d: %s\n", f.Runs, f.Seed, first)
+		lines++
+	}
+	p.lines = lines
+	io.WriteString(p.w, b.String())
+}
+
+// sparkline draws vs, which grow, in at most width bars scaled to the
+// last value.
+func sparkline(vs []int, width int) string {
+	if len(vs) == 0 {
+		return ""
+	}
+	// One bar for every step of runs.
+	step := (len(vs) + width - 1) / width
+	top := vs[len(vs)-1]
+	bars := []rune("▁▂▃▄▅▆▇█")
+	var b strings.Builder
+	for i := step - 1; i < len(vs)+step-1; i += step {
+		v := vs[min(i, len(vs)-1)]
+		j := 0
+		if top > 0 {
+			j = v * (len(bars) - 1) / top
+		}
+		b.WriteRune(bars[j])
+	}
+	return b.String()
+}
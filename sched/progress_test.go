@@ -0,0 +1,35 @@
+package sched
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressDraw(t *testing.T) {
+	var b strings.Builder
+	p := &progress{w: &b, what: "TestX", total: 100, start: time.Now().Add(-10 * time.Second)}
+	cov := Coverage{Orders: map[Order]int{{}: 1}, Growth: []int{0, 1, 1}}
+	fails := []Failure{{Bookmark: Bookmark{Seed: 7}, Errors: []string{"read before written\nmore"}, Runs: 2}}
+	p.draw(50, cov, fails)
+	first := b.String()
+	for _, want := range []string{
+		"sched: TestX: 50/100 runs in 10s, 5.0 runs/s, ETA 10s\n",
+		"coverage: 1 orderings, the last new one in run 2 ▁██\n",
+		"failures: 1\n    2 runs, first with seed 7: read before written\n",
+	} {
+		if !strings.Contains(first, want) {
+			t.Errorf("drawing lacks %q:\n%s", want, first)
+		}
+	}
+	p.draw(60, cov, fails)
+	if redraw := strings.TrimPrefix(b.String(), first); !strings.HasPrefix(redraw, "\x1b[4A\r\x1b[J") {
+		t.Errorf("redrawing %q does not clear the 4 lines drawn", redraw)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]int{0, 2, 4, 7, 7, 7}, 3); got != "▃██" {
+		t.Errorf("sparkline %q", got)
+	}
+}
@@ -0,0 +1,129 @@
+package sched
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/events"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/racelog"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// Race is a data race found by ExploreRaces, in the runs it occurred in.
+type Race struct {
+	racelog.Report // as first reported
+	Seeds          []uint64
+}
+
+// RaceReport summarizes the runs of ExploreRaces.
+type RaceReport struct {
+	Runs  int
+	Races []Race // in the order they were first seen
+}
+
+// runMarker precedes the output of a run of a child process.
+const runMarker = "sched: run with seed "
+
+// ExploreRaces runs fn n times in fresh bubbles under the race detector
+// and reports each data race found once, with the seeds of the runs it was
+// found in, rather than the race detector failing the test with its first
+// report. The runs take place in a child process running only the calling
+// test, from whose standard error the reports are collected; the test
+// binary must be built with -race, else ExploreRaces skips the test.
+//
+// For the race detector to see races, which it only does between
+// accesses that are not ordered by synchronization, the threads are not
+// scheduled one at a time: they run at once, and each Yield and Delay
+// sleeps for a random while of virtual time as configured by d and drawn
+// from the seed, to vary the order of the accesses around them. The
+// failures fn reports are ignored; ExploreRandom finds them. Starting a
+// thread orders what the goroutine starting it did before after the
+// preemption points reached later. With -synctest.seed only that seed is
+// run.
+func ExploreRaces(t testing.TB, n int, d Delays, fn func(t testing.TB)) RaceReport {
+	t.Helper()
+	if d.P <= 0 {
+		d.P = 0.5
+	}
+	if d.Max <= 0 {
+		d.Max = 10 * time.Millisecond
+	}
+	if !racelog.Enabled {
+		t.Skip("sched: ExploreRaces needs the race detector; run with -race")
+	}
+	seed := Seed()
+	if *seedFlag != "" {
+		n = 1
+	}
+	out, ok := racelog.Fork(t, fmt.Sprint(seed), func(arg string) {
+		first, _ := strconv.ParseUint(arg, 10, 64)
+		for i := range uint64(n) {
+			fmt.Fprintf(os.Stderr, "\n%s%d\n", runMarker, first+i)
+			s := newScheduler(first+i, nil)
+			s.free = &d
+			run(s, func() { fn(testtb.New(t)) })
+		}
+	})
+	if !ok {
+		return RaceReport{}
+	}
+	runs := strings.Split(out, "\n"+runMarker)
+	if len(runs) != n+1 {
+		t.Fatalf("sched: the runs of ExploreRaces did not complete:\n%s", out)
+	}
+	rep := RaceReport{Runs: n}
+	byKey := make(map[string]int)
+	for _, r := range runs[1:] {
+		line, _, _ := strings.Cut(r, "\n")
+		seed, _ := strconv.ParseUint(line, 10, 64)
+		for _, race := range racelog.Parse(r) {
+			if i, ok := byKey[race.Key()]; ok {
+				rep.Races[i].Seeds = append(rep.Races[i].Seeds, seed)
+				continue
+			}
+			byKey[race.Key()] = len(rep.Races)
+			rep.Races = append(rep.Races, Race{race, []uint64{seed}})
+		}
+	}
+	for _, r := range rep.Races {
+		var sites []string
+		for _, a := range r.Accesses {
+			f := a.Site()
+			sites = append(sites, fmt.Sprintf("%s at %s:%d", a.Op, f.File, f.Line))
+		}
+		events.Emit(t, events.Event{Action: "fail", Seed: events.Seed(r.Seeds[0]), Runs: len(r.Seeds), Message: "data race between " + strings.Join(sites, " and ")})
+		t.Errorf("sched: data race between %s in %d of %d runs, first with seed %d; replay with -synctest.seed=%d\n%s",
+			strings.Join(sites, " and "), len(r.Seeds), rep.Runs, r.Seeds[0], r.Seeds[0], r.Text)
+	}
+	return rep
+}
+
+// startFree starts f as a thread of a free scheduler, which runs at once
+// and draws from a source derived from its starter's.
+func (s *scheduler) startFree(f func()) {
+	r := s.rand
+	if parent := current(); parent != nil {
+		r = rand.New(rand.NewPCG(parent.r.Uint64(), parent.r.Uint64()))
+	}
+	th := &thread{s: s, r: r}
+	go func() {
+		// Left among the threads until the run ends, since removing it
+		// would order what it did before the preemption points after.
+		setThreads(th, goid.ID())
+		f()
+	}()
+}
+
+// sleep is a preemption point of a thread of a free scheduler.
+func (th *thread) sleep() {
+	d := th.s.free
+	if th.r.Float64() < d.P {
+		time.Sleep(time.Duration(1 + th.r.Int64N(int64(d.Max))))
+	}
+}
@@ -0,0 +1,37 @@
+package sched
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/racelog"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestExploreRaces(t *testing.T) {
+	if !racelog.Enabled {
+		t.Skip("needs -race")
+	}
+	var rep RaceReport
+	errs := testtb.Run(t, func(t testing.TB) {
+		rep = ExploreRaces(t, 5, Delays{}, func(t testing.TB) {
+			v := 0
+			Go("writer", func() { v = 1 })
+			Go("reader", func() {
+				Yield("read")
+				_ = v
+			})
+		})
+	})
+	if rep.Runs != 5 || len(rep.Races) != 1 || len(errs) != 1 {
+		t.Fatalf("%+v, errors %q", rep, errs)
+	}
+	for _, want := range []string{"race_test.go:19", "race_test.go:22"} {
+		if !strings.Contains(errs[0], want) {
+			t.Errorf("got %q, want it to contain %q", errs[0], want)
+		}
+	}
+	if len(rep.Races[0].Seeds) != 5 {
+		t.Errorf("race found with seeds %v, want in all 5 runs", rep.Races[0].Seeds)
+	}
+}
@@ -0,0 +1,113 @@
+package sched
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/events"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// Failure is a way runs failed.
+type Failure struct {
+	Bookmark          // the first run failing so
+	Minimal  Bookmark // a run failing so with the fewest preemptions found
+	Errors   []string // what it reported, and its deadlock
+	Runs     int      // runs failing so
+}
+
+// RandomReport summarizes the runs of ExploreRandom.
+type RandomReport struct {
+	Runs     int
+	Failures []Failure // in the order they were first seen
+	Coverage Coverage
+}
+
+// ExploreRandom runs fn in n fresh bubbles, each scheduled at random from
+// its own seed, the first being Seed. fn reports failures on the t it is
+// given, which records them rather than failing the test. Runs whose
+// failures read the same, or that deadlock with the same goroutines
+// blocked at the same lines, fail the same way. Once all runs are done, t
+// fails once for each way, with the first seed exhibiting it, which Run
+// replays when given as -synctest.seed; with the flag ExploreRandom only
+// runs that seed. Each way is reported with the schedule Minimize shrinks
+// its first run to, failing the same way, for Replay. The coverage of the
+// runs is logged.
+func ExploreRandom(t testing.TB, n int, fn func(t testing.TB)) RandomReport {
+	t.Helper()
+	return exploreSeeds(t, n, func(seed uint64) *scheduler { return newScheduler(seed, seeded(seed)) }, fn)
+}
+
+// exploreSeeds runs fn n times with the schedulers newScheduler derives
+// from the seeds and reports the failures.
+func exploreSeeds(t testing.TB, n int, newScheduler func(seed uint64) *scheduler, fn func(t testing.TB)) RandomReport {
+	t.Helper()
+	var rep RandomReport
+	seed := Seed()
+	if *seedFlag != "" {
+		n = 1
+	}
+	byErrors := make(map[string]int)
+	var sigs []string // of the failures
+	prog := startProgress(t, "", n)
+	events.Emit(t, events.Event{Action: "start", Seed: events.Seed(seed), Runs: n})
+	for i := range uint64(n) {
+		rec := testtb.New(t)
+		res := run(newScheduler(seed+i), func() { fn(rec) })
+		rep.Runs++
+		rep.Coverage.add(res.schedule)
+		errs, sig := outcome(res, rec)
+		if len(errs) > 0 {
+			emitFailure(t, events.Seed(seed+i), res.schedule, int(i)+1, errs)
+		}
+		if j, ok := byErrors[sig]; ok && len(errs) > 0 {
+			rep.Failures[j].Runs++
+		} else if len(errs) > 0 {
+			byErrors[sig] = len(rep.Failures)
+			sigs = append(sigs, sig)
+			rep.Failures = append(rep.Failures, Failure{Bookmark{seed + i, res.schedule}, Bookmark{}, errs, 1})
+		}
+		prog.update(rep.Runs, rep.Coverage, rep.Failures)
+	}
+	prog.done(rep.Runs, rep.Coverage, rep.Failures)
+	events.Emit(t, events.Event{Action: "done", Runs: rep.Runs, Failures: len(rep.Failures), Message: rep.Coverage.String()})
+	for i := range rep.Failures {
+		f := &rep.Failures[i]
+		f.Minimal, _ = minimize(t, f.Bookmark, newScheduler, fn, func(res result, rec *testtb.Recorder) bool {
+			_, s := outcome(res, rec)
+			return s == sigs[i]
+		})
+	}
+	t.Logf("sched: %v", rep.Coverage)
+	for _, f := range rep.Failures {
+		t.Errorf("sched: %d of %d runs failed with:\n\t%s\nfirst with seed %d after schedule %v; replay with -synctest.seed=%d\nminimized to schedule %v with %d preemptions",
+			f.Runs, rep.Runs, strings.ReplaceAll(strings.Join(f.Errors, "\n"), "\n", "\n\t"), f.Seed, f.Schedule, f.Seed, f.Minimal.Schedule, f.Minimal.Schedule.Preemptions())
+	}
+	return rep
+}
+
+// emitFailure emits the event of run failing with errs, with its seed and
+// schedule unless nil.
+func emitFailure(t testing.TB, seed *uint64, s Schedule, run int, errs []string) {
+	if !events.Enabled() {
+		return
+	}
+	t.Helper()
+	msg, _, _ := strings.Cut(strings.Join(errs, "\n"), "\n")
+	e := events.Event{Action: "fail", Seed: seed, Run: run, Message: msg}
+	if s != nil {
+		e.Schedule = s.String()
+	}
+	events.Emit(t, e)
+}
+
+// outcome returns the failures of a run, with its deadlock, and a
+// signature that is the same for runs failing the same way.
+func outcome(res result, rec *testtb.Recorder) (errs []string, sig string) {
+	errs, sig = rec.Errors(), strings.Join(rec.Errors(), "\n")
+	if res.deadlock != "" {
+		errs = append(errs, "bubble deadlocked\n"+strings.TrimSuffix(res.deadlock, "\n"))
+		sig += "\ndeadlock\n" + res.stuck
+	}
+	return errs, sig
+}
@@ -0,0 +1,41 @@
+package sched
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestExploreRandom(t *testing.T) {
+	var rep RandomReport
+	errs := testtb.Run(t, func(t testing.TB) {
+		rep = ExploreRandom(t, 50, func(t testing.TB) {
+			v := 0
+			ch := make(chan int)
+			Go("writer", func() { v = 1 })
+			Go("reader", func() {
+				if v != 1 {
+					t.Error("read before written")
+				}
+				if Rand().IntN(2) == 0 {
+					<-ch
+				}
+			})
+		})
+	})
+	// Reading early, and the reader blocking for good, in any combination.
+	if rep.Runs != 50 || len(rep.Failures) != 3 || len(errs) != 3 {
+		t.Fatalf("%+v, errors %q", rep, errs)
+	}
+	total := 0
+	for i, f := range rep.Failures {
+		total += f.Runs
+		if !strings.Contains(errs[i], "runs failed with:") || !strings.Contains(errs[i], "replay with -synctest.seed=") {
+			t.Errorf("error %q", errs[i])
+		}
+	}
+	if total >= 50 {
+		t.Errorf("every run failed: %+v", rep)
+	}
+}
@@ -0,0 +1,77 @@
+package sched
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/events"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// Verdict tells how a test run by Rerun failed.
+type Verdict int
+
+const (
+	Passed            Verdict = iota // the first run passed, so there were no reruns
+	Deterministic                    // every run failed
+	ScheduleDependent                // some reruns passed
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Passed:
+		return "passed"
+	case Deterministic:
+		return "deterministic"
+	case ScheduleDependent:
+		return "schedule-dependent"
+	}
+	return fmt.Sprintf("Verdict(%d)", int(v))
+}
+
+// RerunReport is how the runs of Rerun went.
+type RerunReport struct {
+	Verdict Verdict
+	Runs    int
+	Failing []uint64 // seeds of the runs that failed, the first one first
+}
+
+// Rerun runs fn in a fresh bubble scheduled at random from Seed, like
+// Run, and if it fails, reruns it with the k next seeds to tell whether
+// the failure is deterministic, failing on every schedule, or depends on
+// the schedule. t then fails with the failures of the first run, the
+// verdict and the seeds of the runs that failed, each of which
+// -synctest.seed replays.
+func Rerun(t testing.TB, k int, fn func(t testing.TB)) RerunReport {
+	t.Helper()
+	seed := Seed()
+	try := func(seed uint64) ([]string, Schedule) {
+		rec := testtb.New(t)
+		res := run(newScheduler(seed, seeded(seed)), func() { fn(rec) })
+		errs, _ := outcome(res, rec)
+		return errs, res.schedule
+	}
+	errs, schedule := try(seed)
+	rep := RerunReport{Runs: 1}
+	if len(errs) == 0 {
+		return rep
+	}
+	emitFailure(t, events.Seed(seed), schedule, 1, errs)
+	rep.Failing = []uint64{seed}
+	for i := range uint64(k) {
+		rep.Runs++
+		if errs, s := try(seed + 1 + i); len(errs) > 0 {
+			emitFailure(t, events.Seed(seed+1+i), s, rep.Runs, errs)
+			rep.Failing = append(rep.Failing, seed+1+i)
+		}
+	}
+	rep.Verdict = ScheduleDependent
+	if len(rep.Failing) == rep.Runs {
+		rep.Verdict = Deterministic
+	}
+	events.Emit(t, events.Event{Action: "done", Runs: rep.Runs, Failures: len(rep.Failing), Message: "failure is " + rep.Verdict.String()})
+	t.Errorf("sched: failed with seed %d after schedule %v with:\n\t%s\nfailure is %v: %d of %d runs failed, with seeds %v; replay with -synctest.seed=%d",
+		seed, schedule, strings.ReplaceAll(strings.Join(errs, "\n"), "\n", "\n\t"), rep.Verdict, len(rep.Failing), rep.Runs, rep.Failing, seed)
+	return rep
+}
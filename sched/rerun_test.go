@@ -0,0 +1,55 @@
+package sched
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestRerun(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		fn      func(t testing.TB)
+		verdict Verdict
+	}{
+		{"passing", func(t testing.TB) {}, Passed},
+		{"deterministic", func(t testing.TB) { t.Error("always") }, Deterministic},
+		{"schedule-dependent", func(t testing.TB) {
+			v := 0
+			Go("writer", func() { v = 1 })
+			Go("reader", func() {
+				if v != 1 {
+					t.Error("read before written")
+				}
+			})
+		}, ScheduleDependent},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var rep RerunReport
+			// Seeds until the first run fails, unless it never does.
+			for range 20 {
+				errs := testtb.Run(t, func(t testing.TB) { rep = Rerun(t, 20, tc.fn) })
+				if rep.Verdict == Passed {
+					if len(errs) != 0 || rep.Runs != 1 {
+						t.Fatalf("passing run: %+v, errors %q", rep, errs)
+					}
+					continue
+				}
+				if len(errs) != 1 || !strings.Contains(errs[0], "failure is "+tc.verdict.String()) {
+					t.Fatalf("errors %q, want one with verdict %v", errs, tc.verdict)
+				}
+				break
+			}
+			if rep.Verdict != tc.verdict {
+				t.Fatalf("verdict %v, want %v: %+v", rep.Verdict, tc.verdict, rep)
+			}
+			if tc.verdict == Passed {
+				return
+			}
+			if rep.Runs != 21 || len(rep.Failing) == 0 || (tc.verdict == Deterministic) != (len(rep.Failing) == 21) {
+				t.Errorf("%+v", rep)
+			}
+		})
+	}
+}
@@ -0,0 +1,489 @@
+// Package sched decides the order in which the goroutines of a synctest
+// bubble run, so that a schedule is chosen by the test rather than by the
+// runtime, and can be repeated.
+//
+// Goroutines started with Go are threads of the scheduler. A thread only
+// runs once the scheduler resumes it: when it starts, and after each
+// preemption point, a call of Yield. Whenever every goroutine of the
+// bubble is blocked, the scheduler resumes one of the threads waiting at
+// a preemption point, as its strategy picks. What the threads do between
+// two preemption points, and goroutines started with a plain go
+// statement, are left to the runtime, so the threads should block on
+// channels, which the bubble can tell apart from running: a goroutine
+// blocked on a sync.Mutex keeps synctest.Wait, and thus the scheduler,
+// waiting. syncx.Mutex blocks on a channel. The threads must not call
+// synctest.Wait themselves.
+//
+// A run is derived from one seed: the scheduler's choices and the numbers
+// Rand draws. Run prints the seed of a failing run, and the flag
+// -synctest.seed makes Run use the given one to replay it.
+//
+// With -synctest.progress, the explorations show on the terminal how they
+// go while they run: the runs made and the time left, the growth of the
+// coverage, and the failures found with their seeds. With
+// -synctest.events=file, they append their starts, failing runs and ends
+// to file as JSON objects, one per line, for CI systems to read.
+package sched
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/bubble"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/timeline"
+)
+
+var seedFlag = flag.String("synctest.seed", "", "seed of the runs of sched.Run, to replay a failing one")
+
+// Decision is one choice of the scheduler: which of the threads waiting
+// at a preemption point it resumed. Threads are numbered in the order they
+// were started, the one running the function of the run being 0.
+type Decision struct {
+	Ready  []int   // the threads waiting, ascending
+	At     []Point // where each of them waits
+	Chosen int
+}
+
+// Point returns where the chosen thread waited.
+func (d Decision) Point() Point {
+	return d.At[slices.Index(d.Ready, d.Chosen)]
+}
+
+// Point is a preemption point.
+type Point struct {
+	Label string // "go" at the start of a thread, else the label of its Yield
+	Site  string // file:line of the call of Go or Yield, empty for thread 0
+}
+
+func (p Point) String() string {
+	if p.Site == "" {
+		return p.Label
+	}
+	return p.Label + " at " + p.Site
+}
+
+// Schedule is the sequence of decisions of a run.
+type Schedule []Decision
+
+func (s Schedule) String() string {
+	ids := make([]string, len(s))
+	for i, d := range s {
+		ids[i] = strconv.Itoa(d.Chosen)
+	}
+	return "[" + strings.Join(ids, " ") + "]"
+}
+
+// strategy picks the thread to resume among ready, the threads waiting,
+// ascending by id; n is the number of decisions taken before.
+type strategy interface {
+	pick(n int, ready []*thread) int
+}
+
+// random picks uniformly.
+type random struct{ r *rand.Rand }
+
+func seeded(seed uint64) random { return random{rand.New(rand.NewPCG(seed, 0))} }
+
+func (s random) pick(_ int, ready []*thread) int { return s.r.IntN(len(ready)) }
+
+type scheduler struct {
+	strategy strategy
+	seed     uint64        // of the run
+	rand     *rand.Rand    // for Rand
+	delays   *delayer      // for Delay, nil if not injecting delays
+	free     *Delays       // if set, threads run at once, see ExploreRaces
+	parallel int           // threads resumed at once, if more than 1
+	wake     chan struct{} // signaled when a thread waits or returns
+
+	mu       sync.Mutex // guards the fields below
+	threads  int        // threads started
+	live     int        // threads started that have not returned
+	waiting  []*thread  // ascending by id
+	schedule Schedule
+}
+
+// thread is a goroutine started by Go.
+type thread struct {
+	s      *scheduler
+	id     int
+	name   string
+	resume chan struct{} // closed when the scheduler resumes the thread
+	at     Point         // where it waits
+	r      *rand.Rand    // of the thread alone, if the scheduler is free
+}
+
+var (
+	running atomic.Int32 // runs in progress, so that Yield returns at once outside them
+
+	// threads holds the thread of each goroutine id. It is replaced on
+	// every change, so that lookups do not synchronize the goroutines as
+	// far as the race detector can tell.
+	threads   atomic.Pointer[map[int64]*thread]
+	threadsMu sync.Mutex // serializes changes of threads
+
+	mu         sync.Mutex
+	schedulers = make(map[int64]*scheduler) // by bubble
+)
+
+// current returns the calling goroutine's thread, or nil.
+func current() *thread {
+	if m := threads.Load(); m != nil {
+		return (*m)[goid.ID()]
+	}
+	return nil
+}
+
+// setThreads makes each goroutine of ids the thread th, or no thread if
+// th is nil.
+func setThreads(th *thread, ids ...int64) {
+	threadsMu.Lock()
+	defer threadsMu.Unlock()
+	m := make(map[int64]*thread)
+	if old := threads.Load(); old != nil {
+		maps.Copy(m, *old)
+	}
+	for _, id := range ids {
+		if th != nil {
+			m[id] = th
+		} else {
+			delete(m, id)
+		}
+	}
+	threads.Store(&m)
+}
+
+// Go starts f in a new goroutine named name. Inside a run, the goroutine
+// is a thread of its scheduler and waits to be resumed before it starts.
+func Go(name string, f func()) {
+	th := current()
+	if th == nil {
+		bubble.Go(name, f)
+		return
+	}
+	th.s.start(name, caller(), f)
+}
+
+// caller returns the file:line its caller was called from.
+func caller() string {
+	_, file, line, _ := runtime.Caller(2)
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// Yield is a preemption point labeled label: inside a run, the calling
+// thread waits until the scheduler resumes it, and may be overtaken by the
+// other threads. Code under test calls it where the order of the threads
+// matters, e.g. between reading shared state and acting on it, as
+// scheduling otherwise only varies where threads start and block. Outside
+// a run, and in goroutines that are not threads, Yield does nothing and
+// costs little.
+func Yield(label string) {
+	if running.Load() == 0 {
+		return
+	}
+	switch th := current(); {
+	case th == nil:
+	case th.s.free != nil:
+		th.sleep()
+	default:
+		th.park(Point{label, caller()})
+	}
+}
+
+// start starts f as a new thread.
+func (s *scheduler) start(name, site string, f func()) {
+	if s.free != nil {
+		s.startFree(f)
+		return
+	}
+	s.mu.Lock()
+	th := &thread{s: s, id: s.threads, name: name}
+	s.threads++
+	s.live++
+	s.mu.Unlock()
+	go func() {
+		id := goid.ID()
+		setThreads(th, id)
+		goid.SetName(name)
+		timeline.Spawn(site)
+		defer func() {
+			timeline.Exit()
+			goid.Forget()
+			setThreads(nil, id)
+			s.mu.Lock()
+			s.live--
+			s.mu.Unlock()
+			s.signal()
+		}()
+		th.park(Point{"go", site})
+		f()
+	}()
+}
+
+// park waits at a preemption point until the scheduler resumes th.
+func (th *thread) park(at Point) {
+	s := th.s
+	s.mu.Lock()
+	th.resume, th.at = make(chan struct{}), at
+	i, _ := slices.BinarySearchFunc(s.waiting, th.id, func(w *thread, id int) int { return w.id - id })
+	s.waiting = slices.Insert(s.waiting, i, th)
+	s.mu.Unlock()
+	timeline.Block("wait to be scheduled at "+at.Label, at.Site)
+	s.signal()
+	<-th.resume
+	timeline.Unblock()
+}
+
+// signal tells the scheduler a thread is waiting or returned.
+func (s *scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loop resumes threads until all have returned.
+func (s *scheduler) loop() {
+	for {
+		synctest.Wait()
+		s.mu.Lock()
+		if s.live == 0 {
+			s.mu.Unlock()
+			return
+		}
+		if len(s.waiting) == 0 {
+			// Every thread is blocked, until time passes.
+			s.mu.Unlock()
+			<-s.wake
+			continue
+		}
+		var resumed []*thread
+		for range max(s.parallel, 1) {
+			if len(s.waiting) == 0 {
+				break
+			}
+			var d Decision
+			for _, th := range s.waiting {
+				d.Ready, d.At = append(d.Ready, th.id), append(d.At, th.at)
+			}
+			i := s.strategy.pick(len(s.schedule), s.waiting)
+			th := s.waiting[i]
+			s.waiting = slices.Delete(s.waiting, i, i+1)
+			d.Chosen = th.id
+			s.schedule = append(s.schedule, d)
+			resumed = append(resumed, th)
+		}
+		s.mu.Unlock()
+		for _, th := range resumed {
+			close(th.resume)
+		}
+	}
+}
+
+// result is the outcome of a run.
+type result struct {
+	schedule Schedule
+	deadlock string // report of the goroutines of a deadlocked bubble
+	stuck    string // where they are blocked, the same for the same deadlock
+}
+
+// newScheduler returns a scheduler following st, with the random numbers
+// of seed.
+func newScheduler(seed uint64, st strategy) *scheduler {
+	return &scheduler{strategy: st, seed: seed, rand: rand.New(&lockedSource{src: rand.NewPCG(seed, 1)})}
+}
+
+// run runs fn as thread 0 of s in a fresh bubble.
+func run(s *scheduler, fn func()) result {
+	running.Add(1)
+	defer running.Add(-1)
+	var root int64
+	group, r := gstack.Run(func() {
+		root = goid.ID()
+		s.wake = make(chan struct{}, 1) // of the bubble, for the scheduler to block durably
+		mu.Lock()
+		schedulers[gstack.Self().Group] = s
+		mu.Unlock()
+		s.start("main", "", fn)
+		if s.free == nil {
+			s.loop()
+		}
+	})
+	mu.Lock()
+	delete(schedulers, group)
+	mu.Unlock()
+	if s.free != nil {
+		var ids []int64
+		for id, th := range *threads.Load() {
+			if th.s == s {
+				ids = append(ids, id)
+			}
+		}
+		setThreads(nil, ids...)
+	}
+	s.mu.Lock() // for the race detector, which does not see the bubble end
+	res := result{schedule: s.schedule}
+	s.mu.Unlock()
+	if r != nil {
+		if msg, ok := r.(string); !ok || !strings.HasPrefix(msg, "deadlock") {
+			panic(r)
+		}
+		var gs []gstack.Goroutine
+		var stuck []string
+		for _, g := range gstack.Group(group) {
+			if g.ID == root {
+				continue
+			}
+			gs = append(gs, g)
+			f, _ := g.UserFrame()
+			stuck = append(stuck, fmt.Sprintf("%s [%s] at %s:%d", goid.Name(g.ID), g.State, f.File, f.Line))
+		}
+		slices.Sort(stuck)
+		res.deadlock, res.stuck = bubble.Report(gs), strings.Join(stuck, "\n")
+	}
+	return res
+}
+
+// Seed returns the seed Run uses: the one of -synctest.seed, or else a
+// random one.
+func Seed() uint64 {
+	if *seedFlag != "" {
+		if seed, err := strconv.ParseUint(*seedFlag, 10, 64); err == nil {
+			return seed
+		}
+	}
+	return rand.Uint64()
+}
+
+// Run runs fn in a fresh bubble, with the threads it starts scheduled at
+// random from Seed. If t failed in the run, Run reports the seed and the
+// schedule, and if the bubble deadlocked, what every goroutine was blocked
+// on.
+func Run(t testing.TB, fn func()) Schedule {
+	t.Helper()
+	if _, err := strconv.ParseUint(*seedFlag, 10, 64); *seedFlag != "" && err != nil {
+		t.Fatalf("sched: invalid -synctest.seed=%s: %v", *seedFlag, err)
+	}
+	return RunSeed(t, Seed(), fn)
+}
+
+// RunSeed is Run with the given seed.
+func RunSeed(t testing.TB, seed uint64, fn func()) Schedule {
+	t.Helper()
+	failed := t.Failed()
+	res := run(newScheduler(seed, seeded(seed)), fn)
+	if res.deadlock != "" {
+		emitFailure(t, &seed, res.schedule, 1, []string{"bubble deadlocked"})
+		t.Fatalf("sched: bubble deadlocked after schedule %v with seed %d; replay with -synctest.seed=%d\n%s", res.schedule, seed, seed, res.deadlock)
+	}
+	if !failed && t.Failed() {
+		emitFailure(t, &seed, res.schedule, 1, []string{"failed"})
+		t.Errorf("sched: failed after schedule %v with seed %d; replay with -synctest.seed=%d", res.schedule, seed, seed)
+	}
+	return res.schedule
+}
+
+// byBytes picks by bytes, of a fuzzer's input or of a program: each
+// decision among more than one ready thread takes the next one, modulo
+// their number, and the first ready thread once they are used up.
+type byBytes struct{ next *[]byte }
+
+func (s byBytes) pick(_ int, ready []*thread) int {
+	if len(ready) < 2 || len(*s.next) == 0 {
+		return 0
+	}
+	b := (*s.next)[0]
+	*s.next = (*s.next)[1:]
+	return int(b) % len(ready)
+}
+
+// RunChoices runs fn in a fresh bubble, as Run does, with the threads it
+// starts resumed as the bytes of choices pick: each decision among more
+// than one ready thread takes the next byte, modulo their number, to
+// pick one, ascending by id, and once the bytes are used up the first
+// ready thread. Rand draws from seed 0. The same choices replay the same
+// schedule, as far as fn starts and blocks its threads the same way. It
+// returns the schedule and, if the bubble deadlocked, an error saying
+// what every goroutine was blocked on.
+func RunChoices(choices []byte, fn func()) (Schedule, error) {
+	res := run(newScheduler(0, byBytes{&choices}), fn)
+	if res.deadlock != "" {
+		return res.schedule, fmt.Errorf("sched: bubble deadlocked after schedule %v\n%s", res.schedule, res.deadlock)
+	}
+	return res.schedule, nil
+}
+
+// Rand returns the random numbers of the caller's run, derived from its
+// seed, for the code under test to draw from, or a randomly seeded source
+// outside a run. It is safe for concurrent use, except in the runs of
+// ExploreRaces, in which every thread draws from a source of its own.
+func Rand() *rand.Rand {
+	if th := current(); th != nil && th.r != nil {
+		return th.r
+	}
+	if s := bubbleScheduler(); s != nil {
+		return s.rand
+	}
+	return rand.New(&lockedSource{src: rand.NewPCG(rand.Uint64(), rand.Uint64())})
+}
+
+// BubbleSeed returns the seed of the caller's run, if it is in one, for
+// the faults and other choices of the code under test to derive from, so
+// that -synctest.seed replays them too.
+func BubbleSeed() (seed uint64, ok bool) {
+	if s := bubbleScheduler(); s != nil {
+		return s.seed, true
+	}
+	return 0, false
+}
+
+// SeedFor returns the seed for the simulation of a kit, such as raftsim,
+// to derive its choices from: that of the caller's run, if it is in one,
+// or else Seed, which it logs, prefixed by kit, if t fails, for
+// -synctest.seed to replay the test.
+func SeedFor(t testing.TB, kit string) uint64 {
+	if seed, ok := BubbleSeed(); ok {
+		return seed
+	}
+	seed := Seed()
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("%s: seed %d, replay with -synctest.seed=%d", kit, seed, seed)
+		}
+	})
+	return seed
+}
+
+// bubbleScheduler returns the scheduler of the caller's bubble, or nil.
+func bubbleScheduler() *scheduler {
+	group := gstack.Self().Group
+	if group == 0 {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return schedulers[group]
+}
+
+// lockedSource makes a source safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
@@ -0,0 +1,156 @@
+package sched
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// order starts three threads and returns the order they ran in, with a
+// number drawn by each.
+func order() (func(), func() []string) {
+	var got []string
+	return func() {
+			ch := make(chan string)
+			for _, name := range []string{"a", "b", "c"} {
+				Go(name, func() { ch <- name + strings.Repeat("+", Rand().IntN(3)) })
+			}
+			for range 3 {
+				got = append(got, <-ch)
+			}
+		}, func() []string {
+			return got
+		}
+}
+
+func TestRunSeedReplays(t *testing.T) {
+	seen := make(map[string]bool)
+	for seed := range uint64(20) {
+		fn, got := order()
+		first := RunSeed(t, seed, fn)
+		want := got()
+		fn, got = order()
+		if again := RunSeed(t, seed, fn); !reflect.DeepEqual(again, first) || !reflect.DeepEqual(got(), want) {
+			t.Errorf("seed %d: schedule %v then %v, order %q then %q", seed, first, again, want, got())
+		}
+		seen[strings.Join(want, " ")] = true
+	}
+	if len(seen) < 4 {
+		t.Errorf("20 seeds ran in %d orders: %v", len(seen), seen)
+	}
+}
+
+func TestRunReportsSeed(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		RunSeed(t, 7, func() { t.Error("boom") })
+	})
+	want := []string{"boom", "sched: failed after schedule [0] with seed 7; replay with -synctest.seed=7"}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+}
+
+func TestRunDeadlock(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		RunSeed(t, 1, func() {
+			ch := make(chan int)
+			Go("stuck", func() { ch <- 1 })
+			<-make(chan int)
+		})
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0], "deadlocked after schedule [0 1] with seed 1") || !strings.Contains(errs[0], "stuck [chan send") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+func TestRunChoices(t *testing.T) {
+	// Thread 0 waits on the channel: the first decision is between a, b
+	// and c, the second between the two left.
+	for _, tt := range []struct {
+		choices []byte
+		want    string
+	}{
+		{nil, "[0 1 2 3]"},
+		{[]byte{2, 1}, "[0 3 2 1]"},
+		{[]byte{4}, "[0 2 1 3]"},
+	} {
+		fn, _ := order()
+		s, err := RunChoices(tt.choices, fn)
+		if err != nil || s.String() != tt.want {
+			t.Errorf("choices %v: schedule %v, %v; want %s", tt.choices, s, err, tt.want)
+		}
+	}
+	_, err := RunChoices(nil, func() { <-make(chan int) })
+	if err == nil || !strings.Contains(err.Error(), "sched: bubble deadlocked after schedule [0]") {
+		t.Errorf("deadlock: %v", err)
+	}
+}
+
+func TestSeedFor(t *testing.T) {
+	RunSeed(t, 7, func() {
+		if seed := SeedFor(t, "kit"); seed != 7 {
+			t.Errorf("seed %d in the run of seed 7", seed)
+		}
+	})
+}
+
+func TestYield(t *testing.T) {
+	Yield("outside a run")
+	lost := func(yield bool) RandomReport {
+		var rep RandomReport
+		testtb.Run(t, func(t testing.TB) {
+			rep = ExploreRandom(t, 30, func(t testing.TB) {
+				n := 0
+				done := make(chan struct{})
+				for range 2 {
+					Go("inc", func() {
+						v := n
+						if yield {
+							Yield("read")
+						}
+						n = v + 1
+						done <- struct{}{}
+					})
+				}
+				<-done
+				<-done
+				if n != 2 {
+					t.Error("lost update")
+				}
+			})
+		})
+		return rep
+	}
+	if rep := lost(false); len(rep.Failures) != 0 {
+		t.Errorf("without Yield: %+v", rep)
+	}
+	if rep := lost(true); len(rep.Failures) != 1 {
+		t.Errorf("with Yield: %+v", rep)
+	}
+
+	var site string
+	schedule := RunSeed(t, 1, func() {
+		Yield("main")
+		_, file, line, _ := runtime.Caller(0)
+		site = fmt.Sprintf("%s:%d", file, line-1)
+	})
+	if len(schedule) != 2 || schedule[0].Point().Label != "go" || schedule[1].Point().Label != "main" || schedule[1].At[0].Site != site {
+		t.Errorf("schedule %+v, want main yielding at %s", schedule, site)
+	}
+}
+
+func TestBubbleSeed(t *testing.T) {
+	var got uint64
+	var ok bool
+	RunSeed(t, 42, func() { got, ok = BubbleSeed() })
+	if got != 42 || !ok {
+		t.Errorf("BubbleSeed() = %d, %v in a run of seed 42", got, ok)
+	}
+	if _, ok := BubbleSeed(); ok {
+		t.Error("BubbleSeed found a run outside one")
+	}
+}
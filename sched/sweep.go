@@ -0,0 +1,122 @@
+package sched
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/events"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// Level is how the runs of a sweep went at one level of parallelism.
+type Level struct {
+	Parallelism int
+	Runs        int
+	Failures    []Failure // in the order they were first seen
+}
+
+// SweepParallel runs fn n times in fresh bubbles at each level of
+// parallelism of levels, the threads scheduled at random from seeds
+// counting from Seed, as ExploreRandom does; at level k up to k waiting
+// threads are resumed at once, so that they run in parallel until they
+// wait again, and level 1 is the schedule of ExploreRandom. Runs at levels
+// above 1 need not replay. Once all runs are done, t fails once for each
+// way runs failed, with the levels it failed at.
+func SweepParallel(t testing.TB, n int, levels []int, fn func(t testing.TB)) []Level {
+	t.Helper()
+	seed := Seed()
+	var rep []Level
+	for _, k := range levels {
+		rep = append(rep, sweep(t, k, n, func(i int, rec *testtb.Recorder) (Bookmark, string, []string) {
+			s := newScheduler(seed+uint64(i), seeded(seed+uint64(i)))
+			s.parallel = k
+			res := run(s, func() { fn(rec) })
+			errs, sig := outcome(res, rec)
+			return Bookmark{seed + uint64(i), res.schedule}, sig, errs
+		}))
+	}
+	reportSweep(t, "level of parallelism", rep)
+	return rep
+}
+
+// SweepGOMAXPROCS runs fn n times outside of bubbles with each value of
+// GOMAXPROCS of procs, restoring it at the end, and fails t like
+// SweepParallel. Runs that fail the same way, with the same failures, are
+// counted together. fn must not be run in parallel with other tests.
+func SweepGOMAXPROCS(t testing.TB, n int, procs []int, fn func(t testing.TB)) []Level {
+	t.Helper()
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	var rep []Level
+	for _, p := range procs {
+		runtime.GOMAXPROCS(p)
+		rep = append(rep, sweep(t, p, n, func(_ int, rec *testtb.Recorder) (Bookmark, string, []string) {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				fn(rec)
+			}()
+			<-done
+			errs := rec.Errors()
+			return Bookmark{}, strings.Join(errs, "\n"), errs
+		}))
+	}
+	reportSweep(t, "GOMAXPROCS", rep)
+	return rep
+}
+
+// sweep makes n runs at level k and collects their failures.
+func sweep(t testing.TB, k, n int, try func(i int, rec *testtb.Recorder) (b Bookmark, sig string, errs []string)) Level {
+	l := Level{Parallelism: k}
+	bySig := make(map[string]int)
+	var cov Coverage
+	prog := startProgress(t, fmt.Sprint("at level ", k), n)
+	events.Emit(t, events.Event{Action: "start", Runs: n, Message: fmt.Sprint("level ", k)})
+	for i := range n {
+		b, sig, errs := try(i, testtb.New(t))
+		l.Runs++
+		cov.add(b.Schedule)
+		if len(errs) > 0 {
+			var seed *uint64
+			if b.Schedule != nil {
+				seed = events.Seed(b.Seed)
+			}
+			emitFailure(t, seed, b.Schedule, i+1, errs)
+		}
+		if j, ok := bySig[sig]; ok && len(errs) > 0 {
+			l.Failures[j].Runs++
+		} else if len(errs) > 0 {
+			bySig[sig] = len(l.Failures)
+			l.Failures = append(l.Failures, Failure{Bookmark: b, Errors: errs, Runs: 1})
+		}
+		prog.update(l.Runs, cov, l.Failures)
+	}
+	prog.done(l.Runs, cov, l.Failures)
+	events.Emit(t, events.Event{Action: "done", Runs: l.Runs, Failures: len(l.Failures), Message: fmt.Sprint("level ", k)})
+	return l
+}
+
+// reportSweep fails t once for each way runs failed, with how often they
+// did at each level.
+func reportSweep(t testing.TB, what string, rep []Level) {
+	t.Helper()
+	var sigs []string
+	at := make(map[string][]string)
+	for _, l := range rep {
+		for _, f := range l.Failures {
+			sig := strings.Join(f.Errors, "\n")
+			if at[sig] == nil {
+				sigs = append(sigs, sig)
+			}
+			runs := fmt.Sprintf("%d of %d runs at %s %d", f.Runs, l.Runs, what, l.Parallelism)
+			if f.Schedule != nil {
+				runs += fmt.Sprintf(" (first with seed %d)", f.Seed)
+			}
+			at[sig] = append(at[sig], runs)
+		}
+	}
+	for _, sig := range sigs {
+		t.Errorf("sched: %s failed with:\n\t%s", strings.Join(at[sig], ", "), strings.ReplaceAll(sig, "\n", "\n\t"))
+	}
+}
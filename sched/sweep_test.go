@@ -0,0 +1,45 @@
+package sched
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestSweepParallel(t *testing.T) {
+	var rep []Level
+	errs := testtb.Run(t, func(t testing.TB) { rep = SweepParallel(t, 10, []int{1, 3}, lostUpdate) })
+	if len(rep) != 2 || rep[1].Parallelism != 3 || rep[1].Runs != 10 {
+		t.Fatalf("%+v", rep)
+	}
+	// Resuming all three at once, they all read before any writes.
+	if f := rep[1].Failures; len(f) != 1 || f[0].Runs != 10 || f[0].Errors[0] != "n = 1 after 3 increments" {
+		t.Errorf("at level 3: %+v", f)
+	}
+	if !strings.Contains(strings.Join(errs, "\n"), "10 of 10 runs at level of parallelism 3") {
+		t.Errorf("errors %q", errs)
+	}
+}
+
+func TestSweepGOMAXPROCS(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+	var rep []Level
+	errs := testtb.Run(t, func(t testing.TB) {
+		rep = SweepGOMAXPROCS(t, 2, []int{1, 2}, func(t testing.TB) {
+			if runtime.GOMAXPROCS(0) == 1 {
+				t.Error("serial")
+			}
+		})
+	})
+	if len(rep) != 2 || len(rep[0].Failures) != 1 || len(rep[1].Failures) != 0 {
+		t.Errorf("%+v", rep)
+	}
+	if want := "sched: 2 of 2 runs at GOMAXPROCS 1 failed with:\n\tserial"; len(errs) != 1 || errs[0] != want {
+		t.Errorf("errors %q, want %q", errs, want)
+	}
+	if runtime.GOMAXPROCS(0) != procs {
+		t.Errorf("GOMAXPROCS left at %d, was %d", runtime.GOMAXPROCS(0), procs)
+	}
+}
@@ -0,0 +1,52 @@
+package sched
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// Weights are how often ExploreWeighted resumes threads relative to each
+// other, by the name they were started with; other threads weigh 1.
+type Weights map[string]float64
+
+// weighted picks at random in proportion to the weights, or uniformly if
+// all ready threads weigh nothing.
+type weighted struct {
+	r *rand.Rand
+	w Weights
+}
+
+func (s weighted) weight(th *thread) float64 {
+	if w, ok := s.w[th.name]; ok {
+		return max(w, 0)
+	}
+	return 1
+}
+
+func (s weighted) pick(_ int, ready []*thread) int {
+	total := 0.0
+	for _, th := range ready {
+		total += s.weight(th)
+	}
+	if total == 0 {
+		return s.r.IntN(len(ready))
+	}
+	x := s.r.Float64() * total
+	for i, th := range ready {
+		if x -= s.weight(th); x < 0 {
+			return i
+		}
+	}
+	return len(ready) - 1
+}
+
+// ExploreWeighted is ExploreRandom with threads resumed in proportion to
+// their weights in w, to model threads the runtime schedules less often
+// than others, e.g. a consumer starved by its producers at a weight of
+// 0.1, which uniform schedules rarely do for long.
+func ExploreWeighted(t testing.TB, n int, w Weights, fn func(t testing.TB)) RandomReport {
+	t.Helper()
+	return exploreSeeds(t, n, func(seed uint64) *scheduler {
+		return newScheduler(seed, weighted{rand.New(rand.NewPCG(seed, 0)), w})
+	}, fn)
+}
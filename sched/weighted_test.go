@@ -0,0 +1,34 @@
+package sched
+
+import (
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestExploreWeighted(t *testing.T) {
+	const runs = 20
+	slowLast := 0
+	fn := func(t testing.TB) {
+		var done []string
+		finish := make(chan bool)
+		for _, name := range []string{"slow", "fast"} {
+			Go(name, func() {
+				for range 10 {
+					Yield("step")
+				}
+				done = append(done, name)
+				finish <- true
+			})
+		}
+		<-finish
+		<-finish
+		if done[0] == "fast" {
+			slowLast++
+		}
+	}
+	errs := testtb.Run(t, func(t testing.TB) { ExploreWeighted(t, runs, Weights{"slow": 0.01}, fn) })
+	if len(errs) > 0 || slowLast < runs-1 {
+		t.Errorf("slow thread finished last in %d of %d runs, errors %q", slowLast, runs, errs)
+	}
+}
@@ -0,0 +1,187 @@
+// Package seqdiag records the messages simulated endpoints exchange over
+// connections and draws them as Mermaid sequence diagrams, for
+// documentation and failure reports to show the message flow a test
+// actually observed. Every write on a recorded connection is a message
+// from its end to the other, labeled with its first line, which for HTTP
+// is the request or status line, and every close is one too. Times are
+// virtual inside a bubble; the diagram notes when time passes between
+// messages.
+package seqdiag
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Message is a write or close on a recorded connection.
+type Message struct {
+	At       time.Duration // since the recording started
+	From, To string
+	Text     string // the first line written, or "close"
+	Bytes    int    // written
+	Close    bool
+	Err      string // of the write, if it failed
+}
+
+func (m Message) String() string {
+	if m.Close {
+		return fmt.Sprintf("+%v %s closed the connection to %s", m.At, m.From, m.To)
+	}
+	s := fmt.Sprintf("+%v %s → %s: %s (%d bytes)", m.At, m.From, m.To, m.Text, m.Bytes)
+	if m.Err != "" {
+		s += " failed: " + m.Err
+	}
+	return s
+}
+
+// Recorder records the messages on the connections it wraps. It is safe
+// for concurrent use.
+type Recorder struct {
+	start time.Time
+
+	mu           sync.Mutex
+	participants []string // in the order they were first seen
+	msgs         []Message
+}
+
+// Record returns a Recorder whose diagram is logged if t fails.
+func Record(t testing.TB) *Recorder {
+	t.Helper()
+	r := &Recorder{start: time.Now()}
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("message flow:\n%s", r.Mermaid())
+		}
+	})
+	return r
+}
+
+// Conn returns c, recording what is written on it as messages from local
+// to remote, and closing it as a message too.
+func (r *Recorder) Conn(c net.Conn, local, remote string) net.Conn {
+	r.mu.Lock()
+	r.participant(local)
+	r.participant(remote)
+	r.mu.Unlock()
+	return &conn{Conn: c, r: r, local: local, remote: remote}
+}
+
+// Pipe returns the ends of a net.Pipe, of endpoints a and b, recorded.
+func (r *Recorder) Pipe(a, b string) (net.Conn, net.Conn) {
+	ca, cb := net.Pipe()
+	return r.Conn(ca, a, b), r.Conn(cb, b, a)
+}
+
+// Messages returns the messages recorded so far, in the order they were
+// sent.
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.msgs)
+}
+
+// WriteMermaid writes the messages as a Mermaid sequence diagram to w.
+func (r *Recorder) WriteMermaid(w io.Writer) error {
+	r.mu.Lock()
+	participants := slices.Clone(r.participants)
+	r.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("sequenceDiagram\n")
+	for _, p := range participants {
+		fmt.Fprintf(&b, "    participant %s\n", p)
+	}
+	var at time.Duration
+	for _, m := range r.Messages() {
+		if m.At != at {
+			at = m.At
+			fmt.Fprintf(&b, "    Note over %s,%s: +%v\n", participants[0], participants[len(participants)-1], at)
+		}
+		if m.Close {
+			fmt.Fprintf(&b, "    %s-x%s: close\n", m.From, m.To)
+		} else {
+			fmt.Fprintf(&b, "    %s->>%s: %s\n", m.From, m.To, escape(m.Text))
+			if m.Err != "" {
+				fmt.Fprintf(&b, "    Note right of %s: %s\n", m.From, escape("failed: "+m.Err))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Mermaid returns the diagram of WriteMermaid.
+func (r *Recorder) Mermaid() string {
+	var b strings.Builder
+	r.WriteMermaid(&b)
+	return b.String()
+}
+
+// escape makes s the text of a Mermaid message, in which ';' ends the
+// statement and '#' starts an entity.
+func escape(s string) string {
+	return strings.NewReplacer("#", "#35;", ";", "#59;").Replace(s)
+}
+
+// participant adds p to the participants, if new; r.mu is held.
+func (r *Recorder) participant(p string) {
+	if !slices.Contains(r.participants, p) {
+		r.participants = append(r.participants, p)
+	}
+}
+
+// add records m and returns its index.
+func (r *Recorder) add(m Message) int {
+	m.At = time.Since(r.start)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, m)
+	return len(r.msgs) - 1
+}
+
+// maxText is how much of the first line a message shows.
+const maxText = 60
+
+// conn is a recorded end of a connection.
+type conn struct {
+	net.Conn
+	r             *Recorder
+	local, remote string
+	closed        sync.Once
+}
+
+// Write records the message before writing it, since a synchronous
+// connection like net.Pipe only returns once the other end read it, and
+// may have answered already.
+func (c *conn) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return c.Conn.Write(p)
+	}
+	line, _, _ := bytes.Cut(p, []byte("\n"))
+	text := strings.TrimSuffix(string(line), "\r")
+	if len(text) > maxText {
+		text = strings.ToValidUTF8(text[:maxText], "") + "…"
+	}
+	if text == "" {
+		text = fmt.Sprintf("%d bytes", len(p))
+	}
+	i := c.r.add(Message{From: c.local, To: c.remote, Text: text, Bytes: len(p)})
+	n, err := c.Conn.Write(p)
+	if err != nil {
+		c.r.mu.Lock()
+		c.r.msgs[i].Bytes, c.r.msgs[i].Err = n, err.Error()
+		c.r.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *conn) Close() error {
+	c.closed.Do(func() { c.r.add(Message{From: c.local, To: c.remote, Text: "close", Close: true}) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,75 @@
+package seqdiag
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// exchange sends a request from client to server, which answers after a
+// second and hangs up.
+func exchange(t testing.TB) *Recorder {
+	var rec *Recorder
+	synctest.Run(func() {
+		rec = Record(t)
+		client, server := rec.Pipe("client", "server")
+		go func() {
+			defer server.Close()
+			if _, err := http.ReadRequest(bufio.NewReader(server)); err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(time.Second)
+			io.WriteString(server, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+		}()
+		req, _ := http.NewRequest("GET", "http://seqdiag.test/a;b", nil)
+		if err := req.Write(client); err != nil {
+			t.Fatal(err)
+		}
+		br := bufio.NewReader(client)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		io.ReadAll(br) // until the server hangs up
+		client.Close()
+	})
+	return rec
+}
+
+func TestMermaid(t *testing.T) {
+	got := exchange(t).Mermaid()
+	want := `sequenceDiagram
+    participant client
+    participant server
+    client->>server: GET /a#59;b HTTP/1.1
+    Note over client,server: +1s
+    server->>client: HTTP/1.1 200 OK
+    server-xclient: close
+    client-xserver: close
+`
+	if got != want {
+		t.Errorf("diagram\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	var got []string
+	for _, m := range exchange(t).Messages() {
+		got = append(got, m.String())
+	}
+	want := []string{
+		"+0s client → server: GET /a;b HTTP/1.1 (73 bytes)",
+		"+1s server → client: HTTP/1.1 200 OK (40 bytes)",
+		"+1s server closed the connection to client",
+		"+1s client closed the connection to server",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("messages\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
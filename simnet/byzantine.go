@@ -0,0 +1,144 @@
+package simnet
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+)
+
+// Byzantine is a model of a misbehaving peer: the probabilities with
+// which each frame its process writes, one Write being one frame, is
+// tampered with in one of these ways instead of sent as written.
+type Byzantine struct {
+	Seed uint64
+	// Corrupt flips a few bytes of the frame.
+	Corrupt float64
+	// Stale sends, in place of the frame, one sent to the same peer before.
+	Stale float64
+	// Equivocate sends the peer a forged variant of the frame, so that the
+	// peers of a broadcast may be told different things.
+	Equivocate float64
+	// Drop sends nothing, reporting success all the same.
+	Drop float64
+	// Forge makes a well-formed variant of a frame that contradicts it,
+	// e.g. a vote for another value, for Equivocate; nil corrupts it.
+	Forge func(r *rand.Rand, frame []byte) []byte
+}
+
+// Act is a frame a Byzantine peer tampered with.
+type Act struct {
+	From, To string // addresses
+	Kind     string // "corrupt", "stale", "equivocate" or "drop"
+	Frame    []byte // as written
+	Sent     []byte // instead, nil if dropped
+}
+
+func (a Act) String() string {
+	if a.Sent == nil {
+		return fmt.Sprintf("%s → %s: %s %q", a.From, a.To, a.Kind, a.Frame)
+	}
+	return fmt.Sprintf("%s → %s: %s %q as %q", a.From, a.To, a.Kind, a.Frame, a.Sent)
+}
+
+// Misbehavior is a Byzantine model applied to a node.
+type Misbehavior struct {
+	Byzantine
+	node *Node
+
+	mu   sync.Mutex
+	sent map[string][][]byte // frames sent honestly, by peer address
+	acts []Act
+}
+
+// Misbehave makes the processes of n behave as b, this one and the ones
+// it restarts as, until Stop, so that protocol implementations can be
+// tested for robustness and not only on the honest path.
+func (n *Node) Misbehave(b Byzantine) *Misbehavior {
+	m := &Misbehavior{Byzantine: b, node: n, sent: make(map[string][][]byte)}
+	n.net.mu.Lock()
+	n.byz = m
+	n.net.mu.Unlock()
+	return m
+}
+
+// Stop makes the node honest again.
+func (m *Misbehavior) Stop() {
+	nw := m.node.net
+	nw.mu.Lock()
+	if m.node.byz == m {
+		m.node.byz = nil
+	}
+	nw.mu.Unlock()
+}
+
+// Acts returns the frames tampered with so far.
+func (m *Misbehavior) Acts() []Act {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Act(nil), m.acts...)
+}
+
+// String lists the acts, for failure messages.
+func (m *Misbehavior) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "node %s misbehaved %d times (seed %d)", m.node.Name, len(m.acts), m.Seed)
+	for _, a := range m.Acts() {
+		fmt.Fprintf(&b, "\n\t%v", a)
+	}
+	return b.String()
+}
+
+// tamper returns what to send in place of frame, the write n on c, nil to
+// send nothing. What it draws is a function of the seed, c and n, like
+// the conditions of the link.
+func (m *Misbehavior) tamper(c *conn, n int, frame []byte) []byte {
+	r := c.rand(m.Seed, n)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	to := string(c.remote)
+	act := Act{From: string(c.local), To: to, Frame: bytes.Clone(frame)}
+	switch p := r.Float64(); {
+	case p < m.Corrupt:
+		act.Kind = "corrupt"
+	case p < m.Corrupt+m.Stale:
+		if len(m.sent[to]) > 0 {
+			act.Kind = "stale"
+		}
+	case p < m.Corrupt+m.Stale+m.Equivocate:
+		act.Kind = "equivocate"
+	case p < m.Corrupt+m.Stale+m.Equivocate+m.Drop:
+		act.Kind = "drop"
+	}
+	switch act.Kind {
+	case "":
+		m.sent[to] = append(m.sent[to], act.Frame)
+		return frame
+	case "corrupt":
+		act.Sent = corrupt(r, frame)
+	case "stale":
+		old := m.sent[to]
+		act.Sent = old[r.IntN(len(old))]
+	case "equivocate":
+		if m.Forge != nil {
+			act.Sent = m.Forge(r, bytes.Clone(frame))
+		} else {
+			act.Sent = corrupt(r, frame)
+		}
+	}
+	m.acts = append(m.acts, act)
+	return act.Sent
+}
+
+// corrupt returns frame with one to three bytes flipped.
+func corrupt(r *rand.Rand, frame []byte) []byte {
+	f := bytes.Clone(frame)
+	if len(f) == 0 {
+		return f
+	}
+	for range 1 + r.IntN(3) {
+		f[r.IntN(len(f))] ^= byte(1 + r.IntN(255))
+	}
+	return f
+}
@@ -0,0 +1,106 @@
+package simnet
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"testing/synctest"
+)
+
+// sink starts a process that sends the frames it reads on port 80 to got.
+func sink(got chan<- string) Boot {
+	return func(p *Proc) {
+		l, _ := p.Listen("80")
+		p.Go(func(context.Context) {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				p.Go(func(context.Context) {
+					buf := make([]byte, 64)
+					for {
+						n, err := c.Read(buf)
+						if err != nil {
+							return
+						}
+						got <- string(buf[:n])
+					}
+				})
+			}
+		})
+	}
+}
+
+// send writes frames from a new node of nw misbehaving as b to the nodes
+// peers, all of them sinks, and returns what each peer got.
+func send(t *testing.T, b Byzantine, frames []string, peers ...string) (map[string][]string, *Misbehavior) {
+	nw := New()
+	defer nw.Close()
+	gots := make(map[string]chan string)
+	for _, name := range peers {
+		gots[name] = make(chan string, len(frames))
+		nw.Add(name, sink(gots[name]))
+	}
+	n := nw.Add("byz", func(*Proc) {})
+	m := n.Misbehave(b)
+	for _, name := range peers {
+		c, err := n.Proc().Dial(name + ":80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range frames {
+			if _, err := c.Write([]byte(f)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		c.Close()
+	}
+	synctest.Wait()
+	res := make(map[string][]string)
+	for name, got := range gots {
+		close(got)
+		for f := range got {
+			res[name] = append(res[name], f)
+		}
+	}
+	return res, m
+}
+
+func TestCorruptAndStale(t *testing.T) {
+	synctest.Run(func() {
+		got, m := send(t, Byzantine{Corrupt: 1}, []string{"hello"}, "a")
+		if f := got["a"]; len(f) != 1 || f[0] == "hello" || len(f[0]) != 5 {
+			t.Errorf("got %q, want hello corrupted", f)
+		}
+		if acts := m.Acts(); len(acts) != 1 || acts[0].Kind != "corrupt" {
+			t.Errorf("acts %v", acts)
+		}
+		got, _ = send(t, Byzantine{Stale: 1}, []string{"1", "2", "3"}, "a")
+		if f := strings.Join(got["a"], ""); f != "111" {
+			t.Errorf("got %q, want the first frame replayed", f)
+		}
+		got, m = send(t, Byzantine{Drop: 1}, []string{"1", "2"}, "a")
+		if len(got["a"]) != 0 || len(m.Acts()) != 2 {
+			t.Errorf("got %q with %v, want both dropped", got["a"], m.Acts())
+		}
+	})
+}
+
+func TestEquivocate(t *testing.T) {
+	synctest.Run(func() {
+		forge := func(*rand.Rand, []byte) []byte { return []byte("vote b") }
+		for seed := range uint64(10) {
+			got, m := send(t, Byzantine{Seed: seed, Equivocate: 0.5, Forge: forge}, []string{"vote a"}, "p", "q")
+			if got["p"][0] != got["q"][0] {
+				if s := m.String(); !strings.Contains(s, `byz:49152 → p:80: equivocate "vote a" as "vote b"`) &&
+					!strings.Contains(s, `byz:49153 → q:80: equivocate "vote a" as "vote b"`) {
+					t.Errorf("misbehavior:\n%s", s)
+				}
+				return
+			}
+		}
+		t.Error("no seed made the peers disagree")
+	})
+}
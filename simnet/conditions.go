@@ -0,0 +1,154 @@
+package simnet
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+)
+
+// Conditions are the conditions of a link between nodes, applied to every
+// write on its connections.
+type Conditions struct {
+	Latency faultfs.Dist // nil for none
+	// Loss is the probability that a write is lost, and so delivered after
+	// a retransmission timeout, RTO, default 200ms, as TCP would.
+	Loss float64
+	RTO  time.Duration
+}
+
+func (c Conditions) rto() time.Duration {
+	if c.RTO > 0 {
+		return c.RTO
+	}
+	return 200 * time.Millisecond
+}
+
+// Seed reseeds the latencies and losses nw draws. Those of a write are a
+// function of the seed, the nodes and port of its connection, how many
+// connections the node dialing it dialed there before, and how many
+// writes came before on it, not of when it happens or of the order of
+// writes on other connections.
+func (nw *Network) Seed(seed uint64) {
+	nw.mu.Lock()
+	nw.seed = seed
+	nw.mu.Unlock()
+}
+
+// SetConditions sets the conditions of every link of nw but those set
+// with SetLink.
+func (nw *Network) SetConditions(c Conditions) {
+	nw.mu.Lock()
+	nw.conditions = c
+	nw.mu.Unlock()
+}
+
+// SetLink sets the conditions of the link between the nodes a and b, both
+// ways.
+func (nw *Network) SetLink(a, b string, c Conditions) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if nw.links == nil {
+		nw.links = make(map[[2]string]Conditions)
+	}
+	nw.links[pair(a, b)] = c
+}
+
+func pair(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// delay draws how long the write n on c takes, with nw.mu held.
+func (nw *Network) delay(c *conn, n int) time.Duration {
+	cond, ok := nw.links[pair(c.proc().node.Name, c.peer().node.Name)]
+	if !ok {
+		cond = nw.conditions
+	}
+	if cond.Latency == nil && cond.Loss == 0 {
+		return 0
+	}
+	r := c.rand(nw.seed, n)
+	var d time.Duration
+	if cond.Latency != nil {
+		d = max(cond.Latency(r), 0)
+	}
+	if cond.Loss > 0 && r.Float64() < cond.Loss {
+		d += cond.rto()
+	}
+	return d
+}
+
+// Nodes returns the nodes of nw, by name.
+func (nw *Network) Nodes() []*Node {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	ns := make([]*Node, 0, len(nw.nodes))
+	for _, n := range nw.nodes {
+		ns = append(ns, n)
+	}
+	slices.SortFunc(ns, func(a, b *Node) int { return strings.Compare(a.Name, b.Name) })
+	return ns
+}
+
+// Disconnect resets the connections of n at both ends, as a network
+// blip would, without crashing its process.
+func (n *Node) Disconnect() {
+	n.net.mu.Lock()
+	defer n.net.mu.Unlock()
+	if n.proc == nil {
+		return
+	}
+	for l := range n.proc.links {
+		l.reset()
+	}
+}
+
+// Partition splits the nodes of nw into groups that cannot reach each
+// other, the nodes in none of groups forming one more: the connections
+// between groups are reset and dials across them fail with
+// syscall.EHOSTUNREACH, until Heal or another Partition.
+func (nw *Network) Partition(groups ...[]string) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.sides = make(map[string]int)
+	for i, g := range groups {
+		for _, n := range g {
+			nw.sides[n] = i + 1
+		}
+	}
+	for _, n := range nw.nodes {
+		if n.proc == nil {
+			continue
+		}
+		for l := range n.proc.links {
+			if !nw.reachable(l.procs[0].node.Name, l.procs[1].node.Name) {
+				l.reset()
+			}
+		}
+	}
+}
+
+// Heal ends the partition of nw.
+func (nw *Network) Heal() {
+	nw.mu.Lock()
+	nw.sides = nil
+	nw.mu.Unlock()
+}
+
+// Reachable reports whether the nodes a and b can reach each other, being
+// on the same side of the partition of nw, if any.
+func (nw *Network) Reachable(a, b string) bool {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return nw.reachable(a, b)
+}
+
+// reachable reports whether the nodes a and b are on the same side of the
+// partition, if any, with nw.mu held.
+func (nw *Network) reachable(a, b string) bool {
+	return nw.sides == nil || nw.sides[a] == nw.sides[b]
+}
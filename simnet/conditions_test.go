@@ -0,0 +1,101 @@
+package simnet
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+)
+
+func TestConditions(t *testing.T) {
+	synctest.Run(func() {
+		nw := New()
+		defer nw.Close()
+		got := make(chan string, 10)
+		nw.Add("near", sink(got))
+		nw.Add("far", sink(got))
+		p := nw.Add("client", func(*Proc) {}).Proc()
+		nw.SetConditions(Conditions{Latency: faultfs.Constant(time.Millisecond)})
+		nw.SetLink("client", "far", Conditions{Latency: faultfs.Constant(time.Second), Loss: 1})
+		for _, c := range []struct {
+			node string
+			want time.Duration
+		}{{"near", time.Millisecond}, {"far", time.Second + 200*time.Millisecond}} {
+			c1, err := p.Dial(c.node + ":80")
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			c1.Write([]byte("x"))
+			<-got
+			if d := time.Since(start); d != c.want {
+				t.Errorf("write to %s took %v, want %v", c.node, d, c.want)
+			}
+		}
+	})
+}
+
+func TestPartition(t *testing.T) {
+	synctest.Run(func() {
+		nw := New()
+		defer nw.Close()
+		got := make(chan string, 10)
+		nw.Add("a", sink(got))
+		nw.Add("b", sink(got))
+		p := nw.Add("c", func(*Proc) {}).Proc()
+		c, err := p.Dial("a:80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nw.Partition([]string{"a"})
+		if _, err := c.Write([]byte("x")); !errors.Is(err, syscall.ECONNRESET) {
+			t.Errorf("write across the partition: %v, want ECONNRESET", err)
+		}
+		if _, err := p.Dial("a:80"); !errors.Is(err, syscall.EHOSTUNREACH) {
+			t.Errorf("dial across the partition: %v, want EHOSTUNREACH", err)
+		}
+		if _, err := p.Dial("b:80"); err != nil {
+			t.Errorf("dial on the same side: %v", err)
+		}
+		if nw.Reachable("a", "c") || !nw.Reachable("b", "c") {
+			t.Errorf("a reachable from c: %v, b: %v; want false, true", nw.Reachable("a", "c"), nw.Reachable("b", "c"))
+		}
+		nw.Heal()
+		if _, err := p.Dial("a:80"); err != nil {
+			t.Errorf("dial once healed: %v", err)
+		}
+	})
+}
+
+func TestMailbox(t *testing.T) {
+	synctest.Run(func() {
+		nw := New()
+		defer nw.Close()
+		boxes := make(map[string]*Mailbox)
+		for _, name := range []string{"a", "b"} {
+			nw.Add(name, func(p *Proc) { boxes[name], _ = p.Mailbox("7") })
+		}
+		boxes["a"].Send("b", []byte("one"))
+		boxes["a"].Send("b", []byte("two"))
+		boxes["b"].Send("a", []byte("three"))
+		for _, want := range []Message{{"a", []byte("one")}, {"a", []byte("two")}} {
+			if m := <-boxes["b"].Inbox(); m.From != want.From || string(m.Data) != string(want.Data) {
+				t.Errorf("b received %s: %q, want %s: %q", m.From, m.Data, want.From, want.Data)
+			}
+		}
+		if m := <-boxes["a"].Inbox(); m.From != "b" || string(m.Data) != "three" {
+			t.Errorf("a received %s: %q", m.From, m.Data)
+		}
+		nw.Partition([]string{"a"})
+		boxes["a"].Send("b", []byte("lost"))
+		synctest.Wait()
+		select {
+		case m := <-boxes["b"].Inbox():
+			t.Errorf("b received %q across the partition", m.Data)
+		default:
+		}
+	})
+}
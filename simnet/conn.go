@@ -0,0 +1,239 @@
+package simnet
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Addr is the address of an endpoint, "node:port".
+type Addr string
+
+func (a Addr) Network() string { return "sim" }
+func (a Addr) String() string  { return string(a) }
+
+// Listen listens on port of the node of p.
+func (p *Proc) Listen(port string) (net.Listener, error) {
+	nw := p.node.net
+	a := Addr(net.JoinHostPort(p.node.Name, port))
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if p.dead {
+		return nil, &net.OpError{Op: "listen", Net: "sim", Addr: a, Err: ErrCrashed}
+	}
+	if p.listeners[port] != nil {
+		return nil, &net.OpError{Op: "listen", Net: "sim", Addr: a, Err: syscall.EADDRINUSE}
+	}
+	l := &listener{p: p, port: port, addr: a, accept: make(chan net.Conn), done: make(chan struct{})}
+	p.listeners[port] = l
+	return l, nil
+}
+
+// Dial connects to addr, "node:port", from the node of p. It fails with
+// syscall.ECONNREFUSED if no process listens there, and blocks until one
+// accepts the connection otherwise.
+func (p *Proc) Dial(addr string) (net.Conn, error) {
+	nw := p.node.net
+	fail := func(err error) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: "sim", Addr: Addr(addr), Err: err}
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fail(err)
+	}
+	nw.mu.Lock()
+	if p.dead {
+		nw.mu.Unlock()
+		return fail(ErrCrashed)
+	}
+	n := nw.nodes[host]
+	if n == nil || !nw.reachable(p.node.Name, host) {
+		nw.mu.Unlock()
+		return fail(syscall.EHOSTUNREACH)
+	}
+	var l *listener
+	if n.proc != nil {
+		l = n.proc.listeners[port]
+	}
+	if l == nil {
+		nw.mu.Unlock()
+		return fail(syscall.ECONNREFUSED)
+	}
+	nw.port++
+	local := Addr(net.JoinHostPort(p.node.Name, strconv.Itoa(nw.port)))
+	c, s := net.Pipe()
+	lk := &link{procs: [2]*Proc{p, l.p}}
+	k := p.node.Name + ">" + addr
+	key := fmt.Sprintf("%s#%d", k, nw.dials[k])
+	nw.dials[k]++
+	lk.ends[0] = &conn{Conn: c, l: lk, local: local, remote: l.addr, key: hash(key + ">")}
+	lk.ends[1] = &conn{Conn: s, l: lk, local: l.addr, remote: local, key: hash(key + "<")}
+	p.links[lk], l.p.links[lk] = true, true
+	nw.mu.Unlock()
+	select {
+	case l.accept <- lk.ends[1]:
+		return lk.ends[0], nil
+	case <-l.done:
+		lk.close()
+		return fail(syscall.ECONNREFUSED)
+	}
+}
+
+type listener struct {
+	p      *Proc
+	port   string
+	addr   Addr
+	accept chan net.Conn
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.done:
+		return nil, &net.OpError{Op: "accept", Net: "sim", Addr: l.addr, Err: net.ErrClosed}
+	}
+}
+
+func (l *listener) Close() error {
+	nw := l.p.node.net
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	l.close()
+	return nil
+}
+
+// close closes l, with nw.mu held.
+func (l *listener) close() {
+	if l.p.listeners[l.port] == l {
+		delete(l.p.listeners, l.port)
+	}
+	l.once.Do(func() { close(l.done) })
+}
+
+func (l *listener) Addr() net.Addr { return l.addr }
+
+// link is a connection between two processes.
+type link struct {
+	procs  [2]*Proc
+	ends   [2]*conn
+	broken atomic.Bool // by a crash
+}
+
+// reset resets l, with nw.mu held.
+func (l *link) reset() {
+	l.broken.Store(true)
+	l.close()
+}
+
+func (l *link) close() {
+	for _, c := range l.ends {
+		c.Conn.Close()
+	}
+}
+
+// conn is an end of a link.
+type conn struct {
+	net.Conn
+	l             *link
+	local, remote Addr
+	key           uint64 // of the end, the same in every run
+	writes        int    // guarded by nw.mu
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// rand returns the random numbers of the write n on c, from seed.
+func (c *conn) rand(seed uint64, n int) *rand.Rand {
+	return rand.New(rand.NewPCG(seed^c.key, uint64(n)))
+}
+
+func (c *conn) LocalAddr() net.Addr  { return c.local }
+func (c *conn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *conn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	return n, c.err("read", err)
+}
+
+// Write writes b, or what the misbehavior of the node of c sends in its
+// place, as if b was written. A write delayed by the conditions of the
+// link fails at once if the process crashes meanwhile.
+func (c *conn) Write(b []byte) (int, error) {
+	nw := c.l.procs[0].node.net
+	nw.mu.Lock()
+	n := c.writes
+	c.writes++
+	d := nw.delay(c, n)
+	m := c.proc().node.byz
+	nw.mu.Unlock()
+	if d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-c.proc().ctx.Done():
+			t.Stop()
+			return 0, &net.OpError{Op: "write", Net: "sim", Source: c.local, Addr: c.remote, Err: ErrCrashed}
+		}
+	}
+	if m != nil {
+		sent := m.tamper(c, n, b)
+		if sent == nil {
+			return len(b), nil
+		}
+		if _, err := c.Conn.Write(sent); err != nil {
+			return 0, c.err("write", err)
+		}
+		return len(b), nil
+	}
+	n, err := c.Conn.Write(b)
+	return n, c.err("write", err)
+}
+
+// proc returns the process of the local end of c.
+func (c *conn) proc() *Proc {
+	if c == c.l.ends[0] {
+		return c.l.procs[0]
+	}
+	return c.l.procs[1]
+}
+
+// peer returns the process of the remote end of c.
+func (c *conn) peer() *Proc {
+	if c == c.l.ends[0] {
+		return c.l.procs[1]
+	}
+	return c.l.procs[0]
+}
+
+// err reports the errors of a reset connection as such.
+func (c *conn) err(op string, err error) error {
+	if err == nil || !c.l.broken.Load() {
+		return err
+	}
+	return &net.OpError{Op: op, Net: "sim", Source: c.local, Addr: c.remote, Err: syscall.ECONNRESET}
+}
+
+// Close closes the connection at both ends, like closing a TCP
+// connection, which the other end reads as EOF.
+func (c *conn) Close() error {
+	nw := c.l.procs[0].node.net
+	nw.mu.Lock()
+	for _, p := range c.l.procs {
+		delete(p.links, c.l)
+	}
+	nw.mu.Unlock()
+	return c.Conn.Close()
+}
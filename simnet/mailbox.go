@@ -0,0 +1,124 @@
+package simnet
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+// Message is a message received by a Mailbox.
+type Message struct {
+	From string // node
+	Data []byte
+}
+
+// Mailbox sends messages to the mailboxes on the same port of other nodes
+// and receives theirs, best effort, as the RPCs of consensus and gossip
+// protocols do: messages are lost when their connection fails, a queue is
+// full or the process crashed, and are otherwise delivered in the order
+// they were sent to each node.
+type Mailbox struct {
+	p     *Proc
+	port  string
+	inbox chan Message
+
+	mu  sync.Mutex
+	out map[string]chan []byte // by node
+}
+
+// Capacity of the queues of a Mailbox, beyond which messages are lost.
+const (
+	outQueue = 256
+	inQueue  = 1024
+)
+
+// Mailbox listens on port and returns the mailbox of p there.
+func (p *Proc) Mailbox(port string) (*Mailbox, error) {
+	l, err := p.Listen(port)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mailbox{p: p, port: port, inbox: make(chan Message, inQueue), out: make(map[string]chan []byte)}
+	p.Go(func(ctx context.Context) {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			from, _, _ := net.SplitHostPort(c.RemoteAddr().String())
+			p.Go(func(context.Context) { m.receive(c, from) })
+		}
+	})
+	return m, nil
+}
+
+// Inbox returns the messages received, in a channel never closed.
+func (m *Mailbox) Inbox() <-chan Message { return m.inbox }
+
+// Send queues data for the node to and reports whether it was queued.
+func (m *Mailbox) Send(to string, data []byte) bool {
+	m.mu.Lock()
+	q := m.out[to]
+	if q == nil {
+		q = make(chan []byte, outQueue)
+		m.out[to] = q
+		m.p.Go(func(ctx context.Context) { m.send(ctx, to, q) })
+	}
+	m.mu.Unlock()
+	select {
+	case q <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// send sends the messages queued for the node to, dialing it as needed.
+func (m *Mailbox) send(ctx context.Context, to string, q chan []byte) {
+	var c net.Conn
+	defer func() {
+		if c != nil {
+			c.Close()
+		}
+	}()
+	for {
+		var data []byte
+		select {
+		case <-ctx.Done():
+			return
+		case data = <-q:
+		}
+		if c == nil {
+			var err error
+			if c, err = m.p.Dial(net.JoinHostPort(to, m.port)); err != nil {
+				c = nil
+				continue
+			}
+		}
+		frame := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		if _, err := c.Write(append(frame, data...)); err != nil {
+			c.Close()
+			c = nil
+		}
+	}
+}
+
+func (m *Mailbox) receive(c net.Conn, from string) {
+	defer c.Close()
+	var n [4]byte
+	for {
+		if _, err := io.ReadFull(c, n[:]); err != nil {
+			return
+		}
+		data := make([]byte, binary.BigEndian.Uint32(n[:]))
+		if _, err := io.ReadFull(c, data); err != nil {
+			return
+		}
+		select {
+		case m.inbox <- Message{from, data}:
+		default:
+		}
+	}
+}
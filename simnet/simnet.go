@@ -0,0 +1,221 @@
+// Package simnet is a virtual network of nodes for multi-node scenarios
+// in a synctest bubble: every node runs a process, booted by a function
+// of the test, that listens and dials by node name over in-memory
+// connections and keeps its state on a disk of its own, a faultfs.FS.
+//
+// A node can crash: its process is abandoned, its connections are reset
+// and its disk loses what was not synced. Restart boots the process again
+// from what the disk kept, so crash-recovery code can be exercised, also
+// at points in the middle of an operation with CrashPolicy. A node can
+// also turn Byzantine, with Misbehave, and tamper with what it sends, and
+// the links between nodes can be slow, lossy or partitioned; see
+// SetConditions and Partition.
+package simnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/failpoint"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// ErrCrashed is the error of the operations of a crashed process.
+var ErrCrashed = errors.New("simnet: process crashed")
+
+// Network is a set of nodes. Create it inside the bubble its nodes run
+// in.
+type Network struct {
+	mu         sync.Mutex
+	nodes      map[string]*Node
+	port       int            // last ephemeral port handed out
+	seed       uint64         // of the latencies and losses drawn
+	dials      map[string]int // connections dialed, by "node>addr"
+	conditions Conditions
+	links      map[[2]string]Conditions // by the names of the nodes, sorted
+	sides      map[string]int           // of the partition by node, nil if none
+}
+
+// New returns an empty Network, with perfect links.
+func New() *Network {
+	return &Network{nodes: make(map[string]*Node), port: 49151, dials: make(map[string]int)}
+}
+
+// Boot starts the process of a node: it reads the node's state from
+// p.Disk, starts the goroutines of the process with p.Go and returns.
+type Boot func(p *Proc)
+
+// Node is a machine of a Network.
+type Node struct {
+	Name string
+	Disk *faultfs.FS // survives crashes, as far as synced
+
+	net  *Network
+	boot Boot
+	proc *Proc        // running, or nil while down; guarded by net.mu
+	runs int          // incarnations booted
+	byz  *Misbehavior // guarded by net.mu
+}
+
+// Add adds the node name to nw and boots it with boot.
+func (nw *Network) Add(name string, boot Boot) *Node {
+	n := &Node{Name: name, Disk: faultfs.New(), net: nw, boot: boot}
+	nw.mu.Lock()
+	if nw.nodes[name] != nil {
+		nw.mu.Unlock()
+		panic(fmt.Sprintf("simnet: node %s added twice", name))
+	}
+	nw.nodes[name] = n
+	nw.mu.Unlock()
+	n.start()
+	return n
+}
+
+// Node returns the node name, or nil.
+func (nw *Network) Node(name string) *Node {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return nw.nodes[name]
+}
+
+// Close crashes every node of nw, for the end of a test, so that their
+// goroutines return and the bubble can end.
+func (nw *Network) Close() {
+	nw.mu.Lock()
+	nodes := make([]*Node, 0, len(nw.nodes))
+	for _, n := range nw.nodes {
+		nodes = append(nodes, n)
+	}
+	nw.mu.Unlock()
+	for _, n := range nodes {
+		n.Crash()
+	}
+}
+
+func (n *Node) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	n.net.mu.Lock()
+	n.runs++
+	p := &Proc{
+		node: n, ctx: ctx, cancel: cancel, incarnation: n.runs,
+		listeners: make(map[string]*listener), links: make(map[*link]bool), goroutines: make(map[int64]bool),
+	}
+	n.proc = p
+	n.net.mu.Unlock()
+	n.boot(p)
+}
+
+// Proc returns the running process of n, or nil while it is down.
+func (n *Node) Proc() *Proc {
+	n.net.mu.Lock()
+	defer n.net.mu.Unlock()
+	return n.proc
+}
+
+// Up reports whether the process of n runs.
+func (n *Node) Up() bool {
+	n.net.mu.Lock()
+	defer n.net.mu.Unlock()
+	return n.proc != nil
+}
+
+// Crash crashes the process of n, if it runs: its context is canceled,
+// its operations fail with ErrCrashed from now on, its listeners are
+// closed, its connections reset at both ends, and its disk loses what was
+// not synced; see faultfs.FS.Crash. Its goroutines are abandoned, not
+// waited for, so they must return once their operations fail.
+func (n *Node) Crash() {
+	n.net.mu.Lock()
+	p := n.proc
+	if p == nil {
+		n.net.mu.Unlock()
+		return
+	}
+	n.proc, p.dead = nil, true
+	p.cancel()
+	for _, l := range p.listeners {
+		l.close()
+	}
+	for l := range p.links {
+		l.reset()
+	}
+	n.net.mu.Unlock()
+	n.Disk.Crash()
+}
+
+// Restart crashes n if it runs and boots it again from its disk, with
+// boot, or the boot it last ran with if nil. It returns once boot did.
+func (n *Node) Restart(boot Boot) {
+	n.Crash()
+	if boot != nil {
+		n.boot = boot
+	}
+	n.start()
+}
+
+// CrashPolicy returns a failpoint.Policy that crashes n when hit: a
+// goroutine of its process that hits it ends there, as with
+// runtime.Goexit, in the middle of what it was doing; any other gets
+// ErrCrashed.
+func (n *Node) CrashPolicy() failpoint.Policy {
+	return func(int) error {
+		id := gstack.Self().ID
+		n.net.mu.Lock()
+		p := n.proc
+		own := p != nil && p.goroutines[id]
+		n.net.mu.Unlock()
+		n.Crash()
+		if own {
+			runtime.Goexit()
+		}
+		return ErrCrashed
+	}
+}
+
+// Proc is one incarnation of the process of a node.
+type Proc struct {
+	node        *Node
+	ctx         context.Context
+	cancel      context.CancelFunc
+	incarnation int
+
+	// guarded by node.net.mu
+	dead       bool
+	listeners  map[string]*listener // by port
+	links      map[*link]bool
+	goroutines map[int64]bool // started by Go
+}
+
+// Node returns the node p runs on.
+func (p *Proc) Node() *Node { return p.node }
+
+// Disk returns the disk of the node p runs on.
+func (p *Proc) Disk() *faultfs.FS { return p.node.Disk }
+
+// Incarnation returns the number of times the node booted up to p,
+// counting from 1.
+func (p *Proc) Incarnation() int { return p.incarnation }
+
+// Context returns the context of p, canceled when it crashes.
+func (p *Proc) Context() context.Context { return p.ctx }
+
+// Go runs f in a goroutine of p, with the context of p, unless p crashed.
+func (p *Proc) Go(f func(ctx context.Context)) {
+	nw := p.node.net
+	nw.mu.Lock()
+	dead := p.dead
+	nw.mu.Unlock()
+	if dead {
+		return
+	}
+	go func() {
+		nw.mu.Lock()
+		p.goroutines[gstack.Self().ID] = true
+		nw.mu.Unlock()
+		f(p.ctx)
+	}()
+}
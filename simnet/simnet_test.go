@@ -0,0 +1,139 @@
+package simnet
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"testing/synctest"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/failpoint"
+)
+
+// counter serves a counter kept on disk: every line read increments it,
+// and the new count is written back once persisted.
+func counter(p *Proc) {
+	data, _ := p.Disk().ReadFile("count")
+	var mu sync.Mutex
+	n, _ := strconv.Atoi(string(data))
+	l, err := p.Listen("80")
+	if err != nil {
+		return
+	}
+	incr := func() (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		f, err := p.Disk().Create("count")
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		fmt.Fprint(f, n+1)
+		if err := failpoint.Inject("counter/persist"); err != nil {
+			return 0, err
+		}
+		if err := f.Sync(); err != nil {
+			return 0, err
+		}
+		n++
+		return n, nil
+	}
+	p.Go(func(context.Context) {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			p.Go(func(context.Context) {
+				defer c.Close()
+				for sc := bufio.NewScanner(c); sc.Scan(); {
+					n, err := incr()
+					if err != nil {
+						return
+					}
+					fmt.Fprintln(c, n)
+				}
+			})
+		}
+	})
+}
+
+type client struct {
+	c  net.Conn
+	sc *bufio.Scanner
+}
+
+func dial(t *testing.T, p *Proc) *client {
+	c, err := p.Dial("server:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{c, bufio.NewScanner(c)}
+}
+
+func (cl *client) incr() (string, error) {
+	if _, err := fmt.Fprintln(cl.c, "incr"); err != nil {
+		return "", err
+	}
+	if !cl.sc.Scan() {
+		return "", cl.sc.Err()
+	}
+	return cl.sc.Text(), nil
+}
+
+func TestCrashRestart(t *testing.T) {
+	synctest.Run(func() {
+		nw := New()
+		srv := nw.Add("server", counter)
+		cp := nw.Add("client", func(*Proc) {}).Proc()
+		cl := dial(t, cp)
+		for _, want := range []string{"1", "2"} {
+			if got, err := cl.incr(); got != want || err != nil {
+				t.Fatalf("incr: %q, %v; want %s", got, err, want)
+			}
+		}
+		ctx := srv.Proc().Context()
+		srv.Crash()
+		if _, err := cl.incr(); !errors.Is(err, syscall.ECONNRESET) {
+			t.Errorf("incr on a crashed server: %v, want ECONNRESET", err)
+		}
+		if ctx.Err() == nil || srv.Up() {
+			t.Error("crashed server still up")
+		}
+		if _, err := cp.Dial("server:80"); !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Errorf("dial of a crashed server: %v, want ECONNREFUSED", err)
+		}
+		srv.Restart(nil)
+		if n := srv.Proc().Incarnation(); n != 2 {
+			t.Errorf("incarnation %d after restart, want 2", n)
+		}
+		if got, err := dial(t, cp).incr(); got != "3" || err != nil {
+			t.Errorf("incr after restart: %q, %v; want 3", got, err)
+		}
+		nw.Close()
+	})
+}
+
+func TestCrashPolicy(t *testing.T) {
+	synctest.Run(func() {
+		nw := New()
+		srv := nw.Add("server", counter)
+		failpoint.Enable(t, "counter/persist", failpoint.OnHit(2, srv.CrashPolicy()))
+		cl := dial(t, nw.Add("client", func(*Proc) {}).Proc())
+		cl.incr()
+		if _, err := cl.incr(); err == nil {
+			t.Error("incr crashing the server mid-way succeeded")
+		}
+		srv.Restart(nil)
+		data, _ := srv.Disk.ReadFile("count")
+		if string(data) != "1" {
+			t.Errorf("count %q on disk after the crash, want the synced 1", data)
+		}
+		nw.Close()
+	})
+}
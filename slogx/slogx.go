@@ -0,0 +1,117 @@
+// Package slogx captures the structured logs of concurrent tests. Its
+// handler stamps every record with the goroutine that emitted it and the
+// virtual time of its bubble, keeps the records of each bubble apart, and
+// only shows them if the test fails, rather than interleaving them with
+// the output of other tests.
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// stamp formats virtual times in UTC, in which bubbles start at midnight.
+const stamp = "15:04:05.000"
+
+// Handler is a slog.Handler buffering the records of a test.
+type Handler struct {
+	log   *buffer
+	inner slog.Handler // formats a record, without its time, into log.buf
+}
+
+// buffer holds the records of a test by bubble.
+type buffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	bubbles []int64            // in the order of their first records
+	lines   map[int64][]string // by bubble, 0 for none
+}
+
+// NewHandler returns a Handler logging the records of t, formatted by
+// slog.TextHandler as set by opts, which may be nil. Once t ends, if it
+// failed, the records are logged on it, those of each bubble together.
+func NewHandler(t testing.TB, opts *slog.HandlerOptions) *Handler {
+	t.Helper()
+	var o slog.HandlerOptions
+	if opts != nil {
+		o = *opts
+	}
+	replace := o.ReplaceAttr
+	o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		if replace != nil {
+			return replace(groups, a)
+		}
+		return a
+	}
+	b := &buffer{lines: make(map[int64][]string)}
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Log(b.String())
+		}
+	})
+	return &Handler{log: b, inner: slog.NewTextHandler(&b.buf, &o)}
+}
+
+// New returns a logger writing to a NewHandler of t.
+func New(t testing.TB) *slog.Logger {
+	t.Helper()
+	return slog.New(NewHandler(t, nil))
+}
+
+func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool { return h.inner.Enabled(ctx, l) }
+
+func (h *Handler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &Handler{h.log, h.inner.WithAttrs(as)}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{h.log, h.inner.WithGroup(name)}
+}
+
+// Handle buffers r, stamped with its time, which is virtual inside a
+// bubble, and the name of the calling goroutine.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	self := gstack.Self()
+	b := h.log
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+	if err := h.inner.Handle(ctx, r); err != nil {
+		return err
+	}
+	if _, ok := b.lines[self.Group]; !ok {
+		b.bubbles = append(b.bubbles, self.Group)
+	}
+	line := fmt.Sprintf("%s %s: %s", r.Time.UTC().Format(stamp), goid.Name(self.ID), strings.TrimSuffix(b.buf.String(), "\n"))
+	b.lines[self.Group] = append(b.lines[self.Group], line)
+	return nil
+}
+
+// String returns the records, by bubble.
+func (b *buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var s strings.Builder
+	for _, g := range b.bubbles {
+		if g == 0 {
+			fmt.Fprintf(&s, "log outside bubbles:\n")
+		} else {
+			fmt.Fprintf(&s, "log of the bubble of goroutine %d:\n", g)
+		}
+		for _, l := range b.lines[g] {
+			fmt.Fprintf(&s, "\t%s\n", l)
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,71 @@
+package slogx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// cleanupT runs the cleanups of a Recorder on demand and keeps its logs.
+type cleanupT struct {
+	*testtb.Recorder
+	cleanups []func()
+	logs     []string
+}
+
+func (t *cleanupT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *cleanupT) Log(args ...any)  { t.logs = append(t.logs, fmt.Sprint(args...)) }
+func (t *cleanupT) end() {
+	for _, f := range t.cleanups {
+		f()
+	}
+}
+
+func logInBubble(t testing.TB) {
+	log := New(t)
+	synctest.Run(func() {
+		done := make(chan struct{})
+		go func() {
+			time.Sleep(1500 * time.Millisecond)
+			log.Info("tick", "n", 1)
+			close(done)
+		}()
+		<-done
+		log.Warn("done")
+	})
+}
+
+func TestDumpOnFailure(t *testing.T) {
+	ft := &cleanupT{Recorder: testtb.New(t)}
+	logInBubble(ft)
+	ft.Error("fail")
+	ft.end()
+	if len(ft.logs) != 1 {
+		t.Fatalf("logged %q, want one dump", ft.logs)
+	}
+	lines := strings.Split(strings.TrimSpace(ft.logs[0]), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "log of the bubble of goroutine ") {
+		t.Fatalf("dump %q, want a header and two records", ft.logs[0])
+	}
+	for i, want := range []string{
+		"00:00:01.500 ", `level=INFO msg=tick n=1`,
+		"00:00:01.500 ", `level=WARN msg=done`,
+	} {
+		if l := lines[1+i/2]; !strings.Contains(l, want) {
+			t.Errorf("record %q lacks %q", l, want)
+		}
+	}
+}
+
+func TestNoDumpOnSuccess(t *testing.T) {
+	ft := &cleanupT{Recorder: testtb.New(t)}
+	logInBubble(ft)
+	ft.end()
+	if len(ft.logs) != 0 {
+		t.Errorf("logged %q on success", ft.logs)
+	}
+}
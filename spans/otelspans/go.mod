@@ -0,0 +1,11 @@
+module github.com/denisjgr/Go-Project-Modelbased-SE/spans/otelspans
+
+go 1.24.0
+
+require (
+	github.com/denisjgr/Go-Project-Modelbased-SE v0.0.0
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+)
+
+replace github.com/denisjgr/Go-Project-Modelbased-SE => ../..
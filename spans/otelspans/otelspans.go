@@ -0,0 +1,126 @@
+// Package otelspans is an OpenTelemetry TracerProvider recording into a
+// spans.Tracer, for testing code instrumented with OpenTelemetry: the
+// spans it starts are timed by the clock of their bubble, whatever
+// timestamps the code passes, and asserted on with the Tracer.
+//
+//	tr := spans.Record(t)
+//	srv := newServer(otelspans.NewTracerProvider(tr))
+//
+// It is a module of its own, so that the one of package spans does not
+// depend on OpenTelemetry. A span with an error status gets the
+// attributes otel.status_code and otel.status_description, as exporters
+// without statuses of their own record them; the name a span is given
+// after it started and its links are not recorded.
+package otelspans
+
+import (
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/spans"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// TracerProvider provides tracers recording into the same spans.Tracer.
+type TracerProvider struct {
+	embedded.TracerProvider
+	tr  *spans.Tracer
+	ids atomic.Uint64 // the last trace and span ID given out
+}
+
+// NewTracerProvider returns a TracerProvider recording into tr.
+func NewTracerProvider(tr *spans.Tracer) *TracerProvider {
+	return &TracerProvider{tr: tr}
+}
+
+// Tracer returns a tracer of p. Its name and options are not recorded.
+func (p *TracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return &tracer{p: p}
+}
+
+type tracer struct {
+	embedded.Tracer
+	p *TracerProvider
+}
+
+// Start starts a span named name, a child of the span of ctx if it is
+// one of the provider and the options do not ask for a new root.
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	parent, ok := trace.SpanFromContext(ctx).(*span)
+	if !ok || parent.p != t.p || cfg.NewRoot() {
+		parent = nil
+	}
+	var traceID trace.TraceID
+	if parent != nil {
+		ctx, traceID = spans.ContextWithSpan(ctx, parent.s), parent.sc.TraceID()
+	} else {
+		ctx = spans.ContextWithSpan(ctx, nil)
+		binary.BigEndian.PutUint64(traceID[8:], t.p.ids.Add(1))
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], t.p.ids.Add(1))
+	ctx, s := t.p.tr.Start(ctx, name, attrs(cfg.Attributes())...)
+	sp := &span{p: t.p, s: s, sc: trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})}
+	return trace.ContextWithSpan(ctx, sp), sp
+}
+
+// span is a span of a TracerProvider, recorded by s.
+type span struct {
+	embedded.Span
+	p     *TracerProvider
+	s     *spans.Span
+	sc    trace.SpanContext
+	ended atomic.Bool
+}
+
+func (sp *span) End(options ...trace.SpanEndOption) {
+	sp.ended.Store(true)
+	sp.s.End()
+}
+
+func (sp *span) AddEvent(name string, options ...trace.EventOption) {
+	cfg := trace.NewEventConfig(options...)
+	sp.s.AddEvent(name, attrs(cfg.Attributes())...)
+}
+
+func (sp *span) AddLink(link trace.Link) {}
+
+func (sp *span) IsRecording() bool { return !sp.ended.Load() }
+
+func (sp *span) RecordError(err error, options ...trace.EventOption) {
+	if err != nil {
+		sp.s.RecordError(err)
+	}
+}
+
+func (sp *span) SpanContext() trace.SpanContext { return sp.sc }
+
+func (sp *span) SetStatus(code codes.Code, description string) {
+	if code == codes.Error {
+		sp.s.SetAttributes(spans.String("otel.status_code", "ERROR"), spans.String("otel.status_description", description))
+	}
+}
+
+func (sp *span) SetName(name string) {}
+
+func (sp *span) SetAttributes(kv ...attribute.KeyValue) { sp.s.SetAttributes(attrs(kv)...) }
+
+func (sp *span) TracerProvider() trace.TracerProvider { return sp.p }
+
+// attrs converts the attributes kv.
+func attrs(kv []attribute.KeyValue) []spans.Attr {
+	as := make([]spans.Attr, len(kv))
+	for i, a := range kv {
+		as[i] = spans.Attr{Key: string(a.Key), Value: a.Value.AsInterface()}
+	}
+	return as
+}
@@ -0,0 +1,71 @@
+package otelspans
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/spans"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// handle serves a request, fetching from a backend that takes 5s.
+func handle(ctx context.Context, tp trace.TracerProvider) {
+	tracer := tp.Tracer("shop")
+	ctx, span := tracer.Start(ctx, "handle", trace.WithAttributes(attribute.String("path", "/a")))
+	defer span.End()
+	time.Sleep(time.Second)
+	_, fetch := tracer.Start(ctx, "fetch", trace.WithTimestamp(time.Unix(0, 0)))
+	time.Sleep(5 * time.Second)
+	fetch.SetStatus(codes.Error, "backend gone")
+	fetch.End()
+	span.SetAttributes(attribute.Int("status", 502))
+}
+
+func TestTracerProvider(t *testing.T) {
+	synctest.Run(func() {
+		tr := spans.Record(t)
+		handle(context.Background(), NewTracerProvider(tr))
+
+		fetch, ok := tr.Find("fetch")
+		if !ok {
+			t.Fatalf("no span fetch in\n%v", tr)
+		}
+		if parent, ok := tr.Parent(fetch); !ok || parent.Name != "handle" {
+			t.Errorf("fetch is a child of %+v, want handle", parent)
+		}
+		if d := fetch.Duration(); d != 5*time.Second {
+			t.Errorf("fetch lasted %v, want 5s", d)
+		}
+		if want := []spans.Attr{spans.String("otel.status_code", "ERROR"), spans.String("otel.status_description", "backend gone")}; !reflect.DeepEqual(fetch.Attrs, want) {
+			t.Errorf("fetch attributes %v, want %v", fetch.Attrs, want)
+		}
+		handle, _ := tr.Find("handle")
+		if want := []spans.Attr{spans.String("path", "/a"), {Key: "status", Value: int64(502)}}; !reflect.DeepEqual(handle.Attrs, want) {
+			t.Errorf("handle attributes %v, want %v", handle.Attrs, want)
+		}
+		if want := "handle 6s\n\tfetch 5s\n"; tr.String() != want {
+			t.Errorf("spans\n%v\nwant\n%s", tr, want)
+		}
+	})
+}
+
+func TestSpanContext(t *testing.T) {
+	tp := NewTracerProvider(new(spans.Tracer))
+	ctx, root := tp.Tracer("t").Start(context.Background(), "root")
+	_, child := tp.Tracer("t").Start(ctx, "child")
+	_, other := tp.Tracer("t").Start(ctx, "other", trace.WithNewRoot())
+	if !root.SpanContext().IsValid() || child.SpanContext().TraceID() != root.SpanContext().TraceID() {
+		t.Errorf("child in trace %v, want %v", child.SpanContext().TraceID(), root.SpanContext().TraceID())
+	}
+	if other.SpanContext().TraceID() == root.SpanContext().TraceID() {
+		t.Errorf("new root in the trace of root")
+	}
+	if child.End(); child.IsRecording() {
+		t.Errorf("span recording after End")
+	}
+}
@@ -0,0 +1,211 @@
+// Package spans is an in-memory tracer for asserting on the spans code
+// under test emits, timed by the clock of its bubble: that span "fetch"
+// is a child of "handle" and lasted 5 virtual seconds. Its API follows
+// the tracer of OpenTelemetry, Start returning a context carrying the new
+// span and spans ending with End, so code instrumented behind a small
+// interface of its own can be given this tracer in tests and an
+// OpenTelemetry one in production. Code instrumented with OpenTelemetry
+// itself is given the TracerProvider of package otelspans, a module of
+// its own so that this one does not depend on OpenTelemetry.
+package spans
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Attr is an attribute of a span or of one of its events.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// String returns the attribute of key and value v.
+func String(key, v string) Attr { return Attr{key, v} }
+
+// Int returns the attribute of key and value v.
+func Int(key string, v int) Attr { return Attr{key, v} }
+
+// Event is something that happened during a span.
+type Event struct {
+	Name  string
+	At    time.Time
+	Attrs []Attr
+}
+
+// SpanID identifies a span of a Tracer; 0 is none.
+type SpanID int
+
+// Data is what a span recorded.
+type Data struct {
+	Name   string
+	ID     SpanID
+	Parent SpanID // 0 for a root span
+	Start  time.Time
+	End    time.Time // zero while the span runs
+	Attrs  []Attr
+	Events []Event
+	Err    error // the last one recorded
+}
+
+// Ended reports whether the span ended.
+func (d Data) Ended() bool { return !d.End.IsZero() }
+
+// Duration is how long the span lasted, or has lasted so far.
+func (d Data) Duration() time.Duration {
+	if !d.Ended() {
+		return time.Since(d.Start)
+	}
+	return d.End.Sub(d.Start)
+}
+
+// Span is a span being recorded. Its methods are safe for concurrent
+// use; those called after End are ignored.
+type Span struct {
+	tr *Tracer
+	i  int // index in tr.spans
+}
+
+// Tracer records spans. Its zero value is ready to use, and it is safe
+// for concurrent use.
+type Tracer struct {
+	mu    sync.Mutex
+	spans []Data
+}
+
+// Record returns a Tracer whose spans are logged if t fails.
+func Record(t testing.TB) *Tracer {
+	t.Helper()
+	tr := new(Tracer)
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("spans:\n%v", tr)
+		}
+	})
+	return tr
+}
+
+type spanKey struct{}
+
+// Start starts a span named name, a child of the span of ctx if any, and
+// returns a context carrying it.
+func (tr *Tracer) Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	var parent SpanID
+	if p := FromContext(ctx); p != nil && p.tr == tr {
+		parent = SpanID(p.i + 1)
+	}
+	tr.mu.Lock()
+	s := &Span{tr, len(tr.spans)}
+	tr.spans = append(tr.spans, Data{Name: name, ID: SpanID(s.i + 1), Parent: parent, Start: time.Now(), Attrs: slices.Clone(attrs)})
+	tr.mu.Unlock()
+	return ContextWithSpan(ctx, s), s
+}
+
+// FromContext returns the span of ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan returns a copy of ctx carrying s, so that the spans
+// started with it are children of s.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// update calls f with the data of s unless s ended.
+func (s *Span) update(f func(d *Data)) {
+	if s == nil {
+		return
+	}
+	s.tr.mu.Lock()
+	defer s.tr.mu.Unlock()
+	if d := &s.tr.spans[s.i]; !d.Ended() {
+		f(d)
+	}
+}
+
+// End ends s now.
+func (s *Span) End() { s.update(func(d *Data) { d.End = time.Now() }) }
+
+// SetAttributes adds attrs to s.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	s.update(func(d *Data) { d.Attrs = append(d.Attrs, attrs...) })
+}
+
+// AddEvent records an event named name in s now.
+func (s *Span) AddEvent(name string, attrs ...Attr) {
+	s.update(func(d *Data) { d.Events = append(d.Events, Event{name, time.Now(), slices.Clone(attrs)}) })
+}
+
+// RecordError records err in s, as an event too.
+func (s *Span) RecordError(err error) {
+	s.update(func(d *Data) {
+		d.Err = err
+		d.Events = append(d.Events, Event{"exception", time.Now(), []Attr{String("exception.message", err.Error())}})
+	})
+}
+
+// Spans returns what the spans recorded so far, in the order they
+// started.
+func (tr *Tracer) Spans() []Data {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	ds := slices.Clone(tr.spans)
+	for i := range ds {
+		ds[i].Attrs = slices.Clone(ds[i].Attrs)
+		ds[i].Events = slices.Clone(ds[i].Events)
+	}
+	return ds
+}
+
+// Find returns the first span named name.
+func (tr *Tracer) Find(name string) (Data, bool) {
+	for _, d := range tr.Spans() {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Data{}, false
+}
+
+// Parent returns the parent of d, if it has one.
+func (tr *Tracer) Parent(d Data) (Data, bool) {
+	ds := tr.Spans()
+	if d.Parent == 0 || int(d.Parent) > len(ds) {
+		return Data{}, false
+	}
+	return ds[d.Parent-1], true
+}
+
+// String draws the spans as a tree, each with its duration, and "running"
+// for those not ended.
+func (tr *Tracer) String() string {
+	ds := tr.Spans()
+	children := make(map[SpanID][]Data)
+	for _, d := range ds {
+		children[d.Parent] = append(children[d.Parent], d)
+	}
+	var b strings.Builder
+	var draw func(parent SpanID, depth int)
+	draw = func(parent SpanID, depth int) {
+		for _, d := range children[parent] {
+			fmt.Fprintf(&b, "%s%s %v", strings.Repeat("\t", depth), d.Name, d.Duration())
+			if !d.Ended() {
+				b.WriteString(" running")
+			}
+			if d.Err != nil {
+				fmt.Fprintf(&b, " error: %v", d.Err)
+			}
+			b.WriteByte('\n')
+			draw(d.ID, depth+1)
+		}
+	}
+	draw(0, 0)
+	return b.String()
+}
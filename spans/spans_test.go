@@ -0,0 +1,61 @@
+package spans
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// handle serves a request, fetching from a backend that takes 5s.
+func handle(ctx context.Context, tr *Tracer) {
+	ctx, span := tr.Start(ctx, "handle", String("path", "/a"))
+	defer span.End()
+	time.Sleep(time.Second)
+	_, fetch := tr.Start(ctx, "fetch")
+	time.Sleep(5 * time.Second)
+	fetch.RecordError(errors.New("backend gone"))
+	fetch.End()
+	span.SetAttributes(Int("status", 502))
+}
+
+func TestSpans(t *testing.T) {
+	synctest.Run(func() {
+		tr := Record(t)
+		handle(context.Background(), tr)
+
+		fetch, ok := tr.Find("fetch")
+		if !ok {
+			t.Fatalf("no span fetch in\n%v", tr)
+		}
+		if parent, ok := tr.Parent(fetch); !ok || parent.Name != "handle" {
+			t.Errorf("fetch is a child of %+v, want handle", parent)
+		}
+		if d := fetch.Duration(); d != 5*time.Second {
+			t.Errorf("fetch lasted %v, want 5s", d)
+		}
+		handle, _ := tr.Find("handle")
+		if want := []Attr{{"path", "/a"}, {"status", 502}}; !reflect.DeepEqual(handle.Attrs, want) {
+			t.Errorf("handle attributes %v, want %v", handle.Attrs, want)
+		}
+		if want := "handle 6s\n\tfetch 5s error: backend gone\n"; tr.String() != want {
+			t.Errorf("spans\n%v\nwant\n%s", tr, want)
+		}
+	})
+}
+
+func TestEndOnce(t *testing.T) {
+	synctest.Run(func() {
+		var tr Tracer
+		_, s := tr.Start(context.Background(), "s")
+		s.End()
+		time.Sleep(time.Second)
+		s.End()
+		s.AddEvent("late")
+		if d := tr.Spans()[0]; d.Duration() != 0 || len(d.Events) != 0 {
+			t.Errorf("span changed after End: %+v", d)
+		}
+	})
+}
@@ -8,9 +8,15 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/synctest"
 	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/chanx"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/syncmodel"
 )
 
 // Test 1: context.AfterFunc
@@ -161,69 +167,90 @@ func TestHTTPExpectContinue(t *testing.T) {
 	})
 }
 
-// Test 4: sync.Once: Verify, dass Do genau einmal ausgeführt wird
+// Test 4: sync.Once gegen das Referenzmodell: Do führt f genau einmal aus,
+// auch wenn f panikt, und alle anderen Aufrufe warten, bis f zurückkehrt
 func TestOnceDo(t *testing.T) {
-	synctest.Run(func() {
-		var once sync.Once
-		counter := 0
-
-		// mehrfach aufrufen
-		once.Do(func() { counter++ })
-		once.Do(func() { counter++ })
-		once.Do(func() { counter++ })
-
-		if counter != 1 {
-			t.Fatalf("expected Do to run once, but ran %d times", counter)
-		}
-	})
+	syncmodel.CheckOnce(t, func() syncmodel.OnceLike { return new(sync.Once) }, model.CommandsConfig{Seed: 4})
 }
 
-// Test 5: sync.Mutex: Stelle sicher, dass Unlock auch nach Lock funktioniert
+// Test 5: sync.Mutex und sync.RWMutex gegen die Referenzmodelle: wer hält
+// die Sperre, wer wartet, und wann gelingt TryLock
 func TestMutexLockUnlock(t *testing.T) {
-	synctest.Run(func() {
+	syncmodel.CheckMutex(t, func() syncmodel.TryLocker { return new(sync.Mutex) }, model.CommandsConfig{Seed: 5})
+	syncmodel.CheckRWMutex(t, func() syncmodel.RWLocker { return new(sync.RWMutex) }, model.CommandsConfig{Seed: 5})
+
+	// Dazu jede Reihenfolge, in der drei Goroutinen einen Zähler unter der
+	// Sperre erhöhen, statt nur der einen, die synctest wählt. Zwischen
+	// Lesen und Schreiben kommen die anderen an die Reihe, so dass ohne
+	// Sperre ein Schritt verloren ginge. Wer die Sperre nicht bekommt,
+	// wartet auf das nächste Unlock, denn auf eine in Lock blockierte
+	// Goroutine wartet synctest nicht
+	rep := sched.ExploreAll(t, 0, func(t testing.TB) {
 		var mu sync.Mutex
-		locked := false
-
-		// Goroutine 1: lock, set flag, unlock
-		go func() {
-			mu.Lock()
-			locked = true
-			mu.Unlock()
-		}()
-
-		// Goroutine 2: versuche zu locken, erst nach Unlock möglich
-		synctest.Wait()
-		if !locked {
-			t.Fatalf("expected first goroutine to acquire lock before waiting")
+		unlocked := make(chan struct{})
+		n := 0
+		done := make(chan struct{})
+		for range 3 {
+			sched.Go("inc", func() {
+				for !mu.TryLock() {
+					<-unlocked
+					sched.Yield("lock")
+				}
+				v := n
+				sched.Yield("read")
+				n = v + 1
+				wake := unlocked
+				unlocked = make(chan struct{})
+				mu.Unlock()
+				close(wake)
+				done <- struct{}{}
+			})
+		}
+		for range 3 {
+			<-done
+		}
+		if n != 3 {
+			t.Errorf("counter %d after 3 increments", n)
 		}
-
-		synctest.Wait() // jetzt blockiert, bis Unlock wieder kommt
-		// Wenn wir hier ankommen, heißt das: keine Deadlocks mehr
 	})
+	if rep.Schedules != 60 {
+		t.Errorf("%d schedules explored, want 60", rep.Schedules)
+	}
 }
 
-// Test 6: sync.WaitGroup: Warte auf alle Done-Calls
+// Test 6: sync.WaitGroup gegen das Referenzmodell: Wait blockiert, bis der
+// Zähler auf null fällt
 func TestWaitGroup(t *testing.T) {
-	synctest.Run(func() {
+	syncmodel.CheckWaitGroup(t, func() syncmodel.WaitGroupLike { return new(sync.WaitGroup) }, model.CommandsConfig{Seed: 6})
+
+	// Dazu jede Reihenfolge, in der zwei Goroutinen je eine weitere
+	// anmelden und sich abmelden, während die erste in Wait wartet: Wait
+	// kehrt erst zurück, wenn alle vier fertig sind
+	rep := sched.ExploreAll(t, 0, func(t testing.TB) {
 		var wg sync.WaitGroup
-		const routines = 3
-		counter := 0
-
-		wg.Add(routines)
-		for i := 0; i < routines; i++ {
-			go func() {
-				defer wg.Done()
-				counter++
-			}()
+		var n atomic.Int32
+		wg.Add(2)
+		for range 2 {
+			sched.Go("worker", func() {
+				wg.Add(1)
+				sched.Go("helper", func() {
+					n.Add(1)
+					wg.Done()
+				})
+				n.Add(1)
+				wg.Done()
+			})
 		}
-
-		// bis alle Done() aufgerufen wurden, bleibt Wait() blockiert
-		synctest.Wait()
-		// nachdem alle wg.Done waren, sollte counter==routines sein
-		if counter != routines {
-			t.Fatalf("expected counter %d, got %d", routines, counter)
+		wg.Wait()
+		if got := n.Load(); got != 4 {
+			t.Errorf("Wait returned after %d of 4 goroutines", got)
 		}
 	})
+	// Die zwei Worker in beiden Reihenfolgen, danach je drei Reihenfolgen
+	// des zweiten Workers und der beiden Helfer
+	if rep.Schedules != 6 {
+		t.Errorf("%d schedules explored, want 6", rep.Schedules)
+	}
 }
 
 // Test 7: Kanäle: Buffered Channel send/receive ohne Deadlock
@@ -236,6 +263,19 @@ func TestChannelBuffer(t *testing.T) {
 		if val != 42 {
 			t.Fatalf("expected 42, got %d", val)
 		}
+
+		// Backpressure: ein Produzent muss blockieren, sobald der Puffer voll
+		// ist, und nach genau einem Empfang genau einen Wert nachlegen
+		buf := chanx.Make[int]("puffer", 2)
+		go func() {
+			for i := 0; i < 5; i++ {
+				buf.Send(i)
+			}
+			buf.Close()
+		}()
+		chanx.ExpectBackpressure(t, buf, 2)
+		for range buf.C() {
+		}
 	})
 }
 
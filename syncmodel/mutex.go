@@ -0,0 +1,123 @@
+package syncmodel
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// TryLocker is a lock like sync.Mutex.
+type TryLocker interface {
+	sync.Locker
+	TryLock() bool
+}
+
+// Mutex is the state of a mutex: the goroutines holding it, at most one,
+// and those blocked in Lock. Unlock hands the mutex to one of those.
+type Mutex struct {
+	Holders, Waiting int
+}
+
+// CheckMutex checks the mutexes newMutex returns against the Mutex model
+// with programs of lock, unlock, trylock and observe commands.
+func CheckMutex(t testing.TB, newMutex func() TryLocker, cfg model.CommandsConfig) {
+	t.Helper()
+	r := startRunner()
+	defer r.stop()
+	model.CheckCommands(t, mutexMachine(r, newMutex), cfg)
+}
+
+func mutexMachine(r runner, newMutex func() TryLocker) model.Machine[Mutex, *mutexSystem] {
+	return model.Machine[Mutex, *mutexSystem]{
+		Init: func() Mutex { return Mutex{} },
+		New: func() *mutexSystem {
+			s := &mutexSystem{outside: outside{r: r}}
+			s.start(func() { s.mu = newMutex() })
+			return s
+		},
+		Commands: []model.Command[Mutex, *mutexSystem]{
+			{
+				Name: "lock",
+				Pre:  func(m Mutex, _ any) bool { return m.Waiting < maxWaiting },
+				Run:  func(s *mutexSystem, _ any) any { s.lock(); return nil },
+				Next: func(m Mutex, _ any) Mutex {
+					if m.Holders == 0 {
+						m.Holders = 1
+					} else {
+						m.Waiting++
+					}
+					return m
+				},
+			},
+			{
+				Name: "unlock",
+				Pre:  func(m Mutex, _ any) bool { return m.Holders > 0 },
+				Run:  func(s *mutexSystem, _ any) any { s.unlock(); return nil },
+				Next: func(m Mutex, _ any) Mutex {
+					m.Holders--
+					if m.Waiting > 0 {
+						m.Waiting--
+						m.Holders++
+					}
+					return m
+				},
+			},
+			{
+				Name: "trylock",
+				Run:  func(s *mutexSystem, _ any) any { return s.tryLock() },
+				Next: func(m Mutex, _ any) Mutex {
+					if m.Holders == 0 {
+						m.Holders = 1
+					}
+					return m
+				},
+				Post: func(m Mutex, _, res any) error { return want("TryLock", res, m.Holders == 0) },
+			},
+			observe[Mutex, *mutexSystem](),
+		},
+	}
+}
+
+type mutexSystem struct {
+	outside
+	mu               TryLocker
+	holders, waiting atomic.Int64
+}
+
+func (s *mutexSystem) observe() Mutex {
+	s.settle()
+	return Mutex{Holders: int(s.holders.Load()), Waiting: int(s.waiting.Load())}
+}
+
+func (s *mutexSystem) lock() {
+	s.waiting.Add(1)
+	s.start(func() {
+		s.mu.Lock()
+		s.waiting.Add(-1)
+		s.holders.Add(1)
+	})
+}
+
+func (s *mutexSystem) unlock() {
+	s.holders.Add(-1)
+	s.mu.Unlock()
+	s.settle()
+}
+
+func (s *mutexSystem) tryLock() bool {
+	ok := s.mu.TryLock()
+	if ok {
+		s.holders.Add(1)
+	}
+	return ok
+}
+
+// Close unlocks the mutex until no goroutine waits for it.
+func (s *mutexSystem) Close() error {
+	for m := s.observe(); m.Waiting > 0 && m.Holders > 0; m = s.observe() {
+		s.unlock()
+	}
+	return nil
+}
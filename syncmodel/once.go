@@ -0,0 +1,122 @@
+package syncmodel
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// OnceLike runs a function once, like sync.Once.
+type OnceLike interface {
+	Do(f func())
+}
+
+// Once is the state of a once: the calls of the function so far, at most
+// one, whether it is running, and the goroutines blocked in Do. Those
+// are all blocked until the function returns, even by panicking, and Do
+// returns at once after that.
+type Once struct {
+	Calls   int
+	Running bool
+	Blocked int
+}
+
+// CheckOnce checks the onces newOnce returns against the Once model with
+// programs of do, release and observe commands. The function passed to Do
+// runs until released, returning or panicking.
+func CheckOnce(t testing.TB, newOnce func() OnceLike, cfg model.CommandsConfig) {
+	t.Helper()
+	r := startRunner()
+	defer r.stop()
+	model.CheckCommands(t, onceMachine(r, newOnce), cfg)
+}
+
+func onceMachine(r runner, newOnce func() OnceLike) model.Machine[Once, *onceSystem] {
+	return model.Machine[Once, *onceSystem]{
+		Init: func() Once { return Once{} },
+		New: func() *onceSystem {
+			s := &onceSystem{outside: outside{r: r}}
+			s.gate.Lock()
+			s.start(func() { s.once = newOnce() })
+			return s
+		},
+		Commands: []model.Command[Once, *onceSystem]{
+			{
+				Name: "do",
+				Pre:  func(m Once, _ any) bool { return m.Blocked < maxWaiting },
+				Run:  func(s *onceSystem, _ any) any { s.do(); return nil },
+				Next: func(m Once, _ any) Once {
+					switch {
+					case m.Calls == 0:
+						m.Calls, m.Running, m.Blocked = 1, true, 1
+					case m.Running:
+						m.Blocked++
+					}
+					return m
+				},
+			},
+			{
+				Name: "release",
+				Gen:  func(r *rand.Rand, _ Once) any { return r.IntN(2) == 0 },
+				Pre:  func(m Once, _ any) bool { return m.Running },
+				Run:  func(s *onceSystem, arg any) any { s.release(arg.(bool)); return nil },
+				Next: func(m Once, _ any) Once { m.Running, m.Blocked = false, 0; return m },
+			},
+			observe[Once, *onceSystem](),
+		},
+	}
+}
+
+type onceSystem struct {
+	outside
+	once OnceLike
+	// gate is locked but while a function is released; panics tells it
+	// whether to panic.
+	gate                    sync.Mutex
+	panics                  atomic.Bool
+	calls, running, blocked atomic.Int64
+}
+
+func (s *onceSystem) observe() Once {
+	s.settle()
+	return Once{Calls: int(s.calls.Load()), Running: s.running.Load() > 0, Blocked: int(s.blocked.Load())}
+}
+
+func (s *onceSystem) do() {
+	s.blocked.Add(1)
+	s.start(func() {
+		defer func() {
+			recover()
+			s.blocked.Add(-1)
+		}()
+		s.once.Do(func() {
+			s.calls.Add(1)
+			s.running.Add(1)
+			s.gate.Lock()
+			s.running.Add(-1)
+			if s.panics.Load() {
+				panic("released with a panic")
+			}
+		})
+	})
+}
+
+// release lets the running function return, or panic.
+func (s *onceSystem) release(panics bool) {
+	if s.observe().Running {
+		s.panics.Store(panics)
+		s.gate.Unlock()
+		s.settle()
+	}
+}
+
+// Close releases the running functions.
+func (s *onceSystem) Close() error {
+	for s.observe().Running {
+		s.release(false)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package syncmodel
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// RWLocker is a lock like sync.RWMutex.
+type RWLocker interface {
+	TryLocker
+	RLock()
+	RUnlock()
+	TryRLock() bool
+}
+
+// RWMutex is the state of a reader/writer mutex: the goroutines holding
+// it, one writer or any number of readers, and those blocked.
+//
+// A blocked Lock keeps new readers out, so that writers do not starve:
+// they get the mutex once the readers holding it have left. Unlock lets
+// in all the readers blocked, before the next writer, so that readers do
+// not starve either.
+type RWMutex struct {
+	Writers, Readers               int
+	WaitingWriters, WaitingReaders int
+}
+
+// CheckRWMutex checks the mutexes newRWMutex returns against the RWMutex
+// model with programs of lock, unlock, rlock, runlock, their try
+// variants and observe commands.
+func CheckRWMutex(t testing.TB, newRWMutex func() RWLocker, cfg model.CommandsConfig) {
+	t.Helper()
+	r := startRunner()
+	defer r.stop()
+	model.CheckCommands(t, rwMutexMachine(r, newRWMutex), cfg)
+}
+
+func (m RWMutex) canRead() bool  { return m.Writers == 0 && m.WaitingWriters == 0 }
+func (m RWMutex) canWrite() bool { return m.Writers == 0 && m.Readers == 0 }
+func (m RWMutex) waiting() int   { return m.WaitingWriters + m.WaitingReaders }
+
+func rwMutexMachine(r runner, newRWMutex func() RWLocker) model.Machine[RWMutex, *rwMutexSystem] {
+	return model.Machine[RWMutex, *rwMutexSystem]{
+		Init: func() RWMutex { return RWMutex{} },
+		New: func() *rwMutexSystem {
+			s := &rwMutexSystem{outside: outside{r: r}}
+			s.start(func() { s.mu = newRWMutex() })
+			return s
+		},
+		Commands: []model.Command[RWMutex, *rwMutexSystem]{
+			{
+				Name: "lock",
+				Pre:  func(m RWMutex, _ any) bool { return m.waiting() < maxWaiting },
+				Run:  func(s *rwMutexSystem, _ any) any { s.lock(); return nil },
+				Next: func(m RWMutex, _ any) RWMutex {
+					if m.canWrite() {
+						m.Writers = 1
+					} else {
+						m.WaitingWriters++
+					}
+					return m
+				},
+			},
+			{
+				Name: "unlock",
+				Pre:  func(m RWMutex, _ any) bool { return m.Writers > 0 },
+				Run:  func(s *rwMutexSystem, _ any) any { s.unlock(); return nil },
+				Next: func(m RWMutex, _ any) RWMutex {
+					m.Writers = 0
+					if m.WaitingReaders > 0 {
+						m.Readers, m.WaitingReaders = m.WaitingReaders, 0
+					} else if m.WaitingWriters > 0 {
+						m.Writers, m.WaitingWriters = 1, m.WaitingWriters-1
+					}
+					return m
+				},
+			},
+			{
+				Name: "rlock",
+				Pre:  func(m RWMutex, _ any) bool { return m.waiting() < maxWaiting },
+				Run:  func(s *rwMutexSystem, _ any) any { s.rlock(); return nil },
+				Next: func(m RWMutex, _ any) RWMutex {
+					if m.canRead() {
+						m.Readers++
+					} else {
+						m.WaitingReaders++
+					}
+					return m
+				},
+			},
+			{
+				Name: "runlock",
+				Pre:  func(m RWMutex, _ any) bool { return m.Readers > 0 },
+				Run:  func(s *rwMutexSystem, _ any) any { s.runlock(); return nil },
+				Next: func(m RWMutex, _ any) RWMutex {
+					m.Readers--
+					if m.Readers == 0 && m.WaitingWriters > 0 {
+						m.Writers, m.WaitingWriters = 1, m.WaitingWriters-1
+					}
+					return m
+				},
+			},
+			{
+				Name: "trylock",
+				Run:  func(s *rwMutexSystem, _ any) any { return s.tryLock() },
+				Next: func(m RWMutex, _ any) RWMutex {
+					if m.canWrite() {
+						m.Writers = 1
+					}
+					return m
+				},
+				Post: func(m RWMutex, _, res any) error { return want("TryLock", res, m.canWrite()) },
+			},
+			{
+				Name: "tryrlock",
+				Run:  func(s *rwMutexSystem, _ any) any { return s.tryRLock() },
+				Next: func(m RWMutex, _ any) RWMutex {
+					if m.canRead() {
+						m.Readers++
+					}
+					return m
+				},
+				Post: func(m RWMutex, _, res any) error { return want("TryRLock", res, m.canRead()) },
+			},
+			observe[RWMutex, *rwMutexSystem](),
+		},
+	}
+}
+
+type rwMutexSystem struct {
+	outside
+	mu                             RWLocker
+	writers, readers               atomic.Int64
+	waitingWriters, waitingReaders atomic.Int64
+}
+
+func (s *rwMutexSystem) observe() RWMutex {
+	s.settle()
+	return RWMutex{
+		Writers: int(s.writers.Load()), Readers: int(s.readers.Load()),
+		WaitingWriters: int(s.waitingWriters.Load()), WaitingReaders: int(s.waitingReaders.Load()),
+	}
+}
+
+func (s *rwMutexSystem) lock() {
+	s.waitingWriters.Add(1)
+	s.start(func() {
+		s.mu.Lock()
+		s.waitingWriters.Add(-1)
+		s.writers.Add(1)
+	})
+}
+
+func (s *rwMutexSystem) unlock() {
+	s.writers.Add(-1)
+	s.mu.Unlock()
+	s.settle()
+}
+
+func (s *rwMutexSystem) rlock() {
+	s.waitingReaders.Add(1)
+	s.start(func() {
+		s.mu.RLock()
+		s.waitingReaders.Add(-1)
+		s.readers.Add(1)
+	})
+}
+
+func (s *rwMutexSystem) runlock() {
+	s.readers.Add(-1)
+	s.mu.RUnlock()
+	s.settle()
+}
+
+func (s *rwMutexSystem) tryLock() bool {
+	ok := s.mu.TryLock()
+	if ok {
+		s.writers.Add(1)
+	}
+	return ok
+}
+
+func (s *rwMutexSystem) tryRLock() bool {
+	ok := s.mu.TryRLock()
+	if ok {
+		s.readers.Add(1)
+	}
+	return ok
+}
+
+// Close releases the mutex until no goroutine waits for it.
+func (s *rwMutexSystem) Close() error {
+	for m := s.observe(); m.waiting() > 0; m = s.observe() {
+		switch {
+		case m.Writers > 0:
+			s.unlock()
+		case m.Readers > 0:
+			s.runlock()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
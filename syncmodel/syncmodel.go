@@ -0,0 +1,126 @@
+// Package syncmodel holds reference models of the primitives of package
+// sync and checks implementations against them with model.CheckCommands.
+// The models are executable documentation: run against the standard
+// library, they show which goroutines a Mutex, RWMutex, WaitGroup or Once
+// lets through and which it blocks, after every call.
+//
+// Calls that block run on goroutines of their own, outside the bubble of
+// the program: a goroutine blocked on a sync.Mutex is not durably
+// blocked, so inside the bubble it would keep synctest.Wait from ever
+// returning. Every command instead waits for those goroutines to return
+// or block, by their states in a stack dump, and an observe command
+// compares the counts of goroutines blocked and holding to the model.
+// The implementations are created outside the bubble too, so that their
+// channels, if any, can be used from there.
+package syncmodel
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// maxWaiting bounds the goroutines blocked at once in generated programs.
+const maxWaiting = 3
+
+// runner starts goroutines outside the bubbles, on behalf of the code in
+// them, which can only start goroutines of the bubble.
+type runner chan func()
+
+func startRunner() runner {
+	r := make(runner)
+	go func() {
+		for f := range r {
+			go f()
+		}
+	}()
+	return r
+}
+
+func (r runner) stop() { close(r) }
+
+// call is a function running outside the bubble.
+type call struct {
+	id   atomic.Int64
+	done atomic.Bool
+}
+
+// outside is the part of a system that runs calls outside the bubble.
+type outside struct {
+	r     runner
+	calls []*call
+}
+
+// start runs f on a goroutine outside the bubble and settles.
+func (o *outside) start(f func()) {
+	c := new(call)
+	o.calls = append(o.calls, c)
+	o.r <- func() {
+		c.id.Store(goid.ID())
+		defer c.done.Store(true)
+		f()
+	}
+	o.settle()
+}
+
+// settle waits until every call has returned or is blocked.
+func (o *outside) settle() {
+	for !o.settled() {
+		runtime.Gosched()
+	}
+}
+
+func (o *outside) settled() bool {
+	var pending []*call
+	for _, c := range o.calls {
+		if !c.done.Load() {
+			pending = append(pending, c)
+		}
+	}
+	o.calls = pending
+	if len(pending) == 0 {
+		return true
+	}
+	states := make(map[int64]string)
+	for _, g := range gstack.All() {
+		states[g.ID] = g.State
+	}
+	for _, c := range pending {
+		switch state, ok := states[c.id.Load()]; {
+		case !ok:
+			// Not started yet, or just returned.
+			return false
+		case state == "running" || state == "runnable" || state == "syscall":
+			return false
+		}
+	}
+	return true
+}
+
+// observe is the command that compares the counts of a system to the
+// model state.
+func observe[S comparable, Sys interface{ observe() S }]() model.Command[S, Sys] {
+	return model.Command[S, Sys]{
+		Name: "observe",
+		Run:  func(sys Sys, _ any) any { return sys.observe() },
+		Next: func(m S, _ any) S { return m },
+		Post: func(m S, _, res any) error {
+			if res.(S) != m {
+				return fmt.Errorf("observed %+v", res)
+			}
+			return nil
+		},
+	}
+}
+
+// want checks the result of a call that reports success.
+func want(call string, res any, ok bool) error {
+	if res.(bool) != ok {
+		return fmt.Errorf("%s returned %v", call, res)
+	}
+	return nil
+}
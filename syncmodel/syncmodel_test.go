@@ -0,0 +1,78 @@
+package syncmodel
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// chanMutex is a mutex made of a channel, to check more than sync.
+type chanMutex chan struct{}
+
+func (m chanMutex) Lock()   { m <- struct{}{} }
+func (m chanMutex) Unlock() { <-m }
+func (m chanMutex) TryLock() bool {
+	select {
+	case m <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestChanMutex(t *testing.T) {
+	CheckMutex(t, func() TryLocker { return make(chanMutex, 1) }, model.CommandsConfig{Seed: 1})
+}
+
+// failing checks that check fails with a minimal program ending in want.
+func failing(t *testing.T, want string, check func(t testing.TB)) {
+	t.Helper()
+	errs := testtb.Run(t, check)
+	if len(errs) != 1 || !strings.HasSuffix(errs[0], want) {
+		t.Errorf("errors %q, want one ending in %q", errs, want)
+	}
+}
+
+// exclusive is a reader/writer mutex that lets one reader in at a time.
+type exclusive struct{ sync.Mutex }
+
+func (m *exclusive) RLock()         { m.Lock() }
+func (m *exclusive) RUnlock()       { m.Unlock() }
+func (m *exclusive) TryRLock() bool { return m.TryLock() }
+
+func TestExclusiveReaders(t *testing.T) {
+	failing(t, "[lock rlock unlock tryrlock]", func(t testing.TB) {
+		CheckRWMutex(t, func() RWLocker { return new(exclusive) }, model.CommandsConfig{Seed: 1})
+	})
+}
+
+// noWait is a wait group whose Wait returns at once.
+type noWait struct{ sync.WaitGroup }
+
+func (*noWait) Wait() {}
+
+func TestNoWait(t *testing.T) {
+	failing(t, "[add(1) wait observe]", func(t testing.TB) {
+		CheckWaitGroup(t, func() WaitGroupLike { return new(noWait) }, model.CommandsConfig{Seed: 1})
+	})
+}
+
+// flagOnce skips f once it has started, without waiting for it to
+// return.
+type flagOnce struct{ started atomic.Bool }
+
+func (o *flagOnce) Do(f func()) {
+	if o.started.CompareAndSwap(false, true) {
+		f()
+	}
+}
+
+func TestFlagOnce(t *testing.T) {
+	failing(t, "[do do observe]", func(t testing.TB) {
+		CheckOnce(t, func() OnceLike { return new(flagOnce) }, model.CommandsConfig{Seed: 1})
+	})
+}
@@ -0,0 +1,130 @@
+package syncmodel
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/model"
+)
+
+// WaitGroupLike is a wait group like sync.WaitGroup.
+type WaitGroupLike interface {
+	Add(delta int)
+	Done()
+	Wait()
+}
+
+// WaitGroup is the state of a wait group: its counter and the goroutines
+// blocked in Wait, which all return once the counter drops to zero. Wait
+// returns at once if it is zero.
+type WaitGroup struct {
+	Counter, Waiting int
+}
+
+// maxCounter bounds the counter in generated programs.
+const maxCounter = 4
+
+// CheckWaitGroup checks the wait groups newWaitGroup returns against the
+// WaitGroup model with programs of add, done, wait and observe commands.
+// Only the goroutines waiting are observed; the counter shows in when
+// they return.
+func CheckWaitGroup(t testing.TB, newWaitGroup func() WaitGroupLike, cfg model.CommandsConfig) {
+	t.Helper()
+	r := startRunner()
+	defer r.stop()
+	model.CheckCommands(t, waitGroupMachine(r, newWaitGroup), cfg)
+}
+
+func waitGroupMachine(r runner, newWaitGroup func() WaitGroupLike) model.Machine[WaitGroup, *waitGroupSystem] {
+	return model.Machine[WaitGroup, *waitGroupSystem]{
+		Init: func() WaitGroup { return WaitGroup{} },
+		New: func() *waitGroupSystem {
+			s := &waitGroupSystem{outside: outside{r: r}}
+			s.start(func() { s.wg = newWaitGroup() })
+			return s
+		},
+		Commands: []model.Command[WaitGroup, *waitGroupSystem]{
+			{
+				Name: "add",
+				Gen:  func(r *rand.Rand, _ WaitGroup) any { return 1 + r.IntN(2) },
+				Shrink: func(arg any) []any {
+					if arg.(int) > 1 {
+						return []any{1}
+					}
+					return nil
+				},
+				Pre:  func(m WaitGroup, arg any) bool { return m.Counter+arg.(int) <= maxCounter },
+				Run:  func(s *waitGroupSystem, arg any) any { s.wg.Add(arg.(int)); return nil },
+				Next: func(m WaitGroup, arg any) WaitGroup { m.Counter += arg.(int); return m },
+			},
+			{
+				Name: "done",
+				Pre:  func(m WaitGroup, _ any) bool { return m.Counter > 0 },
+				Run:  func(s *waitGroupSystem, _ any) any { s.done(); return nil },
+				Next: func(m WaitGroup, _ any) WaitGroup {
+					m.Counter--
+					if m.Counter == 0 {
+						m.Waiting = 0
+					}
+					return m
+				},
+			},
+			{
+				Name: "wait",
+				Pre:  func(m WaitGroup, _ any) bool { return m.Waiting < maxWaiting },
+				Run:  func(s *waitGroupSystem, _ any) any { s.wait(); return nil },
+				Next: func(m WaitGroup, _ any) WaitGroup {
+					if m.Counter > 0 {
+						m.Waiting++
+					}
+					return m
+				},
+			},
+			{
+				Name: "observe",
+				Run:  func(s *waitGroupSystem, _ any) any { return s.observe() },
+				Next: func(m WaitGroup, _ any) WaitGroup { return m },
+				Post: func(m WaitGroup, _, res any) error {
+					if res.(int) != m.Waiting {
+						return fmt.Errorf("%d goroutines waiting", res)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+type waitGroupSystem struct {
+	outside
+	wg      WaitGroupLike
+	waiting atomic.Int64
+}
+
+func (s *waitGroupSystem) observe() int {
+	s.settle()
+	return int(s.waiting.Load())
+}
+
+func (s *waitGroupSystem) wait() {
+	s.waiting.Add(1)
+	s.start(func() {
+		s.wg.Wait()
+		s.waiting.Add(-1)
+	})
+}
+
+func (s *waitGroupSystem) done() {
+	s.wg.Done()
+	s.settle()
+}
+
+// Close calls Done until no goroutine waits.
+func (s *waitGroupSystem) Close() error {
+	for s.observe() > 0 {
+		s.done()
+	}
+	return nil
+}
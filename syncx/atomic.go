@@ -0,0 +1,189 @@
+package syncx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/history"
+)
+
+// AtomicKind is an operation on a single int64 register or counter.
+type AtomicKind int
+
+const (
+	AtomicLoad AtomicKind = iota
+	AtomicStore
+	AtomicAdd
+	AtomicSwap
+	AtomicCAS
+)
+
+var atomicNames = [...]string{"Load", "Store", "Add", "Swap", "CompareAndSwap"}
+
+func (k AtomicKind) String() string { return atomicNames[k] }
+
+// AtomicOp is one completed operation of an AtomicHistory. Call and Return
+// are positions in the history's logical clock; an operation whose Return is
+// smaller than another's Call happened strictly before it.
+type AtomicOp struct {
+	Kind         AtomicKind
+	Arg, Arg2    int64 // stored value, delta, or CAS old/new
+	Result       int64 // loaded value, new value after Add, or old value of Swap
+	OK           bool  // CAS outcome
+	Call, Return int
+}
+
+func (op AtomicOp) String() string {
+	switch op.Kind {
+	case AtomicLoad:
+		return fmt.Sprintf("Load() = %d", op.Result)
+	case AtomicStore:
+		return fmt.Sprintf("Store(%d)", op.Arg)
+	case AtomicAdd:
+		return fmt.Sprintf("Add(%d) = %d", op.Arg, op.Result)
+	case AtomicSwap:
+		return fmt.Sprintf("Swap(%d) = %d", op.Arg, op.Result)
+	}
+	return fmt.Sprintf("CompareAndSwap(%d, %d) = %t", op.Arg, op.Arg2, op.OK)
+}
+
+// AtomicHistory records concurrent operations on one int64 value so they can
+// be checked for linearizability against plain register/counter semantics.
+// Wrap each operation of the structure under test in the matching method;
+// the method values of atomic.Int64 fit directly, e.g. h.Add(1, v.Add).
+type AtomicHistory struct {
+	init int64
+
+	mu    sync.Mutex
+	clock int
+	rec   history.Recorder[AtomicOp, AtomicOp] // inputs without results, outputs complete
+}
+
+// NewAtomicHistory returns an empty history of a value starting at init.
+func NewAtomicHistory(init int64) *AtomicHistory {
+	return &AtomicHistory{init: init}
+}
+
+// call records the call of op and returns the function recording its
+// return with the results of done.
+func (h *AtomicHistory) call(op AtomicOp) func(done AtomicOp) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock++
+	op.Call = h.clock
+	ret := h.rec.Call(0, op)
+	return func(done AtomicOp) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.clock++
+		op.Result, op.OK, op.Return = done.Result, done.OK, h.clock
+		ret(op)
+	}
+}
+
+// Load records fn as a load.
+func (h *AtomicHistory) Load(fn func() int64) int64 {
+	ret := h.call(AtomicOp{Kind: AtomicLoad})
+	v := fn()
+	ret(AtomicOp{Result: v})
+	return v
+}
+
+// Store records fn(v) as a store.
+func (h *AtomicHistory) Store(v int64, fn func(int64)) {
+	ret := h.call(AtomicOp{Kind: AtomicStore, Arg: v})
+	fn(v)
+	ret(AtomicOp{})
+}
+
+// Add records fn(delta) as an add returning the new value.
+func (h *AtomicHistory) Add(delta int64, fn func(int64) int64) int64 {
+	ret := h.call(AtomicOp{Kind: AtomicAdd, Arg: delta})
+	v := fn(delta)
+	ret(AtomicOp{Result: v})
+	return v
+}
+
+// Swap records fn(v) as a swap returning the old value.
+func (h *AtomicHistory) Swap(v int64, fn func(int64) int64) int64 {
+	ret := h.call(AtomicOp{Kind: AtomicSwap, Arg: v})
+	old := fn(v)
+	ret(AtomicOp{Result: old})
+	return old
+}
+
+// CompareAndSwap records fn(old, new) as a compare-and-swap.
+func (h *AtomicHistory) CompareAndSwap(old, new int64, fn func(old, new int64) bool) bool {
+	ret := h.call(AtomicOp{Kind: AtomicCAS, Arg: old, Arg2: new})
+	ok := fn(old, new)
+	ret(AtomicOp{OK: ok})
+	return ok
+}
+
+// Ops returns the completed operations ordered by invocation.
+func (h *AtomicHistory) Ops() []AtomicOp {
+	var ops []AtomicOp
+	for _, op := range h.rec.Ops() {
+		if !op.Pending() {
+			ops = append(ops, op.Output)
+		}
+	}
+	return ops
+}
+
+// step applies op to state v and reports whether the recorded result is
+// what a sequential register would have returned.
+func (op AtomicOp) step(v int64) (int64, bool) {
+	switch op.Kind {
+	case AtomicLoad:
+		return v, op.Result == v
+	case AtomicStore:
+		return op.Arg, true
+	case AtomicAdd:
+		return v + op.Arg, op.Result == v+op.Arg
+	case AtomicSwap:
+		return op.Arg, op.Result == v
+	}
+	if v == op.Arg {
+		return op.Arg2, op.OK
+	}
+	return v, !op.OK
+}
+
+// Check searches for a linearization of the history, i.e. a sequential
+// order consistent with real-time order in which every result matches the
+// register semantics, with history.Linearizable. It returns nil if one
+// exists.
+func (h *AtomicHistory) Check() error {
+	spec := history.Spec[int64, AtomicOp, AtomicOp]{
+		Name: "register",
+		Init: func() int64 { return h.init },
+		Step: func(v int64, in, out AtomicOp) (bool, int64) {
+			in.Result, in.OK = out.Result, out.OK
+			next, ok := in.step(v)
+			return ok, next
+		},
+	}
+	ops := h.rec.Ops()
+	res := history.Linearizable(spec, ops)
+	if res.OK {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "history of %d operations is not linearizable; these %d cannot be linearized:", len(ops), len(res.Window))
+	for _, op := range res.Window {
+		fmt.Fprintf(&b, "\n\t%v [%d,%d]", op.Output, op.Output.Call, op.Output.Return)
+	}
+	return errors.New(b.String())
+}
+
+// ExpectLinearizable fails t unless the history is linearizable.
+func ExpectLinearizable(t testing.TB, h *AtomicHistory) {
+	t.Helper()
+	if err := h.Check(); err != nil {
+		t.Error(err)
+	}
+}
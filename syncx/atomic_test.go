@@ -0,0 +1,64 @@
+package syncx
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestAtomicInt64Linearizable(t *testing.T) {
+	synctest.Run(func() {
+		var v atomic.Int64
+		h := NewAtomicHistory(0)
+		var wg sync.WaitGroup
+		for i := range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.Add(int64(i+1), v.Add)
+				h.CompareAndSwap(int64(i), 100, v.CompareAndSwap)
+				h.Load(v.Load)
+				h.Swap(int64(i), v.Swap)
+			}()
+		}
+		wg.Wait()
+		ExpectLinearizable(t, h)
+	})
+}
+
+// racyCounter implements Add as a separate load and store. The sleep lets
+// every concurrent caller load before anyone stores.
+type racyCounter struct{ v atomic.Int64 }
+
+func (c *racyCounter) Add(d int64) int64 {
+	n := c.v.Load() + d
+	time.Sleep(time.Millisecond)
+	c.v.Store(n)
+	return n
+}
+
+func TestAtomicHistoryDetectsLostUpdate(t *testing.T) {
+	synctest.Run(func() {
+		var c racyCounter
+		h := NewAtomicHistory(0)
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.Add(1, c.Add)
+			}()
+		}
+		wg.Wait()
+		h.Load(c.v.Load)
+		err := h.Check()
+		if err == nil {
+			t.Fatalf("lost update not detected in %v", h.Ops())
+		}
+		if want := "history of 3 operations is not linearizable; these 2 cannot be linearized:\n\tAdd(1) = 1 [1,4]\n\tAdd(1) = 1 [2,3]"; err.Error() != want {
+			t.Errorf("error %q, want %q", err, want)
+		}
+	})
+}
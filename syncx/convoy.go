@@ -0,0 +1,129 @@
+package syncx
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+)
+
+// Contender is a named goroutine competing for the mutex explored by
+// ExploreConvoy. Run is started in its own goroutine and receives the
+// mutex; the exploration waits for it to return.
+type Contender struct {
+	Name string
+	Run  func(mu *Mutex)
+}
+
+// ConvoyStat is the worst case observed for one contender over all runs.
+type ConvoyStat struct {
+	Name         string
+	Acquisitions int           // total over all runs
+	MaxWait      time.Duration // longest virtual time between Lock and acquisition
+	MaxWaitSeed  uint64        // run in which MaxWait was seen
+	MaxOvertakes int           // most acquisitions by others while waiting
+	OvertakeSeed uint64        // run in which MaxOvertakes was seen
+}
+
+// ConvoyReport summarizes an exploration.
+type ConvoyReport struct {
+	Runs  int
+	Stats []ConvoyStat // in the order of the contenders
+}
+
+func (r ConvoyReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "mutex contention over %d runs:", r.Runs)
+	for _, s := range r.Stats {
+		fmt.Fprintf(&b, "\n\t%-12s acquisitions %-5d max wait %v (seed %d), max overtakes %d (seed %d)",
+			s.Name, s.Acquisitions, s.MaxWait, s.MaxWaitSeed, s.MaxOvertakes, s.OvertakeSeed)
+	}
+	return b.String()
+}
+
+// ExpectMaxWait fails t if any contender waited longer than d in some run.
+func (r ConvoyReport) ExpectMaxWait(t testing.TB, d time.Duration) {
+	t.Helper()
+	for _, s := range r.Stats {
+		if s.MaxWait > d {
+			t.Errorf("contender %s waited %v for the mutex (seed %d), limit %v\n%v", s.Name, s.MaxWait, s.MaxWaitSeed, d, r)
+		}
+	}
+}
+
+// ExpectMaxOvertakes fails t if any contender was overtaken by more than n
+// acquisitions while waiting in some run.
+func (r ConvoyReport) ExpectMaxOvertakes(t testing.TB, n int) {
+	t.Helper()
+	for _, s := range r.Stats {
+		if s.MaxOvertakes > n {
+			t.Errorf("contender %s was overtaken %d times while waiting (seed %d), limit %d\n%v", s.Name, s.MaxOvertakes, s.OvertakeSeed, n, r)
+		}
+	}
+}
+
+// ExploreConvoy runs the contenders runs times against a fresh Mutex. Every
+// run starts the contenders and hands the lock to waiters in a different
+// order derived from its seed; run 0 starts them in the given order and
+// serves waiters first come, first served as a baseline. The report lists the
+// worst wait each contender suffered, which exposes convoys and starvation
+// that the single schedule of a normal test never shows.
+func ExploreConvoy(t testing.TB, runs int, contenders []Contender) ConvoyReport {
+	t.Helper()
+	rep := ConvoyReport{Runs: runs, Stats: make([]ConvoyStat, len(contenders))}
+	for i, c := range contenders {
+		rep.Stats[i].Name = c.Name
+	}
+
+	for seed := range uint64(runs) {
+		mu := NewMutex(t, "convoy")
+		order := make([]int, len(contenders))
+		for i := range order {
+			order[i] = i
+		}
+		if seed > 0 {
+			r := rand.New(rand.NewPCG(seed, seed))
+			mu.pick = r.IntN
+			r.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		}
+
+		// contender index by goroutine id, filled before each Run starts
+		byID := make(map[int64]int)
+		mu.observe = func(id int64, wait time.Duration, overtakes int) {
+			i, ok := byID[id]
+			if !ok {
+				return
+			}
+			s := &rep.Stats[i]
+			s.Acquisitions++
+			if wait > s.MaxWait {
+				s.MaxWait, s.MaxWaitSeed = wait, seed
+			}
+			if overtakes > s.MaxOvertakes {
+				s.MaxOvertakes, s.OvertakeSeed = overtakes, seed
+			}
+		}
+
+		// Start the contenders one at a time so their arrival order is
+		// part of the explored schedule as well.
+		done := make(chan struct{})
+		for _, i := range order {
+			go func() {
+				defer func() { done <- struct{}{} }()
+				mu.mu.Lock()
+				byID[goid.ID()] = i
+				mu.mu.Unlock()
+				contenders[i].Run(mu)
+			}()
+			synctest.Wait()
+		}
+		for range contenders {
+			<-done
+		}
+	}
+	return rep
+}
@@ -0,0 +1,48 @@
+package syncx
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func hog(mu *Mutex) {
+	for range 5 {
+		mu.Lock()
+		time.Sleep(time.Millisecond)
+		mu.Unlock()
+	}
+}
+
+func TestExploreConvoy(t *testing.T) {
+	synctest.Run(func() {
+		rep := ExploreConvoy(t, 50, []Contender{
+			{Name: "hog-1", Run: hog},
+			{Name: "hog-2", Run: hog},
+			{Name: "hog-3", Run: hog},
+			{Name: "victim", Run: func(mu *Mutex) {
+				mu.Lock()
+				mu.Unlock()
+			}},
+		})
+		t.Log(rep)
+
+		victim := rep.Stats[3]
+		if victim.Acquisitions != 50 || rep.Stats[0].Acquisitions != 250 {
+			t.Fatalf("unexpected acquisition counts:\n%v", rep)
+		}
+		// Under first come, first served the victim waits for at most one
+		// section per hog; random handoff order lets it starve much longer.
+		if victim.MaxOvertakes <= 3 || victim.MaxWait <= 3*time.Millisecond {
+			t.Fatalf("exploration did not expose starvation of the victim:\n%v", rep)
+		}
+
+		ft := testtb.New(t)
+		rep.ExpectMaxOvertakes(ft, 3)
+		if len(ft.Errors()) == 0 {
+			t.Fatal("ExpectMaxOvertakes did not report the starved victim")
+		}
+	})
+}
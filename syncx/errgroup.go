@@ -0,0 +1,197 @@
+package syncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+	"testing/synctest"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Group wraps an errgroup.Group and keeps track of its children, so tests
+// can make assertions about the lifecycle of the group.
+type Group struct {
+	g   *errgroup.Group
+	ctx context.Context // nil unless created by GroupWithContext
+
+	mu       sync.Mutex
+	pending  int // callers inside Go that have not started their function yet
+	running  int
+	exited   int
+	firstErr error
+	children map[int64]bool // goroutines that ran a child function
+}
+
+// NewGroup returns a tracked zero errgroup.Group.
+func NewGroup() *Group {
+	return &Group{g: new(errgroup.Group)}
+}
+
+// GroupWithContext is the tracked counterpart of errgroup.WithContext.
+func GroupWithContext(ctx context.Context) (*Group, context.Context) {
+	g, ctx := errgroup.WithContext(ctx)
+	return &Group{g: g, ctx: ctx}, ctx
+}
+
+// SetLimit forwards to errgroup.Group.SetLimit.
+func (g *Group) SetLimit(n int) { g.g.SetLimit(n) }
+
+// Go forwards to errgroup.Group.Go. Like the original it blocks while the
+// group is at its limit; such callers are reported by Blocked.
+func (g *Group) Go(f func() error) {
+	g.mu.Lock()
+	g.pending++
+	g.mu.Unlock()
+	g.g.Go(g.wrap(f))
+}
+
+// TryGo forwards to errgroup.Group.TryGo.
+func (g *Group) TryGo(f func() error) bool {
+	g.mu.Lock()
+	g.pending++
+	g.mu.Unlock()
+	ok := g.g.TryGo(g.wrap(f))
+	if !ok {
+		g.mu.Lock()
+		g.pending--
+		g.mu.Unlock()
+	}
+	return ok
+}
+
+func (g *Group) wrap(f func() error) func() error {
+	return func() error {
+		g.mu.Lock()
+		g.pending--
+		g.running++
+		if g.children == nil {
+			g.children = make(map[int64]bool)
+		}
+		g.children[goid.ID()] = true
+		g.mu.Unlock()
+
+		err := f()
+
+		g.mu.Lock()
+		g.running--
+		g.exited++
+		if err != nil && g.firstErr == nil {
+			g.firstErr = err
+		}
+		g.mu.Unlock()
+		return err
+	}
+}
+
+// Wait forwards to errgroup.Group.Wait.
+func (g *Group) Wait() error { return g.g.Wait() }
+
+// Running reports the number of children currently executing.
+func (g *Group) Running() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.running
+}
+
+// Blocked reports the number of callers of Go that are waiting for the
+// limit to admit their function.
+func (g *Group) Blocked() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.pending
+}
+
+// Exited reports the number of children that have returned.
+func (g *Group) Exited() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.exited
+}
+
+// ExpectRunning waits until the bubble is idle and fails t unless exactly
+// running children execute and blocked callers are held back by the limit.
+func (g *Group) ExpectRunning(t testing.TB, running, blocked int) {
+	t.Helper()
+	synctest.Wait()
+	if got := g.Running(); got != running {
+		t.Errorf("errgroup: %d children running, want %d", got, running)
+	}
+	if got := g.Blocked(); got != blocked {
+		t.Errorf("errgroup: %d callers blocked on the limit, want %d", got, blocked)
+	}
+}
+
+// ExpectCanceled waits until the bubble is idle and fails t unless the
+// group context has been canceled with the first error returned by a child
+// as its cause. It also fails if no child has returned an error yet.
+func (g *Group) ExpectCanceled(t testing.TB) {
+	t.Helper()
+	if g.ctx == nil {
+		t.Fatalf("errgroup: ExpectCanceled needs a group created by GroupWithContext")
+	}
+	synctest.Wait()
+	g.mu.Lock()
+	first := g.firstErr
+	g.mu.Unlock()
+	if first == nil {
+		t.Errorf("errgroup: no child returned an error")
+		return
+	}
+	if g.ctx.Err() == nil {
+		t.Errorf("errgroup: context not canceled after child returned %v", first)
+		return
+	}
+	if cause := context.Cause(g.ctx); !errors.Is(cause, first) {
+		t.Errorf("errgroup: context canceled with cause %v, want first error %v", cause, first)
+	}
+}
+
+// ExpectWait calls Wait and fails t unless it returned want (compared with
+// errors.Is), no caller of Go was still waiting for the limit, and, once the
+// bubble has settled, no goroutine started by a child is still alive.
+// Wait only waits for the children themselves, so goroutines they spawn
+// without joining outlive the group unnoticed otherwise.
+func (g *Group) ExpectWait(t testing.TB, want error) {
+	t.Helper()
+	err := g.Wait()
+	if !errors.Is(err, want) {
+		t.Errorf("errgroup: Wait() = %v, want %v", err, want)
+	}
+	synctest.Wait()
+	g.mu.Lock()
+	pending := g.pending
+	spawned := maps.Clone(g.children)
+	g.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("errgroup: Wait returned with %d callers of Go not started", pending)
+	}
+
+	// Goroutines are attributed to a child through their creators; the
+	// ones live now are added until nothing changes, to find grandchildren.
+	live := gstack.Bubble()
+	for grown := true; grown; {
+		grown = false
+		for _, gr := range live {
+			if spawned[gr.Creator] && !spawned[gr.ID] {
+				spawned[gr.ID], grown = true, true
+			}
+		}
+	}
+	for _, gr := range live {
+		if !spawned[gr.ID] {
+			continue
+		}
+		where := ""
+		if f, ok := gr.UserFrame(); ok {
+			where = fmt.Sprintf(" in %s at %s:%d", f.Func, f.File, f.Line)
+		}
+		t.Errorf("errgroup: goroutine %d started by a child still running after Wait [%s]%s", gr.ID, gr.State, where)
+	}
+}
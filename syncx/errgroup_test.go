@@ -0,0 +1,87 @@
+package syncx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestGroupCanceledOnFirstError(t *testing.T) {
+	synctest.Run(func() {
+		g, ctx := GroupWithContext(context.Background())
+		errFirst := errors.New("first")
+
+		for range 3 {
+			g.Go(func() error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		}
+		g.Go(func() error {
+			time.Sleep(time.Second)
+			return errFirst
+		})
+
+		g.ExpectRunning(t, 4, 0)
+		time.Sleep(time.Second)
+		g.ExpectCanceled(t)
+		g.ExpectWait(t, errFirst)
+	})
+}
+
+func TestGroupSetLimit(t *testing.T) {
+	synctest.Run(func() {
+		g := NewGroup()
+		g.SetLimit(2)
+		release := make(chan struct{})
+
+		// Go blocks the caller once the limit is reached, so spawn from
+		// separate goroutines to observe the blocked ones.
+		for range 5 {
+			go g.Go(func() error {
+				<-release
+				return nil
+			})
+		}
+
+		g.ExpectRunning(t, 2, 3)
+		if g.TryGo(func() error { return nil }) {
+			t.Fatal("TryGo succeeded although the group is at its limit")
+		}
+
+		close(release)
+		synctest.Wait()
+		if got := g.Exited(); got != 5 {
+			t.Fatalf("%d children exited, want 5", got)
+		}
+		g.ExpectWait(t, nil)
+	})
+}
+
+func TestGroupExpectWaitLeakedGoroutine(t *testing.T) {
+	synctest.Run(func() {
+		g := NewGroup()
+		release := make(chan struct{})
+		g.Go(func() error {
+			// fire and forget: Wait does not wait for these
+			go func() {
+				go func() { <-release }()
+				<-release
+			}()
+			return nil
+		})
+		g.Go(func() error { return nil })
+
+		ft := testtb.New(t)
+		g.ExpectWait(ft, nil)
+		close(release)
+		if len(ft.Errors()) != 2 || !strings.Contains(ft.Errors()[0], "started by a child still running after Wait [chan receive]") {
+			t.Errorf("errors = %q", ft.Errors())
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package syncx
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/inject"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/vclock"
+)
+
+// Mutex is an instrumented replacement for sync.Mutex. Waiting for it is a
+// channel receive, so goroutines blocked on it count as durably blocked
+// inside a synctest bubble and are reported by bubble.Run when the bubble
+// deadlocks, and it detects misuse that would otherwise hang the bubble.
+//
+// A zero Mutex panics on misuse; one created by NewMutex reports the misuse
+// on its test and ends the offending goroutine instead.
+type Mutex struct {
+	name string
+	t    testing.TB
+
+	mu      sync.Mutex // guards the fields below
+	locked  bool
+	waiters []*mutexWaiter
+	acqs    int       // acquisitions so far
+	owner   int64     // goroutine holding the lock, 0 if unlocked or handed off
+	stack   string    // where owner acquired it
+	since   time.Time // when owner acquired it
+	budget  time.Duration
+
+	// pick chooses which of n waiters receives the lock on Unlock; nil
+	// means first come, first served. observe, if set, is told about every
+	// acquisition. Both are set by ExploreConvoy.
+	pick    func(n int) int
+	observe func(id int64, wait time.Duration, overtakes int)
+}
+
+type mutexWaiter struct {
+	ready chan struct{} // closed when the lock is handed to the waiter
+	acqs  int           // m.acqs when the waiter arrived
+}
+
+// NewMutex returns a Mutex that reports misuse on t. The name appears in
+// reports.
+func NewMutex(t testing.TB, name string) *Mutex {
+	return &Mutex{name: name, t: t}
+}
+
+// SetBudget declares that no critical section on m may last longer than d.
+// Unlock reports sections exceeding it, which usually means a sleep or I/O
+// was performed under the lock. Inside a bubble d is virtual time. Zero
+// disables the check.
+func (m *Mutex) SetBudget(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.budget = d
+}
+
+// Lock acquires m. A goroutine locking a Mutex it already holds is reported
+// with both acquisition stacks, since it would block forever.
+func (m *Mutex) Lock() {
+	inject.Point()
+	id, stack, start := goid.ID(), goid.Stack(), time.Now()
+	m.mu.Lock()
+	if m.owner == id {
+		first := m.stack
+		m.mu.Unlock()
+		m.misuse("goroutine %d re-acquires mutex %s it already holds\n\nfirst acquisition:\n%s\nsecond acquisition:\n%s", id, m, first, stack)
+		return
+	}
+	if !m.locked {
+		m.locked = true
+		m.acquired(id, stack, start, m.acqs)
+		m.mu.Unlock()
+		return
+	}
+	w := &mutexWaiter{ready: make(chan struct{}), acqs: m.acqs}
+	m.waiters = append(m.waiters, w)
+	holder := m.owner
+	m.mu.Unlock()
+
+	leave := block.Enter(block.Op{Kind: "lock", Object: fmt.Sprintf("mutex %s held by goroutine %d", m, holder)}, 1)
+	<-w.ready
+	leave()
+	m.mu.Lock()
+	m.acquired(id, stack, start, w.acqs)
+	m.mu.Unlock()
+}
+
+// TryLock tries to acquire m and reports whether it succeeded.
+func (m *Mutex) TryLock() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locked {
+		return false
+	}
+	m.locked = true
+	m.acquired(goid.ID(), goid.Stack(), time.Now(), m.acqs)
+	return true
+}
+
+// acquired records the new owner with m.mu held. It is called by the
+// methods acquiring m.
+func (m *Mutex) acquired(id int64, stack string, start time.Time, arrivedAt int) {
+	vclock.Acquire(m, "lock", "mutex "+m.String(), 2)
+	m.owner, m.stack, m.since = id, stack, time.Now()
+	if m.observe != nil {
+		m.observe(id, m.since.Sub(start), m.acqs-arrivedAt)
+	}
+	m.acqs++
+}
+
+// Unlock releases m, handing it to a waiting goroutine if there is one.
+// Like sync.Mutex it may be called from a goroutine other than the one
+// holding the lock.
+func (m *Mutex) Unlock() {
+	m.mu.Lock()
+	if !m.locked {
+		m.mu.Unlock()
+		m.misuse("unlock of unlocked mutex %s\n\n%s", m, goid.Stack())
+		return
+	}
+	vclock.Release(m, "unlock", "mutex "+m.String(), 1)
+	held, stack, budget := time.Since(m.since), m.stack, m.budget
+	m.owner, m.stack = 0, ""
+	if len(m.waiters) == 0 {
+		m.locked = false
+	} else {
+		i := 0
+		if m.pick != nil {
+			i = m.pick(len(m.waiters))
+		}
+		w := m.waiters[i]
+		m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+		close(w.ready)
+	}
+	m.mu.Unlock()
+
+	if budget > 0 && held > budget {
+		m.report("critical section on mutex %s lasted %v, budget is %v\n\nacquired at:\n%s\nreleased at:\n%s", m, held, budget, stack, goid.Stack())
+	}
+}
+
+func (m *Mutex) String() string {
+	if m.name == "" {
+		return fmt.Sprintf("%p", m)
+	}
+	return fmt.Sprintf("%q", m.name)
+}
+
+// misuse reports a misuse that cannot be recovered from and ends the calling
+// goroutine.
+func (m *Mutex) misuse(format string, args ...any) {
+	m.report(format, args...)
+	runtime.Goexit()
+}
+
+// report fails the test, or panics for a Mutex without test.
+func (m *Mutex) report(format string, args ...any) {
+	msg := "syncx: " + fmt.Sprintf(format, args...)
+	if m.t == nil {
+		panic(msg)
+	}
+	m.t.Errorf("%s", msg)
+}
@@ -0,0 +1,110 @@
+package syncx
+
+import (
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestMutexExclusion(t *testing.T) {
+	synctest.Run(func() {
+		mu := NewMutex(t, "counter")
+		counter := 0
+		done := make(chan struct{})
+		for range 10 {
+			go func() {
+				mu.Lock()
+				counter++
+				mu.Unlock()
+				done <- struct{}{}
+			}()
+		}
+		for range 10 {
+			<-done
+		}
+		if counter != 10 {
+			t.Fatalf("counter = %d, want 10", counter)
+		}
+	})
+}
+
+func TestMutexBlockedIsDurable(t *testing.T) {
+	synctest.Run(func() {
+		mu := NewMutex(t, "mu")
+		mu.Lock()
+		acquired := false
+		go func() {
+			mu.Lock()
+			acquired = true
+			mu.Unlock()
+		}()
+		synctest.Wait()
+		if acquired {
+			t.Fatal("second goroutine acquired a held mutex")
+		}
+		if mu.TryLock() {
+			t.Fatal("TryLock succeeded on a held mutex")
+		}
+		mu.Unlock()
+		synctest.Wait()
+		if !acquired {
+			t.Fatal("second goroutine did not acquire the released mutex")
+		}
+	})
+}
+
+func lockTwice(mu *Mutex) {
+	mu.Lock()
+	mu.Lock()
+}
+
+func TestMutexReentrantLock(t *testing.T) {
+	synctest.Run(func() {
+		ft := testtb.New(t)
+		mu := NewMutex(ft, "state")
+		go lockTwice(mu)
+		synctest.Wait()
+		if len(ft.Errors()) != 1 {
+			t.Fatalf("got %d reports, want 1: %q", len(ft.Errors()), ft.Errors())
+		}
+		msg := ft.Errors()[0]
+		if !strings.Contains(msg, `re-acquires mutex "state"`) || strings.Count(msg, "syncx.lockTwice") != 2 {
+			t.Fatalf("report lacks both acquisition stacks:\n%s", msg)
+		}
+	})
+}
+
+func TestMutexUnlockOfUnlocked(t *testing.T) {
+	var mu Mutex
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "unlock of unlocked mutex") {
+			t.Fatalf("recover() = %v, want unlock of unlocked mutex", r)
+		}
+	}()
+	mu.Unlock()
+}
+
+func TestMutexBudget(t *testing.T) {
+	synctest.Run(func() {
+		ft := testtb.New(t)
+		mu := NewMutex(ft, "cache")
+		mu.SetBudget(10 * time.Millisecond)
+
+		mu.Lock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Unlock()
+		if len(ft.Errors()) != 0 {
+			t.Fatalf("section within budget reported: %q", ft.Errors())
+		}
+
+		mu.Lock()
+		time.Sleep(11 * time.Millisecond)
+		mu.Unlock()
+		if len(ft.Errors()) != 1 || !strings.Contains(ft.Errors()[0], "lasted 11ms, budget is 10ms") {
+			t.Fatalf("over-budget section not reported correctly: %q", ft.Errors())
+		}
+	})
+}
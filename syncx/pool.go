@@ -0,0 +1,186 @@
+package syncx
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Pool is the part of sync.Pool that code under test should depend on, so
+// tests can substitute a SimPool.
+type Pool[T any] interface {
+	Get() T
+	Put(T)
+}
+
+// StdPool adapts a sync.Pool to Pool.
+type StdPool[T any] struct {
+	p sync.Pool
+}
+
+// NewStdPool returns a Pool backed by sync.Pool.
+func NewStdPool[T any](newFn func() T) *StdPool[T] {
+	return &StdPool[T]{p: sync.Pool{New: func() any { return newFn() }}}
+}
+
+func (p *StdPool[T]) Get() T  { return p.p.Get().(T) }
+func (p *StdPool[T]) Put(x T) { p.p.Put(x) }
+
+// PoolStats counts what happened inside a SimPool.
+type PoolStats struct {
+	Gets, Puts int
+	News       int // Gets that had to call New
+	Steals     int // Gets served from another P's cache
+	Victims    int // Gets served from the victim cache
+	GCs        int
+}
+
+// SimPool is a deterministic model of sync.Pool. Like the runtime it keeps
+// one cache per P and a victim cache that survives a single GC, and each
+// operation runs on a P chosen by a seeded generator, as if the calling
+// goroutine had migrated. Unlike sync.Pool nothing is dropped behind the
+// test's back: objects disappear only through SimulateGC.
+type SimPool[T any] struct {
+	newFn func() T
+
+	// GCEvery, if positive, calls SimulateGC before every GCEvery-th Get.
+	GCEvery int
+
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	local  [][]T
+	victim [][]T
+	stats  PoolStats
+}
+
+// NewSimPool returns a SimPool with ps simulated Ps.
+func NewSimPool[T any](newFn func() T, ps int, seed uint64) *SimPool[T] {
+	if ps < 1 {
+		ps = 1
+	}
+	return &SimPool[T]{
+		newFn:  newFn,
+		rnd:    rand.New(rand.NewPCG(seed, seed)),
+		local:  make([][]T, ps),
+		victim: make([][]T, ps),
+	}
+}
+
+// Get returns an object from the current P's cache, from another P's or the
+// victim cache, or a new one.
+func (p *SimPool[T]) Get() T {
+	p.mu.Lock()
+	p.stats.Gets++
+	if p.GCEvery > 0 && p.stats.Gets%p.GCEvery == 0 {
+		p.gc()
+	}
+	pid := p.rnd.IntN(len(p.local))
+	if x, ok := pop(&p.local[pid]); ok {
+		p.mu.Unlock()
+		return x
+	}
+	for i := 1; i < len(p.local); i++ {
+		if x, ok := steal(&p.local[(pid+i)%len(p.local)]); ok {
+			p.stats.Steals++
+			p.mu.Unlock()
+			return x
+		}
+	}
+	for i := range p.victim {
+		if x, ok := steal(&p.victim[(pid+i)%len(p.victim)]); ok {
+			p.stats.Victims++
+			p.mu.Unlock()
+			return x
+		}
+	}
+	p.stats.News++
+	p.mu.Unlock()
+	return p.newFn()
+}
+
+// Put adds x to the current P's cache.
+func (p *SimPool[T]) Put(x T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Puts++
+	pid := p.rnd.IntN(len(p.local))
+	p.local[pid] = append(p.local[pid], x)
+}
+
+// SimulateGC does what a garbage collection does to a sync.Pool: the victim
+// cache is dropped and the per-P caches become the new victim cache. Two
+// calls in a row empty the pool.
+func (p *SimPool[T]) SimulateGC() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gc()
+}
+
+func (p *SimPool[T]) gc() {
+	p.stats.GCs++
+	p.victim = p.local
+	p.local = make([][]T, len(p.victim))
+}
+
+// Stats returns the counters collected so far.
+func (p *SimPool[T]) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// pop takes the most recently put object, like a P's private slot.
+func pop[T any](s *[]T) (T, bool) {
+	var zero T
+	if len(*s) == 0 {
+		return zero, false
+	}
+	x := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return x, true
+}
+
+// steal takes the oldest object, like stealing from another P's shared list.
+func steal[T any](s *[]T) (T, bool) {
+	var zero T
+	if len(*s) == 0 {
+		return zero, false
+	}
+	x := (*s)[0]
+	*s = (*s)[1:]
+	return x, true
+}
+
+// ExpectPoolAgnostic runs fn against pools with very different reuse
+// behaviour and fails t unless every run produced the same result as a run
+// where the pool never reuses anything. It catches code that forgets to
+// reset pooled objects or that relies on getting back what it put. It does
+// not need a bubble.
+func ExpectPoolAgnostic[T, R any](t testing.TB, newFn func() T, fn func(Pool[T]) R) {
+	t.Helper()
+	want := fn(noReuse[T]{newFn})
+
+	gcEvery := func(n int, p *SimPool[T]) *SimPool[T] { p.GCEvery = n; return p }
+	pools := []struct {
+		name string
+		p    *SimPool[T]
+	}{
+		{"reuse", NewSimPool(newFn, 1, 0)},
+		{"per-P", NewSimPool(newFn, 4, 1)},
+		{"per-P-seed-42", NewSimPool(newFn, 8, 42)},
+		{"gc-every-get", gcEvery(1, NewSimPool(newFn, 2, 2))},
+		{"gc-every-3rd", gcEvery(3, NewSimPool(newFn, 2, 3))},
+	}
+	for _, pp := range pools {
+		if got := fn(pp.p); !reflect.DeepEqual(got, want) {
+			t.Errorf("pool %s: result %v differs from run without reuse %v (stats %+v)", pp.name, got, want, pp.p.Stats())
+		}
+	}
+}
+
+// noReuse is a Pool that always allocates.
+type noReuse[T any] struct{ newFn func() T }
+
+func (p noReuse[T]) Get() T { return p.newFn() }
+func (noReuse[T]) Put(T)    {}
@@ -0,0 +1,53 @@
+package syncx
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestSimPoolGC(t *testing.T) {
+	p := NewSimPool(func() *int { return new(int) }, 2, 7)
+	x := new(int)
+	p.Put(x)
+	p.SimulateGC()
+	if got := p.Get(); got != x {
+		t.Fatalf("object should survive one GC in the victim cache")
+	}
+	p.Put(x)
+	p.SimulateGC()
+	p.SimulateGC()
+	if got := p.Get(); got == x {
+		t.Fatalf("object should be gone after two GCs")
+	}
+	if s := p.Stats(); s.Victims != 1 || s.News != 1 || s.GCs != 3 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func render(p Pool[*bytes.Buffer], reset bool) string {
+	var out []string
+	for i := range 3 {
+		b := p.Get()
+		if reset {
+			b.Reset()
+		}
+		fmt.Fprintf(b, "line %d", i)
+		out = append(out, b.String())
+		p.Put(b)
+	}
+	return fmt.Sprint(out)
+}
+
+func TestExpectPoolAgnostic(t *testing.T) {
+	newBuf := func() *bytes.Buffer { return new(bytes.Buffer) }
+	ExpectPoolAgnostic(t, newBuf, func(p Pool[*bytes.Buffer]) string { return render(p, true) })
+
+	ft := testtb.New(t)
+	ExpectPoolAgnostic(ft, newBuf, func(p Pool[*bytes.Buffer]) string { return render(p, false) })
+	if len(ft.Errors()) == 0 {
+		t.Fatal("missing Reset of pooled buffer not detected")
+	}
+}
@@ -0,0 +1,171 @@
+// Package syncx contains bubble-based test helpers for the concurrency
+// primitives of sync, sync/atomic and golang.org/x/sync.
+//
+// Unless stated otherwise the helpers must be called from within a
+// synctest bubble, since they rely on synctest.Wait and on virtual time.
+package syncx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Flight is what a single caller of singleflight.Group.Do observed.
+type Flight struct {
+	Val    any
+	Err    error
+	Shared bool
+	Panic  any // recovered panic value, nil if Do returned normally
+}
+
+// Flights summarizes a batch of concurrent Do calls.
+type Flights struct {
+	Calls   int      // how often fn was executed
+	Callers []Flight // one entry per caller, in start order
+}
+
+// DoConcurrently starts n callers of g.Do(key, fn) and returns after all of
+// them have returned. fn is held back until every caller is inside Do, so a
+// correct Group executes it exactly once.
+func DoConcurrently(g *singleflight.Group, key string, n int, fn func() (any, error)) Flights {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	held := func() (any, error) {
+		calls.Add(1)
+		<-release
+		return fn()
+	}
+
+	res := Flights{Callers: make([]Flight, n)}
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res.Callers[i] = doRecover(g, key, held)
+		}()
+	}
+
+	// every caller is now either inside fn or waiting for it
+	synctest.Wait()
+	close(release)
+	wg.Wait()
+
+	res.Calls = int(calls.Load())
+	return res
+}
+
+func doRecover(g *singleflight.Group, key string, fn func() (any, error)) (f Flight) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.Panic = r
+		}
+	}()
+	f.Val, f.Err, f.Shared = g.Do(key, fn)
+	return f
+}
+
+// Slow returns a flight function that sleeps for d of virtual time before
+// returning v and err.
+func Slow(d time.Duration, v any, err error) func() (any, error) {
+	return func() (any, error) {
+		time.Sleep(d)
+		return v, err
+	}
+}
+
+// ExpectShared runs n concurrent callers through g and fails t unless fn ran
+// exactly once and every caller received the same, shared result.
+func ExpectShared(t testing.TB, g *singleflight.Group, key string, n int, fn func() (any, error)) Flights {
+	t.Helper()
+	if n < 1 {
+		t.Fatalf("singleflight %q: ExpectShared needs at least one caller, got %d", key, n)
+	}
+	res := DoConcurrently(g, key, n, fn)
+	if res.Calls != 1 {
+		t.Errorf("singleflight %q: fn executed %d times for %d callers, want 1", key, res.Calls, n)
+	}
+	first := res.Callers[0]
+	for i, f := range res.Callers {
+		if f.Panic != nil {
+			t.Errorf("singleflight %q: caller %d panicked: %v", key, i, f.Panic)
+			continue
+		}
+		if n > 1 && !f.Shared {
+			t.Errorf("singleflight %q: caller %d got shared=false", key, i)
+		}
+		if !reflect.DeepEqual(f.Val, first.Val) || f.Err != first.Err {
+			t.Errorf("singleflight %q: caller %d got (%v, %v), caller 0 got (%v, %v)", key, i, f.Val, f.Err, first.Val, first.Err)
+		}
+	}
+	return res
+}
+
+// ExpectForget checks that Forget(key) detaches an in-flight call: a caller
+// arriving after Forget must execute fn again instead of joining the
+// running flight, and both callers must still complete.
+func ExpectForget(t testing.TB, g *singleflight.Group, key string) {
+	t.Helper()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fn := func() (any, error) {
+		n := calls.Add(1)
+		<-release
+		return n, nil
+	}
+
+	results := make(chan Flight, 2)
+	go func() { results <- doRecover(g, key, fn) }()
+	synctest.Wait()
+
+	g.Forget(key)
+	go func() { results <- doRecover(g, key, fn) }()
+	synctest.Wait()
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("singleflight %q: after Forget fn executed %d times, want 2", key, got)
+	}
+	close(release)
+	for range 2 {
+		if f := <-results; f.Shared || f.Panic != nil {
+			t.Errorf("singleflight %q: caller after Forget got %+v, want an unshared result", key, f)
+		}
+	}
+}
+
+// ExpectPanicShared makes fn panic with v while n callers are waiting on it.
+// Every caller must observe the panic, and the key must not stay poisoned:
+// a later Do has to execute its function again.
+func ExpectPanicShared(t testing.TB, g *singleflight.Group, key string, n int, v any) {
+	t.Helper()
+	if n < 1 {
+		t.Fatalf("singleflight %q: ExpectPanicShared needs at least one caller, got %d", key, n)
+	}
+	res := DoConcurrently(g, key, n, func() (any, error) { panic(v) })
+	if res.Calls != 1 {
+		t.Errorf("singleflight %q: panicking fn executed %d times for %d callers, want 1", key, res.Calls, n)
+	}
+	want := fmt.Sprint(v)
+	for i, f := range res.Callers {
+		if f.Panic == nil {
+			t.Errorf("singleflight %q: caller %d returned (%v, %v) instead of panicking", key, i, f.Val, f.Err)
+			continue
+		}
+		if got := fmt.Sprint(f.Panic); !strings.HasPrefix(got, want) {
+			t.Errorf("singleflight %q: caller %d panicked with %q, want %q", key, i, got, want)
+		}
+	}
+
+	after := doRecover(g, key, func() (any, error) { return "fresh", nil })
+	if after.Panic != nil || after.Val != "fresh" {
+		t.Errorf("singleflight %q: Do after panic got %+v, want a fresh execution", key, after)
+	}
+}
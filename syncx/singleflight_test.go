@@ -0,0 +1,66 @@
+package syncx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+func TestExpectShared(t *testing.T) {
+	synctest.Run(func() {
+		var g singleflight.Group
+		res := ExpectShared(t, &g, "k", 5, func() (any, error) { return 42, nil })
+		if len(res.Callers) != 5 || res.Callers[4].Val != 42 {
+			t.Fatalf("unexpected result %+v", res)
+		}
+	})
+}
+
+func TestExpectSharedSlowFlight(t *testing.T) {
+	synctest.Run(func() {
+		var g singleflight.Group
+		errSlow := errors.New("slow")
+		start := time.Now()
+		res := ExpectShared(t, &g, "k", 3, Slow(10*time.Second, nil, errSlow))
+		if got := time.Since(start); got != 10*time.Second {
+			t.Fatalf("slow flight took %v of virtual time, want 10s", got)
+		}
+		for i, f := range res.Callers {
+			if f.Err != errSlow {
+				t.Fatalf("caller %d: err = %v, want %v", i, f.Err, errSlow)
+			}
+		}
+	})
+}
+
+func TestExpectForget(t *testing.T) {
+	synctest.Run(func() {
+		var g singleflight.Group
+		ExpectForget(t, &g, "k")
+	})
+}
+
+func TestExpectPanicShared(t *testing.T) {
+	synctest.Run(func() {
+		var g singleflight.Group
+		ExpectPanicShared(t, &g, "k", 4, "boom")
+	})
+}
+
+func TestExpectSharedNoCallers(t *testing.T) {
+	synctest.Run(func() {
+		var g singleflight.Group
+		errs := testtb.Run(t, func(t testing.TB) {
+			ExpectShared(t, &g, "k", 0, func() (any, error) { return 1, nil })
+		})
+		if len(errs) != 1 || !strings.Contains(errs[0], "needs at least one caller") {
+			t.Errorf("errors = %q", errs)
+		}
+	})
+}
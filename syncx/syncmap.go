@@ -0,0 +1,334 @@
+package syncx
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+)
+
+// MapOpKind is an operation on a sync.Map.
+type MapOpKind int
+
+const (
+	MapLoad MapOpKind = iota
+	MapStore
+	MapLoadOrStore
+	MapDelete
+	MapRange
+	// MapLoadStore is the non-atomic read-modify-write misuse pattern: a
+	// Load followed by a Store of the loaded value plus one. The scheduler
+	// may interleave other operations between the two halves.
+	MapLoadStore
+)
+
+var mapOpNames = [...]string{"Load", "Store", "LoadOrStore", "Delete", "Range", "Load+Store"}
+
+func (k MapOpKind) String() string { return mapOpNames[k] }
+
+// MapOp is one operation of a goroutine's program.
+type MapOp struct {
+	Kind  MapOpKind
+	Key   int
+	Value int
+}
+
+func (op MapOp) String() string {
+	switch op.Kind {
+	case MapStore, MapLoadOrStore:
+		return fmt.Sprintf("%v(%d, %d)", op.Kind, op.Key, op.Value)
+	case MapRange:
+		return "Range"
+	}
+	return fmt.Sprintf("%v(%d)", op.Kind, op.Key)
+}
+
+// MapScenario assigns a program of operations to each goroutine.
+type MapScenario struct {
+	Programs [][]MapOp
+}
+
+// GenMapScenario generates a scenario with the given number of goroutines,
+// each executing ops random operations on keys 0..keys-1. Values are unique
+// per scenario so the checker can tell which write a read observed.
+func GenMapScenario(r *rand.Rand, goroutines, ops, keys int) MapScenario {
+	var sc MapScenario
+	val := 0
+	for range goroutines {
+		prog := make([]MapOp, ops)
+		for i := range prog {
+			val++
+			prog[i] = MapOp{
+				Kind:  MapOpKind(r.IntN(len(mapOpNames))),
+				Key:   r.IntN(keys),
+				Value: val,
+			}
+		}
+		sc.Programs = append(sc.Programs, prog)
+	}
+	return sc
+}
+
+// MapReport is the outcome of one scheduled run of a MapScenario.
+type MapReport struct {
+	Seed  uint64
+	Trace []string // executed steps in schedule order
+	// Violations are results that contradict the documented guarantees of
+	// sync.Map; they indicate a bug in the map.
+	Violations []string
+	// LostUpdates are MapLoadStore operations whose key was written between
+	// their Load and their Store. They are expected and document why
+	// read-modify-write must use CompareAndSwap instead.
+	LostUpdates []string
+}
+
+type mapVal struct {
+	v  int
+	ok bool
+}
+
+func (v mapVal) String() string {
+	if !v.ok {
+		return "<absent>"
+	}
+	return fmt.Sprint(v.v)
+}
+
+// mapChecker is the sequential model. It is only touched by the one worker
+// the scheduler released, so steps apply to it in schedule order.
+type mapChecker struct {
+	mu      sync.Mutex
+	model   map[int]int
+	version map[int]int
+	ranges  map[*mapRange]bool
+	rep     *MapReport
+}
+
+// mapRange tracks a Range call in progress. Range is not a snapshot: a key
+// may be reported with any value it held during the call, keys never
+// removed during the call must be visited, and no key may be visited twice.
+type mapRange struct {
+	g        int
+	possible map[int]map[mapVal]bool
+	touched  map[int]bool
+	visited  map[int]int
+}
+
+func (c *mapChecker) logf(format string, args ...any) {
+	c.rep.Trace = append(c.rep.Trace, fmt.Sprintf(format, args...))
+}
+
+func (c *mapChecker) violatef(format string, args ...any) {
+	c.rep.Violations = append(c.rep.Violations, fmt.Sprintf("step %d: ", len(c.rep.Trace))+fmt.Sprintf(format, args...))
+}
+
+func (c *mapChecker) current(k int) mapVal {
+	v, ok := c.model[k]
+	return mapVal{v, ok}
+}
+
+func (c *mapChecker) set(k int, v mapVal) {
+	if v.ok {
+		c.model[k] = v.v
+	} else {
+		delete(c.model, k)
+	}
+	c.version[k]++
+	for r := range c.ranges {
+		if r.possible[k] == nil {
+			r.possible[k] = make(map[mapVal]bool)
+		}
+		r.possible[k][v] = true
+		r.touched[k] = true
+	}
+}
+
+func (c *mapChecker) expect(g int, op MapOp, got, want mapVal) {
+	c.logf("g%d %v = %v", g, op, got)
+	if got != want {
+		c.violatef("g%d %v returned %v, sequential model has %v", g, op, got, want)
+	}
+}
+
+// RunMapScenario executes sc against a fresh sync.Map, releasing one
+// goroutine step at a time in an order derived from seed, and checks every
+// result against a sequential model. A step is a single operation, a single
+// Range callback, or one half of a MapLoadStore. Violations are reported on
+// t; the full report is returned.
+func RunMapScenario(t testing.TB, sc MapScenario, seed uint64) MapReport {
+	t.Helper()
+	rep := MapReport{Seed: seed}
+	c := &mapChecker{
+		model:   make(map[int]int),
+		version: make(map[int]int),
+		ranges:  make(map[*mapRange]bool),
+		rep:     &rep,
+	}
+	var m sync.Map
+
+	steps := make([]chan struct{}, len(sc.Programs))
+	done := make([]bool, len(sc.Programs))
+	for g, prog := range sc.Programs {
+		steps[g] = make(chan struct{})
+		go func() {
+			for _, op := range prog {
+				<-steps[g]
+				c.mu.Lock()
+				c.run(&m, g, op, steps[g])
+				c.mu.Unlock()
+			}
+			c.mu.Lock()
+			done[g] = true
+			c.mu.Unlock()
+		}()
+	}
+
+	r := rand.New(rand.NewPCG(seed, seed))
+	for {
+		synctest.Wait()
+		c.mu.Lock()
+		var live []int
+		for g, d := range done {
+			if !d {
+				live = append(live, g)
+			}
+		}
+		c.mu.Unlock()
+		if len(live) == 0 {
+			break
+		}
+		steps[live[r.IntN(len(live))]] <- struct{}{}
+	}
+
+	c.checkFinal(&m)
+	if len(rep.Violations) > 0 {
+		t.Errorf("sync.Map scenario (seed %d) violates sequential model:\n\t%s\ntrace:\n\t%s",
+			seed, strings.Join(rep.Violations, "\n\t"), strings.Join(rep.Trace, "\n\t"))
+	}
+	return rep
+}
+
+// run executes op for goroutine g with c.mu held. Operations spanning
+// several steps release c.mu while waiting on step.
+func (c *mapChecker) run(m *sync.Map, g int, op MapOp, step chan struct{}) {
+	switch op.Kind {
+	case MapLoad:
+		v, ok := m.Load(op.Key)
+		c.expect(g, op, toMapVal(v, ok), c.current(op.Key))
+	case MapStore:
+		m.Store(op.Key, op.Value)
+		c.logf("g%d %v", g, op)
+		c.set(op.Key, mapVal{op.Value, true})
+	case MapLoadOrStore:
+		v, loaded := m.LoadOrStore(op.Key, op.Value)
+		want := c.current(op.Key)
+		got := mapVal{v.(int), loaded}
+		if !loaded {
+			got.v, want.v = v.(int), op.Value
+		}
+		c.expect(g, op, got, want)
+		if !want.ok {
+			c.set(op.Key, mapVal{op.Value, true})
+		}
+	case MapDelete:
+		m.Delete(op.Key)
+		c.logf("g%d %v", g, op)
+		c.set(op.Key, mapVal{})
+	case MapLoadStore:
+		v, ok := m.Load(op.Key)
+		c.expect(g, MapOp{Kind: MapLoad, Key: op.Key}, toMapVal(v, ok), c.current(op.Key))
+		ver := c.version[op.Key]
+		c.mu.Unlock()
+		<-step
+		c.mu.Lock()
+		next := 1
+		if ok {
+			next = v.(int) + 1
+		}
+		m.Store(op.Key, next)
+		c.logf("g%d Store(%d, %d) [second half of Load+Store]", g, op.Key, next)
+		if c.version[op.Key] != ver {
+			c.rep.LostUpdates = append(c.rep.LostUpdates,
+				fmt.Sprintf("g%d Load+Store(%d): key written %d time(s) between Load and Store", g, op.Key, c.version[op.Key]-ver))
+		}
+		c.set(op.Key, mapVal{next, true})
+	case MapRange:
+		c.rangeOp(m, g, step)
+	}
+}
+
+func (c *mapChecker) rangeOp(m *sync.Map, g int, step chan struct{}) {
+	r := &mapRange{
+		g:        g,
+		possible: make(map[int]map[mapVal]bool),
+		touched:  make(map[int]bool),
+		visited:  make(map[int]int),
+	}
+	for k, v := range c.model {
+		r.possible[k] = map[mapVal]bool{{v, true}: true}
+	}
+	c.ranges[r] = true
+	c.logf("g%d Range start", g)
+
+	m.Range(func(k, v any) bool {
+		key, val := k.(int), mapVal{v.(int), true}
+		c.logf("g%d Range visits %d = %v", g, key, val)
+		r.visited[key]++
+		if r.visited[key] > 1 {
+			c.violatef("g%d Range visited key %d %d times", g, key, r.visited[key])
+		}
+		if !r.possible[key][val] {
+			c.violatef("g%d Range reported %d = %v, a value the key never held during the call", g, key, val)
+		}
+		c.mu.Unlock()
+		<-step
+		c.mu.Lock()
+		return true
+	})
+
+	delete(c.ranges, r)
+	c.logf("g%d Range end", g)
+	for k := range r.possible {
+		if !r.touched[k] && r.visited[k] == 0 {
+			c.violatef("g%d Range skipped key %d although it was present and unmodified throughout", g, k)
+		}
+	}
+}
+
+func (c *mapChecker) checkFinal(m *sync.Map) {
+	seen := make(map[int]bool)
+	m.Range(func(k, v any) bool {
+		key := k.(int)
+		seen[key] = true
+		if want := c.current(key); want != (mapVal{v.(int), true}) {
+			c.violatef("final map has %d = %v, model has %v", key, v, want)
+		}
+		return true
+	})
+	for k, v := range c.model {
+		if !seen[k] {
+			c.violatef("final map lacks %d = %d", k, v)
+		}
+	}
+}
+
+func toMapVal(v any, ok bool) mapVal {
+	if !ok {
+		return mapVal{}
+	}
+	return mapVal{v.(int), true}
+}
+
+// ExploreMap runs n generated scenarios, each under its own seeded
+// schedule, and returns the reports of all runs.
+func ExploreMap(t testing.TB, n, goroutines, ops, keys int) []MapReport {
+	t.Helper()
+	var reps []MapReport
+	for seed := range uint64(n) {
+		sc := GenMapScenario(rand.New(rand.NewPCG(seed, 0)), goroutines, ops, keys)
+		reps = append(reps, RunMapScenario(t, sc, seed))
+	}
+	return reps
+}
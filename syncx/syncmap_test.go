@@ -0,0 +1,35 @@
+package syncx
+
+import (
+	"testing"
+	"testing/synctest"
+)
+
+func TestSyncMapScenarios(t *testing.T) {
+	synctest.Run(func() {
+		reps := ExploreMap(t, 200, 3, 6, 3)
+
+		// Load followed by Store is not atomic: across enough schedules some
+		// other goroutine writes in between. This is the documented reason to
+		// use CompareAndSwap for read-modify-write.
+		lost := 0
+		for _, rep := range reps {
+			lost += len(rep.LostUpdates)
+		}
+		if lost == 0 {
+			t.Fatal("expected at least one schedule to expose a lost update in Load+Store")
+		}
+	})
+}
+
+func TestSyncMapRangeInterleaved(t *testing.T) {
+	synctest.Run(func() {
+		sc := MapScenario{Programs: [][]MapOp{
+			{{Kind: MapStore, Key: 0, Value: 1}, {Kind: MapStore, Key: 1, Value: 2}, {Kind: MapRange}},
+			{{Kind: MapStore, Key: 0, Value: 3}, {Kind: MapDelete, Key: 1}, {Kind: MapLoadOrStore, Key: 2, Value: 4}},
+		}}
+		for seed := range uint64(50) {
+			RunMapScenario(t, sc, seed)
+		}
+	})
+}
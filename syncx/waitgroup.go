@@ -0,0 +1,35 @@
+package syncx
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/vclock"
+)
+
+// WaitGroup is a sync.WaitGroup whose Done and Wait are recorded in the
+// happens-before graph of package hb: everything before a Done happens
+// before the return of the Wait it lets through.
+type WaitGroup struct {
+	wg sync.WaitGroup
+}
+
+// Add adds delta, which may be negative, to the counter of wg, as
+// sync.WaitGroup.Add does.
+func (wg *WaitGroup) Add(delta int) { wg.wg.Add(delta) }
+
+// Done decrements the counter of wg by one, releasing what the calling
+// goroutine did so far to the Wait it lets through.
+func (wg *WaitGroup) Done() {
+	vclock.Release(wg, "done", wg.String(), 1)
+	wg.wg.Done()
+}
+
+// Wait blocks until the counter of wg is zero. Everything before the Done
+// calls that brought it there happens before Wait returns.
+func (wg *WaitGroup) Wait() {
+	wg.wg.Wait()
+	vclock.Acquire(wg, "wait", wg.String(), 1)
+}
+
+func (wg *WaitGroup) String() string { return fmt.Sprintf("wait group %p", wg) }
@@ -0,0 +1,226 @@
+// Package temporal states temporal properties over the events a bubble
+// run records, such as "the queue length always stays within its
+// capacity" or "every request leads to a response within 30s".
+//
+// Events are stamped with the bubble's virtual time, so deadlines in
+// properties are exact and do not depend on how fast the test runs.
+package temporal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/events"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/goid"
+)
+
+// Event is one recorded event.
+type Event struct {
+	At        time.Time
+	Goroutine string // named with bubble.Go, or "g<id>"
+	Name      string
+	// Key correlates events, e.g. a request with its response; empty if
+	// the event relates to no other.
+	Key   string
+	Value any
+}
+
+func (e Event) String() string {
+	s := e.Name
+	if e.Key != "" {
+		s += "[" + e.Key + "]"
+	}
+	if e.Value != nil {
+		s += fmt.Sprintf("(%v)", e.Value)
+	}
+	return fmt.Sprintf("%s %s by %s", e.At.UTC().Format(stamp), s, e.Goroutine)
+}
+
+const stamp = "15:04:05.000"
+
+// Log records events. It is safe for concurrent use.
+type Log struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Record records an event named name with value at the current time.
+func (l *Log) Record(name string, value any) { l.RecordKey(name, "", value) }
+
+// RecordKey records an event named name for key with value.
+func (l *Log) RecordKey(name, key string, value any) {
+	e := Event{At: time.Now(), Goroutine: goid.Name(goid.ID()), Name: name, Key: key, Value: value}
+	l.mu.Lock()
+	l.events = append(l.events, e)
+	l.mu.Unlock()
+}
+
+// Events returns the events recorded so far, in order.
+func (l *Log) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Event(nil), l.events...)
+}
+
+// Check fails t for every formula the events of l do not satisfy. Call it
+// once the run is over, since formulas such as Eventually judge the whole
+// run.
+func Check(t testing.TB, l *Log, formulas ...Formula) {
+	t.Helper()
+	evs := l.Events()
+	for _, f := range formulas {
+		if err := f.Check(evs); err != nil {
+			events.Emit(t, events.Event{Action: "violation", Message: fmt.Sprintf("%v violated: %v", f, err)})
+			t.Errorf("%v violated:\n\t%v", f, err)
+		}
+	}
+}
+
+// Pred is a named condition on events.
+type Pred struct {
+	Name  string
+	Holds func(e Event) bool
+}
+
+// Is holds for the events named name.
+func Is(name string) Pred {
+	return Pred{name, func(e Event) bool { return e.Name == name }}
+}
+
+// Where holds for the events f accepts; desc describes it in reports.
+func Where(desc string, f func(e Event) bool) Pred { return Pred{desc, f} }
+
+// Value holds for the events named name whose value, of type V, f
+// accepts; desc describes the condition on the value.
+func Value[V any](name, desc string, f func(v V) bool) Pred {
+	return Pred{name + "." + desc, func(e Event) bool {
+		v, ok := e.Value.(V)
+		return e.Name == name && ok && f(v)
+	}}
+}
+
+func (p Pred) And(q Pred) Pred {
+	return Pred{"(" + p.Name + " ∧ " + q.Name + ")", func(e Event) bool { return p.Holds(e) && q.Holds(e) }}
+}
+
+func (p Pred) Or(q Pred) Pred {
+	return Pred{"(" + p.Name + " ∨ " + q.Name + ")", func(e Event) bool { return p.Holds(e) || q.Holds(e) }}
+}
+
+func (p Pred) Not() Pred {
+	return Pred{"¬" + p.Name, func(e Event) bool { return !p.Holds(e) }}
+}
+
+// Implies holds for the events for which p does not hold or q does.
+func (p Pred) Implies(q Pred) Pred {
+	return Pred{"(" + p.Name + " → " + q.Name + ")", func(e Event) bool { return !p.Holds(e) || q.Holds(e) }}
+}
+
+// Formula is a temporal property of a sequence of events.
+type Formula interface {
+	// Check returns why events do not satisfy the formula, or nil.
+	Check(events []Event) error
+	String() string
+}
+
+// Always holds if p holds for every event.
+func Always(p Pred) Formula { return always{p} }
+
+// Never holds if p holds for no event.
+func Never(p Pred) Formula { return never{p} }
+
+// Eventually holds if p holds for some event.
+func Eventually(p Pred) Formula { return eventually{p} }
+
+// LeadsTo holds if every event satisfying p is followed by one satisfying
+// q, with the same Key if the first has one, within d of virtual time; a
+// d of zero allows any time before the end of the run.
+func LeadsTo(p, q Pred, d time.Duration) Formula { return leadsTo{p, q, d} }
+
+type always struct{ p Pred }
+
+func (f always) String() string { return "always " + f.p.Name }
+
+func (f always) Check(events []Event) error {
+	for i, e := range events {
+		if !f.p.Holds(e) {
+			return fmt.Errorf("event %d %v", i+1, e)
+		}
+	}
+	return nil
+}
+
+type never struct{ p Pred }
+
+func (f never) String() string { return "never " + f.p.Name }
+
+func (f never) Check(events []Event) error {
+	for i, e := range events {
+		if f.p.Holds(e) {
+			return fmt.Errorf("event %d %v", i+1, e)
+		}
+	}
+	return nil
+}
+
+type eventually struct{ p Pred }
+
+func (f eventually) String() string { return "eventually " + f.p.Name }
+
+func (f eventually) Check(events []Event) error {
+	for _, e := range events {
+		if f.p.Holds(e) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no such event among %d", len(events))
+}
+
+type leadsTo struct {
+	p, q Pred
+	d    time.Duration
+}
+
+func (f leadsTo) String() string {
+	s := f.p.Name + " leads to " + f.q.Name
+	if f.d > 0 {
+		s += " within " + f.d.String()
+	}
+	return s
+}
+
+func (f leadsTo) Check(events []Event) error {
+	var errs []string
+	for i, e := range events {
+		if !f.p.Holds(e) {
+			continue
+		}
+		var late *Event
+		found := false
+		for j := i + 1; j < len(events) && !found; j++ {
+			r := events[j]
+			if !f.q.Holds(r) || e.Key != "" && r.Key != e.Key {
+				continue
+			}
+			if f.d > 0 && r.At.Sub(e.At) > f.d {
+				late = &events[j]
+				break
+			}
+			found = true
+		}
+		switch {
+		case found:
+		case late != nil:
+			errs = append(errs, fmt.Sprintf("event %d %v answered after %v by %v", i+1, e, late.At.Sub(e.At), *late))
+		default:
+			errs = append(errs, fmt.Sprintf("event %d %v never answered", i+1, e))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
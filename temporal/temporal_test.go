@@ -0,0 +1,77 @@
+package temporal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/bubble"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// serve runs a server answering each request after delay, except those
+// for which slow returns true, which take ten times longer. The queue
+// length is recorded on every change.
+func serve(l *Log, n int, delay time.Duration, slow func(i int) bool) {
+	synctest.Run(func() {
+		reqs := make(chan int, 4)
+		bubble.Go("server", func() {
+			for i := range reqs {
+				l.Record("len", len(reqs))
+				d := delay
+				if slow(i) {
+					d *= 10
+				}
+				time.Sleep(d)
+				l.RecordKey("response", fmt.Sprint(i), nil)
+			}
+		})
+		for i := range n {
+			l.RecordKey("request", fmt.Sprint(i), nil)
+			reqs <- i
+			l.Record("len", len(reqs))
+		}
+		close(reqs)
+	})
+}
+
+func TestHolds(t *testing.T) {
+	var l Log
+	serve(&l, 10, time.Second, func(int) bool { return false })
+	Check(t, &l,
+		Always(Is("len").Implies(Value("len", "≤ 4", func(n int) bool { return n <= 4 }))),
+		Never(Is("error")),
+		Eventually(Is("response")),
+		LeadsTo(Is("request"), Is("response"), 30*time.Second),
+	)
+}
+
+func TestViolated(t *testing.T) {
+	var l Log
+	serve(&l, 3, time.Second, func(i int) bool { return i == 1 })
+	errs := testtb.Run(t, func(t testing.TB) {
+		Check(t, &l,
+			Always(Is("len").Implies(Value("len", "≤ 1", func(n int) bool { return n <= 1 }))),
+			Eventually(Is("error")),
+			LeadsTo(Is("request"), Is("response"), 5*time.Second),
+		)
+	})
+	want := []string{
+		"always (len → len.≤ 1) violated:\n\tevent 4 00:00:00.000 len(2) by g",
+		"eventually error violated:\n\tno such event among 12",
+		"request leads to response within 5s violated:\n\tevent 3 00:00:00.000 request[1] by g",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("errors %q", errs)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i], w) {
+			t.Errorf("error %q, want prefix %q", errs[i], w)
+		}
+	}
+	if !strings.Contains(errs[2], "answered after 11s by 00:00:11.000 response[1] by server") {
+		t.Errorf("error %q", errs[2])
+	}
+}
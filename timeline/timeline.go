@@ -0,0 +1,84 @@
+// Package timeline records what the goroutines of a synctest bubble do
+// over virtual time: when they are started, when they block in an
+// operation of a chanx channel or a syncx mutex and when they go on, and
+// when they exit, along with the operations on channels and mutexes,
+// hb's marks and the failures reported on a test wrapped by T. Only
+// goroutines started with bubble.Go or sched.Go are seen starting and
+// exiting, and only the instrumented operations blocking. A test failing
+// while its bubble is recorded logs the timeline, showing what each
+// goroutine was doing when the failure was reported. With the flag
+// -synctest.report=dir, an HTML page of the timeline with a lane for each
+// goroutine is written to dir for every recorded test, for looking into
+// failures after the fact, e.g. of a CI run, along with the timeline in
+// the Trace Event Format of Chrome, which Perfetto and chrome://tracing
+// open; see Timeline.WriteChromeTrace. Under go test -trace, the events
+// also go to the execution trace, as a task of the test with a region for
+// every time a goroutine blocks and log messages stamped with the virtual
+// time, so that go tool trace shows them beside the runtime's scheduling.
+// Timeline.CheckGolden compares the structure of a timeline, without its
+// times and line numbers, to a golden file, so that changes to what the
+// goroutines do, such as an extra goroutine or locks taken in another
+// order, show up as a diff; -synctest.update rewrites the files.
+// Timeline.BlockProfile tells how long goroutines were blocked at each
+// site, in virtual time, for a test to catch a path that suddenly waits
+// much longer; it is written as a pprof profile with the report.
+package timeline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/block"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/timeline"
+)
+
+// Timeline is the events of the goroutines of a bubble.
+type Timeline = timeline.Timeline
+
+// Event is a change of what a goroutine does.
+type Event = timeline.Event
+
+// Lane is what a goroutine did.
+type Lane = timeline.Lane
+
+// Blocking is how long goroutines were blocked at a site.
+type Blocking = timeline.Blocking
+
+// Record starts recording the caller's bubble, which it must be called
+// in, until the test ends.
+func Record(t testing.TB) *Timeline {
+	t.Helper()
+	return timeline.Record(t)
+}
+
+// T returns t, recording the failures reported on it in the timeline of
+// the bubble they are reported from, if it is recorded.
+func T(t testing.TB) testing.TB { return failures{t} }
+
+// failures records the failures of a test.
+type failures struct{ testing.TB }
+
+func (f failures) Error(args ...any) {
+	f.TB.Helper()
+	timeline.Fail(strings.TrimSuffix(fmt.Sprintln(args...), "\n"), block.Caller(1))
+	f.TB.Error(args...)
+}
+
+func (f failures) Errorf(format string, args ...any) {
+	f.TB.Helper()
+	timeline.Fail(fmt.Sprintf(format, args...), block.Caller(1))
+	f.TB.Errorf(format, args...)
+}
+
+func (f failures) Fatal(args ...any) {
+	f.TB.Helper()
+	timeline.Fail(strings.TrimSuffix(fmt.Sprintln(args...), "\n"), block.Caller(1))
+	f.TB.Fatal(args...)
+}
+
+func (f failures) Fatalf(format string, args ...any) {
+	f.TB.Helper()
+	timeline.Fail(fmt.Sprintf(format, args...), block.Caller(1))
+	f.TB.Fatalf(format, args...)
+}
@@ -0,0 +1,235 @@
+package timeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/trace"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/bubble"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/chanx"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/hb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/syncx"
+)
+
+func TestRecord(t *testing.T) {
+	synctest.Run(func() {
+		tl := Record(t)
+		ch := chanx.Make[int]("ch", 0)
+		bubble.Go("producer", func() {
+			time.Sleep(time.Second)
+			bubble.Annotate("sending 1")
+			ch.Send(1)
+		})
+		bubble.Go("consumer", func() { ch.Recv() })
+		synctest.Wait()
+		if got, want := lanes(tl)["consumer"].State(), `blocked in recv on channel "ch"`; !strings.HasPrefix(got, want) {
+			t.Errorf("consumer %s, want %s", got, want)
+		}
+		time.Sleep(2 * time.Second)
+		for name, want := range map[string][]string{
+			"producer": {"spawn +0s", "note +1s", "op +1s", "exit +1s"},
+			"consumer": {"spawn +0s", "block +0s", "unblock +1s", "op +1s", "exit +1s"},
+		} {
+			var got []string
+			for _, e := range lanes(tl)[name].Events {
+				got = append(got, e.Kind+" +"+e.At.String())
+			}
+			if strings.Join(got, ", ") != strings.Join(want, ", ") {
+				t.Errorf("%s: events %q, want %q", name, got, want)
+			}
+		}
+		if s := tl.String(); !strings.Contains(s, "consumer, exited at +1s:\n") || !strings.Contains(s, "\t+0s block recv on channel \"ch\"") ||
+			!strings.Contains(s, "\t+1s note sending 1 at timeline/timeline_test.go:") {
+			t.Errorf("timeline\n%s", s)
+		}
+	})
+}
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	flag.Set("synctest.report", dir)
+	defer flag.Set("synctest.report", "")
+	t.Run("sub", func(t *testing.T) {
+		errs := testtb.Run(t, func(tb testing.TB) {
+			synctest.Run(func() {
+				Record(tb)
+				tb = T(tb)
+				bubble.Go("checker", func() {
+					hb.Mark("check")
+					tb.Errorf("broken")
+				})
+				synctest.Wait()
+			})
+		})
+		if len(errs) != 1 {
+			t.Errorf("errors %q", errs)
+		}
+	})
+	page, err := os.ReadFile(filepath.Join(dir, "TestReport_sub.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<th>checker</th>", `<span class="mark">mark check</span>`, `<span class="fail">fail broken</span>`, "timeline_test.go:"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("report lacks %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestWriteChromeTrace(t *testing.T) {
+	var b bytes.Buffer
+	synctest.Run(func() {
+		tl := Record(t)
+		mu := syncx.NewMutex(t, "mu")
+		mu.Lock()
+		bubble.Go("waiter", func() {
+			mu.Lock()
+			mu.Unlock()
+		})
+		synctest.Wait()
+		time.Sleep(time.Millisecond)
+		mu.Unlock()
+		synctest.Wait()
+		if err := tl.WriteChromeTrace(&b); err != nil {
+			t.Fatal(err)
+		}
+	})
+	var trace struct {
+		TraceEvents []struct {
+			Name, Cat, Ph string
+			Ts            float64
+			Tid           int
+		}
+	}
+	if err := json.Unmarshal(b.Bytes(), &trace); err != nil {
+		t.Fatalf("%v:\n%s", err, b.Bytes())
+	}
+	var got []string
+	for _, e := range trace.TraceEvents {
+		// The waiter is the second goroutine seen, after the bubble's.
+		if e.Tid == 2 && e.Ph != "M" {
+			got = append(got, fmt.Sprintf("%s %s %s %v", e.Ph, e.Cat, strings.Fields(e.Name + " -")[0], e.Ts))
+		}
+	}
+	want := []string{"B spawn waiter 0", "B block lock 0", "E unblock - 1000", "i op lock 1000", "i op unlock 1000", "E exit - 1000"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("waiter's events\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRuntimeTrace(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("runtime/trace is on already")
+	}
+	var b bytes.Buffer
+	if err := trace.Start(&b); err != nil {
+		t.Fatal(err)
+	}
+	synctest.Run(func() {
+		Record(t)
+		ch := chanx.Make[int]("traced", 0)
+		bubble.Go("sender", func() { ch.Send(1) })
+		time.Sleep(time.Second)
+		ch.Recv()
+		synctest.Wait()
+	})
+	trace.Stop()
+	for _, want := range []string{"bubble TestRuntimeTrace", `+0s send on channel "traced"`, `+1s g`, `recv channel "traced"`} {
+		if !bytes.Contains(b.Bytes(), []byte(want)) {
+			t.Errorf("trace lacks %q", want)
+		}
+	}
+}
+
+func lanes(tl *Timeline) map[string]Lane {
+	m := make(map[string]Lane)
+	for _, l := range tl.Lanes() {
+		m[l.Goroutine] = l
+	}
+	return m
+}
+
+// pipeline runs a producer and a consumer, and with extra an idle
+// goroutine too.
+func pipeline(t testing.TB, extra bool) *Timeline {
+	var tl *Timeline
+	synctest.Run(func() {
+		tl = Record(t)
+		ch := chanx.Make[int]("ch", 0)
+		bubble.Go("producer", func() {
+			time.Sleep(time.Second)
+			ch.Send(1)
+		})
+		bubble.Go("consumer", func() { ch.Recv() })
+		if extra {
+			bubble.Go("idle", func() {})
+		}
+		time.Sleep(2 * time.Second)
+	})
+	return tl
+}
+
+func TestCheckGolden(t *testing.T) {
+	golden := filepath.Join("testdata", "pipeline.golden")
+	pipeline(t, false).CheckGolden(t, golden)
+	if flag.Lookup("synctest.update").Value.String() == "true" {
+		return
+	}
+
+	errs := testtb.Run(t, func(t testing.TB) { pipeline(t, true).CheckGolden(t, golden) })
+	if len(errs) != 1 || !strings.Contains(errs[0], "\n+idle:\n+\t@0 spawn at timeline/timeline_test.go\n+\t@0 exit\n") {
+		t.Errorf("errors %q, want the idle goroutine added", errs)
+	}
+}
+
+func TestBlockProfile(t *testing.T) {
+	var tl *Timeline
+	synctest.Run(func() {
+		tl = Record(t)
+		ch := chanx.Make[int]("ch", 0)
+		mu := syncx.NewMutex(t, "mu")
+		for _, name := range []string{"a", "b"} {
+			bubble.Go(name, func() {
+				mu.Lock()
+				time.Sleep(time.Second)
+				mu.Unlock()
+			})
+		}
+		bubble.Go("consumer", func() { ch.Recv() })
+		time.Sleep(10 * time.Second)
+		ch.Send(1)
+		synctest.Wait()
+	})
+	prof := tl.BlockProfile()
+	if len(prof) != 2 {
+		t.Fatalf("profile %+v, want the recv and the lock", prof)
+	}
+	if p := prof[0]; !strings.HasPrefix(p.What, `recv on channel "ch"`) || p.Count != 1 || p.Delay != 10*time.Second {
+		t.Errorf("longest blocking %+v, want the recv, for 10s", p)
+	}
+	if p := prof[1]; p.What != `lock mutex "mu" held by goroutine` || p.Count != 1 || p.Delay != time.Second {
+		t.Errorf("blocking %+v, want the lock, for 1s", p)
+	}
+	var b bytes.Buffer
+	if err := tl.WriteBlockProfile(&b); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pb, err := io.ReadAll(zr); err != nil || !bytes.Contains(pb, []byte(`recv on channel "ch"`)) {
+		t.Errorf("profile lacks the recv: %q, %v", pb, err)
+	}
+}
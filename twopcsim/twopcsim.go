@@ -0,0 +1,357 @@
+// Package twopcsim is a scenario kit for two-phase commit: it runs a
+// coordinator and N participants on a simnet network in a synctest
+// bubble, crashes the coordinator at the points between prepare and
+// commit a test picks, gives the participants their timeouts on the
+// virtual clock, and checks the atomicity of the transactions from what
+// the nodes report: no transaction is committed by a participant and
+// aborted by another, none is committed unless every participant voted
+// for it, and none ends other than the coordinator decided.
+//
+// The coordinator marks the points with Env.Prepared, Env.Decided and
+// Env.Sent, which are failpoints, "twopcsim/prepared" and so on, that
+// CrashCoordinator enables.
+package twopcsim
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/failpoint"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// Port is the port of the mailboxes of the nodes.
+const Port = "2pc"
+
+// Coordinator is the name of the coordinator's node.
+const Coordinator = "coord"
+
+// Point is a point of the coordinator between prepare and commit.
+type Point string
+
+const (
+	// Prepared is once the coordinator asked the participants to prepare.
+	Prepared Point = "prepared"
+	// Decided is once it decided, and logged its decision.
+	Decided Point = "decided"
+	// Sent is once a participant got the decision.
+	Sent Point = "sent"
+)
+
+// Start starts the coordinator or a participant of env: it reads its
+// state from env.Proc.Disk, starts its goroutines with env.Proc.Go and
+// returns.
+type Start func(env *Env)
+
+// Env is what a node of a Cluster runs with, for an incarnation of its
+// process.
+type Env struct {
+	Name         string
+	Participants []string
+	Proc         *simnet.Proc
+	Mailbox      *simnet.Mailbox // on Port, to exchange messages with the other nodes
+	// Txns are the transactions the coordinator is to run, as Begin
+	// queues them; nil for the participants.
+	Txns <-chan string
+
+	c *Cluster
+}
+
+// Timeout returns how long a participant waits for the coordinator, on
+// the virtual clock, before acting on its own.
+func (e *Env) Timeout() time.Duration { return e.c.cfg.timeout() }
+
+// CanCommit reports whether the participant is to vote for txn.
+func (e *Env) CanCommit(txn string) bool {
+	return e.c.cfg.Refuse == nil || !e.c.cfg.Refuse(e.Name, txn)
+}
+
+// Prepared marks that the coordinator asked the participants to prepare
+// txn. If it returns an error, the coordinator crashed.
+func (e *Env) Prepared(txn string) error { return failpoint.Inject(failpointName(Prepared)) }
+
+// Decided reports that the coordinator decided to commit txn, or abort
+// it, and logged the decision. If it returns an error, the coordinator
+// crashed.
+func (e *Env) Decided(txn string, commit bool) error {
+	e.c.decided(txn, commit)
+	return failpoint.Inject(failpointName(Decided))
+}
+
+// Sent marks that the participant got the decision on txn. If it returns
+// an error, the coordinator crashed.
+func (e *Env) Sent(txn, participant string) error { return failpoint.Inject(failpointName(Sent)) }
+
+// Voted reports the vote of the participant on txn.
+func (e *Env) Voted(txn string, yes bool) { e.c.voted(e.Name, txn, yes) }
+
+// Finished reports that the participant committed txn, or aborted it.
+func (e *Env) Finished(txn string, committed bool) { e.c.finished(e.Name, txn, committed) }
+
+func failpointName(at Point) string { return "twopcsim/" + string(at) }
+
+// Config configures a Cluster.
+type Config struct {
+	Participants int // default 3
+	// Timeout is how long participants wait for the coordinator, default
+	// 1s.
+	Timeout time.Duration
+	// Refuse reports whether a participant votes against a transaction,
+	// nil for never.
+	Refuse func(participant, txn string) bool
+	// Seed is the seed of the network, default that of the caller's sched
+	// run, or else sched.Seed, logged if t fails.
+	Seed uint64
+}
+
+func (c Config) participants() int {
+	if c.Participants > 0 {
+		return c.Participants
+	}
+	return 3
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return time.Second
+}
+
+// Cluster is a coordinator, Coordinator, and participants, "p1" to "pN".
+type Cluster struct {
+	Net *simnet.Network
+
+	t   testing.TB
+	cfg Config
+
+	mu        sync.Mutex
+	txns      chan string     // of the running coordinator
+	decisions map[string]bool // by transaction
+	txs       map[string]*tx
+	order     []string // of the transactions reported
+
+	restartMu sync.Mutex    // held while restarting the coordinator
+	restarts  []*time.Timer // restarting the coordinator crashed
+	closed    bool
+}
+
+// tx is what the participants reported of a transaction.
+type tx struct {
+	votes    map[string]bool // by participant
+	outcomes map[string]bool // committed, by participant
+}
+
+// New starts a cluster of a coordinator running coordinator and
+// cfg.Participants participants running participant, on a network of its
+// own, in the caller's bubble. Close it before the bubble ends.
+func New(t testing.TB, cfg Config, coordinator, participant Start) *Cluster {
+	if cfg.Seed == 0 {
+		cfg.Seed = sched.SeedFor(t, "twopcsim")
+	}
+	c := &Cluster{Net: simnet.New(), t: t, cfg: cfg, decisions: make(map[string]bool), txs: make(map[string]*tx)}
+	c.Net.Seed(cfg.Seed)
+	names := c.Participants()
+	c.Net.Add(Coordinator, func(p *simnet.Proc) {
+		txns := make(chan string, 64)
+		c.mu.Lock()
+		c.txns = txns
+		c.mu.Unlock()
+		c.boot(p, names, txns, coordinator)
+	})
+	for _, name := range names {
+		c.Net.Add(name, func(p *simnet.Proc) { c.boot(p, names, nil, participant) })
+	}
+	return c
+}
+
+// Participants returns the names of the participants of c.
+func (c *Cluster) Participants() []string {
+	names := make([]string, c.cfg.participants())
+	for i := range names {
+		names[i] = fmt.Sprintf("p%d", i+1)
+	}
+	return names
+}
+
+func (c *Cluster) boot(p *simnet.Proc, names []string, txns chan string, start Start) {
+	m, err := p.Mailbox(Port)
+	if err != nil {
+		return
+	}
+	start(&Env{Name: p.Node().Name, Participants: names, Proc: p, Mailbox: m, Txns: txns, c: c})
+}
+
+// Close crashes every node of c, for good, for the end of a scenario.
+func (c *Cluster) Close() {
+	c.restartMu.Lock()
+	c.closed = true
+	for _, t := range c.restarts {
+		t.Stop()
+	}
+	c.restartMu.Unlock()
+	c.Net.Close()
+}
+
+// Node returns the node name of c.
+func (c *Cluster) Node(name string) *simnet.Node { return c.Net.Node(name) }
+
+// Begin queues txn for the coordinator to run, if it is up.
+func (c *Cluster) Begin(txn string) {
+	c.mu.Lock()
+	txns := c.txns
+	c.mu.Unlock()
+	if c.Net.Node(Coordinator).Up() {
+		txns <- txn
+	}
+}
+
+// CrashCoordinator crashes the coordinator the nth time it reaches at,
+// counting from 1, and restarts it after down.
+func (c *Cluster) CrashCoordinator(at Point, n int, down time.Duration) {
+	coord := c.Net.Node(Coordinator)
+	crash := coord.CrashPolicy()
+	failpoint.Enable(c.t, failpointName(at), failpoint.OnHit(n, func(hit int) error {
+		c.restartMu.Lock()
+		defer c.restartMu.Unlock()
+		if c.closed {
+			return nil
+		}
+		c.restarts = append(c.restarts, time.AfterFunc(down, func() {
+			c.restartMu.Lock()
+			defer c.restartMu.Unlock()
+			if !c.closed {
+				coord.Restart(nil)
+			}
+		}))
+		return crash(hit)
+	}))
+}
+
+// Outcome returns whether the participant committed txn, if it ended it.
+func (c *Cluster) Outcome(txn, participant string) (committed, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t := c.txs[txn]; t != nil {
+		committed, ok = t.outcomes[participant]
+	}
+	return committed, ok
+}
+
+// tx returns what was reported of txn, with c.mu held.
+func (c *Cluster) tx(txn string) *tx {
+	t := c.txs[txn]
+	if t == nil {
+		t = &tx{votes: make(map[string]bool), outcomes: make(map[string]bool)}
+		c.txs[txn] = t
+		c.order = append(c.order, txn)
+	}
+	return t
+}
+
+func (c *Cluster) decided(txn string, commit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d, ok := c.decisions[txn]; ok && d != commit {
+		c.fail("%s: the coordinator decided to %s, then to %s", txn, verb(d), verb(commit))
+	}
+	c.decisions[txn] = commit
+	c.tx(txn)
+}
+
+func (c *Cluster) voted(participant, txn string, yes bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tx(txn).votes[participant] = yes
+}
+
+func (c *Cluster) finished(participant, txn string, committed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.tx(txn)
+	if o, ok := t.outcomes[participant]; ok && o != committed {
+		c.fail("%s: %s %s, then %s", txn, participant, past(o), past(committed))
+	}
+	t.outcomes[participant] = committed
+}
+
+// fail fails c.t, with c.mu held.
+func (c *Cluster) fail(format string, args ...any) {
+	c.t.Errorf("twopcsim: "+format, args...)
+}
+
+func verb(commit bool) string {
+	if commit {
+		return "commit"
+	}
+	return "abort"
+}
+
+func past(committed bool) string {
+	if committed {
+		return "committed"
+	}
+	return "aborted"
+}
+
+// CheckAtomicity fails t if a transaction was committed by a participant
+// and aborted by another, committed though a participant did not vote
+// for it, or ended otherwise than the coordinator decided. With decided,
+// it also fails t if a participant that voted for a transaction did not
+// end it.
+func (c *Cluster) CheckAtomicity(decided bool) {
+	c.t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, txn := range c.order {
+		t := c.txs[txn]
+		var committed, aborted, undecided []string
+		for _, p := range c.Participants() {
+			o, ok := t.outcomes[p]
+			switch {
+			case ok && o:
+				committed = append(committed, p)
+			case ok:
+				aborted = append(aborted, p)
+			case t.votes[p]:
+				undecided = append(undecided, p)
+			}
+		}
+		if len(committed) > 0 && len(aborted) > 0 {
+			c.fail("%s: %s committed, %s aborted", txn, list(committed), list(aborted))
+			continue
+		}
+		if len(committed) > 0 {
+			var against []string
+			for _, p := range c.Participants() {
+				if !t.votes[p] {
+					against = append(against, p)
+				}
+			}
+			if len(against) > 0 {
+				c.fail("%s: %s committed without the votes of %s", txn, list(committed), list(against))
+				continue
+			}
+		}
+		if d, ok := c.decisions[txn]; ok && (d && len(aborted) > 0 || !d && len(committed) > 0) {
+			c.fail("%s: the coordinator decided to %s, %s", txn, verb(d), outcomes(committed, aborted))
+			continue
+		}
+		if decided && len(undecided) > 0 {
+			c.fail("%s: %s voted for it but did not end it", txn, list(undecided))
+		}
+	}
+}
+
+func list(names []string) string { return strings.Join(names, ", ") }
+
+func outcomes(committed, aborted []string) string {
+	if len(committed) > 0 {
+		return list(committed) + " committed"
+	}
+	return list(aborted) + " aborted"
+}
@@ -0,0 +1,276 @@
+package twopcsim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/faultfs"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/sched"
+	"github.com/denisjgr/Go-Project-Modelbased-SE/simnet"
+)
+
+// msg is a message of the two-phase commit of the tests: "prepare",
+// "vote", "commit", "abort", "ack" or "query".
+type msg struct {
+	Type string
+	Txn  string
+	Yes  bool // of a vote
+}
+
+func send(env *Env, to string, m msg) {
+	data, _ := json.Marshal(m)
+	env.Mailbox.Send(to, data)
+}
+
+// save writes v to the file name of disk, and syncs it.
+func save(disk *faultfs.FS, name string, v any) error {
+	data, _ := json.Marshal(v)
+	f, err := disk.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// coordinator logs the transactions it begins and its decisions, and
+// aborts those it did not decide when it restarts. It answers the queries
+// of the participants once it decided.
+func coordinator(env *Env) {
+	var log struct {
+		Begun     []string
+		Decisions map[string]bool
+	}
+	if data, err := env.Proc.Disk().ReadFile("log"); err == nil {
+		json.Unmarshal(data, &log)
+	}
+	if log.Decisions == nil {
+		log.Decisions = make(map[string]bool)
+	}
+	for _, txn := range log.Begun {
+		if _, ok := log.Decisions[txn]; !ok {
+			log.Decisions[txn] = false
+			if save(env.Proc.Disk(), "log", log) != nil || env.Decided(txn, false) != nil {
+				return
+			}
+		}
+	}
+	env.Proc.Go(func(ctx context.Context) {
+		answer := func(in simnet.Message) {
+			var m msg
+			json.Unmarshal(in.Data, &m)
+			if commit, ok := log.Decisions[m.Txn]; ok && m.Type == "query" {
+				send(env, in.From, msg{Type: verb(commit), Txn: m.Txn})
+			}
+		}
+		// recv returns the next message of the transaction txn, answering
+		// queries meanwhile, or false after d.
+		recv := func(txn string, d time.Duration) (string, msg, bool) {
+			timeout := time.After(d)
+			for {
+				select {
+				case <-ctx.Done():
+					return "", msg{}, false
+				case <-timeout:
+					return "", msg{}, false
+				case in := <-env.Mailbox.Inbox():
+					var m msg
+					json.Unmarshal(in.Data, &m)
+					if m.Txn == txn {
+						return in.From, m, true
+					}
+					answer(in)
+				}
+			}
+		}
+		for {
+			var txn string
+			select {
+			case <-ctx.Done():
+				return
+			case txn = <-env.Txns:
+			case in := <-env.Mailbox.Inbox():
+				answer(in)
+				continue
+			}
+			log.Begun = append(log.Begun, txn)
+			if save(env.Proc.Disk(), "log", log) != nil {
+				return
+			}
+			for _, p := range env.Participants {
+				send(env, p, msg{Type: "prepare", Txn: txn})
+			}
+			if env.Prepared(txn) != nil {
+				return
+			}
+			votes := make(map[string]bool)
+			for len(votes) < len(env.Participants) {
+				from, m, ok := recv(txn, env.Timeout())
+				if !ok {
+					break
+				}
+				if m.Type == "vote" {
+					votes[from] = m.Yes
+				}
+			}
+			commit := len(votes) == len(env.Participants)
+			for _, yes := range votes {
+				commit = commit && yes
+			}
+			log.Decisions[txn] = commit
+			if save(env.Proc.Disk(), "log", log) != nil || env.Decided(txn, commit) != nil {
+				return
+			}
+			for _, p := range env.Participants {
+				send(env, p, msg{Type: verb(commit), Txn: txn})
+				for {
+					from, m, ok := recv(txn, env.Timeout())
+					if !ok {
+						break
+					}
+					if from == p && m.Type == "ack" {
+						if env.Sent(txn, p) != nil {
+							return
+						}
+						break
+					}
+				}
+			}
+		}
+	})
+}
+
+// participant keeps the states of the transactions on its disk. Unless
+// it is careful, it aborts the transactions it prepared once it waited
+// for the coordinator for its timeout; otherwise it asks the coordinator
+// about them.
+func participant(careful bool) Start {
+	return func(env *Env) {
+		states := make(map[string]string) // "prepared", "commit" or "abort"
+		if data, err := env.Proc.Disk().ReadFile("states"); err == nil {
+			json.Unmarshal(data, &states)
+		}
+		since := make(map[string]time.Time) // by prepared transaction
+		for txn, s := range states {
+			if s == "prepared" {
+				since[txn] = time.Now()
+			}
+		}
+		env.Proc.Go(func(ctx context.Context) {
+			tick := time.NewTicker(env.Timeout() / 2)
+			defer tick.Stop()
+			end := func(txn string, commit bool) bool {
+				states[txn] = verb(commit)
+				delete(since, txn)
+				if save(env.Proc.Disk(), "states", states) != nil {
+					return false
+				}
+				env.Finished(txn, commit)
+				return true
+			}
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-tick.C:
+					for txn, t := range since {
+						if time.Since(t) < env.Timeout() {
+							continue
+						}
+						if careful {
+							send(env, Coordinator, msg{Type: "query", Txn: txn})
+						} else if !end(txn, false) {
+							return
+						}
+					}
+				case in := <-env.Mailbox.Inbox():
+					var m msg
+					json.Unmarshal(in.Data, &m)
+					switch s := states[m.Txn]; m.Type {
+					case "prepare":
+						if s == "" {
+							if env.CanCommit(m.Txn) {
+								states[m.Txn], since[m.Txn] = "prepared", time.Now()
+								if save(env.Proc.Disk(), "states", states) != nil {
+									return
+								}
+								env.Voted(m.Txn, true)
+							} else {
+								env.Voted(m.Txn, false)
+								if !end(m.Txn, false) {
+									return
+								}
+							}
+						}
+						send(env, Coordinator, msg{Type: "vote", Txn: m.Txn, Yes: states[m.Txn] != "abort"})
+					case "commit", "abort":
+						if s == "prepared" && !end(m.Txn, m.Type == "commit") {
+							return
+						}
+						send(env, Coordinator, msg{Type: "ack", Txn: m.Txn})
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCommit(t *testing.T) {
+	sched.ExploreRandom(t, 10, func(t testing.TB) {
+		c := New(t, Config{Refuse: func(p, txn string) bool { return p == "p2" && txn == "t2" }}, coordinator, participant(true))
+		defer c.Close()
+		c.Begin("t1")
+		c.Begin("t2")
+		time.Sleep(5 * time.Second)
+		c.CheckAtomicity(true)
+		for _, p := range c.Participants() {
+			for txn, want := range map[string]bool{"t1": true, "t2": false} {
+				if got, ok := c.Outcome(txn, p); !ok || got != want {
+					t.Errorf("%s ended %s: %v, %v; want %v", p, txn, got, ok, want)
+				}
+			}
+		}
+	})
+}
+
+func TestCrashCoordinator(t *testing.T) {
+	for _, at := range []Point{Prepared, Decided, Sent} {
+		t.Run(string(at), func(t *testing.T) {
+			synctest.Run(func() {
+				c := New(t, Config{Seed: 1}, coordinator, participant(true))
+				defer c.Close()
+				c.CrashCoordinator(at, 1, 3*time.Second)
+				c.Begin("t1")
+				time.Sleep(10 * time.Second)
+				c.CheckAtomicity(true)
+			})
+		})
+	}
+}
+
+func TestAtomicityViolation(t *testing.T) {
+	rec := testtb.New(t)
+	synctest.Run(func() {
+		c := New(rec, Config{Seed: 1}, coordinator, participant(false))
+		defer c.Close()
+		c.CrashCoordinator(Sent, 1, 10*time.Second)
+		c.Begin("t1")
+		time.Sleep(20 * time.Second)
+		c.CheckAtomicity(true)
+	})
+	want := []string{
+		"twopcsim: t1: p1 committed, p2, p3 aborted",
+	}
+	if errs := rec.Errors(); fmt.Sprint(errs) != fmt.Sprint(want) {
+		t.Errorf("participants aborting on their own reported:\n%s\nwant:\n%s", strings.Join(errs, "\n"), strings.Join(want, "\n"))
+	}
+}
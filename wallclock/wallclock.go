@@ -0,0 +1,139 @@
+// Package wallclock is the wall clock of code under test, which tests can
+// make misbehave as real ones do: step it hours forward or back, as NTP
+// does to a clock that drifted or a machine resumed from suspend does, or
+// freeze it while the monotonic clock goes on. Code whose leases, tokens
+// or timeouts depend on wall time reads it with Now instead of time.Now:
+//
+//	if wallclock.Now().After(token.Expiry) {
+//		refresh()
+//	}
+//
+// Durations measured with time.Since stay on the monotonic clock, which
+// the faults leave alone, as the kernel does.
+package wallclock
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/gstack"
+)
+
+// Now returns the wall clock reading, with the faults of the caller's
+// bubble applied. It has no monotonic reading, so that comparing times
+// from Now sees the jumps, as comparing times serialized or taken on
+// another machine would. Without faults it costs little more than
+// time.Now.
+func Now() time.Time {
+	now := time.Now().Round(0)
+	if count.Load() == 0 {
+		return now
+	}
+	if s := skewOf(gstack.Self().Group); s != nil {
+		return s.apply(now)
+	}
+	return now
+}
+
+// Skew is the misbehavior of a wall clock.
+type Skew struct {
+	group int64 // bubble it applies in, 0 for all
+
+	mu     sync.Mutex
+	offset time.Duration
+	frozen bool
+	at     time.Time // reading the clock froze at
+}
+
+var (
+	mu    sync.Mutex
+	skews = make(map[int64]*Skew)
+	count atomic.Int32 // of the skews
+)
+
+// Fault returns the Skew of the wall clock the calling bubble reads, or of
+// the one read everywhere if called outside bubbles, until t ends. It
+// starts out without faults.
+func Fault(t testing.TB) *Skew {
+	t.Helper()
+	s := &Skew{group: gstack.Self().Group}
+	mu.Lock()
+	defer mu.Unlock()
+	for g := range skews {
+		if g == s.group || g == 0 || s.group == 0 {
+			t.Fatal("wallclock: the clock is already faulty")
+		}
+	}
+	skews[s.group] = s
+	count.Add(1)
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(skews, s.group)
+		count.Add(-1)
+	})
+	return s
+}
+
+func skewOf(group int64) *Skew {
+	mu.Lock()
+	defer mu.Unlock()
+	if s := skews[group]; s != nil {
+		return s
+	}
+	return skews[0]
+}
+
+func (s *Skew) apply(now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frozen {
+		return s.at
+	}
+	return now.Add(s.offset)
+}
+
+// Step jumps the clock by d, backward if negative, frozen or not.
+func (s *Skew) Step(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+	s.at = s.at.Add(d)
+}
+
+// Freeze stops the clock at its current reading.
+func (s *Skew) Freeze() {
+	now := time.Now().Round(0)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.frozen {
+		s.frozen, s.at = true, now.Add(s.offset)
+	}
+}
+
+// Thaw restarts a frozen clock from the reading it stopped at, so it is
+// behind by as long as it was frozen, as the clock of a virtual machine
+// that was paused.
+func (s *Skew) Thaw() {
+	now := time.Now().Round(0)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frozen {
+		s.frozen, s.offset = false, s.at.Sub(now)
+	}
+}
+
+// Reset sets the clock right and restarts it if frozen.
+func (s *Skew) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frozen, s.offset = false, 0
+}
+
+// Offset returns how far the clock is ahead of the monotonic time, or
+// behind if negative.
+func (s *Skew) Offset() time.Duration {
+	return s.apply(time.Now().Round(0)).Sub(time.Now().Round(0))
+}
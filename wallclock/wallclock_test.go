@@ -0,0 +1,80 @@
+package wallclock
+
+import (
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/denisjgr/Go-Project-Modelbased-SE/internal/testtb"
+)
+
+// lease expires at a wall time, as a lease granted by another machine.
+type lease struct{ expiry time.Time }
+
+func (l lease) valid() bool { return Now().Before(l.expiry) }
+
+func TestStep(t *testing.T) {
+	synctest.Run(func() {
+		s := Fault(t)
+		l := lease{Now().Add(time.Minute)}
+		start := time.Now()
+		time.AfterFunc(10*time.Second, func() { s.Step(2 * time.Hour) })
+		time.Sleep(20 * time.Second)
+		if l.valid() {
+			t.Error("lease valid after the clock jumped past its expiry")
+		}
+		if d := time.Since(start); d != 20*time.Second {
+			t.Errorf("monotonic time advanced %v, want 20s", d)
+		}
+		s.Step(-3 * time.Hour)
+		if got := s.Offset(); got != -time.Hour {
+			t.Errorf("offset %v, want -1h", got)
+		}
+		if !l.valid() {
+			t.Error("lease expired with the clock set back")
+		}
+	})
+}
+
+func TestFreeze(t *testing.T) {
+	synctest.Run(func() {
+		s := Fault(t)
+		s.Freeze()
+		at := Now()
+		time.Sleep(time.Hour)
+		if !Now().Equal(at) {
+			t.Errorf("frozen clock moved from %v to %v", at, Now())
+		}
+		s.Thaw()
+		time.Sleep(time.Second)
+		if got := Now().Sub(at); got != time.Second {
+			t.Errorf("thawed clock advanced %v, want 1s", got)
+		}
+		if got := s.Offset(); got != -time.Hour {
+			t.Errorf("offset %v, want -1h", got)
+		}
+		s.Reset()
+		if got := s.Offset(); got != 0 {
+			t.Errorf("offset %v after Reset", got)
+		}
+	})
+}
+
+func TestScope(t *testing.T) {
+	synctest.Run(func() {
+		Fault(t).Step(time.Hour)
+	})
+	if d := time.Until(Now()); d > time.Minute {
+		t.Errorf("clock of a bubble faulty outside: %v ahead", d)
+	}
+}
+
+func TestFaultTwice(t *testing.T) {
+	errs := testtb.Run(t, func(t testing.TB) {
+		Fault(t)
+		Fault(t)
+	})
+	if len(errs) != 1 {
+		t.Errorf("faulting twice: errors %q", errs)
+	}
+}